@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ohauer/secrets-sync/internal/audit"
+	"github.com/ohauer/secrets-sync/internal/logger"
+	"github.com/ohauer/secrets-sync/internal/syncer"
+	"go.uber.org/zap"
+)
+
+func printAuditUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: secrets-sync audit verify <audit-log>\n")
+	fmt.Fprintf(os.Stderr, "\nChecks the hash chain of an audit log written with AUDIT_LOG and exits 1 at\n")
+	fmt.Fprintf(os.Stderr, "the first record that was edited, removed or inserted.\n")
+}
+
+// runAudit verifies an audit log
+func runAudit(args []string) int {
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+		printAuditUsage()
+		return 0
+	}
+	if len(args) != 2 || args[0] != "verify" {
+		printAuditUsage()
+		return 1
+	}
+
+	f, err := os.Open(args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer func() { _ = f.Close() }()
+
+	count, err := audit.Verify(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %s: %v\n", args[1], err)
+		return 1
+	}
+	fmt.Printf("✓ %s: %d records, chain intact\n", args[1], count)
+	return 0
+}
+
+// openAuditLog opens the audit log at path and records every file the
+// syncer writes in it
+func openAuditLog(secretSyncer *syncer.SecretSyncer, path string) (*audit.Log, error) {
+	auditLog, err := audit.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	recordWrites(secretSyncer, auditLog)
+	return auditLog, nil
+}
+
+// recordWrites records every file the syncer writes in the audit log. A
+// failed record is logged, the write itself stands.
+func recordWrites(secretSyncer *syncer.SecretSyncer, auditLog *audit.Log) {
+	secretSyncer.WithAuditor(func(e syncer.AuditEvent) {
+		err := auditLog.Write(audit.Record{
+			Secret: e.Secret,
+			Source: e.Source,
+			Path:   e.Path,
+			SHA256: e.SHA256,
+			Size:   e.Size,
+			Reason: e.Reason,
+		})
+		if err != nil {
+			logger.Error("failed to record write in audit log",
+				zap.String("secret", e.Secret),
+				zap.String("path", e.Path),
+				zap.Error(err),
+			)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/bench"
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/logger"
+	"github.com/ohauer/secrets-sync/internal/syncer"
+)
+
+func printBenchUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: secrets-sync bench [options]\n")
+	fmt.Fprintf(os.Stderr, "\nSyncs synthetic secrets from a built-in mock Vault and reports throughput,\n")
+	fmt.Fprintf(os.Stderr, "Vault QPS, write latency and memory usage.\n")
+	fmt.Fprintf(os.Stderr, "\nOptions:\n")
+	fmt.Fprintf(os.Stderr, "  --secrets <n>            Number of secrets (default: 100)\n")
+	fmt.Fprintf(os.Stderr, "  --duration <duration>    How long to run (default: 30s)\n")
+	fmt.Fprintf(os.Stderr, "  --interval <duration>    Refresh interval of each secret (default: 5s)\n")
+	fmt.Fprintf(os.Stderr, "  --latency <duration>     Delay of every mock Vault read (default: 0)\n")
+	fmt.Fprintf(os.Stderr, "  --output-dir <dir>       Directory for the files (default: temporary, removed afterwards)\n")
+	fmt.Fprintf(os.Stderr, "\nExample:\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync bench --secrets 5000 --interval 1s --duration 2m --latency 5ms\n")
+}
+
+// runBench runs the load test and prints its report
+func runBench(args []string) int {
+	opts := bench.Options{
+		Secrets:  100,
+		Duration: 30 * time.Second,
+		Interval: 5 * time.Second,
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "-h" || arg == "--help" {
+			printBenchUsage()
+			return 0
+		}
+		if i+1 >= len(args) {
+			fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", arg)
+			return 1
+		}
+		value := args[i+1]
+		i++
+
+		var err error
+		switch arg {
+		case "--secrets":
+			opts.Secrets, err = strconv.Atoi(value)
+		case "--duration":
+			opts.Duration, err = time.ParseDuration(value)
+		case "--interval":
+			opts.Interval, err = time.ParseDuration(value)
+		case "--latency":
+			opts.Latency, err = time.ParseDuration(value)
+		case "--output-dir":
+			opts.OutputDir = value
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown option: %s\n\n", arg)
+			printBenchUsage()
+			return 1
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid value for %s: %v\n", arg, err)
+			return 1
+		}
+	}
+
+	if opts.OutputDir == "" {
+		dir, err := os.MkdirTemp("", "secrets-sync-bench-")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer func() { _ = os.RemoveAll(dir) }()
+		opts.OutputDir = dir
+	}
+
+	// Keep the output to the report; sync errors are counted instead of logged
+	envCfg := config.LoadEnvConfig()
+	if err := logger.Init("error"); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	opts.Retry = newRetryConfig(envCfg)
+	opts.NewClientFactory = func(cfg *config.Config) syncer.ClientFactory {
+		return newClientFactory(cfg, envCfg)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("Syncing %d secrets every %s for %s (mock Vault latency %s)...\n",
+		opts.Secrets, opts.Interval, opts.Duration, opts.Latency)
+
+	report, err := bench.Run(ctx, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("\n")
+	fmt.Printf("  Elapsed:          %s\n", report.Elapsed.Round(time.Millisecond))
+	if report.InitialSync > 0 {
+		fmt.Printf("  Initial sync:     %s (all %d secrets synced once)\n", report.InitialSync.Round(time.Millisecond), report.Secrets)
+	} else {
+		fmt.Printf("  Initial sync:     not reached\n")
+	}
+	fmt.Printf("  Syncs:            %d (%d failed, %.1f/s)\n", report.Syncs, report.Failures, report.SyncsPerSecond())
+	fmt.Printf("  Vault reads:      %d (%.1f/s)\n", report.VaultRequests, report.VaultQPS())
+	printLatencies("Sync latency:", report.SyncLatency)
+	printLatencies("Write latency:", report.WriteLatency)
+	fmt.Printf("  Peak heap:        %.1f MiB\n", float64(report.PeakHeapBytes)/(1<<20))
+	fmt.Printf("  Peak goroutines:  %d\n", report.PeakGoroutines)
+	fmt.Printf("  GC cycles:        %d\n", report.GCCycles)
+
+	if report.Failures > 0 || report.InitialSync == 0 {
+		return 1
+	}
+	return 0
+}
+
+func printLatencies(label string, l bench.Latencies) {
+	fmt.Printf("  %-17s p50 %s, p95 %s, p99 %s, max %s (%d samples)\n", label,
+		l.P50.Round(time.Microsecond), l.P95.Round(time.Microsecond),
+		l.P99.Round(time.Microsecond), l.Max.Round(time.Microsecond), l.Count)
+}
@@ -0,0 +1,479 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Kinds of flag values and arguments, for shell completion
+const (
+	completeNone   = ""
+	completeValue  = "value"  // Free text, nothing to complete
+	completeFile   = "file"   // A path
+	completeDir    = "dir"    // A directory
+	completeSecret = "secret" // The name of a configured secret
+)
+
+// cliFlag is a flag of a command
+type cliFlag struct {
+	name   string   // Long form, e.g. --secret
+	short  string   // Short form, e.g. -c, if any
+	kind   string   // Kind of value the flag takes, completeNone for none
+	values []string // Fixed set of values, instead of kind
+	help   string
+}
+
+// takesValue reports whether the flag is followed by a value
+func (f cliFlag) takesValue() bool {
+	return f.kind != completeNone || len(f.values) > 0
+}
+
+// cliCommand is a subcommand with the flags and arguments it accepts
+type cliCommand struct {
+	name  string
+	help  string
+	flags []cliFlag
+	args  []string // Fixed words accepted as arguments, e.g. verify
+	kind  string   // Kind of other arguments, completeNone for none
+}
+
+var (
+	configFlag      = cliFlag{name: "--config", short: "-c", kind: completeFile, help: "Configuration file"}
+	kvVersionFlag   = cliFlag{name: "--kv-version", values: []string{"v1", "v2"}, help: "KV engine version"}
+	mountFlag       = cliFlag{name: "--mount", kind: completeValue, help: "Mount path containing slashes"}
+	namespaceFlag   = cliFlag{name: "--namespace", kind: completeValue, help: "OpenBao namespace, / for root"}
+	credentialsFlag = cliFlag{name: "--credentials", kind: completeValue, help: "Named credential set"}
+)
+
+// cliCommands are the subcommands, in the order of the help text. Keep them
+// in sync with main and the usage of each command.
+var cliCommands = []cliCommand{
+	{name: "init", help: "Generate example configuration file", flags: []cliFlag{
+		{name: "--format", values: []string{"yaml", "json", "toml"}, help: "Config format"},
+	}},
+	{name: "validate", help: "Validate configuration file", flags: []cliFlag{
+		configFlag,
+		{name: "--format", values: []string{"text", "json"}, help: "Output format"},
+		{name: "--strict", help: "Also read and render every secret"},
+	}},
+	{name: "fmt", help: "Rewrite configuration file in canonical style", kind: completeFile, flags: []cliFlag{
+		{name: "--check", help: "List files that are not formatted"},
+		{name: "--stdout", help: "Print the formatted config"},
+	}},
+	{name: "convert", help: "Convert external-secrets YAML to secrets-sync format", kind: completeFile, flags: []cliFlag{
+		{name: "--store-file", kind: completeFile, help: "Read SecretStores from file"},
+		{name: "--from-cluster", help: "Read SecretStores with kubectl"},
+		{name: "--mount-path", kind: completeValue, help: "KV mount path if the store is unknown"},
+		{name: "--kv-version", values: []string{"v1", "v2"}, help: "KV version if the store is unknown"},
+		{name: "--output-dir", kind: completeDir, help: "Output directory for secrets"},
+		{name: "--out", kind: completeFile, help: "Write the config to a file or directory"},
+		{name: "--split", help: "Write one file per ExternalSecret"},
+		{name: "--to", values: []string{"secrets-sync", "external-secrets"}, help: "Output format"},
+		{name: "--namespace", kind: completeValue, help: "Namespace of the ExternalSecrets"},
+		{name: "--query-vault", help: "Query Vault for actual field names"},
+		{name: "--vault-addr", kind: completeValue, help: "Vault address"},
+		{name: "--vault-token", kind: completeValue, help: "Vault token"},
+		{name: "--vault-role-id", kind: completeValue, help: "Vault AppRole role_id"},
+		{name: "--vault-secret-id", kind: completeValue, help: "Vault AppRole secret_id"},
+	}},
+	{name: "import", help: "Generate config from existing secret files and a Vault prefix", flags: []cliFlag{
+		{name: "--dir", kind: completeDir, help: "Directory with the existing secret files"},
+		{name: "--vault-prefix", kind: completeValue, help: "Mount and path to search"},
+		{name: "--kv-version", values: []string{"v1", "v2"}, help: "KV version of the mount"},
+		{name: "--namespace", kind: completeValue, help: "Vault/OpenBao namespace"},
+		{name: "--refresh-interval", kind: completeValue, help: "Refresh interval of generated secrets"},
+	}},
+	{name: "plan", help: "Show file changes a sync would make"},
+	{name: "apply", help: "Sync all secrets once and remove orphaned files"},
+	{name: "sync", help: "Sync all secrets once and exit"},
+	{name: "render", help: "Print the files secrets would be written to", flags: []cliFlag{
+		configFlag,
+		{name: "--secret", kind: completeSecret, help: "Render only this secret"},
+		{name: "--data", kind: completeFile, help: "Render from the fields in this JSON object"},
+	}},
+	{name: "diff", help: "Show how files on disk differ from the secrets", flags: []cliFlag{
+		configFlag,
+		{name: "--secret", kind: completeSecret, help: "Compare only this secret"},
+		{name: "--show-values", help: "Show values instead of masking them"},
+	}},
+	{name: "get", help: "Print a secret read with the configured store settings", flags: []cliFlag{
+		configFlag,
+		{name: "--field", kind: completeValue, help: "Print only this field"},
+		{name: "--json", help: "Print the field as JSON"},
+		kvVersionFlag,
+		{name: "--version", kind: completeValue, help: "KV v2 version to read"},
+		mountFlag, namespaceFlag, credentialsFlag,
+	}},
+	{name: "list", help: "List the secrets below a Vault path", flags: []cliFlag{
+		configFlag,
+		{name: "--depth", kind: completeValue, help: "Folder levels to descend into"},
+		{name: "--tree", help: "Print an indented tree"},
+		{name: "--stanzas", help: "Print one secret per key"},
+		{name: "--wildcard", help: "Print one wildcard secret for the path"},
+		{name: "--dir", kind: completeDir, help: "Directory the printed secrets write to"},
+		kvVersionFlag, mountFlag, namespaceFlag, credentialsFlag,
+	}},
+	{name: "run", help: "Run a command with secrets kept up to date", flags: []cliFlag{
+		{name: "--env", kind: completeSecret, help: "Pass the fields of this secret as variables"},
+		{name: "--signal", values: []string{"HUP", "INT", "QUIT", "TERM", "USR1", "USR2", "WINCH"}, help: "Signal sent when only files changed"},
+		{name: "--kill-timeout", kind: completeValue, help: "Wait after SIGTERM before SIGKILL"},
+	}},
+	{name: "bench", help: "Load test against a built-in mock Vault", flags: []cliFlag{
+		{name: "--secrets", kind: completeValue, help: "Number of secrets"},
+		{name: "--duration", kind: completeValue, help: "How long to run"},
+		{name: "--interval", kind: completeValue, help: "Refresh interval of each secret"},
+		{name: "--latency", kind: completeValue, help: "Delay of every mock Vault read"},
+		{name: "--output-dir", kind: completeDir, help: "Directory for the files"},
+	}},
+	{name: "selftest", help: "Check that auth, TLS, secrets and file permissions work", flags: []cliFlag{
+		{name: "--mock", help: "Run against a built-in mock Vault"},
+		{name: "--dir", kind: completeDir, help: "Directory for the --mock config and files"},
+	}},
+	{name: "doctor", help: "Diagnose Vault, TLS, token TTL, policies, directories and clock", flags: []cliFlag{
+		{name: "--no-color", help: "Do not color the report"},
+	}},
+	{name: "audit", help: "Verify the hash chain of an audit log", args: []string{"verify"}, kind: completeFile},
+	{name: "rollback", help: "Restore the latest backup of the files of a secret", kind: completeSecret, flags: []cliFlag{configFlag}},
+	{name: "version", help: "Show version information"},
+	{name: "isready", help: "Check if service is ready", flags: []cliFlag{
+		{name: "--max-age", kind: completeValue, help: "Fail if the last sync is older"},
+	}},
+	{name: "completion", help: "Print a shell completion script", args: []string{"bash", "zsh", "fish"}},
+	{name: "help", help: "Show the help message"},
+}
+
+func printCompletionUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: secrets-sync completion bash|zsh|fish\n")
+	fmt.Fprintf(os.Stderr, "\nPrints a completion script for the shell. Subcommands and flags are completed,\n")
+	fmt.Fprintf(os.Stderr, "and secret names for --secret and --env are read from the configuration.\n")
+	fmt.Fprintf(os.Stderr, "\nExamples:\n")
+	fmt.Fprintf(os.Stderr, "  source <(secrets-sync completion bash)\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync completion bash > /etc/bash_completion.d/secrets-sync\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync completion zsh > \"${fpath[1]}/_secrets-sync\"\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync completion fish > ~/.config/fish/completions/secrets-sync.fish\n")
+}
+
+// runCompletion prints the completion script for a shell. The secrets
+// argument, used by the scripts, prints the names of the configured secrets.
+func runCompletion(args []string) int {
+	if len(args) != 1 {
+		printCompletionUsage()
+		return 1
+	}
+
+	switch args[0] {
+	case "-h", "--help":
+		printCompletionUsage()
+		return 0
+	case "bash":
+		fmt.Print(bashCompletion())
+	case "zsh":
+		fmt.Print(zshCompletion())
+	case "fish":
+		fmt.Print(fishCompletion())
+	case "secrets":
+		// Completion must not print errors into the command line
+		cfg, err := loadConfig(context.Background())
+		if err != nil {
+			return 1
+		}
+		for _, secret := range cfg.Secrets {
+			fmt.Println(secret.Name)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unsupported shell: %s (supported: bash, zsh, fish)\n", args[0])
+		return 1
+	}
+	return 0
+}
+
+// commandNames returns the names of the subcommands
+func commandNames() []string {
+	names := make([]string, 0, len(cliCommands))
+	for _, c := range cliCommands {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// flagWords returns the long and short forms of flags
+func flagWords(flags []cliFlag) []string {
+	var words []string
+	for _, f := range flags {
+		words = append(words, f.name)
+		if f.short != "" {
+			words = append(words, f.short)
+		}
+	}
+	sort.Strings(words)
+	return words
+}
+
+// bashCompletion returns the bash completion script
+func bashCompletion() string {
+	var b strings.Builder
+	b.WriteString(`# bash completion for secrets-sync, generated by: secrets-sync completion bash
+
+_secrets_sync_secrets() {
+    secrets-sync ${1:+--config "$1"} completion secrets 2>/dev/null
+}
+
+_secrets_sync() {
+    local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+    local cmd="" config="" i
+    COMPREPLY=()
+
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        case "${COMP_WORDS[i]}" in
+            -c|--config) config="${COMP_WORDS[i+1]}"; ((i++)) ;;
+            -*) ;;
+            *) [[ -z "$cmd" ]] && cmd="${COMP_WORDS[i]}" ;;
+        esac
+    done
+
+    case "$cmd" in
+        "")
+            case "$prev" in
+                -c|--config) COMPREPLY=($(compgen -f -- "$cur")); return ;;
+            esac
+`)
+	fmt.Fprintf(&b, "            COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(append(commandNames(), "-c", "--config", "-h", "--help"), " "))
+	b.WriteString("            ;;\n")
+
+	for _, c := range cliCommands {
+		fmt.Fprintf(&b, "        %s)\n", c.name)
+		var valueCases []string
+		for _, f := range c.flags {
+			if !f.takesValue() {
+				continue
+			}
+			pattern := f.name
+			if f.short != "" {
+				pattern = f.short + "|" + f.name
+			}
+			// Free text values get no suggestions
+			action := "COMPREPLY=()"
+			switch {
+			case len(f.values) > 0:
+				action = fmt.Sprintf("COMPREPLY=($(compgen -W %q -- \"$cur\"))", strings.Join(f.values, " "))
+			case f.kind == completeFile:
+				action = `COMPREPLY=($(compgen -f -- "$cur"))`
+			case f.kind == completeDir:
+				action = `COMPREPLY=($(compgen -d -- "$cur"))`
+			case f.kind == completeSecret:
+				action = `COMPREPLY=($(compgen -W "$(_secrets_sync_secrets "$config")" -- "$cur"))`
+			}
+			valueCases = append(valueCases, fmt.Sprintf("                %s) %s; return ;;\n", pattern, action))
+		}
+		if len(valueCases) > 0 {
+			b.WriteString("            case \"$prev\" in\n")
+			for _, vc := range valueCases {
+				b.WriteString(vc)
+			}
+			b.WriteString("            esac\n")
+		}
+
+		words := append(flagWords(c.flags), "-h", "--help")
+		if len(c.args) > 0 {
+			words = append(append([]string{}, c.args...), words...)
+		}
+		switch c.kind {
+		case completeFile:
+			args := `$(compgen -f -- "$cur")`
+			if len(c.args) > 0 {
+				args = fmt.Sprintf("$(compgen -W %q -- \"$cur\") %s", strings.Join(c.args, " "), args)
+			}
+			fmt.Fprintf(&b, "            if [[ \"$cur\" == -* ]]; then COMPREPLY=($(compgen -W %q -- \"$cur\")); else COMPREPLY=(%s); fi\n",
+				strings.Join(words, " "), args)
+		case completeSecret:
+			fmt.Fprintf(&b, "            if [[ \"$cur\" == -* ]]; then COMPREPLY=($(compgen -W %q -- \"$cur\")); else COMPREPLY=($(compgen -W \"$(_secrets_sync_secrets \"$config\")\" -- \"$cur\")); fi\n",
+				strings.Join(words, " "))
+		default:
+			fmt.Fprintf(&b, "            COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(words, " "))
+		}
+		b.WriteString("            ;;\n")
+	}
+
+	b.WriteString(`    esac
+}
+
+complete -o filenames -F _secrets_sync secrets-sync
+`)
+	return b.String()
+}
+
+// zshEscape escapes text for the description of a zsh _arguments spec
+func zshEscape(s string) string {
+	return strings.NewReplacer("'", `'\''`, "[", `\[`, "]", `\]`, ":", `\:`).Replace(s)
+}
+
+// zshFlagSpecs returns the _arguments specs of flags
+func zshFlagSpecs(flags []cliFlag) []string {
+	var specs []string
+	for _, f := range flags {
+		var action string
+		switch {
+		case len(f.values) > 0:
+			action = fmt.Sprintf(":%s:(%s)", strings.TrimPrefix(f.name, "--"), strings.Join(f.values, " "))
+		case f.kind == completeFile:
+			action = ":file:_files"
+		case f.kind == completeDir:
+			action = ":directory:_files -/"
+		case f.kind == completeSecret:
+			action = ":secret:_secrets_sync_secrets"
+		case f.kind == completeValue:
+			action = ":" + strings.TrimPrefix(f.name, "--") + ":"
+		}
+		repeat := ""
+		if f.kind == completeSecret {
+			repeat = "*"
+		}
+		if f.short != "" {
+			specs = append(specs, fmt.Sprintf("'(%s %s)'{%s,%s}'[%s]%s'", f.short, f.name, f.short, f.name, zshEscape(f.help), action))
+		} else {
+			specs = append(specs, fmt.Sprintf("'%s%s[%s]%s'", repeat, f.name, zshEscape(f.help), action))
+		}
+	}
+	return specs
+}
+
+// zshCompletion returns the zsh completion script
+func zshCompletion() string {
+	var b strings.Builder
+	b.WriteString(`#compdef secrets-sync
+# zsh completion for secrets-sync, generated by: secrets-sync completion zsh
+
+_secrets_sync_secrets() {
+    local config=${opt_args[--config]:-${opt_args[-c]}}
+    local -a names
+    names=(${(f)"$(secrets-sync ${config:+--config "$config"} completion secrets 2>/dev/null)"})
+    compadd -a names
+}
+
+_secrets_sync() {
+    local curcontext="$curcontext" state line
+    typeset -A opt_args
+
+    _arguments -C \
+        '(-c --config)'{-c,--config}'[Configuration file]:file:_files' \
+        '(- *)'{-h,--help}'[Show the help message]' \
+        '1: :->command' \
+        '*:: :->args'
+
+    case $state in
+        command)
+            local -a commands
+            commands=(
+`)
+	for _, c := range cliCommands {
+		fmt.Fprintf(&b, "                '%s:%s'\n", c.name, zshEscape(c.help))
+	}
+	b.WriteString(`            )
+            _describe 'command' commands
+            ;;
+        args)
+            case $line[1] in
+`)
+	for _, c := range cliCommands {
+		specs := zshFlagSpecs(c.flags)
+		if len(c.args) > 0 {
+			specs = append(specs, fmt.Sprintf("'1:argument:(%s)'", strings.Join(c.args, " ")))
+		}
+		switch c.kind {
+		case completeFile:
+			specs = append(specs, "'*:file:_files'")
+		case completeSecret:
+			specs = append(specs, "'1:secret:_secrets_sync_secrets'")
+		}
+		if len(specs) == 0 {
+			fmt.Fprintf(&b, "                %s) ;;\n", c.name)
+			continue
+		}
+		fmt.Fprintf(&b, "                %s)\n                    _arguments \\\n", c.name)
+		for i, spec := range specs {
+			if i < len(specs)-1 {
+				fmt.Fprintf(&b, "                        %s \\\n", spec)
+			} else {
+				fmt.Fprintf(&b, "                        %s\n", spec)
+			}
+		}
+		b.WriteString("                    ;;\n")
+	}
+	b.WriteString(`            esac
+            ;;
+    esac
+}
+
+if [[ "$funcstack[1]" == "_secrets_sync" ]]; then
+    _secrets_sync "$@"
+else
+    compdef _secrets_sync secrets-sync
+fi
+`)
+	return b.String()
+}
+
+// fishEscape quotes text for fish
+func fishEscape(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// fishCompletion returns the fish completion script
+func fishCompletion() string {
+	var b strings.Builder
+	b.WriteString(`# fish completion for secrets-sync, generated by: secrets-sync completion fish
+
+function __secrets_sync_secrets
+    set -l tokens (commandline -opc)
+    set -l config
+    for i in (seq (count $tokens))
+        if contains -- $tokens[$i] -c --config
+            set config --config $tokens[(math $i + 1)]
+        end
+    end
+    secrets-sync $config completion secrets 2>/dev/null
+end
+
+complete -c secrets-sync -f
+complete -c secrets-sync -n __fish_use_subcommand -s c -l config -r -F -d 'Configuration file'
+complete -c secrets-sync -n __fish_use_subcommand -s h -l help -d 'Show the help message'
+`)
+	for _, c := range cliCommands {
+		fmt.Fprintf(&b, "complete -c secrets-sync -n __fish_use_subcommand -a %s -d %s\n", c.name, fishEscape(c.help))
+	}
+	for _, c := range cliCommands {
+		condition := fishEscape("__fish_seen_subcommand_from " + c.name)
+		for _, f := range c.flags {
+			line := fmt.Sprintf("complete -c secrets-sync -n %s -l %s", condition, strings.TrimPrefix(f.name, "--"))
+			if f.short != "" {
+				line += " -s " + strings.TrimPrefix(f.short, "-")
+			}
+			switch {
+			case len(f.values) > 0:
+				line += " -x -a " + fishEscape(strings.Join(f.values, " "))
+			case f.kind == completeFile:
+				line += " -r -F"
+			case f.kind == completeDir:
+				line += " -x -a '(__fish_complete_directories)'"
+			case f.kind == completeSecret:
+				line += " -x -a '(__secrets_sync_secrets)'"
+			case f.kind == completeValue:
+				line += " -x"
+			}
+			fmt.Fprintf(&b, "%s -d %s\n", line, fishEscape(f.help))
+		}
+		if len(c.args) > 0 {
+			fmt.Fprintf(&b, "complete -c secrets-sync -n %s -a %s\n", condition, fishEscape(strings.Join(c.args, " ")))
+		}
+		switch c.kind {
+		case completeFile:
+			fmt.Fprintf(&b, "complete -c secrets-sync -n %s -F\n", condition)
+		case completeSecret:
+			fmt.Fprintf(&b, "complete -c secrets-sync -n %s -x -a '(__secrets_sync_secrets)'\n", condition)
+		}
+	}
+	return b.String()
+}
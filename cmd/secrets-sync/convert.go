@@ -1,13 +1,25 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode"
 
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/filewriter"
+	"github.com/ohauer/secrets-sync/internal/template"
+	"github.com/ohauer/secrets-sync/internal/vault"
 	"gopkg.in/yaml.v3"
 )
 
@@ -16,7 +28,8 @@ type ExternalSecret struct {
 	APIVersion string `yaml:"apiVersion"`
 	Kind       string `yaml:"kind"`
 	Metadata   struct {
-		Name string `yaml:"name"`
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
 	} `yaml:"metadata"`
 	Spec struct {
 		RefreshInterval string `yaml:"refreshInterval"`
@@ -27,357 +40,1218 @@ type ExternalSecret struct {
 		Target struct {
 			Name     string `yaml:"name"`
 			Template struct {
-				Data map[string]string `yaml:"data"`
+				EngineVersion string            `yaml:"engineVersion"`
+				MergePolicy   string            `yaml:"mergePolicy"`
+				Data          map[string]string `yaml:"data"`
+				TemplateFrom  []struct {
+					ConfigMap *struct {
+						Name string `yaml:"name"`
+					} `yaml:"configMap"`
+					Secret *struct {
+						Name string `yaml:"name"`
+					} `yaml:"secret"`
+					Literal *string `yaml:"literal"`
+				} `yaml:"templateFrom"`
 			} `yaml:"template"`
 		} `yaml:"target"`
-		Data []struct {
-			SecretKey string `yaml:"secretKey"`
-			RemoteRef struct {
-				Key      string `yaml:"key"`
-				Property string `yaml:"property"`
-			} `yaml:"remoteRef"`
-		} `yaml:"data"`
-		DataFrom []struct {
-			Extract struct {
-				Key string `yaml:"key"`
-			} `yaml:"extract"`
-		} `yaml:"dataFrom"`
+		Data     []dataEntry     `yaml:"data"`
+		DataFrom []dataFromEntry `yaml:"dataFrom"`
+	} `yaml:"spec"`
+}
+
+// remoteRef is the Vault key an ExternalSecret reads, and how
+type remoteRef struct {
+	Key                string `yaml:"key"`
+	Property           string `yaml:"property"`
+	Version            string `yaml:"version"`
+	DecodingStrategy   string `yaml:"decodingStrategy"`
+	ConversionStrategy string `yaml:"conversionStrategy"`
+	MetadataPolicy     string `yaml:"metadataPolicy"`
+}
+
+// dataEntry maps a single Vault field to a secret key
+type dataEntry struct {
+	SecretKey string    `yaml:"secretKey"`
+	RemoteRef remoteRef `yaml:"remoteRef"`
+}
+
+// dataFromEntry pulls all fields of a Vault key
+type dataFromEntry struct {
+	Extract   *remoteRef        `yaml:"extract"`
+	Find      *yaml.Node        `yaml:"find"`
+	SourceRef *yaml.Node        `yaml:"sourceRef"`
+	Rewrite   []dataFromRewrite `yaml:"rewrite"`
+}
+
+// dataFromRewrite renames the keys pulled by a dataFrom entry
+type dataFromRewrite struct {
+	Regexp *struct {
+		Source string `yaml:"source"`
+		Target string `yaml:"target"`
+	} `yaml:"regexp"`
+	Transform *struct {
+		Template string `yaml:"template"`
+	} `yaml:"transform"`
+}
+
+// VaultSecretStore represents an external-secrets.io SecretStore or
+// ClusterSecretStore using the Vault provider
+type VaultSecretStore struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Provider struct {
+			Vault *struct {
+				Server     string `yaml:"server"`
+				Path       string `yaml:"path"`
+				Version    string `yaml:"version"`
+				Namespace  string `yaml:"namespace"`
+				CABundle   string `yaml:"caBundle"`
+				CAProvider *struct {
+					Type string `yaml:"type"`
+					Name string `yaml:"name"`
+				} `yaml:"caProvider"`
+				Auth struct {
+					TokenSecretRef *struct {
+						Name string `yaml:"name"`
+					} `yaml:"tokenSecretRef"`
+					AppRole *struct {
+						Path   string `yaml:"path"`
+						RoleID string `yaml:"roleId"`
+					} `yaml:"appRole"`
+					Kubernetes *struct {
+						MountPath         string `yaml:"mountPath"`
+						Role              string `yaml:"role"`
+						ServiceAccountRef *struct {
+							Name string `yaml:"name"`
+						} `yaml:"serviceAccountRef"`
+						SecretRef *struct {
+							Name string `yaml:"name"`
+						} `yaml:"secretRef"`
+					} `yaml:"kubernetes"`
+					Cert *struct {
+						ClientCert *struct {
+							Name string `yaml:"name"`
+						} `yaml:"clientCert"`
+					} `yaml:"cert"`
+				} `yaml:"auth"`
+			} `yaml:"vault"`
+		} `yaml:"provider"`
+	} `yaml:"spec"`
+}
+
+// ClusterExternalSecret represents an external-secrets.io ClusterExternalSecret,
+// which creates the same ExternalSecret in every selected namespace
+type ClusterExternalSecret struct {
+	Metadata struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec struct {
+		ExternalSecretName string          `yaml:"externalSecretName"`
+		ExternalSecretSpec yaml.Node       `yaml:"externalSecretSpec"`
+		NamespaceSelector  *labelSelector  `yaml:"namespaceSelector"`
+		NamespaceSelectors []labelSelector `yaml:"namespaceSelectors"`
+		Namespaces         []string        `yaml:"namespaces"`
 	} `yaml:"spec"`
 }
 
+// labelSelector is a Kubernetes label selector
+type labelSelector struct {
+	MatchLabels      map[string]string `yaml:"matchLabels"`
+	MatchExpressions []struct {
+		Key      string   `yaml:"key"`
+		Operator string   `yaml:"operator"`
+		Values   []string `yaml:"values"`
+	} `yaml:"matchExpressions"`
+}
+
 // ConvertConfig holds conversion parameters
 type ConvertConfig struct {
-	MountPath       string
-	KVVersion       string
-	OutputDir       string
-	AutoDetectMount bool
-	QueryVault      bool
-	VaultAddr       string
-	VaultToken      string
-	VaultRoleID     string
-	VaultSecretID   string
+	MountPath     string
+	KVVersion     string
+	OutputDir     string
+	Out           string // Config file or directory to write, instead of stdout
+	Split         bool   // One config file per ExternalSecret in Out
+	To            string // external-secrets converts a secrets-sync config back
+	Namespace     string // Namespace of the ExternalSecrets written with To
+	StoreFiles    []string
+	FromCluster   bool
+	QueryVault    bool
+	VaultAddr     string
+	VaultToken    string
+	VaultRoleID   string
+	VaultSecretID string
+	VaultClient   *vault.Client // Authenticated client, with QueryVault
 }
 
-// getVaultToken obtains a token from AppRole if roleId and secretId are provided
-func getVaultToken(vaultAddr, roleId, secretId string) (string, error) {
-	if vaultAddr == "" || roleId == "" || secretId == "" {
-		return "", fmt.Errorf("vault address, role_id, and secret_id required")
-	}
+// secretTarget is where a converted secret is read from in Vault
+type secretTarget struct {
+	MountPath   string
+	KVVersion   string
+	Namespace   string
+	Credentials string
+}
 
-	cmd := exec.Command("sh", "-c",
-		fmt.Sprintf("vault write -format=json auth/approle/login role_id=%s secret_id=%s 2>/dev/null | jq -r '.auth.client_token' 2>/dev/null",
-			roleId, secretId))
-	cmd.Env = append(os.Environ(), "VAULT_ADDR="+vaultAddr)
+// sourcedSecret is an ExternalSecret and the input it was read from
+type sourcedSecret struct {
+	secret ExternalSecret
+	source string
+}
 
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("failed to authenticate with approle: %w", err)
-	}
+// sourcedClusterSecret is a ClusterExternalSecret and the input it was read from
+type sourcedClusterSecret struct {
+	secret ClusterExternalSecret
+	source string
+}
 
-	token := strings.TrimSpace(string(output))
-	if token == "" || token == "null" {
-		return "", fmt.Errorf("authentication failed: no token returned")
+// convertInput collects the external-secrets resources of all inputs
+type convertInput struct {
+	secrets        []sourcedSecret
+	clusterSecrets []sourcedClusterSecret
+	stores         map[string]*VaultSecretStore // By storeKey
+	namespaces     map[string]map[string]string // Labels by namespace name
+	skipped        []string                     // Resources that cannot be converted
+}
+
+// storeKey identifies a store the way an ExternalSecret references it
+func storeKey(kind, namespace, name string) string {
+	if kind == "ClusterSecretStore" {
+		return kind + "/" + name
 	}
+	return "SecretStore/" + namespace + "/" + name
+}
 
-	return token, nil
+// server returns the Vault server of the first store by storeKey, or ""
+func (in *convertInput) server() string {
+	keys := make([]string, 0, len(in.stores))
+	for key := range in.stores {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if server := in.stores[key].Spec.Provider.Vault.Server; server != "" {
+			return server
+		}
+	}
+	return ""
 }
 
-// queryVaultFields queries Vault to get actual field names for a secret
-func queryVaultFields(mountPath, key, vaultAddr, vaultToken string) ([]string, error) {
-	if vaultAddr == "" || vaultToken == "" {
-		return nil, fmt.Errorf("vault address and token required")
+// newConvertClient creates a Vault client for --query-vault, authenticated
+// with the token or, without one, the AppRole credentials
+func newConvertClient(ctx context.Context, address string, cfg ConvertConfig) (*vault.Client, error) {
+	envCfg := config.LoadEnvConfig()
+	client, err := vault.NewClientWithConnection(address, newVaultTLSConfig(&config.Config{}, envCfg), newVaultConnection(&config.Config{}, envCfg))
+	if err != nil {
+		return nil, err
 	}
 
-	// Use direct API call to avoid mount metadata query (which requires additional permissions)
-	// Assume KV v2 (most common) - path is: mountPath/data/key
-	apiPath := fmt.Sprintf("%s/data/%s", mountPath, key)
-	cmd := exec.Command("sh", "-c",
-		fmt.Sprintf("curl -s -H 'X-Vault-Token: %s' %s/v1/%s 2>/dev/null | jq -r '.data.data | keys[]' 2>/dev/null",
-			vaultToken, vaultAddr, apiPath))
+	auth := vault.AuthConfig{Method: vault.AuthMethodToken, Token: cfg.VaultToken}
+	if cfg.VaultToken == "" {
+		auth = vault.AuthConfig{
+			Method:   vault.AuthMethodAppRole,
+			RoleID:   cfg.VaultRoleID,
+			SecretID: cfg.VaultSecretID,
+		}
+	}
+	if err := client.Authenticate(ctx, auth); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
 
-	output, err := cmd.Output()
+// queryVaultFields reads a secret to get its actual field names, sorted
+func queryVaultFields(ctx context.Context, client *vault.Client, target secretTarget, key string) ([]string, error) {
+	data, err := client.FetchSecret(ctx, target.MountPath, key, target.KVVersion, target.Namespace)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query vault: %w", err)
+		return nil, err
+	}
+
+	fields := make([]string, 0, len(data))
+	for field := range data {
+		fields = append(fields, field)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no fields found")
 	}
+	sort.Strings(fields)
+	return fields, nil
+}
 
-	fields := strings.Split(strings.TrimSpace(string(output)), "\n")
-	var validFields []string
-	for _, f := range fields {
-		f = strings.TrimSpace(f)
-		if f != "" {
-			validFields = append(validFields, f)
+// readInput reads a file, or stdin for "-"
+func readInput(inputFile string) ([]byte, error) {
+	if inputFile == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stdin: %w", err)
 		}
+		return data, nil
 	}
 
-	if len(validFields) == 0 {
-		return nil, fmt.Errorf("no fields found")
+	data, err := os.ReadFile(inputFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
+	return data, nil
+}
 
-	return validFields, nil
+// loadFile adds the ExternalSecrets and secret stores of an input file
+func (in *convertInput) loadFile(inputFile string) error {
+	data, err := readInput(inputFile)
+	if err != nil {
+		return err
+	}
+
+	sourceFile := inputFile
+	if inputFile == "-" {
+		sourceFile = "stdin"
+	}
+	return in.load(data, sourceFile)
 }
 
-// detectMountPath tries to infer mount path from secret key
-func detectMountPath(key string) string {
-	// All keys are relative to the mount path specified in the SecretStore
-	// For vault-devops SecretStore, the mount is "devops"
-	// Keys like "artifactory/internal/..." are paths within the devops mount
-	// So we should NOT auto-detect based on the key prefix
-	return "devops"
+// load adds the ExternalSecrets and secret stores of single documents,
+// multi-document YAML, JSON and Kubernetes Lists
+func (in *convertInput) load(data []byte, sourceFile string) error {
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	count := 0
+	for i := 0; ; i++ {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("failed to parse document %d: %w", i, err)
+		}
+
+		var list struct {
+			Kind  string      `yaml:"kind"`
+			Items []yaml.Node `yaml:"items"`
+		}
+		if err := doc.Decode(&list); err != nil {
+			// Skip documents that are not Kubernetes objects
+			continue
+		}
+
+		if !strings.HasSuffix(list.Kind, "List") {
+			count += in.add(&doc, fmt.Sprintf("document %d", i), sourceFile)
+			continue
+		}
+		for j := range list.Items {
+			count += in.add(&list.Items[j], fmt.Sprintf("item %d", j), sourceFile)
+		}
+	}
+
+	if count == 0 {
+		return fmt.Errorf("no external-secrets documents found in file")
+	}
+	return nil
 }
 
-func convertExternalSecret(inputFile string, cfg ConvertConfig) error {
-	var data []byte
-	var err error
+// add decodes a single object, returning 1 if it was an external-secrets
+// resource or a Namespace
+func (in *convertInput) add(node *yaml.Node, position, sourceFile string) int {
+	var meta struct {
+		Kind string `yaml:"kind"`
+	}
+	if err := node.Decode(&meta); err != nil {
+		return 0
+	}
 
-	// Support stdin with "-"
-	if inputFile == "-" {
-		data, err = io.ReadAll(os.Stdin)
-		if err != nil {
-			return fmt.Errorf("failed to read stdin: %w", err)
+	switch meta.Kind {
+	case "ExternalSecret":
+		var es ExternalSecret
+		if err := node.Decode(&es); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to parse %s in %s: %v\n", position, sourceFile, err)
+			return 0
 		}
-	} else {
-		data, err = os.ReadFile(inputFile)
-		if err != nil {
-			return fmt.Errorf("failed to read file: %w", err)
+		in.secrets = append(in.secrets, sourcedSecret{secret: es, source: sourceFile})
+	case "SecretStore", "ClusterSecretStore":
+		var store VaultSecretStore
+		if err := node.Decode(&store); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to parse %s in %s: %v\n", position, sourceFile, err)
+			return 0
+		}
+		if store.Spec.Provider.Vault == nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s %q in %s does not use the Vault provider, ignoring it\n",
+				store.Kind, store.Metadata.Name, sourceFile)
+			return 0
+		}
+		in.stores[storeKey(store.Kind, store.Metadata.Namespace, store.Metadata.Name)] = &store
+	case "ClusterExternalSecret":
+		var ces ClusterExternalSecret
+		if err := node.Decode(&ces); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to parse %s in %s: %v\n", position, sourceFile, err)
+			return 0
+		}
+		in.clusterSecrets = append(in.clusterSecrets, sourcedClusterSecret{secret: ces, source: sourceFile})
+	case "PushSecret":
+		var ps struct {
+			Metadata struct {
+				Name      string `yaml:"name"`
+				Namespace string `yaml:"namespace"`
+			} `yaml:"metadata"`
+		}
+		if err := node.Decode(&ps); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to parse %s in %s: %v\n", position, sourceFile, err)
+			return 0
+		}
+		name := ps.Metadata.Name
+		if ps.Metadata.Namespace != "" {
+			name = ps.Metadata.Namespace + "/" + name
+		}
+		skipped := fmt.Sprintf("PushSecret %q in %s writes Kubernetes secrets to Vault; secrets-sync only reads from Vault", name, sourceFile)
+		fmt.Fprintf(os.Stderr, "Warning: %s, skipping it\n", skipped)
+		in.skipped = append(in.skipped, skipped)
+	case "Namespace":
+		var ns struct {
+			Metadata struct {
+				Name   string            `yaml:"name"`
+				Labels map[string]string `yaml:"labels"`
+			} `yaml:"metadata"`
+		}
+		if err := node.Decode(&ns); err != nil || ns.Metadata.Name == "" {
+			return 0
+		}
+		labels := ns.Metadata.Labels
+		if labels == nil {
+			labels = make(map[string]string)
 		}
+		// Kubernetes labels every namespace with its name
+		labels["kubernetes.io/metadata.name"] = ns.Metadata.Name
+		in.namespaces[ns.Metadata.Name] = labels
+	default:
+		return 0
 	}
+	return 1
+}
 
-	// Try to parse as a List first
-	var list struct {
-		APIVersion string      `yaml:"apiVersion"`
-		Kind       string      `yaml:"kind"`
-		Items      []yaml.Node `yaml:"items"`
+// loadCluster adds the secret stores and namespaces of the current kubectl
+// context
+func (in *convertInput) loadCluster() error {
+	cmd := exec.Command("kubectl", "get", "secretstores,clustersecretstores,namespaces", "--all-namespaces", "-o", "yaml")
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("failed to list secret stores with kubectl: %w", err)
 	}
+	return in.load(output, "cluster")
+}
 
-	if err := yaml.Unmarshal(data, &list); err == nil && list.Kind == "List" {
-		// Handle Kubernetes List with multiple ExternalSecrets
-		sourceFile := inputFile
-		if inputFile == "-" {
-			sourceFile = "stdin"
+// matches reports whether a label set is selected. An empty selector
+// selects everything, as in Kubernetes.
+func (sel labelSelector) matches(labels map[string]string) bool {
+	for key, value := range sel.MatchLabels {
+		if v, ok := labels[key]; !ok || v != value {
+			return false
 		}
-		for i, item := range list.Items {
-			var es ExternalSecret
-			if err := item.Decode(&es); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to parse item %d in %s: %v\n", i, sourceFile, err)
-				continue
+	}
+	for _, expr := range sel.MatchExpressions {
+		value, ok := labels[expr.Key]
+		listed := false
+		for _, v := range expr.Values {
+			if ok && v == value {
+				listed = true
 			}
-			if es.Kind == "ExternalSecret" {
-				if err := convertSingleSecret(es, sourceFile, cfg); err != nil {
-					fmt.Fprintf(os.Stderr, "Warning: failed to convert item %d in %s: %v\n", i, sourceFile, err)
-				}
+		}
+		switch expr.Operator {
+		case "In":
+			if !listed {
+				return false
+			}
+		case "NotIn":
+			if listed {
+				return false
+			}
+		case "Exists":
+			if !ok {
+				return false
 			}
+		case "DoesNotExist":
+			if ok {
+				return false
+			}
+		default:
+			return false
 		}
-		return nil
 	}
+	return true
+}
 
-	// Try to parse as multi-document YAML
-	sourceFile := inputFile
-	if inputFile == "-" {
-		sourceFile = "stdin"
+// selectNamespaces returns the sorted namespaces a ClusterExternalSecret
+// creates ExternalSecrets in. Selectors can only match namespaces read from
+// the inputs or the cluster.
+func (in *convertInput) selectNamespaces(ces ClusterExternalSecret) []string {
+	selectors := ces.Spec.NamespaceSelectors
+	if ces.Spec.NamespaceSelector != nil {
+		selectors = append(selectors, *ces.Spec.NamespaceSelector)
 	}
-	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
-	count := 0
-	for {
-		var es ExternalSecret
-		if err := decoder.Decode(&es); err != nil {
-			if err.Error() == "EOF" {
+
+	selected := make(map[string]bool)
+	for _, ns := range ces.Spec.Namespaces {
+		selected[ns] = true
+	}
+	for name, labels := range in.namespaces {
+		for _, sel := range selectors {
+			if sel.matches(labels) {
+				selected[name] = true
 				break
 			}
-			// Skip non-ExternalSecret documents
+		}
+	}
+
+	namespaces := make([]string, 0, len(selected))
+	for ns := range selected {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// expandClusterSecrets adds the ExternalSecrets each ClusterExternalSecret
+// creates. Namespaces that read from the same Vault target share one secret;
+// otherwise every namespace gets its own, prefixed with the namespace name.
+func (in *convertInput) expandClusterSecrets(cfg ConvertConfig) {
+	for _, c := range in.clusterSecrets {
+		ces := c.secret
+		source := fmt.Sprintf("%s, ClusterExternalSecret %q", c.source, ces.Metadata.Name)
+		if ces.Spec.ExternalSecretSpec.Kind == 0 {
+			fmt.Fprintf(os.Stderr, "Warning: %s has no externalSecretSpec, skipping it\n", source)
+			continue
+		}
+
+		var base ExternalSecret
+		base.Kind = "ExternalSecret"
+		base.Metadata.Name = ces.Spec.ExternalSecretName
+		if base.Metadata.Name == "" {
+			base.Metadata.Name = ces.Metadata.Name
+		}
+		if err := ces.Spec.ExternalSecretSpec.Decode(&base.Spec); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to parse externalSecretSpec of %s: %v\n", source, err)
 			continue
 		}
 
-		if es.Kind == "ExternalSecret" {
-			if err := convertSingleSecret(es, sourceFile, cfg); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to convert document %d in %s: %v\n", count, sourceFile, err)
+		namespaces := in.selectNamespaces(ces)
+		if len(namespaces) == 0 {
+			fmt.Fprintf(os.Stderr, "Warning: %s selects no known namespace, converting it once; "+
+				"pass Namespace objects or use --from-cluster to expand its selectors\n", source)
+			in.secrets = append(in.secrets, sourcedSecret{secret: base, source: source})
+			continue
+		}
+
+		expanded := make([]ExternalSecret, len(namespaces))
+		targets := make(map[secretTarget]bool)
+		for i, ns := range namespaces {
+			expanded[i] = base
+			expanded[i].Metadata.Namespace = ns
+			target, _, _ := in.target(expanded[i], cfg)
+			targets[target] = true
+		}
+
+		if len(targets) == 1 {
+			in.secrets = append(in.secrets, sourcedSecret{
+				secret: expanded[0],
+				source: source + " for namespaces " + strings.Join(namespaces, ", "),
+			})
+			continue
+		}
+		for _, es := range expanded {
+			name := es.Spec.Target.Name
+			if name == "" {
+				name = es.Metadata.Name
 			}
-			count++
+			es.Spec.Target.Name = es.Metadata.Namespace + "-" + name
+			in.secrets = append(in.secrets, sourcedSecret{
+				secret: es,
+				source: source + " in namespace " + es.Metadata.Namespace,
+			})
 		}
 	}
+}
 
-	if count > 0 {
-		return nil
+// resolveStore finds the store an ExternalSecret references. Without a
+// namespace on the ExternalSecret, a SecretStore with a unique name matches.
+func (in *convertInput) resolveStore(es ExternalSecret) (*VaultSecretStore, error) {
+	ref := es.Spec.SecretStoreRef
+	if ref.Name == "" {
+		return nil, fmt.Errorf("no secretStoreRef")
+	}
+	kind := ref.Kind
+	if kind == "" {
+		kind = "SecretStore"
+	}
+
+	if store, ok := in.stores[storeKey(kind, es.Metadata.Namespace, ref.Name)]; ok {
+		return store, nil
+	}
+
+	if kind == "SecretStore" && es.Metadata.Namespace == "" {
+		var match *VaultSecretStore
+		for _, store := range in.stores {
+			if store.Kind != "SecretStore" || store.Metadata.Name != ref.Name {
+				continue
+			}
+			if match != nil {
+				return nil, fmt.Errorf("SecretStore %q exists in several namespaces, set metadata.namespace", ref.Name)
+			}
+			match = store
+		}
+		if match != nil {
+			return match, nil
+		}
 	}
 
-	return fmt.Errorf("no ExternalSecret documents found in file")
+	return nil, fmt.Errorf("%s %q not found", kind, ref.Name)
 }
 
-func convertSingleSecret(es ExternalSecret, sourceFile string, cfg ConvertConfig) error {
+// credentialsName names the credential set generated for a store
+func credentialsName(store *VaultSecretStore) string {
+	if store.Kind == "SecretStore" && store.Metadata.Namespace != "" {
+		return store.Metadata.Namespace + "-" + store.Metadata.Name
+	}
+	return store.Metadata.Name
+}
 
-	// Build secret configuration
-	secretName := es.Spec.Target.Name
-	if secretName == "" {
-		secretName = es.Metadata.Name
+// envSuffix turns a credential set name into an environment variable suffix
+func envSuffix(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// storeAuthMethod maps the store's auth to a secrets-sync auth method, or ""
+// if it uses one secrets-sync does not support
+func storeAuthMethod(store *VaultSecretStore) string {
+	auth := store.Spec.Provider.Vault.Auth
+	switch {
+	case auth.AppRole != nil:
+		return "approle"
+	case auth.TokenSecretRef != nil:
+		return "token"
+	case auth.Kubernetes != nil:
+		return "kubernetes"
+	case auth.Cert != nil:
+		return "cert"
+	default:
+		return ""
 	}
+}
 
-	refreshInterval := es.Spec.RefreshInterval
-	if refreshInterval == "" {
-		refreshInterval = "30m"
+// target returns where an ExternalSecret is read from: its store's settings,
+// or the command line defaults if the store is unknown
+func (in *convertInput) target(es ExternalSecret, cfg ConvertConfig) (secretTarget, *VaultSecretStore, error) {
+	target := secretTarget{MountPath: cfg.MountPath, KVVersion: cfg.KVVersion}
+
+	store, err := in.resolveStore(es)
+	if err != nil {
+		return target, nil, err
 	}
 
-	// Determine mount path
-	mountPath := cfg.MountPath
-	var key string
+	provider := store.Spec.Provider.Vault
+	if provider.Path != "" {
+		target.MountPath = provider.Path
+	}
+	if provider.Version != "" {
+		target.KVVersion = provider.Version
+	}
+	target.Namespace = provider.Namespace
+	if storeAuthMethod(store) != "" {
+		target.Credentials = credentialsName(store)
+	}
+	return target, store, nil
+}
 
-	// Handle dataFrom.extract (pulls all fields)
-	if len(es.Spec.DataFrom) > 0 {
-		key = es.Spec.DataFrom[0].Extract.Key
-	} else if len(es.Spec.Data) > 0 {
-		key = es.Spec.Data[0].RemoteRef.Key
+// printSecretStore prints the secretStore section with one credential set
+// per store
+func printSecretStore(w *bytes.Buffer, cfg ConvertConfig, stores []*VaultSecretStore) {
+	address := cfg.VaultAddr
+	servers := make(map[string]bool)
+	for _, store := range stores {
+		servers[store.Spec.Provider.Vault.Server] = true
+		if address == "" {
+			address = store.Spec.Provider.Vault.Server
+		}
+	}
+	if len(servers) > 1 {
+		fmt.Fprintf(os.Stderr, "Warning: secret stores use %d different Vault servers, only %s is configured\n", len(servers), address)
 	}
 
-	// Auto-detect mount path if enabled
-	if cfg.AutoDetectMount && key != "" {
-		mountPath = detectMountPath(key)
+	if address == "" {
+		address = "${VAULT_ADDR}"
 	}
 
-	fmt.Printf("\n# Converted from: %s (secret: %s)\n", sourceFile, secretName)
+	fmt.Fprintln(w, "secretStore:")
+	fmt.Fprintf(w, "  address: %q\n", address)
+	for _, store := range stores {
+		if ca := storeCA(store); ca != "" {
+			todo := fmt.Sprintf("%s %q trusts a custom CA (%s); save it to a file and set tlsCACert", store.Kind, store.Metadata.Name, ca)
+			fmt.Fprintf(w, "  # TODO: %s\n", todo)
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", todo)
+		}
+	}
 
-	// Handle dataFrom.extract (pulls all fields)
-	if len(es.Spec.DataFrom) > 0 {
-		// Try to query vault for actual field names
-		var fields []string
-		queryFailed := false
-		if cfg.QueryVault {
-			queriedFields, err := queryVaultFields(mountPath, key, cfg.VaultAddr, cfg.VaultToken)
-			if err == nil && len(queriedFields) > 0 {
-				fields = queriedFields
-			} else {
-				fmt.Fprintf(os.Stderr, "Warning: Failed to query %s/%s: %v\n", mountPath, key, err)
-				queryFailed = true
-			}
+	// Use AppRole if role_id/secret_id were provided, otherwise token
+	if cfg.VaultRoleID != "" && cfg.VaultSecretID != "" {
+		fmt.Fprintln(w, "  authMethod: \"approle\"")
+		fmt.Fprintln(w, "  roleId: \"${VAULT_ROLE_ID}\"")
+		fmt.Fprintln(w, "  secretId: \"${VAULT_SECRET_ID}\"")
+	} else {
+		fmt.Fprintln(w, "  authMethod: \"token\"")
+		fmt.Fprintln(w, "  token: \"${VAULT_TOKEN}\"")
+	}
+
+	var names []string
+	byName := make(map[string]*VaultSecretStore)
+	for _, store := range stores {
+		if storeAuthMethod(store) == "" {
+			fmt.Fprintf(os.Stderr, "Warning: %s %q uses an auth method other than token, AppRole, Kubernetes or cert, its secrets use the default credentials\n",
+				store.Kind, store.Metadata.Name)
+			continue
+		}
+		name := credentialsName(store)
+		if _, ok := byName[name]; !ok {
+			names = append(names, name)
 		}
+		byName[name] = store
+	}
+	sort.Strings(names)
 
-		// Comment out the entire secret if query failed and no template provided
-		commentPrefix := ""
-		if queryFailed && len(es.Spec.Target.Template.Data) == 0 {
-			commentPrefix = "# "
-			fmt.Printf("# WARNING: Vault query failed - secret commented out, needs manual field mapping\n")
+	if len(names) > 0 {
+		fmt.Fprintln(w, "  credentials:")
+	}
+	for _, name := range names {
+		store := byName[name]
+		suffix := envSuffix(name)
+		fmt.Fprintf(w, "    %s:\n", name)
+		fmt.Fprintf(w, "      # From %s %q\n", store.Kind, store.Metadata.Name)
+		printCredentials(w, store, suffix)
+	}
+	fmt.Fprintln(w)
+}
+
+// printCredentials prints the fields of the credential set of a store.
+// Credentials that live in Kubernetes secrets become ${VAR} placeholders.
+func printCredentials(w *bytes.Buffer, store *VaultSecretStore, suffix string) {
+	auth := store.Spec.Provider.Vault.Auth
+	todo := func(format string, args ...interface{}) {
+		msg := fmt.Sprintf(format, args...)
+		fmt.Fprintf(w, "      # TODO: %s\n", msg)
+		fmt.Fprintf(os.Stderr, "Warning: %s %q: %s\n", store.Kind, store.Metadata.Name, msg)
+	}
+
+	switch storeAuthMethod(store) {
+	case "approle":
+		if path := strings.Trim(auth.AppRole.Path, "/"); path != "" && path != "approle" {
+			todo("AppRole is mounted at %q, secrets-sync logs in at approle", path)
+		}
+		roleID := auth.AppRole.RoleID
+		if roleID == "" {
+			roleID = "${VAULT_ROLE_ID_" + suffix + "}"
+		}
+		fmt.Fprintln(w, "      authMethod: \"approle\"")
+		fmt.Fprintf(w, "      roleId: %q\n", roleID)
+		fmt.Fprintf(w, "      secretId: \"${VAULT_SECRET_ID_%s}\"\n", suffix)
+	case "kubernetes":
+		k8s := auth.Kubernetes
+		switch {
+		case k8s.SecretRef != nil:
+			fmt.Fprintf(w, "      # Service account JWT from secret %q\n", k8s.SecretRef.Name)
+		case k8s.ServiceAccountRef != nil:
+			fmt.Fprintf(w, "      # Logs in as service account %q; run secrets-sync with it, or set kubernetesTokenPath\n", k8s.ServiceAccountRef.Name)
+		}
+		fmt.Fprintln(w, "      authMethod: \"kubernetes\"")
+		fmt.Fprintf(w, "      kubernetesRole: %q\n", k8s.Role)
+		if k8s.SecretRef != nil {
+			fmt.Fprintf(w, "      kubernetesTokenPath: \"${KUBERNETES_TOKEN_PATH_%s}\"\n", suffix)
+		}
+		if path := strings.Trim(k8s.MountPath, "/"); path != "" && path != "kubernetes" {
+			fmt.Fprintf(w, "      kubernetesMountPath: %q\n", path)
+		}
+	case "cert":
+		if auth.Cert.ClientCert != nil {
+			fmt.Fprintf(w, "      # Client certificate from secret %q; set tlsClientCert and tlsClientKey\n", auth.Cert.ClientCert.Name)
 		}
+		fmt.Fprintln(w, "      authMethod: \"cert\"")
+	default:
+		fmt.Fprintln(w, "      authMethod: \"token\"")
+		fmt.Fprintf(w, "      token: \"${VAULT_TOKEN_%s}\"\n", suffix)
+	}
+}
 
-		fmt.Printf("%s  - name: %q\n", commentPrefix, secretName)
+// storeCA describes the custom CA a store trusts, or returns "" if it uses
+// the system roots
+func storeCA(store *VaultSecretStore) string {
+	provider := store.Spec.Provider.Vault
+	switch {
+	case provider.CABundle != "":
+		return "caBundle"
+	case provider.CAProvider != nil:
+		return fmt.Sprintf("caProvider %s %q", provider.CAProvider.Type, provider.CAProvider.Name)
+	default:
+		return ""
+	}
+}
 
-		fmt.Printf("%s    key: %q\n", commentPrefix, key)
-		fmt.Printf("%s    mountPath: %q\n", commentPrefix, mountPath)
-		fmt.Printf("%s    kvVersion: %q\n", commentPrefix, cfg.KVVersion)
-		fmt.Printf("%s    refreshInterval: %q\n", commentPrefix, refreshInterval)
+// convertedSecret is a secrets-sync secret generated from one Vault key of an
+// ExternalSecret
+type convertedSecret struct {
+	key       string
+	version   int // KV v2 version pinned by the remoteRefs, 0 for the latest
+	templates []namedTemplate
+	note      string   // Comment printed above the templates
+	todos     []string // Features that need manual work
+	disabled  bool     // Printed commented out, as it needs manual field mapping
+}
 
-		if len(fields) > 0 {
-			fmt.Printf("    # Fields queried from Vault\n")
+type namedTemplate struct {
+	name  string
+	value string
+}
+
+// todo records a feature that could not be converted and warns about it
+func (c *convertedSecret) todo(format string, args ...interface{}) {
+	c.todos = append(c.todos, fmt.Sprintf(format, args...))
+}
+
+// fieldRef returns a template reading a field of the secret. Fields that are
+// not identifiers, e.g. starting with a dot or containing hyphens, need index
+// syntax (Go template limitation).
+func fieldRef(field string) string {
+	for i, r := range field {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return fmt.Sprintf("{{ index . %q }}", field)
 		}
+	}
+	return fmt.Sprintf("{{ .%s }}", field)
+}
 
-		fmt.Printf("%s    template:\n", commentPrefix)
-		fmt.Printf("%s      data:\n", commentPrefix)
+// convertKey applies an external-secrets conversionStrategy to a key name:
+// characters not valid in a Kubernetes secret key become "_" (Default) or
+// "_Uxxxx_" (Unicode)
+func convertKey(strategy, key string) string {
+	var b strings.Builder
+	for _, r := range key {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) || r == '-' || r == '.' || r == '_' {
+			b.WriteRune(r)
+			continue
+		}
+		switch strategy {
+		case "", "Default":
+			b.WriteRune('_')
+		case "Unicode":
+			fmt.Fprintf(&b, "_U%04x_", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
 
-		// Use template if provided in external-secret
-		if len(es.Spec.Target.Template.Data) > 0 {
-			for k, v := range es.Spec.Target.Template.Data {
-				fmt.Printf("%s        %s: %q\n", commentPrefix, k, v)
-			}
-		} else if len(fields) > 0 {
-			// Use queried fields from Vault
-			for _, field := range fields {
-				// Use index syntax for fields with special characters (Go template limitation)
-				// - Fields starting with dot (e.g., .dockerconfigjson)
-				// - Fields containing hyphens (e.g., jenkins-admin-password)
-				if strings.HasPrefix(field, ".") || strings.Contains(field, "-") {
-					fmt.Printf("%s        %s: '{{ index . %q }}'\n", commentPrefix, field, field)
-				} else {
-					fmt.Printf("%s        %s: '{{ .%s }}'\n", commentPrefix, field, field)
-				}
+// rewriteKey applies the regexp rewrites of a dataFrom entry to a key name
+func rewriteKey(rewrites []dataFromRewrite, key string) (string, error) {
+	for _, rw := range rewrites {
+		if rw.Regexp == nil {
+			continue
+		}
+		re, err := regexp.Compile(rw.Regexp.Source)
+		if err != nil {
+			return "", fmt.Errorf("invalid rewrite regexp %q: %w", rw.Regexp.Source, err)
+		}
+		key = re.ReplaceAllString(key, rw.Regexp.Target)
+	}
+	return key, nil
+}
+
+// checkRemoteRef pins the remoteRef version and records the remoteRef
+// options secrets-sync has no equivalent for
+func (c *convertedSecret) checkRemoteRef(what, kvVersion, decodingStrategy, metadataPolicy, version string) {
+	if decodingStrategy != "" && decodingStrategy != "None" {
+		c.todo("%s uses decodingStrategy %s; values are written as stored in Vault, decode them before use", what, decodingStrategy)
+	}
+	if metadataPolicy == "Fetch" {
+		c.todo("%s uses metadataPolicy Fetch; Vault metadata cannot be read, only secret data", what)
+	}
+	if version != "" {
+		n, err := strconv.Atoi(version)
+		switch {
+		case err != nil || n <= 0:
+			c.todo("%s pins version %q, which is not a KV version number", what, version)
+		case kvVersion != "v2":
+			c.todo("%s pins version %s; KV v1 has no versions, the secret is always read", what, version)
+		case c.version != 0 && c.version != n:
+			c.todo("%s pins version %s, but version %d of the same key is used too; split the secret", what, version, c.version)
+		default:
+			c.version = n
+		}
+	}
+}
+
+// convertExtract converts a dataFrom.extract entry
+func convertExtract(entry dataFromEntry, index int, cfg ConvertConfig, target secretTarget) *convertedSecret {
+	ex := entry.Extract
+	c := &convertedSecret{key: ex.Key}
+	what := fmt.Sprintf("dataFrom[%d]", index)
+	c.checkRemoteRef(what, target.KVVersion, ex.DecodingStrategy, ex.MetadataPolicy, ex.Version)
+	if ex.Property != "" {
+		c.todo("%s extracts property %q as a map; map its fields manually", what, ex.Property)
+	}
+	for _, rw := range entry.Rewrite {
+		if rw.Transform != nil {
+			c.todo("%s rewrites keys with a template transform; rename the templates manually", what)
+		}
+	}
+
+	// Try to query vault for actual field names
+	var fields []string
+	queryFailed := false
+	if cfg.QueryVault {
+		queriedFields, err := queryVaultFields(context.Background(), cfg.VaultClient, target, ex.Key)
+		if err == nil && len(queriedFields) > 0 {
+			fields = queriedFields
+		} else {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to query %s/%s: %v\n", target.MountPath, ex.Key, err)
+			queryFailed = true
+		}
+	}
+
+	if len(fields) == 0 {
+		c.disabled = queryFailed
+		if len(entry.Rewrite) > 0 {
+			c.todo("%s rewrites key names; apply the rewrite to the template names", what)
+		}
+		return c
+	}
+
+	c.note = "Fields queried from Vault"
+	for _, field := range fields {
+		name := field
+		if len(entry.Rewrite) > 0 {
+			rewritten, err := rewriteKey(entry.Rewrite, field)
+			if err != nil {
+				c.todo("%s: %v", what, err)
+			} else {
+				name = rewritten
 			}
 		} else {
-			// Fallback: commented out placeholder
-			fmt.Printf("%s        # TODO: Add field mappings, e.g.: username: '{{ .username }}'\n", commentPrefix)
+			name = convertKey(ex.ConversionStrategy, field)
 		}
+		c.templates = append(c.templates, namedTemplate{name: name, value: fieldRef(field)})
+	}
+	return c
+}
+
+// convertData converts the data entries reading one Vault key
+func convertData(key string, entries []dataEntry, target secretTarget) *convertedSecret {
+	c := &convertedSecret{key: key}
+	for _, d := range entries {
+		what := fmt.Sprintf("data %q", d.SecretKey)
+		c.checkRemoteRef(what, target.KVVersion, d.RemoteRef.DecodingStrategy, d.RemoteRef.MetadataPolicy, d.RemoteRef.Version)
 
-		fmt.Printf("%s    files:\n", commentPrefix)
-		if len(es.Spec.Target.Template.Data) > 0 {
-			for k := range es.Spec.Target.Template.Data {
-				fmt.Printf("%s      - path: %q\n", commentPrefix, filepath.Join(cfg.OutputDir, secretName, k))
-				fmt.Printf("%s        mode: \"0600\"\n", commentPrefix)
+		value := "{{ . }}"
+		if d.RemoteRef.Property != "" {
+			value = fieldRef(d.RemoteRef.Property)
+		}
+		c.templates = append(c.templates, namedTemplate{name: d.SecretKey, value: value})
+	}
+	if c.version > 0 {
+		for _, d := range entries {
+			if d.RemoteRef.Version == "" {
+				c.todo("data %q reads the latest version, but version %d of the same key is pinned; split the secret", d.SecretKey, c.version)
 			}
-		} else if len(fields) > 0 {
-			// Create one file per field
-			for _, field := range fields {
-				fmt.Printf("%s      - path: %q\n", commentPrefix, filepath.Join(cfg.OutputDir, secretName, field))
-				fmt.Printf("%s        mode: \"0600\"\n", commentPrefix)
+		}
+	}
+	return c
+}
+
+// convertSources converts every source of an ExternalSecret into one secret
+// per Vault key, in the order external-secrets merges them: dataFrom entries
+// first, then data, later keys replacing earlier ones of the same name
+func convertSources(es ExternalSecret, cfg ConvertConfig, target secretTarget) ([]*convertedSecret, []string) {
+	var parts []*convertedSecret
+	var todos []string
+
+	for i, entry := range es.Spec.DataFrom {
+		switch {
+		case entry.Extract != nil:
+			parts = append(parts, convertExtract(entry, i, cfg, target))
+		case entry.Find != nil:
+			todos = append(todos, fmt.Sprintf("dataFrom[%d] finds secrets by name or tags; list the matching keys as separate secrets", i))
+		case entry.SourceRef != nil:
+			todos = append(todos, fmt.Sprintf("dataFrom[%d] reads from a sourceRef (generator or other store); it cannot be converted", i))
+		}
+	}
+
+	// Group data entries by Vault key, keeping their order
+	var keys []string
+	byKey := make(map[string][]dataEntry)
+	for _, d := range es.Spec.Data {
+		if _, ok := byKey[d.RemoteRef.Key]; !ok {
+			keys = append(keys, d.RemoteRef.Key)
+		}
+		byKey[d.RemoteRef.Key] = append(byKey[d.RemoteRef.Key], d)
+	}
+	for _, key := range keys {
+		parts = append(parts, convertData(key, byKey[key], target))
+	}
+
+	// The ExternalSecret template replaces the fetched keys, or is merged with them
+	tmpl := es.Spec.Target.Template
+	if len(tmpl.Data) > 0 && len(parts) > 0 {
+		if len(es.Spec.Data) > 0 {
+			todos = append(todos, "target.template refers to data by secretKey; replace those references with Vault field names")
+		}
+		if len(parts) > 1 {
+			todos = append(todos, fmt.Sprintf("target.template combines %d Vault keys; it is attached to the first one, move references to the others", len(parts)))
+		}
+
+		names := make([]string, 0, len(tmpl.Data))
+		for name := range tmpl.Data {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		templates := make([]namedTemplate, 0, len(names))
+		for _, name := range names {
+			templates = append(templates, namedTemplate{name: name, value: tmpl.Data[name]})
+			if err := template.Check(tmpl.Data[name]); err != nil {
+				todos = append(todos, fmt.Sprintf("target.template key %q is not supported: %v", name, err))
 			}
+		}
+
+		if tmpl.MergePolicy == "Merge" {
+			parts[0].templates = append(parts[0].templates, templates...)
+			parts[0].disabled = false
 		} else {
-			// Fallback: commented out placeholder
-			fmt.Printf("%s      - path: %q\n", commentPrefix, filepath.Join(cfg.OutputDir, secretName, "field1"))
-			fmt.Printf("%s        mode: \"0600\"\n", commentPrefix)
+			parts[0].templates = templates
+			parts[0].note = ""
+			parts[0].disabled = false
+			for _, part := range parts[1:] {
+				part.templates = nil
+				part.disabled = false
+			}
 		}
-		return nil
+	}
+	for _, from := range tmpl.TemplateFrom {
+		source := "a literal"
+		switch {
+		case from.ConfigMap != nil:
+			source = fmt.Sprintf("ConfigMap %q", from.ConfigMap.Name)
+		case from.Secret != nil:
+			source = fmt.Sprintf("Secret %q", from.Secret.Name)
+		}
+		todos = append(todos, fmt.Sprintf("target.template.templateFrom reads templates from %s; copy them into template.data", source))
+	}
+	if tmpl.EngineVersion == "v1" {
+		todos = append(todos, "target.template uses engine v1; check the template syntax")
 	}
 
-	// Handle data[] (specific fields)
-	if len(es.Spec.Data) > 0 {
-		fmt.Printf("  - name: %q\n", secretName)
-		fmt.Printf("    key: %q\n", key)
-		fmt.Printf("    mountPath: %q\n", mountPath)
-		fmt.Printf("    kvVersion: %q\n", cfg.KVVersion)
-		fmt.Printf("    refreshInterval: %q\n", refreshInterval)
-		fmt.Printf("    template:\n")
-		fmt.Printf("      data:\n")
-
-		for _, d := range es.Spec.Data {
-			if d.RemoteRef.Property != "" {
-				fmt.Printf("        %s: '{{ .%s }}'\n", d.SecretKey, d.RemoteRef.Property)
-			} else {
-				fmt.Printf("        %s: '{{ . }}'\n", d.SecretKey)
+	// Later sources win for keys of the same name
+	seen := make(map[string]bool)
+	for i := len(parts) - 1; i >= 0; i-- {
+		var kept []namedTemplate
+		for _, t := range parts[i].templates {
+			if seen[t.name] {
+				todos = append(todos, fmt.Sprintf("key %q comes from several Vault keys; only the last one (as in external-secrets) is kept", t.name))
+				continue
 			}
+			seen[t.name] = true
+			kept = append(kept, t)
 		}
+		parts[i].templates = kept
+	}
 
-		fmt.Printf("    files:\n")
-		for _, d := range es.Spec.Data {
-			fmt.Printf("      - path: %q\n", filepath.Join(cfg.OutputDir, secretName, d.SecretKey))
-			fmt.Printf("        mode: \"0600\"\n")
+	// Parts left without templates by merging are dropped
+	var result []*convertedSecret
+	for _, part := range parts {
+		if len(part.templates) > 0 || len(part.todos) > 0 || part.disabled || len(parts) == 1 {
+			result = append(result, part)
+		}
+	}
+	return result, todos
+}
+
+// yamlKey quotes a template name if it is not a plain YAML key
+func yamlKey(name string) string {
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '-' && r != '.' && r != '_' {
+			return fmt.Sprintf("%q", name)
+		}
+	}
+	return name
+}
+
+// yamlValue quotes a template value, preferring single quotes
+func yamlValue(value string) string {
+	if strings.ContainsAny(value, "'\n\\") {
+		return fmt.Sprintf("%q", value)
+	}
+	return "'" + value + "'"
+}
+
+func convertSingleSecret(w *bytes.Buffer, es ExternalSecret, sourceFile string, cfg ConvertConfig, target secretTarget) error {
+
+	// Build secret configuration
+	secretName := es.Spec.Target.Name
+	if secretName == "" {
+		secretName = es.Metadata.Name
+	}
+
+	refreshInterval := es.Spec.RefreshInterval
+	if refreshInterval == "" {
+		refreshInterval = "30m"
+	}
+
+	parts, todos := convertSources(es, cfg, target)
+	if len(parts) == 0 && len(todos) == 0 {
+		return fmt.Errorf("no data or dataFrom found in ExternalSecret")
+	}
+
+	fmt.Fprintf(w, "\n  # Converted from: %s (secret: %s)\n", sourceFile, secretName)
+	for _, todo := range todos {
+		fmt.Fprintf(w, "  # TODO: %s\n", todo)
+		fmt.Fprintf(os.Stderr, "Warning: %s in %s: %s\n", secretName, sourceFile, todo)
+	}
+
+	for i, part := range parts {
+		// Several Vault keys become several secrets writing to the same directory
+		name := secretName
+		if len(parts) > 1 {
+			name = fmt.Sprintf("%s-%d", secretName, i+1)
+		}
+
+		// Comment out the entire secret if query failed and no template provided
+		prefix := ""
+		if part.disabled {
+			prefix = "# "
+			fmt.Fprintf(w, "  # WARNING: Vault query failed - secret commented out, needs manual field mapping\n")
+		}
+
+		fmt.Fprintf(w, "  %s- name: %q\n", prefix, name)
+		for _, todo := range part.todos {
+			fmt.Fprintf(w, "  %s  # TODO: %s\n", prefix, todo)
+			fmt.Fprintf(os.Stderr, "Warning: %s in %s: %s\n", secretName, sourceFile, todo)
+		}
+		fmt.Fprintf(w, "  %s  key: %q\n", prefix, part.key)
+		fmt.Fprintf(w, "  %s  mountPath: %q\n", prefix, target.MountPath)
+		if target.Namespace != "" {
+			fmt.Fprintf(w, "  %s  namespace: %q\n", prefix, target.Namespace)
+		}
+		if target.Credentials != "" {
+			fmt.Fprintf(w, "  %s  credentials: %q\n", prefix, target.Credentials)
+		}
+		fmt.Fprintf(w, "  %s  kvVersion: %q\n", prefix, target.KVVersion)
+		if part.version > 0 {
+			fmt.Fprintf(w, "  %s  version: %d\n", prefix, part.version)
+		}
+		fmt.Fprintf(w, "  %s  refreshInterval: %q\n", prefix, refreshInterval)
+
+		if part.note != "" {
+			fmt.Fprintf(w, "  %s  # %s\n", prefix, part.note)
+		}
+		fmt.Fprintf(w, "  %s  template:\n", prefix)
+		fmt.Fprintf(w, "  %s    data:\n", prefix)
+		if len(part.templates) == 0 {
+			// Fallback: commented out placeholder
+			fmt.Fprintf(w, "  %s      # TODO: Add field mappings, e.g.: username: '{{ .username }}'\n", prefix)
+		}
+		for _, t := range part.templates {
+			fmt.Fprintf(w, "  %s      %s: %s\n", prefix, yamlKey(t.name), yamlValue(t.value))
+		}
+
+		fmt.Fprintf(w, "  %s  files:\n", prefix)
+		if len(part.templates) == 0 {
+			// Fallback: commented out placeholder
+			fmt.Fprintf(w, "  %s    - path: %q\n", prefix, filepath.Join(cfg.OutputDir, secretName, "field1"))
+			fmt.Fprintf(w, "  %s      # template: field1\n", prefix)
+			fmt.Fprintf(w, "  %s      mode: \"0600\"\n", prefix)
+		}
+		for _, t := range part.templates {
+			fmt.Fprintf(w, "  %s    - path: %q\n", prefix, filepath.Join(cfg.OutputDir, secretName, t.name))
+			fmt.Fprintf(w, "  %s      template: %q\n", prefix, t.name)
+			fmt.Fprintf(w, "  %s      mode: \"0600\"\n", prefix)
 		}
-		return nil
 	}
 
-	return fmt.Errorf("no data or dataFrom found in ExternalSecret")
+	return nil
+}
+
+func printConvertUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: secrets-sync convert <external-secret-files...> [options]\n")
+	fmt.Fprintf(os.Stderr, "       secrets-sync convert --to external-secrets [--namespace <ns>] [config-file]\n")
+	fmt.Fprintf(os.Stderr, "\nMount path, KV version, Vault namespace and credentials of each secret are\n")
+	fmt.Fprintf(os.Stderr, "taken from the SecretStore or ClusterSecretStore it references. Stores are\n")
+	fmt.Fprintf(os.Stderr, "read from the input files, --store-file and, with --from-cluster, kubectl.\n")
+	fmt.Fprintf(os.Stderr, "\nClusterExternalSecrets are expanded for the namespaces they list or select;\n")
+	fmt.Fprintf(os.Stderr, "selectors match Namespace objects from the inputs or, with --from-cluster,\n")
+	fmt.Fprintf(os.Stderr, "the cluster. PushSecrets are reported but not converted.\n")
+	fmt.Fprintf(os.Stderr, "\nWith --to external-secrets a secrets-sync config (default: the one selected by\n")
+	fmt.Fprintf(os.Stderr, "--config) is converted the other way: every secret becomes an ExternalSecret,\n")
+	fmt.Fprintf(os.Stderr, "reading from a ClusterSecretStore per credential set, mount and namespace, and\n")
+	fmt.Fprintf(os.Stderr, "database secrets a VaultDynamicSecret generator. Wildcard keys are not converted.\n")
+	fmt.Fprintf(os.Stderr, "\nOptions:\n")
+	fmt.Fprintf(os.Stderr, "  --store-file <file>      Read SecretStores/ClusterSecretStores from file (repeatable)\n")
+	fmt.Fprintf(os.Stderr, "  --from-cluster           Read SecretStores/ClusterSecretStores and Namespaces with kubectl\n")
+	fmt.Fprintf(os.Stderr, "  --mount-path <path>      KV mount path if the store is unknown (default: secret)\n")
+	fmt.Fprintf(os.Stderr, "  --kv-version <v1|v2>     KV version if the store is unknown (default: v2)\n")
+	fmt.Fprintf(os.Stderr, "  --output-dir <dir>       Output directory for secrets (default: ./secrets)\n")
+	fmt.Fprintf(os.Stderr, "  --out <file|dir>         Write the config to a file, merging into an existing one,\n")
+	fmt.Fprintf(os.Stderr, "                           or to config.yaml in a directory (default: stdout)\n")
+	fmt.Fprintf(os.Stderr, "  --split                  Write one file per ExternalSecret into the --out directory\n")
+	fmt.Fprintf(os.Stderr, "  --to <format>            secrets-sync (default) or external-secrets\n")
+	fmt.Fprintf(os.Stderr, "  --namespace <ns>         Namespace of the ExternalSecrets, with --to external-secrets\n")
+	fmt.Fprintf(os.Stderr, "  --query-vault            Query Vault for actual field names\n")
+	fmt.Fprintf(os.Stderr, "  --vault-addr <url>       Vault address (default: $VAULT_ADDR, then the stores' server)\n")
+	fmt.Fprintf(os.Stderr, "  --vault-token <token>    Vault token (default: $VAULT_TOKEN)\n")
+	fmt.Fprintf(os.Stderr, "  --vault-role-id <id>     Vault AppRole role_id (default: $VAULT_ROLE_ID)\n")
+	fmt.Fprintf(os.Stderr, "  --vault-secret-id <id>   Vault AppRole secret_id (default: $VAULT_SECRET_ID)\n")
+	fmt.Fprintf(os.Stderr, "\nExample:\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync convert external-secret.yaml --query-vault\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync convert external-secret.yaml --store-file secret-store.yaml\n")
+	fmt.Fprintf(os.Stderr, "  kubectl get externalsecrets,clusterexternalsecrets,secretstores,clustersecretstores,namespaces -A -o yaml | secrets-sync convert -\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync convert external-secret.yaml --query-vault --vault-role-id <id> --vault-secret-id <id>\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync convert manifests/*.yaml --out /etc/secrets-sync/config.yaml\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync convert manifests/*.yaml --split --out /etc/secrets-sync/conf.d\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync convert --to external-secrets --namespace apps config.yaml > external-secrets.yaml\n")
 }
 
 func runConvert(args []string) int {
 	if len(args) < 1 {
-		fmt.Fprintf(os.Stderr, "Usage: secrets-sync convert <external-secret-files...> [options]\n")
-		fmt.Fprintf(os.Stderr, "\nOptions:\n")
-		fmt.Fprintf(os.Stderr, "  --mount-path <path>      KV mount path (default: auto-detect)\n")
-		fmt.Fprintf(os.Stderr, "  --kv-version <v1|v2>     KV version (default: v2)\n")
-		fmt.Fprintf(os.Stderr, "  --output-dir <dir>       Output directory for secrets (default: ./secrets)\n")
-		fmt.Fprintf(os.Stderr, "  --query-vault            Query Vault for actual field names (requires vault CLI)\n")
-		fmt.Fprintf(os.Stderr, "  --vault-addr <url>       Vault address (default: $VAULT_ADDR)\n")
-		fmt.Fprintf(os.Stderr, "  --vault-token <token>    Vault token (default: $VAULT_TOKEN)\n")
-		fmt.Fprintf(os.Stderr, "  --vault-role-id <id>     Vault AppRole role_id (default: $VAULT_ROLE_ID)\n")
-		fmt.Fprintf(os.Stderr, "  --vault-secret-id <id>   Vault AppRole secret_id (default: $VAULT_SECRET_ID)\n")
-		fmt.Fprintf(os.Stderr, "\nExample:\n")
-		fmt.Fprintf(os.Stderr, "  secrets-sync convert external-secret.yaml --query-vault\n")
-		fmt.Fprintf(os.Stderr, "  secrets-sync convert external-secret.yaml --query-vault --vault-role-id <id> --vault-secret-id <id>\n")
+		printConvertUsage()
 		return 1
 	}
 
 	cfg := ConvertConfig{
-		MountPath:       "secret",
-		KVVersion:       "v2",
-		OutputDir:       "./secrets",
-		AutoDetectMount: true,
-		QueryVault:      false,
-		VaultAddr:       os.Getenv("VAULT_ADDR"),
-		VaultToken:      os.Getenv("VAULT_TOKEN"),
-		VaultRoleID:     os.Getenv("VAULT_ROLE_ID"),
-		VaultSecretID:   os.Getenv("VAULT_SECRET_ID"),
+		MountPath:     "secret",
+		KVVersion:     "v2",
+		OutputDir:     "./secrets",
+		QueryVault:    false,
+		VaultAddr:     os.Getenv("VAULT_ADDR"),
+		VaultToken:    os.Getenv("VAULT_TOKEN"),
+		VaultRoleID:   os.Getenv("VAULT_ROLE_ID"),
+		VaultSecretID: os.Getenv("VAULT_SECRET_ID"),
 	}
 
 	var files []string
 	for i := 0; i < len(args); i++ {
 		arg := args[i]
 		switch arg {
+		case "--store-file":
+			if i+1 < len(args) {
+				cfg.StoreFiles = append(cfg.StoreFiles, args[i+1])
+				i++
+			}
+		case "--from-cluster":
+			cfg.FromCluster = true
 		case "--mount-path":
 			if i+1 < len(args) {
 				cfg.MountPath = args[i+1]
-				cfg.AutoDetectMount = false
 				i++
 			}
 		case "--kv-version":
@@ -390,6 +1264,23 @@ func runConvert(args []string) int {
 				cfg.OutputDir = args[i+1]
 				i++
 			}
+		case "--out":
+			if i+1 < len(args) {
+				cfg.Out = args[i+1]
+				i++
+			}
+		case "--split":
+			cfg.Split = true
+		case "--to":
+			if i+1 < len(args) {
+				cfg.To = args[i+1]
+				i++
+			}
+		case "--namespace":
+			if i+1 < len(args) {
+				cfg.Namespace = args[i+1]
+				i++
+			}
 		case "--query-vault":
 			cfg.QueryVault = true
 		case "--vault-addr":
@@ -412,6 +1303,9 @@ func runConvert(args []string) int {
 				cfg.VaultSecretID = args[i+1]
 				i++
 			}
+		case "-h", "--help":
+			printConvertUsage()
+			return 0
 		default:
 			if !strings.HasPrefix(arg, "--") {
 				files = append(files, arg)
@@ -419,63 +1313,251 @@ func runConvert(args []string) int {
 		}
 	}
 
+	switch cfg.To {
+	case "", "secrets-sync":
+	case "external-secrets":
+		return convertToExternalSecrets(cfg, files)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: --to must be external-secrets or secrets-sync, got %q\n", cfg.To)
+		return 1
+	}
+
 	if len(files) == 0 {
 		fmt.Fprintf(os.Stderr, "Error: no input files specified\n")
 		return 1
 	}
+	if cfg.Split && cfg.Out == "" {
+		fmt.Fprintf(os.Stderr, "Error: --split requires --out <dir>\n")
+		return 1
+	}
+
+	// Read everything first, so stores are known before secrets are printed
+	in := &convertInput{
+		stores:     make(map[string]*VaultSecretStore),
+		namespaces: make(map[string]map[string]string),
+	}
+	for _, file := range append(cfg.StoreFiles, files...) {
+		if err := in.loadFile(file); err != nil {
+			fmt.Fprintf(os.Stderr, "Error converting %s: %v\n", file, err)
+		}
+	}
+	if cfg.FromCluster {
+		if err := in.loadCluster(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	in.expandClusterSecrets(cfg)
 
 	// If query-vault is enabled, ensure we have credentials
 	if cfg.QueryVault {
-		// Try to get token from AppRole if provided
-		if cfg.VaultToken == "" && cfg.VaultRoleID != "" && cfg.VaultSecretID != "" {
-			token, err := getVaultToken(cfg.VaultAddr, cfg.VaultRoleID, cfg.VaultSecretID)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: failed to authenticate with AppRole: %v\n", err)
-				return 1
-			}
-			cfg.VaultToken = token
+		address := cfg.VaultAddr
+		if address == "" {
+			address = in.server()
 		}
-
-		// Verify we have required credentials
-		if cfg.VaultAddr == "" || cfg.VaultToken == "" {
+		hasAppRole := cfg.VaultRoleID != "" && cfg.VaultSecretID != ""
+		if address == "" || (cfg.VaultToken == "" && !hasAppRole) {
 			fmt.Fprintf(os.Stderr, "Error: --query-vault requires vault credentials\n")
 			fmt.Fprintf(os.Stderr, "Provide either:\n")
-			fmt.Fprintf(os.Stderr, "  - VAULT_ADDR and VAULT_TOKEN environment variables\n")
+			fmt.Fprintf(os.Stderr, "  - VAULT_ADDR (or a SecretStore naming the server) and VAULT_TOKEN environment variables\n")
 			fmt.Fprintf(os.Stderr, "  - --vault-addr and --vault-token flags\n")
 			fmt.Fprintf(os.Stderr, "  - --vault-addr, --vault-role-id, and --vault-secret-id flags\n")
 			return 1
 		}
+
+		client, err := newConvertClient(context.Background(), address, cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to authenticate with Vault: %v\n", err)
+			return 1
+		}
+		cfg.VaultClient = client
 	}
 
-	fmt.Println("# Generated configuration from external-secrets")
-	fmt.Println("# Review and adjust template fields as needed")
-	fmt.Println()
+	targets := make([]secretTarget, len(in.secrets))
+	var stores []*VaultSecretStore
+	seen := make(map[*VaultSecretStore]bool)
+	for i, s := range in.secrets {
+		target, store, err := in.target(s.secret, cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s in %s: %v, using mount %q and kv %s\n",
+				s.secret.Metadata.Name, s.source, err, target.MountPath, target.KVVersion)
+		} else if !seen[store] {
+			seen[store] = true
+			stores = append(stores, store)
+		}
+		targets[i] = target
+	}
 
-	// Generate secretStore section if vault credentials are available
-	if cfg.QueryVault && cfg.VaultAddr != "" {
-		fmt.Println("secretStore:")
-		fmt.Printf("  address: %q\n", cfg.VaultAddr)
+	// Stores no secret references still get credentials, so the whole dump
+	// is converted; referenced stores come first and pick the address
+	keys := make([]string, 0, len(in.stores))
+	for key := range in.stores {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if store := in.stores[key]; !seen[store] {
+			seen[store] = true
+			stores = append(stores, store)
+		}
+	}
 
-		// Use AppRole if role_id/secret_id were provided, otherwise token
-		if cfg.VaultRoleID != "" && cfg.VaultSecretID != "" {
-			fmt.Println("  authMethod: \"approle\"")
-			fmt.Println("  roleId: \"${VAULT_ROLE_ID}\"")
-			fmt.Println("  secretId: \"${VAULT_SECRET_ID}\"")
-		} else {
-			fmt.Println("  authMethod: \"token\"")
-			fmt.Println("  token: \"${VAULT_TOKEN}\"")
+	if cfg.Split {
+		if err := writeSplit(cfg, in, targets, stores); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	var buf bytes.Buffer
+	if cfg.Out == "" {
+		// Generate secretStore section if the Vault server is known
+		printConfig(&buf, cfg, in, targets, stores, len(stores) > 0 || (cfg.QueryVault && cfg.VaultAddr != ""))
+		if _, err := os.Stdout.Write(buf.Bytes()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
 		}
-		fmt.Println()
+		return 0
 	}
 
-	fmt.Println("secrets:")
+	// A written config is loaded as is, so it always gets a secretStore
+	printConfig(&buf, cfg, in, targets, stores, true)
+	path := cfg.Out
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, "config.yaml")
+	}
+	if err := writeConfigFile(path, buf.Bytes()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
 
-	for _, file := range files {
-		if err := convertExternalSecret(file, cfg); err != nil {
-			fmt.Fprintf(os.Stderr, "Error converting %s: %v\n", file, err)
+// printConfig prints the converted configuration of every ExternalSecret
+func printConfig(w *bytes.Buffer, cfg ConvertConfig, in *convertInput, targets []secretTarget, stores []*VaultSecretStore, withStore bool) {
+	printHeader(w, in.skipped)
+	if withStore {
+		printSecretStore(w, cfg, stores)
+	}
+
+	fmt.Fprintln(w, "secrets:")
+	for i, s := range in.secrets {
+		if err := convertSingleSecret(w, s.secret, s.source, cfg, targets[i]); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to convert %s in %s: %v\n", s.secret.Metadata.Name, s.source, err)
+		}
+	}
+}
+
+// printHeader prints the comment opening a converted configuration
+func printHeader(w *bytes.Buffer, skipped []string) {
+	fmt.Fprintln(w, "# Generated configuration from external-secrets")
+	fmt.Fprintln(w, "# Review and adjust template fields as needed")
+	for _, s := range skipped {
+		fmt.Fprintf(w, "# Not converted: %s\n", s)
+	}
+	fmt.Fprintln(w)
+}
+
+// splitStoreFile is the file --split writes the secretStore section to,
+// named to sort before the secrets
+const splitStoreFile = "00-secret-store.yaml"
+
+// writeSplit writes the secretStore section and every ExternalSecret to a
+// file of its own in cfg.Out, which can then be used as a config directory
+func writeSplit(cfg ConvertConfig, in *convertInput, targets []secretTarget, stores []*VaultSecretStore) error {
+	if info, err := os.Stat(cfg.Out); err == nil && !info.IsDir() {
+		return fmt.Errorf("--split needs a directory, %s is a file", cfg.Out)
+	}
+
+	var buf bytes.Buffer
+	printHeader(&buf, in.skipped)
+	printSecretStore(&buf, cfg, stores)
+	if err := writeConfigFile(filepath.Join(cfg.Out, splitStoreFile), buf.Bytes()); err != nil {
+		return err
+	}
+
+	used := map[string]bool{splitStoreFile: true}
+	for i, s := range in.secrets {
+		buf.Reset()
+		fmt.Fprintln(&buf, "secrets:")
+		if err := convertSingleSecret(&buf, s.secret, s.source, cfg, targets[i]); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to convert %s in %s: %v\n", s.secret.Metadata.Name, s.source, err)
 			continue
 		}
+		if err := writeConfigFile(filepath.Join(cfg.Out, splitFileName(s.secret, used)), buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unsafeFileChars matches characters not used in file names written by --split
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// splitFileName names the file of an ExternalSecret <namespace>.<name>.yaml;
+// Kubernetes namespaces contain no dots, so names cannot clash across
+// namespaces. Names already used get a number.
+func splitFileName(es ExternalSecret, used map[string]bool) string {
+	base := es.Metadata.Name
+	if es.Metadata.Namespace != "" {
+		base = es.Metadata.Namespace + "." + base
+	}
+	base = strings.Trim(unsafeFileChars.ReplaceAllString(base, "-"), ".")
+	if base == "" {
+		base = "externalsecret"
+	}
+
+	name := base + ".yaml"
+	for i := 2; used[name]; i++ {
+		name = fmt.Sprintf("%s-%d.yaml", base, i)
+	}
+	used[name] = true
+	return name
+}
+
+// writeConfigFile writes a converted config to a YAML file, merging it into
+// the config already there. Existing files keep their mode and ownership.
+func writeConfigFile(path string, generated []byte) error {
+	if format := config.FileFormat(path); format != config.FormatYAML {
+		return fmt.Errorf("%s: converted configs are written as YAML, not %s", path, format)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	file := filewriter.FileConfig{Path: abs, Mode: 0640, Owner: -1, Group: -1}
+	existing, err := os.ReadFile(abs)
+	switch {
+	case err == nil:
+		mode, uid, gid, err := filewriter.GetFileInfo(abs)
+		if err != nil {
+			return err
+		}
+		file.Mode, file.Owner, file.Group = mode.Perm(), uid, gid
+	case !errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("failed to read %s: %w", path, err)
 	}
 
-	return 0
+	data, result, err := config.Merge(existing, generated)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for _, name := range result.Skipped {
+		fmt.Fprintf(os.Stderr, "Warning: secret %q is already in %s, not added\n", name, path)
+	}
+	if len(existing) > 0 && bytes.Equal(data, existing) {
+		return nil
+	}
+
+	if err := filewriter.NewWriter().WriteBytes(context.Background(), file, data); err != nil {
+		return err
+	}
+	if len(result.Added) == 0 {
+		fmt.Fprintf(os.Stderr, "Wrote %s\n", path)
+	} else {
+		fmt.Fprintf(os.Stderr, "Wrote %s (%d secrets added)\n", path, len(result.Added))
+	}
+	return nil
 }
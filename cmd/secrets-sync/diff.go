@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/syncer"
+)
+
+func printDiffUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: secrets-sync diff [options]\n")
+	fmt.Fprintf(os.Stderr, "\nRenders the files of every configured secret and compares them with the files\n")
+	fmt.Fprintf(os.Stderr, "on disk: a unified diff of the content, with values masked, and differences\n")
+	fmt.Fprintf(os.Stderr, "of mode and ownership. Nothing is written.\n")
+	fmt.Fprintf(os.Stderr, "\nExit status is 0 without drift, 1 with drift and 2 if a secret failed.\n")
+	fmt.Fprintf(os.Stderr, "\nOptions:\n")
+	fmt.Fprintf(os.Stderr, "  -c, --config <path>   Configuration file (default: as for the service)\n")
+	fmt.Fprintf(os.Stderr, "  --secret <name>       Compare only this secret (repeatable)\n")
+	fmt.Fprintf(os.Stderr, "  --show-values         Show values in the diff instead of masking them\n")
+	fmt.Fprintf(os.Stderr, "\nExamples:\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync diff --config config.yaml\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync diff --config config.yaml --secret db-credentials --show-values\n")
+}
+
+// runDiff prints how the files on disk differ from what a sync would write
+func runDiff(args []string) int {
+	var names []string
+	showValues := false
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			printDiffUsage()
+			return 0
+		case "--show-values":
+			showValues = true
+			continue
+		}
+		if i+1 >= len(args) {
+			fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", arg)
+			return 2
+		}
+		value := args[i+1]
+		i++
+
+		switch arg {
+		case "-c", "--config":
+			configFile = value
+		case "--secret":
+			names = append(names, value)
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown option: %s\n\n", arg)
+			printDiffUsage()
+			return 2
+		}
+	}
+
+	drifted, err := diff(names, showValues)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if drifted > 0 {
+		return 1
+	}
+	return 0
+}
+
+// diff compares the selected secrets, or all of them, with the files on disk
+// and returns the number of files that drifted
+func diff(names []string, showValues bool) (int, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	secrets, err := selectSecrets(cfg, names)
+	if err != nil {
+		return 0, err
+	}
+
+	envCfg := config.LoadEnvConfig()
+	secretSyncer := syncer.NewSecretSyncer(newClientFactory(cfg, envCfg), newRetryConfig(envCfg)).WithAzure(newAzureClientFactory(cfg))
+
+	files, drifted, failed := 0, 0, 0
+	for _, secret := range secrets {
+		diffs, err := secretSyncer.Diff(ctx, cfg, secret, showValues)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %s: %v\n", secret.Name, err)
+			failed++
+			continue
+		}
+		for _, d := range diffs {
+			files++
+			if !d.Drifted() {
+				continue
+			}
+			drifted++
+			printFileDiff(d)
+		}
+	}
+
+	if drifted == 0 {
+		fmt.Printf("No drift in %d files\n", files)
+	} else {
+		fmt.Printf("%d of %d files drifted\n", drifted, files)
+	}
+	if failed > 0 {
+		return drifted, fmt.Errorf("%d of %d secrets failed to render", failed, len(secrets))
+	}
+	return drifted, nil
+}
+
+// printFileDiff prints the drift of one file
+func printFileDiff(d syncer.FileDiff) {
+	fmt.Printf("--- %s (on disk)\n", d.Path)
+	fmt.Printf("+++ %s (secret: %s)\n", d.Path, d.Secret)
+	switch {
+	case d.Missing:
+		fmt.Printf("file does not exist\n")
+	case d.Binary:
+		fmt.Printf("binary content differs\n")
+	}
+	for _, m := range d.Mismatches {
+		fmt.Printf("%s\n", m)
+	}
+	fmt.Print(d.Content)
+	fmt.Println()
+}
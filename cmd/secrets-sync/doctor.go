@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/doctor"
+	"github.com/ohauer/secrets-sync/internal/logger"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+// doctorSections are the sections of the doctor report, in print order
+var doctorSections = []struct {
+	name  string
+	title string
+}{
+	{"vault", "Vault connectivity"},
+	{"tls", "TLS"},
+	{"clock", "Clock"},
+	{"auth", "Authentication"},
+	{"capabilities", "Capabilities"},
+	{"directories", "Output directories"},
+}
+
+// doctorSymbols are printed in front of each finding, with their color
+var doctorSymbols = map[doctor.Status]struct {
+	symbol string
+	color  string
+}{
+	doctor.StatusOK:   {"✓", "\033[32m"},
+	doctor.StatusWarn: {"!", "\033[33m"},
+	doctor.StatusFail: {"✗", "\033[31m"},
+}
+
+const colorReset = "\033[0m"
+
+func printDoctorUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: secrets-sync [--config <path>] doctor [options]\n")
+	fmt.Fprintf(os.Stderr, "\nDiagnoses the environment: reaches every Vault endpoint and checks its TLS\n")
+	fmt.Fprintf(os.Stderr, "chain and clock, authenticates every credential set and checks its token TTL,\n")
+	fmt.Fprintf(os.Stderr, "asks Vault (sys/capabilities-self) whether each secret may be read, and probes\n")
+	fmt.Fprintf(os.Stderr, "each output directory. Secrets are not read and managed files are not touched.\n")
+	fmt.Fprintf(os.Stderr, "Exits 1 if any check fails; warnings do not fail.\n")
+	fmt.Fprintf(os.Stderr, "\nOptions:\n")
+	fmt.Fprintf(os.Stderr, "  --no-color    Do not color the report (default: color on a terminal,\n")
+	fmt.Fprintf(os.Stderr, "                unless NO_COLOR is set)\n")
+	fmt.Fprintf(os.Stderr, "\nExamples:\n")
+	fmt.Fprintf(os.Stderr, "  sudo -u secrets-sync secrets-sync --config /etc/secrets-sync/config.yaml doctor\n")
+}
+
+// runDoctor runs every diagnosis and prints the report
+func runDoctor(args []string) int {
+	color := useColor()
+
+	for _, arg := range args {
+		switch arg {
+		case "-h", "--help":
+			printDoctorUsage()
+			return 0
+		case "--no-color":
+			color = false
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown option: %s\n\n", arg)
+			printDoctorUsage()
+			return 1
+		}
+	}
+
+	envCfg := config.LoadEnvConfig()
+	if err := logger.Init("error"); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	tlsConfig := newVaultTLSConfig(cfg, envCfg)
+	conn := newVaultConnection(cfg, envCfg)
+	report := doctor.Run(ctx, cfg, doctor.Options{
+		NewClient: func(address string) (*vault.Client, error) {
+			return vault.NewClientWithConnection(address, tlsConfig, conn)
+		},
+		Factory: newClientFactory(cfg, envCfg),
+	})
+
+	fmt.Printf("Diagnosing %s\n", getConfigFile())
+	for _, section := range doctorSections {
+		printed := false
+		for _, f := range report.Findings {
+			if f.Section != section.name {
+				continue
+			}
+			if !printed {
+				fmt.Printf("\n%s\n", section.title)
+				printed = true
+			}
+			printFinding(f, color)
+		}
+	}
+
+	failed, warned := report.Count(doctor.StatusFail), report.Count(doctor.StatusWarn)
+	fmt.Printf("\n%d checks: %d passed, %d warnings, %d failed\n", len(report.Findings), len(report.Findings)-failed-warned, warned, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// printFinding prints one finding, colored by its status if color is set
+func printFinding(f doctor.Finding, color bool) {
+	s := doctorSymbols[f.Status]
+	symbol := s.symbol
+	if color {
+		symbol = s.color + symbol + colorReset
+	}
+	if f.Detail == "" {
+		fmt.Printf("  %s %s\n", symbol, f.Name)
+		return
+	}
+	// Vault API errors span several lines; keep one finding per line
+	fmt.Printf("  %s %s: %s\n", symbol, f.Name, strings.Join(strings.Fields(f.Detail), " "))
+}
+
+// useColor reports whether stdout is a terminal that should show colors
+func useColor() bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/filewriter"
+)
+
+func printFmtUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: secrets-sync fmt [options] [config-files...]\n")
+	fmt.Fprintf(os.Stderr, "\nRewrites config files in canonical style. Without files, the config file\n")
+	fmt.Fprintf(os.Stderr, "selected by --config, CONFIG_FILE or the default locations is formatted.\n")
+	fmt.Fprintf(os.Stderr, "\nOptions:\n")
+	fmt.Fprintf(os.Stderr, "  --check     List files that are not formatted and exit 1, without writing\n")
+	fmt.Fprintf(os.Stderr, "  --stdout    Print the formatted config instead of rewriting the file\n")
+}
+
+// runFmt canonicalizes config files in place
+func runFmt(args []string) int {
+	var check, stdout bool
+	var files []string
+
+	for _, arg := range args {
+		switch arg {
+		case "--check":
+			check = true
+		case "--stdout":
+			stdout = true
+		case "-h", "--help":
+			printFmtUsage()
+			return 0
+		default:
+			if strings.HasPrefix(arg, "--") {
+				fmt.Fprintf(os.Stderr, "Unknown option: %s\n\n", arg)
+				printFmtUsage()
+				return 1
+			}
+			files = append(files, arg)
+		}
+	}
+
+	if len(files) == 0 {
+		files = []string{getConfigFile()}
+	}
+
+	exitCode := 0
+	for _, file := range files {
+		changed, err := formatFile(file, check, stdout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", file, err)
+			exitCode = 1
+			continue
+		}
+		if check && changed {
+			fmt.Println(file)
+			exitCode = 1
+		}
+	}
+
+	return exitCode
+}
+
+// formatFile formats a single config file and reports whether it changed
+func formatFile(path string, check, stdout bool) (bool, error) {
+	if format := config.FileFormat(path); format != config.FormatYAML {
+		return false, fmt.Errorf("only YAML config files can be formatted, not %s", format)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	formatted, err := config.Format(data)
+	if err != nil {
+		return false, err
+	}
+
+	changed := !bytes.Equal(data, formatted)
+	if check {
+		return changed, nil
+	}
+	if stdout {
+		_, err := os.Stdout.Write(formatted)
+		return changed, err
+	}
+	if !changed {
+		return false, nil
+	}
+
+	mode, uid, gid, err := filewriter.GetFileInfo(path)
+	if err != nil {
+		return false, err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false, err
+	}
+
+	// Replace atomically, keeping the permissions and ownership of the original file
+	writer := filewriter.NewWriter()
+	if err := writer.WriteBytes(context.Background(), filewriter.FileConfig{Path: abs, Mode: mode.Perm(), Owner: uid, Group: gid}, formatted); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
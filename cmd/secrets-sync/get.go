@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+func printGetUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: secrets-sync get [options] <mount>/<key>\n")
+	fmt.Fprintf(os.Stderr, "\nReads one secret with the address, TLS settings and credentials of the\n")
+	fmt.Fprintf(os.Stderr, "configured secret store and prints it to stdout: the value of --field, or\n")
+	fmt.Fprintf(os.Stderr, "all fields as a JSON object. The output contains secret values.\n")
+	fmt.Fprintf(os.Stderr, "\nOptions:\n")
+	fmt.Fprintf(os.Stderr, "  -c, --config <path>     Configuration file (default: as for the service)\n")
+	fmt.Fprintf(os.Stderr, "  --field <name>          Print only this field\n")
+	fmt.Fprintf(os.Stderr, "  --json                  Print the field as JSON, e.g. to tell \"1\" from 1\n")
+	fmt.Fprintf(os.Stderr, "  --kv-version <v1|v2>    KV engine version (default: secretDefaults.kvVersion or v2)\n")
+	fmt.Fprintf(os.Stderr, "  --version <n>           KV v2 version to read (default: latest)\n")
+	fmt.Fprintf(os.Stderr, "  --mount <path>          Mount path, if it contains slashes; the argument is then the key\n")
+	fmt.Fprintf(os.Stderr, "  --namespace <ns>        OpenBao namespace, / for root (default: secretStore.namespace)\n")
+	fmt.Fprintf(os.Stderr, "  --credentials <name>    Named credential set (default: secretDefaults.credentials)\n")
+	fmt.Fprintf(os.Stderr, "\nExamples:\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync get secret/app/database --field password\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync get --mount kv/team-a app/database --kv-version v1\n")
+}
+
+// getOptions are the options of the get subcommand
+type getOptions struct {
+	store   storeOptions
+	path    string
+	field   string
+	asJSON  bool
+	version int
+}
+
+// storeOptions select the mount, namespace and credentials the get and list
+// subcommands read with
+type storeOptions struct {
+	kvVersion   string
+	mountPath   string
+	namespace   string
+	credentials string
+}
+
+// parse takes the value of a store option, reporting false for other options
+func (o *storeOptions) parse(arg, value string) (bool, error) {
+	switch arg {
+	case "--kv-version":
+		if value != "v1" && value != "v2" {
+			return true, fmt.Errorf("--kv-version must be v1 or v2, got: %s", value)
+		}
+		o.kvVersion = value
+	case "--mount":
+		o.mountPath = strings.Trim(value, "/")
+	case "--namespace":
+		o.namespace = value
+	case "--credentials":
+		o.credentials = value
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// storeTarget is a path to read, resolved against the configuration, with a
+// client authenticated with its credential set
+type storeTarget struct {
+	client    *vault.Client
+	mountPath string
+	key       string
+	kvVersion string
+	namespace string
+}
+
+// openStore resolves location, <mount>/<key> or the key below --mount, and
+// authenticates a client with the configured store settings. The key may be
+// empty only if keyOptional is set.
+func openStore(ctx context.Context, cfg *config.Config, opts storeOptions, location string, keyOptional bool) (*storeTarget, error) {
+	if cfg.SecretStore.IsAzureKeyVault() {
+		return nil, fmt.Errorf("only Vault and OpenBao are supported, not secretStore type azureKeyVault")
+	}
+
+	target := &storeTarget{mountPath: opts.mountPath, key: strings.Trim(location, "/")}
+	if target.mountPath == "" {
+		target.mountPath, target.key, _ = strings.Cut(target.key, "/")
+	}
+	if target.mountPath == "" || (target.key == "" && !keyOptional) {
+		return nil, fmt.Errorf("expected <mount>/<key>, got: %s", location)
+	}
+
+	target.kvVersion = opts.kvVersion
+	if target.kvVersion == "" {
+		target.kvVersion = cfg.SecretDefaults.KVVersion
+	}
+	if target.kvVersion == "" {
+		target.kvVersion = "v2"
+	}
+
+	credentials := opts.credentials
+	if credentials == "" {
+		credentials = cfg.SecretDefaults.Credentials
+	}
+	creds, ok := cfg.SecretStore.GetCredentials(credentials)
+	if !ok {
+		return nil, fmt.Errorf("credentials %q not found in secretStore.credentials", credentials)
+	}
+
+	secret := config.Secret{Namespace: opts.namespace}
+	target.namespace = secret.ResolveNamespace(cfg.SecretStore.Namespace)
+
+	client, err := newClientFactory(cfg, config.LoadEnvConfig())(ctx, creds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate: %w", err)
+	}
+	target.client = client
+	return target, nil
+}
+
+// String names the path of a target in error messages
+func (t *storeTarget) String() string {
+	location := path.Join(t.mountPath, t.key)
+	if t.namespace == "" {
+		return location + " in root namespace"
+	}
+	return location + " in namespace " + t.namespace
+}
+
+// runGet prints a secret read with the configured client settings
+func runGet(args []string) int {
+	var opts getOptions
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			printGetUsage()
+			return 0
+		case "--json":
+			opts.asJSON = true
+			continue
+		}
+		if !strings.HasPrefix(arg, "-") {
+			if opts.path != "" {
+				fmt.Fprintf(os.Stderr, "Error: only one secret can be read at a time\n")
+				return 1
+			}
+			opts.path = arg
+			continue
+		}
+		if i+1 >= len(args) {
+			fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", arg)
+			return 1
+		}
+		value := args[i+1]
+		i++
+
+		if ok, err := opts.store.parse(arg, value); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		} else if ok {
+			continue
+		}
+		switch arg {
+		case "-c", "--config":
+			configFile = value
+		case "--field":
+			opts.field = value
+		case "--version":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				fmt.Fprintf(os.Stderr, "Error: --version must be a positive number, got: %s\n", value)
+				return 1
+			}
+			opts.version = n
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown option: %s\n\n", arg)
+			printGetUsage()
+			return 1
+		}
+	}
+
+	if opts.path == "" {
+		printGetUsage()
+		return 1
+	}
+
+	if err := get(opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// get reads the secret named by opts and prints it
+func get(opts getOptions) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	target, err := openStore(ctx, cfg, opts.store, opts.path, false)
+	if err != nil {
+		return err
+	}
+	if opts.version > 0 && target.kvVersion != "v2" {
+		return fmt.Errorf("--version requires KV v2")
+	}
+
+	var data vault.SecretData
+	if target.kvVersion == "v2" {
+		data, _, err = target.client.FetchSecretVersion(ctx, target.mountPath, target.key, target.namespace, opts.version)
+	} else {
+		data, err = target.client.FetchSecret(ctx, target.mountPath, target.key, target.kvVersion, target.namespace)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", target, err)
+	}
+
+	if opts.field == "" {
+		out, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode fields: %w", err)
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
+	value, ok := data[opts.field]
+	if !ok {
+		fields := make([]string, 0, len(data))
+		for name := range data {
+			fields = append(fields, name)
+		}
+		sort.Strings(fields)
+		return fmt.Errorf("field %q not found (fields: %s)", opts.field, strings.Join(fields, ", "))
+	}
+	if s, ok := value.(string); ok && !opts.asJSON {
+		fmt.Println(s)
+		return nil
+	}
+	out, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode field: %w", err)
+	}
+	fmt.Println(string(out))
+	return nil
+}
@@ -13,15 +13,31 @@ USAGE:
 
 COMMANDS:
     (none)      Run the secrets sync service (default)
-    init        Generate example configuration file
+    init        Generate example configuration file (YAML, JSON or TOML)
     validate    Validate configuration file
+    fmt         Rewrite configuration file in canonical style
     convert     Convert external-secrets YAML to secrets-sync format
+    import      Generate config from existing secret files and a Vault prefix
+    plan        Show file changes a sync would make (create/update/delete)
+    apply       Sync all secrets once and remove orphaned files
+    sync        Sync all secrets once and exit (for init containers and CI)
+    render      Print the files secrets would be written to, without writing
+    diff        Show how files on disk differ from the secrets, values masked
+    get         Print a secret read with the configured store settings (get <mount>/<key>)
+    list        List the secrets below a Vault path (list <mount>/<prefix>)
+    run         Run a command with secrets kept up to date, restarting it on change
+    bench       Load test against a built-in mock Vault
+    selftest    Check that auth, TLS, secrets and file permissions work on this host
+    doctor      Diagnose Vault connectivity, TLS, token TTL, policies, directories and clock
+    audit       Verify the hash chain of an audit log (audit verify <file>)
+    rollback    Restore the latest backup of the files of a secret (rollback <secret>)
     version     Show version information
-    isready     Check if service is ready (for healthchecks)
+    isready     Check if service is ready (for healthchecks), optionally with --max-age
+    completion  Print a shell completion script (completion bash|zsh|fish)
     help        Show this help message
 
 FLAGS:
-    -c, --config <path>  Path to configuration file
+    -c, --config <path>  Path to configuration file or directory
     -h, --help           Show this help message
 
 CONFIGURATION:
@@ -32,7 +48,9 @@ CONFIGURATION:
     4. /etc/secrets-sync/config.yaml (system-wide)
 
 ENVIRONMENT VARIABLES:
-    CONFIG_FILE              Path to configuration file
+    CONFIG_FILE              Path to configuration file or directory
+    CONFIG_URL              Fetch the config from https://, s3:// or vault:// instead
+    CONFIG_REFRESH_INTERVAL Re-fetch interval of CONFIG_URL, 0 to disable (default: 5m)
     VAULT_ADDR              Vault/OpenBao server address
     VAULT_TOKEN             Vault token for authentication
     VAULT_ROLE_ID           AppRole role ID
@@ -42,13 +60,58 @@ ENVIRONMENT VARIABLES:
     VAULT_SKIP_VERIFY       Skip TLS verification (insecure)
     VAULT_CLIENT_CERT       Path to client certificate (mTLS)
     VAULT_CLIENT_KEY        Path to client key (mTLS)
-    LOG_LEVEL               Log level (debug, info, warn, error)
+    VAULT_CLIENT_TIMEOUT    Deadline of one Vault request (default: 60s)
+    VAULT_DIAL_TIMEOUT      Deadline of connecting to Vault (default: 30s)
+    VAULT_KEEPALIVE         Interval of TCP keepalive probes (default: 30s)
+    VAULT_MAX_IDLE_CONNS    Idle connections to Vault kept open for reuse
+    VAULT_HTTP_PROXY        Proxy all Vault requests go through (default: HTTPS_PROXY)
+    VAULT_RATE_LIMIT        Vault requests per second of all clients together (default: 0, no limit)
+    VAULT_RATE_BURST        Vault requests allowed at once (default: VAULT_RATE_LIMIT rounded up)
+    LOG_LEVEL               Log level (debug, info, warn, error); SIGUSR1 toggles debug
     WATCH_CONFIG            Enable config hot reload (default: false)
+    MANIFEST_FILE           State manifest of managed files (default: disabled)
+    STATE_FILE              Sync times, versions and leases kept across restarts (default: disabled)
+    AUDIT_LOG               Hash-chained log of every file written, - for stdout (default: disabled)
+    CACHE_DIR               Encrypted cache for offline restarts (default: disabled)
+    CACHE_KEY_FILE          Cache key file, generated if missing (required with CACHE_DIR)
+    MAX_CONCURRENT_SYNCS    Secrets synced at the same time, 0 for no limit (default: 10)
+    MAX_SECRETS             Secrets a config may define, unless maxSecrets is set (default: 1000)
+    SYNC_JITTER             Maximum random offset spreading refreshes (default: 30s)
+    REFRESH_JITTER          Random share each refresh is moved by, up to 50% (default: 0%)
+    STARTUP_SPLAY           Maximum random delay of each secret's first sync (default: 0)
+    MAX_RETRIES             Retries of a Vault read within one sync (default: 3)
+    FAILURE_RETRIES         Retries of a failed sync before the next refresh (default: 5)
+    FAILURE_BACKOFF         Delay before the first retry, doubled for each further one (default: 30s)
+    FAILURE_ALERT_AFTER     Failed syncs in a row that raise an alert, 0 for never (default: 3)
+    SYNC_TIMEOUT            Deadline for syncing one secret, including retries (default: 5m)
+    MAX_STALENESS           Serve stale files at most this long while Vault is down (default: 0, no limit)
+    MEMORY_CACHE_TTL        Keep fetched data in memory this long to rewrite lost files during outages (default: 0, disabled)
+    DELETED_SECRET_ACTION   Files of secrets deleted in Vault: keep, delete, quarantine (default: keep)
+    QUARANTINE_DIR          Where quarantined files are moved (required with quarantine)
+    VERIFY_INTERVAL         How often managed files are checked for drift (default: 0, disabled)
+    VERIFY_REPAIR           Restore mode/ownership and resync modified files (default: true)
+    RESTORE_DELETED_FILES   Rewrite deleted or truncated files immediately (default: false)
+    RESTORE_MODIFIED_FILES  Also rewrite files modified out-of-band immediately (default: false)
+    FSYNC_WRITES            Flush files and directories to disk on every write, false for tmpfs (default: true)
+    REVOKE_LEASES_ON_SHUTDOWN  Revoke leases of database credentials on exit (default: true)
+    LEADER_LOCK_FILE        Lock file on a shared volume; only the holder writes (default: disabled)
+    LEADER_RETRY_INTERVAL   How often a standby tries to take the lock (default: 5s)
+    DIAGNOSTICS_DIR         Directory for SIGQUIT diagnostics snapshots (default: stderr)
+    SEAL_POLL_INTERVAL      Seal status check interval while paused for a sealed Vault (default: 10s)
+    DISABLE_MLOCK           Do not lock memory to keep secrets out of swap (default: false)
+    SANDBOX                 Landlock/seccomp self-sandboxing: strict, off (default: off)
+    RUN_AS_USER             UID to switch to after startup as root
+    RUN_AS_GROUP            GID to switch to (default: RUN_AS_USER)
+    KEEP_CHOWN_CAPS         Keep CAP_CHOWN/CAP_FOWNER after dropping root (default: false)
 
 METRICS:
     METRICS_ADDR            Metrics server listen address (default: 127.0.0.1)
     METRICS_PORT            Metrics server port (default: 8080, range: 1025-65535)
     ENABLE_METRICS          Enable metrics/health endpoints (default: true)
+    READY_POLICY            Secrets synced before ready: any, all or a percentage like 80% (default: any)
+    READY_MAX_AGE           Fail /ready and isready if a secret has not synced for this long (default: 0, no limit)
+    ENABLE_DIAGNOSTICS_API  Serve /debug/diagnostics (default: false)
+    ADMIN_TOKEN_FILE        Bearer token enabling POST /api/v1/sync[/<secret>], POST /api/v1/rollback/<secret> and PUT /loglevel (default: disabled)
 
 EXAMPLES:
     # Run with config file (flag)
@@ -63,23 +126,75 @@ EXAMPLES:
 
     # Generate example config
     secrets-sync init > config.yaml
+    secrets-sync init --format toml > config.toml
 
     # Validate config
     secrets-sync validate
     secrets-sync --config custom.yaml validate
+    secrets-sync validate --strict --format json   # CI: also read and render every secret
+
+    # Canonicalize config (or only check it in CI)
+    secrets-sync fmt
+    secrets-sync fmt --check config.yaml
+
+    # Review changes before applying them
+    MANIFEST_FILE=/var/lib/secrets-sync/manifest.json secrets-sync plan
+    MANIFEST_FILE=/var/lib/secrets-sync/manifest.json secrets-sync apply
+
+    # Review rendered files, from Vault or from fixture data
+    secrets-sync render --config config.yaml --secret db-credentials
+    secrets-sync render --config config.yaml --secret db-credentials --data fixture.json
+
+    # Check files on disk for drift before forcing a resync (exit 1 on drift)
+    secrets-sync diff --config config.yaml
+
+    # Write all secrets once, e.g. in an init container
+    secrets-sync --config /etc/secrets-sync/config.yaml sync
+
+    # Check the host before enabling the service
+    secrets-sync --config /etc/secrets-sync/config.yaml selftest
+    secrets-sync selftest --mock
+    secrets-sync --config /etc/secrets-sync/config.yaml doctor
+
+    # Enable shell completion for the current bash session
+    source <(secrets-sync completion bash)
+
+    # Measure throughput with 5000 secrets against a mock Vault
+    secrets-sync bench --secrets 5000 --interval 1s --duration 1m
+
+    # Record every secret write and check the record later
+    AUDIT_LOG=/var/log/secrets-sync/audit.log secrets-sync
+    secrets-sync audit verify /var/log/secrets-sync/audit.log
+
+    # Put back the previous files of a secret with keepBackups
+    secrets-sync rollback app-config
 
     # Check version
     secrets-sync version
 
     # Healthcheck
     secrets-sync isready
+    secrets-sync isready --max-age 2h
 
     # Convert external-secrets to secrets-sync format
     secrets-sync convert external-secret.yaml --mount-path devops
 
+    # Take mount, namespace and credentials from the referenced SecretStores
+    secrets-sync convert external-secret.yaml --store-file secret-store.yaml
+    secrets-sync convert external-secret.yaml --from-cluster
+
     # Convert with vault query (auto-detect field names)
     secrets-sync convert external-secret.yaml --query-vault
 
+    # Write one config file per ExternalSecret into a config directory
+    secrets-sync convert manifests/*.yaml --split --out /etc/secrets-sync/conf.d
+
+    # Convert a secrets-sync config to ExternalSecret manifests
+    secrets-sync convert --to external-secrets --namespace apps config.yaml
+
+    # Propose a config for files already on disk
+    secrets-sync import --dir /run/secrets --vault-prefix secret/apps > config.yaml
+
 For more information, see: https://github.com/ohauer/secrets-sync
 `)
 }
@@ -0,0 +1,447 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/filewriter"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+const (
+	// importMaxFileSize skips files too large to be rendered secrets
+	importMaxFileSize = 1 << 20
+	// importMinValueLength is the shortest value matched inside a file, so
+	// values like "1" or "true" do not match everywhere
+	importMinValueLength = 6
+)
+
+// ImportConfig holds import parameters
+type ImportConfig struct {
+	Dir             string
+	MountPath       string
+	Prefix          string // Path below the mount
+	KVVersion       string
+	Namespace       string
+	RefreshInterval time.Duration
+}
+
+// vaultField is one string field of a Vault secret
+type vaultField struct {
+	name  string
+	value string
+}
+
+// localFile is an existing file that may hold secret values
+type localFile struct {
+	path    string
+	content string
+	mode    os.FileMode
+	uid     int
+	gid     int
+}
+
+// importMatch binds a local file to the Vault secret it is rendered from
+type importMatch struct {
+	file     localFile
+	key      string
+	template string
+	others   []string // Other secrets the file matches equally well
+}
+
+func printImportUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: secrets-sync import --dir <dir> --vault-prefix <mount/path> [options]\n")
+	fmt.Fprintf(os.Stderr, "\nLists the secrets below a Vault prefix, matches them against the files in a\n")
+	fmt.Fprintf(os.Stderr, "directory by content and prints a config reproducing those files, with\n")
+	fmt.Fprintf(os.Stderr, "templates, modes and ownership taken from disk. Vault is addressed and\n")
+	fmt.Fprintf(os.Stderr, "authenticated with VAULT_ADDR and VAULT_TOKEN or VAULT_ROLE_ID/VAULT_SECRET_ID.\n")
+	fmt.Fprintf(os.Stderr, "\nOptions:\n")
+	fmt.Fprintf(os.Stderr, "  --dir <dir>                Directory with the existing secret files\n")
+	fmt.Fprintf(os.Stderr, "  --vault-prefix <path>      Mount and path to search, e.g. secret/apps\n")
+	fmt.Fprintf(os.Stderr, "  --kv-version <v1|v2>       KV version of the mount (default: v2)\n")
+	fmt.Fprintf(os.Stderr, "  --namespace <ns>           Vault/OpenBao namespace\n")
+	fmt.Fprintf(os.Stderr, "  --refresh-interval <dur>   Refresh interval of generated secrets (default: 30m)\n")
+	fmt.Fprintf(os.Stderr, "\nExample:\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync import --dir /run/secrets --vault-prefix secret/apps > config.yaml\n")
+}
+
+// runImport generates a config from files already on disk
+func runImport(args []string) int {
+	cfg := ImportConfig{
+		KVVersion:       "v2",
+		RefreshInterval: 30 * time.Minute,
+	}
+
+	var prefix string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		var value string
+		switch arg {
+		case "-h", "--help":
+			printImportUsage()
+			return 0
+		case "--dir", "--vault-prefix", "--kv-version", "--namespace", "--refresh-interval":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n\n", arg)
+				printImportUsage()
+				return 1
+			}
+			value = args[i+1]
+			i++
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown option: %s\n\n", arg)
+			printImportUsage()
+			return 1
+		}
+
+		switch arg {
+		case "--dir":
+			cfg.Dir = value
+		case "--vault-prefix":
+			prefix = value
+		case "--kv-version":
+			cfg.KVVersion = value
+		case "--namespace":
+			cfg.Namespace = value
+		case "--refresh-interval":
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --refresh-interval: %v\n", err)
+				return 1
+			}
+			cfg.RefreshInterval = d
+		}
+	}
+
+	if cfg.Dir == "" || prefix == "" {
+		printImportUsage()
+		return 1
+	}
+	if cfg.KVVersion != "v1" && cfg.KVVersion != "v2" {
+		fmt.Fprintf(os.Stderr, "Error: --kv-version must be v1 or v2\n")
+		return 1
+	}
+	mount, rest, _ := strings.Cut(strings.Trim(prefix, "/"), "/")
+	cfg.MountPath = mount
+	cfg.Prefix = rest
+
+	ctx := context.Background()
+	envCfg := config.LoadEnvConfig()
+	client, err := newImportClient(ctx, envCfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	keys, err := listVaultKeys(ctx, client, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	secrets := make(map[string][]vaultField, len(keys))
+	for _, key := range keys {
+		fields, err := fetchFields(ctx, client, cfg, key)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s/%s: %v\n", cfg.MountPath, key, err)
+			continue
+		}
+		secrets[key] = fields
+	}
+
+	files, err := readLocalFiles(cfg.Dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	var matches []importMatch
+	var unmatched []string
+	for _, file := range files {
+		match, ok := matchFile(file, keys, secrets)
+		if !ok {
+			unmatched = append(unmatched, file.path)
+			continue
+		}
+		if len(match.others) > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: %s also matches %s, using %s\n",
+				file.path, strings.Join(match.others, ", "), match.key)
+		}
+		matches = append(matches, match)
+	}
+
+	out, err := buildImportConfig(cfg, envCfg, matches)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Println("# Generated by secrets-sync import from existing files")
+	fmt.Printf("# Searched %s/%s (%d secrets) and %s (%d files)\n",
+		cfg.MountPath, cfg.Prefix, len(secrets), cfg.Dir, len(files))
+	fmt.Println("# Review templates: parts of a file not found in Vault are kept as literal text")
+	for _, path := range unmatched {
+		fmt.Printf("# Unmatched: %s\n", path)
+	}
+	fmt.Println()
+	fmt.Print(string(out))
+
+	fmt.Fprintf(os.Stderr, "Matched %d of %d files\n", len(matches), len(files))
+	return 0
+}
+
+// newImportClient creates a Vault client authenticated from the environment
+func newImportClient(ctx context.Context, envCfg *config.EnvConfig) (*vault.Client, error) {
+	if envCfg.VaultAddr == "" {
+		return nil, fmt.Errorf("VAULT_ADDR is required")
+	}
+
+	client, err := vault.NewClientWithConnection(envCfg.VaultAddr, newVaultTLSConfig(&config.Config{}, envCfg), newVaultConnection(&config.Config{}, envCfg))
+	if err != nil {
+		return nil, err
+	}
+
+	auth := vault.AuthConfig{Method: vault.AuthMethodToken, Token: envCfg.VaultToken}
+	if envCfg.VaultRoleID != "" && envCfg.VaultSecretID != "" {
+		auth = vault.AuthConfig{
+			Method:   vault.AuthMethodAppRole,
+			RoleID:   envCfg.VaultRoleID,
+			SecretID: envCfg.VaultSecretID,
+		}
+	}
+	if err := client.Authenticate(ctx, auth); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// listVaultKeys returns the sorted paths of all secrets below the prefix,
+// relative to the mount
+func listVaultKeys(ctx context.Context, client *vault.Client, cfg ImportConfig) ([]string, error) {
+	var keys []string
+	pending := []string{cfg.Prefix}
+	for len(pending) > 0 {
+		dir := pending[0]
+		pending = pending[1:]
+
+		entries, err := client.ListSecrets(ctx, cfg.MountPath, dir, cfg.KVVersion, cfg.Namespace)
+		if err != nil {
+			if dir == cfg.Prefix {
+				return nil, fmt.Errorf("failed to list %s/%s: %w", cfg.MountPath, dir, err)
+			}
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s/%s: %v\n", cfg.MountPath, dir, err)
+			continue
+		}
+		for _, entry := range entries {
+			path := strings.TrimPrefix(dir+"/"+entry, "/")
+			if strings.HasSuffix(entry, "/") {
+				pending = append(pending, strings.TrimSuffix(path, "/"))
+				continue
+			}
+			keys = append(keys, path)
+		}
+	}
+
+	// The prefix may name a single secret rather than a folder
+	if len(keys) == 0 && cfg.Prefix != "" {
+		keys = append(keys, cfg.Prefix)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// fetchFields returns the string fields of a secret, sorted by name
+func fetchFields(ctx context.Context, client *vault.Client, cfg ImportConfig, key string) ([]vaultField, error) {
+	data, err := client.FetchSecret(ctx, cfg.MountPath, key, cfg.KVVersion, cfg.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]vaultField, 0, len(data))
+	for name, value := range data {
+		if s, ok := value.(string); ok && s != "" {
+			fields = append(fields, vaultField{name: name, value: s})
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].name < fields[j].name })
+	return fields, nil
+}
+
+// readLocalFiles reads the regular files below dir, sorted by path
+func readLocalFiles(dir string) ([]localFile, error) {
+	var files []localFile
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		mode, uid, gid, err := filewriter.GetFileInfo(path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() > importMaxFileSize {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: larger than %d bytes\n", path, importMaxFileSize)
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		files = append(files, localFile{path: abs, content: string(content), mode: mode.Perm(), uid: uid, gid: gid})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	return files, nil
+}
+
+// matchFile finds the secret a file was rendered from. A file holding exactly
+// one field value wins over a file that embeds values in other text; among
+// embedding secrets, the one with the most fields found wins.
+func matchFile(file localFile, keys []string, secrets map[string][]vaultField) (importMatch, bool) {
+	var match importMatch
+	found := false
+	for _, key := range keys {
+		for _, f := range secrets[key] {
+			var template string
+			switch file.content {
+			case f.value:
+				template = fieldRef(f.name)
+			case f.value + "\n":
+				template = fieldRef(f.name) + "\n"
+			default:
+				continue
+			}
+			if found {
+				match.others = append(match.others, key+"#"+f.name)
+				continue
+			}
+			match = importMatch{file: file, key: key, template: template}
+			found = true
+		}
+	}
+	if found {
+		return match, true
+	}
+
+	// Literal braces would be read as template actions
+	if strings.Contains(file.content, "{{") {
+		return match, false
+	}
+
+	best := 0
+	for _, key := range keys {
+		var embedded []vaultField
+		for _, f := range secrets[key] {
+			if len(f.value) >= importMinValueLength && strings.Contains(file.content, f.value) {
+				embedded = append(embedded, f)
+			}
+		}
+		if len(embedded) == 0 || len(embedded) < best {
+			continue
+		}
+		if len(embedded) == best {
+			match.others = append(match.others, key)
+			continue
+		}
+
+		// Replace longer values first, so values containing others stay whole
+		sort.SliceStable(embedded, func(i, j int) bool { return len(embedded[i].value) > len(embedded[j].value) })
+		pairs := make([]string, 0, 2*len(embedded))
+		for _, f := range embedded {
+			pairs = append(pairs, f.value, fieldRef(f.name))
+		}
+		match = importMatch{
+			file:     file,
+			key:      key,
+			template: strings.NewReplacer(pairs...).Replace(file.content),
+		}
+		best = len(embedded)
+	}
+	return match, best > 0
+}
+
+// buildImportConfig renders matched files as a formatted config, one secret
+// per Vault key
+func buildImportConfig(cfg ImportConfig, envCfg *config.EnvConfig, matches []importMatch) ([]byte, error) {
+	out := config.Config{
+		SecretStore: config.SecretStore{
+			Address:    envCfg.VaultAddr,
+			Namespace:  cfg.Namespace,
+			AuthMethod: "token",
+			Token:      "${VAULT_TOKEN}",
+		},
+	}
+	if envCfg.VaultRoleID != "" && envCfg.VaultSecretID != "" {
+		out.SecretStore.AuthMethod = "approle"
+		out.SecretStore.Token = ""
+		out.SecretStore.RoleID = "${VAULT_ROLE_ID}"
+		out.SecretStore.SecretID = "${VAULT_SECRET_ID}"
+	}
+
+	byKey := make(map[string]int)
+	for _, m := range matches {
+		i, ok := byKey[m.key]
+		if !ok {
+			i = len(out.Secrets)
+			byKey[m.key] = i
+			out.Secrets = append(out.Secrets, config.Secret{
+				Name:            strings.ReplaceAll(m.key, "/", "-"),
+				Key:             m.key,
+				MountPath:       cfg.MountPath,
+				KVVersion:       cfg.KVVersion,
+				RefreshInterval: cfg.RefreshInterval,
+				Template:        config.Template{Data: make(map[string]string)},
+			})
+		}
+		secret := &out.Secrets[i]
+
+		name := filepath.Base(m.file.path)
+		for n := 2; ; n++ {
+			if _, taken := secret.Template.Data[name]; !taken {
+				break
+			}
+			name = fmt.Sprintf("%s-%d", filepath.Base(m.file.path), n)
+		}
+		secret.Template.Data[name] = m.template
+		file := config.File{
+			Path:     m.file.path,
+			Template: name,
+			Mode:     fmt.Sprintf("%04o", m.file.mode),
+		}
+		// Ownership is unknown on platforms without Unix file owners
+		if m.file.uid >= 0 {
+			file.Owner = strconv.Itoa(m.file.uid)
+			file.Group = strconv.Itoa(m.file.gid)
+		}
+		secret.Files = append(secret.Files, file)
+	}
+
+	data, err := yaml.Marshal(&out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	return config.Format(data)
+}
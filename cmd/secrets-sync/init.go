@@ -2,25 +2,93 @@ package main
 
 import (
 	"fmt"
+	"os"
+
+	"github.com/ohauer/secrets-sync/internal/config"
 )
 
-func printInitConfig() {
-	fmt.Print(`# Docker Secrets Sync Configuration
+func printInitUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: secrets-sync init [options]\n")
+	fmt.Fprintf(os.Stderr, "\nPrints an example configuration file.\n")
+	fmt.Fprintf(os.Stderr, "\nOptions:\n")
+	fmt.Fprintf(os.Stderr, "  --format <format>   Config format: yaml (default), json or toml;\n")
+	fmt.Fprintf(os.Stderr, "                      comments are kept for yaml only\n")
+	fmt.Fprintf(os.Stderr, "\nExamples:\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync init > config.yaml\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync init --format toml > config.toml\n")
+}
+
+// runInit prints the example configuration in the requested format
+func runInit(args []string) int {
+	format := config.FormatYAML
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			printInitUsage()
+			return 0
+		case "--format":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", arg)
+				return 1
+			}
+			format = args[i+1]
+			i++
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown option: %s\n\n", arg)
+			printInitUsage()
+			return 1
+		}
+	}
+	if format != config.FormatYAML && format != config.FormatJSON && format != config.FormatTOML {
+		fmt.Fprintf(os.Stderr, "Error: unsupported format: %s (supported: yaml, json, toml)\n", format)
+		return 1
+	}
+
+	out, err := config.Encode([]byte(initConfig), format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if _, err := os.Stdout.Write(out); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// initConfig is the example configuration printed by init
+const initConfig = `# Docker Secrets Sync Configuration
 # See https://github.com/ohauer/secrets-sync for full documentation
 
 secretStore:
   # Vault/OpenBao server address
   address: "https://vault.example.com"
 
-  # Authentication method: token or approle
+  # Authentication method: token, tokenFile, approle, kubernetes or cert
   authMethod: "token"
 
   # Token authentication (use environment variable: VAULT_TOKEN)
   token: "${VAULT_TOKEN}"
 
+  # Token file authentication, e.g. a Vault Agent sink (uncomment if using tokenFile)
+  # tokenFile: "/run/vault-agent/token"
+
   # AppRole authentication (uncomment if using approle)
   # roleId: "${VAULT_ROLE_ID}"
   # secretId: "${VAULT_SECRET_ID}"
+  # secretIdFile: "/run/secrets/secret-id"  # instead of secretId
+  # secretIdWrapped: true                    # secret ID is a response-wrapping token
+
+  # Kubernetes authentication (uncomment if running in a pod)
+  # kubernetesRole: "secrets-sync"
+  # kubernetesTokenPath: "/var/run/secrets/kubernetes.io/serviceaccount/token"
+  # kubernetesMountPath: "kubernetes"
+
+  # TLS certificate authentication (uncomment if using cert, needs tlsClientCert and tlsClientKey)
+  # certRole: "secrets-sync"
+  # certMountPath: "cert"
 
   # OpenBao namespace (optional, global default for all secrets)
   # namespace: "team-a"
@@ -42,6 +110,13 @@ secretStore:
   # tlsClientCert: "/certs/client.pem"     # Client certificate (mTLS)
   # tlsClientKey: "/certs/client-key.pem"  # Client key (mTLS)
 
+# Defaults inherited by every secret unless it sets the field (optional)
+# secretDefaults:
+#   mountPath: "secret"
+#   kvVersion: "v2"
+#   refreshInterval: "1h"
+#   mode: "0600"
+
 # Secret Configuration
 # Each secret must specify:
 #   - key: Path to the secret in Vault (e.g., "app/database/credentials")
@@ -63,9 +138,9 @@ secretStore:
 #   - If not specified, uses default credentials from secretStore
 #
 # Template mapping:
-#   - First key in template.data -> First file in files list
-#   - Second key in template.data -> Second file in files list
+#   - Each file names the template.data key it is rendered from with "template"
 #   - The key names are just labels; actual file paths come from the files list
+#   - Files without "template" are bound by position to the sorted keys (deprecated)
 
 secrets:
   # Example: TLS certificate from KV v2
@@ -74,6 +149,7 @@ secrets:
     mountPath: "secret"
     kvVersion: "v2"
     refreshInterval: "30m"
+    # syncTimeout: "1m"  # Optional: override SYNC_TIMEOUT (default: 5m)
     # namespace: ""  # Optional: override global namespace
     template:
       data:
@@ -81,8 +157,10 @@ secrets:
         tls.key: '{{ .tlsKey }}'   # -> /secrets/tls.key
     files:
       - path: "/secrets/tls.crt"
+        template: "tls.crt"
         mode: "0644"
       - path: "/secrets/tls.key"
+        template: "tls.key"
         mode: "0600"
 
   # Example: Database credentials from KV v2
@@ -97,8 +175,10 @@ secrets:
         password: '{{ .password }}'  # -> /secrets/db-password
     files:
       - path: "/secrets/db-username"
+        template: "username"
         mode: "0600"
       - path: "/secrets/db-password"
+        template: "password"
         mode: "0600"
 
   # Example: API keys from KV v1 (legacy)
@@ -113,8 +193,9 @@ secrets:
         apiSecret: '{{ .apiSecret }}' # -> /secrets/api-secret
     files:
       - path: "/secrets/api-key"
+        template: "apiKey"
         mode: "0600"
       - path: "/secrets/api-secret"
+        template: "apiSecret"
         mode: "0600"
-`)
-}
+`
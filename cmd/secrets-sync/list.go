@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+)
+
+func printListUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: secrets-sync list [options] <mount>[/<prefix>]\n")
+	fmt.Fprintf(os.Stderr, "\nLists the secrets below a path, recursively, with the address, TLS settings\n")
+	fmt.Fprintf(os.Stderr, "and credentials of the configured secret store. Needs the list capability,\n")
+	fmt.Fprintf(os.Stderr, "on <mount>/metadata/<prefix> for KV v2. Secret values are not read.\n")
+	fmt.Fprintf(os.Stderr, "\nOptions:\n")
+	fmt.Fprintf(os.Stderr, "  -c, --config <path>     Configuration file (default: as for the service)\n")
+	fmt.Fprintf(os.Stderr, "  --depth <n>             Folder levels to descend into, 0 for all (default: 0)\n")
+	fmt.Fprintf(os.Stderr, "  --tree                  Print an indented tree instead of one key per line\n")
+	fmt.Fprintf(os.Stderr, "  --stanzas               Print a secrets: block with one secret per key\n")
+	fmt.Fprintf(os.Stderr, "  --wildcard              Print a secrets: block with one wildcard secret for the path\n")
+	fmt.Fprintf(os.Stderr, "  --dir <path>            Directory the printed secrets write to (default: /secrets)\n")
+	fmt.Fprintf(os.Stderr, "  --kv-version <v1|v2>    KV engine version (default: secretDefaults.kvVersion or v2)\n")
+	fmt.Fprintf(os.Stderr, "  --mount <path>          Mount path, if it contains slashes; the argument is then the prefix\n")
+	fmt.Fprintf(os.Stderr, "  --namespace <ns>        OpenBao namespace, / for root (default: secretStore.namespace)\n")
+	fmt.Fprintf(os.Stderr, "  --credentials <name>    Named credential set (default: secretDefaults.credentials)\n")
+	fmt.Fprintf(os.Stderr, "\nExamples:\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync list secret/app --tree\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync list secret/app/configs --depth 1 --wildcard --dir /run/secrets\n")
+}
+
+// listOptions are the options of the list subcommand
+type listOptions struct {
+	store    storeOptions
+	path     string
+	depth    int
+	tree     bool
+	stanzas  bool
+	wildcard bool
+	dir      string
+}
+
+// runList prints the secrets below a Vault path
+func runList(args []string) int {
+	opts := listOptions{dir: "/secrets"}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			printListUsage()
+			return 0
+		case "--tree":
+			opts.tree = true
+			continue
+		case "--stanzas":
+			opts.stanzas = true
+			continue
+		case "--wildcard":
+			opts.wildcard = true
+			continue
+		}
+		if !strings.HasPrefix(arg, "-") {
+			if opts.path != "" {
+				fmt.Fprintf(os.Stderr, "Error: only one path can be listed at a time\n")
+				return 1
+			}
+			opts.path = arg
+			continue
+		}
+		if i+1 >= len(args) {
+			fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", arg)
+			return 1
+		}
+		value := args[i+1]
+		i++
+
+		if ok, err := opts.store.parse(arg, value); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		} else if ok {
+			continue
+		}
+		switch arg {
+		case "-c", "--config":
+			configFile = value
+		case "--depth":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				fmt.Fprintf(os.Stderr, "Error: --depth must be 0 or more, got: %s\n", value)
+				return 1
+			}
+			opts.depth = n
+		case "--dir":
+			if !filepath.IsAbs(value) {
+				fmt.Fprintf(os.Stderr, "Error: --dir must be absolute, got: %s\n", value)
+				return 1
+			}
+			opts.dir = value
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown option: %s\n\n", arg)
+			printListUsage()
+			return 1
+		}
+	}
+
+	if opts.path == "" && opts.store.mountPath == "" {
+		printListUsage()
+		return 1
+	}
+	if opts.tree && (opts.stanzas || opts.wildcard) || opts.stanzas && opts.wildcard {
+		fmt.Fprintf(os.Stderr, "Error: only one of --tree, --stanzas and --wildcard can be used\n")
+		return 1
+	}
+
+	if err := list(opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// list lists the path named by opts and prints it in the selected form
+func list(opts listOptions) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	target, err := openStore(ctx, cfg, opts.store, opts.path, true)
+	if err != nil {
+		return err
+	}
+
+	if opts.wildcard {
+		return printWildcardStanza(cfg, opts, target)
+	}
+
+	entries, err := walkSecrets(ctx, target, opts.depth)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Fprintf(os.Stderr, "No secrets below %s\n", target)
+		return nil
+	}
+
+	switch {
+	case opts.tree:
+		printTree(target, entries)
+	case opts.stanzas:
+		return printKeyStanzas(cfg, opts, target, entries)
+	default:
+		for _, entry := range entries {
+			fmt.Println(joinKey(target.key, entry))
+		}
+	}
+	return nil
+}
+
+// walkSecrets lists the target recursively and returns the keys found
+// relative to it, sorted. Folders are returned, ending in a slash, only where
+// depth stopped the walk. Folders that cannot be listed are skipped with a
+// warning.
+func walkSecrets(ctx context.Context, target *storeTarget, depth int) ([]string, error) {
+	type folder struct {
+		path  string
+		level int
+	}
+
+	var entries []string
+	pending := []folder{{level: 1}}
+	for len(pending) > 0 {
+		dir := pending[0]
+		pending = pending[1:]
+
+		listed, err := target.client.ListSecrets(ctx, target.mountPath, joinKey(target.key, dir.path), target.kvVersion, target.namespace)
+		if err != nil {
+			if dir.path == "" {
+				return nil, fmt.Errorf("%s: %w", target, err)
+			}
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: %v\n", joinKey(target.key, dir.path), err)
+			continue
+		}
+		for _, entry := range listed {
+			if !strings.HasSuffix(entry, "/") {
+				entries = append(entries, dir.path+entry)
+				continue
+			}
+			if depth > 0 && dir.level >= depth {
+				entries = append(entries, dir.path+entry)
+				continue
+			}
+			pending = append(pending, folder{path: dir.path + entry, level: dir.level + 1})
+		}
+	}
+
+	sort.Strings(entries)
+	return entries, nil
+}
+
+// printTree prints entries as an indented tree below the target
+func printTree(target *storeTarget, entries []string) {
+	fmt.Println(strings.TrimSuffix(joinKey(target.mountPath, target.key), "/") + "/")
+
+	var printed []string
+	for _, entry := range entries {
+		parts := strings.SplitAfter(entry, "/")
+		if parts[len(parts)-1] == "" {
+			parts = parts[:len(parts)-1]
+		}
+		for level, part := range parts {
+			if level < len(printed) && printed[level] == part {
+				continue
+			}
+			printed = append(printed[:level], part)
+			fmt.Printf("%s%s\n", strings.Repeat("  ", level+1), part)
+		}
+	}
+}
+
+// printKeyStanzas prints one secret per key, writing all of its fields as
+// a JSON object to <dir>/<key>.json
+func printKeyStanzas(cfg *config.Config, opts listOptions, target *storeTarget, entries []string) error {
+	var secrets []map[string]interface{}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry, "/") {
+			continue
+		}
+		key := joinKey(target.key, entry)
+		secret := newStanza(cfg, opts, target, strings.ReplaceAll(key, "/", "-"), key)
+		secret["files"] = []map[string]string{{
+			"path":   filepath.Join(opts.dir, filepath.FromSlash(key)+".json"),
+			"format": "json",
+			"mode":   "0600",
+		}}
+		secrets = append(secrets, secret)
+	}
+	return printStanzas(secrets)
+}
+
+// printWildcardStanza prints one secret syncing every key below the target
+// into its own subdirectory of dir, recursively unless depth is 1
+func printWildcardStanza(cfg *config.Config, opts listOptions, target *storeTarget) error {
+	pattern := "**"
+	if opts.depth == 1 {
+		pattern = "*"
+	}
+	name := strings.ReplaceAll(joinKey(target.mountPath, target.key), "/", "-")
+	secret := newStanza(cfg, opts, target, name, joinKey(target.key, pattern))
+	secret["directory"] = map[string]string{
+		"path": filepath.Join(opts.dir, filepath.FromSlash(target.key)),
+		"mode": "0600",
+	}
+	return printStanzas([]map[string]interface{}{secret})
+}
+
+// newStanza returns the fields of a secret reading key from the target:
+// those given on the command line and not inherited from secretDefaults
+func newStanza(cfg *config.Config, opts listOptions, target *storeTarget, name, key string) map[string]interface{} {
+	secret := map[string]interface{}{
+		"name": name,
+		"key":  key,
+	}
+	if cfg.SecretDefaults.MountPath != target.mountPath {
+		secret["mountPath"] = target.mountPath
+	}
+	if cfg.SecretDefaults.KVVersion != target.kvVersion {
+		secret["kvVersion"] = target.kvVersion
+	}
+	if opts.store.namespace != "" {
+		secret["namespace"] = opts.store.namespace
+	}
+	if opts.store.credentials != "" {
+		secret["credentials"] = opts.store.credentials
+	}
+	if cfg.SecretDefaults.RefreshInterval == 0 {
+		secret["refreshInterval"] = "30m"
+	}
+	return secret
+}
+
+// printStanzas prints secrets as a formatted secrets: block, with their
+// keys in the order of the config
+func printStanzas(secrets []map[string]interface{}) error {
+	data, err := yaml.Marshal(map[string]interface{}{"secrets": secrets})
+	if err != nil {
+		return fmt.Errorf("failed to encode secrets: %w", err)
+	}
+	formatted, err := config.Format(data)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(formatted)
+	return err
+}
+
+// joinKey joins a prefix and a path below it, either of which may be empty
+func joinKey(prefix, path string) string {
+	if prefix == "" {
+		return path
+	}
+	if path == "" {
+		return prefix
+	}
+	return prefix + "/" + path
+}
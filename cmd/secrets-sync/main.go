@@ -1,19 +1,34 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/ohauer/secrets-sync/internal/audit"
+	"github.com/ohauer/secrets-sync/internal/azure"
+	"github.com/ohauer/secrets-sync/internal/cache"
 	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/diagnostics"
 	"github.com/ohauer/secrets-sync/internal/filewriter"
 	"github.com/ohauer/secrets-sync/internal/health"
+	"github.com/ohauer/secrets-sync/internal/leader"
 	"github.com/ohauer/secrets-sync/internal/logger"
+	"github.com/ohauer/secrets-sync/internal/memlock"
 	"github.com/ohauer/secrets-sync/internal/metrics"
+	"github.com/ohauer/secrets-sync/internal/notify"
+	"github.com/ohauer/secrets-sync/internal/privdrop"
+	"github.com/ohauer/secrets-sync/internal/remoteconfig"
+	"github.com/ohauer/secrets-sync/internal/sandbox"
 	"github.com/ohauer/secrets-sync/internal/shutdown"
+	"github.com/ohauer/secrets-sync/internal/state"
 	"github.com/ohauer/secrets-sync/internal/syncer"
 	"github.com/ohauer/secrets-sync/internal/tracing"
 	"github.com/ohauer/secrets-sync/internal/vault"
@@ -46,14 +61,45 @@ func main() {
 			printVersion()
 			os.Exit(0)
 		case "init":
-			printInitConfig()
-			os.Exit(0)
+			os.Exit(runInit(args[1:]))
 		case "validate":
-			os.Exit(runValidate())
+			os.Exit(runValidate(args[1:]))
+		case "fmt":
+			os.Exit(runFmt(args[1:]))
 		case "convert":
 			os.Exit(runConvert(args[1:]))
+		case "import":
+			os.Exit(runImport(args[1:]))
+		case "plan":
+			os.Exit(runPlan(false))
+		case "apply":
+			os.Exit(runPlan(true))
+		case "sync":
+			os.Exit(runSync(args[1:]))
+		case "render":
+			os.Exit(runRender(args[1:]))
+		case "diff":
+			os.Exit(runDiff(args[1:]))
+		case "get":
+			os.Exit(runGet(args[1:]))
+		case "list":
+			os.Exit(runList(args[1:]))
+		case "run":
+			os.Exit(runChild(args[1:]))
 		case "isready":
-			os.Exit(isReady())
+			os.Exit(isReady(args[1:]))
+		case "bench":
+			os.Exit(runBench(args[1:]))
+		case "selftest":
+			os.Exit(runSelftest(args[1:]))
+		case "doctor":
+			os.Exit(runDoctor(args[1:]))
+		case "completion":
+			os.Exit(runCompletion(args[1:]))
+		case "audit":
+			os.Exit(runAudit(args[1:]))
+		case "rollback":
+			os.Exit(runRollback(args[1:]))
 		default:
 			fmt.Fprintf(os.Stderr, "Unknown command: %s\n", cmd)
 			printUsage()
@@ -92,8 +138,35 @@ func getConfigFile() string {
 	return "./config.yaml"
 }
 
+// loadConfig loads the configuration from CONFIG_URL if set, otherwise
+// from the config file
+func loadConfig(ctx context.Context) (*config.Config, error) {
+	if rawURL := os.Getenv("CONFIG_URL"); rawURL != "" {
+		source, err := remoteconfig.New(rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("CONFIG_URL: %w", err)
+		}
+		return source.Load(ctx)
+	}
+	return config.Load(ctx, getConfigFile())
+}
+
+// configField names where the configuration is read from in log entries:
+// the remote source with any password redacted, or the absolute config path
+func configField(remote *remoteconfig.Source, configPath string) zap.Field {
+	if remote != nil {
+		return zap.String("config_url", remote.String())
+	}
+	absConfigPath, err := filepath.Abs(configPath)
+	if err != nil {
+		absConfigPath = configPath
+	}
+	return zap.String("config_file", absConfigPath)
+}
+
 func run() error {
 	envCfg := config.LoadEnvConfig()
+	startTime := time.Now()
 	configPath := getConfigFile()
 
 	if err := logger.Init(envCfg.LogLevel); err != nil {
@@ -101,6 +174,20 @@ func run() error {
 	}
 	defer logger.Sync()
 
+	// Keep fetched secrets out of swap, mirroring Vault's disable_mlock semantics
+	memoryLocked := false
+	if envCfg.DisableMlock {
+		logger.Info("memory locking disabled")
+	} else if err := memlock.Lock(); err != nil {
+		logger.Warn("failed to lock memory, secrets may be swapped to disk "+
+			"(grant CAP_IPC_LOCK or set DISABLE_MLOCK=true)",
+			zap.Error(err),
+		)
+	} else {
+		memoryLocked = true
+		logger.Info("process memory locked")
+	}
+
 	// Log working directory for relative path resolution
 	workDir, err := os.Getwd()
 	if err != nil {
@@ -108,19 +195,56 @@ func run() error {
 		workDir = "unknown"
 	}
 
-	// Resolve config path to absolute for logging
-	absConfigPath, err := filepath.Abs(configPath)
-	if err != nil {
-		absConfigPath = configPath
+	// A remote config takes precedence over the config file
+	var remoteSource *remoteconfig.Source
+	if envCfg.ConfigURL != "" {
+		if remoteSource, err = remoteconfig.New(envCfg.ConfigURL); err != nil {
+			return fmt.Errorf("CONFIG_URL: %w", err)
+		}
+	}
+	loadCfg := func(ctx context.Context) (*config.Config, error) {
+		if remoteSource != nil {
+			return remoteSource.Load(ctx)
+		}
+		return config.Load(ctx, configPath)
 	}
 
 	logger.Info("starting secrets-sync",
-		zap.String("config_file", absConfigPath),
+		configField(remoteSource, configPath),
 		zap.String("working_directory", workDir),
 		zap.Bool("watch_config", envCfg.WatchConfig),
 	)
 
-	cfg, err := config.Load(configPath)
+	sandboxMode, err := sandbox.ParseMode(envCfg.Sandbox)
+	if err != nil {
+		return err
+	}
+
+	deletionPolicy, err := syncer.ParseDeletionPolicy(envCfg.DeletedSecretAction)
+	if err != nil {
+		return fmt.Errorf("DELETED_SECRET_ACTION: %w", err)
+	}
+	if deletionPolicy == syncer.DeletionQuarantine && envCfg.QuarantineDir == "" {
+		return fmt.Errorf("QUARANTINE_DIR is required when DELETED_SECRET_ACTION is quarantine")
+	}
+
+	refreshJitter, err := config.ParseJitter(envCfg.RefreshJitter)
+	if err != nil {
+		return fmt.Errorf("REFRESH_JITTER: %w", err)
+	}
+
+	readyPolicy, err := health.ParseReadyPolicy(envCfg.ReadyPolicy)
+	if err != nil {
+		return fmt.Errorf("READY_POLICY: %w", err)
+	}
+
+	if envCfg.VaultHTTPProxy != "" {
+		if err := config.ValidateProxyURL(envCfg.VaultHTTPProxy); err != nil {
+			return fmt.Errorf("VAULT_HTTP_PROXY: %w", err)
+		}
+	}
+
+	cfg, err := loadCfg(context.Background())
 	if err != nil {
 		return err
 	}
@@ -128,6 +252,55 @@ func run() error {
 	logger.Info("configuration loaded",
 		zap.Int("secret_count", len(cfg.Secrets)),
 	)
+	warnConfig(cfg)
+	redactConfigSecrets(cfg)
+
+	outputDirs := outputDirectories(cfg)
+
+	// Open the encrypted cache before dropping privileges, so the key file
+	// can live somewhere only root can read
+	var secretCache *cache.Cache
+	if envCfg.CacheDir != "" {
+		if envCfg.CacheKeyFile == "" {
+			return fmt.Errorf("CACHE_KEY_FILE is required when CACHE_DIR is set")
+		}
+		key, err := cache.LoadKeyFile(envCfg.CacheKeyFile)
+		if err != nil {
+			return err
+		}
+		secretCache, err = cache.New(envCfg.CacheDir, key)
+		memlock.Zero(key)
+		if err != nil {
+			return err
+		}
+		logger.Info("encrypted secret cache enabled", zap.String("cache_dir", envCfg.CacheDir))
+	}
+
+	// Read the admin API token before dropping privileges too
+	var adminToken string
+	if envCfg.AdminTokenFile != "" {
+		if adminToken, err = loadAdminToken(envCfg.AdminTokenFile); err != nil {
+			return err
+		}
+		logger.RegisterSecret(adminToken)
+	}
+
+	// Open the audit log before dropping privileges as well, so it can live
+	// in a directory only root can write to
+	var auditLog *audit.Log
+	if envCfg.AuditLog != "" {
+		if auditLog, err = audit.Open(envCfg.AuditLog); err != nil {
+			return err
+		}
+		defer func() { _ = auditLog.Close() }()
+	}
+
+	// Drop root once the directories the service writes to are prepared
+	if envCfg.RunAsUser != "" {
+		if err := dropPrivileges(envCfg, outputDirs, memoryLocked); err != nil {
+			return err
+		}
+	}
 
 	// Initialize tracing if enabled
 	var tracingShutdown func()
@@ -141,104 +314,162 @@ func run() error {
 		}
 	}
 
-	// Create Vault client with TLS configuration
-	tlsConfig := &vault.TLSConfig{
-		CACert:     cfg.SecretStore.TLSCACert,
-		CAPath:     cfg.SecretStore.TLSCAPath,
-		ClientCert: cfg.SecretStore.TLSClientCert,
-		ClientKey:  cfg.SecretStore.TLSClientKey,
-		SkipVerify: cfg.SecretStore.TLSSkipVerify,
-	}
+	clientFactory := newClientFactory(cfg, envCfg)
+	azureFactory := newAzureClientFactory(cfg)
 
-	// Override with environment variables if set
-	if envCfg.VaultCACert != "" {
-		tlsConfig.CACert = envCfg.VaultCACert
-	}
-	if envCfg.VaultCAPath != "" {
-		tlsConfig.CAPath = envCfg.VaultCAPath
+	// Create default client to verify connectivity
+	defaultCreds := cfg.SecretStore.GetDefaultCredentials()
+	storeName := "vault"
+	connect := func(ctx context.Context) error {
+		_, err := clientFactory(ctx, defaultCreds)
+		return err
 	}
-	if envCfg.VaultClientCert != "" {
-		tlsConfig.ClientCert = envCfg.VaultClientCert
+	if cfg.SecretStore.IsAzureKeyVault() {
+		storeName = "azure key vault"
+		connect = func(ctx context.Context) error {
+			_, err := azureFactory(ctx, defaultCreds)
+			return err
+		}
 	}
-	if envCfg.VaultClientKey != "" {
-		tlsConfig.ClientKey = envCfg.VaultClientKey
+	var waitStore bool // Syncing starts once the store is reachable
+	if err := connect(context.Background()); err != nil {
+		switch {
+		case secretCache != nil:
+			// With a cache, files can still be restored while the store is unreachable
+			logger.Warn(storeName+" unavailable at startup, serving secrets from cache",
+				zap.String("address", cfg.SecretStore.Address),
+				zap.Error(err),
+			)
+		case cfg.StartupMode == config.StartupModeWait:
+			logger.Warn(storeName+" unavailable at startup, waiting for it before syncing",
+				zap.String("address", cfg.SecretStore.Address),
+				zap.Error(err),
+			)
+			waitStore = true
+		default:
+			return err
+		}
+	} else {
+		logger.Info("authenticated to "+storeName,
+			zap.String("address", cfg.SecretStore.Address),
+			zap.String("auth_method", cfg.SecretStore.AuthMethod),
+		)
 	}
-	if envCfg.VaultSkipVerify {
-		tlsConfig.SkipVerify = true
+
+	// Warn if using HTTP (insecure)
+	if strings.HasPrefix(cfg.SecretStore.Address, "http://") &&
+		!strings.Contains(cfg.SecretStore.Address, "localhost") &&
+		!strings.Contains(cfg.SecretStore.Address, "127.0.0.1") {
+		logger.Warn("using insecure HTTP connection to Vault - use HTTPS in production",
+			zap.String("address", cfg.SecretStore.Address),
+		)
 	}
 
-	// Create client factory for on-demand client creation
-	clientFactory := func(creds config.CredentialSet) (*vault.Client, error) {
-		client, err := vault.NewClientWithTLS(cfg.SecretStore.Address, tlsConfig)
+	// Create syncer with client factory
+	secretSyncer := syncer.NewSecretSyncer(clientFactory, newRetryConfig(envCfg)).WithAzure(azureFactory)
+
+	// Record every written file when a manifest is configured
+	if envCfg.ManifestFile != "" {
+		manifest, err := state.LoadManifest(envCfg.ManifestFile)
 		if err != nil {
-			return nil, err
+			return err
 		}
+		secretSyncer.WithManifest(manifest)
+		logger.Info("state manifest enabled", zap.String("manifest_file", envCfg.ManifestFile))
+	}
+	if secretCache != nil {
+		secretSyncer.WithCache(secretCache)
+	}
+	secretSyncer.WithMemoryCache(envCfg.MemoryCacheTTL)
+	secretSyncer.WithMaxStaleness(envCfg.MaxStaleness)
+	secretSyncer.WithSyncTimeout(envCfg.SyncTimeout)
+	secretSyncer.WithFsync(envCfg.FsyncWrites)
+	secretSyncer.WithAllowedPaths(cfg.AllowedOutputPaths)
+	secretSyncer.WithDeletionPolicy(deletionPolicy, envCfg.QuarantineDir)
+	secretSyncer.WithFileObserver(metrics.RecordFileWrite)
+	secretSyncer.WithPhaseObserver(metrics.RecordSyncPhase)
+	secretSyncer.WithHashObserver(logger.RegisterContentHash)
 
-		// Set up circuit breaker
-		client.WithCircuitBreaker(
-			vault.BreakerConfig{
-				MaxRequests: uint32(envCfg.CircuitBreakerMaxReqs),
-				Interval:    envCfg.CircuitBreakerInterval,
-				Timeout:     envCfg.CircuitBreakerTimeout,
-			},
-			func(from, to string) {
-				logger.Info("circuit breaker state changed",
-					zap.String("from", from),
-					zap.String("to", to),
-				)
-				metrics.SetCircuitBreakerState("vault-client", to)
-			},
+	// Continue from what the previous run synced
+	if envCfg.StateFile != "" {
+		stateFile, err := loadStateFile(envCfg.StateFile, cfg)
+		if err != nil {
+			return err
+		}
+		secretSyncer.WithStateFile(stateFile)
+		logger.Info("state file enabled",
+			zap.String("state_file", envCfg.StateFile),
+			zap.Int("secrets_restored", len(stateFile.Names())),
 		)
+	}
 
-		// Authenticate with provided credentials
-		authConfig := vault.AuthConfig{
-			Method:   vault.AuthMethod(creds.AuthMethod),
-			Token:    creds.Token,
-			RoleID:   creds.RoleID,
-			SecretID: creds.SecretID,
+	// Rewrite deleted, truncated or modified files right away instead of at
+	// the next refresh
+	var fileGuard *syncer.FileGuard
+	if envCfg.RestoreDeletedFiles || envCfg.RestoreModifiedFiles {
+		fileGuard, err = syncer.NewFileGuard(reportRestore)
+		if err != nil {
+			return err
 		}
-
-		if err := client.Authenticate(authConfig); err != nil {
-			return nil, err
+		if envCfg.RestoreModifiedFiles {
+			fileGuard.WithContentCheck()
 		}
-
-		return client, nil
+		fileGuard.Start()
+		defer fileGuard.Stop()
+		secretSyncer.WithFileGuard(fileGuard)
+		logger.Info("restoring deleted files enabled", zap.Bool("modified_files", envCfg.RestoreModifiedFiles))
 	}
 
-	// Create default client to verify connectivity
-	defaultCreds := cfg.SecretStore.GetDefaultCredentials()
-	_, err = clientFactory(defaultCreds)
-	if err != nil {
-		return err
+	// Record every file written with secret material
+	if auditLog != nil {
+		recordWrites(secretSyncer, auditLog)
+		logger.Info("audit log enabled", zap.String("audit_log", envCfg.AuditLog))
 	}
 
-	logger.Info("authenticated to vault",
-		zap.String("address", cfg.SecretStore.Address),
-		zap.String("auth_method", cfg.SecretStore.AuthMethod),
-	)
-
-	// Warn if using HTTP (insecure)
-	if strings.HasPrefix(cfg.SecretStore.Address, "http://") &&
-		!strings.Contains(cfg.SecretStore.Address, "localhost") &&
-		!strings.Contains(cfg.SecretStore.Address, "127.0.0.1") {
-		logger.Warn("using insecure HTTP connection to Vault - use HTTPS in production",
-			zap.String("address", cfg.SecretStore.Address),
+	resultStore := syncer.NewStateStore()
+	metrics.SetVaultSealed(false)
+	scheduler := syncer.NewScheduler(secretSyncer).
+		WithStateStore(resultStore).
+		WithMaxConcurrentSyncs(envCfg.MaxConcurrentSyncs).
+		WithJitter(envCfg.SyncJitter).
+		WithRefreshJitter(refreshJitter).
+		WithStartupSplay(envCfg.StartupSplay).
+		WithFailureRetry(envCfg.FailureRetries, envCfg.FailureBackoff).
+		WithFailureAlert(envCfg.FailureAlertAfter).
+		WithVerification(envCfg.VerifyInterval, envCfg.VerifyRepair, reportDrift).
+		WithSealPolling(envCfg.SealPollInterval, reportSealed).
+		WithRemoveObserver(metrics.ForgetSecret)
+	if envCfg.VerifyInterval > 0 {
+		logger.Info("file verification enabled",
+			zap.Duration("interval", envCfg.VerifyInterval),
+			zap.Bool("repair", envCfg.VerifyRepair),
+			zap.Bool("content_check", envCfg.ManifestFile != ""),
 		)
 	}
 
-	// Create syncer with client factory
-	retryConfig := vault.RetryConfig{
-		InitialBackoff: envCfg.InitialBackoff,
-		MaxBackoff:     envCfg.MaxBackoff,
-		Multiplier:     envCfg.BackoffMultiplier,
-		MaxRetries:     3,
+	// Diagnostics snapshots for SIGQUIT and the optional admin endpoint
+	configSource := configPath
+	if remoteSource != nil {
+		configSource = remoteSource.String()
 	}
+	diag := &diagnostics.Collector{
+		Version:    Version,
+		ConfigFile: configSource,
+		StartTime:  startTime,
+		Syncer:     secretSyncer,
+		State:      resultStore,
+		Scheduler:  func() *syncer.Scheduler { return scheduler },
+	}
+
+	// Whether this replica syncs; false while standby for the leader lock
+	var active atomic.Bool
 
-	secretSyncer := syncer.NewSecretSyncer(clientFactory, retryConfig)
-	scheduler := syncer.NewScheduler(secretSyncer)
+	// The configuration in effect, whose cleanupOnShutdown applies on exit
+	var currentCfg atomic.Pointer[config.Config]
+	currentCfg.Store(cfg)
 
 	// Set up health status
-	status := health.NewStatus(envCfg.StatusFile)
+	status := health.NewStatus(envCfg.StatusFile).WithMaxAge(envCfg.ReadyMaxAge).WithReadyPolicy(readyPolicy)
 
 	// Validate metrics port
 	if envCfg.MetricsPort < 1025 || envCfg.MetricsPort > 65535 {
@@ -252,6 +483,27 @@ func run() error {
 	var healthServer *health.Server
 	if envCfg.EnableMetrics {
 		healthServer = health.NewServer(status, envCfg.MetricsAddr, envCfg.MetricsPort)
+		if envCfg.EnableDiagnosticsAPI {
+			healthServer.WithDiagnostics(diag.Write)
+		}
+		if adminToken != "" {
+			healthServer.WithSyncAPI(adminToken, func(ctx context.Context, name string) ([]health.SyncResult, error) {
+				if !active.Load() {
+					return nil, fmt.Errorf("%w: not the leader", health.ErrSyncUnavailable)
+				}
+				logger.Info("on-demand sync requested", zap.String("name", name))
+				return syncNow(ctx, scheduler, name)
+			})
+			healthServer.WithRollbackAPI(adminToken, func(ctx context.Context, name string) ([]string, error) {
+				if !active.Load() {
+					return nil, fmt.Errorf("%w: not the leader", health.ErrSyncUnavailable)
+				}
+				logger.Info("rollback requested", zap.String("name", name))
+				return rollbackNow(ctx, scheduler, name)
+			})
+			healthServer.WithLogLevel(adminToken, logger.LevelHandler())
+			logger.Info("admin API enabled")
+		}
 		if err := healthServer.Start(); err != nil {
 			return err
 		}
@@ -265,67 +517,218 @@ func run() error {
 
 	// Set metrics
 	metrics.SetSecretsConfigured(len(cfg.Secrets))
+	status.SetCritical(criticalSecrets(cfg))
 
-	// Start syncing secrets
-	for _, secret := range cfg.Secrets {
-		scheduler.AddSecret(cfg, secret)
-		logger.Info("secret sync started",
-			zap.String("name", secret.Name),
-			zap.Duration("refresh_interval", secret.RefreshInterval),
+	// startSyncing begins writing files; with a leader lock only the replica
+	// holding it does so, so replicas on a shared volume never race on renames
+	startSyncing := func() {
+		active.Store(true)
+
+		// Cleanup orphaned .tmp files from previous runs
+		if err := filewriter.CleanupOrphanedTempFiles(outputDirs, logger.Get()); err != nil {
+			logger.Warn("failed to cleanup orphaned temp files", zap.Error(err))
+		}
+
+		// A standby starts with the config last reloaded
+		cfg := currentCfg.Load()
+		for _, secret := range cfg.Secrets {
+			scheduler.AddSecret(cfg, secret)
+			logger.Info("secret sync started",
+				zap.String("name", secret.Name),
+				zap.Duration("refresh_interval", secret.RefreshInterval),
+			)
+		}
+	}
+
+	var leaderLock *leader.FileLock
+	elected := make(chan error, 1)
+	electionDone := make(chan struct{})
+	electionCtx, stopElection := context.WithCancel(context.Background())
+	defer stopElection()
+	if envCfg.LeaderLockFile != "" {
+		leaderLock = leader.NewFileLock(envCfg.LeaderLockFile)
+		metrics.SetLeader(false)
+		logger.Info("standing by until leader lock is acquired",
+			zap.String("lock_file", envCfg.LeaderLockFile),
+			zap.String("holder", leaderLock.Holder()),
 		)
 	}
+	if leaderLock != nil || waitStore {
+		// The store is waited for first, so a replica does not hold the
+		// lock while it cannot sync
+		go func() {
+			defer close(electionDone)
+			var err error
+			if waitStore {
+				err = waitForStore(electionCtx, envCfg, storeName, connect)
+			}
+			if err == nil && leaderLock != nil {
+				err = leaderLock.Wait(electionCtx, envCfg.LeaderRetryInterval)
+			}
+			elected <- err
+		}()
+	} else {
+		close(electionDone)
+		startSyncing()
+	}
 
-	// Monitor sync results
+	// Monitor sync results; every result is delivered, and readiness is
+	// derived from the latest state of each secret
+	var secretCount atomic.Int64
+	secretCount.Store(int64(len(cfg.Secrets)))
+	notifier := newNotifier(cfg.Notifications)
+	notifier.Start()
+	defer notifier.Stop()
+	results := resultStore.Subscribe(100)
 	go func() {
-		syncedCount := 0
-		for result := range scheduler.Results() {
-			if result.Success {
-				syncedCount++
+		alerting := make(map[string]bool) // Secrets whose alert was logged
+		for result := range results.C() {
+			notifier.Observe(result)
+			metrics.RecordSyncDuration(result.SecretName, result.Duration.Seconds())
+			metrics.RecordSyncResult(result.SecretName, result.Success, !result.Stale, result.Timestamp)
+			metrics.SetSecretAlerting(result.SecretName, result.Alerting)
+			metrics.SetLeaseExpiry(result.SecretName, result.ExpiresAt)
+			for _, file := range result.Files {
+				metrics.SetCertificateExpiry(result.SecretName, file.Path, file.CertExpiry)
+			}
+			if result.Alerting && !alerting[result.SecretName] {
+				alerting[result.SecretName] = true
+				logger.Error("secret keeps failing to sync",
+					zap.String("event", "secret_sync_alert"),
+					zap.String("name", result.SecretName),
+					zap.Int("consecutive_failures", result.Failures),
+					zap.Error(result.Error),
+				)
+			} else if result.Recovered {
+				delete(alerting, result.SecretName)
+				logger.Info("secret synced again after repeated failures",
+					zap.String("event", "secret_sync_recovered"),
+					zap.String("name", result.SecretName),
+				)
+			}
+			if result.Success && result.Stale {
+				logger.Warn("vault unavailable, serving stale secret",
+					zap.String("name", result.SecretName),
+					zap.Time("fetched_at", result.FetchedAt),
+					zap.Error(result.Error),
+				)
+				metrics.RecordFetchError(result.SecretName, "", "stale")
+				metrics.SetSecretStale(result.SecretName, true, time.Since(result.FetchedAt).Seconds())
+			} else if result.Success {
 				logger.Info("secret synced successfully",
 					zap.String("name", result.SecretName),
 					zap.Time("timestamp", result.Timestamp),
 				)
 				metrics.RecordFetchSuccess(result.SecretName, "")
-				metrics.SetSecretsSynced(syncedCount)
+				metrics.SetSecretStale(result.SecretName, false, 0)
+			} else if result.Deleted {
+				var deleted *syncer.DeletedError
+				if errors.As(result.Error, &deleted) && len(deleted.Files) > 0 {
+					logger.Warn("secret deleted in vault, local files removed",
+						zap.String("event", "secret_deleted"),
+						zap.String("name", result.SecretName),
+						zap.String("action", string(deleted.Action)),
+						zap.Strings("files", deleted.Files),
+						zap.Error(result.Error),
+					)
+					metrics.RecordSecretDeleted(result.SecretName, string(deleted.Action))
+				} else {
+					logger.Error("secret sync failed, secret deleted in vault",
+						zap.String("name", result.SecretName),
+						zap.Error(result.Error),
+					)
+				}
+				metrics.RecordFetchError(result.SecretName, "", "deleted")
+				metrics.SetSecretStale(result.SecretName, false, 0)
 			} else {
 				logger.Error("secret sync failed",
 					zap.String("name", result.SecretName),
+					zap.String("error_kind", string(result.Kind)),
 					zap.Error(result.Error),
 					zap.Time("timestamp", result.Timestamp),
+					zap.Int("consecutive_failures", result.Failures),
+					zap.Time("retry_at", result.RetryAt),
 				)
-				metrics.RecordFetchError(result.SecretName, "", "sync_error")
+				metrics.RecordFetchError(result.SecretName, "", string(result.Kind))
+				metrics.SetSecretStale(result.SecretName, false, 0)
 			}
 
-			// Update readiness status
-			_ = status.SetReady(len(cfg.Secrets), syncedCount)
+			updateStatus(status, resultStore, int(secretCount.Load()))
 		}
 	}()
 
-	// Set up config watcher if enabled
-	if envCfg.WatchConfig {
-		watcher, err := config.NewWatcher(
-			envCfg.ConfigFile,
-			func(newCfg *config.Config) error {
-				workDir, _ := os.Getwd()
-				if workDir == "" {
-					workDir = "unknown"
-				}
-				absConfigPath, err := filepath.Abs(configPath)
-				if err != nil {
-					absConfigPath = configPath
-				}
-				logger.Info("configuration reloaded",
-					zap.String("config_file", absConfigPath),
-					zap.String("working_directory", workDir),
-					zap.Int("secret_count", len(newCfg.Secrets)),
-				)
-				// Update secrets
-				for _, secret := range newCfg.Secrets {
-					scheduler.AddSecret(newCfg, secret)
-				}
-				metrics.SetSecretsConfigured(len(newCfg.Secrets))
-				return nil
+	// applyConfig applies a validated config, whether found by the file
+	// watcher, by re-fetching a remote config or on SIGHUP. Reloads are
+	// serialized, and a standby only records the config to start with.
+	var reloadMu sync.Mutex
+	applyConfig := func(newCfg *config.Config) error {
+		reloadMu.Lock()
+		defer reloadMu.Unlock()
+
+		// Log working directory for relative path resolution
+		workDir, err := os.Getwd()
+		if err != nil {
+			logger.Warn("failed to get working directory", zap.Error(err))
+			workDir = "unknown"
+		}
+		warnConfig(newCfg)
+		redactConfigSecrets(newCfg)
+
+		currentCfg.Store(newCfg)
+		secretSyncer.WithAllowedPaths(newCfg.AllowedOutputPaths)
+		logger.Info("configuration reloaded",
+			configField(remoteSource, configPath),
+			zap.String("working_directory", workDir),
+			zap.Int("secret_count", len(newCfg.Secrets)),
+		)
+
+		metrics.SetSecretsConfigured(len(newCfg.Secrets))
+		status.SetCritical(criticalSecrets(newCfg))
+		notifier.Configure(newCfg.Notifications)
+
+		// Forget secrets that are no longer configured
+		if fileGuard != nil {
+			fileGuard.Retain(newCfg)
+		}
+		configured := make(map[string]bool, len(newCfg.Secrets))
+		for _, secret := range newCfg.Secrets {
+			configured[secret.Name] = true
+		}
+		for _, result := range resultStore.All() {
+			if !configured[result.SecretName] {
+				resultStore.Remove(result.SecretName)
+			}
+		}
+		secretCount.Store(int64(len(newCfg.Secrets)))
+		updateStatus(status, resultStore, len(newCfg.Secrets))
+
+		// Restart only changed jobs; a standby starts them once elected
+		if !active.Load() {
+			return nil
+		}
+		reconcileSecrets(scheduler, newCfg)
+		return nil
+	}
+
+	// Re-fetch a remote config periodically
+	if remoteSource != nil && envCfg.ConfigRefreshInterval > 0 {
+		remoteWatcher := remoteconfig.NewWatcher(remoteSource, envCfg.ConfigRefreshInterval, applyConfig,
+			func(err error) {
+				logger.Error("remote config error", zap.Error(err))
 			},
+		)
+		remoteWatcher.Start()
+		defer remoteWatcher.Stop()
+		logger.Info("remote config refresh started",
+			zap.Duration("interval", envCfg.ConfigRefreshInterval),
+		)
+	}
+
+	// Set up config watcher if enabled; a remote config is re-fetched instead
+	if envCfg.WatchConfig && remoteSource == nil {
+		watcher, err := config.NewWatcher(
+			configPath,
+			applyConfig,
 			func(err error) {
 				logger.Error("config watcher error", zap.Error(err))
 			},
@@ -339,19 +742,11 @@ func run() error {
 		}
 	}
 
-	// Set up graceful shutdown
+	// Set up graceful shutdown; handlers run in reverse registration order,
+	// so register in startup order: tracing, metrics server, leader lock,
+	// scheduler. Leases are revoked and files cleaned up after the scheduler
+	// stopped renewing and writing them.
 	shutdownHandler := shutdown.NewHandler(30 * time.Second)
-	shutdownHandler.Register(func() error {
-		logger.Info("shutting down scheduler")
-		scheduler.Stop()
-		return nil
-	})
-	if healthServer != nil {
-		shutdownHandler.Register(func() error {
-			logger.Info("shutting down metrics server")
-			return healthServer.Stop()
-		})
-	}
 	if tracingShutdown != nil {
 		shutdownHandler.Register(func() error {
 			logger.Info("shutting down tracing")
@@ -359,17 +754,72 @@ func run() error {
 			return nil
 		})
 	}
-
-	// Cleanup orphaned .tmp files from previous runs
-	var allFilePaths []string
-	for _, secret := range cfg.Secrets {
-		for _, file := range secret.Files {
-			allFilePaths = append(allFilePaths, file.Path)
+	if healthServer != nil {
+		shutdownHandler.RegisterWithTimeout(func() error {
+			logger.Info("shutting down metrics server")
+			return healthServer.Stop()
+		}, 5*time.Second)
+	}
+	if leaderLock != nil {
+		shutdownHandler.Register(func() error {
+			stopElection()
+			<-electionDone
+			if !leaderLock.Held() {
+				return nil
+			}
+			logger.Info("releasing leader lock")
+			metrics.SetLeader(false)
+			return leaderLock.Release()
+		})
+	}
+	shutdownHandler.Register(func() error {
+		// A standby never wrote the files; they belong to the leader
+		if !active.Load() {
+			return nil
 		}
+		return cleanupSecrets(secretSyncer, currentCfg.Load())
+	})
+	if envCfg.RevokeLeases {
+		shutdownHandler.RegisterWithTimeout(func() error {
+			logger.Info("revoking leases of dynamic secrets")
+			return revokeLeases(secretSyncer)
+		}, revokeTimeout)
 	}
-	outputDirs := filewriter.GetOutputDirectories(allFilePaths)
-	if err := filewriter.CleanupOrphanedTempFiles(outputDirs, logger.Get()); err != nil {
-		logger.Warn("failed to cleanup orphaned temp files", zap.Error(err))
+	shutdownHandler.Register(func() error {
+		logger.Info("shutting down scheduler, draining in-flight syncs",
+			zap.Int("in_flight", scheduler.InFlight()),
+		)
+		return scheduler.Shutdown(syncer.DefaultDrainTimeout)
+	})
+
+	// Restrict the process now that initialization is complete
+	if sandboxMode == sandbox.ModeStrict {
+		writableDirs := append(outputDirs, filepath.Dir(envCfg.StatusFile))
+		if envCfg.ManifestFile != "" {
+			writableDirs = append(writableDirs, filepath.Dir(envCfg.ManifestFile))
+		}
+		if envCfg.StateFile != "" {
+			writableDirs = append(writableDirs, filepath.Dir(envCfg.StateFile))
+		}
+		if envCfg.CacheDir != "" {
+			writableDirs = append(writableDirs, envCfg.CacheDir)
+		}
+		if envCfg.LeaderLockFile != "" {
+			writableDirs = append(writableDirs, filepath.Dir(envCfg.LeaderLockFile))
+		}
+		if envCfg.DiagnosticsDir != "" {
+			writableDirs = append(writableDirs, envCfg.DiagnosticsDir)
+		}
+		if envCfg.QuarantineDir != "" {
+			writableDirs = append(writableDirs, envCfg.QuarantineDir)
+		}
+		if err := sandbox.Apply(sandbox.Config{WritableDirs: writableDirs}); err != nil {
+			return fmt.Errorf("failed to apply sandbox: %w", err)
+		}
+		logger.Info("sandbox enabled",
+			zap.String("mode", string(sandboxMode)),
+			zap.Strings("writable_dirs", writableDirs),
+		)
 	}
 
 	logger.Info("docker secrets sync running, waiting for shutdown signal")
@@ -388,24 +838,37 @@ func run() error {
 			logger.Info("shutdown complete")
 			return nil
 
-		case <-shutdownHandler.WaitReload():
-			logger.Info("reload signal (SIGHUP) received, reloading configuration")
+		case <-shutdownHandler.WaitDiagnostics():
+			dumpDiagnostics(diag, envCfg.DiagnosticsDir)
 
-			// Log working directory for relative path resolution
-			workDir, err := os.Getwd()
-			if err != nil {
-				logger.Warn("failed to get working directory", zap.Error(err))
-				workDir = "unknown"
-			}
+		case <-shutdownHandler.WaitDebugToggle():
+			logger.ToggleDebug()
 
-			// Resolve config path to absolute for logging
-			absConfigPath, err := filepath.Abs(configPath)
-			if err != nil {
-				absConfigPath = configPath
+		case <-shutdownHandler.WaitResync():
+			// A standby starts syncing once elected
+			if !active.Load() {
+				logger.Info("resync signal (SIGUSR2) ignored, not syncing yet")
+				continue
 			}
+			logger.Info("resync signal (SIGUSR2) received, syncing all secrets")
+			go resyncAll(scheduler)
 
-			// Reload configuration
-			newCfg, err := config.Load(configPath)
+		case err := <-elected:
+			if err != nil {
+				return fmt.Errorf("leader election failed: %w", err)
+			}
+			if leaderLock != nil {
+				logger.Info("acquired leader lock, starting sync",
+					zap.String("lock_file", envCfg.LeaderLockFile),
+				)
+				metrics.SetLeader(true)
+			}
+			startSyncing()
+
+		case <-shutdownHandler.WaitReload():
+			logger.Info("reload signal (SIGHUP) received, reloading configuration")
+
+			newCfg, err := loadCfg(context.Background())
 			if err != nil {
 				logger.Error("failed to reload configuration", zap.Error(err))
 				continue
@@ -417,38 +880,728 @@ func run() error {
 				continue
 			}
 
-			// Stop current scheduler
-			scheduler.Stop()
+			_ = applyConfig(newCfg)
+		}
+	}
+}
+
+// waitForStore calls connect with exponential backoff, from INITIAL_BACKOFF
+// up to MAX_BACKOFF, until it succeeds or ctx is done
+func waitForStore(ctx context.Context, envCfg *config.EnvConfig, storeName string, connect func(context.Context) error) error {
+	delay := envCfg.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		err := connect(ctx)
+		if err == nil {
+			logger.Info(storeName+" reachable, starting sync", zap.Int("attempts", attempt))
+			return nil
+		}
+		delay = min(time.Duration(float64(delay)*envCfg.BackoffMultiplier), envCfg.MaxBackoff)
+		logger.Warn(storeName+" still unavailable",
+			zap.Int("attempts", attempt),
+			zap.Duration("retry_in", delay),
+			zap.Error(err),
+		)
+	}
+}
+
+// reconcileSecrets applies a reloaded configuration to the scheduler and
+// logs the jobs it added, restarted and removed
+func reconcileSecrets(scheduler *syncer.Scheduler, cfg *config.Config) {
+	result := scheduler.Reconcile(cfg)
+	refresh := make(map[string]time.Duration, len(cfg.Secrets))
+	for _, secret := range cfg.Secrets {
+		refresh[secret.Name] = secret.RefreshInterval
+	}
+
+	for _, name := range result.Added {
+		logger.Info("secret sync started",
+			zap.String("name", name),
+			zap.Duration("refresh_interval", refresh[name]),
+		)
+	}
+	for _, name := range result.Updated {
+		logger.Info("secret sync restarted",
+			zap.String("name", name),
+			zap.Duration("refresh_interval", refresh[name]),
+		)
+	}
+	for _, name := range result.Removed {
+		logger.Info("secret sync stopped", zap.String("name", name))
+	}
+	logger.Info("secret jobs reconciled",
+		zap.Int("added", len(result.Added)),
+		zap.Int("restarted", len(result.Updated)),
+		zap.Int("removed", len(result.Removed)),
+		zap.Int("unchanged", len(result.Unchanged)),
+	)
+}
+
+// loadStateFile reads the state recorded by the previous run, drops the
+// secrets no longer configured and restores the last sync time metrics
+func loadStateFile(path string, cfg *config.Config) (*state.Store, error) {
+	stateFile, err := state.LoadStore(path)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(cfg.Secrets))
+	for _, secret := range cfg.Secrets {
+		names = append(names, secret.Name)
+	}
+	if stateFile.Retain(names) {
+		if err := stateFile.Save(); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range stateFile.Names() {
+		if entry, ok := stateFile.Get(name); ok && !entry.FetchedAt.IsZero() {
+			metrics.SetLastSync(name, entry.FetchedAt)
+		}
+	}
+	return stateFile, nil
+}
+
+// cleanupSecrets removes or shreds the files of secrets with
+// cleanupOnShutdown set
+func cleanupSecrets(secretSyncer *syncer.SecretSyncer, cfg *config.Config) error {
+	result, err := secretSyncer.Cleanup(cfg.Secrets)
+	if len(result.Removed) > 0 {
+		logger.Info("removed secret files on shutdown", zap.Strings("files", result.Removed))
+	}
+	if len(result.Unshredded) > 0 {
+		logger.Warn("removed secret files without overwriting them, they could not be opened for writing",
+			zap.Strings("files", result.Unshredded),
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to clean up secret files: %w", err)
+	}
+	return nil
+}
+
+// revokeTimeout bounds revoking the leases of dynamic secrets on exit
+const revokeTimeout = 10 * time.Second
+
+// revokeLeases revokes the leases of the credentials written for dynamic
+// secrets, so they are not left valid once nothing renews or replaces them
+func revokeLeases(secretSyncer *syncer.SecretSyncer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), revokeTimeout)
+	defer cancel()
+	if err := secretSyncer.Leases().RevokeAll(ctx); err != nil {
+		return fmt.Errorf("failed to revoke leases: %w", err)
+	}
+	return nil
+}
+
+// newNotifier creates a notifier for the webhooks of cfg, logging every
+// delivery
+func newNotifier(cfg config.Notifications) *notify.Notifier {
+	if len(cfg.Webhooks) > 0 {
+		logger.Info("notifications enabled", zap.Int("webhooks", len(cfg.Webhooks)))
+	}
+	return notify.New(cfg, func(event notify.Event, endpoint string, err error) {
+		if err != nil {
+			logger.Warn("failed to send notification",
+				zap.String("event", event.Type),
+				zap.String("name", event.Secret),
+				zap.String("endpoint", endpoint),
+				zap.Error(err),
+			)
+			return
+		}
+		logger.Debug("notification sent",
+			zap.String("event", event.Type),
+			zap.String("name", event.Secret),
+			zap.String("endpoint", endpoint),
+		)
+	})
+}
+
+// dumpDiagnostics writes a diagnostics snapshot to a file in dir, or to
+// stderr when no directory is configured
+func dumpDiagnostics(diag *diagnostics.Collector, dir string) {
+	if dir == "" {
+		if err := diag.Write(os.Stderr); err != nil {
+			logger.Error("failed to write diagnostics", zap.Error(err))
+			return
+		}
+		logger.Info("diagnostics written to stderr")
+		return
+	}
+
+	path, err := diag.WriteFile(dir)
+	if err != nil {
+		logger.Error("failed to write diagnostics", zap.Error(err))
+		return
+	}
+	logger.Info("diagnostics written", zap.String("path", path))
+}
+
+// reportDrift logs and counts a managed file that drifted from its expected state
+func reportDrift(drift syncer.Drift) {
+	fields := []zap.Field{
+		zap.String("event", "file_drift"),
+		zap.String("name", drift.Secret),
+		zap.String("path", drift.Path),
+		zap.String("kind", string(drift.Kind)),
+		zap.String("detail", drift.Detail),
+		zap.Bool("repaired", drift.Repaired),
+	}
+	if drift.RepairErr != nil {
+		logger.Error("managed file drifted, repair failed", append(fields, zap.Error(drift.RepairErr))...)
+	} else {
+		logger.Warn("managed file drifted", fields...)
+	}
+	metrics.RecordFileDrift(drift.Secret, string(drift.Kind))
+}
+
+// reportSealed logs and exposes pausing and resuming around a sealed Vault
+func reportSealed(sealed bool) {
+	metrics.SetVaultSealed(sealed)
+	if sealed {
+		logger.Warn("vault is sealed, pausing sync until it is unsealed")
+		return
+	}
+	logger.Info("vault is unsealed, resuming sync")
+}
+
+// reportRestore logs and counts the restore of a tampered file
+func reportRestore(secret, path string, tamper syncer.Tamper, err error) {
+	metrics.RecordFileTampered(secret, string(tamper))
+	metrics.RecordFileRestored(secret, err == nil)
+	if err != nil {
+		logger.Error("failed to restore managed file",
+			zap.String("event", "file_restored"),
+			zap.String("name", secret),
+			zap.String("path", path),
+			zap.String("reason", string(tamper)),
+			zap.Error(err),
+		)
+		return
+	}
+	logger.Warn("managed file changed outside secrets-sync, restored",
+		zap.String("event", "file_restored"),
+		zap.String("name", secret),
+		zap.String("path", path),
+		zap.String("reason", string(tamper)),
+	)
+}
+
+// resyncAll syncs every secret now, without reloading the configuration,
+// and logs how many succeeded
+func resyncAll(scheduler *syncer.Scheduler) {
+	results, err := scheduler.SyncAllNow(context.Background())
+	if err != nil {
+		logger.Warn("resync failed", zap.Error(err))
+		return
+	}
+	failed := 0
+	for _, result := range results {
+		if !result.Success {
+			failed++
+		}
+	}
+	logger.Info("resync complete",
+		zap.Int("synced", len(results)-failed),
+		zap.Int("failed", failed),
+	)
+}
+
+// rollbackNow restores the latest backup of the files of a secret, for the
+// admin API
+func rollbackNow(ctx context.Context, scheduler *syncer.Scheduler, name string) ([]string, error) {
+	files, err := scheduler.Rollback(ctx, name)
+	switch {
+	case errors.Is(err, syncer.ErrUnknownSecret):
+		return nil, fmt.Errorf("%w: %s", health.ErrUnknownSecret, name)
+	case errors.Is(err, syncer.ErrNoBackup):
+		return nil, fmt.Errorf("%w: %v", health.ErrNoBackup, err)
+	case errors.Is(err, syncer.ErrSyncRunning):
+		return nil, fmt.Errorf("%w: %v", health.ErrSyncUnavailable, err)
+	case err != nil:
+		logger.Error("rollback failed", zap.String("name", name), zap.Strings("files", files), zap.Error(err))
+		return files, err
+	}
+	logger.Info("rolled back to backup", zap.String("name", name), zap.Strings("files", files))
+	return files, nil
+}
+
+// syncNow runs an on-demand sync of one secret, or of all secrets if name
+// is empty, for the admin API
+func syncNow(ctx context.Context, scheduler *syncer.Scheduler, name string) ([]health.SyncResult, error) {
+	var results []syncer.SyncResult
+	var err error
+	if name == "" {
+		results, err = scheduler.SyncAllNow(ctx)
+	} else {
+		var result syncer.SyncResult
+		result, err = scheduler.SyncNow(ctx, name)
+		results = []syncer.SyncResult{result}
+	}
+	switch {
+	case errors.Is(err, syncer.ErrUnknownSecret):
+		return nil, fmt.Errorf("%w: %s", health.ErrUnknownSecret, name)
+	case errors.Is(err, syncer.ErrPaused), errors.Is(err, syncer.ErrStopped):
+		return nil, fmt.Errorf("%w: %v", health.ErrSyncUnavailable, err)
+	case err != nil:
+		return nil, err
+	}
+
+	converted := make([]health.SyncResult, 0, len(results))
+	for _, result := range results {
+		entry := health.SyncResult{
+			SecretStatus: secretStatus(result),
+			Duration:     result.Duration.Seconds(),
+		}
+		if result.Error != nil && !result.Success {
+			entry.Kind = string(result.Kind)
+		}
+		converted = append(converted, entry)
+	}
+	return converted, nil
+}
+
+// secretStatus reports the outcome of a sync result on /status
+func secretStatus(result syncer.SyncResult) health.SecretStatus {
+	entry := health.SecretStatus{Name: result.SecretName, LastSync: result.Timestamp}
+	switch {
+	case result.Success && result.Stale:
+		fetchedAt := result.FetchedAt
+		entry.State = "stale"
+		entry.FetchedAt = &fetchedAt
+	case result.Success:
+		entry.State = "synced"
+	case result.Deleted:
+		entry.State = "deleted"
+	default:
+		entry.State = "failed"
+	}
+	if result.Error != nil {
+		entry.Error = logger.RedactedString(result.Error.Error())
+	}
+	if !result.NextSync.IsZero() {
+		nextSync := result.NextSync
+		entry.NextSync = &nextSync
+	}
+	if !result.RetryAt.IsZero() {
+		retryAt := result.RetryAt
+		entry.RetryAt = &retryAt
+	}
+	entry.ConsecutiveFailures = result.Failures
+	entry.Alerting = result.Alerting
+	for _, file := range result.Files {
+		fileStatus := health.FileStatus{Path: file.Path, SHA256: file.Hash}
+		if !file.CertExpiry.IsZero() {
+			certExpiry := file.CertExpiry
+			fileStatus.CertExpiry = &certExpiry
+		}
+		entry.Files = append(entry.Files, fileStatus)
+	}
+	return entry
+}
+
+// criticalSecrets returns the names of the secrets marked critical
+func criticalSecrets(cfg *config.Config) []string {
+	var names []string
+	for _, secret := range cfg.Secrets {
+		if secret.Critical {
+			names = append(names, secret.Name)
+		}
+	}
+	return names
+}
+
+// updateStatus derives readiness, metrics and the per-secret status from the
+// latest result of each secret. Stale secrets count as synced so readiness
+// does not flap while Vault is unavailable.
+func updateStatus(status *health.Status, store *syncer.StateStore, secretCount int) {
+	var synced, stale int
+	latest := store.All()
+	secrets := make([]health.SecretStatus, 0, len(latest))
+
+	for _, result := range latest {
+		entry := secretStatus(result)
+		switch entry.State {
+		case "stale":
+			synced++
+			stale++
+		case "synced":
+			synced++
+		}
+		secrets = append(secrets, entry)
+	}
 
-			// Update configuration
-			cfg = newCfg
-			logger.Info("configuration reloaded",
-				zap.String("config_file", absConfigPath),
-				zap.String("working_directory", workDir),
-				zap.Int("secret_count", len(cfg.Secrets)),
+	metrics.SetSecretsSynced(synced)
+	status.SetSecrets(secrets)
+	status.SetStaleCount(stale)
+	_ = status.SetReady(secretCount, synced)
+}
+
+// loadAdminToken reads the bearer token of the admin API
+func loadAdminToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read ADMIN_TOKEN_FILE: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	memlock.Zero(data)
+	if len(token) < 16 {
+		return "", fmt.Errorf("ADMIN_TOKEN_FILE must hold a token of at least 16 characters")
+	}
+	return token, nil
+}
+
+// redactConfigSecrets registers the tokens, secret IDs, client secrets and
+// webhook headers of a configuration for redaction from the logs
+func redactConfigSecrets(cfg *config.Config) {
+	store := cfg.SecretStore
+	for _, value := range []string{store.Token, store.SecretID, store.ClientSecret} {
+		logger.RegisterSecret(value)
+	}
+	for _, creds := range store.Credentials {
+		for _, value := range []string{creds.Token, creds.SecretID, creds.ClientSecret} {
+			logger.RegisterSecret(value)
+		}
+	}
+	for _, webhook := range cfg.Notifications.Webhooks {
+		for _, value := range webhook.Headers {
+			logger.RegisterSecret(value)
+		}
+	}
+}
+
+// warnConfig logs what a configuration should change though it is valid:
+// files bound to templates by position and a high refresh rate
+func warnConfig(cfg *config.Config) {
+	for _, secret := range cfg.Secrets {
+		if secret.UsesImplicitTemplates() {
+			logger.Warn("files are bound to templates by position, which is deprecated; set template on each file",
+				zap.String("name", secret.Name),
 			)
+		}
+	}
+	if rate := cfg.FetchRate(); rate > config.HighFetchRate {
+		logger.Warn("secrets refresh at a high rate, consider longer refresh intervals",
+			zap.Int("secret_count", len(cfg.Secrets)),
+			zap.Float64("reads_per_second", rate),
+		)
+	}
+}
 
-			// Restart scheduler with new secrets
-			scheduler = syncer.NewScheduler(secretSyncer)
-			for _, secret := range cfg.Secrets {
-				scheduler.AddSecret(cfg, secret)
-				logger.Info("secret sync restarted",
-					zap.String("name", secret.Name),
-					zap.Duration("refresh_interval", secret.RefreshInterval),
+// dropPrivileges hands the output directories to RUN_AS_USER/RUN_AS_GROUP
+// and switches the process to that identity
+func dropPrivileges(envCfg *config.EnvConfig, outputDirs []string, memoryLocked bool) error {
+	uid, err := privdrop.ParseID(envCfg.RunAsUser)
+	if err != nil {
+		return fmt.Errorf("RUN_AS_USER: %w", err)
+	}
+	gid, err := privdrop.ParseID(envCfg.RunAsGroup)
+	if err != nil {
+		return fmt.Errorf("RUN_AS_GROUP: %w", err)
+	}
+	if gid < 0 {
+		gid = uid
+	}
+
+	// Locked memory keeps growing with the heap, and without CAP_IPC_LOCK
+	// the runtime aborts once it outgrows RLIMIT_MEMLOCK
+	dropCfg := privdrop.Config{
+		UID:           uid,
+		GID:           gid,
+		KeepChownCaps: envCfg.KeepChownCaps,
+		KeepIPCLock:   memoryLocked,
+	}
+
+	dirs := append([]string{}, outputDirs...)
+	if envCfg.StatusFile != "" {
+		dirs = append(dirs, filepath.Dir(envCfg.StatusFile))
+	}
+	if envCfg.ManifestFile != "" {
+		dirs = append(dirs, filepath.Dir(envCfg.ManifestFile))
+	}
+	if envCfg.StateFile != "" {
+		dirs = append(dirs, filepath.Dir(envCfg.StateFile))
+	}
+	if envCfg.CacheDir != "" {
+		dirs = append(dirs, envCfg.CacheDir)
+	}
+	if envCfg.LeaderLockFile != "" {
+		dirs = append(dirs, filepath.Dir(envCfg.LeaderLockFile))
+	}
+	if envCfg.DiagnosticsDir != "" {
+		dirs = append(dirs, envCfg.DiagnosticsDir)
+	}
+	if envCfg.QuarantineDir != "" {
+		dirs = append(dirs, envCfg.QuarantineDir)
+	}
+	if err := privdrop.PrepareDirs(dropCfg, dirs); err != nil {
+		return err
+	}
+
+	if err := privdrop.Drop(dropCfg); err != nil {
+		if memoryLocked && errors.Is(err, privdrop.ErrKeepCapsUnsupported) {
+			return fmt.Errorf("failed to drop privileges: %w (locked memory needs CAP_IPC_LOCK, set DISABLE_MLOCK=true)", err)
+		}
+		return fmt.Errorf("failed to drop privileges: %w", err)
+	}
+
+	logger.Info("dropped privileges",
+		zap.Int("uid", uid),
+		zap.Int("gid", gid),
+		zap.Bool("keep_chown_caps", envCfg.KeepChownCaps),
+		zap.Bool("keep_ipc_lock", memoryLocked),
+	)
+	return nil
+}
+
+// newVaultTLSConfig builds the Vault TLS configuration, letting environment
+// variables override the config file
+func newVaultTLSConfig(cfg *config.Config, envCfg *config.EnvConfig) *vault.TLSConfig {
+	tlsConfig := &vault.TLSConfig{
+		CACert:     cfg.SecretStore.TLSCACert,
+		CAPath:     cfg.SecretStore.TLSCAPath,
+		ClientCert: cfg.SecretStore.TLSClientCert,
+		ClientKey:  cfg.SecretStore.TLSClientKey,
+		SkipVerify: cfg.SecretStore.TLSSkipVerify,
+	}
+
+	// Override with environment variables if set
+	if envCfg.VaultCACert != "" {
+		tlsConfig.CACert = envCfg.VaultCACert
+	}
+	if envCfg.VaultCAPath != "" {
+		tlsConfig.CAPath = envCfg.VaultCAPath
+	}
+	if envCfg.VaultClientCert != "" {
+		tlsConfig.ClientCert = envCfg.VaultClientCert
+	}
+	if envCfg.VaultClientKey != "" {
+		tlsConfig.ClientKey = envCfg.VaultClientKey
+	}
+	if envCfg.VaultSkipVerify {
+		tlsConfig.SkipVerify = true
+	}
+
+	return tlsConfig
+}
+
+// newVaultConnection returns the connection settings of the Vault clients,
+// taken from secretStore.connection or the environment. The clients share
+// one rate limiter, so the limit holds for all credential sets together.
+func newVaultConnection(cfg *config.Config, envCfg *config.EnvConfig) *vault.ConnectionConfig {
+	conn := config.Connection{
+		Timeout:      envCfg.VaultClientTimeout,
+		DialTimeout:  envCfg.VaultDialTimeout,
+		KeepAlive:    envCfg.VaultKeepAlive,
+		MaxIdleConns: envCfg.VaultMaxIdleConns,
+		Proxy:        envCfg.VaultHTTPProxy,
+		RateLimit:    envCfg.VaultRateLimit,
+		RateBurst:    envCfg.VaultRateBurst,
+	}
+
+	// Override with the configuration file if set
+	if c := cfg.SecretStore.Connection; c != nil {
+		if c.Timeout > 0 {
+			conn.Timeout = c.Timeout
+		}
+		if c.DialTimeout > 0 {
+			conn.DialTimeout = c.DialTimeout
+		}
+		if c.KeepAlive > 0 {
+			conn.KeepAlive = c.KeepAlive
+		}
+		if c.MaxIdleConns > 0 {
+			conn.MaxIdleConns = c.MaxIdleConns
+		}
+		if c.Proxy != "" {
+			conn.Proxy = c.Proxy
+		}
+		if c.RateLimit > 0 {
+			conn.RateLimit = c.RateLimit
+		}
+		if c.RateBurst > 0 {
+			conn.RateBurst = c.RateBurst
+		}
+	}
+
+	return &vault.ConnectionConfig{
+		Timeout:      conn.Timeout,
+		DialTimeout:  conn.DialTimeout,
+		KeepAlive:    conn.KeepAlive,
+		MaxIdleConns: conn.MaxIdleConns,
+		Proxy:        conn.Proxy,
+		Limiter:      vault.NewRateLimiter(conn.RateLimit, conn.RateBurst),
+	}
+}
+
+// newClientFactory returns a factory creating authenticated Vault clients
+func newClientFactory(cfg *config.Config, envCfg *config.EnvConfig) syncer.ClientFactory {
+	tlsConfig := newVaultTLSConfig(cfg, envCfg)
+	conn := newVaultConnection(cfg, envCfg)
+
+	return func(ctx context.Context, creds config.CredentialSet) (*vault.Client, error) {
+		client, err := vault.NewClientWithConnection(cfg.SecretStore.Address, tlsConfig, conn)
+		if err != nil {
+			return nil, err
+		}
+
+		// Set up circuit breaker
+		client.WithCircuitBreaker(
+			vault.BreakerConfig{
+				MaxRequests: uint32(envCfg.CircuitBreakerMaxReqs),
+				Interval:    envCfg.CircuitBreakerInterval,
+				Timeout:     envCfg.CircuitBreakerTimeout,
+			},
+			func(from, to string) {
+				logger.Info("circuit breaker state changed",
+					zap.String("from", from),
+					zap.String("to", to),
 				)
-			}
+				metrics.SetCircuitBreakerState("vault-client", to)
+			},
+		)
 
-			metrics.SetSecretsConfigured(len(cfg.Secrets))
+		// Fail over between the configured endpoints
+		endpoints := cfg.SecretStore.Endpoints()
+		client.WithFailover(endpoints, func(from, to string) {
+			logger.Warn("vault endpoint unreachable, failed over",
+				zap.String("from", from),
+				zap.String("to", to),
+			)
+			metrics.SetVaultActiveEndpoint(to, endpoints)
+		})
+		metrics.SetVaultActiveEndpoint(client.Address(), endpoints)
+		client.WithFetchObserver(metrics.RecordVaultFetch)
+		client.WithCredentialObserver(logger.RegisterSecret)
+
+		// Authenticate with provided credentials
+		authConfig := vault.AuthConfig{
+			Method:   vault.AuthMethod(creds.AuthMethod),
+			Token:    creds.Token,
+			RoleID:   creds.RoleID,
+			SecretID: creds.SecretID,
+
+			TokenFile:       creds.TokenFile,
+			SecretIDFile:    creds.SecretIDFile,
+			SecretIDWrapped: creds.SecretIDWrapped,
+
+			KubernetesRole:      creds.KubernetesRole,
+			KubernetesTokenPath: creds.KubernetesTokenPath,
+			KubernetesMountPath: creds.KubernetesMountPath,
+
+			CertRole:      creds.CertRole,
+			CertMountPath: creds.CertMountPath,
+
+			Namespace: creds.AuthNamespace,
 		}
+
+		if err := client.Authenticate(ctx, authConfig); err != nil {
+			return nil, err
+		}
+
+		return client, nil
 	}
 }
 
-func isReady() int {
+// newAzureClientFactory returns a factory creating authenticated Azure Key Vault clients
+func newAzureClientFactory(cfg *config.Config) syncer.AzureClientFactory {
+	return func(ctx context.Context, creds config.CredentialSet) (*azure.Client, error) {
+		client, err := azure.NewClient(cfg.SecretStore.Address)
+		if err != nil {
+			return nil, err
+		}
+
+		authConfig := azure.AuthConfig{
+			Method:       azure.AuthMethod(creds.AuthMethod),
+			TenantID:     creds.TenantID,
+			ClientID:     creds.ClientID,
+			ClientSecret: creds.ClientSecret,
+		}
+		if err := client.Authenticate(ctx, authConfig); err != nil {
+			return nil, err
+		}
+
+		return client, nil
+	}
+}
+
+// outputDirectories returns the directories the configured secrets are written to
+func outputDirectories(cfg *config.Config) []string {
+	var allFilePaths []string
+	for _, secret := range cfg.Secrets {
+		allFilePaths = append(allFilePaths, secret.OutputPaths()...)
+		// checksumFile may lie in a directory holding none of the files
+		if secret.ChecksumFile != "" {
+			allFilePaths = append(allFilePaths, secret.ChecksumFile)
+		}
+	}
+	outputDirs := filewriter.GetOutputDirectories(allFilePaths)
+	for _, secret := range cfg.Secrets {
+		if secret.Directory != nil {
+			outputDirs = append(outputDirs, secret.Directory.Path)
+		}
+	}
+	return outputDirs
+}
+
+// newRetryConfig builds the default Vault retry configuration from
+// environment settings; secrets may override it with retry blocks
+func newRetryConfig(envCfg *config.EnvConfig) vault.RetryConfig {
+	return vault.RetryConfig{
+		InitialBackoff: envCfg.InitialBackoff,
+		MaxBackoff:     envCfg.MaxBackoff,
+		Multiplier:     envCfg.BackoffMultiplier,
+		MaxRetries:     max(envCfg.MaxRetries, 0),
+	}
+}
+
+func isReady(args []string) int {
 	envCfg := config.LoadEnvConfig()
+	maxAge := envCfg.ReadyMaxAge
 
-	if err := health.CheckReadiness(envCfg.StatusFile); err != nil {
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--max-age":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --max-age requires a duration\n")
+				return 1
+			}
+			i++
+			d, err := time.ParseDuration(args[i])
+			if err != nil || d < 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --max-age %q\n", args[i])
+				return 1
+			}
+			maxAge = d
+		case "-h", "--help":
+			printIsReadyUsage()
+			return 0
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown option: %s\n\n", args[i])
+			printIsReadyUsage()
+			return 1
+		}
+	}
+
+	if err := health.CheckReadiness(envCfg.StatusFile, maxAge); err != nil {
+		if maxAge > 0 {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
 		return 1
 	}
 
 	return 0
 }
+
+func printIsReadyUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: secrets-sync isready [--max-age <duration>]\n")
+	fmt.Fprintf(os.Stderr, "\nExits 0 if the service is ready: STATUS_FILE exists. With --max-age (default:\n")
+	fmt.Fprintf(os.Stderr, "READY_MAX_AGE), it also fails if a secret last synced successfully longer ago.\n")
+	fmt.Fprintf(os.Stderr, "\nExamples:\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync isready --max-age 2h\n")
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/state"
+	"github.com/ohauer/secrets-sync/internal/syncer"
+)
+
+// planSymbols maps plan actions to the prefix printed in front of each path
+var planSymbols = map[syncer.Action]string{
+	syncer.ActionCreate:    "+",
+	syncer.ActionUpdate:    "~",
+	syncer.ActionDelete:    "-",
+	syncer.ActionUnchanged: "=",
+}
+
+// runPlan compares config and Vault against disk, optionally applying the result
+func runPlan(apply bool) int {
+	envCfg := config.LoadEnvConfig()
+
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	secretSyncer := syncer.NewSecretSyncer(newClientFactory(cfg, envCfg), newRetryConfig(envCfg)).WithAzure(newAzureClientFactory(cfg))
+
+	if envCfg.ManifestFile != "" {
+		manifest, err := state.LoadManifest(envCfg.ManifestFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		secretSyncer.WithManifest(manifest)
+	} else {
+		fmt.Fprintf(os.Stderr, "Warning: MANIFEST_FILE not set, orphaned files cannot be detected\n")
+	}
+	if apply && envCfg.AuditLog != "" {
+		auditLog, err := openAuditLog(secretSyncer, envCfg.AuditLog)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer func() { _ = auditLog.Close() }()
+	}
+
+	plan, err := secretSyncer.Plan(context.Background(), cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	for _, c := range plan.Changes {
+		if c.Reason != "" {
+			fmt.Printf("%s %-9s %s (secret: %s, %s)\n", planSymbols[c.Action], c.Action, c.Path, c.Secret, c.Reason)
+		} else {
+			fmt.Printf("%s %-9s %s (secret: %s)\n", planSymbols[c.Action], c.Action, c.Path, c.Secret)
+		}
+	}
+
+	fmt.Printf("\nPlan: %d to create, %d to update, %d to delete, %d unchanged\n",
+		plan.Count(syncer.ActionCreate),
+		plan.Count(syncer.ActionUpdate),
+		plan.Count(syncer.ActionDelete),
+		plan.Count(syncer.ActionUnchanged),
+	)
+
+	if !apply {
+		return 0
+	}
+
+	if err := secretSyncer.Apply(syncer.WithReason(context.Background(), syncer.ReasonOneShot), plan); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("✓ Apply complete\n")
+	return 0
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"unicode/utf8"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/memlock"
+	"github.com/ohauer/secrets-sync/internal/syncer"
+)
+
+func printRenderUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: secrets-sync render [options]\n")
+	fmt.Fprintf(os.Stderr, "\nRenders the files of every configured secret and prints their paths and\n")
+	fmt.Fprintf(os.Stderr, "content without writing anything. The output contains secret values.\n")
+	fmt.Fprintf(os.Stderr, "\nOptions:\n")
+	fmt.Fprintf(os.Stderr, "  -c, --config <path>   Configuration file (default: as for the service)\n")
+	fmt.Fprintf(os.Stderr, "  --secret <name>       Render only this secret (repeatable)\n")
+	fmt.Fprintf(os.Stderr, "  --data <file.json>    Render from the fields in this JSON object instead of\n")
+	fmt.Fprintf(os.Stderr, "                        reading Vault; wildcard keys need Vault\n")
+	fmt.Fprintf(os.Stderr, "\nExamples:\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync render --config config.yaml --secret db-credentials\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync render --config config.yaml --secret db-credentials --data fixture.json\n")
+}
+
+// runRender prints the files each secret would be written to
+func runRender(args []string) int {
+	var names []string
+	var dataFile string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "-h" || arg == "--help" {
+			printRenderUsage()
+			return 0
+		}
+		if i+1 >= len(args) {
+			fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", arg)
+			return 1
+		}
+		value := args[i+1]
+		i++
+
+		switch arg {
+		case "-c", "--config":
+			configFile = value
+		case "--secret":
+			names = append(names, value)
+		case "--data":
+			dataFile = value
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown option: %s\n\n", arg)
+			printRenderUsage()
+			return 1
+		}
+	}
+
+	if err := render(names, dataFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// render renders the selected secrets, or all of them, and prints each file
+func render(names []string, dataFile string) error {
+	var fixture map[string]interface{}
+	if dataFile != "" {
+		var err error
+		if fixture, err = loadFixture(dataFile); err != nil {
+			return err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	secrets, err := selectSecrets(cfg, names)
+	if err != nil {
+		return err
+	}
+
+	envCfg := config.LoadEnvConfig()
+	secretSyncer := syncer.NewSecretSyncer(newClientFactory(cfg, envCfg), newRetryConfig(envCfg)).WithAzure(newAzureClientFactory(cfg))
+
+	failed := 0
+	for _, secret := range secrets {
+		files, err := secretSyncer.Render(ctx, cfg, secret, fixture)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %s: %v\n", secret.Name, err)
+			failed++
+			continue
+		}
+		for _, f := range files {
+			fmt.Printf("# %s (secret: %s, mode: %s)\n", f.Path, f.Secret, f.Mode)
+			if !utf8.Valid(f.Content) {
+				// Keystores are binary and would garble the terminal
+				fmt.Printf("(%d bytes of binary content)\n", len(f.Content))
+			} else {
+				_, _ = os.Stdout.Write(f.Content)
+				if !bytes.HasSuffix(f.Content, []byte("\n")) {
+					fmt.Println()
+				}
+			}
+			fmt.Println()
+			memlock.Zero(f.Content)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d secrets failed to render", failed, len(secrets))
+	}
+	return nil
+}
+
+// selectSecrets returns the secrets with the given names, or all of them if
+// none are given
+func selectSecrets(cfg *config.Config, names []string) ([]config.Secret, error) {
+	if len(names) == 0 {
+		return cfg.Secrets, nil
+	}
+
+	selected := make([]config.Secret, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, secret := range cfg.Secrets {
+			if secret.Name == name {
+				selected = append(selected, secret)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("secret %q not found in %s", name, getConfigFile())
+		}
+	}
+	return selected, nil
+}
+
+// loadFixture reads the fields of a secret from a JSON object. Numbers are
+// kept as written, as they are in Vault responses.
+func loadFixture(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data file: %w", err)
+	}
+	defer memlock.Zero(data)
+
+	var fixture map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&fixture); err != nil {
+		return nil, fmt.Errorf("data file %s must hold a JSON object: %w", path, err)
+	}
+	if fixture == nil {
+		return nil, fmt.Errorf("data file %s must hold a JSON object", path)
+	}
+	return fixture, nil
+}
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+)
+
+// esoAPIVersion is the external-secrets API version of the manifests written
+const esoAPIVersion = "external-secrets.io/v1beta1"
+
+// esoGeneratorAPIVersion is the API version of the generators written for
+// database secrets
+const esoGeneratorAPIVersion = "generators.external-secrets.io/v1alpha1"
+
+// esoStoreKey is what the secrets read from one ClusterSecretStore share
+type esoStoreKey struct {
+	credentials string // Credential set name, empty for the default
+	mountPath   string // Empty for a generator, which reads no KV mount
+	kvVersion   string
+	namespace   string
+}
+
+// esoStore is a generated ClusterSecretStore
+type esoStore struct {
+	esoStoreKey
+	name string
+}
+
+// invalidObjectName matches characters not allowed in Kubernetes object names
+var invalidObjectName = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// invalidSecretKey matches characters not allowed in Kubernetes secret keys
+var invalidSecretKey = regexp.MustCompile(`[^-._a-zA-Z0-9]+`)
+
+// objectName turns a name into a Kubernetes object name
+func objectName(name string) string {
+	name = strings.Trim(invalidObjectName.ReplaceAllString(strings.ToLower(name), "-"), "-.")
+	if len(name) > 253 {
+		name = strings.Trim(name[:253], "-.")
+	}
+	if name == "" {
+		name = "secrets-sync"
+	}
+	return name
+}
+
+// shortDuration formats a duration without zero minutes and seconds, e.g.
+// 1h instead of 1h0m0s
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+// esoConverter collects the manifests generated from a secrets-sync config
+type esoConverter struct {
+	cfg       *config.Config
+	namespace string                 // Namespace of the ExternalSecrets, unset if empty
+	stores    map[esoStoreKey]string // Store names
+	order     []esoStore
+	docs      []*bytes.Buffer
+	skipped   []string
+	warned    map[string]bool
+}
+
+// convertToExternalSecrets prints the secrets of a secrets-sync config as
+// ExternalSecret manifests, with the ClusterSecretStores they read from
+func convertToExternalSecrets(cfg ConvertConfig, files []string) int {
+	if cfg.Out != "" || cfg.Split {
+		fmt.Fprintf(os.Stderr, "Error: --out and --split are not supported with --to external-secrets\n")
+		return 1
+	}
+	if len(files) > 1 {
+		fmt.Fprintf(os.Stderr, "Error: --to external-secrets converts one config, got %d\n", len(files))
+		return 1
+	}
+	path := getConfigFile()
+	if len(files) == 1 {
+		path = files[0]
+	}
+
+	nativeCfg, err := config.Load(context.Background(), path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if nativeCfg.SecretStore.IsAzureKeyVault() {
+		fmt.Fprintf(os.Stderr, "Error: only Vault and OpenBao configs can be converted to external-secrets\n")
+		return 1
+	}
+
+	c := &esoConverter{cfg: nativeCfg, namespace: cfg.Namespace, stores: make(map[esoStoreKey]string), warned: make(map[string]bool)}
+	for _, secret := range nativeCfg.Secrets {
+		c.convert(secret)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Generated from secrets-sync config %s\n", path)
+	fmt.Fprintln(&buf, "# Create the Kubernetes secrets holding the Vault credentials before applying")
+	for _, skipped := range c.skipped {
+		fmt.Fprintf(&buf, "# Not converted: %s\n", skipped)
+	}
+	for _, store := range c.order {
+		fmt.Fprintln(&buf, "---")
+		c.printStore(&buf, store)
+	}
+	for _, doc := range c.docs {
+		fmt.Fprintln(&buf, "---")
+		_, _ = buf.Write(doc.Bytes())
+	}
+	if _, err := os.Stdout.Write(buf.Bytes()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// todo records what needs manual work as a TODO comment, and warns about it
+// once
+func (c *esoConverter) todo(w *bytes.Buffer, indent, subject, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	fmt.Fprintf(w, "%s# TODO: %s\n", indent, msg)
+	if warning := subject + ": " + msg; !c.warned[warning] {
+		c.warned[warning] = true
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+}
+
+// store returns the ClusterSecretStore reading a KV path, adding it if new
+func (c *esoConverter) store(secret config.Secret, mountPath, kvVersion string) string {
+	key := esoStoreKey{
+		credentials: secret.ResolveCredentials(),
+		mountPath:   mountPath,
+		kvVersion:   kvVersion,
+		namespace:   secret.ResolveNamespace(c.cfg.SecretStore.Namespace),
+	}
+	if name, ok := c.stores[key]; ok {
+		return name
+	}
+
+	parts := []string{"vault"}
+	if key.credentials != "" {
+		parts = append(parts, key.credentials)
+	}
+	if key.namespace != "" {
+		parts = append(parts, key.namespace)
+	}
+	parts = append(parts, key.mountPath, key.kvVersion)
+	name := objectName(strings.Join(parts, "-"))
+	for i := 2; c.taken(name); i++ {
+		name = objectName(fmt.Sprintf("%s-%d", strings.Join(parts, "-"), i))
+	}
+
+	c.stores[key] = name
+	c.order = append(c.order, esoStore{esoStoreKey: key, name: name})
+	return name
+}
+
+// taken reports whether a store name is already used
+func (c *esoConverter) taken(name string) bool {
+	for _, store := range c.order {
+		if store.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// convert adds the manifests of one secret
+func (c *esoConverter) convert(secret config.Secret) {
+	if secret.IsWildcard() {
+		c.skipped = append(c.skipped, fmt.Sprintf("secret %q, wildcard key %s has no ExternalSecret equivalent", secret.Name, secret.Key))
+		fmt.Fprintf(os.Stderr, "Warning: %s: wildcard keys have no ExternalSecret equivalent, not converted\n", secret.Name)
+		return
+	}
+
+	name := objectName(secret.Name)
+	w := &bytes.Buffer{}
+	c.docs = append(c.docs, w)
+
+	if secret.IsDynamic() {
+		c.printGenerator(w, secret, name)
+		fmt.Fprintln(w, "---")
+	}
+
+	fmt.Fprintf(w, "# From secret %q\n", secret.Name)
+	fmt.Fprintf(w, "apiVersion: %s\n", esoAPIVersion)
+	fmt.Fprintln(w, "kind: ExternalSecret")
+	fmt.Fprintln(w, "metadata:")
+	fmt.Fprintf(w, "  name: %q\n", name)
+	if c.namespace != "" {
+		fmt.Fprintf(w, "  namespace: %q\n", c.namespace)
+	}
+	fmt.Fprintln(w, "spec:")
+	fmt.Fprintf(w, "  refreshInterval: %q\n", shortDuration(secret.RefreshInterval))
+
+	// Sources are merged into one secret; their fields are prefixed with the
+	// source name, as templates cannot nest them
+	templates := secret.Template.Data
+	if len(secret.Sources) > 0 {
+		templates = make(map[string]string, len(secret.Template.Data))
+		for name, tmpl := range secret.Template.Data {
+			templates[name] = prefixSourceFields(tmpl, secret.Sources)
+		}
+	}
+
+	storeName := ""
+	if !secret.IsDynamic() {
+		if len(secret.Sources) > 0 {
+			storeName = c.store(secret, secret.Sources[0].MountPath, secret.Sources[0].KVVersion)
+		} else {
+			storeName = c.store(secret, secret.MountPath, secret.KVVersion)
+		}
+		fmt.Fprintln(w, "  secretStoreRef:")
+		fmt.Fprintln(w, "    kind: ClusterSecretStore")
+		fmt.Fprintf(w, "    name: %q\n", storeName)
+	}
+
+	fmt.Fprintln(w, "  target:")
+	fmt.Fprintf(w, "    name: %q\n", name)
+	c.printTemplate(w, secret, templates)
+
+	fmt.Fprintln(w, "  dataFrom:")
+	switch {
+	case secret.IsDynamic():
+		fmt.Fprintln(w, "    - sourceRef:")
+		fmt.Fprintln(w, "        generatorRef:")
+		fmt.Fprintf(w, "          apiVersion: %s\n", esoGeneratorAPIVersion)
+		fmt.Fprintln(w, "          kind: VaultDynamicSecret")
+		fmt.Fprintf(w, "          name: %q\n", name)
+	case len(secret.Sources) > 0:
+		for _, source := range secret.Sources {
+			printExtract(w, source.Key, source.Version)
+			if other := c.store(secret, source.MountPath, source.KVVersion); other != storeName {
+				fmt.Fprintln(w, "      sourceRef:")
+				fmt.Fprintln(w, "        storeRef:")
+				fmt.Fprintln(w, "          kind: ClusterSecretStore")
+				fmt.Fprintf(w, "          name: %q\n", other)
+			}
+			fmt.Fprintln(w, "      rewrite:")
+			fmt.Fprintln(w, "        - regexp:")
+			fmt.Fprintln(w, "            source: \"(.*)\"")
+			fmt.Fprintf(w, "            target: %q\n", source.Name+"_$1")
+		}
+	default:
+		printExtract(w, secret.Key, secret.Version)
+	}
+}
+
+// printExtract prints a dataFrom entry reading all fields of a key
+func printExtract(w *bytes.Buffer, key string, version int) {
+	fmt.Fprintln(w, "    - extract:")
+	fmt.Fprintf(w, "        key: %q\n", key)
+	if version > 0 {
+		fmt.Fprintf(w, "        version: \"%d\"\n", version)
+	}
+}
+
+// printTemplate prints the target template: one key per file, named after
+// the file, holding the file's template. Without templates the fields of
+// the secret become its keys, as external-secrets does by default.
+func (c *esoConverter) printTemplate(w *bytes.Buffer, secret config.Secret, templates map[string]string) {
+	type entry struct {
+		key, path, value string
+	}
+	var entries []entry
+	used := make(map[string]bool)
+	fileTemplates := secret.FileTemplates()
+	for i, file := range secret.Files {
+		if file.Format != "" {
+			c.todo(w, "    ", secret.Name, "%s is written with format %s; external-secrets has no equivalent", file.Path, file.Format)
+			continue
+		}
+		tmpl, ok := templates[fileTemplates[i]]
+		if !ok {
+			continue
+		}
+		key := invalidSecretKey.ReplaceAllString(filepath.Base(file.Path), "_")
+		if used[key] {
+			key = invalidSecretKey.ReplaceAllString(fileTemplates[i], "_")
+		}
+		for n := 2; used[key]; n++ {
+			key = fmt.Sprintf("%s-%d", invalidSecretKey.ReplaceAllString(fileTemplates[i], "_"), n)
+		}
+		used[key] = true
+		entries = append(entries, entry{key: key, path: file.Path, value: tmpl})
+	}
+	if len(secret.Sources) > 0 {
+		c.todo(w, "    ", secret.Name, "source fields are read as <source>_<field>; check the rewritten templates")
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "    template:")
+	fmt.Fprintln(w, "      engineVersion: v2")
+	fmt.Fprintln(w, "      data:")
+	for _, e := range entries {
+		fmt.Fprintf(w, "        # %s\n", e.path)
+		fmt.Fprintf(w, "        %s: %s\n", yamlKey(e.key), yamlValue(e.value))
+	}
+}
+
+// sourceField matches a source field in a template, e.g. .db.password
+var sourceField = regexp.MustCompile(`\.([A-Za-z_][A-Za-z0-9_]*)\.([A-Za-z_][A-Za-z0-9_]*)`)
+
+// prefixSourceFields rewrites .<source>.<field> to .<source>_<field>, the
+// name the source rewrite gives the field
+func prefixSourceFields(tmpl string, sources []config.Source) string {
+	names := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		names[source.Name] = true
+	}
+	return sourceField.ReplaceAllStringFunc(tmpl, func(m string) string {
+		parts := sourceField.FindStringSubmatch(m)
+		if !names[parts[1]] {
+			return m
+		}
+		return "." + parts[1] + "_" + parts[2]
+	})
+}
+
+// printGenerator prints the VaultDynamicSecret generator reading the
+// credentials of a database secret
+func (c *esoConverter) printGenerator(w *bytes.Buffer, secret config.Secret, name string) {
+	fmt.Fprintf(w, "# Generates the credentials of secret %q\n", secret.Name)
+	fmt.Fprintf(w, "apiVersion: %s\n", esoGeneratorAPIVersion)
+	fmt.Fprintln(w, "kind: VaultDynamicSecret")
+	fmt.Fprintln(w, "metadata:")
+	fmt.Fprintf(w, "  name: %q\n", name)
+	if c.namespace != "" {
+		fmt.Fprintf(w, "  namespace: %q\n", c.namespace)
+	}
+	fmt.Fprintln(w, "spec:")
+	fmt.Fprintf(w, "  path: %q\n", "/"+strings.Trim(secret.MountPath, "/")+"/creds/"+secret.Key)
+	fmt.Fprintln(w, "  method: GET")
+	fmt.Fprintln(w, "  provider:")
+	c.printProvider(w, "    ", esoStoreKey{
+		credentials: secret.ResolveCredentials(),
+		namespace:   secret.ResolveNamespace(c.cfg.SecretStore.Namespace),
+	})
+}
+
+// printStore prints a ClusterSecretStore
+func (c *esoConverter) printStore(w *bytes.Buffer, store esoStore) {
+	fmt.Fprintf(w, "apiVersion: %s\n", esoAPIVersion)
+	fmt.Fprintln(w, "kind: ClusterSecretStore")
+	fmt.Fprintln(w, "metadata:")
+	fmt.Fprintf(w, "  name: %q\n", store.name)
+	fmt.Fprintln(w, "spec:")
+	fmt.Fprintln(w, "  provider:")
+	fmt.Fprintln(w, "    vault:")
+	c.printProvider(w, "      ", store.esoStoreKey)
+}
+
+// credentialsSecret names the Kubernetes secret holding a credential set
+func credentialsSecret(name string) string {
+	if name == "" {
+		return "vault-credentials"
+	}
+	return objectName("vault-credentials-" + name)
+}
+
+// printProvider prints the fields of a Vault provider at indent: server,
+// path and version if store has a mount, namespace and auth
+func (c *esoConverter) printProvider(w *bytes.Buffer, indent string, store esoStoreKey) {
+	ss := c.cfg.SecretStore
+	label := credentialsLabel(store.credentials)
+
+	fmt.Fprintf(w, "%sserver: %q\n", indent, ss.Address)
+	if len(ss.Addresses) > 0 {
+		c.todo(w, indent, "secretStore", "failover addresses %s are not supported by external-secrets", strings.Join(ss.Addresses, ", "))
+	}
+	if ss.TLSCACert != "" || ss.TLSCAPath != "" {
+		c.todo(w, indent, "secretStore", "set caBundle to the CA in %s", strings.TrimSpace(ss.TLSCACert+" "+ss.TLSCAPath))
+	}
+	if ss.TLSSkipVerify {
+		c.todo(w, indent, "secretStore", "tlsSkipVerify has no external-secrets equivalent, set caBundle")
+	}
+	if store.mountPath != "" {
+		fmt.Fprintf(w, "%spath: %q\n", indent, store.mountPath)
+		fmt.Fprintf(w, "%sversion: %q\n", indent, store.kvVersion)
+	}
+	if store.namespace != "" {
+		fmt.Fprintf(w, "%snamespace: %q\n", indent, store.namespace)
+	}
+
+	creds, ok := ss.GetCredentials(store.credentials)
+	if !ok {
+		creds = ss.GetDefaultCredentials()
+	}
+	if creds.AuthNamespace != "" {
+		c.todo(w, indent, label, "logs in in namespace %q; set auth.namespace if your external-secrets version supports it", creds.AuthNamespace)
+	}
+
+	secretName := credentialsSecret(store.credentials)
+	fmt.Fprintf(w, "%sauth:\n", indent)
+	switch creds.AuthMethod {
+	case "approle":
+		fmt.Fprintf(w, "%s  # Kubernetes secret %q with keys role-id and secret-id\n", indent, secretName)
+		fmt.Fprintf(w, "%s  appRole:\n", indent)
+		fmt.Fprintf(w, "%s    path: approle\n", indent)
+		fmt.Fprintf(w, "%s    roleRef:\n", indent)
+		fmt.Fprintf(w, "%s      name: %q\n", indent, secretName)
+		fmt.Fprintf(w, "%s      key: role-id\n", indent)
+		fmt.Fprintf(w, "%s    secretRef:\n", indent)
+		fmt.Fprintf(w, "%s      name: %q\n", indent, secretName)
+		fmt.Fprintf(w, "%s      key: secret-id\n", indent)
+	case "kubernetes":
+		mountPath := creds.KubernetesMountPath
+		if mountPath == "" {
+			mountPath = "kubernetes"
+		}
+		c.todo(w, indent+"  ", label, "name the service account Vault role %q is bound to", creds.KubernetesRole)
+		fmt.Fprintf(w, "%s  kubernetes:\n", indent)
+		fmt.Fprintf(w, "%s    mountPath: %q\n", indent, mountPath)
+		fmt.Fprintf(w, "%s    role: %q\n", indent, creds.KubernetesRole)
+		fmt.Fprintf(w, "%s    serviceAccountRef:\n", indent)
+		fmt.Fprintf(w, "%s      name: \"secrets-sync\"\n", indent)
+	case "cert":
+		if creds.CertRole != "" || (creds.CertMountPath != "" && creds.CertMountPath != "cert") {
+			c.todo(w, indent+"  ", label, "cert role and mount cannot be set in external-secrets, it logs in at cert")
+		}
+		fmt.Fprintf(w, "%s  # Kubernetes TLS secret %q\n", indent, secretName)
+		fmt.Fprintf(w, "%s  cert:\n", indent)
+		fmt.Fprintf(w, "%s    clientCert:\n", indent)
+		fmt.Fprintf(w, "%s      name: %q\n", indent, secretName)
+		fmt.Fprintf(w, "%s      key: tls.crt\n", indent)
+		fmt.Fprintf(w, "%s    secretRef:\n", indent)
+		fmt.Fprintf(w, "%s      name: %q\n", indent, secretName)
+		fmt.Fprintf(w, "%s      key: tls.key\n", indent)
+	default:
+		// token and tokenFile
+		fmt.Fprintf(w, "%s  # Kubernetes secret %q with key token\n", indent, secretName)
+		fmt.Fprintf(w, "%s  tokenSecretRef:\n", indent)
+		fmt.Fprintf(w, "%s    name: %q\n", indent, secretName)
+		fmt.Fprintf(w, "%s    key: token\n", indent)
+	}
+}
+
+// credentialsLabel names a credential set in warnings
+func credentialsLabel(name string) string {
+	if name == "" {
+		return "default credentials"
+	}
+	return fmt.Sprintf("credentials %q", name)
+}
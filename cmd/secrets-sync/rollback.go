@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/logger"
+	"github.com/ohauer/secrets-sync/internal/syncer"
+)
+
+func printRollbackUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: secrets-sync rollback [options] <secret>\n")
+	fmt.Fprintf(os.Stderr, "\nWrites the latest backup of every file of a secret that sets keepBackups\n")
+	fmt.Fprintf(os.Stderr, "back in place. The content replaced becomes the latest backup, so rolling\n")
+	fmt.Fprintf(os.Stderr, "back twice undoes the first rollback. Nothing is written unless every such\n")
+	fmt.Fprintf(os.Stderr, "file has a backup. MANIFEST_FILE, AUDIT_LOG and FSYNC_WRITES apply as in the\n")
+	fmt.Fprintf(os.Stderr, "service. While the service runs, use POST /api/v1/rollback/<secret> instead,\n")
+	fmt.Fprintf(os.Stderr, "so it knows about the restored files.\n")
+	fmt.Fprintf(os.Stderr, "\nOptions:\n")
+	fmt.Fprintf(os.Stderr, "  -c, --config <path>     Configuration file (default: as for the service)\n")
+	fmt.Fprintf(os.Stderr, "\nExamples:\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync rollback db-credentials\n")
+}
+
+// runRollback restores the latest backup of the files of one secret
+func runRollback(args []string) int {
+	var name string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-h" || arg == "--help":
+			printRollbackUsage()
+			return 0
+		case arg == "-c" || arg == "--config":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", arg)
+				return 1
+			}
+			configFile = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "-"):
+			fmt.Fprintf(os.Stderr, "Unknown option: %s\n\n", arg)
+			printRollbackUsage()
+			return 1
+		case name != "":
+			fmt.Fprintf(os.Stderr, "Error: only one secret can be rolled back at a time\n")
+			return 1
+		default:
+			name = arg
+		}
+	}
+	if name == "" {
+		printRollbackUsage()
+		return 1
+	}
+
+	envCfg := config.LoadEnvConfig()
+	if err := logger.Init(envCfg.LogLevel); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer logger.Sync()
+
+	if err := rollback(envCfg, name); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// rollback restores the backups of the named secret and prints each file
+func rollback(envCfg *config.EnvConfig, name string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	var secret *config.Secret
+	for i := range cfg.Secrets {
+		if cfg.Secrets[i].Name == name {
+			secret = &cfg.Secrets[i]
+			break
+		}
+	}
+	if secret == nil {
+		return fmt.Errorf("secret %q is not configured", name)
+	}
+
+	secretSyncer, err := newStandaloneSyncer(cfg, envCfg)
+	if err != nil {
+		return err
+	}
+	files, err := secretSyncer.Rollback(ctx, *secret)
+	for _, file := range files {
+		fmt.Printf("✓ %s\n", file)
+	}
+	if errors.Is(err, syncer.ErrNoBackup) {
+		return fmt.Errorf("nothing rolled back: %w", err)
+	}
+	return err
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/filewriter"
+	"github.com/ohauer/secrets-sync/internal/health"
+	"github.com/ohauer/secrets-sync/internal/logger"
+	"github.com/ohauer/secrets-sync/internal/supervisor"
+	"github.com/ohauer/secrets-sync/internal/syncer"
+	"github.com/ohauer/secrets-sync/internal/vault"
+	"go.uber.org/zap"
+)
+
+// rotationDelay collects the changes of secrets refreshed together into one
+// restart or signal of the child
+const rotationDelay = time.Second
+
+func printRunUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: secrets-sync [--config <path>] run [options] -- <command> [args...]\n")
+	fmt.Fprintf(os.Stderr, "\nSyncs every configured secret, then starts the command and keeps the secrets\n")
+	fmt.Fprintf(os.Stderr, "up to date while it runs. When a file is rewritten or a secret given with --env\n")
+	fmt.Fprintf(os.Stderr, "changes, the command is restarted, or sent --signal if only files changed.\n")
+	fmt.Fprintf(os.Stderr, "Signals are forwarded to the command, exited children are reaped when running\n")
+	fmt.Fprintf(os.Stderr, "as PID 1, and secrets-sync exits with the exit code of the command.\n")
+	fmt.Fprintf(os.Stderr, "\nOptions:\n")
+	fmt.Fprintf(os.Stderr, "  --env <secret>           Pass the fields of this secret to the command as\n")
+	fmt.Fprintf(os.Stderr, "                           environment variables (repeatable)\n")
+	fmt.Fprintf(os.Stderr, "  --signal <name>          Signal sent when only files changed, e.g. HUP\n")
+	fmt.Fprintf(os.Stderr, "                           (default: restart the command)\n")
+	fmt.Fprintf(os.Stderr, "  --kill-timeout <dur>     Wait after SIGTERM before SIGKILL (default: 10s)\n")
+	fmt.Fprintf(os.Stderr, "\nExamples:\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync run --env app-env -- myapp --flag\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync run --signal HUP -- nginx -g 'daemon off;'\n")
+}
+
+// runOptions are the options of the run command
+type runOptions struct {
+	envSecrets  []string
+	signal      os.Signal
+	killTimeout time.Duration
+	command     []string
+}
+
+// runChild runs a command under supervision and returns its exit code
+func runChild(args []string) int {
+	opts, err := parseRunArgs(args)
+	if err != nil {
+		if errors.Is(err, errHelp) {
+			printRunUsage()
+			return 0
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		printRunUsage()
+		return 1
+	}
+
+	envCfg := config.LoadEnvConfig()
+	if err := logger.Init(envCfg.LogLevel); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer logger.Sync()
+
+	code, err := superviseCommand(envCfg, opts)
+	if err != nil {
+		logger.Error("run failed", zap.Error(err))
+		return 1
+	}
+	return code
+}
+
+// errHelp is returned by parseRunArgs when usage was requested
+var errHelp = errors.New("help requested")
+
+// parseRunArgs parses the options before the command
+func parseRunArgs(args []string) (runOptions, error) {
+	opts := runOptions{killTimeout: supervisor.DefaultKillTimeout}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			return opts, errHelp
+		case "--":
+			opts.command = args[i+1:]
+		case "--env", "--signal", "--kill-timeout":
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("%s requires a value", arg)
+			}
+			i++
+			var err error
+			switch arg {
+			case "--env":
+				opts.envSecrets = append(opts.envSecrets, args[i])
+			case "--signal":
+				opts.signal, err = supervisor.ParseSignal(args[i])
+			case "--kill-timeout":
+				opts.killTimeout, err = time.ParseDuration(args[i])
+				if err == nil && opts.killTimeout <= 0 {
+					err = fmt.Errorf("--kill-timeout must be positive")
+				}
+			}
+			if err != nil {
+				return opts, err
+			}
+			continue
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return opts, fmt.Errorf("unknown option: %s", arg)
+			}
+			opts.command = args[i:]
+		}
+		break
+	}
+
+	if len(opts.command) == 0 {
+		return opts, fmt.Errorf("no command given")
+	}
+	return opts, nil
+}
+
+// superviseCommand syncs the secrets, starts the command and restarts or
+// signals it on every change until it exits
+func superviseCommand(envCfg *config.EnvConfig, opts runOptions) (int, error) {
+	// Register before the first child starts, so no signal is missed
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, supervisor.ForwardedSignals...)
+	defer signal.Stop(sigs)
+
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	envSecrets := make([]config.Secret, 0, len(opts.envSecrets))
+	for _, name := range opts.envSecrets {
+		i := slices.IndexFunc(cfg.Secrets, func(s config.Secret) bool { return s.Name == name })
+		if i < 0 {
+			return 0, fmt.Errorf("--env: secret %q not found in config", name)
+		}
+		if cfg.Secrets[i].IsWildcard() {
+			return 0, fmt.Errorf("--env: secret %q has a wildcard key", name)
+		}
+		if cfg.Secrets[i].IsDynamic() {
+			return 0, fmt.Errorf("--env: secret %q has dynamic credentials, write them to a file instead", name)
+		}
+		envSecrets = append(envSecrets, cfg.Secrets[i])
+	}
+
+	refreshJitter, err := config.ParseJitter(envCfg.RefreshJitter)
+	if err != nil {
+		return 0, fmt.Errorf("REFRESH_JITTER: %w", err)
+	}
+	readyPolicy, err := health.ParseReadyPolicy(envCfg.ReadyPolicy)
+	if err != nil {
+		return 0, fmt.Errorf("READY_POLICY: %w", err)
+	}
+
+	secretSyncer, err := newStandaloneSyncer(cfg, envCfg)
+	if err != nil {
+		return 0, err
+	}
+	var filesChanged atomic.Bool
+	secretSyncer.WithFileObserver(func(secret string, written bool) {
+		if written {
+			filesChanged.Store(true)
+		}
+	})
+	// The environment is built from the data each sync wrote, so it matches
+	// the files and Vault is not read a second time
+	written := newWrittenEnv(envSecrets)
+	secretSyncer.WithDataObserver(written.observe)
+
+	if err := filewriter.CleanupOrphanedTempFiles(outputDirectories(cfg), logger.Get()); err != nil {
+		logger.Warn("failed to cleanup orphaned temp files", zap.Error(err))
+	}
+
+	status := health.NewStatus(envCfg.StatusFile).WithReadyPolicy(readyPolicy)
+	status.SetCritical(criticalSecrets(cfg))
+	scheduler := syncer.NewScheduler(secretSyncer).
+		WithMaxConcurrentSyncs(envCfg.MaxConcurrentSyncs).
+		WithJitter(envCfg.SyncJitter).
+		WithRefreshJitter(refreshJitter).
+		WithStartupSplay(envCfg.StartupSplay).
+		WithFailureRetry(envCfg.FailureRetries, envCfg.FailureBackoff).
+		WithFailureAlert(envCfg.FailureAlertAfter)
+	defer func() {
+		_ = scheduler.Shutdown(syncer.DefaultDrainTimeout)
+		// The command is gone, nothing uses the credentials anymore
+		if envCfg.RevokeLeases {
+			if err := revokeLeases(secretSyncer); err != nil {
+				logger.Warn("failed to revoke leases", zap.Error(err))
+			}
+		}
+		if err := cleanupSecrets(secretSyncer, cfg); err != nil {
+			logger.Warn("failed to clean up secret files", zap.Error(err))
+		}
+	}()
+	notifier := newNotifier(cfg.Notifications)
+	notifier.Start()
+	defer notifier.Stop()
+	sub := scheduler.State().Subscribe(len(cfg.Secrets))
+	defer sub.Close()
+
+	for _, secret := range cfg.Secrets {
+		scheduler.AddSecret(cfg, secret)
+	}
+
+	// Start the command only once every secret is in place
+	pending := make(map[string]bool, len(cfg.Secrets))
+	for _, secret := range cfg.Secrets {
+		pending[secret.Name] = true
+	}
+	for len(pending) > 0 {
+		select {
+		case result := <-sub.C():
+			updateStatus(status, scheduler.State(), len(cfg.Secrets))
+			notifier.Observe(result)
+			if !pending[result.SecretName] {
+				continue
+			}
+			if !result.Success {
+				// A retry, or with startupMode wait the next refresh, may still succeed
+				if result.RetryAt.IsZero() && cfg.StartupMode != config.StartupModeWait {
+					return 0, fmt.Errorf("secret %s failed to sync: %w", result.SecretName, result.Error)
+				}
+				logger.Warn("secret failed to sync, waiting for it before starting the command",
+					zap.String("name", result.SecretName),
+					zap.Time("retry_at", result.RetryAt),
+					zap.Error(result.Error),
+				)
+				continue
+			}
+			delete(pending, result.SecretName)
+		case sig := <-sigs:
+			if isTermination(sig) {
+				return 128 + signalNumber(sig), nil
+			}
+		}
+	}
+
+	secretEnv := make(map[string][]string, len(envSecrets))
+	for _, secret := range envSecrets {
+		env, ok, err := written.take(secret.Name)
+		// Nothing was written when the files were up to date already
+		if !ok {
+			env, err = secretSyncer.Environment(context.Background(), cfg, secret)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("secret %s: %w", secret.Name, err)
+		}
+		secretEnv[secret.Name] = env
+	}
+	childEnv := func() []string {
+		env := os.Environ()
+		for _, secret := range envSecrets {
+			env = append(env, secretEnv[secret.Name]...)
+		}
+		return env
+	}
+
+	child := supervisor.New(opts.command, opts.killTimeout)
+	filesChanged.Store(false)
+	if err := child.Start(childEnv()); err != nil {
+		return 0, err
+	}
+	logger.Info("command started", zap.String("command", opts.command[0]))
+
+	var rotate <-chan time.Time
+	var envChanged bool
+	var terminating <-chan time.Time
+	for {
+		select {
+		case code := <-child.Exited():
+			logger.Info("command exited", zap.Int("exit_code", code))
+			return code, nil
+
+		case sig := <-sigs:
+			if err := child.Signal(sig); err != nil {
+				logger.Warn("failed to forward signal", zap.String("signal", sig.String()), zap.Error(err))
+			}
+			if isTermination(sig) && terminating == nil {
+				logger.Info("stopping command", zap.String("signal", sig.String()))
+				terminating = time.After(opts.killTimeout)
+			}
+
+		case <-terminating:
+			logger.Warn("command did not exit in time, killing it")
+			if err := child.Stop(); err != nil {
+				return 0, err
+			}
+			return 128 + int(syscall.SIGKILL), nil
+
+		case result := <-sub.C():
+			updateStatus(status, scheduler.State(), len(cfg.Secrets))
+			notifier.Observe(result)
+			if !result.Success || terminating != nil {
+				continue
+			}
+			changed := filesChanged.Swap(false) || result.Deleted
+			// Nothing is taken when the secret was unchanged and not written
+			if newEnv, ok, err := written.take(result.SecretName); ok && err != nil {
+				logger.Warn("failed to read environment, keeping the current one",
+					zap.String("name", result.SecretName),
+					zap.Error(err),
+				)
+			} else if ok && !slices.Equal(secretEnv[result.SecretName], newEnv) {
+				secretEnv[result.SecretName] = newEnv
+				envChanged = true
+				changed = true
+			}
+			if changed {
+				rotate = time.After(rotationDelay)
+			}
+
+		case <-rotate:
+			rotate = nil
+			if envChanged || opts.signal == nil {
+				logger.Info("secrets changed, restarting command", zap.Bool("environment_changed", envChanged))
+				envChanged = false
+				if err := child.Restart(childEnv()); err != nil {
+					return 0, err
+				}
+				continue
+			}
+			logger.Info("secrets changed, signalling command", zap.String("signal", opts.signal.String()))
+			if err := child.Signal(opts.signal); err != nil {
+				logger.Warn("failed to signal command", zap.Error(err))
+			}
+		}
+	}
+}
+
+// writtenEnv holds the environment of the --env secrets last written, until
+// it is taken
+type writtenEnv struct {
+	mu    sync.Mutex
+	names map[string]bool
+	env   map[string][]string
+	errs  map[string]error
+}
+
+// newWrittenEnv returns a writtenEnv for the given secrets
+func newWrittenEnv(secrets []config.Secret) *writtenEnv {
+	w := &writtenEnv{
+		names: make(map[string]bool, len(secrets)),
+		env:   make(map[string][]string),
+		errs:  make(map[string]error),
+	}
+	for _, secret := range secrets {
+		w.names[secret.Name] = true
+	}
+	return w
+}
+
+// observe is the data observer of the syncer
+func (w *writtenEnv) observe(secret string, data vault.SecretData) {
+	if !w.names[secret] {
+		return
+	}
+	env, err := filewriter.EnvVars(data)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.env[secret], w.errs[secret] = env, err
+}
+
+// take returns the environment of a secret written since the last call, ok
+// is false if it was not written
+func (w *writtenEnv) take(secret string) (env []string, ok bool, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	env, ok = w.env[secret]
+	err = w.errs[secret]
+	delete(w.env, secret)
+	delete(w.errs, secret)
+	return env, ok, err
+}
+
+// isTermination reports whether sig asks the process to exit
+func isTermination(sig os.Signal) bool {
+	return sig == syscall.SIGTERM || sig == os.Interrupt
+}
+
+// signalNumber returns the number of a signal for a shell-style exit code
+func signalNumber(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return int(s)
+	}
+	return 0
+}
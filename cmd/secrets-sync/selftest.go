@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/logger"
+	"github.com/ohauer/secrets-sync/internal/selftest"
+	"github.com/ohauer/secrets-sync/internal/syncer"
+)
+
+func printSelftestUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: secrets-sync [--config <path>] selftest [options]\n")
+	fmt.Fprintf(os.Stderr, "\nChecks that this host can run the service: loads the config, authenticates\n")
+	fmt.Fprintf(os.Stderr, "every credential set, fetches and renders every secret and probes each target\n")
+	fmt.Fprintf(os.Stderr, "directory with the configured mode and ownership. Managed files are not touched.\n")
+	fmt.Fprintf(os.Stderr, "\nOptions:\n")
+	fmt.Fprintf(os.Stderr, "  --mock         Run load, auth, fetch, render, write and reload against a\n")
+	fmt.Fprintf(os.Stderr, "                 built-in mock Vault instead of the configured one\n")
+	fmt.Fprintf(os.Stderr, "  --dir <dir>    Directory for the --mock config and files (default: temporary,\n")
+	fmt.Fprintf(os.Stderr, "                 removed afterwards)\n")
+	fmt.Fprintf(os.Stderr, "\nExamples:\n")
+	fmt.Fprintf(os.Stderr, "  sudo -u secrets-sync secrets-sync --config /etc/secrets-sync/config.yaml selftest\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync selftest --mock --dir /var/lib/secrets-sync/selftest\n")
+}
+
+// runSelftest runs the self-test and prints the outcome of every check
+func runSelftest(args []string) int {
+	mock := false
+	dir := ""
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-h", "--help":
+			printSelftestUsage()
+			return 0
+		case "--mock":
+			mock = true
+		case "--dir":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --dir requires a value\n")
+				return 1
+			}
+			dir = args[i+1]
+			i++
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown option: %s\n\n", args[i])
+			printSelftestUsage()
+			return 1
+		}
+	}
+	if dir != "" && !mock {
+		fmt.Fprintf(os.Stderr, "Error: --dir requires --mock\n")
+		return 1
+	}
+
+	// Failures are reported per check; keep log output out of the way
+	envCfg := config.LoadEnvConfig()
+	if err := logger.Init("error"); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	newFactory := func(cfg *config.Config) syncer.ClientFactory {
+		return newClientFactory(cfg, envCfg)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var report *selftest.Report
+	if mock {
+		if dir == "" {
+			tmp, err := os.MkdirTemp("", "secrets-sync-selftest-")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			defer func() { _ = os.RemoveAll(tmp) }()
+			dir = tmp
+		}
+		fmt.Printf("Running self-test against a built-in mock Vault in %s\n", dir)
+		report = selftest.RunMock(ctx, dir, newFactory)
+	} else {
+		configPath := getConfigFile()
+		fmt.Printf("Running self-test with %s\n", configPath)
+		report = selftest.RunConfig(ctx, configPath, newFactory)
+	}
+
+	for _, check := range report.Checks {
+		if check.Err != nil {
+			fmt.Printf("✗ %s: %v\n", check.Name, check.Err)
+		} else {
+			fmt.Printf("✓ %s\n", check.Name)
+		}
+	}
+
+	if failed := report.Failed(); failed > 0 {
+		fmt.Printf("\n%d of %d checks failed\n", failed, len(report.Checks))
+		return 1
+	}
+	fmt.Printf("\nAll %d checks passed\n", len(report.Checks))
+	return 0
+}
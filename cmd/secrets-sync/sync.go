@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ohauer/secrets-sync/internal/cache"
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/filewriter"
+	"github.com/ohauer/secrets-sync/internal/logger"
+	"github.com/ohauer/secrets-sync/internal/memlock"
+	"github.com/ohauer/secrets-sync/internal/state"
+	"github.com/ohauer/secrets-sync/internal/syncer"
+)
+
+func printSyncUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: secrets-sync [--config <path>] sync\n")
+	fmt.Fprintf(os.Stderr, "\nSyncs every configured secret once and exits: 0 if all secrets were written,\n")
+	fmt.Fprintf(os.Stderr, "1 otherwise. No scheduler, metrics server or config watcher is started, which\n")
+	fmt.Fprintf(os.Stderr, "suits init containers and CI pipelines. MANIFEST_FILE, CACHE_DIR, SYNC_TIMEOUT,\n")
+	fmt.Fprintf(os.Stderr, "DELETED_SECRET_ACTION, AUDIT_LOG and the retry settings apply as in the service.\n")
+	fmt.Fprintf(os.Stderr, "\nExamples:\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync --config /etc/secrets-sync/config.yaml sync\n")
+}
+
+// runSync syncs every configured secret once and prints the outcome of each
+func runSync(args []string) int {
+	for _, arg := range args {
+		switch arg {
+		case "-h", "--help":
+			printSyncUsage()
+			return 0
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown option: %s\n\n", arg)
+			printSyncUsage()
+			return 1
+		}
+	}
+
+	envCfg := config.LoadEnvConfig()
+	if err := logger.Init(envCfg.LogLevel); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer logger.Sync()
+
+	if err := syncOnce(envCfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// syncOnce runs a single synchronization pass, returning an error if any
+// secret could not be written
+func syncOnce(envCfg *config.EnvConfig) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx = syncer.WithReason(ctx, syncer.ReasonOneShot)
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	secretSyncer, err := newStandaloneSyncer(cfg, envCfg)
+	if err != nil {
+		return err
+	}
+
+	if err := filewriter.CleanupOrphanedTempFiles(outputDirectories(cfg), logger.Get()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to clean up temp files: %v\n", err)
+	}
+
+	failed := 0
+	for _, secret := range cfg.Secrets {
+		err := secretSyncer.SyncSecret(ctx, cfg, secret)
+
+		// Stale files count as synced, as they do for the service's readiness
+		var stale *syncer.StaleError
+		switch {
+		case err == nil:
+			fmt.Printf("✓ %s\n", secret.Name)
+		case errors.As(err, &stale):
+			fmt.Printf("! %s: %v\n", secret.Name, err)
+		default:
+			fmt.Printf("✗ %s: %v\n", secret.Name, err)
+			failed++
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d secrets failed to sync", failed, len(cfg.Secrets))
+	}
+	fmt.Printf("\nAll %d secrets synced\n", len(cfg.Secrets))
+	return nil
+}
+
+// newStandaloneSyncer creates a syncer for commands running without the
+// service: the cache, manifest, staleness, timeout and deletion settings
+// apply as in the service
+func newStandaloneSyncer(cfg *config.Config, envCfg *config.EnvConfig) (*syncer.SecretSyncer, error) {
+	deletionPolicy, err := syncer.ParseDeletionPolicy(envCfg.DeletedSecretAction)
+	if err != nil {
+		return nil, fmt.Errorf("DELETED_SECRET_ACTION: %w", err)
+	}
+	if deletionPolicy == syncer.DeletionQuarantine && envCfg.QuarantineDir == "" {
+		return nil, fmt.Errorf("QUARANTINE_DIR is required when DELETED_SECRET_ACTION is quarantine")
+	}
+
+	secretSyncer := syncer.NewSecretSyncer(newClientFactory(cfg, envCfg), newRetryConfig(envCfg)).WithAzure(newAzureClientFactory(cfg))
+	secretSyncer.WithMemoryCache(envCfg.MemoryCacheTTL)
+	secretSyncer.WithMaxStaleness(envCfg.MaxStaleness)
+	secretSyncer.WithSyncTimeout(envCfg.SyncTimeout)
+	secretSyncer.WithFsync(envCfg.FsyncWrites)
+	secretSyncer.WithAllowedPaths(cfg.AllowedOutputPaths)
+	secretSyncer.WithDeletionPolicy(deletionPolicy, envCfg.QuarantineDir)
+	secretSyncer.WithHashObserver(logger.RegisterContentHash)
+	redactConfigSecrets(cfg)
+
+	if envCfg.ManifestFile != "" {
+		manifest, err := state.LoadManifest(envCfg.ManifestFile)
+		if err != nil {
+			return nil, err
+		}
+		secretSyncer.WithManifest(manifest)
+	}
+	if envCfg.CacheDir != "" {
+		if envCfg.CacheKeyFile == "" {
+			return nil, fmt.Errorf("CACHE_KEY_FILE is required when CACHE_DIR is set")
+		}
+		key, err := cache.LoadKeyFile(envCfg.CacheKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		secretCache, err := cache.New(envCfg.CacheDir, key)
+		memlock.Zero(key)
+		if err != nil {
+			return nil, err
+		}
+		secretSyncer.WithCache(secretCache)
+	}
+	// Left open until the process exits; every record is synced as written
+	if envCfg.AuditLog != "" {
+		if _, err := openAuditLog(secretSyncer, envCfg.AuditLog); err != nil {
+			return nil, err
+		}
+	}
+	return secretSyncer, nil
+}
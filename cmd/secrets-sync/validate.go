@@ -1,36 +1,207 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/memlock"
+	"github.com/ohauer/secrets-sync/internal/syncer"
+	"github.com/ohauer/secrets-sync/internal/vault"
 )
 
-func validateConfig(configFile string) error {
-	cfg, err := config.Load(configFile)
-	if err != nil {
-		return fmt.Errorf("validation failed: %w", err)
+func printValidateUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: secrets-sync [--config <path>] validate [options]\n")
+	fmt.Fprintf(os.Stderr, "\nChecks the configuration file and reports every error and warning with the\n")
+	fmt.Fprintf(os.Stderr, "line it is at. The exit code is non-zero if there are errors.\n")
+	fmt.Fprintf(os.Stderr, "\nOptions:\n")
+	fmt.Fprintf(os.Stderr, "  -c, --config <path>   Configuration file (default: as for the service)\n")
+	fmt.Fprintf(os.Stderr, "  --format <format>     Output format: text (default) or json\n")
+	fmt.Fprintf(os.Stderr, "  --strict              Also connect to Vault, read every secret and render its\n")
+	fmt.Fprintf(os.Stderr, "                        templates; nothing is written\n")
+	fmt.Fprintf(os.Stderr, "\nExamples:\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync validate --config config.yaml\n")
+	fmt.Fprintf(os.Stderr, "  secrets-sync validate --config config.yaml --strict --format json\n")
+}
+
+// validateReport is the output of validate --format json
+type validateReport struct {
+	ConfigFile  string              `json:"config_file"`
+	Valid       bool                `json:"valid"`
+	Strict      bool                `json:"strict"`
+	Diagnostics []config.Diagnostic `json:"diagnostics"`
+}
+
+func runValidate(args []string) int {
+	format := "text"
+	strict := false
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-h", "--help":
+			printValidateUsage()
+			return 0
+		case "--strict":
+			strict = true
+			continue
+		}
+		if i+1 >= len(args) {
+			fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", arg)
+			return 1
+		}
+		value := args[i+1]
+		i++
+
+		switch arg {
+		case "-c", "--config":
+			configFile = value
+		case "--format":
+			format = value
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown option: %s\n\n", arg)
+			printValidateUsage()
+			return 1
+		}
+	}
+	if format != "text" && format != "json" {
+		fmt.Fprintf(os.Stderr, "Error: unsupported format: %s (supported: text, json)\n", format)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	configPath := getConfigFile()
+	cfg, diags := config.Diagnose(ctx, configPath)
+	if cfg != nil && strict {
+		diags = append(diags, checkLive(ctx, configPath, cfg)...)
+	}
+
+	report := validateReport{ConfigFile: configPath, Valid: true, Strict: strict, Diagnostics: diags}
+	for _, d := range diags {
+		if d.Severity == config.SeverityError {
+			report.Valid = false
+		}
+	}
+	if report.Diagnostics == nil {
+		report.Diagnostics = []config.Diagnostic{}
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	} else {
+		printValidateReport(report, cfg)
+	}
+
+	if !report.Valid {
+		return 1
+	}
+	return 0
+}
+
+// printValidateReport prints the diagnostics, and a summary of a valid config
+func printValidateReport(report validateReport, cfg *config.Config) {
+	for _, d := range report.Diagnostics {
+		location := report.ConfigFile
+		if d.File != "" {
+			location = d.File
+		}
+		if d.Line > 0 {
+			location = fmt.Sprintf("%s:%d", location, d.Line)
+		}
+		if d.Severity == config.SeverityWarning {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", location, d.Message)
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: %s: %s\n", location, d.Message)
+		}
+	}
+	if !report.Valid {
+		fmt.Fprintf(os.Stderr, "✗ Configuration is invalid\n")
+		return
 	}
 
 	fmt.Printf("✓ Configuration is valid\n")
 	fmt.Printf("  Vault address: %s\n", cfg.SecretStore.Address)
+	for _, address := range cfg.SecretStore.Addresses {
+		fmt.Printf("  Failover:      %s\n", address)
+	}
 	if cfg.SecretStore.Namespace != "" {
 		fmt.Printf("  Namespace:     %s\n", cfg.SecretStore.Namespace)
 	}
+	if cfg.SecretStore.AuthNamespace != "" {
+		fmt.Printf("  Auth namespace: %s\n", cfg.SecretStore.AuthNamespace)
+	}
 	fmt.Printf("  Auth method:   %s\n", cfg.SecretStore.AuthMethod)
 	fmt.Printf("  Secrets:       %d configured\n", len(cfg.Secrets))
-
-	return nil
+	if report.Strict {
+		fmt.Printf("  Strict:        every secret read and rendered\n")
+	}
 }
 
-func runValidate() int {
-	configPath := getConfigFile()
+// checkLive connects to the secret store, reads every secret and renders its
+// templates, reporting what fails. Database secrets are not read, since that
+// issues new credentials.
+func checkLive(ctx context.Context, configPath string, cfg *config.Config) []config.Diagnostic {
+	locator, err := config.NewLocator(configPath)
+	if err != nil {
+		return []config.Diagnostic{{Severity: config.SeverityError, Message: err.Error()}}
+	}
+	diag := locator.Diagnostic
 
-	if err := validateConfig(configPath); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		return 1
+	envCfg := config.LoadEnvConfig()
+	if !cfg.SecretStore.IsAzureKeyVault() {
+		client, err := vault.NewClientWithConnection(cfg.SecretStore.Address, newVaultTLSConfig(cfg, envCfg), newVaultConnection(cfg, envCfg))
+		if err == nil {
+			client.WithFailover(cfg.SecretStore.Endpoints(), nil)
+			err = client.Ping(ctx)
+		}
+		if err != nil {
+			return []config.Diagnostic{diag(config.SeverityError, fmt.Sprintf("secretStore: address %s unreachable: %v", strings.Join(cfg.SecretStore.Endpoints(), ", "), err))}
+		}
 	}
 
-	return 0
+	secretSyncer := syncer.NewSecretSyncer(newClientFactory(cfg, envCfg), newRetryConfig(envCfg)).WithAzure(newAzureClientFactory(cfg))
+
+	var diags []config.Diagnostic
+	for i, secret := range cfg.Secrets {
+		if secret.IsDynamic() {
+			diags = append(diags, diag(config.SeverityWarning, fmt.Sprintf("secrets[%d]: not read in strict mode, reading a database secret issues new credentials", i)))
+			continue
+		}
+
+		files, err := renderWithTimeout(ctx, secretSyncer, cfg, secret, envCfg.SyncTimeout)
+		if err != nil {
+			diags = append(diags, diag(config.SeverityError, fmt.Sprintf("secrets[%d]: %v", i, err)))
+			continue
+		}
+		for _, f := range files {
+			memlock.Zero(f.Content)
+		}
+	}
+	return diags
+}
+
+// renderWithTimeout renders a secret from Vault within its sync timeout
+func renderWithTimeout(ctx context.Context, secretSyncer *syncer.SecretSyncer, cfg *config.Config, secret config.Secret, timeout time.Duration) ([]syncer.RenderedFile, error) {
+	if secret.SyncTimeout > 0 {
+		timeout = secret.SyncTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return secretSyncer.Render(ctx, cfg, secret, nil)
 }
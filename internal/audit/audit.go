@@ -0,0 +1,173 @@
+// Package audit keeps an append-only, hash-chained record of secret material
+// written to disk. Records hold hashes and sizes, never the content.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Stdout is the path that writes the audit log to standard output
+const Stdout = "-"
+
+// maxLineSize bounds a single record when reading a log back
+const maxLineSize = 1 << 20
+
+// Record describes one file written with secret material
+type Record struct {
+	Seq    uint64    `json:"seq"`
+	Time   time.Time `json:"time"`
+	Secret string    `json:"secret"`
+	Source string    `json:"source"` // Vault path, or paths, the content was read from
+	Path   string    `json:"path"`
+	SHA256 string    `json:"sha256"`
+	Size   int       `json:"size"`
+	Reason string    `json:"reason"`
+	Prev   string    `json:"prev"` // SHA-256 of the previous line, empty for the first record
+}
+
+// Log appends records to a file or stdout. Each record carries the hash of
+// the line before it, so removing or editing a record breaks the chain.
+type Log struct {
+	mu   sync.Mutex
+	w    io.Writer
+	file *os.File // Nil when writing to stdout
+	seq  uint64
+	prev string
+}
+
+// Open opens the audit log at path for appending, continuing the chain of
+// the records already in it; Stdout writes to standard output instead
+func Open(path string) (*Log, error) {
+	if path == Stdout {
+		return &Log{w: os.Stdout}, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	l := &Log{w: f, file: f}
+	if err := l.resume(f); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// resume picks up the sequence number and hash of the last record in r
+func (l *Log) resume(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	var last []byte
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) > 0 {
+			last = append(last[:0], scanner.Bytes()...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+	if last == nil {
+		return nil
+	}
+
+	var rec Record
+	if err := json.Unmarshal(last, &rec); err != nil {
+		return fmt.Errorf("failed to parse last audit record: %w", err)
+	}
+	l.seq = rec.Seq
+	l.prev = hashLine(last)
+	return nil
+}
+
+// Write appends a record, filling in its sequence number, time and the hash
+// of the previous record. Records in a file are synced to disk before
+// Write returns.
+func (l *Log) Write(rec Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rec.Seq = l.seq + 1
+	rec.Prev = l.prev
+	if rec.Time.IsZero() {
+		rec.Time = time.Now().UTC()
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+
+	if _, err := l.w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	if l.file != nil {
+		if err := l.file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync audit log: %w", err)
+		}
+	}
+	l.seq = rec.Seq
+	l.prev = hashLine(line)
+	return nil
+}
+
+// Close closes the audit log file; stdout is left open
+func (l *Log) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// Verify checks the hash chain of an audit log from its first record and
+// returns the number of records in it. The error names the first record
+// that does not follow on from the one before it.
+func Verify(r io.Reader) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	var count int
+	var seq uint64
+	var prev string
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return count, fmt.Errorf("line %d: invalid record: %w", line, err)
+		}
+		// The chain starts at the first record, so leading records cannot
+		// be cut off unnoticed
+		if count == 0 && (rec.Seq != 1 || rec.Prev != "") {
+			return count, fmt.Errorf("line %d: record %d is not the first record of the log", line, rec.Seq)
+		}
+		if count > 0 && rec.Seq != seq+1 {
+			return count, fmt.Errorf("line %d: record %d follows record %d", line, rec.Seq, seq)
+		}
+		if count > 0 && rec.Prev != prev {
+			return count, fmt.Errorf("line %d: record %d does not match the hash of record %d", line, rec.Seq, seq)
+		}
+		seq = rec.Seq
+		prev = hashLine(scanner.Bytes())
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		return count, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return count, nil
+}
+
+// hashLine returns the hex SHA-256 of a record line without its newline
+func hashLine(line []byte) string {
+	sum := sha256.Sum256(line)
+	return hex.EncodeToString(sum[:])
+}
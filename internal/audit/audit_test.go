@@ -0,0 +1,84 @@
+package audit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeRecords appends a record for each path to the log at logPath
+func writeRecords(t *testing.T, logPath string, paths ...string) {
+	t.Helper()
+	l, err := Open(logPath)
+	if err != nil {
+		t.Fatalf("failed to open audit log: %v", err)
+	}
+	defer func() { _ = l.Close() }()
+	for _, path := range paths {
+		rec := Record{Secret: "app", Source: "secret/app", Path: path, SHA256: "abc", Size: 3, Reason: "schedule"}
+		if err := l.Write(rec); err != nil {
+			t.Fatalf("failed to write record: %v", err)
+		}
+	}
+}
+
+func TestLog_ChainAcrossReopen(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	writeRecords(t, logPath, "/secrets/a", "/secrets/b")
+	writeRecords(t, logPath, "/secrets/c")
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := Verify(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("expected a valid chain, got %v", err)
+	}
+	if n != 3 {
+		t.Errorf("expected 3 records, got %d", n)
+	}
+	if !strings.Contains(string(data), `"seq":3`) {
+		t.Errorf("expected the sequence to continue after reopening, got:\n%s", data)
+	}
+
+	info, err := os.Stat(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %o", info.Mode().Perm())
+	}
+}
+
+func TestVerify_Tampered(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	writeRecords(t, logPath, "/secrets/a", "/secrets/b", "/secrets/c")
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+
+	tests := []struct {
+		name string
+		log  string
+		want string
+	}{
+		{"edited", strings.Replace(string(data), "/secrets/b", "/secrets/x", 1), "record 3 does not match the hash of record 2"},
+		{"removed", lines[0] + lines[2], "record 3 follows record 1"},
+		{"first removed", lines[1] + lines[2], "line 1: record 2 is not the first record of the log"},
+		{"leading removed", lines[2], "line 1: record 3 is not the first record of the log"},
+		{"garbage", lines[0] + "not json\n", "line 2: invalid record"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Verify(strings.NewReader(tt.log))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/errkind"
+)
+
+// AuthMethod represents the authentication method
+type AuthMethod string
+
+const (
+	AuthMethodManagedIdentity AuthMethod = "managedIdentity"
+	AuthMethodClientSecret    AuthMethod = "clientSecret"
+)
+
+const (
+	// DefaultAuthorityHost is the Microsoft Entra ID endpoint of the public cloud
+	DefaultAuthorityHost = "https://login.microsoftonline.com"
+
+	// imdsEndpoint is the token endpoint of the Azure Instance Metadata Service
+	imdsEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+
+	// tokenRefreshMargin is how long before expiry an access token is renewed
+	tokenRefreshMargin = 5 * time.Minute
+)
+
+// AuthConfig holds authentication configuration
+type AuthConfig struct {
+	Method        AuthMethod
+	TenantID      string
+	ClientID      string // Application with clientSecret, user-assigned identity with managedIdentity (optional)
+	ClientSecret  string
+	AuthorityHost string // Defaults to AZURE_AUTHORITY_HOST, then DefaultAuthorityHost
+}
+
+type accessToken struct {
+	value     string
+	expiresAt time.Time
+}
+
+// tokenSource requests a new access token
+type tokenSource func(ctx context.Context) (accessToken, error)
+
+// Authenticate sets how the client obtains access tokens and requests the
+// first one, so bad credentials are reported right away
+func (c *Client) Authenticate(ctx context.Context, config AuthConfig) error {
+	var source tokenSource
+	switch config.Method {
+	case AuthMethodManagedIdentity:
+		source = c.managedIdentity(config.ClientID)
+	case AuthMethodClientSecret:
+		if config.TenantID == "" || config.ClientID == "" || config.ClientSecret == "" {
+			return errkind.Wrap(errkind.Auth, fmt.Errorf("tenantId, clientId and clientSecret are required for clientSecret auth"))
+		}
+		source = c.clientSecret(config)
+	default:
+		return errkind.Wrap(errkind.Auth, fmt.Errorf("unsupported auth method: %s", config.Method))
+	}
+
+	c.mu.Lock()
+	c.source = source
+	c.token = accessToken{}
+	c.mu.Unlock()
+
+	_, err := c.accessToken(ctx)
+	return err
+}
+
+// accessToken returns a cached access token, renewing it shortly before it expires
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.source == nil {
+		return "", errkind.Wrap(errkind.Auth, fmt.Errorf("client is not authenticated"))
+	}
+	if c.token.value != "" && time.Until(c.token.expiresAt) > tokenRefreshMargin {
+		return c.token.value, nil
+	}
+
+	token, err := c.source(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.token = token
+	return token.value, nil
+}
+
+// clearToken drops the cached access token, e.g. after it was rejected
+func (c *Client) clearToken() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = accessToken{}
+}
+
+// managedIdentity requests tokens for the identity of the VM, container or
+// App Service the daemon runs on
+func (c *Client) managedIdentity(clientID string) tokenSource {
+	// App Service and Container Apps provide their own endpoint
+	endpoint, header := os.Getenv("IDENTITY_ENDPOINT"), os.Getenv("IDENTITY_HEADER")
+
+	return func(ctx context.Context) (accessToken, error) {
+		query := url.Values{"resource": {c.resource}}
+		if clientID != "" {
+			query.Set("client_id", clientID)
+		}
+
+		var req *http.Request
+		var err error
+		if endpoint != "" && header != "" {
+			query.Set("api-version", "2019-08-01")
+			req, err = http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
+			if err == nil {
+				req.Header.Set("X-IDENTITY-HEADER", header)
+			}
+		} else {
+			query.Set("api-version", "2018-02-01")
+			req, err = http.NewRequestWithContext(ctx, http.MethodGet, c.imdsEndpoint+"?"+query.Encode(), nil)
+			if err == nil {
+				req.Header.Set("Metadata", "true")
+			}
+		}
+		if err != nil {
+			return accessToken{}, fmt.Errorf("failed to create token request: %w", err)
+		}
+		return c.requestToken(req, "managed identity")
+	}
+}
+
+// clientSecret requests tokens for an application registration
+func (c *Client) clientSecret(config AuthConfig) tokenSource {
+	authority := config.AuthorityHost
+	if authority == "" {
+		authority = os.Getenv("AZURE_AUTHORITY_HOST")
+	}
+	if authority == "" {
+		authority = DefaultAuthorityHost
+	}
+	endpoint := fmt.Sprintf("%s/%s/oauth2/v2.0/token", strings.TrimSuffix(authority, "/"), url.PathEscape(config.TenantID))
+
+	return func(ctx context.Context) (accessToken, error) {
+		form := url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {config.ClientID},
+			"client_secret": {config.ClientSecret},
+			"scope":         {c.resource + "/.default"},
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+		if err != nil {
+			return accessToken{}, fmt.Errorf("failed to create token request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return c.requestToken(req, "client secret")
+	}
+}
+
+// requestToken sends a token request. Failures are classified as Auth,
+// unless the token endpoint was unreachable.
+func (c *Client) requestToken(req *http.Request, method string) (accessToken, error) {
+	body, err := c.do(req)
+	if err != nil {
+		err = fmt.Errorf("%s login failed: %w", method, err)
+		if errkind.Of(err) == errkind.Network {
+			return accessToken{}, err
+		}
+		return accessToken{}, &errkind.Error{Kind: errkind.Auth, Err: err}
+	}
+
+	var resp struct {
+		AccessToken string      `json:"access_token"`
+		ExpiresIn   json.Number `json:"expires_in"`
+		ExpiresOn   json.Number `json:"expires_on"` // Unix time, instead of expires_in from App Service and Container Apps
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return accessToken{}, errkind.Wrap(errkind.Auth, fmt.Errorf("%s login failed: invalid token response: %w", method, err))
+	}
+	if resp.AccessToken == "" {
+		return accessToken{}, errkind.Wrap(errkind.Auth, fmt.Errorf("%s login failed: no access token in response", method))
+	}
+
+	var expiresAt time.Time
+	switch {
+	case resp.ExpiresIn != "":
+		expiresIn, err := strconv.Atoi(resp.ExpiresIn.String())
+		if err != nil {
+			return accessToken{}, errkind.Wrap(errkind.Auth, fmt.Errorf("%s login failed: invalid expires_in %q", method, resp.ExpiresIn))
+		}
+		expiresAt = time.Now().Add(time.Duration(expiresIn) * time.Second)
+	case resp.ExpiresOn != "":
+		expiresOn, err := strconv.ParseInt(resp.ExpiresOn.String(), 10, 64)
+		if err != nil {
+			return accessToken{}, errkind.Wrap(errkind.Auth, fmt.Errorf("%s login failed: invalid expires_on %q", method, resp.ExpiresOn))
+		}
+		expiresAt = time.Unix(expiresOn, 0)
+	default:
+		return accessToken{}, errkind.Wrap(errkind.Auth, fmt.Errorf("%s login failed: no expiry in response", method))
+	}
+	return accessToken{value: resp.AccessToken, expiresAt: expiresAt}, nil
+}
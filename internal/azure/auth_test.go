@@ -0,0 +1,180 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/errkind"
+)
+
+func TestAuthenticate_ManagedIdentity(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.Header.Get("Metadata") != "true" {
+			t.Error("expected Metadata header")
+		}
+		if query.Get("resource") != "https://vault.azure.net" || query.Get("client_id") != "identity-id" || query.Get("api-version") != "2018-02-01" {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+		_, _ = w.Write([]byte(`{"access_token": "token", "expires_in": "86399", "token_type": "Bearer"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("https://myvault.vault.azure.net")
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	client.imdsEndpoint = server.URL
+
+	if err := client.Authenticate(context.Background(), AuthConfig{Method: AuthMethodManagedIdentity, ClientID: "identity-id"}); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if until := time.Until(client.token.expiresAt); until < 23*time.Hour {
+		t.Errorf("expected the token to expire in a day, got %s", until)
+	}
+}
+
+func TestAuthenticate_AppServiceIdentity(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-IDENTITY-HEADER") != "header-secret" || r.URL.Query().Get("api-version") != "2019-08-01" {
+			t.Errorf("unexpected request: %s %v", r.URL, r.Header)
+		}
+		// Unlike IMDS, the response holds the expiry as a Unix time string
+		_, _ = fmt.Fprintf(w, `{"access_token": "token", "expires_on": "%d", "resource": "https://vault.azure.net", "token_type": "Bearer", "client_id": "identity-id"}`,
+			time.Now().Add(time.Hour).Unix())
+	}))
+	defer server.Close()
+	t.Setenv("IDENTITY_ENDPOINT", server.URL)
+	t.Setenv("IDENTITY_HEADER", "header-secret")
+
+	client, err := NewClient("https://myvault.vault.azure.net")
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	if err := client.Authenticate(context.Background(), AuthConfig{Method: AuthMethodManagedIdentity}); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if until := time.Until(client.token.expiresAt); until < 59*time.Minute || until > time.Hour {
+		t.Errorf("expected the token to expire in an hour, got %s", until)
+	}
+}
+
+func TestAuthenticate_ClientSecret(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/tenant-id/oauth2/v2.0/token" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("failed to parse form: %v", err)
+		}
+		if r.PostForm.Get("grant_type") != "client_credentials" ||
+			r.PostForm.Get("client_id") != "app-id" ||
+			r.PostForm.Get("client_secret") != "app-secret" ||
+			r.PostForm.Get("scope") != "https://vault.azure.cn/.default" {
+			t.Errorf("unexpected form: %v", r.PostForm)
+		}
+		_, _ = w.Write([]byte(`{"token_type": "Bearer", "expires_in": 3599, "access_token": "token"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("https://myvault.vault.azure.cn")
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	config := AuthConfig{
+		Method:        AuthMethodClientSecret,
+		TenantID:      "tenant-id",
+		ClientID:      "app-id",
+		ClientSecret:  "app-secret",
+		AuthorityHost: server.URL,
+	}
+	if err := client.Authenticate(context.Background(), config); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+}
+
+func TestAuthenticate_InvalidClientSecret(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error": "invalid_client", "error_description": "AADSTS7000215: Invalid client secret provided."}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("https://myvault.vault.azure.net")
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	err = client.Authenticate(context.Background(), AuthConfig{
+		Method:        AuthMethodClientSecret,
+		TenantID:      "tenant-id",
+		ClientID:      "app-id",
+		ClientSecret:  "wrong",
+		AuthorityHost: server.URL,
+	})
+	if errkind.Of(err) != errkind.Auth {
+		t.Fatalf("expected auth error, got %v", err)
+	}
+	if want := "client secret login failed: status 401: invalid_client: AADSTS7000215: Invalid client secret provided."; err.Error() != want {
+		t.Errorf("expected %q, got %q", want, err.Error())
+	}
+}
+
+func TestAuthenticate_Unreachable(t *testing.T) {
+	client, err := NewClient("https://myvault.vault.azure.net")
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	client.imdsEndpoint = "http://127.0.0.1:1/token"
+
+	err = client.Authenticate(context.Background(), AuthConfig{Method: AuthMethodManagedIdentity})
+	if errkind.Of(err) != errkind.Network {
+		t.Errorf("expected network error, got %v", err)
+	}
+}
+
+func TestAuthenticate_Invalid(t *testing.T) {
+	client, err := NewClient("https://myvault.vault.azure.net")
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	for _, config := range []AuthConfig{
+		{Method: "token"},
+		{Method: AuthMethodClientSecret, TenantID: "tenant-id", ClientID: "app-id"},
+	} {
+		if err := client.Authenticate(context.Background(), config); errkind.Of(err) != errkind.Auth {
+			t.Errorf("%+v: expected auth error, got %v", config, err)
+		}
+	}
+}
+
+func TestAccessToken_RenewsBeforeExpiry(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`{"access_token": "token", "expires_in": "3600"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("https://myvault.vault.azure.net")
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	client.imdsEndpoint = server.URL
+	if err := client.Authenticate(context.Background(), AuthConfig{Method: AuthMethodManagedIdentity}); err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+
+	if _, err := client.accessToken(context.Background()); err != nil || requests != 1 {
+		t.Fatalf("expected the cached token, got %d requests (%v)", requests, err)
+	}
+
+	client.token.expiresAt = time.Now().Add(time.Minute)
+	if _, err := client.accessToken(context.Background()); err != nil || requests != 2 {
+		t.Errorf("expected the token to be renewed, got %d requests (%v)", requests, err)
+	}
+}
@@ -0,0 +1,196 @@
+// Package azure reads secrets, keys and certificates from Azure Key Vault
+// through its REST API
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/errkind"
+)
+
+const (
+	// APIVersion is the Key Vault REST API version used
+	APIVersion = "7.4"
+	// MaxResponseSize is the maximum allowed size for Key Vault responses (10MB)
+	MaxResponseSize = 10 * 1024 * 1024
+)
+
+// Object types a secret can be read as
+const (
+	ObjectSecret      = "secret"
+	ObjectKey         = "key"
+	ObjectCertificate = "certificate"
+)
+
+// ErrNotFound is returned when an object does not exist
+var ErrNotFound = errors.New("object not found")
+
+// ResponseError is an error response of Key Vault or Microsoft Entra ID
+type ResponseError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *ResponseError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("status %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Client reads objects from a single Azure Key Vault
+type Client struct {
+	vaultURL     string
+	resource     string // Audience of access tokens, e.g. https://vault.azure.net
+	httpClient   *http.Client
+	imdsEndpoint string
+
+	mu     sync.Mutex
+	source tokenSource
+	token  accessToken
+}
+
+// NewClient creates a client for the vault at vaultURL, e.g.
+// https://myvault.vault.azure.net
+func NewClient(vaultURL string) (*Client, error) {
+	u, err := url.Parse(vaultURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key vault URL: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid key vault URL: %s", vaultURL)
+	}
+
+	// Tokens are issued for the vault's DNS suffix, which differs between
+	// the public and sovereign clouds
+	resource := "https://vault.azure.net"
+	host := u.Hostname()
+	if _, suffix, ok := strings.Cut(host, "."); ok && strings.Contains(suffix, ".") && net.ParseIP(host) == nil {
+		resource = "https://" + suffix
+	}
+
+	return &Client{
+		vaultURL:     strings.TrimSuffix(u.String(), "/"),
+		resource:     resource,
+		httpClient:   &http.Client{Timeout: 30 * time.Second},
+		imdsEndpoint: imdsEndpoint,
+	}, nil
+}
+
+// Fetch reads the latest version of an object and returns its fields for
+// templates. An empty objectType reads a secret.
+func (c *Client) Fetch(ctx context.Context, objectType, name string) (map[string]interface{}, error) {
+	switch objectType {
+	case ObjectSecret, "":
+		return c.fetchSecret(ctx, name)
+	case ObjectKey:
+		return c.fetchKey(ctx, name)
+	case ObjectCertificate:
+		return c.fetchCertificate(ctx, name)
+	default:
+		return nil, fmt.Errorf("unsupported object type: %s", objectType)
+	}
+}
+
+// get reads an object from the vault and decodes it into out
+func (c *Client) get(ctx context.Context, collection, name string, out interface{}) error {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/%s/%s?api-version=%s", c.vaultURL, collection, url.PathEscape(name), APIVersion)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	body, err := c.do(req)
+	if err != nil {
+		var respErr *ResponseError
+		if errors.As(err, &respErr) && respErr.StatusCode == http.StatusUnauthorized {
+			c.clearToken()
+		}
+		return fmt.Errorf("failed to read %s %s: %w", strings.TrimSuffix(collection, "s"), name, err)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to decode %s %s: %w", strings.TrimSuffix(collection, "s"), name, err)
+	}
+	return nil
+}
+
+// do sends a request and returns the body of a successful response
+func (c *Client) do(req *http.Request) ([]byte, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, errkind.Wrap(errkind.Network, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxResponseSize+1))
+	if err != nil {
+		return nil, errkind.Wrap(errkind.Network, fmt.Errorf("failed to read response: %w", err))
+	}
+	if len(body) > MaxResponseSize {
+		return nil, fmt.Errorf("response exceeds %d bytes", MaxResponseSize)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+// responseError decodes an error response and classifies it by status
+func responseError(status int, body []byte) error {
+	respErr := &ResponseError{StatusCode: status}
+
+	// Key Vault nests the error, Microsoft Entra ID does not
+	var parsed struct {
+		Error json.RawMessage `json:"error"`
+		Desc  string          `json:"error_description"`
+	}
+	if json.Unmarshal(body, &parsed) == nil {
+		var nested struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(parsed.Error, &nested) == nil {
+			respErr.Code, respErr.Message = nested.Code, nested.Message
+		} else if json.Unmarshal(parsed.Error, &respErr.Code) == nil {
+			respErr.Message = parsed.Desc
+		}
+	}
+
+	var err error = respErr
+	if status == http.StatusNotFound {
+		err = fmt.Errorf("%w: %w", ErrNotFound, respErr)
+	}
+	return errkind.Wrap(kindOf(status), err)
+}
+
+// kindOf classifies an HTTP status of Key Vault
+func kindOf(status int) errkind.Kind {
+	switch {
+	case status == http.StatusUnauthorized:
+		return errkind.Auth
+	case status == http.StatusForbidden:
+		return errkind.Permission
+	case status == http.StatusNotFound:
+		return errkind.NotFound
+	case status == http.StatusTooManyRequests, status >= 500:
+		return errkind.Network
+	}
+	return errkind.Unknown
+}
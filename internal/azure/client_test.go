@@ -0,0 +1,152 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ohauer/secrets-sync/internal/errkind"
+)
+
+// newTestClient returns a client authenticated with a managed identity,
+// whose vault and token endpoint are served by handler. Token requests are
+// answered with a token numbered by tokens.
+func newTestClient(t *testing.T, tokens *atomic.Int32, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			n := tokens.Add(1)
+			_, _ = fmt.Fprintf(w, `{"access_token": "token-%d", "expires_in": "3600"}`, n)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	client.imdsEndpoint = server.URL + "/token"
+	if err := client.Authenticate(context.Background(), AuthConfig{Method: AuthMethodManagedIdentity}); err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+	return client
+}
+
+func TestNewClient_Resource(t *testing.T) {
+	tests := map[string]string{
+		"https://myvault.vault.azure.net":         "https://vault.azure.net",
+		"https://myvault.vault.azure.cn/":         "https://vault.azure.cn",
+		"https://myvault.vault.usgovcloudapi.net": "https://vault.usgovcloudapi.net",
+		"http://127.0.0.1:8200":                   "https://vault.azure.net",
+	}
+	for vaultURL, want := range tests {
+		client, err := NewClient(vaultURL)
+		if err != nil {
+			t.Fatalf("NewClient(%q) failed: %v", vaultURL, err)
+		}
+		if client.resource != want {
+			t.Errorf("NewClient(%q): expected resource %s, got %s", vaultURL, want, client.resource)
+		}
+	}
+
+	if _, err := NewClient("myvault"); err == nil {
+		t.Error("expected error for URL without scheme")
+	}
+}
+
+func TestFetch_Secret(t *testing.T) {
+	var tokens atomic.Int32
+	client := newTestClient(t, &tokens, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/secrets/db-password" || r.URL.Query().Get("api-version") != APIVersion {
+			t.Errorf("unexpected request: %s", r.URL)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer token-1" {
+			t.Errorf("unexpected authorization: %q", got)
+		}
+		_, _ = w.Write([]byte(`{"value": "s3cret", "contentType": "text/plain", "id": "https://v/secrets/db-password/abc"}`))
+	})
+
+	data, err := client.Fetch(context.Background(), ObjectSecret, "db-password")
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if data["value"] != "s3cret" || data["contentType"] != "text/plain" {
+		t.Errorf("unexpected data: %v", data)
+	}
+}
+
+func TestFetch_Errors(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		kind   errkind.Kind
+	}{
+		{http.StatusNotFound, `{"error": {"code": "SecretNotFound", "message": "A secret with (name/id) missing was not found in this key vault."}}`, errkind.NotFound},
+		{http.StatusForbidden, `{"error": {"code": "Forbidden", "message": "The user does not have secrets get permission"}}`, errkind.Permission},
+		{http.StatusTooManyRequests, `{"error": {"code": "Throttled", "message": "Request was not processed because too many requests were received"}}`, errkind.Network},
+		{http.StatusServiceUnavailable, ``, errkind.Network},
+	}
+
+	for _, tt := range tests {
+		var tokens atomic.Int32
+		client := newTestClient(t, &tokens, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			_, _ = w.Write([]byte(tt.body))
+		})
+
+		_, err := client.Fetch(context.Background(), ObjectSecret, "missing")
+		if err == nil {
+			t.Fatalf("status %d: expected error", tt.status)
+		}
+		if kind := errkind.Of(err); kind != tt.kind {
+			t.Errorf("status %d: expected kind %s, got %s (%v)", tt.status, tt.kind, kind, err)
+		}
+		if errors.Is(err, ErrNotFound) != (tt.status == http.StatusNotFound) {
+			t.Errorf("status %d: unexpected ErrNotFound match: %v", tt.status, err)
+		}
+	}
+}
+
+func TestFetch_RenewsRejectedToken(t *testing.T) {
+	var tokens atomic.Int32
+	client := newTestClient(t, &tokens, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error": {"code": "Unauthorized", "message": "AKV10032: Invalid issuer."}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"value": "s3cret"}`))
+	})
+
+	_, err := client.Fetch(context.Background(), ObjectSecret, "db-password")
+	if errkind.Of(err) != errkind.Auth || !strings.Contains(err.Error(), "AKV10032") {
+		t.Fatalf("expected auth error, got %v", err)
+	}
+
+	data, err := client.Fetch(context.Background(), ObjectSecret, "db-password")
+	if err != nil {
+		t.Fatalf("expected a new token to be requested, got %v", err)
+	}
+	if data["value"] != "s3cret" || tokens.Load() != 2 {
+		t.Errorf("unexpected data %v after %d tokens", data, tokens.Load())
+	}
+}
+
+func TestFetch_UnsupportedObjectType(t *testing.T) {
+	var tokens atomic.Int32
+	client := newTestClient(t, &tokens, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request: %s", r.URL)
+	})
+
+	if _, err := client.Fetch(context.Background(), "blob", "name"); err == nil {
+		t.Error("expected error for unsupported object type")
+	}
+}
@@ -0,0 +1,268 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"golang.org/x/crypto/pkcs12"
+)
+
+// Content types of the secret backing a certificate
+const (
+	contentTypePEM    = "application/x-pem-file"
+	contentTypePKCS12 = "application/x-pkcs12"
+)
+
+type secretBundle struct {
+	Value       string `json:"value"`
+	ContentType string `json:"contentType"`
+}
+
+// fetchSecret reads a secret: its value and content type
+func (c *Client) fetchSecret(ctx context.Context, name string) (map[string]interface{}, error) {
+	var secret secretBundle
+	if err := c.get(ctx, "secrets", name, &secret); err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{"value": secret.Value}
+	if secret.ContentType != "" {
+		data["contentType"] = secret.ContentType
+	}
+	return data, nil
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// fetchKey reads the public part of a key: its ID, type, JSON Web Key and,
+// for RSA and EC keys, its PEM encoding
+func (c *Client) fetchKey(ctx context.Context, name string) (map[string]interface{}, error) {
+	var bundle struct {
+		Key json.RawMessage `json:"key"`
+	}
+	if err := c.get(ctx, "keys", name, &bundle); err != nil {
+		return nil, err
+	}
+	var key jsonWebKey
+	if err := json.Unmarshal(bundle.Key, &key); err != nil {
+		return nil, fmt.Errorf("failed to decode key %s: %w", name, err)
+	}
+
+	data := map[string]interface{}{
+		"kid": key.Kid,
+		"kty": key.Kty,
+		"jwk": string(bundle.Key),
+	}
+	pub, err := key.publicKey()
+	if err != nil {
+		return nil, fmt.Errorf("invalid key %s: %w", name, err)
+	}
+	if pub != nil {
+		der, err := x509.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode key %s: %w", name, err)
+		}
+		data["publicKey"] = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	}
+	return data, nil
+}
+
+// publicKey decodes an RSA or EC key; other key types return nil
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA", "RSA-HSM":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent: %w", err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("exponent too large")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC", "EC-HSM":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, nil
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x coordinate: %w", err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y coordinate: %w", err)
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) > size || len(y) > size {
+			return nil, errors.New("coordinates too long for curve")
+		}
+		point := make([]byte, 1+2*size)
+		point[0] = 4 // Uncompressed
+		copy(point[1+size-len(x):], x)
+		copy(point[1+2*size-len(y):], y)
+		return ecdsa.ParseUncompressedPublicKey(curve, point)
+	}
+	return nil, nil
+}
+
+// fetchCertificate reads a certificate. If its key is exportable, the
+// private key and chain are read from the secret backing it.
+func (c *Client) fetchCertificate(ctx context.Context, name string) (map[string]interface{}, error) {
+	var bundle struct {
+		Cer    string `json:"cer"`
+		Policy struct {
+			KeyProps struct {
+				Exportable bool `json:"exportable"`
+			} `json:"key_props"`
+		} `json:"policy"`
+	}
+	if err := c.get(ctx, "certificates", name, &bundle); err != nil {
+		return nil, err
+	}
+	der, err := base64.StdEncoding.DecodeString(bundle.Cer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate %s: %w", name, err)
+	}
+
+	data := map[string]interface{}{
+		"certificate": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+	}
+	if !bundle.Policy.KeyProps.Exportable {
+		return data, nil
+	}
+
+	var secret secretBundle
+	if err := c.get(ctx, "secrets", name, &secret); err != nil {
+		return nil, fmt.Errorf("failed to read private key of certificate %s: %w", name, err)
+	}
+	key, chain, err := decodeCertificateSecret(secret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key of certificate %s: %w", name, err)
+	}
+	data["privateKey"] = string(key)
+	data["chain"] = string(chain)
+	data["bundle"] = string(chain) + string(key)
+	return data, nil
+}
+
+// decodeCertificateSecret splits the secret backing a certificate into a
+// PKCS#8 private key and the certificate chain, leaf first, both PEM encoded
+func decodeCertificateSecret(secret secretBundle) (key, chain []byte, err error) {
+	var blocks []*pem.Block
+	switch secret.ContentType {
+	case contentTypePKCS12:
+		pfx, err := base64.StdEncoding.DecodeString(secret.Value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid PKCS#12 encoding: %w", err)
+		}
+		if blocks, err = pkcs12.ToPEM(pfx, ""); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode PKCS#12: %w", err)
+		}
+	case contentTypePEM:
+		rest := []byte(secret.Value)
+		for {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			blocks = append(blocks, block)
+		}
+	default:
+		return nil, nil, fmt.Errorf("unsupported content type %q", secret.ContentType)
+	}
+
+	var privateKey crypto.Signer
+	var certs []*x509.Certificate
+	for _, block := range blocks {
+		switch block.Type {
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid certificate: %w", err)
+			}
+			certs = append(certs, cert)
+		case "PRIVATE KEY", "RSA PRIVATE KEY", "EC PRIVATE KEY":
+			if privateKey != nil {
+				return nil, nil, errors.New("more than one private key")
+			}
+			if privateKey, err = parsePrivateKey(block.Bytes); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if privateKey == nil {
+		return nil, nil, errors.New("no private key")
+	}
+	if len(certs) == 0 {
+		return nil, nil, errors.New("no certificate")
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+	key = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	// PKCS#12 does not order certificates; the leaf is the one matching the key
+	for i, cert := range certs {
+		if pub, ok := cert.PublicKey.(interface{ Equal(crypto.PublicKey) bool }); ok && pub.Equal(privateKey.Public()) {
+			certs = append([]*x509.Certificate{cert}, append(certs[:i:i], certs[i+1:]...)...)
+			break
+		}
+	}
+	var buf bytes.Buffer
+	for _, cert := range certs {
+		_ = pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+	return key, buf.Bytes(), nil
+}
+
+// parsePrivateKey parses a PKCS#8, PKCS#1 or SEC 1 private key. PKCS#12
+// decoding labels every key PRIVATE KEY, whatever its encoding.
+func parsePrivateKey(der []byte) (crypto.Signer, error) {
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer, nil
+		}
+		return nil, errors.New("unsupported private key type")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("unsupported private key encoding")
+}
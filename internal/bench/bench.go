@@ -0,0 +1,276 @@
+// Package bench measures sync throughput and resource usage by syncing
+// synthetic secrets from an in-process mock Vault
+package bench
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/syncer"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+// sampleInterval is how often memory and goroutines are sampled
+const sampleInterval = 250 * time.Millisecond
+
+// Options controls the size and length of a benchmark run
+type Options struct {
+	Secrets   int           // Number of synthetic secrets
+	Duration  time.Duration // How long to keep syncing
+	Interval  time.Duration // Refresh interval of every secret
+	Latency   time.Duration // Artificial delay of every mock Vault read
+	OutputDir string        // Directory the secret files are written to
+
+	// NewClientFactory builds the Vault client factory for the generated
+	// config; nil uses plain clients with token auth
+	NewClientFactory func(cfg *config.Config) syncer.ClientFactory
+	Retry            vault.RetryConfig
+}
+
+// Latencies summarizes a set of durations
+type Latencies struct {
+	Count int
+	P50   time.Duration
+	P95   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// Report holds the results of a benchmark run
+type Report struct {
+	Secrets        int
+	Elapsed        time.Duration
+	InitialSync    time.Duration // Until every secret synced once, zero if never reached
+	Syncs          int
+	Failures       int
+	SyncLatency    Latencies
+	WriteLatency   Latencies
+	VaultRequests  int64
+	PeakHeapBytes  uint64
+	PeakGoroutines int
+	GCCycles       uint32
+}
+
+// SyncsPerSecond returns the sync throughput
+func (r *Report) SyncsPerSecond() float64 {
+	return perSecond(int64(r.Syncs), r.Elapsed)
+}
+
+// VaultQPS returns the rate of KV reads served by the mock Vault
+func (r *Report) VaultQPS() float64 {
+	return perSecond(r.VaultRequests, r.Elapsed)
+}
+
+// NewConfig generates a config with n secrets of one file each, read from
+// the mock Vault at addr. Refresh intervals below the 30s minimum enforced
+// for real configs are allowed, so the config is not validated.
+func NewConfig(addr string, n int, interval time.Duration, outputDir string) *config.Config {
+	cfg := &config.Config{
+		SecretStore: config.SecretStore{
+			Address:    addr,
+			AuthMethod: "token",
+			Token:      "bench",
+		},
+		Secrets: make([]config.Secret, 0, n),
+	}
+
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("bench-%05d", i)
+		cfg.Secrets = append(cfg.Secrets, config.Secret{
+			Name:            name,
+			Key:             "bench/" + name,
+			MountPath:       MountPath,
+			KVVersion:       "v2",
+			RefreshInterval: interval,
+			Template: config.Template{
+				Data: map[string]string{"credentials": "{{ .username }}:{{ .password }}:{{ .version }}\n"},
+			},
+			Files: []config.File{{
+				Path:     filepath.Join(outputDir, name, "credentials"),
+				Template: "credentials",
+				Mode:     "0600",
+			}},
+		})
+	}
+
+	return cfg
+}
+
+// Run syncs opts.Secrets synthetic secrets against a mock Vault until
+// opts.Duration has passed or ctx is cancelled, and reports what it measured
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	if opts.Secrets <= 0 {
+		return nil, fmt.Errorf("number of secrets must be positive")
+	}
+	if opts.Duration <= 0 || opts.Interval <= 0 {
+		return nil, fmt.Errorf("duration and interval must be positive")
+	}
+	if opts.OutputDir == "" {
+		return nil, fmt.Errorf("output directory is required")
+	}
+
+	mock := NewMockVault(opts.Latency)
+	defer mock.Close()
+
+	cfg := NewConfig(mock.URL(), opts.Secrets, opts.Interval, opts.OutputDir)
+
+	newFactory := opts.NewClientFactory
+	if newFactory == nil {
+		newFactory = tokenClientFactory
+	}
+
+	var writes recorder
+	secretSyncer := syncer.NewSecretSyncer(newFactory(cfg), opts.Retry).
+		WithWriteObserver(writes.add)
+
+	scheduler := syncer.NewScheduler(secretSyncer)
+	sub := scheduler.State().Subscribe(opts.Secrets)
+	defer sub.Close()
+
+	report := &Report{Secrets: opts.Secrets}
+	var baseline runtime.MemStats
+	runtime.ReadMemStats(&baseline)
+
+	start := time.Now()
+	for _, secret := range cfg.Secrets {
+		scheduler.AddSecret(cfg, secret)
+	}
+
+	var syncs recorder
+	synced := make(map[string]bool, opts.Secrets)
+	deadline := time.NewTimer(opts.Duration)
+	defer deadline.Stop()
+	sampler := time.NewTicker(sampleInterval)
+	defer sampler.Stop()
+
+	report.sample()
+loop:
+	for {
+		select {
+		case result := <-sub.C():
+			syncs.add(result.Duration)
+			if !result.Success {
+				report.Failures++
+				continue
+			}
+			synced[result.SecretName] = true
+			if report.InitialSync == 0 && len(synced) == opts.Secrets {
+				report.InitialSync = time.Since(start)
+			}
+		case <-sampler.C:
+			report.sample()
+		case <-deadline.C:
+			break loop
+		case <-ctx.Done():
+			break loop
+		}
+	}
+
+	// Stop scheduling and let in-flight syncs finish; their results are not counted
+	report.Elapsed = time.Since(start)
+	report.VaultRequests = mock.Requests()
+	report.sample()
+	go func() {
+		for {
+			select {
+			case <-sub.C():
+			case <-sub.Done():
+				return
+			}
+		}
+	}()
+	if err := scheduler.Shutdown(syncer.DefaultDrainTimeout); err != nil {
+		return nil, err
+	}
+
+	var final runtime.MemStats
+	runtime.ReadMemStats(&final)
+	report.GCCycles = final.NumGC - baseline.NumGC
+	report.Syncs = syncs.count()
+	report.SyncLatency = syncs.summarize()
+	report.WriteLatency = writes.summarize()
+
+	return report, nil
+}
+
+// sample records the peak heap usage and goroutine count
+func (r *Report) sample() {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	if m.HeapInuse > r.PeakHeapBytes {
+		r.PeakHeapBytes = m.HeapInuse
+	}
+	if n := runtime.NumGoroutine(); n > r.PeakGoroutines {
+		r.PeakGoroutines = n
+	}
+}
+
+// tokenClientFactory creates unprotected clients authenticated with the config token
+func tokenClientFactory(cfg *config.Config) syncer.ClientFactory {
+	return func(ctx context.Context, creds config.CredentialSet) (*vault.Client, error) {
+		client, err := vault.NewClient(cfg.SecretStore.Address)
+		if err != nil {
+			return nil, err
+		}
+		if err := client.Authenticate(ctx, vault.AuthConfig{Method: vault.AuthMethodToken, Token: creds.Token, Namespace: creds.AuthNamespace}); err != nil {
+			return nil, err
+		}
+		return client, nil
+	}
+}
+
+// recorder collects durations from concurrent callers
+type recorder struct {
+	mu        sync.Mutex
+	durations []time.Duration
+}
+
+func (r *recorder) add(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.durations = append(r.durations, d)
+}
+
+func (r *recorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.durations)
+}
+
+// summarize returns the percentiles of the recorded durations
+func (r *recorder) summarize() Latencies {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.durations) == 0 {
+		return Latencies{}
+	}
+
+	sorted := append([]time.Duration(nil), r.durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	percentile := func(p float64) time.Duration {
+		return sorted[int(p*float64(len(sorted)-1))]
+	}
+
+	return Latencies{
+		Count: len(sorted),
+		P50:   percentile(0.50),
+		P95:   percentile(0.95),
+		P99:   percentile(0.99),
+		Max:   sorted[len(sorted)-1],
+	}
+}
+
+func perSecond(n int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(n) / d.Seconds()
+}
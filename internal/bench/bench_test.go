@@ -0,0 +1,89 @@
+package bench
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+
+	report, err := Run(context.Background(), Options{
+		Secrets:   20,
+		Duration:  500 * time.Millisecond,
+		Interval:  100 * time.Millisecond,
+		OutputDir: dir,
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if report.Failures != 0 {
+		t.Errorf("expected no failures, got %d", report.Failures)
+	}
+	if report.InitialSync == 0 {
+		t.Error("expected every secret to sync at least once")
+	}
+	if report.Syncs < 20 {
+		t.Errorf("expected at least 20 syncs, got %d", report.Syncs)
+	}
+	if report.VaultRequests < int64(report.Syncs) {
+		t.Errorf("expected at least %d vault reads, got %d", report.Syncs, report.VaultRequests)
+	}
+	if report.WriteLatency.Count < 20 {
+		t.Errorf("expected at least 20 timed writes, got %d", report.WriteLatency.Count)
+	}
+	if report.PeakHeapBytes == 0 || report.PeakGoroutines == 0 {
+		t.Error("expected memory and goroutines to be sampled")
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "bench-00000", "credentials"))
+	if err != nil {
+		t.Fatalf("expected file to be written: %v", err)
+	}
+	if len(content) == 0 {
+		t.Error("expected rendered content")
+	}
+}
+
+func TestRun_InvalidOptions(t *testing.T) {
+	tests := []Options{
+		{Secrets: 0, Duration: time.Second, Interval: time.Second, OutputDir: "/tmp"},
+		{Secrets: 1, Duration: 0, Interval: time.Second, OutputDir: "/tmp"},
+		{Secrets: 1, Duration: time.Second, Interval: time.Second},
+	}
+
+	for _, opts := range tests {
+		if _, err := Run(context.Background(), opts); err == nil {
+			t.Errorf("expected error for %+v", opts)
+		}
+	}
+}
+
+func TestRecorder_Summarize(t *testing.T) {
+	var r recorder
+	if got := r.summarize(); got.Count != 0 {
+		t.Errorf("expected empty summary, got %+v", got)
+	}
+
+	for i := 1; i <= 100; i++ {
+		r.add(time.Duration(i) * time.Millisecond)
+	}
+
+	got := r.summarize()
+	if got.Count != 100 {
+		t.Errorf("expected 100 samples, got %d", got.Count)
+	}
+	if got.P50 != 50*time.Millisecond {
+		t.Errorf("expected p50 50ms, got %s", got.P50)
+	}
+	if got.P99 != 99*time.Millisecond {
+		t.Errorf("expected p99 99ms, got %s", got.P99)
+	}
+	if got.Max != 100*time.Millisecond {
+		t.Errorf("expected max 100ms, got %s", got.Max)
+	}
+}
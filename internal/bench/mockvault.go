@@ -0,0 +1,79 @@
+package bench
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// MountPath is the KV v2 mount served by the mock Vault
+const MountPath = "secret"
+
+// MockVault is an in-process Vault server answering token lookups and KV v2
+// reads for any path. Every read returns a new version, so each sync writes.
+type MockVault struct {
+	server   *httptest.Server
+	latency  time.Duration
+	requests atomic.Int64 // KV reads served
+	version  atomic.Int64
+}
+
+// NewMockVault starts a mock Vault that delays every KV read by latency
+func NewMockVault(latency time.Duration) *MockVault {
+	m := &MockVault{latency: latency}
+	m.server = httptest.NewServer(http.HandlerFunc(m.handle))
+	return m
+}
+
+// URL returns the address of the mock Vault
+func (m *MockVault) URL() string {
+	return m.server.URL
+}
+
+// Requests returns the number of KV reads served
+func (m *MockVault) Requests() int64 {
+	return m.requests.Load()
+}
+
+// Close shuts the mock Vault down
+func (m *MockVault) Close() {
+	m.server.Close()
+}
+
+func (m *MockVault) handle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.URL.Path == "/v1/auth/token/lookup-self" {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"id": "bench", "policies": []string{"root"}},
+		})
+		return
+	}
+
+	key, ok := strings.CutPrefix(r.URL.Path, "/v1/"+MountPath+"/data/")
+	if !ok || r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors":[]}`))
+		return
+	}
+
+	m.requests.Add(1)
+	if m.latency > 0 {
+		time.Sleep(m.latency)
+	}
+
+	version := m.version.Add(1)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": map[string]interface{}{
+			"data": map[string]interface{}{
+				"username": key,
+				"password": "bench-password",
+				"version":  version,
+			},
+			"metadata": map[string]interface{}{"version": version},
+		},
+	})
+}
@@ -0,0 +1,189 @@
+// Package cache persists the last fetched secret data encrypted on disk, so
+// files can be restored after a restart while Vault is unreachable
+package cache
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/filewriter"
+	"github.com/ohauer/secrets-sync/internal/memlock"
+)
+
+const (
+	// keyFileSize is the number of random bytes in a generated key file
+	keyFileSize = 32
+
+	// keyInfo binds derived keys to this use
+	keyInfo = "secrets-sync cache v1"
+)
+
+// ErrNotFound is returned when no cache entry exists for a secret
+var ErrNotFound = errors.New("no cached data")
+
+// Cache stores secret data encrypted with AES-256-GCM
+type Cache struct {
+	dir  string
+	aead cipher.AEAD
+}
+
+// entry is the plaintext form of a cached secret
+type entry struct {
+	Name      string                 `json:"name"`
+	FetchedAt time.Time              `json:"fetchedAt"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// New creates a cache in dir using a key derived from keyMaterial
+func New(dir string, keyMaterial []byte) (*Cache, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("cache directory is required")
+	}
+	if len(keyMaterial) < keyFileSize {
+		return nil, fmt.Errorf("cache key must be at least %d bytes, got %d", keyFileSize, len(keyMaterial))
+	}
+
+	key, err := hkdf.Key(sha256.New, keyMaterial, nil, keyInfo, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive cache key: %w", err)
+	}
+	defer memlock.Zero(key)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	return &Cache{dir: dir, aead: aead}, nil
+}
+
+// LoadKeyFile reads the key material from path, generating a random key
+// file with mode 0600 if it does not exist yet
+func LoadKeyFile(path string) ([]byte, error) {
+	key, err := os.ReadFile(path)
+	if err == nil {
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read cache key file: %w", err)
+	}
+
+	key = make([]byte, keyFileSize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate cache key: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create cache key directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache key file: %w", err)
+	}
+	if _, err := f.Write(key); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to write cache key file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write cache key file: %w", err)
+	}
+
+	return key, nil
+}
+
+// Dir returns the directory holding the cache files
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Put encrypts and stores the data fetched for a secret
+func (c *Cache) Put(ctx context.Context, name string, data map[string]interface{}, fetchedAt time.Time) error {
+	plaintext, err := json.Marshal(entry{Name: name, FetchedAt: fetchedAt, Data: data})
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+	defer memlock.Zero(plaintext)
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	// The secret name is authenticated so entries cannot be swapped on disk
+	sealed := c.aead.Seal(nonce, nonce, plaintext, []byte(name))
+
+	writer := filewriter.NewWriter()
+	if err := writer.WriteBytes(ctx, filewriter.FileConfig{
+		Path:  c.path(name),
+		Mode:  0600,
+		Owner: -1,
+		Group: -1,
+	}, sealed); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	return nil
+}
+
+// Get decrypts the cached data of a secret and returns when it was fetched
+func (c *Cache) Get(name string) (map[string]interface{}, time.Time, error) {
+	sealed, err := os.ReadFile(c.path(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, time.Time{}, ErrNotFound
+		}
+		return nil, time.Time{}, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, time.Time{}, fmt.Errorf("cache entry for %q is truncated", name)
+	}
+
+	plaintext, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(name))
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to decrypt cache entry for %q: %w", name, err)
+	}
+	defer memlock.Zero(plaintext)
+
+	var e entry
+	if err := json.Unmarshal(plaintext, &e); err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to decode cache entry: %w", err)
+	}
+
+	return e.Data, e.FetchedAt, nil
+}
+
+// Delete removes the cached data of a secret; a missing entry is not an error
+func (c *Cache) Delete(name string) error {
+	if err := os.Remove(c.path(name)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete cache entry: %w", err)
+	}
+	return nil
+}
+
+// path returns the cache file for a secret; names are hashed so they never
+// leak into file names or escape the cache directory
+func (c *Cache) path(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".enc")
+}
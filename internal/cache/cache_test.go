@@ -0,0 +1,173 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, keyFileSize)
+}
+
+func TestCache_RoundTrip(t *testing.T) {
+	c, err := New(t.TempDir(), testKey(1))
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+
+	fetchedAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := c.Put(context.Background(), "db", map[string]interface{}{"password": "s3cret"}, fetchedAt); err != nil {
+		t.Fatalf("put failed: %v", err)
+	}
+
+	data, at, err := c.Get("db")
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if data["password"] != "s3cret" {
+		t.Errorf("expected password 's3cret', got %v", data["password"])
+	}
+	if !at.Equal(fetchedAt) {
+		t.Errorf("expected fetchedAt %v, got %v", fetchedAt, at)
+	}
+}
+
+func TestCache_EncryptedOnDisk(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(dir, testKey(1))
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+
+	if err := c.Put(context.Background(), "db", map[string]interface{}{"password": "s3cret"}, time.Now()); err != nil {
+		t.Fatalf("put failed: %v", err)
+	}
+
+	raw, err := os.ReadFile(c.path("db"))
+	if err != nil {
+		t.Fatalf("failed to read cache file: %v", err)
+	}
+	if bytes.Contains(raw, []byte("s3cret")) || bytes.Contains(raw, []byte("db")) {
+		t.Error("expected cache file not to contain plaintext")
+	}
+
+	info, err := os.Stat(c.path("db"))
+	if err != nil {
+		t.Fatalf("stat failed: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %04o", info.Mode().Perm())
+	}
+}
+
+func TestCache_WrongKey(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(dir, testKey(1))
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	if err := c.Put(context.Background(), "db", map[string]interface{}{"k": "v"}, time.Now()); err != nil {
+		t.Fatalf("put failed: %v", err)
+	}
+
+	other, err := New(dir, testKey(2))
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	if _, _, err := other.Get("db"); err == nil {
+		t.Error("expected decryption with a different key to fail")
+	}
+}
+
+func TestCache_SwappedEntry(t *testing.T) {
+	c, err := New(t.TempDir(), testKey(1))
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	if err := c.Put(context.Background(), "a", map[string]interface{}{"k": "a"}, time.Now()); err != nil {
+		t.Fatalf("put failed: %v", err)
+	}
+
+	// Copy the entry of "a" over the entry of "b"
+	raw, err := os.ReadFile(c.path("a"))
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if err := os.WriteFile(c.path("b"), raw, 0600); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	if _, _, err := c.Get("b"); err == nil {
+		t.Error("expected entry stored under another name to be rejected")
+	}
+}
+
+func TestCache_NotFound(t *testing.T) {
+	c, err := New(t.TempDir(), testKey(1))
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+
+	if _, _, err := c.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestCache_Delete(t *testing.T) {
+	c, err := New(t.TempDir(), testKey(1))
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+
+	if err := c.Put(context.Background(), "db", map[string]interface{}{"password": "s3cret"}, time.Now()); err != nil {
+		t.Fatalf("put failed: %v", err)
+	}
+	if err := c.Delete("db"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if _, _, err := c.Get("db"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+	if err := c.Delete("db"); err != nil {
+		t.Errorf("expected deleting a missing entry to succeed, got %v", err)
+	}
+}
+
+func TestNew_ShortKey(t *testing.T) {
+	if _, err := New(t.TempDir(), []byte("short")); err == nil {
+		t.Error("expected error for short key")
+	}
+}
+
+func TestLoadKeyFile_Generates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "cache.key")
+
+	key, err := LoadKeyFile(path)
+	if err != nil {
+		t.Fatalf("failed to load key file: %v", err)
+	}
+	if len(key) != keyFileSize {
+		t.Errorf("expected %d byte key, got %d", keyFileSize, len(key))
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("expected key file to be created: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %04o", info.Mode().Perm())
+	}
+
+	again, err := LoadKeyFile(path)
+	if err != nil {
+		t.Fatalf("failed to reload key file: %v", err)
+	}
+	if !bytes.Equal(key, again) {
+		t.Error("expected existing key file to be reused")
+	}
+}
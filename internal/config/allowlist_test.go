@@ -0,0 +1,78 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate_AllowedOutputPaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		files   []File
+		wantErr string
+	}{
+		{
+			name:    "inside",
+			allowed: []string{"/run/secrets/", "/etc/myapp"},
+			files:   []File{{Path: "/run/secrets/db/pass", Template: "pass", Copies: []string{"/etc/myapp/pass"}}},
+		},
+		{
+			name:  "no allowlist",
+			files: []File{{Path: "/etc/passwd", Template: "pass"}},
+		},
+		{
+			name:    "file outside",
+			allowed: []string{"/run/secrets"},
+			files:   []File{{Path: "/etc/passwd", Template: "pass"}},
+			wantErr: "secrets[0]: files[0]: path /etc/passwd is outside allowedOutputPaths",
+		},
+		{
+			name:    "copy outside",
+			allowed: []string{"/run/secrets"},
+			files:   []File{{Path: "/run/secrets/pass", Template: "pass", Copies: []string{"/run/secrets-old/pass"}}},
+			wantErr: "files[0]: path /run/secrets-old/pass is outside allowedOutputPaths",
+		},
+		{
+			name:    "relative entry",
+			allowed: []string{"secrets"},
+			files:   []File{{Path: "/run/secrets/pass", Template: "pass"}},
+			wantErr: "allowedOutputPaths[0] must be an absolute path",
+		},
+		{
+			name:    "traversal",
+			allowed: []string{"/run/secrets/../.."},
+			files:   []File{{Path: "/run/secrets/pass", Template: "pass"}},
+			wantErr: "allowedOutputPaths[0] contains '..'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := bindingConfig(map[string]string{"pass": "{{ .password }}"}, tt.files)
+			cfg.AllowedOutputPaths = tt.allowed
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_AllowedOutputPathsWildcard(t *testing.T) {
+	cfg := bindingConfig(map[string]string{"pass": "{{ .password }}"}, nil)
+	cfg.Secrets[0].Key = "app/*"
+	cfg.Secrets[0].Directory = &Directory{Path: "/var/lib/app"}
+	cfg.AllowedOutputPaths = []string{"/run/secrets"}
+
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "directory: path /var/lib/app is outside allowedOutputPaths") {
+		t.Fatalf("expected the wildcard directory to be rejected, got: %v", err)
+	}
+}
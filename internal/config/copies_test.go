@@ -0,0 +1,151 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad_FileCopies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `secretStore:
+  address: "https://vault.example.com"
+  authMethod: "token"
+  token: "test"
+secrets:
+  - name: "tls"
+    key: "app/tls"
+    mountPath: "secret"
+    kvVersion: "v2"
+    refreshInterval: "5m"
+    template:
+      data:
+        ca: "{{ .ca }}"
+        key: "{{ .key }}"
+    files:
+      - path: "/etc/nginx/certs/ca.crt"
+        mode: "0644"
+        owner: "101"
+        copies:
+          - "/etc/postfix/certs/ca.crt"
+          - "/etc/dovecot/ca.crt"
+      - path: "/etc/nginx/certs/tls.key"
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+
+	secret := cfg.Secrets[0]
+	want := []File{
+		{Path: "/etc/nginx/certs/ca.crt", Template: "ca", Mode: "0644", Owner: "101"},
+		{Path: "/etc/postfix/certs/ca.crt", Template: "ca", Mode: "0644", Owner: "101"},
+		{Path: "/etc/dovecot/ca.crt", Template: "ca", Mode: "0644", Owner: "101"},
+		{Path: "/etc/nginx/certs/tls.key", Template: "key", Mode: "0600"},
+	}
+	if len(secret.Files) != len(want) {
+		t.Fatalf("expected %d files, got %+v", len(want), secret.Files)
+	}
+	for i, file := range secret.Files {
+		if file.Path != want[i].Path || file.Template != want[i].Template || file.Mode != want[i].Mode ||
+			file.Owner != want[i].Owner || file.Copies != nil {
+			t.Errorf("files[%d]: expected %+v, got %+v", i, want[i], file)
+		}
+	}
+
+	// Expanded copies validate again on reload
+	if err := Validate(cfg); err != nil {
+		t.Errorf("expected expanded config to stay valid, got: %v", err)
+	}
+}
+
+func TestValidate_FileCopies(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []File
+		wantErr string
+	}{
+		{
+			name:    "empty copy",
+			files:   []File{{Path: "/secrets/ca.crt", Template: "ca", Copies: []string{""}}},
+			wantErr: "copies[0]: path is required",
+		},
+		{
+			name:    "copy of itself",
+			files:   []File{{Path: "/secrets/ca.crt", Template: "ca", Copies: []string{"/secrets/ca.crt"}}},
+			wantErr: "same path listed multiple times",
+		},
+		{
+			name: "copy of another file",
+			files: []File{
+				{Path: "/secrets/a/ca.crt", Template: "ca", Copies: []string{"/secrets/b/ca.crt"}},
+				{Path: "/secrets/b/ca.crt", Template: "ca"},
+			},
+			wantErr: "same path listed multiple times",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(bindingConfig(map[string]string{"ca": "{{ .ca }}"}, tt.files))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_Checksums(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     []File
+		checksums string
+		wantErr   string
+	}{
+		{
+			name:      "companions and checksum file",
+			files:     []File{{Path: "/secrets/ca.crt", Template: "ca", Checksum: true, Copies: []string{"/secrets/b/ca.crt"}}},
+			checksums: "/secrets/SHA256SUMS",
+		},
+		{
+			name: "companion of another file",
+			files: []File{
+				{Path: "/secrets/ca.crt", Template: "ca", Checksum: true},
+				{Path: "/secrets/ca.crt.sha256", Template: "ca"},
+			},
+			wantErr: `duplicate file path "/secrets/ca.crt.sha256"`,
+		},
+		{
+			name:      "checksum file of a file",
+			files:     []File{{Path: "/secrets/ca.crt", Template: "ca"}},
+			checksums: "/secrets/ca.crt",
+			wantErr:   `duplicate file path "/secrets/ca.crt"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := bindingConfig(map[string]string{"ca": "{{ .ca }}"}, tt.files)
+			cfg.Secrets[0].ChecksumFile = tt.checksums
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got: %v", err)
+				}
+				if got := cfg.Secrets[0].ChecksumPaths(); len(got) != 3 || got[0] != "/secrets/ca.crt.sha256" {
+					t.Errorf("unexpected checksum paths %q", got)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -75,6 +75,8 @@ func TestGetDefaultCredentials(t *testing.T) {
 		AuthMethod: "approle",
 		RoleID:     "test-role",
 		SecretID:   "test-secret",
+
+		SecretIDWrapped: true,
 	}
 
 	creds := store.GetDefaultCredentials()
@@ -88,6 +90,9 @@ func TestGetDefaultCredentials(t *testing.T) {
 	if creds.SecretID != "test-secret" {
 		t.Errorf("SecretID = %q, want %q", creds.SecretID, "test-secret")
 	}
+	if !creds.SecretIDWrapped {
+		t.Error("SecretIDWrapped = false, want true")
+	}
 }
 
 func TestValidate_CredentialSets(t *testing.T) {
@@ -154,6 +159,224 @@ func TestValidate_CredentialSets(t *testing.T) {
 			wantErr: true,
 			errMsg:  "token is required",
 		},
+		{
+			name: "kubernetes credential set",
+			config: Config{
+				SecretStore: SecretStore{
+					Address:    "http://localhost:8200",
+					AuthMethod: "token",
+					Token:      "default-token",
+					Credentials: map[string]CredentialSet{
+						"team-k8s": {
+							AuthMethod:          "kubernetes",
+							KubernetesRole:      "team-k8s",
+							KubernetesMountPath: "k8s/prod",
+						},
+					},
+				},
+				Secrets: []Secret{
+					{
+						Name:            "test",
+						Key:             "test/path",
+						MountPath:       "secret",
+						KVVersion:       "v2",
+						RefreshInterval: 30 * time.Minute,
+						Credentials:     "team-k8s",
+						Template: Template{
+							Data: map[string]string{"test": "{{ .value }}"},
+						},
+						Files: []File{
+							{Path: "/tmp/test", Mode: "0600"},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid credential set - missing kubernetes role",
+			config: Config{
+				SecretStore: SecretStore{
+					Address:    "http://localhost:8200",
+					AuthMethod: "token",
+					Token:      "default-token",
+					Credentials: map[string]CredentialSet{
+						"team-k8s": {
+							AuthMethod: "kubernetes",
+						},
+					},
+				},
+				Secrets: []Secret{},
+			},
+			wantErr: true,
+			errMsg:  "kubernetesRole is required",
+		},
+		{
+			name: "invalid credential set - relative kubernetes token path",
+			config: Config{
+				SecretStore: SecretStore{
+					Address:             "http://localhost:8200",
+					AuthMethod:          "kubernetes",
+					KubernetesRole:      "secrets-sync",
+					KubernetesTokenPath: "token",
+				},
+				Secrets: []Secret{},
+			},
+			wantErr: true,
+			errMsg:  "kubernetesTokenPath must be absolute",
+		},
+		{
+			name: "token file credential set",
+			config: Config{
+				SecretStore: SecretStore{
+					Address:    "http://localhost:8200",
+					AuthMethod: "tokenFile",
+					TokenFile:  "/run/vault-agent/token",
+					Credentials: map[string]CredentialSet{
+						"team-agent": {
+							AuthMethod: "tokenFile",
+							TokenFile:  "/run/vault-agent/team-token",
+						},
+					},
+				},
+				Secrets: []Secret{
+					{
+						Name:            "test",
+						Key:             "test/path",
+						MountPath:       "secret",
+						KVVersion:       "v2",
+						RefreshInterval: 30 * time.Minute,
+						Credentials:     "team-agent",
+						Template: Template{
+							Data: map[string]string{"test": "{{ .value }}"},
+						},
+						Files: []File{
+							{Path: "/tmp/test", Mode: "0600"},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid credential set - relative token file",
+			config: Config{
+				SecretStore: SecretStore{
+					Address:    "http://localhost:8200",
+					AuthMethod: "token",
+					Token:      "default-token",
+					Credentials: map[string]CredentialSet{
+						"team-agent": {
+							AuthMethod: "tokenFile",
+							TokenFile:  "token",
+						},
+					},
+				},
+				Secrets: []Secret{},
+			},
+			wantErr: true,
+			errMsg:  "tokenFile must be absolute",
+		},
+		{
+			name: "invalid store - missing token file",
+			config: Config{
+				SecretStore: SecretStore{
+					Address:    "http://localhost:8200",
+					AuthMethod: "tokenFile",
+				},
+				Secrets: []Secret{},
+			},
+			wantErr: true,
+			errMsg:  "tokenFile is required",
+		},
+		{
+			name: "cert credential set",
+			config: Config{
+				SecretStore: SecretStore{
+					Address:    "https://localhost:8200",
+					AuthMethod: "cert",
+					Credentials: map[string]CredentialSet{
+						"team-web": {
+							AuthMethod:    "cert",
+							CertRole:      "web",
+							CertMountPath: "cert/prod",
+						},
+					},
+				},
+				Secrets: []Secret{
+					{
+						Name:            "test",
+						Key:             "test/path",
+						MountPath:       "secret",
+						KVVersion:       "v2",
+						RefreshInterval: 30 * time.Minute,
+						Credentials:     "team-web",
+						Template: Template{
+							Data: map[string]string{"test": "{{ .value }}"},
+						},
+						Files: []File{
+							{Path: "/tmp/test", Mode: "0600"},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid credential set - cert mount path with slash",
+			config: Config{
+				SecretStore: SecretStore{
+					Address:    "https://localhost:8200",
+					AuthMethod: "token",
+					Token:      "default-token",
+					Credentials: map[string]CredentialSet{
+						"team-web": {
+							AuthMethod:    "cert",
+							CertMountPath: "/cert",
+						},
+					},
+				},
+				Secrets: []Secret{},
+			},
+			wantErr: true,
+			errMsg:  "certMountPath must not start or end with a slash",
+		},
+		{
+			name: "invalid credential set - secretId and secretIdFile",
+			config: Config{
+				SecretStore: SecretStore{
+					Address:    "http://localhost:8200",
+					AuthMethod: "token",
+					Token:      "default-token",
+					Credentials: map[string]CredentialSet{
+						"team-b": {
+							AuthMethod:   "approle",
+							RoleID:       "role-id",
+							SecretID:     "secret-id",
+							SecretIDFile: "/run/secrets/secret-id",
+						},
+					},
+				},
+				Secrets: []Secret{},
+			},
+			wantErr: true,
+			errMsg:  "secretId and secretIdFile are mutually exclusive",
+		},
+		{
+			name: "invalid credential set - relative secretIdFile",
+			config: Config{
+				SecretStore: SecretStore{
+					Address:         "http://localhost:8200",
+					AuthMethod:      "approle",
+					RoleID:          "role-id",
+					SecretIDFile:    "secret-id",
+					SecretIDWrapped: true,
+				},
+				Secrets: []Secret{},
+			},
+			wantErr: true,
+			errMsg:  "secretIdFile must be absolute",
+		},
 		{
 			name: "secret references non-existent credentials",
 			config: Config{
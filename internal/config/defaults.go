@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/ohauer/secrets-sync/internal/filewriter"
+)
+
+// applySecretDefaults fills in the fields secrets leave unset from
+// secretDefaults. Fields that do not apply to a secret are left alone:
+// mountPath and kvVersion with Azure Key Vault, kvVersion for database
+// secrets, and both for secrets with sources, whose sources inherit them
+// instead.
+func applySecretDefaults(cfg *Config) {
+	d := cfg.SecretDefaults
+	azure := cfg.SecretStore.IsAzureKeyVault()
+
+	for i := range cfg.Secrets {
+		secret := &cfg.Secrets[i]
+		switch {
+		case azure:
+		case len(secret.Sources) > 0:
+			for j := range secret.Sources {
+				source := &secret.Sources[j]
+				source.MountPath = orDefault(source.MountPath, d.MountPath)
+				source.KVVersion = orDefault(source.KVVersion, d.KVVersion)
+			}
+		default:
+			secret.MountPath = orDefault(secret.MountPath, d.MountPath)
+			if secret.Type != SecretTypeDatabase {
+				secret.KVVersion = orDefault(secret.KVVersion, d.KVVersion)
+			}
+		}
+
+		if secret.RefreshInterval == 0 {
+			secret.RefreshInterval = d.RefreshInterval
+		}
+		secret.Credentials = orDefault(secret.Credentials, d.Credentials)
+		secret.CleanupOnShutdown = orDefault(secret.CleanupOnShutdown, d.CleanupOnShutdown)
+		secret.RequireTmpfs = secret.RequireTmpfs || d.RequireTmpfs
+
+		for j := range secret.Files {
+			file := &secret.Files[j]
+			file.Mode = orDefault(file.Mode, d.Mode)
+			file.Owner = orDefault(file.Owner, d.Owner)
+			file.Group = orDefault(file.Group, d.Group)
+			file.DirMode = orDefault(file.DirMode, d.DirMode)
+			file.DirOwner = orDefault(file.DirOwner, d.DirOwner)
+			file.DirGroup = orDefault(file.DirGroup, d.DirGroup)
+			file.SELinuxContext = orDefault(file.SELinuxContext, d.SELinuxContext)
+		}
+		if dir := secret.Directory; dir != nil {
+			dir.Mode = orDefault(dir.Mode, d.Mode)
+			dir.Owner = orDefault(dir.Owner, d.Owner)
+			dir.Group = orDefault(dir.Group, d.Group)
+			dir.DirMode = orDefault(dir.DirMode, d.DirMode)
+			dir.DirOwner = orDefault(dir.DirOwner, d.DirOwner)
+			dir.DirGroup = orDefault(dir.DirGroup, d.DirGroup)
+			dir.SELinuxContext = orDefault(dir.SELinuxContext, d.SELinuxContext)
+		}
+	}
+}
+
+// orDefault returns value, or def if value is empty
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// validateSecretDefaults checks the defaults on their own, so a bad default
+// is reported once rather than for every secret inheriting it
+func validateSecretDefaults(store *SecretStore, d *SecretDefaults) error {
+	if d.KVVersion != "" && d.KVVersion != "v1" && d.KVVersion != "v2" {
+		return fmt.Errorf("kvVersion must be v1 or v2, got: %s", d.KVVersion)
+	}
+	if d.RefreshInterval < 0 {
+		return fmt.Errorf("refreshInterval must be positive")
+	}
+	if d.Credentials != "" {
+		if _, ok := store.Credentials[d.Credentials]; !ok {
+			return fmt.Errorf("credentials %q not found in secretStore.credentials", d.Credentials)
+		}
+	}
+	if err := validateCleanup(d.CleanupOnShutdown); err != nil {
+		return err
+	}
+	if d.Mode != "" {
+		if _, err := filewriter.ParseMode(d.Mode); err != nil {
+			return fmt.Errorf("invalid mode '%s': %w", d.Mode, err)
+		}
+	}
+	if d.Owner != "" {
+		if _, err := filewriter.ParseOwner(d.Owner); err != nil {
+			return fmt.Errorf("invalid owner '%s': %w", d.Owner, err)
+		}
+	}
+	if d.Group != "" {
+		if _, err := filewriter.ParseGroup(d.Group); err != nil {
+			return fmt.Errorf("invalid group '%s': %w", d.Group, err)
+		}
+	}
+	return validateDirAttributes(d.DirMode, d.DirOwner, d.DirGroup, d.SELinuxContext)
+}
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad_SecretDefaults(t *testing.T) {
+	path := writeConfig(t, `secretStore:
+  address: "https://vault.example.com"
+  authMethod: "token"
+  token: "test-token"
+  credentials:
+    team-a:
+      authMethod: "token"
+      token: "team-a-token"
+secretDefaults:
+  mountPath: "secret"
+  kvVersion: "v2"
+  refreshInterval: "1h"
+  credentials: "team-a"
+  mode: "0640"
+  owner: "1000"
+  dirMode: "0750"
+  seLinuxContext: "system_u:object_r:container_file_t:s0"
+secrets:
+  - name: "inherits"
+    key: "app/inherits"
+    template:
+      data:
+        key: "{{ .key }}"
+    files:
+      - path: "/secrets/inherits"
+        template: "key"
+  - name: "overrides"
+    key: "app/overrides"
+    mountPath: "kv"
+    kvVersion: "v1"
+    refreshInterval: "5m"
+    template:
+      data:
+        key: "{{ .key }}"
+    files:
+      - path: "/secrets/overrides"
+        template: "key"
+        mode: "0600"
+  - name: "db"
+    type: "database"
+    key: "readonly"
+    mountPath: "database"
+    template:
+      data:
+        key: "{{ .username }}"
+    files:
+      - path: "/secrets/db"
+        template: "key"
+  - name: "combined"
+    sources:
+      - name: "db"
+        key: "app/db"
+      - name: "tls"
+        key: "app/tls"
+        kvVersion: "v1"
+    template:
+      data:
+        key: "{{ .db.password }}"
+    files:
+      - path: "/secrets/combined"
+        template: "key"
+`)
+
+	cfg, err := Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	inherits := cfg.Secrets[0]
+	if inherits.MountPath != "secret" || inherits.KVVersion != "v2" || inherits.RefreshInterval != time.Hour || inherits.Credentials != "team-a" {
+		t.Errorf("expected the defaults, got %+v", inherits)
+	}
+	if f := inherits.Files[0]; f.Mode != "0640" || f.Owner != "1000" || f.DirMode != "0750" || f.SELinuxContext != "system_u:object_r:container_file_t:s0" {
+		t.Errorf("expected the default mode, owner, dirMode and seLinuxContext, got %+v", f)
+	}
+
+	overrides := cfg.Secrets[1]
+	if overrides.MountPath != "kv" || overrides.KVVersion != "v1" || overrides.RefreshInterval != 5*time.Minute {
+		t.Errorf("expected the secret's own values, got %+v", overrides)
+	}
+	if f := overrides.Files[0]; f.Mode != "0600" || f.Owner != "1000" {
+		t.Errorf("expected mode 0600 and the default owner, got %+v", f)
+	}
+
+	if db := cfg.Secrets[2]; db.KVVersion != "" {
+		t.Errorf("expected no kvVersion for a database secret, got %q", db.KVVersion)
+	}
+
+	combined := cfg.Secrets[3]
+	if combined.MountPath != "" || combined.KVVersion != "" {
+		t.Errorf("expected no location on a secret with sources, got %+v", combined)
+	}
+	if s := combined.Sources[0]; s.MountPath != "secret" || s.KVVersion != "v2" {
+		t.Errorf("expected the defaults on the source, got %+v", s)
+	}
+	if s := combined.Sources[1]; s.KVVersion != "v1" {
+		t.Errorf("expected the source's own kvVersion, got %+v", s)
+	}
+}
+
+func TestValidate_SecretDefaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		defaults SecretDefaults
+		want     string
+	}{
+		{"kvVersion", SecretDefaults{KVVersion: "v3"}, "secretDefaults: kvVersion must be v1 or v2"},
+		{"credentials", SecretDefaults{Credentials: "missing"}, `secretDefaults: credentials "missing" not found`},
+		{"mode", SecretDefaults{Mode: "rw"}, "secretDefaults: invalid mode 'rw'"},
+		{"dirMode", SecretDefaults{DirMode: "0777"}, "secretDefaults: invalid dirMode '0777'"},
+		{"seLinuxContext", SecretDefaults{SELinuxContext: "container_file_t"}, "secretDefaults: invalid seLinuxContext"},
+		{"refreshInterval", SecretDefaults{RefreshInterval: -time.Minute}, "secretDefaults: refreshInterval must be positive"},
+		{"cleanupOnShutdown", SecretDefaults{CleanupOnShutdown: "wipe"}, "secretDefaults: cleanupOnShutdown must be keep, remove or shred, got: wipe"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				SecretStore:    SecretStore{Address: "https://vault.example.com", AuthMethod: "token", Token: "test"},
+				SecretDefaults: tt.defaults,
+				Secrets: []Secret{{
+					Name:            "app",
+					Key:             "app/config",
+					MountPath:       "secret",
+					KVVersion:       "v2",
+					RefreshInterval: time.Hour,
+					Template:        Template{Data: map[string]string{"key": "{{ .key }}"}},
+					Files:           []File{{Path: "/secrets/app", Template: "key"}},
+				}},
+			}
+			if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestFormat_SecretDefaultsMode(t *testing.T) {
+	out, err := Format([]byte(`secrets:
+  - name: app
+    key: app/config
+    template:
+      data:
+        key: '{{ .key }}'
+    files:
+      - path: /secrets/app
+        template: key
+secretDefaults:
+  refreshInterval: 3600s
+  mode: "0640"
+`))
+	if err != nil {
+		t.Fatalf("failed to format: %v", err)
+	}
+	if strings.Contains(string(out), "0600") {
+		t.Errorf("expected files to inherit the default mode, got:\n%s", out)
+	}
+	if !strings.HasPrefix(string(out), "secretDefaults:\n  refreshInterval: \"1h\"\n") {
+		t.Errorf("expected secretDefaults first with a normalized interval, got:\n%s", out)
+	}
+}
+
+func TestLoad_CleanupOnShutdownDefault(t *testing.T) {
+	path := writeConfig(t, testStoreConfig+`secretDefaults:
+  cleanupOnShutdown: "shred"
+`+testSecretConfig("web")+`  - name: "api"
+    key: "app/api"
+    mountPath: "secret"
+    kvVersion: "v2"
+    refreshInterval: "1h"
+    cleanupOnShutdown: "keep"
+    template:
+      data:
+        key: "{{ .key }}"
+    files:
+      - path: "/secrets/api"
+        template: "key"
+`)
+
+	cfg, err := Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if got := cfg.Secrets[0].CleanupOnShutdown; got != CleanupShred {
+		t.Errorf("expected the default shred, got %q", got)
+	}
+	if got := cfg.Secrets[1].CleanupOnShutdown; got != CleanupKeep {
+		t.Errorf("expected the secret's own keep, got %q", got)
+	}
+}
+
+func TestLoad_RequireTmpfsDefault(t *testing.T) {
+	path := writeConfig(t, testStoreConfig+`secretDefaults:
+  requireTmpfs: true
+`+testSecretConfig("web")+`  - name: "api"
+    key: "app/api"
+    mountPath: "secret"
+    kvVersion: "v2"
+    refreshInterval: "1h"
+    requireTmpfs: false
+    template:
+      data:
+        token: "{{ .token }}"
+    files:
+      - path: "/tmp/api-token"
+`)
+
+	cfg, err := Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	for _, secret := range cfg.Secrets {
+		if !secret.RequireTmpfs {
+			t.Errorf("expected requireTmpfs enforced for %s", secret.Name)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Diagnostic severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Diagnostic is a problem found in a configuration file
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Path     string `json:"path,omitempty"` // Field the problem was found at, e.g. secrets[2].files[0]
+	Line     int    `json:"line,omitempty"` // Line of that field in the file, 0 if unknown
+	File     string `json:"file,omitempty"` // File of a configuration read from several files
+}
+
+// errorLine matches the line yaml.v3 reports a parse or type error at
+var errorLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): `)
+
+// errorField matches the field prefix of a validation error, e.g. secrets[2]:
+var errorField = regexp.MustCompile(`^([A-Za-z]+)(?:\[(\d+)\])?: `)
+
+// Diagnose reads a configuration like Load, but reports every problem found
+// instead of the first one, with the file and line it is at. Fields unknown
+// to the configuration are reported as warnings. The configuration is
+// returned if there are no errors.
+func Diagnose(ctx context.Context, path string) (*Config, []Diagnostic) {
+	if err := ctx.Err(); err != nil {
+		return nil, []Diagnostic{{Severity: SeverityError, Message: fmt.Sprintf("config load cancelled: %v", err)}}
+	}
+
+	files, err := readConfigFiles(path)
+	if err != nil {
+		diag := Diagnostic{Severity: SeverityError, Message: err.Error(), Line: tomlLine(err)}
+		var fileErr *fileError
+		if errors.As(err, &fileErr) && filepath.Clean(fileErr.path) != filepath.Clean(path) {
+			diag.File = fileErr.path
+		}
+		return nil, []Diagnostic{diag}
+	}
+
+	var diags []Diagnostic
+	valid := true
+	for _, f := range files {
+		if err := yaml.Unmarshal(f.data, &f.cfg); err != nil {
+			valid = false
+			for _, diag := range yamlDiagnostics(SeverityError, err, nil) {
+				diags = append(diags, files.locate(f, diag))
+			}
+			continue
+		}
+		dec := yaml.NewDecoder(bytes.NewReader(f.data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&Config{}); err != nil {
+			for _, diag := range yamlDiagnostics(SeverityWarning, err, func(msg string) bool {
+				return strings.Contains(msg, "not found in type")
+			}) {
+				diags = append(diags, files.locate(f, diag))
+			}
+		}
+
+		ExpandEnvVars(&f.cfg)
+		loadTemplateFiles(&f.cfg, f.dir, func(err error) bool {
+			valid = false
+			diags = append(diags, files.locate(f, Diagnostic{Severity: SeverityError, Message: err.Error()}))
+			return true
+		})
+	}
+	if !valid {
+		return nil, diags
+	}
+
+	cfg, err := files.merge()
+	if err != nil {
+		return nil, append(diags, Diagnostic{Severity: SeverityError, Message: err.Error()})
+	}
+
+	validate(cfg, func(err error) bool {
+		valid = false
+		diags = append(diags, files.diagnostic(SeverityError, err.Error()))
+		return true
+	})
+
+	for i, secret := range cfg.Secrets {
+		if secret.UsesImplicitTemplates() {
+			diag := files.diagnostic(SeverityWarning, fmt.Sprintf("secrets[%d]: ", i))
+			diag.Message = fmt.Sprintf("secret %q binds files to templates by position (deprecated); set template on each file", secret.Name)
+			diags = append(diags, diag)
+		}
+	}
+
+	if warning := cfg.FetchRateWarning(); warning != "" {
+		diags = append(diags, Diagnostic{Severity: SeverityWarning, Message: warning})
+	}
+
+	if !valid {
+		return nil, diags
+	}
+	for i := range cfg.Secrets {
+		cfg.Secrets[i].expandCopies()
+	}
+	return cfg, diags
+}
+
+// yamlDiagnostics turns a yaml.v3 error into one diagnostic per problem,
+// keeping those accepted by keep, or all if keep is nil
+func yamlDiagnostics(severity string, err error, keep func(msg string) bool) []Diagnostic {
+	messages := []string{err.Error()}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+
+	var diags []Diagnostic
+	for _, msg := range messages {
+		if keep != nil && !keep(msg) {
+			continue
+		}
+		diag := Diagnostic{Severity: severity, Message: msg}
+		if m := errorLine.FindStringSubmatch(msg); m != nil {
+			diag.Line, _ = strconv.Atoi(m[1])
+			diag.Message = msg[len(m[0]):]
+		}
+		diags = append(diags, diag)
+	}
+	return diags
+}
+
+// Locate returns the path and line of the field in the configuration data
+// that a message prefixed like a validation error refers to, e.g.
+// "secrets[2]: not readable"
+func Locate(data []byte, msg string) (string, int) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil || len(root.Content) != 1 {
+		return "", 0
+	}
+	return locate(root.Content[0], msg)
+}
+
+// locate finds the field a validation error refers to from its prefixes,
+// e.g. "secrets[2]: files[0]: ...", and a field named at the start of the
+// message, e.g. "mountPath is required". It returns the path of the deepest
+// field found and its line.
+func locate(root *yaml.Node, msg string) (string, int) {
+	node := root
+	var path []string
+	line := 0
+
+	for {
+		m := errorField.FindStringSubmatch(msg)
+		if m == nil {
+			break
+		}
+		key, value := mappingKey(node, m[1])
+		if value == nil {
+			break
+		}
+		segment, target := m[1], value
+		line = key.Line
+		if m[2] != "" {
+			i, _ := strconv.Atoi(m[2])
+			if value.Kind != yaml.SequenceNode || i >= len(value.Content) {
+				break
+			}
+			segment, target = fmt.Sprintf("%s[%d]", m[1], i), value.Content[i]
+			line = target.Line
+		}
+		path = append(path, segment)
+		node = target
+		msg = msg[len(m[0]):]
+	}
+
+	// A field named by the message itself, such as onFailure.maxRetries
+	if word, _, ok := strings.Cut(msg, " "); ok {
+		for _, field := range strings.Split(word, ".") {
+			key, value := mappingKey(node, field)
+			if value == nil {
+				break
+			}
+			path = append(path, field)
+			line = key.Line
+			node = value
+		}
+	}
+	return strings.Join(path, "."), line
+}
+
+// mappingKey returns the key and value nodes of a key of a mapping
+func mappingKey(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
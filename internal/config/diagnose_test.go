@@ -0,0 +1,118 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDiagnose(t *testing.T) {
+	path := writeConfig(t, `secretStore:
+  address: "https://vault.example.com"
+  authMethod: "token"
+  token: "test"
+secrets:
+  - name: "ok"
+    key: "app/ok"
+    mountPath: "secret"
+    kvVersion: "v2"
+    refreshInterval: 1m
+    template:
+      data:
+        key: "{{ .key }}"
+    files:
+      - path: "/tmp/ok"
+  - name: "broken"
+    key: "app/broken"
+    kvVersion: "v2"
+    refreshInterval: 1m
+    refreshJiter: 10%
+    template:
+      data:
+        key: "{{ .key }}"
+    files:
+      - path: "/tmp/broken"
+  - name: "no-files"
+    key: "app/no-files"
+    mountPath: "secret"
+    kvVersion: "v2"
+    refreshInterval: 1m
+    template:
+      data:
+        key: "{{ .key }}"
+  - name: "too-fast"
+    key: "app/too-fast"
+    mountPath: "secret"
+    kvVersion: "v2"
+    refreshInterval: 10s
+    template:
+      data:
+        key: "{{ .key }}"
+    files:
+      - path: "/tmp/too-fast"
+`)
+
+	cfg, diags := Diagnose(context.Background(), path)
+	if cfg != nil {
+		t.Error("expected no config for an invalid file")
+	}
+
+	want := []Diagnostic{
+		{Severity: SeverityWarning, Message: "field refreshJiter not found in type config.Secret", Line: 20},
+		{Severity: SeverityError, Message: "secrets[1]: mountPath is required", Path: "secrets[1]", Line: 16},
+		{Severity: SeverityError, Message: "secrets[2]: files must have at least one entry", Path: "secrets[2]", Line: 26},
+		{Severity: SeverityError, Message: "secrets[3]: refreshInterval must be at least 30s, got: 10s", Path: "secrets[3].refreshInterval", Line: 38},
+	}
+	if len(diags) != len(want) {
+		t.Fatalf("expected %d diagnostics, got %+v", len(want), diags)
+	}
+	for i, d := range diags {
+		if d != want[i] {
+			t.Errorf("diagnostic %d: expected %+v, got %+v", i, want[i], d)
+		}
+	}
+}
+
+func TestDiagnose_Valid(t *testing.T) {
+	path := writeConfig(t, `secretStore:
+  address: "https://vault.example.com"
+  authMethod: "token"
+  token: "test"
+secrets:
+  - name: "ok"
+    key: "app/ok"
+    mountPath: "secret"
+    kvVersion: "v2"
+    refreshInterval: 1m
+    template:
+      data:
+        key: "{{ .key }}"
+    files:
+      - path: "/tmp/ok"
+        template: "key"
+`)
+
+	cfg, diags := Diagnose(context.Background(), path)
+	if cfg == nil || len(diags) != 0 {
+		t.Fatalf("expected a valid config, got %+v", diags)
+	}
+}
+
+func TestDiagnose_ParseError(t *testing.T) {
+	path := writeConfig(t, "secretStore:\n  address: [\n")
+
+	cfg, diags := Diagnose(context.Background(), path)
+	if cfg != nil || len(diags) != 1 || diags[0].Severity != SeverityError || diags[0].Line == 0 {
+		t.Fatalf("expected one located parse error, got %+v", diags)
+	}
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Config file formats
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+	FormatTOML = "toml"
+)
+
+// FileFormat returns the format of a config file from its extension; files
+// without a .json or .toml extension are read as YAML
+func FileFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON
+	case ".toml":
+		return FormatTOML
+	default:
+		return FormatYAML
+	}
+}
+
+// toYAML converts config data of the given format to YAML, so that every
+// format is decoded by the same yaml tags and validated alike. JSON is valid
+// YAML and is returned as it is, which keeps its line numbers.
+func toYAML(data []byte, format string) ([]byte, error) {
+	switch format {
+	case FormatYAML, FormatJSON:
+		return data, nil
+	case FormatTOML:
+		var doc map[string]interface{}
+		if err := toml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		return yaml.Marshal(doc)
+	default:
+		return nil, fmt.Errorf("unsupported config format: %s", format)
+	}
+}
+
+// tomlLine returns the line a TOML parse error is at, 0 if unknown
+func tomlLine(err error) int {
+	var parseErr toml.ParseError
+	if errors.As(err, &parseErr) {
+		return parseErr.Position.Line
+	}
+	return 0
+}
+
+// Encode converts a YAML config to the given format. Comments are kept for
+// YAML only.
+func Encode(data []byte, format string) ([]byte, error) {
+	if format == FormatYAML {
+		return data, nil
+	}
+
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(doc); err != nil {
+			return nil, fmt.Errorf("failed to encode config: %w", err)
+		}
+	case FormatTOML:
+		enc := toml.NewEncoder(&buf)
+		enc.Indent = ""
+		if err := enc.Encode(doc); err != nil {
+			return nil, fmt.Errorf("failed to encode config: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config format: %s", format)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFileFormat(t *testing.T) {
+	tests := map[string]string{
+		"config.yaml":      FormatYAML,
+		"config.yml":       FormatYAML,
+		"config":           FormatYAML,
+		"config.json":      FormatJSON,
+		"/etc/config.TOML": FormatTOML,
+	}
+	for path, want := range tests {
+		if got := FileFormat(path); got != want {
+			t.Errorf("FileFormat(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestLoad_JSONAndTOML(t *testing.T) {
+	t.Setenv("VAULT_TOKEN", "test-token")
+
+	data, err := os.ReadFile("../../testdata/valid-config.yaml")
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	want, err := Load(context.Background(), "../../testdata/valid-config.yaml")
+	if err != nil {
+		t.Fatalf("failed to load YAML config: %v", err)
+	}
+
+	for _, format := range []string{FormatJSON, FormatTOML} {
+		t.Run(format, func(t *testing.T) {
+			encoded, err := Encode(data, format)
+			if err != nil {
+				t.Fatalf("failed to encode config: %v", err)
+			}
+			path := filepath.Join(t.TempDir(), "config."+format)
+			if err := os.WriteFile(path, encoded, 0600); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+
+			got, err := Load(context.Background(), path)
+			if err != nil {
+				t.Fatalf("failed to load config: %v\n%s", err, encoded)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("config differs from YAML:\ngot:  %+v\nwant: %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestDiagnose_TOML(t *testing.T) {
+	dir := t.TempDir()
+
+	broken := filepath.Join(dir, "broken.toml")
+	if err := os.WriteFile(broken, []byte("[secretStore]\naddress = \"https://vault.example.com\"\nauthMethod = \n"), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	_, diags := Diagnose(context.Background(), broken)
+	if len(diags) != 1 || diags[0].Line != 3 {
+		t.Errorf("expected a parse error at line 3, got %+v", diags)
+	}
+
+	invalid := filepath.Join(dir, "invalid.toml")
+	if err := os.WriteFile(invalid, []byte("[secretStore]\nauthMethod = \"token\"\ntoken = \"t\"\n"), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	_, diags = Diagnose(context.Background(), invalid)
+	if len(diags) == 0 {
+		t.Fatal("expected diagnostics for a config without address")
+	}
+	for _, diag := range diags {
+		if diag.Line != 0 {
+			t.Errorf("expected no line for a TOML config, got %+v", diag)
+		}
+	}
+}
+
+func TestEncode_UnsupportedFormat(t *testing.T) {
+	if _, err := Encode([]byte("secrets: []\n"), "ini"); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
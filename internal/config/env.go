@@ -17,7 +17,16 @@ type EnvConfig struct {
 	VaultSkipVerify        bool
 	VaultClientCert        string
 	VaultClientKey         string
+	VaultClientTimeout     time.Duration
+	VaultDialTimeout       time.Duration
+	VaultKeepAlive         time.Duration
+	VaultMaxIdleConns      int
+	VaultHTTPProxy         string
+	VaultRateLimit         float64
+	VaultRateBurst         int
 	ConfigFile             string
+	ConfigURL              string
+	ConfigRefreshInterval  time.Duration
 	WatchConfig            bool
 	CircuitBreakerMaxReqs  int
 	CircuitBreakerInterval time.Duration
@@ -27,11 +36,48 @@ type EnvConfig struct {
 	MetricsPort            int
 	EnableMetrics          bool
 	StatusFile             string
+	ReadyMaxAge            time.Duration
+	ReadyPolicy            string
 	EnableTracing          bool
 	OTELExporterEndpoint   string
 	InitialBackoff         time.Duration
 	MaxBackoff             time.Duration
 	BackoffMultiplier      float64
+	MaxRetries             int
+	ManifestFile           string
+	StateFile              string
+	AuditLog               string
+	DisableMlock           bool
+	Sandbox                string
+	RunAsUser              string
+	RunAsGroup             string
+	KeepChownCaps          bool
+	CacheDir               string
+	CacheKeyFile           string
+	MaxStaleness           time.Duration
+	MemoryCacheTTL         time.Duration
+	SyncTimeout            time.Duration
+	MaxConcurrentSyncs     int
+	SyncJitter             time.Duration
+	RefreshJitter          string
+	StartupSplay           time.Duration
+	FailureRetries         int
+	FailureBackoff         time.Duration
+	FailureAlertAfter      int
+	DeletedSecretAction    string
+	QuarantineDir          string
+	VerifyInterval         time.Duration
+	VerifyRepair           bool
+	RestoreDeletedFiles    bool
+	RestoreModifiedFiles   bool
+	FsyncWrites            bool
+	RevokeLeases           bool
+	SealPollInterval       time.Duration
+	LeaderLockFile         string
+	LeaderRetryInterval    time.Duration
+	DiagnosticsDir         string
+	EnableDiagnosticsAPI   bool
+	AdminTokenFile         string
 }
 
 // LoadEnvConfig loads configuration from environment variables
@@ -46,7 +92,16 @@ func LoadEnvConfig() *EnvConfig {
 		VaultSkipVerify:        getEnvBool("VAULT_SKIP_VERIFY", false),
 		VaultClientCert:        getEnv("VAULT_CLIENT_CERT", ""),
 		VaultClientKey:         getEnv("VAULT_CLIENT_KEY", ""),
+		VaultClientTimeout:     getEnvDuration("VAULT_CLIENT_TIMEOUT", 0),
+		VaultDialTimeout:       getEnvDuration("VAULT_DIAL_TIMEOUT", 0),
+		VaultKeepAlive:         getEnvDuration("VAULT_KEEPALIVE", 0),
+		VaultMaxIdleConns:      getEnvInt("VAULT_MAX_IDLE_CONNS", 0),
+		VaultHTTPProxy:         getEnv("VAULT_HTTP_PROXY", ""),
+		VaultRateLimit:         getEnvFloat("VAULT_RATE_LIMIT", 0),
+		VaultRateBurst:         getEnvInt("VAULT_RATE_BURST", 0),
 		ConfigFile:             getEnv("CONFIG_FILE", "/config.yaml"),
+		ConfigURL:              getEnv("CONFIG_URL", ""),
+		ConfigRefreshInterval:  getEnvDuration("CONFIG_REFRESH_INTERVAL", 5*time.Minute),
 		WatchConfig:            getEnvBool("WATCH_CONFIG", false),
 		CircuitBreakerMaxReqs:  getEnvInt("CIRCUIT_BREAKER_MAX_REQUESTS", 3),
 		CircuitBreakerInterval: getEnvDuration("CIRCUIT_BREAKER_INTERVAL", 60*time.Second),
@@ -56,11 +111,48 @@ func LoadEnvConfig() *EnvConfig {
 		MetricsPort:            getEnvIntRange("METRICS_PORT", 8080, 1025, 65535),
 		EnableMetrics:          getEnvBool("ENABLE_METRICS", true),
 		StatusFile:             getEnv("STATUS_FILE", "/tmp/.ready-state"),
+		ReadyMaxAge:            getEnvDuration("READY_MAX_AGE", 0),
+		ReadyPolicy:            getEnv("READY_POLICY", "any"),
 		EnableTracing:          getEnvBool("ENABLE_TRACING", false),
 		OTELExporterEndpoint:   getEnv("OTEL_EXPORTER_ENDPOINT", ""),
 		InitialBackoff:         getEnvDuration("INITIAL_BACKOFF", 1*time.Second),
 		MaxBackoff:             getEnvDuration("MAX_BACKOFF", 5*time.Minute),
 		BackoffMultiplier:      getEnvFloat("BACKOFF_MULTIPLIER", 2.0),
+		MaxRetries:             getEnvInt("MAX_RETRIES", 3),
+		ManifestFile:           getEnv("MANIFEST_FILE", ""),
+		StateFile:              getEnv("STATE_FILE", ""),
+		AuditLog:               getEnv("AUDIT_LOG", ""),
+		DisableMlock:           getEnvBool("DISABLE_MLOCK", false),
+		Sandbox:                getEnv("SANDBOX", "off"),
+		RunAsUser:              getEnv("RUN_AS_USER", ""),
+		RunAsGroup:             getEnv("RUN_AS_GROUP", ""),
+		KeepChownCaps:          getEnvBool("KEEP_CHOWN_CAPS", false),
+		CacheDir:               getEnv("CACHE_DIR", ""),
+		CacheKeyFile:           getEnv("CACHE_KEY_FILE", ""),
+		MaxStaleness:           getEnvDuration("MAX_STALENESS", 0),
+		MemoryCacheTTL:         getEnvDuration("MEMORY_CACHE_TTL", 0),
+		SyncTimeout:            getEnvDuration("SYNC_TIMEOUT", 5*time.Minute),
+		MaxConcurrentSyncs:     getEnvInt("MAX_CONCURRENT_SYNCS", 10),
+		SyncJitter:             getEnvDuration("SYNC_JITTER", 30*time.Second),
+		RefreshJitter:          getEnv("REFRESH_JITTER", ""),
+		StartupSplay:           getEnvDuration("STARTUP_SPLAY", 0),
+		FailureRetries:         getEnvInt("FAILURE_RETRIES", 5),
+		FailureBackoff:         getEnvDuration("FAILURE_BACKOFF", 30*time.Second),
+		FailureAlertAfter:      getEnvInt("FAILURE_ALERT_AFTER", 3),
+		DeletedSecretAction:    getEnv("DELETED_SECRET_ACTION", "keep"),
+		QuarantineDir:          getEnv("QUARANTINE_DIR", ""),
+		VerifyInterval:         getEnvDuration("VERIFY_INTERVAL", 0),
+		VerifyRepair:           getEnvBool("VERIFY_REPAIR", true),
+		RestoreDeletedFiles:    getEnvBool("RESTORE_DELETED_FILES", false),
+		RestoreModifiedFiles:   getEnvBool("RESTORE_MODIFIED_FILES", false),
+		FsyncWrites:            getEnvBool("FSYNC_WRITES", true),
+		RevokeLeases:           getEnvBool("REVOKE_LEASES_ON_SHUTDOWN", true),
+		SealPollInterval:       getEnvDuration("SEAL_POLL_INTERVAL", 10*time.Second),
+		LeaderLockFile:         getEnv("LEADER_LOCK_FILE", ""),
+		LeaderRetryInterval:    getEnvDuration("LEADER_RETRY_INTERVAL", 5*time.Second),
+		DiagnosticsDir:         getEnv("DIAGNOSTICS_DIR", ""),
+		EnableDiagnosticsAPI:   getEnvBool("ENABLE_DIAGNOSTICS_API", false),
+		AdminTokenFile:         getEnv("ADMIN_TOKEN_FILE", ""),
 	}
 }
 
@@ -0,0 +1,303 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format rewrites a config in canonical style: keys in struct order, secrets
+// sorted by name, files sorted by path and bound to their template explicitly,
+// default file modes filled in, relative paths resolved and durations
+// normalized. Comments and ${VAR} references are kept; nothing is expanded.
+func Format(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config must be a YAML mapping")
+	}
+
+	root := doc.Content[0]
+	normalizeStyle(root)
+	orderKeys(root, reflect.TypeOf(Config{}))
+
+	if store := mappingValue(root, "secretStore"); store != nil && store.Kind == yaml.MappingNode {
+		orderKeys(store, reflect.TypeOf(SecretStore{}))
+		if creds := mappingValue(store, "credentials"); creds != nil && creds.Kind == yaml.MappingNode {
+			sortMapping(creds)
+			for i := 1; i < len(creds.Content); i += 2 {
+				orderKeys(creds.Content[i], reflect.TypeOf(CredentialSet{}))
+			}
+		}
+	}
+
+	// Files inherit the mode of secretDefaults instead of the default mode
+	fillMode := true
+	if defaults := mappingValue(root, "secretDefaults"); defaults != nil && defaults.Kind == yaml.MappingNode {
+		orderKeys(defaults, reflect.TypeOf(SecretDefaults{}))
+		var d SecretDefaults
+		if err := defaults.Decode(&d); err != nil {
+			return nil, fmt.Errorf("secretDefaults: %w", err)
+		}
+		if interval := mappingValue(defaults, "refreshInterval"); interval != nil && d.RefreshInterval > 0 {
+			setScalar(interval, formatDuration(d.RefreshInterval))
+		}
+		fillMode = d.Mode == ""
+	}
+
+	if secrets := mappingValue(root, "secrets"); secrets != nil && secrets.Kind == yaml.SequenceNode {
+		for i, node := range secrets.Content {
+			if err := formatSecret(node, fillMode); err != nil {
+				return nil, fmt.Errorf("secrets[%d]: %w", i, err)
+			}
+		}
+		sort.SliceStable(secrets.Content, func(i, j int) bool {
+			return scalarValue(secrets.Content[i], "name") < scalarValue(secrets.Content[j], "name")
+		})
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// formatSecret canonicalizes a single secret node in place, filling in the
+// default mode of files without one if fillMode is set
+func formatSecret(node *yaml.Node, fillMode bool) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("secret must be a mapping")
+	}
+
+	var secret Secret
+	if err := node.Decode(&secret); err != nil {
+		return err
+	}
+	// The content of a template file is not read, only its name matters here
+	if secret.Template.File != "" {
+		if secret.Template.Data == nil {
+			secret.Template.Data = make(map[string]string, 1)
+		}
+		secret.Template.Data[TemplateFileName(secret.Template.File)] = ""
+	}
+	// Files of a wildcard key are derived from its templates at sync time
+	if !secret.IsWildcard() {
+		if err := validateTemplateBinding(&secret); err != nil {
+			return err
+		}
+	}
+
+	orderKeys(node, reflect.TypeOf(Secret{}))
+
+	if interval := mappingValue(node, "refreshInterval"); interval != nil && secret.RefreshInterval > 0 {
+		setScalar(interval, formatDuration(secret.RefreshInterval))
+	}
+	if timeout := mappingValue(node, "syncTimeout"); timeout != nil && secret.SyncTimeout > 0 {
+		setScalar(timeout, formatDuration(secret.SyncTimeout))
+	}
+
+	if tmpl := mappingValue(node, "template"); tmpl != nil && tmpl.Kind == yaml.MappingNode {
+		orderKeys(tmpl, reflect.TypeOf(Template{}))
+		if data := mappingValue(tmpl, "data"); data != nil && data.Kind == yaml.MappingNode {
+			sortMapping(data)
+		}
+	}
+
+	if dir := mappingValue(node, "directory"); dir != nil && dir.Kind == yaml.MappingNode {
+		if err := formatFileNode(dir, fillMode); err != nil {
+			return fmt.Errorf("directory: %w", err)
+		}
+		orderKeys(dir, reflect.TypeOf(Directory{}))
+	}
+
+	files := mappingValue(node, "files")
+	if files == nil || files.Kind != yaml.SequenceNode {
+		return nil
+	}
+
+	// Bind templates explicitly before reordering, positional binding depends on the order
+	templates := secret.FileTemplates()
+	for i, file := range files.Content {
+		if file.Kind != yaml.MappingNode {
+			return fmt.Errorf("files[%d]: file must be a mapping", i)
+		}
+
+		if err := formatFileNode(file, fillMode); err != nil {
+			return fmt.Errorf("files[%d]: %w", i, err)
+		}
+		if mappingValue(file, "template") == nil && templates[i] != "" {
+			addScalar(file, "template", templates[i])
+		}
+
+		orderKeys(file, reflect.TypeOf(File{}))
+	}
+
+	sort.SliceStable(files.Content, func(i, j int) bool {
+		return scalarValue(files.Content[i], "path") < scalarValue(files.Content[j], "path")
+	})
+
+	return nil
+}
+
+// formatFileNode resolves the path of a file or directory node and fills in
+// the default mode if fillMode is set
+func formatFileNode(node *yaml.Node, fillMode bool) error {
+	if path := mappingValue(node, "path"); path != nil && path.Value != "" {
+		abs, err := filepath.Abs(path.Value)
+		if err != nil {
+			return fmt.Errorf("failed to resolve path: %w", err)
+		}
+		setScalar(path, filepath.Clean(abs))
+	}
+	if mode := mappingValue(node, "mode"); mode == nil {
+		if fillMode {
+			addScalar(node, "mode", "0600")
+		}
+	} else {
+		setScalar(mode, mode.Value)
+	}
+	return nil
+}
+
+// formatDuration drops zero minutes and seconds from time.Duration.String,
+// turning 1h0m0s into 1h and 5m0s into 5m
+func formatDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+// normalizeStyle switches flow collections to block style, keys to plain
+// and string values to double quotes; block scalars keep their style
+func normalizeStyle(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		node.Style &^= yaml.FlowStyle
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			node.Content[i].Style &^= yaml.DoubleQuotedStyle | yaml.SingleQuotedStyle
+			normalizeStyle(node.Content[i+1])
+		}
+	case yaml.SequenceNode:
+		node.Style &^= yaml.FlowStyle
+		for _, child := range node.Content {
+			normalizeStyle(child)
+		}
+	case yaml.ScalarNode:
+		quoteString(node)
+	}
+}
+
+// quoteString double-quotes a string value unless it is a block scalar
+func quoteString(node *yaml.Node) {
+	if node.Tag != "!!str" || node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		return
+	}
+	node.Style = yaml.DoubleQuotedStyle | (node.Style & yaml.TaggedStyle)
+}
+
+// orderKeys sorts the keys of a mapping in the field order of t.
+// Keys unknown to t keep their relative order after the known ones.
+func orderKeys(node *yaml.Node, t reflect.Type) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	rank := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		rank[name] = i
+	}
+	position := func(key string) int {
+		if r, ok := rank[key]; ok {
+			return r
+		}
+		return len(rank)
+	}
+
+	pairs := mappingPairs(node)
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return position(pairs[i][0].Value) < position(pairs[j][0].Value)
+	})
+	setMappingPairs(node, pairs)
+}
+
+// sortMapping sorts the keys of a mapping alphabetically
+func sortMapping(node *yaml.Node) {
+	pairs := mappingPairs(node)
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i][0].Value < pairs[j][0].Value
+	})
+	setMappingPairs(node, pairs)
+}
+
+func mappingPairs(node *yaml.Node) [][2]*yaml.Node {
+	pairs := make([][2]*yaml.Node, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		pairs = append(pairs, [2]*yaml.Node{node.Content[i], node.Content[i+1]})
+	}
+	return pairs
+}
+
+func setMappingPairs(node *yaml.Node, pairs [][2]*yaml.Node) {
+	node.Content = node.Content[:0]
+	for _, pair := range pairs {
+		node.Content = append(node.Content, pair[0], pair[1])
+	}
+}
+
+// mappingValue returns the value node of a key, or nil if it is not set
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// scalarValue returns the value of a scalar key of a mapping, or ""
+func scalarValue(node *yaml.Node, key string) string {
+	if value := mappingValue(node, key); value != nil && value.Kind == yaml.ScalarNode {
+		return value.Value
+	}
+	return ""
+}
+
+// setScalar replaces the value of a scalar node with a string
+func setScalar(node *yaml.Node, value string) {
+	node.Kind = yaml.ScalarNode
+	node.Tag = "!!str"
+	node.Value = value
+	quoteString(node)
+}
+
+// addScalar appends a string key to a mapping
+func addScalar(node *yaml.Node, key, value string) {
+	node.Content = append(node.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, Style: yaml.DoubleQuotedStyle},
+	)
+}
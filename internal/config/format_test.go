@@ -0,0 +1,182 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	input := `# secrets-sync
+secrets:
+  - files:
+      - {path: /secrets/b, mode: 0644}
+      - path: /secrets/a
+    name: zeta   # last
+    syncTimeout: 90s
+    refreshInterval: 300s
+    kvVersion: v2
+    mountPath: secret
+    key: app/zeta
+    template:
+      data:
+        user: '{{ .user }}'
+        pass: "{{ .pass }}"
+  - name: alpha
+    key: app/alpha
+    mountPath: secret
+    kvVersion: v2
+    refreshInterval: 1h
+    template:
+      data:
+        tls.crt: |
+          {{ .crt }}
+    files:
+      - path: /secrets/tls.crt
+secretStore:
+  token: ${VAULT_TOKEN}
+  authMethod: token
+  address: ${VAULT_ADDR}
+`
+
+	want := `secretStore:
+  address: "${VAULT_ADDR}"
+  authMethod: "token"
+  token: "${VAULT_TOKEN}"
+# secrets-sync
+secrets:
+  - name: "alpha"
+    key: "app/alpha"
+    mountPath: "secret"
+    kvVersion: "v2"
+    refreshInterval: "1h"
+    template:
+      data:
+        tls.crt: |
+          {{ .crt }}
+    files:
+      - path: "/secrets/tls.crt"
+        template: "tls.crt"
+        mode: "0600"
+  - name: "zeta" # last
+    key: "app/zeta"
+    mountPath: "secret"
+    kvVersion: "v2"
+    refreshInterval: "5m"
+    syncTimeout: "1m30s"
+    template:
+      data:
+        pass: "{{ .pass }}"
+        user: "{{ .user }}"
+    files:
+      - path: "/secrets/a"
+        template: "user"
+        mode: "0600"
+      - path: "/secrets/b"
+        template: "pass"
+        mode: "0644"
+`
+
+	got, err := Format([]byte(input))
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+
+	again, err := Format(got)
+	if err != nil {
+		t.Fatalf("Format of formatted config failed: %v", err)
+	}
+	if string(again) != string(got) {
+		t.Errorf("Format is not idempotent:\n%s", again)
+	}
+}
+
+func TestFormat_ResolvesRelativePaths(t *testing.T) {
+	input := `secrets:
+  - name: app
+    template:
+      data:
+        key: "{{ .key }}"
+    files:
+      - path: out/../secrets/key
+`
+
+	got, err := Format([]byte(input))
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	abs, err := filepath.Abs("secrets/key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), `path: "`+abs+`"`) {
+		t.Errorf("expected path resolved to %s, got:\n%s", abs, got)
+	}
+}
+
+func TestFormat_WildcardDirectory(t *testing.T) {
+	input := `secrets:
+  - name: apps
+    key: apps/*
+    directory:
+      owner: "1000"
+      path: /secrets/apps
+    template:
+      data:
+        conf: "{{ .value }}"
+`
+
+	got, err := Format([]byte(input))
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	want := `    directory:
+      path: "/secrets/apps"
+      mode: "0600"
+      owner: "1000"
+`
+	if !strings.Contains(string(got), want) {
+		t.Errorf("expected directory with default mode in field order, got:\n%s", got)
+	}
+}
+
+func TestFormat_Errors(t *testing.T) {
+	tests := map[string]string{
+		"invalid yaml":      "secrets: [",
+		"not a mapping":     "- a\n- b\n",
+		"positional counts": "secrets:\n  - name: a\n    template:\n      data:\n        x: x\n        y: y\n    files:\n      - path: /a\n",
+		"unknown template":  "secrets:\n  - name: a\n    template:\n      data:\n        x: x\n    files:\n      - path: /a\n        template: y\n",
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Format([]byte(input)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		30 * time.Second:                    "30s",
+		90 * time.Second:                    "1m30s",
+		5 * time.Minute:                     "5m",
+		time.Hour:                           "1h",
+		90 * time.Minute:                    "1h30m",
+		time.Hour + 30*time.Second:          "1h0m30s",
+		24*time.Hour + 500*time.Millisecond: "24h0m0.5s",
+	}
+
+	for d, want := range tests {
+		if got := formatDuration(d); got != want {
+			t.Errorf("formatDuration(%s) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,409 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configExtensions are the extensions of the files read from a directory
+var configExtensions = map[string]bool{".yaml": true, ".yml": true, ".json": true, ".toml": true}
+
+// secretIndex matches the secret prefix of a validation error, e.g. secrets[2]:
+var secretIndex = regexp.MustCompile(`^secrets\[(\d+)\]: `)
+
+// configFile is one of the files a configuration is read from
+type configFile struct {
+	path    string
+	dir     string // Relative template files are resolved against
+	format  string
+	data    []byte // Converted to YAML
+	include []string
+	cfg     Config
+	first   int // Index of its first secret in the merged configuration
+}
+
+// configFiles are the files of a configuration, the main file first
+type configFiles []*configFile
+
+// fileError is a problem in one of several files of a configuration. msg,
+// if set, replaces the message of err, e.g. to number secrets as in the file.
+type fileError struct {
+	path string
+	msg  string
+	err  error
+}
+
+func (e *fileError) Error() string {
+	msg := e.msg
+	if msg == "" {
+		msg = e.err.Error()
+	}
+	return e.path + ": " + msg
+}
+
+func (e *fileError) Unwrap() error {
+	return e.err
+}
+
+// readConfigFiles reads the files of a configuration: every config file in
+// path if it is a directory, otherwise path and the files it includes
+func readConfigFiles(path string) (configFiles, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if info.IsDir() {
+		paths, err := dirConfigFiles(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config directory: %w", err)
+		}
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("no config files found in %s", path)
+		}
+		var files configFiles
+		for _, p := range paths {
+			f, err := readConfigFile(p)
+			if err != nil {
+				return nil, err
+			}
+			if len(f.include) > 0 {
+				return nil, &fileError{path: p, err: errors.New("include is not allowed in a config directory")}
+			}
+			files = append(files, f)
+		}
+		return files, nil
+	}
+
+	main, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	files := configFiles{main}
+	paths, err := resolveIncludes(main.include, filepath.Dir(path))
+	if err != nil {
+		return nil, &fileError{path: path, err: err}
+	}
+	seen := map[string]bool{filepath.Clean(path): true}
+	for _, p := range paths {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		f, err := readConfigFile(p)
+		if err != nil {
+			return nil, err
+		}
+		if len(f.include) > 0 {
+			return nil, &fileError{path: p, err: errors.New("include is only allowed in the main config file")}
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// readConfigFile reads a config file and its include list, leaving decoding
+// the rest to the caller
+func readConfigFile(path string) (*configFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	f, err := parseConfigFile(filepath.Clean(path), FileFormat(path), data)
+	if err != nil {
+		return nil, err
+	}
+	f.dir = filepath.Dir(f.path)
+	return f, nil
+}
+
+// parseConfigFile converts config data of the given format to YAML and
+// reads its include list
+func parseConfigFile(path, format string, data []byte) (*configFile, error) {
+	f := &configFile{path: path, format: format}
+	var err error
+	if f.data, err = toYAML(data, format); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", &fileError{path: path, err: err})
+	}
+
+	// A file that cannot be parsed includes nothing; decoding it reports why
+	var head struct {
+		Include []string `yaml:"include"`
+	}
+	if yaml.Unmarshal(f.data, &head) == nil {
+		f.include = head.Include
+	}
+	return f, nil
+}
+
+// resolveIncludes returns the files the include entries of a config file in
+// dir refer to, in order: a directory adds its config files, sorted, and a
+// glob the files it matches
+func resolveIncludes(include []string, dir string) ([]string, error) {
+	var paths []string
+	for i, entry := range include {
+		entry = resolveInclude(entry, dir)
+		if strings.ContainsAny(entry, "*?[") {
+			matches, err := filepath.Glob(entry)
+			if err != nil {
+				return nil, fmt.Errorf("include[%d]: %w", i, err)
+			}
+			for _, match := range matches {
+				if info, err := os.Stat(match); err == nil && info.Mode().IsRegular() {
+					paths = append(paths, match)
+				}
+			}
+			continue
+		}
+
+		info, err := os.Stat(entry)
+		if err != nil {
+			return nil, fmt.Errorf("include[%d]: %w", i, err)
+		}
+		if !info.IsDir() {
+			paths = append(paths, entry)
+			continue
+		}
+		files, err := dirConfigFiles(entry)
+		if err != nil {
+			return nil, fmt.Errorf("include[%d]: %w", i, err)
+		}
+		paths = append(paths, files...)
+	}
+	return paths, nil
+}
+
+// resolveInclude expands an include entry and makes it absolute
+func resolveInclude(entry, dir string) string {
+	entry = expandEnv(entry)
+	if !filepath.IsAbs(entry) {
+		entry = filepath.Join(dir, entry)
+	}
+	return filepath.Clean(entry)
+}
+
+// dirConfigFiles returns the config files directly in dir, sorted by name.
+// Hidden files, such as editor backups, are skipped.
+func dirConfigFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, entry := range entries {
+		if entry.IsDir() || !isConfigFile(entry.Name()) {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, entry.Name()))
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// isConfigFile reports whether a file in a config directory is read
+func isConfigFile(name string) bool {
+	base := filepath.Base(name)
+	return !strings.HasPrefix(base, ".") && configExtensions[strings.ToLower(filepath.Ext(base))]
+}
+
+// merge combines the decoded files into one configuration. Secrets are
+// added in file order; secretStore, secretDefaults, notifications,
+// maxSecrets, startupMode and allowedOutputPaths may each be set in one file
+// only, and secretDefaults apply to the secrets of every file.
+func (files configFiles) merge() (*Config, error) {
+	var cfg Config
+	var store, defaults, notifications, limit, startup, allowed *configFile
+	names := make(map[string]*configFile)
+
+	for _, f := range files {
+		if !reflect.ValueOf(f.cfg.SecretStore).IsZero() {
+			if store != nil {
+				return nil, fmt.Errorf("secretStore is set in both %s and %s", store.path, f.path)
+			}
+			store = f
+			cfg.SecretStore = f.cfg.SecretStore
+		}
+		if !reflect.ValueOf(f.cfg.SecretDefaults).IsZero() {
+			if defaults != nil {
+				return nil, fmt.Errorf("secretDefaults is set in both %s and %s", defaults.path, f.path)
+			}
+			defaults = f
+			cfg.SecretDefaults = f.cfg.SecretDefaults
+		}
+		if !reflect.ValueOf(f.cfg.Notifications).IsZero() {
+			if notifications != nil {
+				return nil, fmt.Errorf("notifications is set in both %s and %s", notifications.path, f.path)
+			}
+			notifications = f
+			cfg.Notifications = f.cfg.Notifications
+		}
+		if f.cfg.MaxSecrets != 0 {
+			if limit != nil {
+				return nil, fmt.Errorf("maxSecrets is set in both %s and %s", limit.path, f.path)
+			}
+			limit = f
+			cfg.MaxSecrets = f.cfg.MaxSecrets
+		}
+		if f.cfg.StartupMode != "" {
+			if startup != nil {
+				return nil, fmt.Errorf("startupMode is set in both %s and %s", startup.path, f.path)
+			}
+			startup = f
+			cfg.StartupMode = f.cfg.StartupMode
+		}
+		if len(f.cfg.AllowedOutputPaths) > 0 {
+			if allowed != nil {
+				return nil, fmt.Errorf("allowedOutputPaths is set in both %s and %s", allowed.path, f.path)
+			}
+			allowed = f
+			cfg.AllowedOutputPaths = f.cfg.AllowedOutputPaths
+		}
+
+		f.first = len(cfg.Secrets)
+		for _, secret := range f.cfg.Secrets {
+			// Duplicates within a file are left to validation
+			if other, ok := names[secret.Name]; ok && other != f {
+				return nil, fmt.Errorf("secret %q is defined in both %s and %s", secret.Name, other.path, f.path)
+			}
+			names[secret.Name] = f
+		}
+		cfg.Secrets = append(cfg.Secrets, f.cfg.Secrets...)
+	}
+	return &cfg, nil
+}
+
+// inFile names f in an error about it if the configuration has several files
+func (files configFiles) inFile(f *configFile, err error) error {
+	if len(files) == 1 {
+		return err
+	}
+	return &fileError{path: f.path, err: err}
+}
+
+// attribute returns the file a validation error of the merged configuration
+// refers to, and the error as it applies to that file. Errors that refer to
+// no single file are returned with a nil file.
+func (files configFiles) attribute(err error) (*configFile, error) {
+	if len(files) == 1 {
+		return files[0], err
+	}
+
+	msg := err.Error()
+	if m := secretIndex.FindStringSubmatch(msg); m != nil {
+		i, _ := strconv.Atoi(m[1])
+		for j := len(files) - 1; j >= 0; j-- {
+			if f := files[j]; i >= f.first {
+				local := fmt.Sprintf("secrets[%d]: %s", i-f.first, msg[len(m[0]):])
+				return f, &fileError{path: f.path, msg: local, err: err}
+			}
+		}
+	}
+	for _, f := range files {
+		switch {
+		case strings.HasPrefix(msg, "secretStore: ") && !reflect.ValueOf(f.cfg.SecretStore).IsZero(),
+			strings.HasPrefix(msg, "secretDefaults: ") && !reflect.ValueOf(f.cfg.SecretDefaults).IsZero(),
+			strings.HasPrefix(msg, "notifications: ") && !reflect.ValueOf(f.cfg.Notifications).IsZero(),
+			strings.HasPrefix(msg, "maxSecrets ") && f.cfg.MaxSecrets != 0,
+			strings.HasPrefix(msg, "startupMode ") && f.cfg.StartupMode != "",
+			strings.HasPrefix(msg, "allowedOutputPaths") && len(f.cfg.AllowedOutputPaths) > 0:
+			return f, &fileError{path: f.path, err: err}
+		}
+	}
+	return nil, err
+}
+
+// diagnostic locates a validation message in the file it refers to
+func (files configFiles) diagnostic(severity, msg string) Diagnostic {
+	f, err := files.attribute(errors.New(msg))
+	if f == nil {
+		return Diagnostic{Severity: severity, Message: msg}
+	}
+	var fileErr *fileError
+	if errors.As(err, &fileErr) && fileErr.msg != "" {
+		msg = fileErr.msg
+	}
+	return files.locate(f, Diagnostic{Severity: severity, Message: msg})
+}
+
+// locate fills in the path and line of a diagnostic about f, and names f
+// if the configuration has several files
+func (files configFiles) locate(f *configFile, diag Diagnostic) Diagnostic {
+	if diag.Path == "" && diag.Line == 0 {
+		diag.Path, diag.Line = Locate(f.data, diag.Message)
+	}
+	// Lines of a TOML file are lost in the conversion to YAML
+	if f.format == FormatTOML {
+		diag.Line = 0
+	}
+	if len(files) > 1 {
+		diag.File = f.path
+	}
+	return diag
+}
+
+// Locator finds the file and line of messages about a configuration, such
+// as errors found when reading its secrets
+type Locator struct {
+	files configFiles
+}
+
+// NewLocator reads the files of the configuration at path
+func NewLocator(path string) (*Locator, error) {
+	files, err := readConfigFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if err := yaml.Unmarshal(f.data, &f.cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config: %w", err)
+		}
+	}
+	if _, err := files.merge(); err != nil {
+		return nil, err
+	}
+	return &Locator{files: files}, nil
+}
+
+// Diagnostic returns a diagnostic for a message prefixed like a validation
+// error of the merged configuration, e.g. "secrets[12]: not readable"
+func (l *Locator) Diagnostic(severity, msg string) Diagnostic {
+	return l.files.diagnostic(severity, msg)
+}
+
+// includeSources returns the included files of the configuration at path,
+// and the directories and globs new config files may appear in
+func includeSources(path string) (files, sources []string) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return nil, []string{filepath.Clean(path)}
+	}
+	main, err := readConfigFile(path)
+	if err != nil {
+		return nil, nil
+	}
+	for _, entry := range main.include {
+		entry = resolveInclude(entry, filepath.Dir(path))
+		if info, err := os.Stat(entry); strings.ContainsAny(entry, "*?[") || (err == nil && info.IsDir()) {
+			sources = append(sources, entry)
+		}
+	}
+	files, _ = resolveIncludes(main.include, filepath.Dir(path))
+	return files, sources
+}
+
+// matchesSource reports whether a file is a config file of a directory or
+// matches a glob returned by includeSources
+func matchesSource(source, name string) bool {
+	if strings.ContainsAny(source, "*?[") {
+		ok, _ := filepath.Match(source, name)
+		return ok
+	}
+	return filepath.Dir(name) == source && isConfigFile(name)
+}
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testStoreConfig = `secretStore:
+  address: "https://vault.example.com"
+  authMethod: "token"
+  token: "test-token"
+`
+
+// testSecretConfig returns a config file with a single secret
+func testSecretConfig(name string) string {
+	return `secrets:
+  - name: "` + name + `"
+    key: "app/` + name + `"
+    mountPath: "secret"
+    kvVersion: "v2"
+    refreshInterval: "1h"
+    template:
+      data:
+        key: "{{ .key }}"
+    files:
+      - path: "/secrets/` + name + `"
+        template: "key"
+`
+}
+
+// writeFiles writes files below dir, creating directories as needed
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// secretNames returns the names of the secrets of cfg in order
+func secretNames(cfg *Config) string {
+	var names []string
+	for _, secret := range cfg.Secrets {
+		names = append(names, secret.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestLoad_Directory(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"00-store.yaml":  testStoreConfig,
+		"web.yaml":       testSecretConfig("web"),
+		"app.json":       `{"secrets": [{"name": "app", "key": "app/app", "mountPath": "secret", "kvVersion": "v2", "refreshInterval": "1h", "template": {"data": {"key": "{{ .key }}"}}, "files": [{"path": "/secrets/app", "template": "key"}]}]}`,
+		".web.yaml.swp":  "not a config",
+		"README.md":      "not a config",
+		"nested/db.yaml": testSecretConfig("db"),
+	})
+
+	cfg, err := Load(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("failed to load config directory: %v", err)
+	}
+	if got := secretNames(cfg); got != "app,web" {
+		t.Errorf("expected secrets app,web in file order, got %s", got)
+	}
+	if cfg.SecretStore.Address != "https://vault.example.com" {
+		t.Errorf("expected the secret store of 00-store.yaml, got %+v", cfg.SecretStore)
+	}
+}
+
+func TestLoad_Include(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config.yaml": `include:
+  - "apps/*.yaml"
+  - "db"
+` + testStoreConfig + testSecretConfig("main"),
+		"apps/web.yaml":  testSecretConfig("web"),
+		"apps/api.yaml":  testSecretConfig("api"),
+		"apps/notes.txt": "not matched",
+		"db/pg.yaml":     testSecretConfig("pg"),
+	})
+
+	cfg, err := Load(context.Background(), filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if got := secretNames(cfg); got != "main,api,web,pg" {
+		t.Errorf("expected secrets main,api,web,pg, got %s", got)
+	}
+}
+
+func TestLoad_IncludeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name: "duplicate name across files",
+			files: map[string]string{
+				"config.yaml": "include: [\"web.yaml\"]\n" + testStoreConfig + testSecretConfig("web"),
+				"web.yaml":    testSecretConfig("web"),
+			},
+			want: `secret "web" is defined in both`,
+		},
+		{
+			name: "secret store set twice",
+			files: map[string]string{
+				"config.yaml": "include: [\"other.yaml\"]\n" + testStoreConfig + testSecretConfig("main"),
+				"other.yaml":  testStoreConfig,
+			},
+			want: "secretStore is set in both",
+		},
+		{
+			name: "startup mode set twice",
+			files: map[string]string{
+				"config.yaml": "include: [\"other.yaml\"]\nstartupMode: wait\n" + testStoreConfig + testSecretConfig("main"),
+				"other.yaml":  "startupMode: exit\n",
+			},
+			want: "startupMode is set in both",
+		},
+		{
+			name: "allowed output paths set twice",
+			files: map[string]string{
+				"config.yaml": "include: [\"other.yaml\"]\nallowedOutputPaths: [\"/run/secrets\"]\n" + testStoreConfig + testSecretConfig("main"),
+				"other.yaml":  "allowedOutputPaths: [\"/etc\"]\n",
+			},
+			want: "allowedOutputPaths is set in both",
+		},
+		{
+			name: "nested include",
+			files: map[string]string{
+				"config.yaml": "include: [\"web.yaml\"]\n" + testStoreConfig,
+				"web.yaml":    "include: [\"other.yaml\"]\n" + testSecretConfig("web"),
+			},
+			want: "include is only allowed in the main config file",
+		},
+		{
+			name: "missing include",
+			files: map[string]string{
+				"config.yaml": "include: [\"missing.yaml\"]\n" + testStoreConfig + testSecretConfig("main"),
+			},
+			want: "include[0]: ",
+		},
+		{
+			name: "invalid secret in included file",
+			files: map[string]string{
+				"config.yaml": "include: [\"web.yaml\"]\n" + testStoreConfig + testSecretConfig("main"),
+				"web.yaml":    strings.Replace(testSecretConfig("web"), `    mountPath: "secret"`+"\n", "", 1),
+			},
+			want: "web.yaml: secrets[0]: mountPath is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			_, err := Load(context.Background(), filepath.Join(dir, "config.yaml"))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestValidate_DuplicateSecretName(t *testing.T) {
+	path := writeConfig(t, testStoreConfig+testSecretConfig("web")+strings.TrimPrefix(strings.Replace(testSecretConfig("web"), "/secrets/web", "/secrets/web2", 1), "secrets:\n"))
+	_, err := Load(context.Background(), path)
+	if err == nil || !strings.Contains(err.Error(), `secrets[1]: duplicate name "web"`) {
+		t.Errorf("expected duplicate name error, got %v", err)
+	}
+}
+
+func TestDiagnose_IncludedFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config.yaml": "include: [\"apps\"]\n" + testStoreConfig + testSecretConfig("main"),
+		"apps/web.yaml": testSecretConfig("web") + `  - name: "broken"
+    key: "app/broken"
+    kvVersion: "v2"
+    refreshInterval: "1h"
+    template:
+      data:
+        key: "{{ .key }}"
+    files:
+      - path: "/secrets/broken"
+        template: "key"
+`,
+	})
+
+	_, diags := Diagnose(context.Background(), filepath.Join(dir, "config.yaml"))
+	if len(diags) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %+v", diags)
+	}
+	want := Diagnostic{
+		Severity: SeverityError,
+		Message:  "secrets[1]: mountPath is required",
+		Path:     "secrets[1]",
+		Line:     13,
+		File:     filepath.Join(dir, "apps", "web.yaml"),
+	}
+	if diags[0] != want {
+		t.Errorf("expected %+v, got %+v", want, diags[0])
+	}
+}
@@ -0,0 +1,56 @@
+package config
+
+import "fmt"
+
+// DefaultMaxSecrets is the number of secrets a configuration may define
+// unless maxSecrets or MAX_SECRETS sets another limit
+const DefaultMaxSecrets = 1000
+
+// HighFetchRate is the estimated number of reads per second from the
+// secret store above which a configuration is reported as heavy
+const HighFetchRate = 10.0
+
+// maxSecrets returns the limit on the number of secrets: maxSecrets, or
+// MAX_SECRETS if the configuration does not set it
+func maxSecrets(cfg *Config) int {
+	if cfg.MaxSecrets > 0 {
+		return cfg.MaxSecrets
+	}
+	return getEnvInt("MAX_SECRETS", DefaultMaxSecrets)
+}
+
+// validateMaxSecrets checks the number of secrets against the limit
+func validateMaxSecrets(cfg *Config) error {
+	if cfg.MaxSecrets < 0 {
+		return fmt.Errorf("maxSecrets must not be negative")
+	}
+	if limit := maxSecrets(cfg); len(cfg.Secrets) > limit {
+		return fmt.Errorf("too many secrets defined (%d), maximum is %d; raise maxSecrets or MAX_SECRETS", len(cfg.Secrets), limit)
+	}
+	return nil
+}
+
+// FetchRate estimates the reads per second from the secret store needed to
+// refresh every secret at its interval, counting each source of a secret
+func (cfg *Config) FetchRate() float64 {
+	var rate float64
+	for _, secret := range cfg.Secrets {
+		if secret.RefreshInterval <= 0 {
+			continue
+		}
+		reads := max(len(secret.Sources), 1)
+		rate += float64(reads) / secret.RefreshInterval.Seconds()
+	}
+	return rate
+}
+
+// FetchRateWarning describes a configuration whose FetchRate exceeds
+// HighFetchRate, or returns "" if it does not
+func (cfg *Config) FetchRateWarning() string {
+	rate := cfg.FetchRate()
+	if rate <= HighFetchRate {
+		return ""
+	}
+	return fmt.Sprintf("%d secrets need about %.0f reads per second from the secret store to refresh; "+
+		"consider longer refresh intervals", len(cfg.Secrets), rate)
+}
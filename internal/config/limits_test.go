@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// manySecrets returns a valid configuration with n secrets refreshed every
+// interval
+func manySecrets(n int, interval time.Duration) *Config {
+	cfg := &Config{SecretStore: SecretStore{Address: "https://vault.example.com", AuthMethod: "token", Token: "test-token"}}
+	for i := 0; i < n; i++ {
+		cfg.Secrets = append(cfg.Secrets, Secret{
+			Name:            fmt.Sprintf("secret-%d", i),
+			Key:             fmt.Sprintf("app/secret-%d", i),
+			MountPath:       "secret",
+			KVVersion:       "v2",
+			RefreshInterval: interval,
+			Template:        Template{Data: map[string]string{"key": "{{ .key }}"}},
+			Files:           []File{{Path: fmt.Sprintf("/secrets/secret-%d", i), Template: "key"}},
+		})
+	}
+	return cfg
+}
+
+func TestValidate_MaxSecrets(t *testing.T) {
+	tests := []struct {
+		name       string
+		secrets    int
+		maxSecrets int
+		env        string
+		wantErr    string
+	}{
+		{name: "below default", secrets: 400},
+		{name: "above default", secrets: DefaultMaxSecrets + 1, wantErr: "maximum is 1000"},
+		{name: "config raises limit", secrets: DefaultMaxSecrets + 1, maxSecrets: 2000},
+		{name: "config lowers limit", secrets: 11, maxSecrets: 10, wantErr: "too many secrets defined (11), maximum is 10"},
+		{name: "env sets limit", secrets: 11, env: "10", wantErr: "maximum is 10"},
+		{name: "config overrides env", secrets: 11, maxSecrets: 20, env: "10"},
+		{name: "negative", secrets: 1, maxSecrets: -1, wantErr: "maxSecrets must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MAX_SECRETS", tt.env)
+			cfg := manySecrets(tt.secrets, time.Hour)
+			cfg.MaxSecrets = tt.maxSecrets
+
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFetchRate(t *testing.T) {
+	cfg := manySecrets(400, time.Minute)
+	cfg.Secrets[0].Sources = []Source{{Name: "a"}, {Name: "b"}}
+
+	if rate, want := cfg.FetchRate(), 401.0/60; rate < want-0.001 || rate > want+0.001 {
+		t.Errorf("FetchRate() = %v, want %v", rate, want)
+	}
+	if warning := cfg.FetchRateWarning(); warning != "" {
+		t.Errorf("FetchRateWarning() = %q, want none below %v/s", warning, HighFetchRate)
+	}
+
+	cfg = manySecrets(400, 30*time.Second)
+	if warning := cfg.FetchRateWarning(); !strings.Contains(warning, "400 secrets need about 13 reads per second") {
+		t.Errorf("FetchRateWarning() = %q", warning)
+	}
+}
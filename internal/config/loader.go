@@ -1,29 +1,81 @@
 package config
 
 import (
+	"context"
 	"fmt"
-	"os"
 
 	"gopkg.in/yaml.v3"
 )
 
-// Load reads and parses the configuration file
-func Load(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
+// Load reads and parses the configuration. path is a file, in YAML, JSON or
+// TOML as told by its extension, or a directory whose config files are
+// merged. ctx bounds reading the source, so a reload can be abandoned when
+// the service shuts down.
+func Load(ctx context.Context, path string) (*Config, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("config load cancelled: %w", err)
+	}
+
+	files, err := readConfigFiles(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, err
+	}
+	return loadFiles(files)
+}
+
+// LoadData parses a configuration fetched from a remote source. name, such
+// as the URL it was fetched from, tells the format by its extension unless
+// format is set. A remote configuration cannot include files; relative
+// template files are resolved against the working directory.
+func LoadData(ctx context.Context, name, format string, data []byte) (*Config, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("config load cancelled: %w", err)
 	}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config: %w", err)
+	if format == "" {
+		format = FileFormat(name)
+	}
+	f, err := parseConfigFile(name, format, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.include) > 0 {
+		return nil, fmt.Errorf("invalid config: include is not supported in a remote config")
 	}
+	f.dir = "."
+	return loadFiles(configFiles{f})
+}
+
+// loadFiles decodes, merges and validates the files of a configuration
+func loadFiles(files configFiles) (*Config, error) {
+	for _, f := range files {
+		if err := yaml.Unmarshal(f.data, &f.cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config: %w", files.inFile(f, err))
+		}
 
-	ExpandEnvVars(&cfg)
+		ExpandEnvVars(&f.cfg)
+
+		var loadErr error
+		loadTemplateFiles(&f.cfg, f.dir, func(err error) bool {
+			loadErr = err
+			return false
+		})
+		if loadErr != nil {
+			return nil, fmt.Errorf("invalid config: %w", files.inFile(f, loadErr))
+		}
+	}
 
-	if err := Validate(&cfg); err != nil {
+	cfg, err := files.merge()
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if err := Validate(cfg); err != nil {
+		_, err = files.attribute(err)
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
+	for i := range cfg.Secrets {
+		cfg.Secrets[i].expandCopies()
+	}
 
-	return &cfg, nil
+	return cfg, nil
 }
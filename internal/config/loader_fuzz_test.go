@@ -1,6 +1,7 @@
 package config
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -60,7 +61,7 @@ secrets:
 		}
 
 		// Should not panic
-		_, _ = Load(configPath)
+		_, _ = Load(context.Background(), configPath)
 	})
 }
 
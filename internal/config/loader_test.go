@@ -1,6 +1,8 @@
 package config
 
 import (
+	"context"
+	"errors"
 	"os"
 	"strings"
 	"testing"
@@ -11,7 +13,7 @@ func TestLoad_ValidConfig(t *testing.T) {
 	_ = os.Setenv("VAULT_TOKEN", "test-token")
 	defer func() { _ = os.Unsetenv("VAULT_TOKEN") }()
 
-	cfg, err := Load("../../testdata/valid-config.yaml")
+	cfg, err := Load(context.Background(), "../../testdata/valid-config.yaml")
 	if err != nil {
 		t.Fatalf("expected no error, got: %v", err)
 	}
@@ -38,14 +40,14 @@ func TestLoad_ValidConfig(t *testing.T) {
 }
 
 func TestLoad_InvalidConfig(t *testing.T) {
-	_, err := Load("../../testdata/invalid-config.yaml")
+	_, err := Load(context.Background(), "../../testdata/invalid-config.yaml")
 	if err == nil {
 		t.Fatal("expected error for invalid config, got nil")
 	}
 }
 
 func TestLoad_NonExistentFile(t *testing.T) {
-	_, err := Load("nonexistent.yaml")
+	_, err := Load(context.Background(), "nonexistent.yaml")
 	if err == nil {
 		t.Fatal("expected error for nonexistent file, got nil")
 	}
@@ -77,6 +79,127 @@ func TestValidate_MissingAddress(t *testing.T) {
 	}
 }
 
+func TestValidate_FailoverAddresses(t *testing.T) {
+	tests := []struct {
+		name      string
+		addresses []string
+		wantErr   string
+	}{
+		{name: "failover", addresses: []string{"https://vault-dc2.example.com", "https://vault-dc3.example.com"}},
+		{name: "invalid", addresses: []string{"vault-dc2.example.com"}, wantErr: "addresses[0]: address must include scheme"},
+		{name: "same as address", addresses: []string{"https://vault.example.com"}, wantErr: "addresses[0]: duplicate address"},
+		{name: "duplicate", addresses: []string{"https://vault-dc2.example.com", "https://vault-dc2.example.com"}, wantErr: "addresses[1]: duplicate address"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				SecretStore: SecretStore{Address: "https://vault.example.com", Addresses: tt.addresses, AuthMethod: "token", Token: "test"},
+				Secrets: []Secret{{
+					Name: "app", Key: "app", MountPath: "secret", KVVersion: "v2", RefreshInterval: time.Minute,
+					Template: Template{Data: map[string]string{"key": "{{ .key }}"}},
+					Files:    []File{{Path: "/test"}},
+				}},
+			}
+
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if endpoints := cfg.SecretStore.Endpoints(); len(endpoints) != 3 || endpoints[0] != cfg.SecretStore.Address {
+					t.Errorf("expected address first in endpoints, got %v", endpoints)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_Retry(t *testing.T) {
+	negative, three := -1, 3
+	tests := []struct {
+		name    string
+		store   *Retry
+		secret  *Retry
+		wantErr string
+	}{
+		{name: "unset"},
+		{name: "valid", store: &Retry{MaxRetries: &three}, secret: &Retry{InitialBackoff: time.Second, MaxBackoff: time.Minute, Multiplier: 1.5}},
+		{name: "negative backoff", secret: &Retry{InitialBackoff: -time.Second}, wantErr: "secrets[0]: retry.initialBackoff must not be negative"},
+		{name: "backoff above max", secret: &Retry{InitialBackoff: time.Minute, MaxBackoff: time.Second}, wantErr: "retry.initialBackoff must not exceed retry.maxBackoff"},
+		{name: "multiplier below 1", secret: &Retry{Multiplier: 0.5}, wantErr: "retry.multiplier must be at least 1"},
+		{name: "negative store retries", store: &Retry{MaxRetries: &negative}, wantErr: "secretStore: retry.maxRetries must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				SecretStore: SecretStore{Address: "https://vault.example.com", AuthMethod: "token", Token: "test", Retry: tt.store},
+				Secrets: []Secret{{
+					Name: "app", Key: "app", MountPath: "secret", KVVersion: "v2", RefreshInterval: time.Minute,
+					Template: Template{Data: map[string]string{"key": "{{ .key }}"}},
+					Files:    []File{{Path: "/test"}},
+					Retry:    tt.secret,
+				}},
+			}
+
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_Connection(t *testing.T) {
+	tests := []struct {
+		name       string
+		connection *Connection
+		wantErr    string
+	}{
+		{name: "unset"},
+		{name: "valid", connection: &Connection{Timeout: time.Minute, Proxy: "http://proxy.example.com:3128", RateLimit: 20, RateBurst: 40}},
+		{name: "negative timeout", connection: &Connection{Timeout: -time.Second}, wantErr: "secretStore: connection.timeout must not be negative"},
+		{name: "negative rate", connection: &Connection{RateLimit: -1}, wantErr: "connection.rateLimit must not be negative"},
+		{name: "proxy scheme", connection: &Connection{Proxy: "ftp://proxy.example.com"}, wantErr: "connection.proxy must use http, https or socks5"},
+		{name: "proxy host", connection: &Connection{Proxy: "http://"}, wantErr: "connection.proxy must include a host"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				SecretStore: SecretStore{Address: "https://vault.example.com", AuthMethod: "token", Token: "test", Connection: tt.connection},
+				Secrets: []Secret{{
+					Name: "app", Key: "app", MountPath: "secret", KVVersion: "v2", RefreshInterval: time.Minute,
+					Template: Template{Data: map[string]string{"key": "{{ .key }}"}},
+					Files:    []File{{Path: "/test"}},
+				}},
+			}
+
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
 func TestValidate_InvalidAuthMethod(t *testing.T) {
 	cfg := &Config{
 		SecretStore: SecretStore{
@@ -135,6 +258,70 @@ func TestExpandEnvVars(t *testing.T) {
 	}
 }
 
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("DC", "fra1")
+	t.Setenv("EMPTY", "")
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"${DC}", "fra1"},
+		{"https://vault.${DC}.example.com:8200", "https://vault.fra1.example.com:8200"},
+		{"${DC}/${DC}", "fra1/fra1"},
+		{"${UNSET_VAR_FOR_TEST}", ""},
+		{"${UNSET_VAR_FOR_TEST:-secret}", "secret"},
+		{"${EMPTY:-fallback}", "fallback"},
+		{"${DC:-fallback}", "fra1"},
+		{"${UNSET_VAR_FOR_TEST:-}", ""},
+		{"$${DC}", "${DC}"},
+		{"pa$$word${DC}", "pa$$wordfra1"},
+		{"$DC", "$DC"},
+		{"${DC", "${DC"},
+		{"${not a name}", "${not a name}"},
+		{"no references", "no references"},
+	}
+	for _, tt := range tests {
+		if got := expandEnv(tt.in); got != tt.want {
+			t.Errorf("expandEnv(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExpandEnvVars_PathsAndKeys(t *testing.T) {
+	t.Setenv("APP_ENV", "prod")
+	t.Setenv("TEAM_A_TOKEN", "team-a-token")
+
+	cfg := &Config{
+		SecretStore: SecretStore{
+			Credentials: map[string]CredentialSet{"team-a": {AuthMethod: "token", Token: "${TEAM_A_TOKEN}"}},
+		},
+		Secrets: []Secret{{
+			Key:       "${APP_ENV}/database",
+			MountPath: "${MOUNT:-secret}",
+			Files:     []File{{Path: "/secrets/${APP_ENV}/db", Copies: []string{"/backup/${APP_ENV}/db"}}},
+			Directory: &Directory{Path: "/secrets/${APP_ENV}"},
+			Sources:   []Source{{Key: "${APP_ENV}/tls"}},
+		}},
+	}
+
+	ExpandEnvVars(cfg)
+
+	secret := cfg.Secrets[0]
+	if secret.Key != "prod/database" || secret.MountPath != "secret" {
+		t.Errorf("expected key and mount path expanded, got %q and %q", secret.Key, secret.MountPath)
+	}
+	if secret.Files[0].Path != "/secrets/prod/db" || secret.Files[0].Copies[0] != "/backup/prod/db" {
+		t.Errorf("expected file paths expanded, got %+v", secret.Files[0])
+	}
+	if secret.Directory.Path != "/secrets/prod" || secret.Sources[0].Key != "prod/tls" {
+		t.Errorf("expected directory and source expanded, got %+v and %+v", secret.Directory, secret.Sources[0])
+	}
+	if token := cfg.SecretStore.Credentials["team-a"].Token; token != "team-a-token" {
+		t.Errorf("expected credential set token expanded, got %q", token)
+	}
+}
+
 func TestValidate_KVVersion(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -218,6 +405,266 @@ func TestValidate_MissingMountPath(t *testing.T) {
 	}
 }
 
+func TestValidate_NegativeSyncTimeout(t *testing.T) {
+	cfg := &Config{
+		SecretStore: SecretStore{
+			Address:    "https://vault.example.com",
+			AuthMethod: "token",
+			Token:      "test",
+		},
+		Secrets: []Secret{
+			{
+				Name:            "test",
+				Key:             "test/path",
+				MountPath:       "secret",
+				KVVersion:       "v2",
+				RefreshInterval: 5 * time.Minute,
+				SyncTimeout:     -time.Second,
+				Template:        Template{Data: map[string]string{"key": "value"}},
+				Files:           []File{{Path: "/test"}},
+			},
+		},
+	}
+
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "syncTimeout") {
+		t.Fatalf("expected syncTimeout error, got: %v", err)
+	}
+}
+
+func TestValidate_Version(t *testing.T) {
+	tests := []struct {
+		name      string
+		kvVersion string
+		version   int
+		wantErr   string
+	}{
+		{name: "pinned", kvVersion: "v2", version: 3},
+		{name: "negative", kvVersion: "v2", version: -1, wantErr: "version must not be negative"},
+		{name: "kv v1", kvVersion: "v1", version: 3, wantErr: "version requires kvVersion v2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				SecretStore: SecretStore{
+					Address:    "https://vault.example.com",
+					AuthMethod: "token",
+					Token:      "test",
+				},
+				Secrets: []Secret{
+					{
+						Name:            "test",
+						Key:             "test/path",
+						MountPath:       "secret",
+						KVVersion:       tt.kvVersion,
+						Version:         tt.version,
+						RefreshInterval: 5 * time.Minute,
+						Template:        Template{Data: map[string]string{"key": "value"}},
+						Files:           []File{{Path: "/test"}},
+					},
+				},
+			}
+
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_AzureKeyVault(t *testing.T) {
+	store := func() SecretStore {
+		return SecretStore{
+			Type:       StoreTypeAzureKeyVault,
+			Address:    "https://myvault.vault.azure.net",
+			AuthMethod: "managedIdentity",
+		}
+	}
+	secret := func() Secret {
+		return Secret{
+			Name:            "tls",
+			Key:             "web-tls",
+			ObjectType:      "certificate",
+			RefreshInterval: 5 * time.Minute,
+			Template:        Template{Data: map[string]string{"key": "{{ .privateKey }}"}},
+			Files:           []File{{Path: "/test"}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*SecretStore, *Secret)
+		wantErr string
+	}{
+		{name: "managed identity"},
+		{name: "client secret", modify: func(st *SecretStore, _ *Secret) {
+			st.AuthMethod, st.TenantID, st.ClientID, st.ClientSecret = "clientSecret", "tenant", "app", "secret"
+		}},
+		{name: "client secret without tenant", modify: func(st *SecretStore, _ *Secret) {
+			st.AuthMethod, st.ClientID, st.ClientSecret = "clientSecret", "app", "secret"
+		}, wantErr: "tenantId is required for clientSecret auth"},
+		{name: "vault auth method", modify: func(st *SecretStore, _ *Secret) {
+			st.AuthMethod = "token"
+		}, wantErr: "unsupported authMethod: token (supported: managedIdentity, clientSecret)"},
+		{name: "invalid credential set", modify: func(st *SecretStore, _ *Secret) {
+			st.Credentials = map[string]CredentialSet{"team": {AuthMethod: "clientSecret"}}
+		}, wantErr: "credentials[team]: tenantId is required"},
+		{name: "http address", modify: func(st *SecretStore, _ *Secret) {
+			st.Address = "http://myvault.vault.azure.net"
+		}, wantErr: "address must use https"},
+		{name: "namespace", modify: func(st *SecretStore, _ *Secret) {
+			st.Namespace = "team"
+		}, wantErr: "namespace cannot be used with type azureKeyVault"},
+		{name: "unknown type", modify: func(st *SecretStore, _ *Secret) {
+			st.Type = "gcp"
+		}, wantErr: "unsupported type: gcp"},
+		{name: "path as key", modify: func(_ *SecretStore, s *Secret) {
+			s.Key = "app/db"
+		}, wantErr: "key must be a Key Vault object name"},
+		{name: "unknown object type", modify: func(_ *SecretStore, s *Secret) {
+			s.ObjectType = "blob"
+		}, wantErr: "objectType must be secret, key or certificate"},
+		{name: "mount path", modify: func(_ *SecretStore, s *Secret) {
+			s.MountPath, s.KVVersion = "secret", "v2"
+		}, wantErr: "cannot be used with secretStore type azureKeyVault"},
+		{name: "object type with vault", modify: func(st *SecretStore, s *Secret) {
+			*st = SecretStore{Address: "https://vault.example.com", AuthMethod: "token", Token: "test"}
+			s.Key, s.MountPath, s.KVVersion = "test/path", "secret", "v2"
+		}, wantErr: "objectType requires secretStore type azureKeyVault"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{SecretStore: store(), Secrets: []Secret{secret()}}
+			if tt.modify != nil {
+				tt.modify(&cfg.SecretStore, &cfg.Secrets[0])
+			}
+
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_DatabaseSecret(t *testing.T) {
+	secret := func() Secret {
+		return Secret{
+			Name:            "db",
+			Key:             "app-readonly",
+			Type:            SecretTypeDatabase,
+			MountPath:       "database",
+			RefreshInterval: 5 * time.Minute,
+			Template:        Template{Data: map[string]string{"user": "{{ .username }}"}},
+			Files:           []File{{Path: "/test", Template: "user"}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*Secret)
+		wantErr string
+	}{
+		{name: "role"},
+		{name: "kv version", modify: func(s *Secret) {
+			s.KVVersion = "v2"
+		}, wantErr: "kvVersion and version cannot be used with type database"},
+		{name: "path as key", modify: func(s *Secret) {
+			s.Key = "creds/app"
+		}, wantErr: "key must be a database role name"},
+		{name: "wildcard", modify: func(s *Secret) {
+			s.Key = "*"
+		}, wantErr: "key must be a database role name"},
+		{name: "missing mount path", modify: func(s *Secret) {
+			s.MountPath = ""
+		}, wantErr: "mountPath is required"},
+		{name: "unknown type", modify: func(s *Secret) {
+			s.Type = "pki"
+		}, wantErr: "type must be kv or database, got: pki"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				SecretStore: SecretStore{Address: "https://vault.example.com", AuthMethod: "token", Token: "test"},
+				Secrets:     []Secret{secret()},
+			}
+			if tt.modify != nil {
+				tt.modify(&cfg.Secrets[0])
+			}
+
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_Notifications(t *testing.T) {
+	tests := []struct {
+		name    string
+		webhook Webhook
+		wantErr string
+	}{
+		{name: "json", webhook: Webhook{URL: "https://hooks.example.com/secrets"}},
+		{name: "slack", webhook: Webhook{URL: "https://hooks.slack.com/services/T0/B0/x", Format: "slack", Events: []string{"failure", "recovery"}}},
+		{name: "missing url", webhook: Webhook{}, wantErr: "url must be an http or https URL"},
+		{name: "relative url", webhook: Webhook{URL: "hooks/secrets"}, wantErr: "url must be an http or https URL"},
+		{name: "unknown format", webhook: Webhook{URL: "https://hooks.example.com", Format: "teams"}, wantErr: "unsupported format: teams"},
+		{name: "unknown event", webhook: Webhook{URL: "https://hooks.example.com", Events: []string{"sync"}}, wantErr: "unsupported event: sync"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				SecretStore: SecretStore{Address: "https://vault.example.com", AuthMethod: "token", Token: "test"},
+				Secrets: []Secret{{
+					Name: "app", Key: "app", MountPath: "secret", KVVersion: "v2", RefreshInterval: time.Minute,
+					Template: Template{Data: map[string]string{"key": "{{ .key }}"}},
+					Files:    []File{{Path: "/test"}},
+				}},
+				Notifications: Notifications{Webhooks: []Webhook{tt.webhook}},
+			}
+
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if cfg.Notifications.ExpiryWarning != DefaultExpiryWarning {
+					t.Errorf("expected default expiry warning, got %s", cfg.Notifications.ExpiryWarning)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q error, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
 func TestValidate_MissingKey(t *testing.T) {
 	cfg := &Config{
 		SecretStore: SecretStore{
@@ -383,3 +830,73 @@ func TestValidate_RelativePathResolution(t *testing.T) {
 		t.Errorf("expected absolute path, got: %s", cfg.Secrets[0].Files[0].Path)
 	}
 }
+
+func TestLoad_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Load(ctx, "../../testdata/valid-config.yaml")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestLoadData(t *testing.T) {
+	t.Setenv("VAULT_TOKEN", "test-token")
+	data, err := os.ReadFile("../../testdata/valid-config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadData(context.Background(), "/configs/app", "", data)
+	if err != nil {
+		t.Fatalf("LoadData() error = %v", err)
+	}
+	if cfg.SecretStore.Token != "test-token" || len(cfg.Secrets) == 0 {
+		t.Errorf("LoadData() = %+v, want expanded token and secrets", cfg)
+	}
+
+	// The format is told by the name unless given
+	if _, err := LoadData(context.Background(), "/configs/app.toml", "", data); err == nil {
+		t.Error("LoadData() parsed YAML as TOML")
+	}
+	if _, err := LoadData(context.Background(), "/configs/app.toml", FormatYAML, data); err != nil {
+		t.Errorf("LoadData() with format error = %v", err)
+	}
+
+	_, err = LoadData(context.Background(), "/configs/app", "", append([]byte("include: [other.yaml]\n"), data...))
+	if err == nil || !strings.Contains(err.Error(), "include is not supported") {
+		t.Errorf("LoadData() error = %v, want include rejected", err)
+	}
+}
+
+func TestParseJitter(t *testing.T) {
+	valid := map[string]float64{"": 0, "0%": 0, "10%": 0.1, "12.5": 0.125, "50%": 0.5}
+	for input, want := range valid {
+		got, err := ParseJitter(input)
+		if err != nil || got != want {
+			t.Errorf("ParseJitter(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"51%", "-1%", "ten", "5m"} {
+		if _, err := ParseJitter(input); err == nil {
+			t.Errorf("ParseJitter(%q): expected error", input)
+		}
+	}
+}
+
+func TestValidate_StartupMode(t *testing.T) {
+	for _, mode := range []string{"", StartupModeExit, StartupModeWait} {
+		cfg := manySecrets(1, time.Hour)
+		cfg.StartupMode = mode
+		if err := Validate(cfg); err != nil {
+			t.Errorf("startupMode %q: unexpected error %v", mode, err)
+		}
+	}
+
+	cfg := manySecrets(1, time.Hour)
+	cfg.StartupMode = "retry"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "startupMode must be exit or wait, got: retry") {
+		t.Errorf("expected startupMode error, got %v", err)
+	}
+}
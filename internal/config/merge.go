@@ -0,0 +1,162 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MergeResult names the secrets Merge added and those it left out
+type MergeResult struct {
+	Added   []string
+	Skipped []string // Already defined in the existing config
+}
+
+// Merge adds the secrets of a generated YAML config to an existing one.
+// Secrets whose name the existing config already defines are left out, so
+// merging the same config twice adds nothing. The secretStore, and any of
+// its credential sets, are added only where the existing config lacks them.
+// Comments are kept. An empty existing config returns generated, and one
+// that gains nothing is returned unchanged.
+func Merge(existing, generated []byte) ([]byte, MergeResult, error) {
+	var result MergeResult
+
+	gen, err := parseMapping(generated)
+	if err != nil {
+		return nil, result, fmt.Errorf("generated config: %w", err)
+	}
+	if len(bytes.TrimSpace(existing)) == 0 {
+		if secrets := mappingValue(gen.Content[0], "secrets"); secrets != nil && secrets.Kind == yaml.SequenceNode {
+			for _, secret := range secrets.Content {
+				result.Added = append(result.Added, scalarValue(secret, "name"))
+			}
+		}
+		return generated, result, nil
+	}
+
+	doc, err := parseMapping(existing)
+	if err != nil {
+		return nil, result, err
+	}
+	root, genRoot := doc.Content[0], gen.Content[0]
+	changed := false
+
+	if genStore := mappingValue(genRoot, "secretStore"); genStore != nil {
+		store := mappingValue(root, "secretStore")
+		switch {
+		case store == nil:
+			// Keep the store in front of the secrets, as generated
+			root.Content = append([]*yaml.Node{{Kind: yaml.ScalarNode, Tag: "!!str", Value: "secretStore"}, genStore}, root.Content...)
+			changed = true
+		case store.Kind == yaml.MappingNode:
+			changed = mergeCredentials(store, genStore)
+		}
+	}
+
+	// Secrets commented out at the end of generated are its foot comment
+	if gen.FootComment != "" && !containsComment(existing, gen.FootComment) {
+		doc.FootComment = strings.TrimSpace(doc.FootComment + "\n\n" + gen.FootComment)
+		changed = true
+	}
+
+	genSecrets := mappingValue(genRoot, "secrets")
+	if genSecrets == nil || genSecrets.Kind != yaml.SequenceNode || len(genSecrets.Content) == 0 {
+		if !changed {
+			return existing, result, nil
+		}
+		return encodeNode(&doc, result)
+	}
+	secrets := mappingValue(root, "secrets")
+	if secrets == nil {
+		secrets = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "secrets"}, secrets)
+	}
+	if secrets.Kind != yaml.SequenceNode {
+		// "secrets:" without entries is null
+		if secrets.Kind != yaml.ScalarNode || secrets.Tag != "!!null" {
+			return nil, result, fmt.Errorf("secrets must be a list")
+		}
+		*secrets = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", HeadComment: secrets.HeadComment, LineComment: secrets.LineComment}
+	}
+
+	names := make(map[string]bool, len(secrets.Content))
+	for _, secret := range secrets.Content {
+		names[scalarValue(secret, "name")] = true
+	}
+	for _, secret := range genSecrets.Content {
+		name := scalarValue(secret, "name")
+		if names[name] {
+			result.Skipped = append(result.Skipped, name)
+			continue
+		}
+		names[name] = true
+		secrets.Content = append(secrets.Content, secret)
+		result.Added = append(result.Added, name)
+	}
+	if !changed && len(result.Added) == 0 {
+		return existing, result, nil
+	}
+	return encodeNode(&doc, result)
+}
+
+// mergeCredentials adds the credential sets of generated that store lacks,
+// reporting whether it added any
+func mergeCredentials(store, generated *yaml.Node) bool {
+	genCreds := mappingValue(generated, "credentials")
+	if genCreds == nil || genCreds.Kind != yaml.MappingNode || len(genCreds.Content) == 0 {
+		return false
+	}
+	creds := mappingValue(store, "credentials")
+	if creds == nil {
+		store.Content = append(store.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "credentials"}, genCreds)
+		return true
+	}
+	if creds.Kind != yaml.MappingNode {
+		return false
+	}
+	added := false
+	for _, pair := range mappingPairs(genCreds) {
+		if mappingValue(creds, pair[0].Value) == nil {
+			creds.Content = append(creds.Content, pair[0], pair[1])
+			added = true
+		}
+	}
+	return added
+}
+
+// containsComment reports whether data already holds a comment, ignoring
+// differences in indentation
+func containsComment(data []byte, comment string) bool {
+	strip := func(s string) string {
+		return strings.Join(strings.Fields(s), "")
+	}
+	return strings.Contains(strip(string(data)), strip(comment))
+}
+
+// parseMapping parses a YAML document holding a mapping
+func parseMapping(data []byte) (yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return doc, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return doc, fmt.Errorf("config must be a YAML mapping")
+	}
+	return doc, nil
+}
+
+// encodeNode encodes a merged document the way Format does
+func encodeNode(doc *yaml.Node, result MergeResult) ([]byte, MergeResult, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, result, fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, result, fmt.Errorf("failed to encode config: %w", err)
+	}
+	return buf.Bytes(), result, nil
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const generatedConfig = `# Generated configuration from external-secrets
+
+secretStore:
+  address: "https://vault.example.com"
+  authMethod: "token"
+  token: "${VAULT_TOKEN}"
+  credentials:
+    team-a:
+      authMethod: "approle"
+      roleId: "abc"
+      secretId: "${VAULT_SECRET_ID_TEAM_A}"
+
+secrets:
+
+  # Converted from: es.yaml (secret: api)
+  - name: "api"
+    key: "app/api"
+    files:
+      - path: "/secrets/api"
+
+  # Converted from: es.yaml (secret: web)
+  - name: "web"
+    key: "app/web"
+    files:
+      - path: "/secrets/web"
+
+  # Converted from: es.yaml (secret: db)
+  # WARNING: Vault query failed - secret commented out, needs manual field mapping
+  # - name: "db"
+  #   key: "app/db"
+`
+
+func TestMerge_Empty(t *testing.T) {
+	out, result, err := Merge(nil, []byte(generatedConfig))
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if string(out) != generatedConfig {
+		t.Errorf("expected generated config unchanged, got:\n%s", out)
+	}
+	if !reflect.DeepEqual(result.Added, []string{"api", "web"}) {
+		t.Errorf("expected api and web added, got %v", result.Added)
+	}
+}
+
+func TestMerge_SkipsExistingSecrets(t *testing.T) {
+	existing := `# Production
+secretStore:
+  address: "https://vault.example.com"
+  authMethod: "token"
+  token: "${VAULT_TOKEN}" # from systemd
+
+secrets:
+  # Hand-written
+  - name: "api"
+    key: "app/api"
+    files:
+      - path: "/secrets/api"
+`
+	out, result, err := Merge([]byte(existing), []byte(generatedConfig))
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if !reflect.DeepEqual(result.Added, []string{"web"}) || !reflect.DeepEqual(result.Skipped, []string{"api"}) {
+		t.Errorf("expected web added and api skipped, got %+v", result)
+	}
+
+	for _, want := range []string{
+		"# Production",
+		"# from systemd",
+		"# Hand-written",
+		"# Converted from: es.yaml (secret: web)",
+		`# - name: "db"`,
+		"team-a:",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("expected %q in merged config:\n%s", want, out)
+		}
+	}
+	if strings.Count(string(out), `name: "api"`) != 1 {
+		t.Errorf("expected api once, got:\n%s", out)
+	}
+	if strings.Count(string(out), "secretStore:") != 1 {
+		t.Errorf("expected one secretStore, got:\n%s", out)
+	}
+
+	// Merging again adds nothing and leaves the file as it is
+	again, result, err := Merge(out, []byte(generatedConfig))
+	if err != nil {
+		t.Fatalf("second Merge failed: %v", err)
+	}
+	if len(result.Added) != 0 || string(again) != string(out) {
+		t.Errorf("expected no change merging twice, got %+v:\n%s", result, again)
+	}
+}
+
+func TestMerge_AddsSecretStore(t *testing.T) {
+	existing := "secrets:\n"
+
+	out, result, err := Merge([]byte(existing), []byte(generatedConfig))
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if len(result.Added) != 2 {
+		t.Errorf("expected 2 secrets added, got %+v", result)
+	}
+	if !strings.HasPrefix(string(out), "secretStore:") {
+		t.Errorf("expected secretStore first, got:\n%s", out)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(out, &cfg); err != nil {
+		t.Fatalf("merged config does not parse: %v", err)
+	}
+	if cfg.SecretStore.Address != "https://vault.example.com" || len(cfg.Secrets) != 2 {
+		t.Errorf("unexpected merged config: %+v", cfg)
+	}
+	if _, ok := cfg.SecretStore.Credentials["team-a"]; !ok {
+		t.Errorf("expected credentials team-a, got %+v", cfg.SecretStore.Credentials)
+	}
+}
+
+func TestMerge_Invalid(t *testing.T) {
+	if _, _, err := Merge([]byte("secrets: {}\n"), []byte(generatedConfig)); err == nil || !strings.Contains(err.Error(), "secrets must be a list") {
+		t.Errorf("expected an error for secrets that are not a list, got %v", err)
+	}
+	if _, _, err := Merge([]byte("- a\n"), []byte(generatedConfig)); err == nil {
+		t.Error("expected an error for a config that is not a mapping")
+	}
+}
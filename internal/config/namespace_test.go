@@ -33,6 +33,12 @@ func TestResolveNamespace(t *testing.T) {
 			globalNamespace: "",
 			expected:        "team-b",
 		},
+		{
+			name:            "root namespace overrides global namespace",
+			secretNamespace: RootNamespace,
+			globalNamespace: "default",
+			expected:        "",
+		},
 	}
 
 	for _, tt := range tests {
@@ -48,3 +54,27 @@ func TestResolveNamespace(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateNamespace(t *testing.T) {
+	tests := []struct {
+		namespace string
+		wantErr   bool
+	}{
+		{"", false},
+		{"team-a", false},
+		{"tenant/team-a", false},
+		{"tenant/team-a/", false},
+		{"/team-a", true},
+		{"tenant//team-a", true},
+		{"tenant/../team-a", true},
+		{"team a", true},
+		{"team-a\n", true},
+	}
+
+	for _, tt := range tests {
+		err := validateNamespace("namespace", tt.namespace)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validateNamespace(%q) error = %v, wantErr %v", tt.namespace, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// sourcesConfig returns a valid config whose only secret reads the given sources
+func sourcesConfig(sources []Source) *Config {
+	cfg := bindingConfig(
+		map[string]string{"ini": "{{ .db.password }}"},
+		[]File{{Path: "/secrets/pgbouncer.ini", Template: "ini"}},
+	)
+	secret := &cfg.Secrets[0]
+	secret.Key, secret.MountPath, secret.KVVersion = "", "", ""
+	secret.Sources = sources
+	return cfg
+}
+
+func TestValidate_Sources(t *testing.T) {
+	db := Source{Name: "db", Key: "app/db", MountPath: "secret", KVVersion: "v2"}
+
+	tests := []struct {
+		name    string
+		modify  func(cfg *Config)
+		wantErr string
+	}{
+		{"valid", func(cfg *Config) {}, ""},
+		{"with key", func(cfg *Config) { cfg.Secrets[0].Key = "app/other" }, "cannot be used with sources"},
+		{"with type database", func(cfg *Config) { cfg.Secrets[0].Type = SecretTypeDatabase }, "cannot be used with type database"},
+		{"azure", func(cfg *Config) {
+			cfg.SecretStore = SecretStore{Type: StoreTypeAzureKeyVault, Address: "https://app.vault.azure.net", AuthMethod: "managedIdentity"}
+		}, "sources cannot be used with secretStore type azureKeyVault"},
+		{"no name", func(cfg *Config) { cfg.Secrets[0].Sources[0].Name = "" }, "sources[0]: name is required"},
+		{"name with dash", func(cfg *Config) { cfg.Secrets[0].Sources[0].Name = "my-db" }, "sources[0]: name must be"},
+		{"duplicate name", func(cfg *Config) { cfg.Secrets[0].Sources = append(cfg.Secrets[0].Sources, db) }, `sources[1]: duplicate name "db"`},
+		{"no mountPath", func(cfg *Config) { cfg.Secrets[0].Sources[0].MountPath = "" }, "sources[0]: mountPath is required"},
+		{"wildcard", func(cfg *Config) { cfg.Secrets[0].Sources[0].Key = "app/*" }, "sources[0]: key cannot be a wildcard"},
+		{"bad kvVersion", func(cfg *Config) { cfg.Secrets[0].Sources[0].KVVersion = "v3" }, "sources[0]: kvVersion must be"},
+		{"version with v1", func(cfg *Config) {
+			cfg.Secrets[0].Sources[0].KVVersion = "v1"
+			cfg.Secrets[0].Sources[0].Version = 2
+		}, "sources[0]: version requires kvVersion v2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := sourcesConfig([]Source{db})
+			tt.modify(cfg)
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSecret_ForSource(t *testing.T) {
+	secret := Secret{Name: "pgbouncer", Namespace: "team", Sources: []Source{{Name: "db", Key: "app/db", MountPath: "secret", KVVersion: "v2", Version: 3}}}
+
+	got := secret.ForSource(secret.Sources[0])
+	if got.Key != "app/db" || got.MountPath != "secret" || got.KVVersion != "v2" || got.Version != 3 {
+		t.Errorf("expected the source's location, got %+v", got)
+	}
+	if got.Namespace != "team" || got.Sources != nil {
+		t.Errorf("expected the secret's namespace and no sources, got %+v", got)
+	}
+}
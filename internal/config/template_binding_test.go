@@ -0,0 +1,342 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// bindingConfig returns a valid config whose only secret has the given templates and files
+func bindingConfig(data map[string]string, files []File) *Config {
+	return &Config{
+		SecretStore: SecretStore{
+			Address:    "https://vault.example.com",
+			AuthMethod: "token",
+			Token:      "test",
+		},
+		Secrets: []Secret{
+			{
+				Name:            "test",
+				Key:             "test/path",
+				MountPath:       "secret",
+				KVVersion:       "v2",
+				RefreshInterval: 5 * time.Minute,
+				Template:        Template{Data: data},
+				Files:           files,
+			},
+		},
+	}
+}
+
+func TestValidate_ExplicitTemplateBinding(t *testing.T) {
+	cfg := bindingConfig(
+		map[string]string{"tls.crt": "{{ .crt }}", "tls.key": "{{ .key }}"},
+		[]File{
+			{Path: "/secrets/tls.key", Template: "tls.key"},
+			{Path: "/secrets/tls.crt", Template: "tls.crt"},
+		},
+	)
+
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if cfg.Secrets[0].UsesImplicitTemplates() {
+		t.Error("expected explicit binding not to be reported as implicit")
+	}
+
+	got := cfg.Secrets[0].FileTemplates()
+	if got[0] != "tls.key" || got[1] != "tls.crt" {
+		t.Errorf("expected templates to follow the template field, got %v", got)
+	}
+}
+
+func TestValidate_TemplateUsedByMultipleFiles(t *testing.T) {
+	cfg := bindingConfig(
+		map[string]string{"ca": "{{ .ca }}"},
+		[]File{
+			{Path: "/secrets/a/ca.crt", Template: "ca"},
+			{Path: "/secrets/b/ca.crt", Template: "ca"},
+		},
+	)
+
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+}
+
+func TestValidate_TemplateBindingErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string]string
+		files   []File
+		wantErr string
+	}{
+		{
+			name:    "unknown template",
+			data:    map[string]string{"a": "x"},
+			files:   []File{{Path: "/secrets/a", Template: "b"}},
+			wantErr: `template "b" not found`,
+		},
+		{
+			name: "unused template",
+			data: map[string]string{"a": "x", "b": "y"},
+			files: []File{
+				{Path: "/secrets/a", Template: "a"},
+				{Path: "/secrets/b", Template: "a"},
+			},
+			wantErr: "not used by any file",
+		},
+		{
+			name: "mixed binding",
+			data: map[string]string{"a": "x", "b": "y"},
+			files: []File{
+				{Path: "/secrets/a", Template: "a"},
+				{Path: "/secrets/b"},
+			},
+			wantErr: "all set template or none",
+		},
+		{
+			name:    "format with template",
+			files:   []File{{Path: "/secrets/app.env", Format: "env", Template: "a"}},
+			wantErr: "template cannot be used with format",
+		},
+		{
+			name:    "unknown format",
+			files:   []File{{Path: "/secrets/app.yaml", Format: "yaml"}},
+			wantErr: "format must be json, env, pkcs12, jks or concat",
+		},
+		{
+			name:    "keys without format",
+			data:    map[string]string{"a": "x"},
+			files:   []File{{Path: "/secrets/a", Template: "a", Keys: []string{"a"}}},
+			wantErr: "keys requires format",
+		},
+		{
+			name:    "template unused with format only",
+			data:    map[string]string{"a": "x"},
+			files:   []File{{Path: "/secrets/app.json", Format: "json"}},
+			wantErr: "not used by any file",
+		},
+		{
+			name:    "keystore without keystore settings",
+			files:   []File{{Path: "/secrets/app.p12", Format: "pkcs12"}},
+			wantErr: "keystore is required with format pkcs12",
+		},
+		{
+			name: "keystore without password",
+			data: map[string]string{"crt": "x", "key": "y"},
+			files: []File{{Path: "/secrets/app.p12", Format: "pkcs12",
+				Keystore: &Keystore{Certificate: "crt", PrivateKey: "key"}}},
+			wantErr: "keystore requires certificate, privateKey and password",
+		},
+		{
+			name: "keystore alias with pkcs12",
+			data: map[string]string{"crt": "x", "key": "y", "pass": "z"},
+			files: []File{{Path: "/secrets/app.p12", Format: "pkcs12",
+				Keystore: &Keystore{Certificate: "crt", PrivateKey: "key", Password: "pass", Alias: "web"}}},
+			wantErr: "keystore alias requires format jks",
+		},
+		{
+			name: "keystore with unknown template",
+			data: map[string]string{"crt": "x", "key": "y"},
+			files: []File{{Path: "/secrets/app.jks", Format: "jks",
+				Keystore: &Keystore{Certificate: "crt", PrivateKey: "key", Password: "pass"}}},
+			wantErr: `keystore template "pass" not found`,
+		},
+		{
+			name: "keystore settings without keystore format",
+			files: []File{{Path: "/secrets/app.json", Format: "json",
+				Keystore: &Keystore{Certificate: "crt"}}},
+			wantErr: "keystore requires format pkcs12 or jks",
+		},
+		{
+			name: "keystore with positional files",
+			data: map[string]string{"crt": "x", "key": "y", "pass": "z"},
+			files: []File{
+				{Path: "/secrets/app.p12", Format: "pkcs12",
+					Keystore: &Keystore{Certificate: "crt", PrivateKey: "key", Password: "pass"}},
+				{Path: "/secrets/tls.crt"},
+			},
+			wantErr: "files must set template when a keystore or concat file is written",
+		},
+		{
+			name:    "concat without templates",
+			files:   []File{{Path: "/secrets/chain.pem", Format: "concat", Concat: &Concat{}}},
+			wantErr: "concat.templates is required with format concat",
+		},
+		{
+			name: "concat with unknown template",
+			data: map[string]string{"crt": "x"},
+			files: []File{{Path: "/secrets/chain.pem", Format: "concat",
+				Concat: &Concat{Templates: []string{"crt", "ca"}}}},
+			wantErr: `concat template "ca" not found`,
+		},
+		{
+			name: "concat settings without concat format",
+			files: []File{{Path: "/secrets/app.json", Format: "json",
+				Concat: &Concat{Templates: []string{"crt"}}}},
+			wantErr: "concat requires format concat",
+		},
+		{
+			name: "positional count mismatch",
+			data: map[string]string{"a": "x"},
+			files: []File{
+				{Path: "/secrets/a"},
+				{Path: "/secrets/b"},
+			},
+			wantErr: "same number of entries",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(bindingConfig(tt.data, tt.files))
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestFileTemplates_Positional(t *testing.T) {
+	secret := Secret{
+		Template: Template{Data: map[string]string{"username": "u", "password": "p"}},
+		Files:    []File{{Path: "/secrets/first"}, {Path: "/secrets/second"}},
+	}
+
+	if !secret.UsesImplicitTemplates() {
+		t.Error("expected positional binding to be reported as implicit")
+	}
+
+	got := secret.FileTemplates()
+	if got[0] != "password" || got[1] != "username" {
+		t.Errorf("expected sorted template names by position, got %v", got)
+	}
+}
+
+func TestUsesImplicitTemplates_SingleFile(t *testing.T) {
+	secret := Secret{
+		Template: Template{Data: map[string]string{"key": "k"}},
+		Files:    []File{{Path: "/secrets/key"}},
+	}
+
+	if secret.UsesImplicitTemplates() {
+		t.Error("expected a single template and file to be unambiguous")
+	}
+}
+
+func TestValidate_FormatFiles(t *testing.T) {
+	cfg := bindingConfig(nil, []File{
+		{Path: "/secrets/app.env", Format: "env"},
+		{Path: "/secrets/db.json", Format: "json", Keys: []string{"username", "password"}},
+	})
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// Formatted files are left out of positional binding
+	secret := Secret{
+		Template: Template{Data: map[string]string{"key": "k"}},
+		Files:    []File{{Path: "/secrets/app.env", Format: "env"}, {Path: "/secrets/key"}},
+	}
+	if secret.UsesImplicitTemplates() {
+		t.Error("expected a single template and templated file to be unambiguous")
+	}
+	if got := secret.FileTemplates(); got[0] != "" || got[1] != "key" {
+		t.Errorf("expected only the templated file to be bound, got %v", got)
+	}
+}
+
+func TestValidate_KeystoreFiles(t *testing.T) {
+	cfg := bindingConfig(
+		map[string]string{"crt": "{{ .certificate }}", "key": "{{ .privateKey }}", "pass": "{{ .password }}"},
+		[]File{
+			{Path: "/secrets/app.p12", Format: "pkcs12",
+				Keystore: &Keystore{Certificate: "crt", PrivateKey: "key", Password: "pass"}},
+			{Path: "/secrets/app.jks", Format: "jks",
+				Keystore: &Keystore{Certificate: "crt", PrivateKey: "key", Password: "pass", Alias: "web"}},
+			{Path: "/secrets/tls.crt", Template: "crt"},
+		},
+	)
+
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if cfg.Secrets[0].UsesImplicitTemplates() {
+		t.Error("expected keystores not to count as positional binding")
+	}
+}
+
+func TestValidate_ConcatFiles(t *testing.T) {
+	separator := "\n---\n"
+	cfg := bindingConfig(
+		map[string]string{"crt": "{{ .certificate }}", "int": "{{ .intermediate }}", "ca": "{{ .ca }}"},
+		[]File{
+			{Path: "/secrets/fullchain.pem", Format: "concat", CertExpiry: true,
+				Concat: &Concat{Templates: []string{"crt", "int", "ca"}}},
+			{Path: "/secrets/chain.pem", Format: "concat",
+				Concat: &Concat{Templates: []string{"int", "ca"}, Separator: &separator}},
+		},
+	)
+
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if cfg.Secrets[0].UsesImplicitTemplates() {
+		t.Error("expected concat files not to count as positional binding")
+	}
+}
+
+func TestValidate_CertExpiry(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    File
+		wantErr string
+	}{
+		{"template", File{Path: "/secrets/tls.crt", Template: "crt", CertExpiry: true}, ""},
+		{"keystore", File{Path: "/secrets/app.p12", Format: "pkcs12", CertExpiry: true,
+			Keystore: &Keystore{Certificate: "crt", PrivateKey: "key", Password: "pass"}}, ""},
+		{"json", File{Path: "/secrets/app.json", Format: "json", CertExpiry: true}, "certExpiry requires a template"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := []File{tt.file}
+			if tt.file.Keystore == nil {
+				files = append(files, File{Path: "/secrets/app.p12", Format: "pkcs12",
+					Keystore: &Keystore{Certificate: "crt", PrivateKey: "key", Password: "pass"}})
+			}
+			cfg := bindingConfig(
+				map[string]string{"crt": "{{ .certificate }}", "key": "{{ .privateKey }}", "pass": "{{ .password }}"},
+				files,
+			)
+			err := Validate(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_KeepBackups(t *testing.T) {
+	for keep, wantErr := range map[int]bool{0: false, 3: false, 100: false, -1: true, 101: true} {
+		cfg := bindingConfig(map[string]string{"crt": "{{ .certificate }}"},
+			[]File{{Path: "/secrets/tls.crt", KeepBackups: keep}})
+		err := Validate(cfg)
+		if wantErr && (err == nil || !strings.Contains(err.Error(), "keepBackups must be between 0 and 100")) {
+			t.Errorf("keepBackups %d: expected a range error, got: %v", keep, err)
+		}
+		if !wantErr && err != nil {
+			t.Errorf("keepBackups %d: expected no error, got: %v", keep, err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// TemplateFileName returns the template.data key the content of a
+// template.file is found at: its base name, e.g. pgpass.tmpl
+func TemplateFileName(path string) string {
+	return filepath.Base(path)
+}
+
+// loadTemplateFiles reads the template.file of every secret into its
+// template.data. Relative paths are resolved against dir, the directory of
+// the config file. Every problem found is passed to report, stopping once
+// report returns false.
+func loadTemplateFiles(cfg *Config, dir string, report func(error) bool) {
+	for i := range cfg.Secrets {
+		tmpl := &cfg.Secrets[i].Template
+		if tmpl.File == "" {
+			continue
+		}
+		if err := loadTemplateFile(tmpl, dir); err != nil && !report(fmt.Errorf("secrets[%d]: %w", i, err)) {
+			return
+		}
+	}
+}
+
+// loadTemplateFile reads a template.file into template.data
+func loadTemplateFile(tmpl *Template, dir string) error {
+	path := tmpl.File
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	tmpl.File = filepath.Clean(path)
+
+	name := TemplateFileName(tmpl.File)
+	if _, ok := tmpl.Data[name]; ok {
+		return fmt.Errorf("template.file is named %q like a template in template.data", name)
+	}
+	content, err := os.ReadFile(tmpl.File)
+	if err != nil {
+		return fmt.Errorf("template.file cannot be read: %w", err)
+	}
+	if tmpl.Data == nil {
+		tmpl.Data = make(map[string]string, 1)
+	}
+	tmpl.Data[name] = string(content)
+	return nil
+}
+
+// TemplateFiles returns the template files the secrets are rendered from,
+// sorted and without duplicates
+func (c *Config) TemplateFiles() []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, secret := range c.Secrets {
+		if path := secret.Template.File; path != "" && !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
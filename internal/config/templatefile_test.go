@@ -0,0 +1,83 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const templateFileConfig = `secretStore:
+  address: "https://vault.example.com"
+  authMethod: "token"
+  token: "test"
+secrets:
+  - name: "pgpass"
+    key: "app/db"
+    mountPath: "secret"
+    kvVersion: "v2"
+    refreshInterval: 5m
+    template:
+      file: "templates/pgpass.tmpl"
+      data:
+        user: "{{ .username }}"
+    files:
+      - path: "/secrets/pgpass"
+        template: "pgpass.tmpl"
+      - path: "/secrets/user"
+        template: "user"
+`
+
+func TestLoad_TemplateFile(t *testing.T) {
+	path := writeConfig(t, templateFileConfig)
+	dir := filepath.Join(filepath.Dir(path), "templates")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	content := "db:5432:*:{{ .username }}:{{ .password }}\n"
+	if err := os.WriteFile(filepath.Join(dir, "pgpass.tmpl"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	tmpl := cfg.Secrets[0].Template
+	if tmpl.Data["pgpass.tmpl"] != content || tmpl.Data["user"] != "{{ .username }}" {
+		t.Errorf("expected the file and inline templates, got %v", tmpl.Data)
+	}
+	wantPath := filepath.Join(dir, "pgpass.tmpl")
+	if tmpl.File != wantPath {
+		t.Errorf("expected the path resolved against the config directory, got %s", tmpl.File)
+	}
+	if files := cfg.TemplateFiles(); len(files) != 1 || files[0] != wantPath {
+		t.Errorf("expected %s as template file, got %v", wantPath, files)
+	}
+}
+
+func TestLoad_TemplateFileErrors(t *testing.T) {
+	t.Run("missing", func(t *testing.T) {
+		path := writeConfig(t, templateFileConfig)
+
+		_, err := Load(context.Background(), path)
+		if err == nil || !strings.Contains(err.Error(), "secrets[0]: template.file cannot be read") {
+			t.Fatalf("expected read error, got: %v", err)
+		}
+
+		_, diags := Diagnose(context.Background(), path)
+		if len(diags) != 1 || diags[0].Line != 12 {
+			t.Errorf("expected one located diagnostic, got %+v", diags)
+		}
+	})
+
+	t.Run("name taken", func(t *testing.T) {
+		path := writeConfig(t, strings.Replace(templateFileConfig, `user: "{{ .username }}"`, `pgpass.tmpl: "inline"`, 1))
+
+		_, err := Load(context.Background(), path)
+		if err == nil || !strings.Contains(err.Error(), `template.file is named "pgpass.tmpl" like a template in template.data`) {
+			t.Fatalf("expected name collision, got: %v", err)
+		}
+	})
+}
@@ -1,25 +1,112 @@
 package config
 
-import "time"
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // Config represents the complete configuration
 type Config struct {
-	SecretStore SecretStore `yaml:"secretStore"`
-	Secrets     []Secret    `yaml:"secrets"`
+	Include        []string       `yaml:"include,omitempty"` // Files, directories or globs whose secrets are added, relative to this file
+	SecretStore    SecretStore    `yaml:"secretStore"`
+	SecretDefaults SecretDefaults `yaml:"secretDefaults,omitempty"` // Inherited by secrets that do not set the field
+	Secrets        []Secret       `yaml:"secrets"`
+	Notifications  Notifications  `yaml:"notifications,omitempty"`
+	MaxSecrets     int            `yaml:"maxSecrets,omitempty"`  // Limit on the number of secrets (default: MAX_SECRETS or DefaultMaxSecrets)
+	StartupMode    string         `yaml:"startupMode,omitempty"` // exit (default) or wait when the secret store is unreachable at startup
+
+	AllowedOutputPaths []string `yaml:"allowedOutputPaths,omitempty"` // Directories files may be written to, any if empty
+}
+
+// Startup modes, what the service does when it cannot authenticate to the
+// secret store at startup
+const (
+	StartupModeExit = "exit" // Exit with an error
+	StartupModeWait = "wait" // Keep running, not ready, and retry until the store is reachable
+)
+
+// SecretDefaults are the fields secrets inherit unless they set them. Mode,
+// owner and group apply to files and directories.
+type SecretDefaults struct {
+	MountPath         string        `yaml:"mountPath,omitempty"`
+	KVVersion         string        `yaml:"kvVersion,omitempty"`
+	RefreshInterval   time.Duration `yaml:"refreshInterval,omitempty"`
+	Credentials       string        `yaml:"credentials,omitempty"`
+	Mode              string        `yaml:"mode,omitempty"`
+	Owner             string        `yaml:"owner,omitempty"`
+	Group             string        `yaml:"group,omitempty"`
+	DirMode           string        `yaml:"dirMode,omitempty"`
+	DirOwner          string        `yaml:"dirOwner,omitempty"`
+	DirGroup          string        `yaml:"dirGroup,omitempty"`
+	SELinuxContext    string        `yaml:"seLinuxContext,omitempty"`
+	CleanupOnShutdown string        `yaml:"cleanupOnShutdown,omitempty"`
+	RequireTmpfs      bool          `yaml:"requireTmpfs,omitempty"` // Enforced for every secret, which cannot opt out
 }
 
-// SecretStore defines Vault/OpenBao connection settings
+// Cleanup modes, what happens to the files of a secret when the service
+// shuts down
+const (
+	CleanupKeep   = "keep"   // Leave the files in place
+	CleanupRemove = "remove" // Delete the files
+	CleanupShred  = "shred"  // Overwrite the files, then delete them
+)
+
+// Secret store types
+const (
+	StoreTypeVault         = "vault"
+	StoreTypeAzureKeyVault = "azureKeyVault"
+)
+
+// Secret types, the Vault secrets engine a secret is read from
+const (
+	SecretTypeKV       = "kv"
+	SecretTypeDatabase = "database"
+)
+
+// SecretStore defines Vault/OpenBao or Azure Key Vault connection settings
 type SecretStore struct {
-	Address    string `yaml:"address"`
-	Namespace  string `yaml:"namespace,omitempty"` // OpenBao namespace (optional)
-	AuthMethod string `yaml:"authMethod"`
-	Token      string `yaml:"token"`
-	RoleID     string `yaml:"roleId"`
-	SecretID   string `yaml:"secretId"`
+	Type          string   `yaml:"type,omitempty"` // vault (default) or azureKeyVault
+	Address       string   `yaml:"address"`
+	Addresses     []string `yaml:"addresses,omitempty"`     // Failover endpoints, tried in order when address is unreachable
+	Namespace     string   `yaml:"namespace,omitempty"`     // OpenBao namespace (optional)
+	AuthNamespace string   `yaml:"authNamespace,omitempty"` // Namespace to log in in, root if unset (optional)
+	AuthMethod    string   `yaml:"authMethod"`
+	Token         string   `yaml:"token"`
+	RoleID        string   `yaml:"roleId"`
+	SecretID      string   `yaml:"secretId"`
+	TokenFile     string   `yaml:"tokenFile,omitempty"` // File holding the token for tokenFile auth, e.g. a Vault Agent sink
+
+	// AppRole secret ID delivery
+	SecretIDFile    string `yaml:"secretIdFile,omitempty"`    // File holding the secret ID, read on every login (optional)
+	SecretIDWrapped bool   `yaml:"secretIdWrapped,omitempty"` // The secret ID is a response-wrapping token to unwrap
+
+	// Kubernetes authentication
+	KubernetesRole      string `yaml:"kubernetesRole,omitempty"`      // Vault role bound to the service account
+	KubernetesTokenPath string `yaml:"kubernetesTokenPath,omitempty"` // Service account JWT (default: /var/run/secrets/kubernetes.io/serviceaccount/token)
+	KubernetesMountPath string `yaml:"kubernetesMountPath,omitempty"` // Auth mount (default: kubernetes)
+
+	// TLS certificate authentication, with tlsClientCert and tlsClientKey
+	CertRole      string `yaml:"certRole,omitempty"`      // Certificate role (default: any matching role)
+	CertMountPath string `yaml:"certMountPath,omitempty"` // Auth mount (default: cert)
+
+	// Azure Key Vault authentication
+	TenantID     string `yaml:"tenantId,omitempty"`     // Microsoft Entra tenant, for clientSecret
+	ClientID     string `yaml:"clientId,omitempty"`     // Application, or user-assigned managed identity (optional)
+	ClientSecret string `yaml:"clientSecret,omitempty"` // Application secret, for clientSecret
 
 	// Named credential sets for different namespaces/teams
 	Credentials map[string]CredentialSet `yaml:"credentials,omitempty"`
 
+	// Retries of Vault reads for every secret (optional)
+	Retry *Retry `yaml:"retry,omitempty"`
+
+	// HTTP connections to Vault, shared by all credential sets (optional)
+	Connection *Connection `yaml:"connection,omitempty"`
+
 	// TLS Configuration
 	TLSSkipVerify bool   `yaml:"tlsSkipVerify,omitempty"` // Skip TLS verification (insecure)
 	TLSCACert     string `yaml:"tlsCACert,omitempty"`     // Path to CA certificate file
@@ -28,47 +115,383 @@ type SecretStore struct {
 	TLSClientKey  string `yaml:"tlsClientKey,omitempty"`  // Path to client key
 }
 
+// Notifications defines the webhooks told about failing secrets, their
+// recovery and credentials about to expire
+type Notifications struct {
+	Webhooks      []Webhook     `yaml:"webhooks"`
+	ExpiryWarning time.Duration `yaml:"expiryWarning,omitempty"` // Warn when leased credentials failing to renew expire within this time (default: 1h)
+}
+
+// Notification events
+const (
+	EventFailure  = "failure"  // A secret failed as many syncs in a row as its alert threshold
+	EventRecovery = "recovery" // A secret synced again after a failure event
+	EventExpiry   = "expiry"   // Leased credentials could not be replaced and expire soon
+)
+
+// Webhook formats
+const (
+	WebhookFormatJSON  = "json"
+	WebhookFormatSlack = "slack"
+)
+
+// DefaultExpiryWarning is how long before leased credentials expire an
+// expiry notification is sent if they could not be replaced
+const DefaultExpiryWarning = time.Hour
+
+// Webhook is an endpoint notifications are POSTed to
+type Webhook struct {
+	URL     string            `yaml:"url"`
+	Format  string            `yaml:"format,omitempty"`  // json (default) or slack
+	Events  []string          `yaml:"events,omitempty"`  // failure, recovery, expiry; all if unset
+	Headers map[string]string `yaml:"headers,omitempty"` // Sent with every request, e.g. Authorization
+}
+
+// Wants reports whether the webhook receives event
+func (w Webhook) Wants(event string) bool {
+	return len(w.Events) == 0 || slices.Contains(w.Events, event)
+}
+
 // CredentialSet defines authentication credentials
 type CredentialSet struct {
 	AuthMethod string `yaml:"authMethod"`
 	Token      string `yaml:"token,omitempty"`
 	RoleID     string `yaml:"roleId,omitempty"`
 	SecretID   string `yaml:"secretId,omitempty"`
+	TokenFile  string `yaml:"tokenFile,omitempty"`
+
+	SecretIDFile    string `yaml:"secretIdFile,omitempty"`
+	SecretIDWrapped bool   `yaml:"secretIdWrapped,omitempty"`
+
+	KubernetesRole      string `yaml:"kubernetesRole,omitempty"`
+	KubernetesTokenPath string `yaml:"kubernetesTokenPath,omitempty"`
+	KubernetesMountPath string `yaml:"kubernetesMountPath,omitempty"`
+
+	CertRole      string `yaml:"certRole,omitempty"`
+	CertMountPath string `yaml:"certMountPath,omitempty"`
+
+	AuthNamespace string `yaml:"authNamespace,omitempty"`
+
+	TenantID     string `yaml:"tenantId,omitempty"`
+	ClientID     string `yaml:"clientId,omitempty"`
+	ClientSecret string `yaml:"clientSecret,omitempty"`
 }
 
 // Secret defines a single secret to sync
 type Secret struct {
-	Name            string        `yaml:"name"`
-	Key             string        `yaml:"key"`
-	MountPath       string        `yaml:"mountPath"`
-	Namespace       string        `yaml:"namespace,omitempty"`   // OpenBao namespace override (optional)
-	Credentials     string        `yaml:"credentials,omitempty"` // Named credential set (optional)
-	KVVersion       string        `yaml:"kvVersion"`
-	RefreshInterval time.Duration `yaml:"refreshInterval"`
-	Template        Template      `yaml:"template"`
-	Files           []File        `yaml:"files"`
+	Name              string        `yaml:"name"`
+	Key               string        `yaml:"key"`
+	Type              string        `yaml:"type,omitempty"`       // kv (default) or database: dynamic credentials of the role named by key
+	ObjectType        string        `yaml:"objectType,omitempty"` // Azure Key Vault: secret (default), key or certificate
+	MountPath         string        `yaml:"mountPath"`
+	Namespace         string        `yaml:"namespace,omitempty"`   // OpenBao namespace override (optional)
+	Credentials       string        `yaml:"credentials,omitempty"` // Named credential set (optional)
+	KVVersion         string        `yaml:"kvVersion"`
+	Version           int           `yaml:"version,omitempty"` // KV v2 version to pin, latest if unset (optional)
+	RefreshInterval   time.Duration `yaml:"refreshInterval"`
+	RefreshJitter     string        `yaml:"refreshJitter,omitempty"`     // Random share each refresh is moved by, e.g. 10%, overrides REFRESH_JITTER (optional)
+	SyncTimeout       time.Duration `yaml:"syncTimeout,omitempty"`       // Deadline for one sync, overrides SYNC_TIMEOUT (optional)
+	Retry             *Retry        `yaml:"retry,omitempty"`             // Retries of Vault reads within one sync, overrides secretStore.retry (optional)
+	OnFailure         *OnFailure    `yaml:"onFailure,omitempty"`         // Retries and alerting after failed syncs (optional)
+	Critical          bool          `yaml:"critical,omitempty"`          // The service is not ready until this secret synced (optional)
+	CleanupOnShutdown string        `yaml:"cleanupOnShutdown,omitempty"` // keep (default), remove or shred the files on exit (optional)
+	RequireTmpfs      bool          `yaml:"requireTmpfs,omitempty"`      // Refuse to write files outside tmpfs or ramfs (optional)
+	ChecksumFile      string        `yaml:"checksumFile,omitempty"`      // File listing the SHA-256 of every file, in sha256sum format (optional)
+	Template          Template      `yaml:"template"`
+	Files             []File        `yaml:"files"`
+	Directory         *Directory    `yaml:"directory,omitempty"` // Target of a wildcard key, instead of files
+	Sources           []Source      `yaml:"sources,omitempty"`   // KV secrets rendered together, instead of key (optional)
+}
+
+// Source is one of several KV secrets a secret's templates are rendered
+// from. Its fields are found below its name in the template context, e.g.
+// {{ .db.password }}. Namespace and credentials are the secret's.
+type Source struct {
+	Name      string `yaml:"name"`
+	Key       string `yaml:"key"`
+	MountPath string `yaml:"mountPath"`
+	KVVersion string `yaml:"kvVersion"`
+	Version   int    `yaml:"version,omitempty"` // KV v2 version to pin, latest if unset (optional)
+}
+
+// OnFailure defines how failed syncs of a secret are retried before its next
+// refresh, and when they raise an alert. Unset fields use the environment
+// defaults.
+type OnFailure struct {
+	MaxRetries *int `yaml:"maxRetries,omitempty"` // Retries with backoff after a failure, overrides FAILURE_RETRIES; 0 waits for the next refresh
+	AlertAfter *int `yaml:"alertAfter,omitempty"` // Failed syncs in a row that raise an alert, overrides FAILURE_ALERT_AFTER; 0 never alerts
+}
+
+// Retry defines how a read failing on network trouble is retried within one
+// sync. Unset fields fall back to secretStore.retry, then to the environment
+// defaults.
+type Retry struct {
+	InitialBackoff time.Duration `yaml:"initialBackoff,omitempty"` // Delay before the first retry, overrides INITIAL_BACKOFF
+	MaxBackoff     time.Duration `yaml:"maxBackoff,omitempty"`     // Longest delay between retries, overrides MAX_BACKOFF
+	Multiplier     float64       `yaml:"multiplier,omitempty"`     // Growth of the delay per retry, overrides BACKOFF_MULTIPLIER
+	MaxRetries     *int          `yaml:"maxRetries,omitempty"`     // Retries after the first attempt, overrides MAX_RETRIES; 0 never retries
+}
+
+// Connection tunes the HTTP connections to Vault; zero fields are unset
+type Connection struct {
+	Timeout      time.Duration `yaml:"timeout,omitempty"`      // Deadline of one request, overrides VAULT_CLIENT_TIMEOUT
+	DialTimeout  time.Duration `yaml:"dialTimeout,omitempty"`  // Deadline of a TCP connect, overrides VAULT_DIAL_TIMEOUT
+	KeepAlive    time.Duration `yaml:"keepAlive,omitempty"`    // Interval of TCP keepalive probes, overrides VAULT_KEEPALIVE
+	MaxIdleConns int           `yaml:"maxIdleConns,omitempty"` // Idle connections kept open, overrides VAULT_MAX_IDLE_CONNS
+	Proxy        string        `yaml:"proxy,omitempty"`        // HTTP(S) proxy URL, overrides VAULT_HTTP_PROXY
+	RateLimit    float64       `yaml:"rateLimit,omitempty"`    // Requests per second of all clients together, overrides VAULT_RATE_LIMIT
+	RateBurst    int           `yaml:"rateBurst,omitempty"`    // Requests allowed at once, overrides VAULT_RATE_BURST
+}
+
+// Directory defines where the secrets matched by a wildcard key are written:
+// one subdirectory per secret, holding one file per field, or one file per
+// template if template.data is set
+type Directory struct {
+	Path           string `yaml:"path"`
+	Mode           string `yaml:"mode"` // Mode of every file written
+	Owner          string `yaml:"owner"`
+	Group          string `yaml:"group"`
+	DirMode        string `yaml:"dirMode,omitempty"` // Mode of the directories created
+	DirOwner       string `yaml:"dirOwner,omitempty"`
+	DirGroup       string `yaml:"dirGroup,omitempty"`
+	SELinuxContext string `yaml:"seLinuxContext,omitempty"` // Label of the files and directories
+	Checksum       bool   `yaml:"checksum,omitempty"`       // Write a <file>.sha256 companion for every file
 }
 
 // Template defines how to map secret fields to file content
 type Template struct {
 	Data map[string]string `yaml:"data"`
+	File string            `yaml:"file,omitempty"` // Template read from a file, found in data by its base name (optional)
 }
 
 // File defines output file configuration
 type File struct {
-	Path  string `yaml:"path"`
-	Mode  string `yaml:"mode"`
-	Owner string `yaml:"owner"`
-	Group string `yaml:"group"`
+	Path           string    `yaml:"path"`
+	Template       string    `yaml:"template,omitempty"`   // template.data key rendered into this file
+	Format         string    `yaml:"format,omitempty"`     // json or env: write the secret's fields; pkcs12 or jks: write a keystore; concat: join templates
+	Keys           []string  `yaml:"keys,omitempty"`       // Fields written with json or env, all if unset
+	Keystore       *Keystore `yaml:"keystore,omitempty"`   // Templates assembled by pkcs12 or jks
+	Concat         *Concat   `yaml:"concat,omitempty"`     // Templates joined by concat
+	Copies         []string  `yaml:"copies,omitempty"`     // Further paths the same content is written to
+	CertExpiry     bool      `yaml:"certExpiry,omitempty"` // Export the expiry of the PEM certificates in the file as a metric
+	Mode           string    `yaml:"mode"`
+	Owner          string    `yaml:"owner"`
+	Group          string    `yaml:"group"`
+	DirMode        string    `yaml:"dirMode,omitempty"`        // Mode of the parent directory, applied when set
+	DirOwner       string    `yaml:"dirOwner,omitempty"`       // Owner of the parent directory, applied when set
+	DirGroup       string    `yaml:"dirGroup,omitempty"`       // Group of the parent directory, applied when set
+	SELinuxContext string    `yaml:"seLinuxContext,omitempty"` // SELinux label of the file and the directories created
+	KeepBackups    int       `yaml:"keepBackups,omitempty"`    // Previous versions kept as <path>.bak.1..N
+	Checksum       bool      `yaml:"checksum,omitempty"`       // Write the SHA-256 of the content to <path>.sha256
+}
+
+// ChecksumSuffix is appended to the path of a file for its checksum companion
+const ChecksumSuffix = ".sha256"
+
+// ChecksumPath returns the path of the checksum companion of a file, empty
+// if it has none
+func (f File) ChecksumPath() string {
+	if !f.Checksum {
+		return ""
+	}
+	return f.Path + ChecksumSuffix
+}
+
+// Keystore names the templates a pkcs12 or jks file is assembled from
+type Keystore struct {
+	Certificate string `yaml:"certificate"`     // PEM certificate, optionally followed by its chain
+	PrivateKey  string `yaml:"privateKey"`      // PEM private key
+	Chain       string `yaml:"chain,omitempty"` // PEM intermediate and CA certificates (optional)
+	Password    string `yaml:"password"`        // Keystore password
+	Alias       string `yaml:"alias,omitempty"` // Entry name with jks (default: secret name)
+}
+
+// Templates returns the template names the keystore uses
+func (k *Keystore) Templates() []string {
+	names := []string{k.Certificate, k.PrivateKey, k.Password}
+	if k.Chain != "" {
+		names = append(names, k.Chain)
+	}
+	return names
 }
 
-// ResolveNamespace returns the effective namespace for a secret
-// Per-secret namespace takes precedence over global namespace
+// Concat names the templates a concat file is joined from, in order
+type Concat struct {
+	Templates []string `yaml:"templates"`
+	Separator *string  `yaml:"separator,omitempty"` // Written between parts; unset ends each part with a newline
+}
+
+// IsWildcard reports whether the key ends in /* (every secret directly below
+// the path) or /** (every secret below the path, recursively)
+func (s *Secret) IsWildcard() bool {
+	_, _, ok := s.WildcardPrefix()
+	return ok
+}
+
+// WildcardPrefix returns the path listed for a wildcard key, ending in a slash
+// or empty for the whole mount, and whether it is listed recursively
+func (s *Secret) WildcardPrefix() (prefix string, recursive bool, ok bool) {
+	switch {
+	case s.Key == "**":
+		return "", true, true
+	case s.Key == "*":
+		return "", false, true
+	case strings.HasSuffix(s.Key, "/**"):
+		return strings.TrimSuffix(s.Key, "**"), true, true
+	case strings.HasSuffix(s.Key, "/*"):
+		return strings.TrimSuffix(s.Key, "*"), false, true
+	}
+	return "", false, false
+}
+
+// UsesImplicitTemplates reports whether files are bound to templates by
+// position (deprecated) rather than by their template field. A single
+// template with a single file is unambiguous and does not count.
+func (s *Secret) UsesImplicitTemplates() bool {
+	templated := 0
+	implicit := false
+	for _, file := range s.Files {
+		if file.Format != "" {
+			continue
+		}
+		templated++
+		if file.Template == "" {
+			implicit = true
+		}
+	}
+	return implicit && !(templated == 1 && len(s.Template.Data) == 1)
+}
+
+// FileTemplates returns the template name rendered into each file, in file order.
+// Files without a template field fall back to the deprecated positional
+// binding: the n-th file gets the n-th template name in sorted order. Files
+// with a format have no template.
+func (s *Secret) FileTemplates() []string {
+	names := make([]string, 0, len(s.Template.Data))
+	for name := range s.Template.Data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	templates := make([]string, len(s.Files))
+	position := 0
+	for i, file := range s.Files {
+		if file.Format != "" {
+			continue
+		}
+		switch {
+		case file.Template != "":
+			templates[i] = file.Template
+		case position < len(names):
+			templates[i] = names[position]
+		}
+		position++
+	}
+	return templates
+}
+
+// OutputPaths returns the path and copies of every file, in file order
+func (s *Secret) OutputPaths() []string {
+	paths := make([]string, 0, len(s.Files))
+	for _, file := range s.Files {
+		paths = append(paths, file.Path)
+		paths = append(paths, file.Copies...)
+	}
+	return paths
+}
+
+// ChecksumPaths returns the checksum files written for a secret: the
+// companion of every file and copy with checksum set, then checksumFile
+func (s *Secret) ChecksumPaths() []string {
+	var paths []string
+	for _, file := range s.Files {
+		if !file.Checksum {
+			continue
+		}
+		paths = append(paths, file.ChecksumPath())
+		for _, path := range file.Copies {
+			paths = append(paths, path+ChecksumSuffix)
+		}
+	}
+	if s.ChecksumFile != "" {
+		paths = append(paths, s.ChecksumFile)
+	}
+	return paths
+}
+
+// expandCopies replaces the copies of each file by files of their own
+// placed after it, bound to the same template. Positional template binding
+// is pinned first, as the added files would shift it.
+func (s *Secret) expandCopies() {
+	if !slices.ContainsFunc(s.Files, func(f File) bool { return len(f.Copies) > 0 }) {
+		return
+	}
+
+	templates := s.FileTemplates()
+	files := make([]File, 0, len(s.OutputPaths()))
+	for i, file := range s.Files {
+		file.Template = templates[i]
+		copies := file.Copies
+		file.Copies = nil
+		files = append(files, file)
+		for _, path := range copies {
+			file.Path = path
+			files = append(files, file)
+		}
+	}
+	s.Files = files
+}
+
+// MaxRefreshJitter is the largest share of the refresh interval a refresh
+// may be moved by
+const MaxRefreshJitter = 50
+
+// ParseJitter parses a refresh jitter given as a percentage such as 10% and
+// returns it as a fraction of the refresh interval; empty means none
+func ParseJitter(s string) (float64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+	if err != nil || percent < 0 || percent > MaxRefreshJitter {
+		return 0, fmt.Errorf("jitter must be a percentage between 0%% and %d%%, got: %s", MaxRefreshJitter, s)
+	}
+	return percent / 100, nil
+}
+
+// ForSource returns the secret reading one of its sources in place of its
+// key
+func (s *Secret) ForSource(source Source) Secret {
+	secret := *s
+	secret.Key = source.Key
+	secret.MountPath = source.MountPath
+	secret.KVVersion = source.KVVersion
+	secret.Version = source.Version
+	secret.Sources = nil
+	return secret
+}
+
+// IsDynamic reports whether a secret holds leased credentials that are
+// renewed and replaced before they expire
+func (s *Secret) IsDynamic() bool {
+	return s.Type == SecretTypeDatabase
+}
+
+// RootNamespace as the namespace of a secret reads it from the root
+// namespace when secretStore.namespace is set
+const RootNamespace = "/"
+
+// ResolveNamespace returns the effective namespace for a secret, empty for
+// the root namespace. Per-secret namespace takes precedence over global namespace
 func (s *Secret) ResolveNamespace(globalNamespace string) string {
-	if s.Namespace != "" {
+	switch s.Namespace {
+	case "":
+		return globalNamespace
+	case RootNamespace:
+		return ""
+	default:
 		return s.Namespace
 	}
-	return globalNamespace
 }
 
 // ResolveCredentials returns the effective credentials for a secret
@@ -84,9 +507,37 @@ func (ss *SecretStore) GetDefaultCredentials() CredentialSet {
 		Token:      ss.Token,
 		RoleID:     ss.RoleID,
 		SecretID:   ss.SecretID,
+		TokenFile:  ss.TokenFile,
+
+		SecretIDFile:    ss.SecretIDFile,
+		SecretIDWrapped: ss.SecretIDWrapped,
+
+		KubernetesRole:      ss.KubernetesRole,
+		KubernetesTokenPath: ss.KubernetesTokenPath,
+		KubernetesMountPath: ss.KubernetesMountPath,
+
+		CertRole:      ss.CertRole,
+		CertMountPath: ss.CertMountPath,
+
+		AuthNamespace: ss.AuthNamespace,
+
+		TenantID:     ss.TenantID,
+		ClientID:     ss.ClientID,
+		ClientSecret: ss.ClientSecret,
 	}
 }
 
+// IsAzureKeyVault reports whether secrets are read from Azure Key Vault
+func (ss *SecretStore) IsAzureKeyVault() bool {
+	return ss.Type == StoreTypeAzureKeyVault
+}
+
+// Endpoints returns the Vault addresses in the order they are tried:
+// address, then the failover addresses
+func (ss *SecretStore) Endpoints() []string {
+	return append([]string{ss.Address}, ss.Addresses...)
+}
+
 // GetCredentials returns credentials by name, or default if name is empty
 func (ss *SecretStore) GetCredentials(name string) (CredentialSet, bool) {
 	if name == "" {
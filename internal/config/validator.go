@@ -5,42 +5,168 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/ohauer/secrets-sync/internal/filewriter"
 )
 
 // Validate checks if the configuration is valid
 func Validate(cfg *Config) error {
-	if err := validateSecretStore(&cfg.SecretStore); err != nil {
-		return fmt.Errorf("secretStore: %w", err)
+	var first error
+	validate(cfg, func(err error) bool {
+		first = err
+		return false
+	})
+	return first
+}
+
+// validate checks cfg and passes every problem found to report, stopping
+// once report returns false
+func validate(cfg *Config, report func(error) bool) {
+	if err := validateSecretStore(&cfg.SecretStore); err != nil && !report(fmt.Errorf("secretStore: %w", err)) {
+		return
+	}
+
+	if err := validateSecretDefaults(&cfg.SecretStore, &cfg.SecretDefaults); err != nil && !report(fmt.Errorf("secretDefaults: %w", err)) {
+		return
 	}
+	applySecretDefaults(cfg)
 
-	if len(cfg.Secrets) == 0 {
-		return fmt.Errorf("at least one secret must be defined")
+	if len(cfg.Secrets) == 0 && !report(fmt.Errorf("at least one secret must be defined")) {
+		return
 	}
 
 	// Limit maximum number of secrets to prevent resource exhaustion
-	if len(cfg.Secrets) > 100 {
-		return fmt.Errorf("too many secrets defined (%d), maximum is 100", len(cfg.Secrets))
+	if err := validateMaxSecrets(cfg); err != nil && !report(err) {
+		return
+	}
+
+	switch cfg.StartupMode {
+	case "", StartupModeExit, StartupModeWait:
+	default:
+		if !report(fmt.Errorf("startupMode must be exit or wait, got: %s", cfg.StartupMode)) {
+			return
+		}
 	}
 
 	// Check for duplicate file paths
-	if err := validateNoDuplicatePaths(cfg.Secrets); err != nil {
-		return err
+	if err := validateNoDuplicatePaths(cfg.Secrets); err != nil && !report(err) {
+		return
 	}
 
-	for i, secret := range cfg.Secrets {
-		if err := validateSecret(&cfg.SecretStore, &secret); err != nil {
-			return fmt.Errorf("secrets[%d]: %w", i, err)
+	allowedErr := validateAllowedOutputPaths(cfg.AllowedOutputPaths)
+	if allowedErr != nil && !report(allowedErr) {
+		return
+	}
+
+	names := make(map[string]bool, len(cfg.Secrets))
+	for i := range cfg.Secrets {
+		secret := &cfg.Secrets[i]
+		if err := validateSecret(&cfg.SecretStore, secret); err != nil && !report(fmt.Errorf("secrets[%d]: %w", i, err)) {
+			return
 		}
+		if allowedErr == nil {
+			if err := validateOutputPaths(secret, cfg.AllowedOutputPaths); err != nil && !report(fmt.Errorf("secrets[%d]: %w", i, err)) {
+				return
+			}
+		}
+		if secret.Name != "" && names[secret.Name] && !report(fmt.Errorf("secrets[%d]: duplicate name %q", i, secret.Name)) {
+			return
+		}
+		names[secret.Name] = true
 	}
 
+	if err := validateNotifications(&cfg.Notifications); err != nil {
+		report(fmt.Errorf("notifications: %w", err))
+	}
+}
+
+// validateAllowedOutputPaths checks the allowlist of output directories and
+// cleans its entries
+func validateAllowedOutputPaths(dirs []string) error {
+	for i, dir := range dirs {
+		if !filepath.IsAbs(dir) {
+			return fmt.Errorf("allowedOutputPaths[%d] must be an absolute path, got: %q", i, dir)
+		}
+		if strings.Contains(dir, "..") {
+			return fmt.Errorf("allowedOutputPaths[%d] contains '..' which is not allowed", i)
+		}
+		dirs[i] = filepath.Clean(dir)
+	}
+	return nil
+}
+
+// validateOutputPaths checks that every file of a secret, and the directory
+// of a wildcard secret, lies in one of the allowed directories
+func validateOutputPaths(secret *Secret, allowed []string) error {
+	if len(allowed) == 0 {
+		return nil
+	}
+	if secret.Directory != nil && !filewriter.PathAllowed(secret.Directory.Path, allowed) {
+		return fmt.Errorf("directory: path %s is outside allowedOutputPaths", secret.Directory.Path)
+	}
+	for i, file := range secret.Files {
+		for _, path := range append([]string{file.Path}, file.Copies...) {
+			if !filewriter.PathAllowed(path, allowed) {
+				return fmt.Errorf("files[%d]: path %s is outside allowedOutputPaths", i, path)
+			}
+		}
+	}
+	if secret.ChecksumFile != "" && !filewriter.PathAllowed(secret.ChecksumFile, allowed) {
+		return fmt.Errorf("checksumFile: path %s is outside allowedOutputPaths", secret.ChecksumFile)
+	}
+	return nil
+}
+
+// validateNotifications checks the webhooks and defaults the expiry warning
+func validateNotifications(n *Notifications) error {
+	if n.ExpiryWarning < 0 {
+		return fmt.Errorf("expiryWarning must not be negative")
+	}
+	if n.ExpiryWarning == 0 {
+		n.ExpiryWarning = DefaultExpiryWarning
+	}
+
+	for i, webhook := range n.Webhooks {
+		u, err := url.Parse(webhook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhooks[%d]: url must be an http or https URL", i)
+		}
+		switch webhook.Format {
+		case "", WebhookFormatJSON, WebhookFormatSlack:
+		default:
+			return fmt.Errorf("webhooks[%d]: unsupported format: %s (supported: json, slack)", i, webhook.Format)
+		}
+		for _, event := range webhook.Events {
+			switch event {
+			case EventFailure, EventRecovery, EventExpiry:
+			default:
+				return fmt.Errorf("webhooks[%d]: unsupported event: %s (supported: failure, recovery, expiry)", i, event)
+			}
+		}
+	}
 	return nil
 }
 
 func validateSecretStore(store *SecretStore) error {
+	if err := validateRetry(store.Retry); err != nil {
+		return err
+	}
+	if err := validateConnection(store.Connection); err != nil {
+		return err
+	}
+
+	switch store.Type {
+	case "", StoreTypeVault:
+	case StoreTypeAzureKeyVault:
+		return validateAzureStore(store)
+	default:
+		return fmt.Errorf("unsupported type: %s (supported: vault, azureKeyVault)", store.Type)
+	}
+
 	if store.Address == "" {
 		return fmt.Errorf("address is required")
 	}
@@ -49,6 +175,23 @@ func validateSecretStore(store *SecretStore) error {
 	if err := validateVaultAddress(store.Address); err != nil {
 		return err
 	}
+	seen := map[string]bool{store.Address: true}
+	for i, address := range store.Addresses {
+		if err := validateVaultAddress(address); err != nil {
+			return fmt.Errorf("addresses[%d]: %w", i, err)
+		}
+		if seen[address] {
+			return fmt.Errorf("addresses[%d]: duplicate address: %s", i, address)
+		}
+		seen[address] = true
+	}
+
+	if err := validateNamespace("namespace", store.Namespace); err != nil {
+		return err
+	}
+	if err := validateNamespace("authNamespace", store.AuthNamespace); err != nil {
+		return err
+	}
 
 	if store.AuthMethod == "" {
 		return fmt.Errorf("authMethod is required")
@@ -59,15 +202,30 @@ func validateSecretStore(store *SecretStore) error {
 		if store.Token == "" {
 			return fmt.Errorf("token is required for token auth")
 		}
+	case "tokenFile":
+		if err := validateTokenFile(store.TokenFile); err != nil {
+			return err
+		}
 	case "approle":
 		if store.RoleID == "" {
 			return fmt.Errorf("roleId is required for approle auth")
 		}
-		if store.SecretID == "" {
-			return fmt.Errorf("secretId is required for approle auth")
+		if err := validateSecretID(store.SecretID, store.SecretIDFile); err != nil {
+			return err
+		}
+	case "kubernetes":
+		if store.KubernetesRole == "" {
+			return fmt.Errorf("kubernetesRole is required for kubernetes auth")
+		}
+		if err := validateKubernetesPaths(store.KubernetesTokenPath, store.KubernetesMountPath); err != nil {
+			return err
+		}
+	case "cert":
+		if strings.Trim(store.CertMountPath, "/") != store.CertMountPath {
+			return fmt.Errorf("certMountPath must not start or end with a slash: %s", store.CertMountPath)
 		}
 	default:
-		return fmt.Errorf("unsupported authMethod: %s (supported: token, approle)", store.AuthMethod)
+		return fmt.Errorf("unsupported authMethod: %s (supported: token, tokenFile, approle, kubernetes, cert)", store.AuthMethod)
 	}
 
 	// Validate credential sets
@@ -115,6 +273,26 @@ func validateSecretStore(store *SecretStore) error {
 	return nil
 }
 
+// validateNamespace checks an OpenBao namespace path such as team-a or
+// tenant/team-a; empty is the root namespace
+func validateNamespace(field, namespace string) error {
+	if namespace == "" {
+		return nil
+	}
+	if strings.IndexFunc(namespace, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0 {
+		return fmt.Errorf("%s must not contain whitespace or control characters, got: %q", field, namespace)
+	}
+	if strings.HasPrefix(namespace, "/") {
+		return fmt.Errorf("%s must not start with a slash, got: %s", field, namespace)
+	}
+	for _, name := range strings.Split(strings.TrimSuffix(namespace, "/"), "/") {
+		if name == "" || name == "." || name == ".." {
+			return fmt.Errorf("%s must be a path of namespace names separated by single slashes, got: %s", field, namespace)
+		}
+	}
+	return nil
+}
+
 // validateCredentialSet validates a named credential set
 func validateCredentialSet(name string, creds CredentialSet) error {
 	if name == "" {
@@ -125,22 +303,234 @@ func validateCredentialSet(name string, creds CredentialSet) error {
 		return fmt.Errorf("authMethod is required")
 	}
 
+	if err := validateNamespace("authNamespace", creds.AuthNamespace); err != nil {
+		return err
+	}
+
 	switch creds.AuthMethod {
 	case "token":
 		if creds.Token == "" {
 			return fmt.Errorf("token is required for token auth")
 		}
+	case "tokenFile":
+		if err := validateTokenFile(creds.TokenFile); err != nil {
+			return err
+		}
 	case "approle":
 		if creds.RoleID == "" {
 			return fmt.Errorf("roleId is required for approle auth")
 		}
-		if creds.SecretID == "" {
-			return fmt.Errorf("secretId is required for approle auth")
+		if err := validateSecretID(creds.SecretID, creds.SecretIDFile); err != nil {
+			return err
+		}
+	case "kubernetes":
+		if creds.KubernetesRole == "" {
+			return fmt.Errorf("kubernetesRole is required for kubernetes auth")
+		}
+		if err := validateKubernetesPaths(creds.KubernetesTokenPath, creds.KubernetesMountPath); err != nil {
+			return err
+		}
+	case "cert":
+		if strings.Trim(creds.CertMountPath, "/") != creds.CertMountPath {
+			return fmt.Errorf("certMountPath must not start or end with a slash: %s", creds.CertMountPath)
+		}
+	default:
+		return fmt.Errorf("unsupported authMethod: %s (supported: token, tokenFile, approle, kubernetes, cert)", creds.AuthMethod)
+	}
+
+	return nil
+}
+
+// validateAzureStore checks an Azure Key Vault store, whose address is the
+// vault URL, e.g. https://myvault.vault.azure.net
+func validateAzureStore(store *SecretStore) error {
+	if store.Address == "" {
+		return fmt.Errorf("address is required")
+	}
+	if err := validateVaultAddress(store.Address); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(store.Address, "https://") {
+		return fmt.Errorf("address must use https with type azureKeyVault")
+	}
+	if store.Namespace != "" {
+		return fmt.Errorf("namespace cannot be used with type azureKeyVault")
+	}
+	if len(store.Addresses) > 0 {
+		return fmt.Errorf("addresses cannot be used with type azureKeyVault")
+	}
+	if store.Connection != nil {
+		return fmt.Errorf("connection cannot be used with type azureKeyVault")
+	}
+	if store.TLSSkipVerify || store.TLSCACert != "" || store.TLSCAPath != "" || store.TLSClientCert != "" || store.TLSClientKey != "" {
+		return fmt.Errorf("tls settings cannot be used with type azureKeyVault")
+	}
+
+	if err := validateAzureCredentials(store.GetDefaultCredentials()); err != nil {
+		return err
+	}
+	for name, creds := range store.Credentials {
+		if name == "" {
+			return fmt.Errorf("credentials: credential set name cannot be empty")
+		}
+		if err := validateAzureCredentials(creds); err != nil {
+			return fmt.Errorf("credentials[%s]: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// validateAzureCredentials checks credentials for Azure Key Vault
+func validateAzureCredentials(creds CredentialSet) error {
+	if creds.AuthNamespace != "" {
+		return fmt.Errorf("authNamespace cannot be used with type azureKeyVault")
+	}
+	switch creds.AuthMethod {
+	case "":
+		return fmt.Errorf("authMethod is required")
+	case "managedIdentity":
+		if creds.ClientSecret != "" {
+			return fmt.Errorf("clientSecret cannot be used with managedIdentity auth")
+		}
+	case "clientSecret":
+		if creds.TenantID == "" {
+			return fmt.Errorf("tenantId is required for clientSecret auth")
+		}
+		if creds.ClientID == "" {
+			return fmt.Errorf("clientId is required for clientSecret auth")
+		}
+		if creds.ClientSecret == "" {
+			return fmt.Errorf("clientSecret is required for clientSecret auth")
 		}
 	default:
-		return fmt.Errorf("unsupported authMethod: %s (supported: token, approle)", creds.AuthMethod)
+		return fmt.Errorf("unsupported authMethod: %s (supported: managedIdentity, clientSecret)", creds.AuthMethod)
 	}
+	return nil
+}
+
+// azureObjectName matches the names of Azure Key Vault secrets, keys and certificates
+var azureObjectName = regexp.MustCompile(`^[0-9A-Za-z-]{1,127}$`)
+
+// validateAzureSecret checks a secret read from Azure Key Vault, whose key
+// is the name of a secret, key or certificate
+func validateAzureSecret(secret *Secret) error {
+	if !azureObjectName.MatchString(secret.Key) {
+		return fmt.Errorf("key must be a Key Vault object name of up to 127 letters, digits and dashes, got: %s", secret.Key)
+	}
+	switch secret.ObjectType {
+	case "", "secret", "key", "certificate":
+	default:
+		return fmt.Errorf("objectType must be secret, key or certificate, got: %s", secret.ObjectType)
+	}
+	if secret.MountPath != "" || secret.KVVersion != "" || secret.Namespace != "" || secret.Version != 0 || secret.Type != "" {
+		return fmt.Errorf("mountPath, kvVersion, namespace, version and type cannot be used with secretStore type azureKeyVault")
+	}
+	if secret.Directory != nil {
+		return fmt.Errorf("directory cannot be used with secretStore type azureKeyVault")
+	}
+	return nil
+}
+
+// validateRetry checks a retry block; zero fields are unset
+func validateRetry(r *Retry) error {
+	if r == nil {
+		return nil
+	}
+	switch {
+	case r.InitialBackoff < 0:
+		return fmt.Errorf("retry.initialBackoff must not be negative")
+	case r.MaxBackoff < 0:
+		return fmt.Errorf("retry.maxBackoff must not be negative")
+	case r.InitialBackoff > 0 && r.MaxBackoff > 0 && r.InitialBackoff > r.MaxBackoff:
+		return fmt.Errorf("retry.initialBackoff must not exceed retry.maxBackoff, got: %s > %s", r.InitialBackoff, r.MaxBackoff)
+	case r.Multiplier != 0 && r.Multiplier < 1:
+		return fmt.Errorf("retry.multiplier must be at least 1, got: %g", r.Multiplier)
+	case r.MaxRetries != nil && *r.MaxRetries < 0:
+		return fmt.Errorf("retry.maxRetries must not be negative")
+	}
+	return nil
+}
 
+// validateConnection checks a connection block; zero fields are unset
+func validateConnection(c *Connection) error {
+	if c == nil {
+		return nil
+	}
+	switch {
+	case c.Timeout < 0:
+		return fmt.Errorf("connection.timeout must not be negative")
+	case c.DialTimeout < 0:
+		return fmt.Errorf("connection.dialTimeout must not be negative")
+	case c.KeepAlive < 0:
+		return fmt.Errorf("connection.keepAlive must not be negative")
+	case c.MaxIdleConns < 0:
+		return fmt.Errorf("connection.maxIdleConns must not be negative")
+	case c.RateLimit < 0:
+		return fmt.Errorf("connection.rateLimit must not be negative")
+	case c.RateBurst < 0:
+		return fmt.Errorf("connection.rateBurst must not be negative")
+	}
+	if c.Proxy != "" {
+		if err := ValidateProxyURL(c.Proxy); err != nil {
+			return fmt.Errorf("connection.proxy %w", err)
+		}
+	}
+	return nil
+}
+
+// ValidateProxyURL checks the URL of an HTTP, HTTPS or SOCKS5 proxy
+func ValidateProxyURL(proxy string) error {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return fmt.Errorf("is not a valid URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return fmt.Errorf("must use http, https or socks5, got: %s", proxy)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("must include a host, got: %s", proxy)
+	}
+	return nil
+}
+
+// validateTokenFile checks the token file of tokenFile auth. The file is read
+// at login, so it may not exist yet.
+func validateTokenFile(tokenFile string) error {
+	switch {
+	case tokenFile == "":
+		return fmt.Errorf("tokenFile is required for tokenFile auth")
+	case !filepath.IsAbs(tokenFile):
+		return fmt.Errorf("tokenFile must be absolute: %s", tokenFile)
+	}
+	return nil
+}
+
+// validateSecretID checks that an AppRole secret ID is given either inline or
+// as a file. The file is read at login, so it may not exist yet.
+func validateSecretID(secretID, secretIDFile string) error {
+	switch {
+	case secretID == "" && secretIDFile == "":
+		return fmt.Errorf("secretId or secretIdFile is required for approle auth")
+	case secretID != "" && secretIDFile != "":
+		return fmt.Errorf("secretId and secretIdFile are mutually exclusive")
+	case secretIDFile != "" && !filepath.IsAbs(secretIDFile):
+		return fmt.Errorf("secretIdFile must be absolute: %s", secretIDFile)
+	}
+	return nil
+}
+
+// validateKubernetesPaths checks the optional service account token path and
+// auth mount of kubernetes auth. The token file is read at login, so it may
+// not exist yet when the config is validated outside the pod.
+func validateKubernetesPaths(tokenPath, mountPath string) error {
+	if tokenPath != "" && !filepath.IsAbs(tokenPath) {
+		return fmt.Errorf("kubernetesTokenPath must be absolute: %s", tokenPath)
+	}
+	if strings.Trim(mountPath, "/") != mountPath {
+		return fmt.Errorf("kubernetesMountPath must not start or end with a slash: %s", mountPath)
+	}
 	return nil
 }
 
@@ -171,12 +561,14 @@ func validateSecret(store *SecretStore, secret *Secret) error {
 		return fmt.Errorf("name is required")
 	}
 
-	if secret.Key == "" {
+	if secret.Key == "" && len(secret.Sources) == 0 {
 		return fmt.Errorf("key is required")
 	}
 
-	if secret.MountPath == "" {
-		return fmt.Errorf("mountPath is required")
+	if secret.Namespace != RootNamespace {
+		if err := validateNamespace("namespace", secret.Namespace); err != nil {
+			return err
+		}
 	}
 
 	// Validate credential reference if specified
@@ -186,12 +578,19 @@ func validateSecret(store *SecretStore, secret *Secret) error {
 		}
 	}
 
-	if secret.KVVersion == "" {
-		return fmt.Errorf("kvVersion is required")
-	}
-
-	if secret.KVVersion != "v1" && secret.KVVersion != "v2" {
-		return fmt.Errorf("kvVersion must be v1 or v2, got: %s", secret.KVVersion)
+	switch {
+	case len(secret.Sources) > 0:
+		if err := validateSources(store, secret); err != nil {
+			return err
+		}
+	case store.IsAzureKeyVault():
+		if err := validateAzureSecret(secret); err != nil {
+			return err
+		}
+	default:
+		if err := validateVaultSecret(secret); err != nil {
+			return err
+		}
 	}
 
 	if secret.RefreshInterval <= 0 {
@@ -203,16 +602,48 @@ func validateSecret(store *SecretStore, secret *Secret) error {
 		return fmt.Errorf("refreshInterval must be at least 30s, got: %s", secret.RefreshInterval)
 	}
 
-	if len(secret.Template.Data) == 0 {
-		return fmt.Errorf("template.data must have at least one entry")
+	if secret.SyncTimeout < 0 {
+		return fmt.Errorf("syncTimeout must not be negative")
+	}
+
+	if _, err := ParseJitter(secret.RefreshJitter); err != nil {
+		return fmt.Errorf("refreshJitter: %w", err)
+	}
+
+	if err := validateRetry(secret.Retry); err != nil {
+		return err
+	}
+
+	if f := secret.OnFailure; f != nil {
+		if f.MaxRetries != nil && *f.MaxRetries < 0 {
+			return fmt.Errorf("onFailure.maxRetries must not be negative")
+		}
+		if f.AlertAfter != nil && *f.AlertAfter < 0 {
+			return fmt.Errorf("onFailure.alertAfter must not be negative")
+		}
+	}
+
+	if err := validateCleanup(secret.CleanupOnShutdown); err != nil {
+		return err
+	}
+
+	if secret.IsWildcard() || secret.Directory != nil {
+		if secret.ChecksumFile != "" {
+			return fmt.Errorf("checksumFile cannot be used with directory, set checksum on the directory instead")
+		}
+		return validateWildcard(secret)
+	}
+
+	if strings.Contains(secret.Key, "*") {
+		return fmt.Errorf("key may only contain * as a trailing /* or /** wildcard")
 	}
 
 	if len(secret.Files) == 0 {
 		return fmt.Errorf("files must have at least one entry")
 	}
 
-	if len(secret.Template.Data) != len(secret.Files) {
-		return fmt.Errorf("template.data and files must have the same number of entries")
+	if err := validateTemplateBinding(secret); err != nil {
+		return err
 	}
 
 	for i := range secret.Files {
@@ -221,6 +652,294 @@ func validateSecret(store *SecretStore, secret *Secret) error {
 		}
 	}
 
+	if secret.ChecksumFile != "" {
+		absPath, err := filepath.Abs(secret.ChecksumFile)
+		if err != nil {
+			return fmt.Errorf("checksumFile: failed to resolve path: %w", err)
+		}
+		secret.ChecksumFile = filepath.Clean(absPath)
+		if err := validateFilePath(secret.ChecksumFile); err != nil {
+			return fmt.Errorf("checksumFile: invalid path: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// validateVaultSecret checks where a secret is read from in Vault
+func validateVaultSecret(secret *Secret) error {
+	if secret.MountPath == "" {
+		return fmt.Errorf("mountPath is required")
+	}
+
+	switch secret.Type {
+	case "", SecretTypeKV:
+	case SecretTypeDatabase:
+		return validateDatabaseSecret(secret)
+	default:
+		return fmt.Errorf("type must be kv or database, got: %s", secret.Type)
+	}
+
+	if secret.KVVersion == "" {
+		return fmt.Errorf("kvVersion is required")
+	}
+
+	if secret.KVVersion != "v1" && secret.KVVersion != "v2" {
+		return fmt.Errorf("kvVersion must be v1 or v2, got: %s", secret.KVVersion)
+	}
+
+	if secret.Version < 0 {
+		return fmt.Errorf("version must not be negative")
+	}
+
+	if secret.Version > 0 && secret.KVVersion != "v2" {
+		return fmt.Errorf("version requires kvVersion v2")
+	}
+
+	if secret.ObjectType != "" {
+		return fmt.Errorf("objectType requires secretStore type azureKeyVault")
+	}
+
+	return nil
+}
+
+// validateSources checks the KV secrets a secret is rendered from, which
+// replace its own key
+func validateSources(store *SecretStore, secret *Secret) error {
+	if store.IsAzureKeyVault() {
+		return fmt.Errorf("sources cannot be used with secretStore type azureKeyVault")
+	}
+	if secret.Key != "" || secret.MountPath != "" || secret.KVVersion != "" || secret.Version != 0 {
+		return fmt.Errorf("key, mountPath, kvVersion and version cannot be used with sources")
+	}
+	if secret.Type != "" && secret.Type != SecretTypeKV {
+		return fmt.Errorf("sources cannot be used with type %s", secret.Type)
+	}
+	if secret.Directory != nil {
+		return fmt.Errorf("directory cannot be used with sources")
+	}
+	if secret.ObjectType != "" {
+		return fmt.Errorf("objectType requires secretStore type azureKeyVault")
+	}
+
+	names := make(map[string]bool, len(secret.Sources))
+	for i, source := range secret.Sources {
+		if err := validateSource(source); err != nil {
+			return fmt.Errorf("sources[%d]: %w", i, err)
+		}
+		if names[source.Name] {
+			return fmt.Errorf("sources[%d]: duplicate name %q", i, source.Name)
+		}
+		names[source.Name] = true
+	}
+	return nil
+}
+
+// sourceName matches the names of sources, which are used as fields in
+// templates
+var sourceName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateSource checks one KV secret of a secret with sources
+func validateSource(source Source) error {
+	if source.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if !sourceName.MatchString(source.Name) {
+		return fmt.Errorf("name must be letters, digits and _, not starting with a digit, got: %s", source.Name)
+	}
+	if source.Key == "" {
+		return fmt.Errorf("key is required")
+	}
+	if strings.Contains(source.Key, "*") {
+		return fmt.Errorf("key cannot be a wildcard in sources")
+	}
+	if source.MountPath == "" {
+		return fmt.Errorf("mountPath is required")
+	}
+	if source.KVVersion != "v1" && source.KVVersion != "v2" {
+		return fmt.Errorf("kvVersion must be v1 or v2, got: %s", source.KVVersion)
+	}
+	if source.Version < 0 {
+		return fmt.Errorf("version must not be negative")
+	}
+	if source.Version > 0 && source.KVVersion != "v2" {
+		return fmt.Errorf("version requires kvVersion v2")
+	}
+	return nil
+}
+
+// validateDatabaseSecret checks a secret read from the database secrets
+// engine, whose key is the name of a role
+func validateDatabaseSecret(secret *Secret) error {
+	if secret.KVVersion != "" || secret.Version != 0 {
+		return fmt.Errorf("kvVersion and version cannot be used with type database")
+	}
+	if secret.IsWildcard() || secret.Directory != nil || strings.Contains(secret.Key, "/") {
+		return fmt.Errorf("key must be a database role name with type database, got: %s", secret.Key)
+	}
+	if secret.ObjectType != "" {
+		return fmt.Errorf("objectType requires secretStore type azureKeyVault")
+	}
+	return nil
+}
+
+// validateWildcard checks a secret whose key matches many secrets. Its files
+// are derived per matched secret, so it sets a directory instead.
+func validateWildcard(secret *Secret) error {
+	if !secret.IsWildcard() {
+		return fmt.Errorf("directory requires a wildcard key ending in /* or /**")
+	}
+	prefix, _, _ := secret.WildcardPrefix()
+	if strings.Contains(prefix, "*") {
+		return fmt.Errorf("key may only contain * as a trailing /* or /** wildcard")
+	}
+	if secret.Directory == nil {
+		return fmt.Errorf("directory is required for wildcard key %q", secret.Key)
+	}
+	if len(secret.Files) > 0 {
+		return fmt.Errorf("files cannot be used with wildcard key %q, use directory", secret.Key)
+	}
+	if secret.Version > 0 {
+		return fmt.Errorf("version cannot be used with wildcard key %q", secret.Key)
+	}
+
+	// Validated like a file so relative paths resolve and modes are checked
+	d := secret.Directory
+	dir := File{Path: d.Path, Mode: d.Mode, Owner: d.Owner, Group: d.Group, DirMode: d.DirMode, DirOwner: d.DirOwner, DirGroup: d.DirGroup, SELinuxContext: d.SELinuxContext}
+	if err := validateFile(&dir); err != nil {
+		return fmt.Errorf("directory: %w", err)
+	}
+	secret.Directory.Path = dir.Path
+	secret.Directory.Mode = dir.Mode
+
+	for name := range secret.Template.Data {
+		if !filepath.IsLocal(name) || strings.ContainsRune(name, filepath.Separator) {
+			return fmt.Errorf("template.data[%s]: name must be a plain file name with wildcard keys", name)
+		}
+	}
+
+	return nil
+}
+
+// validateTemplateBinding checks that every file is bound to an existing
+// template and every template is written to at least one file
+func validateTemplateBinding(secret *Secret) error {
+	templated, explicit := 0, 0
+	used := make(map[string]bool, len(secret.Template.Data))
+	for i, file := range secret.Files {
+		if file.Format != "" {
+			if file.Template != "" {
+				return fmt.Errorf("files[%d]: template cannot be used with format", i)
+			}
+			// Missing names and formats are reported by validateFile
+			if file.Keystore != nil && filewriter.IsKeystoreFormat(file.Format) {
+				for _, name := range file.Keystore.Templates() {
+					if name == "" {
+						continue
+					}
+					if _, ok := secret.Template.Data[name]; !ok {
+						return fmt.Errorf("files[%d]: keystore template %q not found in template.data", i, name)
+					}
+					used[name] = true
+				}
+			}
+			if file.Concat != nil && file.Format == filewriter.FormatConcat {
+				for _, name := range file.Concat.Templates {
+					if _, ok := secret.Template.Data[name]; !ok {
+						return fmt.Errorf("files[%d]: concat template %q not found in template.data", i, name)
+					}
+					used[name] = true
+				}
+			}
+			continue
+		}
+		templated++
+		if file.Template != "" {
+			explicit++
+		}
+	}
+
+	// Files with a format only need no templates, unless they are keystores
+	// or concat files
+	if templated == 0 {
+		if len(used) == 0 && len(secret.Template.Data) > 0 {
+			return fmt.Errorf("template.data is not used by any file")
+		}
+		return unusedTemplate(secret, used)
+	}
+	if len(secret.Template.Data) == 0 {
+		return fmt.Errorf("template.data must have at least one entry")
+	}
+
+	// Deprecated positional binding: sorted template names map to files by index
+	if explicit == 0 {
+		if len(used) > 0 {
+			return fmt.Errorf("files must set template when a keystore or concat file is written")
+		}
+		if len(secret.Template.Data) != templated {
+			return fmt.Errorf("template.data and files must have the same number of entries")
+		}
+		return nil
+	}
+
+	if explicit != templated {
+		return fmt.Errorf("files must either all set template or none (positional binding is deprecated)")
+	}
+
+	for i, file := range secret.Files {
+		if file.Format != "" {
+			continue
+		}
+		if _, ok := secret.Template.Data[file.Template]; !ok {
+			return fmt.Errorf("files[%d]: template %q not found in template.data", i, file.Template)
+		}
+		used[file.Template] = true
+	}
+
+	return unusedTemplate(secret, used)
+}
+
+// unusedTemplate reports a template.data entry no file uses
+func unusedTemplate(secret *Secret, used map[string]bool) error {
+	for name := range secret.Template.Data {
+		if !used[name] {
+			return fmt.Errorf("template.data[%s] is not used by any file", name)
+		}
+	}
+	return nil
+}
+
+// validateKeystore checks the templates of a pkcs12 or jks file
+func validateKeystore(file *File) error {
+	ks := file.Keystore
+	if ks == nil {
+		return fmt.Errorf("keystore is required with format %s", file.Format)
+	}
+	if ks.Certificate == "" || ks.PrivateKey == "" || ks.Password == "" {
+		return fmt.Errorf("keystore requires certificate, privateKey and password")
+	}
+	if ks.Alias != "" && file.Format != filewriter.FormatJKS {
+		return fmt.Errorf("keystore alias requires format %s", filewriter.FormatJKS)
+	}
+	if len(file.Keys) > 0 {
+		return fmt.Errorf("keys cannot be used with format %s", file.Format)
+	}
+	return nil
+}
+
+// validateConcat checks the templates of a concat file
+func validateConcat(file *File) error {
+	if file.Concat == nil || len(file.Concat.Templates) == 0 {
+		return fmt.Errorf("concat.templates is required with format %s", filewriter.FormatConcat)
+	}
+	for i, name := range file.Concat.Templates {
+		if name == "" {
+			return fmt.Errorf("concat.templates[%d]: name is required", i)
+		}
+	}
+	if len(file.Keys) > 0 {
+		return fmt.Errorf("keys cannot be used with format %s", file.Format)
+	}
 	return nil
 }
 
@@ -241,6 +960,46 @@ func validateFile(file *File) error {
 		return fmt.Errorf("invalid path: %w", err)
 	}
 
+	for i, copyPath := range file.Copies {
+		if copyPath == "" {
+			return fmt.Errorf("copies[%d]: path is required", i)
+		}
+		absPath, err := filepath.Abs(copyPath)
+		if err != nil {
+			return fmt.Errorf("copies[%d]: failed to resolve path: %w", i, err)
+		}
+		file.Copies[i] = filepath.Clean(absPath)
+	}
+
+	if file.Format != "" && !filewriter.ValidFormat(file.Format) {
+		return fmt.Errorf("format must be %s, %s, %s, %s or %s, got: %s",
+			filewriter.FormatJSON, filewriter.FormatEnv, filewriter.FormatPKCS12, filewriter.FormatJKS, filewriter.FormatConcat, file.Format)
+	}
+	if len(file.Keys) > 0 && file.Format == "" {
+		return fmt.Errorf("keys requires format")
+	}
+	if filewriter.IsKeystoreFormat(file.Format) {
+		if err := validateKeystore(file); err != nil {
+			return err
+		}
+	} else if file.Keystore != nil {
+		return fmt.Errorf("keystore requires format %s or %s", filewriter.FormatPKCS12, filewriter.FormatJKS)
+	}
+	if file.Format == filewriter.FormatConcat {
+		if err := validateConcat(file); err != nil {
+			return err
+		}
+	} else if file.Concat != nil {
+		return fmt.Errorf("concat requires format %s", filewriter.FormatConcat)
+	}
+	if file.CertExpiry && (file.Format == filewriter.FormatJSON || file.Format == filewriter.FormatEnv) {
+		return fmt.Errorf("certExpiry requires a template or format %s, %s or %s", filewriter.FormatPKCS12, filewriter.FormatJKS, filewriter.FormatConcat)
+	}
+
+	if file.KeepBackups < 0 || file.KeepBackups > filewriter.MaxBackups {
+		return fmt.Errorf("keepBackups must be between 0 and %d, got: %d", filewriter.MaxBackups, file.KeepBackups)
+	}
+
 	// Set default mode if empty
 	if file.Mode == "" {
 		file.Mode = "0600"
@@ -260,11 +1019,37 @@ func validateFile(file *File) error {
 
 	// Validate group if specified
 	if file.Group != "" {
-		if _, err := filewriter.ParseOwner(file.Group); err != nil {
+		if _, err := filewriter.ParseGroup(file.Group); err != nil {
 			return fmt.Errorf("invalid group '%s': %w", file.Group, err)
 		}
 	}
 
+	return validateDirAttributes(file.DirMode, file.DirOwner, file.DirGroup, file.SELinuxContext)
+}
+
+// validateDirAttributes checks the directory mode, owner and group and the
+// SELinux context of a file, each optional
+func validateDirAttributes(mode, owner, group, context string) error {
+	if mode != "" {
+		if _, err := filewriter.ParseDirMode(mode); err != nil {
+			return fmt.Errorf("invalid dirMode '%s': %w", mode, err)
+		}
+	}
+	if owner != "" {
+		if _, err := filewriter.ParseOwner(owner); err != nil {
+			return fmt.Errorf("invalid dirOwner '%s': %w", owner, err)
+		}
+	}
+	if group != "" {
+		if _, err := filewriter.ParseGroup(group); err != nil {
+			return fmt.Errorf("invalid dirGroup '%s': %w", group, err)
+		}
+	}
+	if context != "" {
+		if err := filewriter.ValidateSELinuxContext(context); err != nil {
+			return fmt.Errorf("invalid seLinuxContext: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -294,26 +1079,121 @@ func validateFilePath(path string) error {
 // ExpandEnvVars expands environment variables in configuration
 func ExpandEnvVars(cfg *Config) {
 	cfg.SecretStore.Address = expandEnv(cfg.SecretStore.Address)
+	for i, address := range cfg.SecretStore.Addresses {
+		cfg.SecretStore.Addresses[i] = expandEnv(address)
+	}
 	cfg.SecretStore.Namespace = expandEnv(cfg.SecretStore.Namespace)
+	cfg.SecretStore.AuthNamespace = expandEnv(cfg.SecretStore.AuthNamespace)
 	cfg.SecretStore.Token = expandEnv(cfg.SecretStore.Token)
 	cfg.SecretStore.RoleID = expandEnv(cfg.SecretStore.RoleID)
 	cfg.SecretStore.SecretID = expandEnv(cfg.SecretStore.SecretID)
+	cfg.SecretStore.SecretIDFile = expandEnv(cfg.SecretStore.SecretIDFile)
+	cfg.SecretStore.TokenFile = expandEnv(cfg.SecretStore.TokenFile)
+	cfg.SecretStore.KubernetesRole = expandEnv(cfg.SecretStore.KubernetesRole)
+	cfg.SecretStore.KubernetesTokenPath = expandEnv(cfg.SecretStore.KubernetesTokenPath)
+	cfg.SecretStore.CertRole = expandEnv(cfg.SecretStore.CertRole)
+	cfg.SecretStore.TenantID = expandEnv(cfg.SecretStore.TenantID)
+	cfg.SecretStore.ClientID = expandEnv(cfg.SecretStore.ClientID)
+	cfg.SecretStore.ClientSecret = expandEnv(cfg.SecretStore.ClientSecret)
 	cfg.SecretStore.TLSCACert = expandEnv(cfg.SecretStore.TLSCACert)
 	cfg.SecretStore.TLSCAPath = expandEnv(cfg.SecretStore.TLSCAPath)
 	cfg.SecretStore.TLSClientCert = expandEnv(cfg.SecretStore.TLSClientCert)
 	cfg.SecretStore.TLSClientKey = expandEnv(cfg.SecretStore.TLSClientKey)
+	if cfg.SecretStore.Connection != nil {
+		cfg.SecretStore.Connection.Proxy = expandEnv(cfg.SecretStore.Connection.Proxy)
+	}
 
+	for name, creds := range cfg.SecretStore.Credentials {
+		creds.Token = expandEnv(creds.Token)
+		creds.RoleID = expandEnv(creds.RoleID)
+		creds.SecretID = expandEnv(creds.SecretID)
+		creds.SecretIDFile = expandEnv(creds.SecretIDFile)
+		creds.TokenFile = expandEnv(creds.TokenFile)
+		creds.KubernetesRole = expandEnv(creds.KubernetesRole)
+		creds.KubernetesTokenPath = expandEnv(creds.KubernetesTokenPath)
+		creds.CertRole = expandEnv(creds.CertRole)
+		creds.AuthNamespace = expandEnv(creds.AuthNamespace)
+		creds.TenantID = expandEnv(creds.TenantID)
+		creds.ClientID = expandEnv(creds.ClientID)
+		creds.ClientSecret = expandEnv(creds.ClientSecret)
+		cfg.SecretStore.Credentials[name] = creds
+	}
+
+	cfg.SecretDefaults.MountPath = expandEnv(cfg.SecretDefaults.MountPath)
+
+	for i, dir := range cfg.AllowedOutputPaths {
+		cfg.AllowedOutputPaths[i] = expandEnv(dir)
+	}
 	for i := range cfg.Secrets {
-		cfg.Secrets[i].Namespace = expandEnv(cfg.Secrets[i].Namespace)
+		secret := &cfg.Secrets[i]
+		secret.Key = expandEnv(secret.Key)
+		secret.MountPath = expandEnv(secret.MountPath)
+		secret.Namespace = expandEnv(secret.Namespace)
+		secret.Template.File = expandEnv(secret.Template.File)
+		secret.ChecksumFile = expandEnv(secret.ChecksumFile)
+		for j := range secret.Sources {
+			secret.Sources[j].Key = expandEnv(secret.Sources[j].Key)
+			secret.Sources[j].MountPath = expandEnv(secret.Sources[j].MountPath)
+		}
+		for j := range secret.Files {
+			file := &secret.Files[j]
+			file.Path = expandEnv(file.Path)
+			for k, copyPath := range file.Copies {
+				file.Copies[k] = expandEnv(copyPath)
+			}
+		}
+		if secret.Directory != nil {
+			secret.Directory.Path = expandEnv(secret.Directory.Path)
+		}
+	}
+
+	// Webhook URLs such as Slack's carry their credentials
+	for i := range cfg.Notifications.Webhooks {
+		webhook := &cfg.Notifications.Webhooks[i]
+		webhook.URL = expandEnv(webhook.URL)
+		for name, value := range webhook.Headers {
+			webhook.Headers[name] = expandEnv(value)
+		}
 	}
 }
 
+// envName matches the name of an environment variable
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// expandEnv replaces every ${VAR} in s with the value of the environment
+// variable, and ${VAR:-default} with default if VAR is unset or empty.
+// $${ escapes a reference and is kept as ${. Anything else, such as a lone
+// $ or an unterminated ${, is kept as it is.
 func expandEnv(s string) string {
-	if strings.HasPrefix(s, "${") && strings.HasSuffix(s, "}") {
-		envVar := s[2 : len(s)-1]
-		return os.Getenv(envVar)
+	if !strings.Contains(s, "${") {
+		return s
 	}
-	return s
+
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], "$${") {
+			b.WriteString("${")
+			i += 3
+			continue
+		}
+		if strings.HasPrefix(s[i:], "${") {
+			if end := strings.IndexByte(s[i+2:], '}'); end >= 0 {
+				name, def, hasDef := strings.Cut(s[i+2:i+2+end], ":-")
+				if envName.MatchString(name) {
+					value := os.Getenv(name)
+					if value == "" && hasDef {
+						value = def
+					}
+					b.WriteString(value)
+					i += end + 3
+					continue
+				}
+			}
+		}
+		b.WriteByte(s[i])
+		i++
+	}
+	return b.String()
 }
 
 // validateNoDuplicatePaths checks that no two different secrets write to the same file path
@@ -321,21 +1201,31 @@ func validateNoDuplicatePaths(secrets []Secret) error {
 	pathToSecret := make(map[string]string) // path -> secret name
 
 	for _, secret := range secrets {
-		for _, file := range secret.Files {
-			if existingSecret, found := pathToSecret[file.Path]; found {
+		for _, path := range append(secret.OutputPaths(), secret.ChecksumPaths()...) {
+			if existingSecret, found := pathToSecret[path]; found {
 				if existingSecret != secret.Name {
 					// Different secrets writing to same path - race condition
 					return fmt.Errorf("duplicate file path %q: used by both secret %q and secret %q (race condition)",
-						file.Path, existingSecret, secret.Name)
+						path, existingSecret, secret.Name)
 				} else {
 					// Same secret writing to same path multiple times - configuration error
 					return fmt.Errorf("duplicate file path %q in secret %q (same path listed multiple times)",
-						file.Path, secret.Name)
+						path, secret.Name)
 				}
 			}
-			pathToSecret[file.Path] = secret.Name
+			pathToSecret[path] = secret.Name
 		}
 	}
 
 	return nil
 }
+
+// validateCleanup checks a cleanupOnShutdown value; empty keeps the files
+func validateCleanup(mode string) error {
+	switch mode {
+	case "", CleanupKeep, CleanupRemove, CleanupShred:
+		return nil
+	default:
+		return fmt.Errorf("cleanupOnShutdown must be keep, remove or shred, got: %s", mode)
+	}
+}
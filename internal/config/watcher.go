@@ -1,20 +1,28 @@
 package config
 
 import (
+	"context"
 	"fmt"
+	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/fsnotify/fsnotify"
 )
 
-// Watcher watches configuration file for changes
+// Watcher watches configuration file, and the files it includes and the
+// template files it refers to, for changes
 type Watcher struct {
 	configPath string
 	watcher    *fsnotify.Watcher
+	files      map[string]bool // Included and template files watched
+	sources    []string        // Directories and globs included config files are found in
 	onChange   func(*Config) error
 	onError    func(error)
 	mu         sync.Mutex
 	stopCh     chan struct{}
+	ctx        context.Context // Cancelled by Stop, aborting a reload in progress
+	cancel     context.CancelFunc
 }
 
 // NewWatcher creates a new configuration file watcher
@@ -29,13 +37,24 @@ func NewWatcher(configPath string, onChange func(*Config) error, onError func(er
 		return nil, fmt.Errorf("failed to watch config file: %w", err)
 	}
 
-	return &Watcher{
+	ctx, cancel := context.WithCancel(context.Background())
+	watcher := &Watcher{
 		configPath: configPath,
 		watcher:    w,
+		files:      make(map[string]bool),
 		onChange:   onChange,
 		onError:    onError,
 		stopCh:     make(chan struct{}),
-	}, nil
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+	// An invalid config refers to no template files until it is fixed
+	cfg, err := Load(ctx, configPath)
+	if err != nil {
+		cfg = &Config{}
+	}
+	watcher.watchFiles(cfg)
+	return watcher, nil
 }
 
 // Start begins watching for configuration changes
@@ -45,6 +64,7 @@ func (w *Watcher) Start() {
 
 // Stop stops watching for configuration changes
 func (w *Watcher) Stop() {
+	w.cancel()
 	close(w.stopCh)
 	_ = w.watcher.Close()
 }
@@ -56,7 +76,7 @@ func (w *Watcher) watch() {
 			if !ok {
 				return
 			}
-			if event.Op&fsnotify.Write == fsnotify.Write {
+			if event.Op&fsnotify.Write == fsnotify.Write || w.fileReplaced(event) || w.fileAdded(event) {
 				w.handleChange()
 			}
 		case err, ok := <-w.watcher.Errors:
@@ -76,7 +96,7 @@ func (w *Watcher) handleChange() {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	cfg, err := Load(w.configPath)
+	cfg, err := Load(w.ctx, w.configPath)
 	if err != nil {
 		if w.onError != nil {
 			w.onError(fmt.Errorf("failed to reload config: %w", err))
@@ -84,9 +104,76 @@ func (w *Watcher) handleChange() {
 		return
 	}
 
+	w.watchFiles(cfg)
+
 	if err := w.onChange(cfg); err != nil {
 		if w.onError != nil {
 			w.onError(fmt.Errorf("failed to apply config changes: %w", err))
 		}
 	}
 }
+
+// fileReplaced reports whether an event removed or renamed an included or
+// template file, as editors and config management do when replacing it. The
+// watch ends with the file, so the config is reloaded, which watches the new
+// one.
+func (w *Watcher) fileReplaced(event fsnotify.Event) bool {
+	if event.Op&(fsnotify.Remove|fsnotify.Rename) == 0 {
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.files[event.Name]
+}
+
+// fileAdded reports whether an event created, removed or renamed a config
+// file in a config directory or one matching an include glob
+func (w *Watcher) fileAdded(event fsnotify.Event) bool {
+	if event.Op&(fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, source := range w.sources {
+		if matchesSource(source, event.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchFiles watches the included and template files of cfg, and the
+// directories new config files may be added to, and stops watching those no
+// longer referred to; the caller holds w.mu or has not started watching
+func (w *Watcher) watchFiles(cfg *Config) {
+	included, sources := includeSources(w.configPath)
+
+	current := make(map[string]bool)
+	for _, path := range append(included, cfg.TemplateFiles()...) {
+		current[path] = true
+		// Added again every time, a replaced file needs a new watch
+		if err := w.watcher.Add(path); err != nil && w.onError != nil {
+			w.onError(fmt.Errorf("failed to watch file: %w", err))
+		}
+	}
+	for _, source := range sources {
+		dir := source
+		if strings.ContainsAny(source, "*?[") {
+			dir = filepath.Dir(source)
+		}
+		if current[dir] {
+			continue
+		}
+		current[dir] = true
+		if err := w.watcher.Add(dir); err != nil && w.onError != nil {
+			w.onError(fmt.Errorf("failed to watch directory: %w", err))
+		}
+	}
+	for path := range w.files {
+		if !current[path] && path != w.configPath {
+			_ = w.watcher.Remove(path)
+		}
+	}
+	w.files = current
+	w.sources = sources
+}
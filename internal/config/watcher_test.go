@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -149,3 +150,92 @@ secrets: []
 	case <-time.After(500 * time.Millisecond):
 	}
 }
+
+func TestWatcher_DetectsTemplateFileChanges(t *testing.T) {
+	dir := t.TempDir()
+	templatePath := filepath.Join(dir, "key.tmpl")
+	if err := os.WriteFile(templatePath, []byte("{{ .value }}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(`secretStore:
+  address: "https://vault.example.com"
+  authMethod: "token"
+  token: "test-token"
+secrets:
+  - name: "test-secret"
+    key: "test/path"
+    mountPath: "secret"
+    kvVersion: "v2"
+    refreshInterval: "5m"
+    template:
+      file: "key.tmpl"
+    files:
+      - path: "/test/key"
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	changeDetected := make(chan *Config, 1)
+	watcher, err := NewWatcher(configPath, func(cfg *Config) error {
+		changeDetected <- cfg
+		return nil
+	}, func(err error) {})
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	defer watcher.Stop()
+	watcher.Start()
+
+	if err := os.WriteFile(templatePath, []byte("{{ .other }}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case cfg := <-changeDetected:
+		if got := cfg.Secrets[0].Template.Data["key.tmpl"]; got != "{{ .other }}" {
+			t.Errorf("expected the changed template, got %q", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for template file change detection")
+	}
+}
+
+func TestWatcher_DetectsFilesAddedToConfigDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "00-store.yaml"), []byte(testStoreConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "app.yaml"), []byte(testSecretConfig("app")), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	changeDetected := make(chan *Config, 1)
+	watcher, err := NewWatcher(dir, func(cfg *Config) error {
+		changeDetected <- cfg
+		return nil
+	}, func(err error) {})
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	defer watcher.Stop()
+	watcher.Start()
+
+	// Written elsewhere and renamed into place, as config management does
+	tmp := filepath.Join(t.TempDir(), "web.yaml")
+	if err := os.WriteFile(tmp, []byte(testSecretConfig("web")), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, "web.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case cfg := <-changeDetected:
+		if len(cfg.Secrets) != 2 {
+			t.Errorf("expected 2 secrets after adding a file, got %d", len(cfg.Secrets))
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for the added config file to be detected")
+	}
+}
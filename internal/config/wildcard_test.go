@@ -0,0 +1,125 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// wildcardConfig returns a config whose only secret has the given key, writing to directory dir
+func wildcardConfig(key string, dir *Directory) *Config {
+	return &Config{
+		SecretStore: SecretStore{
+			Address:    "https://vault.example.com",
+			AuthMethod: "token",
+			Token:      "test",
+		},
+		Secrets: []Secret{
+			{
+				Name:            "apps",
+				Key:             key,
+				MountPath:       "secret",
+				KVVersion:       "v2",
+				RefreshInterval: 5 * time.Minute,
+				Directory:       dir,
+			},
+		},
+	}
+}
+
+func TestSecret_WildcardPrefix(t *testing.T) {
+	tests := []struct {
+		key       string
+		prefix    string
+		recursive bool
+		ok        bool
+	}{
+		{"app/configs/*", "app/configs/", false, true},
+		{"app/configs/**", "app/configs/", true, true},
+		{"*", "", false, true},
+		{"**", "", true, true},
+		{"app/configs", "", false, false},
+		{"app/conf*", "", false, false},
+	}
+
+	for _, tt := range tests {
+		secret := Secret{Key: tt.key}
+		prefix, recursive, ok := secret.WildcardPrefix()
+		if prefix != tt.prefix || recursive != tt.recursive || ok != tt.ok {
+			t.Errorf("WildcardPrefix(%q) = %q, %v, %v; want %q, %v, %v",
+				tt.key, prefix, recursive, ok, tt.prefix, tt.recursive, tt.ok)
+		}
+	}
+}
+
+func TestValidate_Wildcard(t *testing.T) {
+	cfg := wildcardConfig("app/configs/*", &Directory{Path: "/secrets/configs"})
+
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if cfg.Secrets[0].Directory.Mode != "0600" {
+		t.Errorf("expected default mode 0600, got %q", cfg.Secrets[0].Directory.Mode)
+	}
+}
+
+func TestValidate_WildcardErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    *Config
+		errMsg string
+	}{
+		{
+			name:   "wildcard without directory",
+			cfg:    wildcardConfig("app/*", nil),
+			errMsg: "directory is required",
+		},
+		{
+			name:   "directory without wildcard",
+			cfg:    wildcardConfig("app/db", &Directory{Path: "/secrets"}),
+			errMsg: "directory requires a wildcard key",
+		},
+		{
+			name:   "wildcard inside the path",
+			cfg:    wildcardConfig("app/*/db", nil),
+			errMsg: "trailing /* or /**",
+		},
+		{
+			name: "wildcard with files",
+			cfg: func() *Config {
+				cfg := wildcardConfig("app/*", &Directory{Path: "/secrets"})
+				cfg.Secrets[0].Files = []File{{Path: "/secrets/file"}}
+				return cfg
+			}(),
+			errMsg: "files cannot be used",
+		},
+		{
+			name: "template name with a path",
+			cfg: func() *Config {
+				cfg := wildcardConfig("app/*", &Directory{Path: "/secrets"})
+				cfg.Secrets[0].Template = Template{Data: map[string]string{"../escape": "{{ .value }}"}}
+				return cfg
+			}(),
+			errMsg: "plain file name",
+		},
+		{
+			name:   "insecure directory mode",
+			cfg:    wildcardConfig("app/*", &Directory{Path: "/secrets", Mode: "0666"}),
+			errMsg: "directory: invalid mode",
+		},
+		{
+			name:   "unknown directory group",
+			cfg:    wildcardConfig("app/*", &Directory{Path: "/secrets", Group: "no-such-group-secrets-sync"}),
+			errMsg: `invalid group 'no-such-group-secrets-sync': group "no-such-group-secrets-sync" does not exist`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got: %v", tt.errMsg, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+// Package diagnostics writes a snapshot of the running service for
+// post-mortem debugging of stuck instances. Secret values are never included.
+package diagnostics
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/syncer"
+)
+
+// Collector gathers the state included in a diagnostics snapshot
+type Collector struct {
+	Version    string
+	ConfigFile string
+	StartTime  time.Time
+	Syncer     *syncer.SecretSyncer
+	State      *syncer.StateStore
+	Scheduler  func() *syncer.Scheduler // Current scheduler, replaced on reload
+}
+
+// Write writes a snapshot to w
+func (c *Collector) Write(w io.Writer) error {
+	var b bytes.Buffer
+	now := time.Now()
+
+	fmt.Fprintf(&b, "=== secrets-sync diagnostics ===\n")
+	fmt.Fprintf(&b, "time:        %s\n", now.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "version:     %s\n", c.Version)
+	fmt.Fprintf(&b, "go:          %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&b, "pid:         %d\n", os.Getpid())
+	if !c.StartTime.IsZero() {
+		fmt.Fprintf(&b, "uptime:      %s\n", now.Sub(c.StartTime).Truncate(time.Second))
+	}
+	fmt.Fprintf(&b, "goroutines:  %d\n", runtime.NumGoroutine())
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	fmt.Fprintf(&b, "heap:        %d bytes in use, %d GC cycles\n", mem.HeapInuse, mem.NumGC)
+
+	fmt.Fprintf(&b, "\n=== config ===\n")
+	fmt.Fprintf(&b, "file:        %s\n", c.ConfigFile)
+	fmt.Fprintf(&b, "sha256:      %s\n", fingerprint(c.ConfigFile))
+
+	if c.Scheduler != nil {
+		if scheduler := c.Scheduler(); scheduler != nil {
+			fmt.Fprintf(&b, "\n=== scheduler jobs (in flight: %d, paused for sealed vault: %t) ===\n",
+				scheduler.InFlight(), scheduler.Paused())
+			rows := [][]string{{"SECRET", "INTERVAL", "LAST SYNC", "NEXT SYNC", "RUNNING FOR"}}
+			for _, job := range scheduler.Jobs() {
+				running := "-"
+				if !job.RunningSince.IsZero() {
+					running = now.Sub(job.RunningSince).Truncate(time.Millisecond).String()
+				}
+				rows = append(rows, []string{job.Name, job.RefreshInterval.String(), formatTime(job.LastSync), formatTime(job.NextSync), running})
+			}
+			writeTable(&b, rows)
+		}
+	}
+
+	if c.Syncer != nil {
+		fmt.Fprintf(&b, "\n=== vault client pool ===\n")
+		rows := [][]string{{"CREDENTIALS", "CIRCUIT BREAKER"}}
+		for _, client := range c.Syncer.Clients() {
+			rows = append(rows, []string{client.Credentials, client.BreakerState})
+		}
+		writeTable(&b, rows)
+	}
+
+	if c.State != nil {
+		fmt.Fprintf(&b, "\n=== recent sync history (oldest first) ===\n")
+		rows := [][]string{{"TIME", "SECRET", "RESULT", "ERROR"}}
+		for _, result := range c.State.Recent() {
+			outcome := "synced"
+			switch {
+			case result.Success && result.Stale:
+				outcome = "stale"
+			case !result.Success:
+				outcome = "failed (" + string(result.Kind) + ")"
+			}
+			errMsg := ""
+			if result.Error != nil {
+				errMsg = result.Error.Error()
+			}
+			rows = append(rows, []string{formatTime(result.Timestamp), result.SecretName, outcome, errMsg})
+		}
+		writeTable(&b, rows)
+	}
+
+	fmt.Fprintf(&b, "\n=== goroutines ===\n")
+	if err := pprof.Lookup("goroutine").WriteTo(&b, 2); err != nil {
+		return fmt.Errorf("failed to dump goroutines: %w", err)
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// writeTable writes rows as left-aligned columns
+func writeTable(b *bytes.Buffer, rows [][]string) {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(row)-1 {
+				b.WriteString(cell)
+				break
+			}
+			fmt.Fprintf(b, "%-*s  ", widths[i], cell)
+		}
+		b.WriteString("\n")
+	}
+}
+
+// WriteFile writes a snapshot to a new file in dir and returns its path
+func (c *Collector) WriteFile(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create diagnostics directory: %w", err)
+	}
+
+	name := fmt.Sprintf("secrets-sync-diagnostics-%s-%d.txt", time.Now().UTC().Format("20060102T150405Z"), os.Getpid())
+	path := filepath.Join(dir, name)
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", fmt.Errorf("failed to create diagnostics file: %w", err)
+	}
+
+	if err := c.Write(f); err != nil {
+		_ = f.Close()
+		return "", fmt.Errorf("failed to write diagnostics: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to write diagnostics: %w", err)
+	}
+
+	return path, nil
+}
+
+// fingerprint returns the SHA-256 of a file, identifying the config in use
+// without revealing any credentials it contains
+func fingerprint(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Sprintf("unavailable (%v)", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// formatTime formats a timestamp, showing a dash for the zero time
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
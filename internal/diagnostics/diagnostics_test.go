@@ -0,0 +1,104 @@
+package diagnostics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/syncer"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+func newTestCollector(t *testing.T) *Collector {
+	t.Helper()
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configFile, []byte("token: super-secret-token\n"), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	factory := func(ctx context.Context, creds config.CredentialSet) (*vault.Client, error) {
+		return nil, errors.New("unused")
+	}
+	secretSyncer := syncer.NewSecretSyncer(factory, vault.RetryConfig{})
+	store := syncer.NewStateStore()
+	scheduler := syncer.NewScheduler(secretSyncer).WithStateStore(store)
+	t.Cleanup(scheduler.Stop)
+
+	_ = store.Publish(context.Background(), syncer.SyncResult{
+		SecretName: "db",
+		Success:    false,
+		Error:      errors.New("permission denied"),
+		Timestamp:  time.Now(),
+	})
+
+	return &Collector{
+		Version:    "test",
+		ConfigFile: configFile,
+		StartTime:  time.Now().Add(-time.Minute),
+		Syncer:     secretSyncer,
+		State:      store,
+		Scheduler:  func() *syncer.Scheduler { return scheduler },
+	}
+}
+
+func TestCollector_Write(t *testing.T) {
+	c := newTestCollector(t)
+
+	var buf bytes.Buffer
+	if err := c.Write(&buf); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"=== config ===",
+		"=== scheduler jobs",
+		"=== vault client pool ===",
+		"=== recent sync history",
+		"permission denied",
+		"=== goroutines ===",
+		"goroutine ",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q", want)
+		}
+	}
+
+	if strings.Contains(out, "super-secret-token") {
+		t.Error("expected config contents not to be included")
+	}
+}
+
+func TestCollector_WriteFile(t *testing.T) {
+	c := newTestCollector(t)
+	dir := filepath.Join(t.TempDir(), "diag")
+
+	path, err := c.WriteFile(dir)
+	if err != nil {
+		t.Fatalf("write file failed: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("expected diagnostics file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %04o", info.Mode().Perm())
+	}
+	if filepath.Dir(path) != dir {
+		t.Errorf("expected file in %s, got %s", dir, path)
+	}
+}
+
+func TestFingerprint_MissingFile(t *testing.T) {
+	if got := fingerprint(filepath.Join(t.TempDir(), "missing")); !strings.HasPrefix(got, "unavailable") {
+		t.Errorf("expected unavailable, got %s", got)
+	}
+}
@@ -0,0 +1,350 @@
+// Package doctor diagnoses the environment the service runs in: the
+// connection to Vault and its TLS chain, authentication and token TTL, the
+// policies of every secret, the target directories and the clock
+package doctor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/selftest"
+	"github.com/ohauer/secrets-sync/internal/syncer"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+// certExpiryWarning is how long before a certificate of the Vault TLS chain
+// expires that it is reported
+const certExpiryWarning = 30 * 24 * time.Hour
+
+// tokenTTLWarning is the TTL below which a token that cannot be renewed is
+// reported
+const tokenTTLWarning = 24 * time.Hour
+
+// maxClockSkew is the difference from Vault's clock that is reported; the
+// Date header has a resolution of one second
+const maxClockSkew = 5 * time.Second
+
+// Status is the outcome of a check
+type Status int
+
+const (
+	StatusOK Status = iota
+	StatusWarn
+	StatusFail
+)
+
+// String returns the name of a status
+func (s Status) String() string {
+	switch s {
+	case StatusOK:
+		return "ok"
+	case StatusWarn:
+		return "warn"
+	default:
+		return "fail"
+	}
+}
+
+// Finding is the outcome of one check
+type Finding struct {
+	Section string
+	Name    string
+	Status  Status
+	Detail  string
+}
+
+// Report holds the findings of every check that ran, in order
+type Report struct {
+	Findings []Finding
+}
+
+// Count returns the number of findings with a status
+func (r *Report) Count(status Status) int {
+	n := 0
+	for _, f := range r.Findings {
+		if f.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// add records a finding
+func (r *Report) add(section, name string, status Status, detail string) {
+	r.Findings = append(r.Findings, Finding{Section: section, Name: name, Status: status, Detail: detail})
+}
+
+// Options are what Run needs from the caller to reach Vault
+type Options struct {
+	NewClient func(address string) (*vault.Client, error) // Unauthenticated client for one endpoint
+	Factory   syncer.ClientFactory                        // Authenticated client for a credential set
+}
+
+// Run checks every endpoint, credential set, secret and target directory of
+// cfg. Managed files are not touched and secrets are not read.
+func Run(ctx context.Context, cfg *config.Config, opts Options) *Report {
+	r := &Report{}
+	if cfg.SecretStore.IsAzureKeyVault() {
+		r.add("vault", "secret store", StatusFail, "doctor supports Vault and OpenBao secret stores only")
+		return r
+	}
+
+	checkEndpoints(ctx, r, cfg, opts)
+	clients := checkCredentials(ctx, r, cfg, opts)
+	checkCapabilities(ctx, r, cfg, clients)
+	for _, check := range selftest.ProbeDirectories(ctx, cfg) {
+		if check.Err != nil {
+			r.add("directories", check.Name, StatusFail, check.Err.Error())
+		} else {
+			r.add("directories", check.Name, StatusOK, "")
+		}
+	}
+	return r
+}
+
+// checkEndpoints probes every endpoint for its state and TLS chain, and the
+// first reachable one for clock skew
+func checkEndpoints(ctx context.Context, r *Report, cfg *config.Config, opts Options) {
+	clockChecked := false
+	for _, address := range cfg.SecretStore.Endpoints() {
+		client, err := opts.NewClient(address)
+		if err != nil {
+			r.add("vault", address, StatusFail, err.Error())
+			continue
+		}
+		info, err := client.Probe(ctx)
+		if err != nil {
+			r.add("vault", address, StatusFail, err.Error())
+			continue
+		}
+		received := time.Now()
+
+		switch {
+		case !info.Initialized:
+			r.add("vault", address, StatusFail, "not initialized")
+		case info.Sealed:
+			r.add("vault", address, StatusFail, "sealed")
+		default:
+			role := "active"
+			if info.Standby {
+				role = "standby"
+			}
+			r.add("vault", address, StatusOK, fmt.Sprintf("version %s, %s, %s round trip", info.Version, role, info.RoundTrip.Round(time.Millisecond)))
+		}
+
+		status, detail := checkTLS(info.TLS, received)
+		r.add("tls", address, status, detail)
+
+		if !clockChecked && !info.Date.IsZero() {
+			clockChecked = true
+			// The server answered about half a round trip before we received it
+			skew := info.Date.Sub(received.Add(-info.RoundTrip / 2)).Round(time.Second)
+			switch {
+			case skew > maxClockSkew || skew < -maxClockSkew:
+				r.add("clock", address, StatusWarn, fmt.Sprintf("local clock is %s off from Vault; token, lease and certificate times will be wrong", (-skew).String()))
+			default:
+				r.add("clock", address, StatusOK, fmt.Sprintf("within %s of Vault", maxClockSkew))
+			}
+		}
+	}
+}
+
+// checkTLS describes the TLS connection to an endpoint
+func checkTLS(state *tls.ConnectionState, now time.Time) (Status, string) {
+	if state == nil {
+		return StatusWarn, "plain HTTP, tokens and secrets are sent unencrypted"
+	}
+	if len(state.VerifiedChains) == 0 {
+		return StatusWarn, fmt.Sprintf("%s, certificate not verified (tlsSkipVerify)", tls.VersionName(state.Version))
+	}
+
+	chain := state.VerifiedChains[0]
+	names := make([]string, 0, len(chain))
+	var expiring *x509.Certificate
+	for _, cert := range chain {
+		names = append(names, certName(cert))
+		if expiring == nil || cert.NotAfter.Before(expiring.NotAfter) {
+			expiring = cert
+		}
+	}
+	detail := fmt.Sprintf("%s, chain %s", tls.VersionName(state.Version), strings.Join(names, " < "))
+	if left := expiring.NotAfter.Sub(now); left < certExpiryWarning {
+		return StatusWarn, fmt.Sprintf("%s; %s expires %s", detail, certName(expiring), expiring.NotAfter.UTC().Format(time.RFC3339))
+	}
+	return StatusOK, fmt.Sprintf("%s, valid until %s", detail, expiring.NotAfter.UTC().Format("2006-01-02"))
+}
+
+// certName names a certificate by its common name, or its subject
+func certName(cert *x509.Certificate) string {
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName
+	}
+	return cert.Subject.String()
+}
+
+// checkCredentials authenticates every credential set the secrets use and
+// checks the TTL of its token. It returns the clients that authenticated.
+func checkCredentials(ctx context.Context, r *Report, cfg *config.Config, opts Options) map[string]*vault.Client {
+	clients := make(map[string]*vault.Client)
+	for _, name := range selftest.CredentialNames(cfg) {
+		label := credentialLabel(name)
+		creds, ok := cfg.SecretStore.GetCredentials(name)
+		if !ok {
+			r.add("auth", label, StatusFail, "not found in secretStore.credentials")
+			continue
+		}
+		client, err := opts.Factory(ctx, creds)
+		if err != nil {
+			r.add("auth", label, StatusFail, err.Error())
+			continue
+		}
+		clients[name] = client
+
+		info, err := client.LookupToken(ctx)
+		if err != nil {
+			r.add("auth", label, StatusWarn, fmt.Sprintf("authenticated with %s, but %v", creds.AuthMethod, err))
+			continue
+		}
+		status, detail := describeToken(creds.AuthMethod, info)
+		r.add("auth", label, status, detail)
+	}
+	return clients
+}
+
+// describeToken reports the TTL and policies of a token. Tokens given as
+// token or tokenFile cannot be replaced by logging in again, so one that is
+// about to expire without renewal is reported.
+func describeToken(method string, info vault.TokenInfo) (Status, string) {
+	ttl := "no expiry"
+	if info.TTL > 0 {
+		ttl = "TTL " + info.TTL.String()
+		if info.Renewable {
+			ttl += ", renewable"
+		}
+	}
+	policies := "none"
+	if len(info.Policies) > 0 {
+		policies = strings.Join(info.Policies, ", ")
+	}
+	detail := fmt.Sprintf("%s, %s, policies: %s", method, ttl, policies)
+
+	staticToken := method == string(vault.AuthMethodToken) || method == string(vault.AuthMethodTokenFile)
+	if staticToken && info.TTL > 0 && !info.Renewable && info.TTL < tokenTTLWarning {
+		return StatusWarn, detail + "; the token cannot be renewed and secrets stop syncing when it expires"
+	}
+	return StatusOK, detail
+}
+
+// requirement is a capability a secret needs on a path
+type requirement struct {
+	path       string
+	capability string
+	optional   bool   // Missing it only disables an optimization
+	purpose    string // Why it is needed, for optional capabilities
+}
+
+// checkCapabilities asks Vault whether the token of every secret may read
+// its paths, in the secret's namespace
+func checkCapabilities(ctx context.Context, r *Report, cfg *config.Config, clients map[string]*vault.Client) {
+	for _, secret := range cfg.Secrets {
+		client, ok := clients[secret.ResolveCredentials()]
+		if !ok {
+			// Failed logins were already reported
+			continue
+		}
+		namespace := secret.ResolveNamespace(cfg.SecretStore.Namespace)
+
+		var missing, optional, granted []string
+		failed := false
+		for _, req := range requirements(secret) {
+			capabilities, err := client.Capabilities(ctx, req.path, namespace)
+			if err != nil {
+				r.add("capabilities", secret.Name, StatusFail, err.Error())
+				failed = true
+				break
+			}
+			if hasCapability(capabilities, req.capability) {
+				granted = append(granted, req.capability+" "+req.path)
+				continue
+			}
+			entry := fmt.Sprintf("no %s on %s (has: %s)", req.capability, req.path, strings.Join(capabilities, ", "))
+			if req.optional {
+				optional = append(optional, entry+", "+req.purpose)
+			} else {
+				missing = append(missing, entry)
+			}
+		}
+
+		switch {
+		case failed:
+		case len(missing) > 0:
+			r.add("capabilities", secret.Name, StatusFail, strings.Join(append(missing, optional...), "; "))
+		case len(optional) > 0:
+			r.add("capabilities", secret.Name, StatusWarn, strings.Join(optional, "; "))
+		default:
+			r.add("capabilities", secret.Name, StatusOK, strings.Join(granted, ", "))
+		}
+	}
+}
+
+// requirements returns the capabilities reading a secret needs
+func requirements(secret config.Secret) []requirement {
+	if secret.IsDynamic() {
+		return []requirement{{path: path.Join(secret.MountPath, "creds", secret.Key), capability: "read"}}
+	}
+	if len(secret.Sources) > 0 {
+		var reqs []requirement
+		for _, source := range secret.Sources {
+			reqs = append(reqs, kvRequirements(secret.ForSource(source))...)
+		}
+		return reqs
+	}
+	return kvRequirements(secret)
+}
+
+// kvRequirements returns the capabilities reading a KV secret needs
+func kvRequirements(secret config.Secret) []requirement {
+	if prefix, _, ok := secret.WildcardPrefix(); ok {
+		if secret.KVVersion == "v2" {
+			return []requirement{{path: path.Join(secret.MountPath, "metadata", prefix) + "/", capability: "list"}}
+		}
+		return []requirement{{path: path.Join(secret.MountPath, prefix) + "/", capability: "list"}}
+	}
+	if secret.KVVersion != "v2" {
+		return []requirement{{path: path.Join(secret.MountPath, secret.Key), capability: "read"}}
+	}
+	reqs := []requirement{{path: path.Join(secret.MountPath, "data", secret.Key), capability: "read"}}
+	if secret.Version == 0 {
+		reqs = append(reqs, requirement{
+			path:       path.Join(secret.MountPath, "metadata", secret.Key),
+			capability: "read",
+			optional:   true,
+			purpose:    "so every refresh reads the full secret",
+		})
+	}
+	return reqs
+}
+
+// hasCapability reports whether capabilities grant one, root granting all
+func hasCapability(capabilities []string, capability string) bool {
+	for _, c := range capabilities {
+		if c == capability || c == "root" {
+			return true
+		}
+	}
+	return false
+}
+
+// credentialLabel names a credential set in findings
+func credentialLabel(name string) string {
+	if name == "" {
+		return "default credentials"
+	}
+	return fmt.Sprintf("credentials %q", name)
+}
@@ -0,0 +1,187 @@
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+// newMockVault serves health, token lookup and capabilities, granting the
+// paths in granted
+func newMockVault(t *testing.T, date time.Time, granted map[string][]string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/sys/health":
+			w.Header().Set("Date", date.UTC().Format(http.TimeFormat))
+			_, _ = w.Write([]byte(`{"initialized": true, "sealed": false, "version": "1.15.0"}`))
+		case "/v1/auth/token/lookup-self":
+			_, _ = w.Write([]byte(`{"data": {"ttl": 600, "renewable": false, "policies": ["app"]}}`))
+		case "/v1/sys/capabilities-self":
+			var body struct {
+				Path string `json:"path"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			capabilities, ok := granted[body.Path]
+			if !ok {
+				capabilities = []string{"deny"}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{body.Path: capabilities},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newOptions(t *testing.T, address string) Options {
+	t.Helper()
+
+	return Options{
+		NewClient: func(endpoint string) (*vault.Client, error) {
+			client, err := vault.NewClient(endpoint)
+			if err != nil {
+				return nil, err
+			}
+			client.GetAPIClient().SetMaxRetries(0)
+			return client, nil
+		},
+		Factory: func(ctx context.Context, creds config.CredentialSet) (*vault.Client, error) {
+			client, err := vault.NewClient(address)
+			if err != nil {
+				return nil, err
+			}
+			client.GetAPIClient().SetToken(creds.Token)
+			return client, nil
+		},
+	}
+}
+
+func newConfig(address, dir string) *config.Config {
+	return &config.Config{
+		SecretStore: config.SecretStore{
+			Address:    address,
+			AuthMethod: "token",
+			Token:      "test-token",
+		},
+		Secrets: []config.Secret{
+			{
+				Name:      "app",
+				Key:       "app",
+				MountPath: "secret",
+				KVVersion: "v2",
+				Files:     []config.File{{Path: filepath.Join(dir, "app"), Mode: "0600"}},
+			},
+			{
+				Name:      "legacy",
+				Key:       "legacy",
+				MountPath: "kv",
+				KVVersion: "v1",
+				Files:     []config.File{{Path: filepath.Join(dir, "legacy"), Mode: "0600"}},
+			},
+		},
+	}
+}
+
+func findings(r *Report, section string) []Finding {
+	var found []Finding
+	for _, f := range r.Findings {
+		if f.Section == section {
+			found = append(found, f)
+		}
+	}
+	return found
+}
+
+func TestRun(t *testing.T) {
+	server := newMockVault(t, time.Now(), map[string][]string{
+		"secret/data/app":     {"read"},
+		"secret/metadata/app": {"read", "list"},
+	})
+	cfg := newConfig(server.URL, t.TempDir())
+
+	r := Run(context.Background(), cfg, newOptions(t, server.URL))
+
+	if vault := findings(r, "vault"); len(vault) != 1 || vault[0].Status != StatusOK {
+		t.Errorf("expected reachable Vault, got %+v", vault)
+	}
+	if tls := findings(r, "tls"); len(tls) != 1 || tls[0].Status != StatusWarn {
+		t.Errorf("expected a warning for plain HTTP, got %+v", tls)
+	}
+	if clock := findings(r, "clock"); len(clock) != 1 || clock[0].Status != StatusOK {
+		t.Errorf("expected no clock skew, got %+v", clock)
+	}
+
+	auth := findings(r, "auth")
+	if len(auth) != 1 || auth[0].Status != StatusWarn || !strings.Contains(auth[0].Detail, "cannot be renewed") {
+		t.Errorf("expected a warning for a short-lived static token, got %+v", auth)
+	}
+
+	capabilities := findings(r, "capabilities")
+	if len(capabilities) != 2 {
+		t.Fatalf("expected 2 capability findings, got %+v", capabilities)
+	}
+	if capabilities[0].Status != StatusOK {
+		t.Errorf("expected app to be readable, got %+v", capabilities[0])
+	}
+	if capabilities[1].Status != StatusFail || !strings.Contains(capabilities[1].Detail, "no read on kv/legacy") {
+		t.Errorf("expected legacy to be denied, got %+v", capabilities[1])
+	}
+
+	if dirs := findings(r, "directories"); len(dirs) != 1 || dirs[0].Status != StatusOK {
+		t.Errorf("expected a writable directory, got %+v", dirs)
+	}
+}
+
+func TestRun_ClockSkewAndMissingMetadata(t *testing.T) {
+	server := newMockVault(t, time.Now().Add(-2*time.Minute), map[string][]string{
+		"secret/data/app": {"read"},
+		"kv/legacy":       {"root"},
+	})
+	cfg := newConfig(server.URL, t.TempDir())
+
+	r := Run(context.Background(), cfg, newOptions(t, server.URL))
+
+	if clock := findings(r, "clock"); len(clock) != 1 || clock[0].Status != StatusWarn {
+		t.Errorf("expected a clock skew warning, got %+v", clock)
+	}
+	capabilities := findings(r, "capabilities")
+	if len(capabilities) != 2 || capabilities[0].Status != StatusWarn || capabilities[1].Status != StatusOK {
+		t.Errorf("expected a warning for missing metadata read only, got %+v", capabilities)
+	}
+	if r.Count(StatusFail) != 0 {
+		t.Errorf("expected no failures, got %+v", r.Findings)
+	}
+}
+
+func TestRun_Unreachable(t *testing.T) {
+	cfg := newConfig("http://127.0.0.1:1", t.TempDir())
+	opts := newOptions(t, cfg.SecretStore.Address)
+	opts.Factory = func(ctx context.Context, creds config.CredentialSet) (*vault.Client, error) {
+		return nil, context.DeadlineExceeded
+	}
+
+	r := Run(context.Background(), cfg, opts)
+
+	if vault := findings(r, "vault"); len(vault) != 1 || vault[0].Status != StatusFail {
+		t.Errorf("expected unreachable Vault to fail, got %+v", vault)
+	}
+	if auth := findings(r, "auth"); len(auth) != 1 || auth[0].Status != StatusFail {
+		t.Errorf("expected failed authentication, got %+v", auth)
+	}
+	if capabilities := findings(r, "capabilities"); len(capabilities) != 0 {
+		t.Errorf("expected no capability checks without a client, got %+v", capabilities)
+	}
+}
@@ -0,0 +1,89 @@
+// Package errkind classifies errors by cause, so retries, metrics and sync
+// results can be decided with errors.Is and errors.As instead of matching
+// error messages.
+package errkind
+
+import "errors"
+
+// Kind is the cause of an error. Kinds are errors themselves, so
+// errors.Is(err, errkind.Auth) reports whether err was classified as Auth.
+type Kind string
+
+const (
+	// Unknown is the kind of errors that were not classified
+	Unknown Kind = "unknown"
+	// Auth means logging in to Vault failed
+	Auth Kind = "auth"
+	// NotFound means the secret does not exist in Vault
+	NotFound Kind = "not_found"
+	// Permission means Vault denied access to the secret
+	Permission Kind = "permission"
+	// Network means Vault could not be reached or was unavailable
+	Network Kind = "network"
+	// Template means a template failed to parse or render
+	Template Kind = "template"
+	// Filesystem means a file could not be written
+	Filesystem Kind = "filesystem"
+)
+
+// Error returns the kind's name
+func (k Kind) Error() string {
+	return string(k)
+}
+
+// Error is an error classified with a kind. Its message is that of the
+// wrapped error.
+type Error struct {
+	Kind Kind
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the error's kind
+func (e *Error) Is(target error) bool {
+	kind, ok := target.(Kind)
+	return ok && kind == e.Kind
+}
+
+// Wrap classifies err as kind. Nil stays nil, Unknown leaves err as is and
+// an error that is already classified keeps its kind, so the cause found
+// closest to the failure wins.
+func Wrap(kind Kind, err error) error {
+	if err == nil || kind == Unknown {
+		return err
+	}
+	var classified *Error
+	if errors.As(err, &classified) {
+		return err
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// Of returns the kind of err, or Unknown if it was not classified
+func Of(err error) Kind {
+	var classified *Error
+	if errors.As(err, &classified) {
+		return classified.Kind
+	}
+	return Unknown
+}
+
+// Retryable reports whether asking again may succeed: network errors and
+// errors of unknown cause. Auth, permission and not found errors persist
+// until someone changes Vault; template and filesystem errors until the
+// config or host is fixed.
+func Retryable(err error) bool {
+	switch Of(err) {
+	case Network, Unknown:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,68 @@
+package errkind
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	base := errors.New("permission denied")
+	err := fmt.Errorf("failed to read secret: %w", Wrap(Permission, base))
+
+	if !errors.Is(err, Permission) {
+		t.Error("expected errors.Is to match Permission")
+	}
+	if errors.Is(err, Auth) {
+		t.Error("expected errors.Is not to match Auth")
+	}
+	if !errors.Is(err, base) {
+		t.Error("expected the wrapped error to stay reachable")
+	}
+	if Of(err) != Permission {
+		t.Errorf("expected kind permission, got %s", Of(err))
+	}
+	if err.Error() != "failed to read secret: permission denied" {
+		t.Errorf("expected message to be unchanged, got %q", err.Error())
+	}
+}
+
+func TestWrap_KeepsFirstKind(t *testing.T) {
+	err := Wrap(Auth, fmt.Errorf("login: %w", Wrap(Network, errors.New("connection refused"))))
+	if Of(err) != Network {
+		t.Errorf("expected the inner kind network, got %s", Of(err))
+	}
+}
+
+func TestWrap_NilAndUnknown(t *testing.T) {
+	if Wrap(Network, nil) != nil {
+		t.Error("expected nil to stay nil")
+	}
+	base := errors.New("boom")
+	if Wrap(Unknown, base) != base {
+		t.Error("expected Unknown to leave the error unwrapped")
+	}
+	if Of(base) != Unknown {
+		t.Errorf("expected unclassified error to be unknown, got %s", Of(base))
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("boom"), true},
+		{Wrap(Network, errors.New("timeout")), true},
+		{Wrap(Auth, errors.New("bad token")), false},
+		{Wrap(Permission, errors.New("denied")), false},
+		{Wrap(NotFound, errors.New("missing")), false},
+		{Wrap(Template, errors.New("bad template")), false},
+		{Wrap(Filesystem, errors.New("read-only")), false},
+	}
+	for _, tt := range tests {
+		if got := Retryable(tt.err); got != tt.want {
+			t.Errorf("Retryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
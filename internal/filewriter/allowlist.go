@@ -0,0 +1,85 @@
+package filewriter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrPathNotAllowed is returned for a write outside the allowed directories
+var ErrPathNotAllowed = errors.New("path is outside the allowed output paths")
+
+// PathAllowed reports whether a clean absolute path is one of dirs or lies
+// below one of them; an empty dirs allows every path. Only the names are
+// compared, symlinks are not resolved.
+func PathAllowed(path string, dirs []string) bool {
+	if len(dirs) == 0 {
+		return true
+	}
+	for _, dir := range dirs {
+		if path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithAllowedPaths restricts writes to files below one of dirs, clean
+// absolute paths; none allows every path (default). It may be called again
+// while files are written, e.g. on reload.
+func (w *Writer) WithAllowedPaths(dirs []string) *Writer {
+	dirs = append([]string(nil), dirs...)
+	w.allowed.Store(&dirs)
+	return w
+}
+
+// checkAllowed rejects a path outside the allowed directories, by name and
+// once symlinks in its existing directories are resolved, so a link inside
+// an allowed directory cannot lead a write elsewhere
+func (w *Writer) checkAllowed(path string) error {
+	dirs := w.allowed.Load()
+	if dirs == nil || len(*dirs) == 0 {
+		return nil
+	}
+	if !PathAllowed(path, *dirs) {
+		return fmt.Errorf("%w: %s", ErrPathNotAllowed, path)
+	}
+
+	resolved, err := resolveExisting(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	allowed := make([]string, 0, len(*dirs))
+	for _, dir := range *dirs {
+		if r, err := resolveExisting(dir); err == nil {
+			allowed = append(allowed, r)
+		}
+	}
+	if !PathAllowed(resolved, allowed) {
+		return fmt.Errorf("%w: %s resolves to %s", ErrPathNotAllowed, path, resolved)
+	}
+	return nil
+}
+
+// resolveExisting resolves the symlinks of the longest existing part of a
+// path and appends the missing rest, which is created as plain directories
+func resolveExisting(path string) (string, error) {
+	missing := ""
+	for {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(resolved, missing), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+		}
+		missing = filepath.Join(filepath.Base(path), missing)
+		path = parent
+	}
+}
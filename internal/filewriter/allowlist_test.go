@@ -0,0 +1,69 @@
+package filewriter
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPathAllowed(t *testing.T) {
+	dirs := []string{"/run/secrets", "/etc/myapp/"}
+	tests := map[string]bool{
+		"/run/secrets":            true,
+		"/run/secrets/db/pass":    true,
+		"/etc/myapp/config.yaml":  true,
+		"/run/secrets-other/pass": false,
+		"/etc/passwd":             false,
+		"/run":                    false,
+	}
+	for path, want := range tests {
+		if got := PathAllowed(path, dirs); got != want {
+			t.Errorf("PathAllowed(%q) = %v, want %v", path, got, want)
+		}
+	}
+	if !PathAllowed("/etc/passwd", nil) {
+		t.Error("expected every path allowed without an allowlist")
+	}
+	if !PathAllowed("/etc/passwd", []string{"/"}) {
+		t.Error("expected every path allowed below /")
+	}
+}
+
+func TestWriteFile_AllowedPaths(t *testing.T) {
+	tmpDir := t.TempDir()
+	allowed := filepath.Join(tmpDir, "allowed")
+	outside := filepath.Join(tmpDir, "outside")
+	if err := os.MkdirAll(outside, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(allowed, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(allowed, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	writer := NewWriter().WithAllowedPaths([]string{allowed})
+	write := func(path string) error {
+		return writer.WriteFile(context.Background(), FileConfig{Path: path, Mode: 0600, Owner: -1, Group: -1}, "content")
+	}
+
+	if err := write(filepath.Join(allowed, "app", "key")); err != nil {
+		t.Errorf("expected a write below an allowed directory to succeed, got %v", err)
+	}
+	for _, path := range []string{filepath.Join(outside, "key"), filepath.Join(allowed, "link", "key"), filepath.Join(allowed, "link", "sub", "key")} {
+		if err := write(path); !errors.Is(err, ErrPathNotAllowed) {
+			t.Errorf("expected %s to be rejected, got %v", path, err)
+		}
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+		t.Errorf("expected nothing written outside the allowed directory, got %v", entries)
+	}
+
+	writer.WithAllowedPaths(nil)
+	if err := write(filepath.Join(outside, "key")); err != nil {
+		t.Errorf("expected every path allowed once the allowlist is cleared, got %v", err)
+	}
+}
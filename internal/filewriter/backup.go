@@ -0,0 +1,150 @@
+package filewriter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MaxBackups is the most previous versions kept of one file
+const MaxBackups = 100
+
+// ErrNoBackup is returned by ReadBackup for a file without backups
+var ErrNoBackup = errors.New("no backup")
+
+// BackupPath returns the path of the n-th previous version of a file, 1
+// being the latest
+func BackupPath(path string, n int) string {
+	return fmt.Sprintf("%s.bak.%d", path, n)
+}
+
+// Backups returns the paths of the backups of a file, latest first
+func Backups(path string) ([]string, error) {
+	numbers, err := backupNumbers(path)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(numbers))
+	for _, n := range numbers {
+		paths = append(paths, BackupPath(path, n))
+	}
+	return paths, nil
+}
+
+// backupNumbers returns the numbers of the backups of a file, ascending
+func backupNumbers(path string) ([]int, error) {
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	prefix := filepath.Base(path) + ".bak."
+	var numbers []int
+	for _, entry := range entries {
+		suffix, ok := strings.CutPrefix(entry.Name(), prefix)
+		if !ok || !entry.Type().IsRegular() {
+			continue
+		}
+		if n, err := strconv.Atoi(suffix); err == nil && n > 0 && strconv.Itoa(n) == suffix {
+			numbers = append(numbers, n)
+		}
+	}
+	sort.Ints(numbers)
+	return numbers, nil
+}
+
+// ReadBackup returns the content of the latest backup of a file
+func ReadBackup(path string) ([]byte, error) {
+	backup := BackupPath(path, 1)
+	if err := validateFileType(backup); err != nil {
+		return nil, fmt.Errorf("invalid backup: %w", err)
+	}
+	f, err := os.Open(backup)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w of %s", ErrNoBackup, path)
+		}
+		return nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	content, err := io.ReadAll(io.LimitReader(f, MaxSecretSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+	if len(content) > MaxSecretSize {
+		clear(content)
+		return nil, fmt.Errorf("backup %s exceeds maximum allowed size %d", backup, MaxSecretSize)
+	}
+	return content, nil
+}
+
+// RemoveBackups removes every backup of a file and returns their paths
+func RemoveBackups(path string) ([]string, error) {
+	backups, err := Backups(path)
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	var errs []error
+	for _, backup := range backups {
+		if err := os.Remove(backup); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, fmt.Errorf("failed to remove backup: %w", err))
+			continue
+		}
+		removed = append(removed, backup)
+	}
+	return removed, errors.Join(errs...)
+}
+
+// rotateBackups keeps the content of a file about to be replaced as its
+// latest backup, numbering older ones up and dropping those beyond keep.
+// The file stays in place: the backup is a hard link to it, or a copy with
+// the same mode where links are not possible.
+func (w *Writer) rotateBackups(path string, keep int) error {
+	info, err := os.Lstat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		// Nothing to back up yet
+		return nil
+	}
+
+	numbers, err := backupNumbers(path)
+	if err != nil {
+		return err
+	}
+	for i := len(numbers) - 1; i >= 0; i-- {
+		n := numbers[i]
+		if n >= keep {
+			if err := os.Remove(BackupPath(path, n)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove backup: %w", err)
+			}
+			continue
+		}
+		if err := os.Rename(BackupPath(path, n), BackupPath(path, n+1)); err != nil {
+			return fmt.Errorf("failed to rotate backup: %w", err)
+		}
+	}
+
+	latest := BackupPath(path, 1)
+	if err := os.Link(path, latest); err == nil {
+		return nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	defer clear(content)
+	if err := w.writeTemp(latest, content, info.Mode().Perm()); err != nil {
+		_ = os.Remove(latest)
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	return nil
+}
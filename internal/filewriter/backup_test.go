@@ -0,0 +1,104 @@
+package filewriter
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFile_KeepBackups(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "secret.txt")
+
+	writer := NewWriter()
+	config := FileConfig{Path: filePath, Mode: 0640, Owner: -1, Group: -1, KeepBackups: 2}
+	for _, content := range []string{"v1", "v2", "v2", "v3", "v4"} {
+		if err := writer.WriteFile(context.Background(), config, content); err != nil {
+			t.Fatalf("failed to write %s: %v", content, err)
+		}
+	}
+
+	want := map[string]string{filePath: "v4", BackupPath(filePath, 1): "v3", BackupPath(filePath, 2): "v2"}
+	for path, content := range want {
+		data, err := os.ReadFile(path)
+		if err != nil || string(data) != content {
+			t.Errorf("expected %s to hold %q, got %q (%v)", path, content, data, err)
+		}
+		if info, err := os.Stat(path); err == nil && info.Mode().Perm() != 0640 {
+			t.Errorf("expected %s to have mode 0640, got %o", path, info.Mode().Perm())
+		}
+	}
+	if _, err := os.Stat(BackupPath(filePath, 3)); !os.IsNotExist(err) {
+		t.Errorf("expected no backup beyond keepBackups, got %v", err)
+	}
+
+	backups, err := Backups(filePath)
+	if err != nil || len(backups) != 2 || backups[0] != BackupPath(filePath, 1) {
+		t.Errorf("expected 2 backups latest first, got %v (%v)", backups, err)
+	}
+}
+
+func TestWriteFile_KeepBackupsShrinks(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "secret.txt")
+	for n := 1; n <= 3; n++ {
+		if err := os.WriteFile(BackupPath(filePath, n), []byte("old"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filePath, []byte("current"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := FileConfig{Path: filePath, Mode: 0600, Owner: -1, Group: -1, KeepBackups: 1}
+	if err := NewWriter().WriteFile(context.Background(), config, "new"); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	backups, err := Backups(filePath)
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("expected a single backup, got %v (%v)", backups, err)
+	}
+	if content, err := ReadBackup(filePath); err != nil || string(content) != "current" {
+		t.Errorf("expected the replaced content as backup, got %q (%v)", content, err)
+	}
+}
+
+func TestBackups_IgnoresOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "secret.txt")
+	for _, name := range []string{"secret.txt.bak.1", "secret.txt.bak.01", "secret.txt.bak.x", "secret.txt.bak.0", "other.bak.1"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := Backups(filePath)
+	if err != nil || len(backups) != 1 || backups[0] != BackupPath(filePath, 1) {
+		t.Errorf("expected only secret.txt.bak.1, got %v (%v)", backups, err)
+	}
+}
+
+func TestReadBackup_Missing(t *testing.T) {
+	_, err := ReadBackup(filepath.Join(t.TempDir(), "secret.txt"))
+	if !errors.Is(err, ErrNoBackup) {
+		t.Errorf("expected ErrNoBackup, got %v", err)
+	}
+}
+
+func TestRemoveBackups(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "secret.txt")
+	for n := 1; n <= 2; n++ {
+		if err := os.WriteFile(BackupPath(filePath, n), []byte("old"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := RemoveBackups(filePath)
+	if err != nil || len(removed) != 2 {
+		t.Fatalf("expected 2 backups removed, got %v (%v)", removed, err)
+	}
+	if backups, _ := Backups(filePath); len(backups) != 0 {
+		t.Errorf("expected no backups left, got %v", backups)
+	}
+}
@@ -0,0 +1,25 @@
+package filewriter
+
+import "bytes"
+
+// FormatConcat joins several rendered templates into one file, e.g. a
+// certificate with its intermediate and root as a full-chain PEM
+const FormatConcat = "concat"
+
+// Concat joins the parts of a concat file in order. Without a separator a
+// part not ending in a newline gets one, so PEM blocks never run together;
+// with one, parts are joined by it as is.
+func Concat(parts [][]byte, separator *string) []byte {
+	if separator != nil {
+		return bytes.Join(parts, []byte(*separator))
+	}
+
+	var buf bytes.Buffer
+	for _, part := range parts {
+		buf.Write(part)
+		if len(part) > 0 && part[len(part)-1] != '\n' {
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,30 @@
+package filewriter
+
+import "testing"
+
+func TestConcat(t *testing.T) {
+	comma := ","
+	tests := []struct {
+		name      string
+		parts     []string
+		separator *string
+		want      string
+	}{
+		{"newline added", []string{"a", "b"}, nil, "a\nb\n"},
+		{"newline kept", []string{"a\n", "b\n"}, nil, "a\nb\n"},
+		{"empty part", []string{"a", "", "b"}, nil, "a\nb\n"},
+		{"separator", []string{"a", "b", "c"}, &comma, "a,b,c"},
+		{"separator verbatim", []string{"a\n", "b\n"}, &comma, "a\n,b\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := make([][]byte, 0, len(tt.parts))
+			for _, part := range tt.parts {
+				parts = append(parts, []byte(part))
+			}
+			if got := string(Concat(parts, tt.separator)); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
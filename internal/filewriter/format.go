@@ -0,0 +1,155 @@
+package filewriter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ohauer/secrets-sync/internal/errkind"
+)
+
+// Output formats writing a whole secret into one file
+const (
+	FormatJSON = "json" // One JSON object
+	FormatEnv  = "env"  // One KEY=value line per field, as read by dotenv
+)
+
+// envName matches the names accepted for variables in an env file
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// envSafe matches values written to an env file without quotes
+var envSafe = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,=-]*$`)
+
+// ValidFormat reports whether format names a supported output format
+func ValidFormat(format string) bool {
+	return format == FormatJSON || format == FormatEnv || format == FormatConcat || IsKeystoreFormat(format)
+}
+
+// RenderFormat renders the given fields of a secret, or every field if keys
+// is empty, in one of the output formats. A requested field missing from the
+// secret is an error.
+func RenderFormat(format string, data map[string]interface{}, keys []string) ([]byte, error) {
+	if len(keys) == 0 {
+		keys = make([]string, 0, len(data))
+		for key := range data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+	}
+
+	fields := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		value, ok := data[key]
+		if !ok {
+			return nil, errkind.Wrap(errkind.Template, fmt.Errorf("field %q not found in secret", key))
+		}
+		fields[key] = value
+	}
+
+	switch format {
+	case FormatJSON:
+		return renderJSON(fields)
+	case FormatEnv:
+		return renderEnv(keys, fields)
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// renderJSON writes the fields as an indented JSON object with sorted keys
+func renderJSON(fields map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(fields); err != nil {
+		return nil, errkind.Wrap(errkind.Template, fmt.Errorf("failed to encode JSON: %w", err))
+	}
+	return buf.Bytes(), nil
+}
+
+// renderEnv writes one KEY=value line per field, in the order of keys.
+// Values other than plain words are double-quoted with backslash escapes;
+// nested values are written as JSON.
+func renderEnv(keys []string, fields map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, key := range keys {
+		if !envName.MatchString(key) {
+			return nil, errkind.Wrap(errkind.Template, fmt.Errorf("field %q is not a valid environment variable name", key))
+		}
+
+		value, err := envValue(fields[key])
+		if err != nil {
+			return nil, errkind.Wrap(errkind.Template, fmt.Errorf("field %q: %w", key, err))
+		}
+		buf.WriteString(key)
+		buf.WriteByte('=')
+		buf.WriteString(quoteEnv(value))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// EnvVars returns the fields of a secret as NAME=value pairs for a process
+// environment, sorted by name. Values are not quoted; nested values are
+// written as JSON.
+func EnvVars(data map[string]interface{}) ([]string, error) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	env := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if !envName.MatchString(key) {
+			return nil, errkind.Wrap(errkind.Template, fmt.Errorf("field %q is not a valid environment variable name", key))
+		}
+		value, err := envValue(data[key])
+		if err != nil {
+			return nil, errkind.Wrap(errkind.Template, fmt.Errorf("field %q: %w", key, err))
+		}
+		env = append(env, key+"="+value)
+	}
+	return env, nil
+}
+
+// envValue formats a field value as a string
+func envValue(v interface{}) (string, error) {
+	switch value := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	case json.Number, bool, float64, int:
+		return fmt.Sprint(value), nil
+	default:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode JSON: %w", err)
+		}
+		return string(data), nil
+	}
+}
+
+// envEscaper escapes the characters with a meaning inside double quotes
+var envEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	`$`, `\$`,
+	"`", "\\`",
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+)
+
+// quoteEnv quotes a value unless it consists of plain word characters only
+func quoteEnv(value string) string {
+	if envSafe.MatchString(value) {
+		return value
+	}
+	return `"` + envEscaper.Replace(value) + `"`
+}
@@ -0,0 +1,114 @@
+package filewriter
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ohauer/secrets-sync/internal/errkind"
+)
+
+func TestRenderFormat(t *testing.T) {
+	data := map[string]interface{}{
+		"USERNAME": "admin",
+		"PASSWORD": `p@ss "word" $HOME`,
+		"CERT":     "line1\nline2",
+		"PORT":     json.Number("5432"),
+		"ENABLED":  true,
+		"OPTIONS":  map[string]interface{}{"ssl": "on"},
+		"EMPTY":    "",
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		keys   []string
+		want   string
+	}{
+		{
+			name:   "env all",
+			format: FormatEnv,
+			want: "CERT=\"line1\\nline2\"\n" +
+				"EMPTY=\n" +
+				"ENABLED=true\n" +
+				"OPTIONS=\"{\\\"ssl\\\":\\\"on\\\"}\"\n" +
+				"PASSWORD=\"p@ss \\\"word\\\" \\$HOME\"\n" +
+				"PORT=5432\n" +
+				"USERNAME=admin\n",
+		},
+		{
+			name:   "env keys in order",
+			format: FormatEnv,
+			keys:   []string{"USERNAME", "PORT"},
+			want:   "USERNAME=admin\nPORT=5432\n",
+		},
+		{
+			name:   "json keys",
+			format: FormatJSON,
+			keys:   []string{"USERNAME", "PORT", "OPTIONS"},
+			want:   "{\n  \"OPTIONS\": {\n    \"ssl\": \"on\"\n  },\n  \"PORT\": 5432,\n  \"USERNAME\": \"admin\"\n}\n",
+		},
+		{
+			name:   "json escaping",
+			format: FormatJSON,
+			keys:   []string{"PASSWORD"},
+			want:   "{\n  \"PASSWORD\": \"p@ss \\\"word\\\" $HOME\"\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderFormat(tt.format, data, tt.keys)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRenderFormat_Errors(t *testing.T) {
+	data := map[string]interface{}{"db-password": "secret", "USER": "admin"}
+
+	tests := []struct {
+		name   string
+		format string
+		keys   []string
+	}{
+		{name: "missing key", format: FormatJSON, keys: []string{"MISSING"}},
+		{name: "invalid env name", format: FormatEnv},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := RenderFormat(tt.format, data, tt.keys)
+			if !errors.Is(err, errkind.Template) {
+				t.Errorf("expected template error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestEnvVars(t *testing.T) {
+	env, err := EnvVars(map[string]interface{}{
+		"PASSWORD": `p@ss "word" $HOME`,
+		"PORT":     json.Number("5432"),
+		"OPTIONS":  map[string]interface{}{"ssl": "on"},
+		"EMPTY":    nil,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"EMPTY=", `OPTIONS={"ssl":"on"}`, `PASSWORD=p@ss "word" $HOME`, "PORT=5432"}
+	if strings.Join(env, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected %q, got %q", want, env)
+	}
+
+	if _, err := EnvVars(map[string]interface{}{"db-password": "secret"}); !errors.Is(err, errkind.Template) {
+		t.Errorf("expected template error for invalid name, got: %v", err)
+	}
+}
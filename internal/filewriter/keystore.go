@@ -0,0 +1,279 @@
+package filewriter
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf16"
+
+	"github.com/ohauer/secrets-sync/internal/errkind"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// Keystore formats, assembling a certificate and private key into one file
+const (
+	FormatPKCS12 = "pkcs12" // PKCS#12 with AES-256 and PBKDF2, read by Java 12+ and OpenSSL 1.1+
+	FormatJKS    = "jks"    // Java KeyStore, for Java versions older than PKCS#12 support
+)
+
+// Keystore is the PEM material and password of a keystore file
+type Keystore struct {
+	Certificate []byte // Leaf certificate, optionally followed by its chain
+	PrivateKey  []byte // PKCS#8, PKCS#1 or SEC 1 private key
+	Chain       []byte // Intermediate and CA certificates (optional)
+	Password    []byte
+	Alias       string // Entry name, for jks
+}
+
+// IsKeystoreFormat reports whether format writes a keystore
+func IsKeystoreFormat(format string) bool {
+	return format == FormatPKCS12 || format == FormatJKS
+}
+
+// RenderKeystore encodes the certificate, its chain and private key as a
+// keystore protected by the password. The output only depends on its input,
+// so an unchanged secret does not rewrite the file on every sync.
+func RenderKeystore(format string, ks Keystore) ([]byte, error) {
+	if len(ks.Password) == 0 {
+		return nil, errkind.Wrap(errkind.Template, errors.New("keystore password is empty"))
+	}
+
+	certs, err := parseCertificates(append(append([]byte{}, ks.Certificate...), ks.Chain...))
+	if err != nil {
+		return nil, errkind.Wrap(errkind.Template, err)
+	}
+	if len(certs) == 0 {
+		return nil, errkind.Wrap(errkind.Template, errors.New("keystore certificate holds no PEM certificate"))
+	}
+	key, err := parsePrivateKeyPEM(ks.PrivateKey)
+	if err != nil {
+		return nil, errkind.Wrap(errkind.Template, err)
+	}
+	if pub, ok := certs[0].PublicKey.(interface{ Equal(crypto.PublicKey) bool }); !ok || !pub.Equal(key.Public()) {
+		return nil, errkind.Wrap(errkind.Template, errors.New("keystore private key does not match the certificate"))
+	}
+
+	rand, err := contentRand(ks, certs, key)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case FormatPKCS12:
+		data, err := pkcs12.Modern2023.WithRand(rand).Encode(key, certs[0], certs[1:], string(ks.Password))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode PKCS#12: %w", err)
+		}
+		return data, nil
+	case FormatJKS:
+		return encodeJKS(rand, ks.Alias, key, certs, ks.Password)
+	default:
+		return nil, fmt.Errorf("unknown keystore format %q", format)
+	}
+}
+
+// parseCertificates parses every CERTIFICATE block, in order
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return certs, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid keystore certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+}
+
+// parsePrivateKeyPEM parses the first private key block
+func parsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("keystore private key holds no PEM private key")
+		}
+
+		var key interface{}
+		var err error
+		switch block.Type {
+		case "PRIVATE KEY":
+			key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "RSA PRIVATE KEY":
+			key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			key, err = x509.ParseECPrivateKey(block.Bytes)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid keystore private key: %w", err)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("unsupported keystore private key type")
+		}
+		return signer, nil
+	}
+}
+
+// contentRand returns the salts and IVs for encoding a keystore: an AES-CTR
+// stream keyed by a hash of the password and the material. Equal input gives
+// an equal file; an attacker learns no more than that the content repeated.
+func contentRand(ks Keystore, certs []*x509.Certificate, key crypto.Signer) (io.Reader, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+
+	h := sha256.New()
+	for _, part := range [][]byte{ks.Password, []byte(ks.Alias), der} {
+		_ = binary.Write(h, binary.BigEndian, uint32(len(part)))
+		h.Write(part)
+	}
+	for _, cert := range certs {
+		h.Write(cert.Raw)
+	}
+
+	block, err := aes.NewCipher(h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.StreamReader{S: cipher.NewCTR(block, make([]byte, aes.BlockSize)), R: zeroReader{}}, nil
+}
+
+// zeroReader reads zero bytes, the plaintext of a keystream
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// JKS format constants, see sun.security.provider.JavaKeyStore
+const (
+	jksMagic         = 0xfeedfeed
+	jksVersion       = 2
+	jksPrivateKeyTag = 1
+	jksIntegritySalt = "Mighty Aphrodite"
+)
+
+// oidJKSKeyProtector identifies the key protection of sun.security.provider.KeyProtector
+var oidJKSKeyProtector = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 42, 2, 17, 1, 1}
+
+// encodeJKS writes a JKS keystore with a single private key entry. The key
+// is protected with the store password, as keytool does by default.
+func encodeJKS(rand io.Reader, alias string, key crypto.Signer, certs []*x509.Certificate, password []byte) ([]byte, error) {
+	if alias == "" {
+		return nil, errkind.Wrap(errkind.Template, errors.New("keystore alias is empty"))
+	}
+	pass := jksPassword(password)
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+	protected, err := jksProtectKey(rand, der, pass)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	write := func(v interface{}) { _ = binary.Write(&buf, binary.BigEndian, v) }
+	writeUTF := func(s string) {
+		write(uint16(len(s)))
+		buf.WriteString(s)
+	}
+
+	write(uint32(jksMagic))
+	write(uint32(jksVersion))
+	write(uint32(1))
+
+	write(uint32(jksPrivateKeyTag))
+	writeUTF(alias)
+	// The leaf's start of validity keeps the output stable
+	write(certs[0].NotBefore.UnixMilli())
+	write(uint32(len(protected)))
+	buf.Write(protected)
+	write(uint32(len(certs)))
+	for _, cert := range certs {
+		writeUTF("X.509")
+		write(uint32(len(cert.Raw)))
+		buf.Write(cert.Raw)
+	}
+
+	digest := sha1.New()
+	digest.Write(pass)
+	digest.Write([]byte(jksIntegritySalt))
+	digest.Write(buf.Bytes())
+	buf.Write(digest.Sum(nil))
+	return buf.Bytes(), nil
+}
+
+// jksPassword encodes a password as Java chars, two bytes big-endian each
+func jksPassword(password []byte) []byte {
+	chars := utf16.Encode([]rune(string(password)))
+	out := make([]byte, 2*len(chars))
+	for i, c := range chars {
+		binary.BigEndian.PutUint16(out[2*i:], c)
+	}
+	return out
+}
+
+// jksProtectKey encrypts a PKCS#8 key the way KeyProtector does: XOR with a
+// SHA-1 chain seeded by a random salt, followed by a SHA-1 check over the
+// plain key, wrapped in an EncryptedPrivateKeyInfo
+func jksProtectKey(rand io.Reader, plain, pass []byte) ([]byte, error) {
+	salt := make([]byte, sha1.Size)
+	if _, err := io.ReadFull(rand, salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	encrypted := make([]byte, 0, 2*sha1.Size+len(plain))
+	encrypted = append(encrypted, salt...)
+	digest := salt
+	for i := 0; i < len(plain); i += sha1.Size {
+		h := sha1.New()
+		h.Write(pass)
+		h.Write(digest)
+		digest = h.Sum(nil)
+		for j := 0; j < sha1.Size && i+j < len(plain); j++ {
+			encrypted = append(encrypted, plain[i+j]^digest[j])
+		}
+	}
+	check := sha1.New()
+	check.Write(pass)
+	check.Write(plain)
+	encrypted = append(encrypted, check.Sum(nil)...)
+
+	info := struct {
+		Algorithm     pkix.AlgorithmIdentifier
+		EncryptedData []byte
+	}{
+		Algorithm:     pkix.AlgorithmIdentifier{Algorithm: oidJKSKeyProtector, Parameters: asn1.NullRawValue},
+		EncryptedData: encrypted,
+	}
+	der, err := asn1.Marshal(info)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode protected key: %w", err)
+	}
+	return der, nil
+}
@@ -0,0 +1,276 @@
+package filewriter
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/errkind"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// testKeyPair returns a PEM certificate signed by parent (self-signed if nil)
+// and its PEM private key
+func testKeyPair(t *testing.T, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, []byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:              time.Date(2034, 1, 1, 0, 0, 0, 0, time.UTC),
+		IsCA:                  parent == nil,
+		BasicConstraintsValid: true,
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	return cert, key,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+}
+
+// testKeystore returns a keystore with a leaf certificate and a CA chain
+func testKeystore(t *testing.T) Keystore {
+	t.Helper()
+	ca, caKey, caPEM, _ := testKeyPair(t, "test ca", nil, nil)
+	_, _, certPEM, keyPEM := testKeyPair(t, "app.example.com", ca, caKey)
+	return Keystore{
+		Certificate: certPEM,
+		PrivateKey:  keyPEM,
+		Chain:       caPEM,
+		Password:    []byte("changeit"),
+		Alias:       "app",
+	}
+}
+
+func TestRenderKeystore_PKCS12(t *testing.T) {
+	ks := testKeystore(t)
+
+	data, err := RenderKeystore(FormatPKCS12, ks)
+	if err != nil {
+		t.Fatalf("RenderKeystore failed: %v", err)
+	}
+
+	key, cert, chain, err := pkcs12.DecodeChain(data, "changeit")
+	if err != nil {
+		t.Fatalf("failed to decode PKCS#12: %v", err)
+	}
+	if cert.Subject.CommonName != "app.example.com" {
+		t.Errorf("expected leaf certificate, got %q", cert.Subject.CommonName)
+	}
+	if len(chain) != 1 || chain[0].Subject.CommonName != "test ca" {
+		t.Errorf("expected CA chain, got %d certificates", len(chain))
+	}
+	if !key.(*ecdsa.PrivateKey).PublicKey.Equal(cert.PublicKey) {
+		t.Error("private key does not match certificate")
+	}
+
+	again, err := RenderKeystore(FormatPKCS12, ks)
+	if err != nil {
+		t.Fatalf("RenderKeystore failed: %v", err)
+	}
+	if !bytes.Equal(data, again) {
+		t.Error("expected equal input to render an equal keystore")
+	}
+
+	ks.Password = []byte("other")
+	changed, err := RenderKeystore(FormatPKCS12, ks)
+	if err != nil {
+		t.Fatalf("RenderKeystore failed: %v", err)
+	}
+	if bytes.Equal(data, changed) {
+		t.Error("expected a new password to render a different keystore")
+	}
+}
+
+func TestRenderKeystore_JKS(t *testing.T) {
+	ks := testKeystore(t)
+
+	data, err := RenderKeystore(FormatJKS, ks)
+	if err != nil {
+		t.Fatalf("RenderKeystore failed: %v", err)
+	}
+
+	pass := jksPassword(ks.Password)
+	body, sum := data[:len(data)-sha1.Size], data[len(data)-sha1.Size:]
+	digest := sha1.New()
+	digest.Write(pass)
+	digest.Write([]byte(jksIntegritySalt))
+	digest.Write(body)
+	if !bytes.Equal(sum, digest.Sum(nil)) {
+		t.Fatal("keystore integrity digest does not match")
+	}
+
+	r := bytes.NewReader(body)
+	read := func(v interface{}) {
+		if err := binary.Read(r, binary.BigEndian, v); err != nil {
+			t.Fatalf("truncated keystore: %v", err)
+		}
+	}
+	readBytes := func(n int) []byte {
+		b := make([]byte, n)
+		if _, err := r.Read(b); err != nil {
+			t.Fatalf("truncated keystore: %v", err)
+		}
+		return b
+	}
+
+	var magic, version, count, tag uint32
+	read(&magic)
+	read(&version)
+	read(&count)
+	read(&tag)
+	if magic != jksMagic || version != jksVersion || count != 1 || tag != jksPrivateKeyTag {
+		t.Fatalf("unexpected header %x %d %d %d", magic, version, count, tag)
+	}
+
+	var aliasLen uint16
+	read(&aliasLen)
+	if alias := string(readBytes(int(aliasLen))); alias != "app" {
+		t.Errorf("expected alias app, got %q", alias)
+	}
+	var created int64
+	read(&created)
+
+	var keyLen uint32
+	read(&keyLen)
+	var info struct {
+		Algorithm     pkix.AlgorithmIdentifier
+		EncryptedData []byte
+	}
+	if _, err := asn1.Unmarshal(readBytes(int(keyLen)), &info); err != nil {
+		t.Fatalf("invalid protected key: %v", err)
+	}
+	if !info.Algorithm.Algorithm.Equal(oidJKSKeyProtector) {
+		t.Errorf("unexpected key protection %v", info.Algorithm.Algorithm)
+	}
+
+	// Reverse the key protection to recover the PKCS#8 key
+	enc := info.EncryptedData
+	salt, cipherText, check := enc[:sha1.Size], enc[sha1.Size:len(enc)-sha1.Size], enc[len(enc)-sha1.Size:]
+	plain := make([]byte, 0, len(cipherText))
+	stream := salt
+	for i := 0; i < len(cipherText); i += sha1.Size {
+		h := sha1.New()
+		h.Write(pass)
+		h.Write(stream)
+		stream = h.Sum(nil)
+		for j := 0; j < sha1.Size && i+j < len(cipherText); j++ {
+			plain = append(plain, cipherText[i+j]^stream[j])
+		}
+	}
+	h := sha1.New()
+	h.Write(pass)
+	h.Write(plain)
+	if !bytes.Equal(check, h.Sum(nil)) {
+		t.Fatal("protected key check digest does not match")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(plain)
+	if err != nil {
+		t.Fatalf("failed to parse recovered key: %v", err)
+	}
+
+	var certCount uint32
+	read(&certCount)
+	if certCount != 2 {
+		t.Fatalf("expected 2 certificates, got %d", certCount)
+	}
+	var certs []*x509.Certificate
+	for range certCount {
+		var typeLen uint16
+		read(&typeLen)
+		if certType := string(readBytes(int(typeLen))); certType != "X.509" {
+			t.Errorf("unexpected certificate type %q", certType)
+		}
+		var certLen uint32
+		read(&certLen)
+		cert, err := x509.ParseCertificate(readBytes(int(certLen)))
+		if err != nil {
+			t.Fatalf("invalid certificate: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+	if r.Len() != 0 {
+		t.Errorf("unexpected %d trailing bytes", r.Len())
+	}
+	if certs[0].Subject.CommonName != "app.example.com" || certs[1].Subject.CommonName != "test ca" {
+		t.Errorf("unexpected certificate order %q, %q", certs[0].Subject.CommonName, certs[1].Subject.CommonName)
+	}
+	if created != certs[0].NotBefore.UnixMilli() {
+		t.Errorf("expected creation date %d, got %d", certs[0].NotBefore.UnixMilli(), created)
+	}
+	if !key.(*ecdsa.PrivateKey).PublicKey.Equal(certs[0].PublicKey) {
+		t.Error("recovered key does not match certificate")
+	}
+
+	again, err := RenderKeystore(FormatJKS, ks)
+	if err != nil {
+		t.Fatalf("RenderKeystore failed: %v", err)
+	}
+	if !bytes.Equal(data, again) {
+		t.Error("expected equal input to render an equal keystore")
+	}
+}
+
+func TestRenderKeystore_Errors(t *testing.T) {
+	ks := testKeystore(t)
+	_, _, _, otherKey := testKeyPair(t, "other", nil, nil)
+
+	tests := []struct {
+		name   string
+		format string
+		modify func(*Keystore)
+		want   string
+	}{
+		{"empty password", FormatPKCS12, func(k *Keystore) { k.Password = nil }, "keystore password is empty"},
+		{"no certificate", FormatPKCS12, func(k *Keystore) { k.Certificate, k.Chain = []byte("not pem"), nil }, "keystore certificate holds no PEM certificate"},
+		{"no private key", FormatPKCS12, func(k *Keystore) { k.PrivateKey = k.Certificate }, "keystore private key holds no PEM private key"},
+		{"mismatched key", FormatJKS, func(k *Keystore) { k.PrivateKey = otherKey }, "keystore private key does not match the certificate"},
+		{"empty jks alias", FormatJKS, func(k *Keystore) { k.Alias = "" }, "keystore alias is empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := ks
+			tt.modify(&k)
+			_, err := RenderKeystore(tt.format, k)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if err.Error() != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, err.Error())
+			}
+			if !errors.Is(err, errkind.Template) {
+				t.Errorf("expected template error kind, got %v", err)
+			}
+		})
+	}
+}
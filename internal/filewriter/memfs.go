@@ -0,0 +1,39 @@
+package filewriter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotMemoryBacked is returned for a file that must be written to tmpfs
+// or ramfs but whose directory is on another filesystem
+var ErrNotMemoryBacked = errors.New("not on a memory-backed filesystem (tmpfs or ramfs)")
+
+// CheckMemoryBacked returns an error wrapping ErrNotMemoryBacked unless dir
+// is on tmpfs or ramfs. A missing dir is judged by its nearest existing
+// parent, which it would be created on.
+func CheckMemoryBacked(dir string) error {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to stat %s: %w", dir, err)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return fmt.Errorf("no existing parent directory for %s", dir)
+		}
+		dir = parent
+	}
+
+	name, memory, err := filesystemType(dir)
+	if err != nil {
+		return fmt.Errorf("failed to determine the filesystem of %s: %w", dir, err)
+	}
+	if !memory {
+		return fmt.Errorf("%s is %w, it is on %s", dir, ErrNotMemoryBacked, name)
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+//go:build freebsd || darwin
+// +build freebsd darwin
+
+package filewriter
+
+import (
+	"bytes"
+
+	"golang.org/x/sys/unix"
+)
+
+// filesystemType returns the name of the filesystem holding path and
+// whether it keeps its files in memory only
+func filesystemType(path string) (string, bool, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return "", false, err
+	}
+	name := string(bytes.TrimRight(st.Fstypename[:], "\x00"))
+	return name, name == "tmpfs", nil
+}
@@ -0,0 +1,36 @@
+//go:build linux
+// +build linux
+
+package filewriter
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// filesystemNames names common filesystems by their statfs magic number
+var filesystemNames = map[int64]string{
+	unix.TMPFS_MAGIC:           "tmpfs",
+	unix.RAMFS_MAGIC:           "ramfs",
+	unix.EXT4_SUPER_MAGIC:      "ext4",
+	unix.XFS_SUPER_MAGIC:       "xfs",
+	unix.BTRFS_SUPER_MAGIC:     "btrfs",
+	unix.OVERLAYFS_SUPER_MAGIC: "overlayfs",
+	unix.NFS_SUPER_MAGIC:       "nfs",
+}
+
+// filesystemType returns the name of the filesystem holding path and
+// whether it keeps its files in memory only
+func filesystemType(path string) (string, bool, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return "", false, err
+	}
+	fsType := int64(st.Type)
+	name, ok := filesystemNames[fsType]
+	if !ok {
+		name = fmt.Sprintf("filesystem type %#x", fsType)
+	}
+	return name, fsType == unix.TMPFS_MAGIC || fsType == unix.RAMFS_MAGIC, nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !freebsd && !darwin
+// +build !linux,!freebsd,!darwin
+
+package filewriter
+
+import "fmt"
+
+// filesystemType is not available on this platform
+func filesystemType(path string) (string, bool, error) {
+	return "", false, fmt.Errorf("filesystem types are only supported on Linux, FreeBSD and macOS")
+}
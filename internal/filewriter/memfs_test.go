@@ -0,0 +1,44 @@
+package filewriter
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCheckMemoryBacked(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("filesystem magic numbers are Linux specific")
+	}
+	if _, memory, err := filesystemType("/dev/shm"); err != nil || !memory {
+		t.Skip("/dev/shm is not a tmpfs here")
+	}
+
+	if err := CheckMemoryBacked("/dev/shm"); err != nil {
+		t.Errorf("expected /dev/shm to be memory-backed, got %v", err)
+	}
+	// Missing directories are judged by their nearest existing parent
+	if err := CheckMemoryBacked("/dev/shm/secrets-sync-missing/app"); err != nil {
+		t.Errorf("expected a missing directory on tmpfs to be accepted, got %v", err)
+	}
+}
+
+func TestWriteFile_RequireTmpfs(t *testing.T) {
+	dir := t.TempDir()
+	if _, memory, err := filesystemType(dir); err != nil || memory {
+		t.Skip("the test directory is not on a persistent filesystem")
+	}
+
+	filePath := filepath.Join(dir, "secret.txt")
+	config := FileConfig{Path: filePath, Mode: 0600, Owner: -1, Group: -1, RequireTmpfs: true}
+	err := NewWriter().WriteFile(context.Background(), config, "content")
+	if !errors.Is(err, ErrNotMemoryBacked) {
+		t.Fatalf("expected ErrNotMemoryBacked, got %v", err)
+	}
+	if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+		t.Errorf("expected nothing written, got %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+//go:build linux
+// +build linux
+
+package filewriter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// selinuxXattr is the extended attribute holding the SELinux context
+const selinuxXattr = "security.selinux"
+
+// SELinuxContext returns the SELinux context of path, without following a
+// symlink. It is empty if the file has none or the filesystem does not
+// support labels.
+func SELinuxContext(path string) (string, error) {
+	for {
+		size, err := unix.Lgetxattr(path, selinuxXattr, nil)
+		if errors.Is(err, unix.ENODATA) || errors.Is(err, unix.ENOTSUP) {
+			return "", nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read SELinux context of %s: %w", path, err)
+		}
+
+		buf := make([]byte, size)
+		n, err := unix.Lgetxattr(path, selinuxXattr, buf)
+		if errors.Is(err, unix.ERANGE) {
+			// Relabelled between the two calls
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read SELinux context of %s: %w", path, err)
+		}
+		return strings.TrimRight(string(buf[:n]), "\x00"), nil
+	}
+}
+
+// SetSELinuxContext labels path, without following a symlink
+func SetSELinuxContext(path, context string) error {
+	if err := unix.Lsetxattr(path, selinuxXattr, []byte(context), 0); err != nil {
+		if errors.Is(err, unix.ENOTSUP) {
+			return fmt.Errorf("failed to set SELinux context of %s: the filesystem does not support labels: %w", path, err)
+		}
+		return fmt.Errorf("failed to set SELinux context of %s: %w", path, err)
+	}
+	return nil
+}
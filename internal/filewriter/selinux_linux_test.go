@@ -0,0 +1,44 @@
+//go:build linux
+// +build linux
+
+package filewriter
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteBytes_SELinuxContext(t *testing.T) {
+	const label = "system_u:object_r:container_file_t:s0"
+
+	dir := t.TempDir()
+	probe := filepath.Join(dir, "probe")
+	writer := NewWriter()
+	if err := writer.WriteFile(context.Background(), FileConfig{Path: probe, Mode: 0600, Owner: -1, Group: -1}, "x"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetSELinuxContext(probe, label); err != nil {
+		t.Skipf("SELinux labels cannot be set here: %v", err)
+	}
+
+	filePath := filepath.Join(dir, "sub", "secret")
+	config := FileConfig{Path: filePath, Mode: 0600, Owner: -1, Group: -1, SELinuxContext: label}
+	if err := writer.WriteFile(context.Background(), config, "content"); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	for _, path := range []string{filePath, filepath.Dir(filePath)} {
+		if got, err := SELinuxContext(path); err != nil || got != label {
+			t.Errorf("expected %s labelled %s, got %q (%v)", path, label, got, err)
+		}
+	}
+
+	// A rewrite without a context keeps the one of the file it replaces
+	config.SELinuxContext = ""
+	if err := writer.WriteFile(context.Background(), config, "changed"); err != nil {
+		t.Fatalf("failed to rewrite file: %v", err)
+	}
+	if got, _ := SELinuxContext(filePath); got != label {
+		t.Errorf("expected context %s kept, got %q", label, got)
+	}
+}
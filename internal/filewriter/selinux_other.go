@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package filewriter
+
+import "fmt"
+
+// SELinuxContext returns an empty context, SELinux exists on Linux only
+func SELinuxContext(path string) (string, error) {
+	return "", nil
+}
+
+// SetSELinuxContext is not available on this platform
+func SetSELinuxContext(path, context string) error {
+	return fmt.Errorf("failed to set SELinux context of %s: SELinux is only supported on Linux", path)
+}
@@ -1,13 +1,24 @@
 package filewriter
 
 import (
+	"bytes"
+	"context"
 	"crypto/rand"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/user"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
+
+	"github.com/ohauer/secrets-sync/internal/errkind"
 )
 
 const (
@@ -15,24 +26,141 @@ const (
 	MaxSecretSize = 1 * 1024 * 1024
 )
 
+// DefaultDirMode is the mode of directories created for a file unless
+// FileConfig.Dir sets one
+const DefaultDirMode os.FileMode = 0755
+
+// DirConfig holds the attributes of the directory a file is written to
+type DirConfig struct {
+	Mode  os.FileMode // 0 for unset, DefaultDirMode for new directories
+	Owner int         // -1 for unset
+	Group int         // -1 for unset
+}
+
 // FileConfig holds file writing configuration
 type FileConfig struct {
 	Path  string
 	Mode  os.FileMode
 	Owner int
 	Group int
+
+	// Dir, if set, gives the missing directories of the file their mode and
+	// ownership and brings the existing parent in line. Without it they are
+	// created with DefaultDirMode, owned by the process.
+	Dir *DirConfig
+
+	// SELinuxContext labels the file and the directories created for it.
+	// If empty, a rewritten file keeps the context of the one it replaces.
+	SELinuxContext string
+
+	// KeepBackups is how many previous versions of the file are kept as
+	// BackupPath(Path, 1..KeepBackups) when it is replaced, 0 for none
+	KeepBackups int
+
+	// RequireTmpfs refuses the write unless the directory of the file is on
+	// tmpfs or ramfs, so the content never reaches a persistent disk
+	RequireTmpfs bool
 }
 
 // Writer handles atomic file writing
-type Writer struct{}
+type Writer struct {
+	noSync  bool                     // Skip fsync, see WithSync
+	allowed atomic.Pointer[[]string] // Directories files may be written to, see WithAllowedPaths
+}
 
 // NewWriter creates a new file writer
 func NewWriter() *Writer {
 	return &Writer{}
 }
 
+// WithSync sets whether writes are made durable (default: true): the
+// temporary file is flushed to disk before it is renamed into place, and the
+// directory after, so a crash leaves either the old or the new content.
+// Turning it off saves the disk flushes on tmpfs, where nothing survives a
+// crash anyway.
+func (w *Writer) WithSync(enabled bool) *Writer {
+	w.noSync = !enabled
+	return w
+}
+
 // WriteFile writes content to a file atomically
-func (w *Writer) WriteFile(config FileConfig, content string) error {
+func (w *Writer) WriteFile(ctx context.Context, config FileConfig, content string) error {
+	return w.WriteBytes(ctx, config, []byte(content))
+}
+
+// WriteBytes writes content to a file atomically without copying it, so the
+// caller can wipe the buffer once the write returns. Errors are classified as
+// errkind.Filesystem.
+//
+// ctx is checked between the steps of a write: a system call already in
+// progress cannot be interrupted, but once it returns a cancelled write
+// removes its temporary file and leaves the target untouched.
+func (w *Writer) WriteBytes(ctx context.Context, config FileConfig, content []byte) error {
+	err := w.writeBytes(ctx, config, content)
+	if ctxErr := ctx.Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+		return err
+	}
+	return errkind.Wrap(errkind.Filesystem, err)
+}
+
+// WriteBytesIfChanged writes content like WriteBytes, unless the file already
+// holds it with the configured mode and ownership. It reports whether the
+// file was written.
+func (w *Writer) WriteBytesIfChanged(ctx context.Context, config FileConfig, content []byte) (bool, error) {
+	if unchanged(config, content) {
+		return false, nil
+	}
+	return true, w.WriteBytes(ctx, config, content)
+}
+
+// unchanged reports whether config.Path is a regular file holding content
+// with the configured mode and ownership. Any error counts as a change, so
+// the write goes ahead and reports it.
+func unchanged(config FileConfig, content []byte) bool {
+	info, err := os.Lstat(config.Path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() != int64(len(content)) {
+		return false
+	}
+	if info.Mode().Perm() != config.Mode.Perm() {
+		return false
+	}
+	if config.SELinuxContext != "" {
+		if context, err := SELinuxContext(config.Path); err != nil || context != config.SELinuxContext {
+			return false
+		}
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		if config.Owner >= 0 && int(stat.Uid) != config.Owner {
+			return false
+		}
+		if config.Group >= 0 && int(stat.Gid) != config.Group {
+			return false
+		}
+	}
+
+	f, err := os.Open(config.Path)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = f.Close() }()
+
+	// The buffer holds secret content, wipe it once hashed
+	buf := make([]byte, 32*1024)
+	defer clear(buf)
+	h := sha256.New()
+	if _, err := io.CopyBuffer(h, f, buf); err != nil {
+		return false
+	}
+
+	want := sha256.Sum256(content)
+	return bytes.Equal(h.Sum(nil), want[:])
+}
+
+func (w *Writer) writeBytes(ctx context.Context, config FileConfig, content []byte) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("write cancelled: %w", err)
+	}
+
 	// Validate content size
 	if len(content) > MaxSecretSize {
 		return fmt.Errorf("content size %d exceeds maximum allowed size %d", len(content), MaxSecretSize)
@@ -48,28 +176,50 @@ func (w *Writer) WriteFile(config FileConfig, content string) error {
 		return fmt.Errorf("invalid file type: %w", err)
 	}
 
-	if err := w.ensureDir(filepath.Dir(config.Path)); err != nil {
+	if err := w.checkAllowed(config.Path); err != nil {
 		return err
 	}
+	if config.RequireTmpfs {
+		if err := CheckMemoryBacked(filepath.Dir(config.Path)); err != nil {
+			return err
+		}
+	}
+
+	if err := w.ensureDir(filepath.Dir(config.Path), config); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("write cancelled: %w", err)
+	}
 
 	tmpFile := config.Path + ".tmp." + randomString(8)
 
-	if err := os.WriteFile(tmpFile, []byte(content), config.Mode); err != nil {
-		return fmt.Errorf("failed to write temp file: %w", err)
+	if err := w.writeTemp(tmpFile, content, config.Mode); err != nil {
+		_ = os.Remove(tmpFile)
+		return err
 	}
 
-	if config.Owner >= 0 || config.Group >= 0 {
-		uid := config.Owner
-		gid := config.Group
-		if uid < 0 {
-			uid = -1
-		}
-		if gid < 0 {
-			gid = -1
+	if err := chown(tmpFile, config.Owner, config.Group); err != nil {
+		_ = os.Remove(tmpFile)
+		return err
+	}
+	if config.SELinuxContext != "" {
+		if err := SetSELinuxContext(tmpFile, config.SELinuxContext); err != nil {
+			_ = os.Remove(tmpFile)
+			return err
 		}
-		if err := os.Chown(tmpFile, uid, gid); err != nil {
+	}
+	keepAttributes(tmpFile, config)
+
+	if err := ctx.Err(); err != nil {
+		_ = os.Remove(tmpFile)
+		return fmt.Errorf("write cancelled: %w", err)
+	}
+
+	if config.KeepBackups > 0 {
+		if err := w.rotateBackups(config.Path, config.KeepBackups); err != nil {
 			_ = os.Remove(tmpFile)
-			return fmt.Errorf("failed to set ownership: %w", err)
+			return err
 		}
 	}
 
@@ -78,6 +228,57 @@ func (w *Writer) WriteFile(config FileConfig, content string) error {
 		return fmt.Errorf("failed to rename temp file: %w", err)
 	}
 
+	if !w.noSync {
+		if err := syncDir(filepath.Dir(config.Path)); err != nil {
+			return fmt.Errorf("file written, but the rename may not survive a crash: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// writeTemp writes content to a new temporary file with exactly mode, not
+// lowered by the umask, flushing it to disk unless syncing is off
+func (w *Writer) writeTemp(path string, content []byte, mode os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := f.Chmod(mode); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to set permissions on temp file: %w", err)
+	}
+	if _, err := f.Write(content); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if !w.noSync {
+		if err := f.Sync(); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("failed to sync temp file: %w", err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	return nil
+}
+
+// syncDir flushes a directory to disk, so entries created or renamed in it
+// persist. Filesystems that cannot sync directories are not an error.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		// Directories cannot be opened for syncing; NTFS journals renames
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open directory %s: %w", dir, err)
+	}
+	defer func() { _ = d.Close() }()
+	if err := d.Sync(); err != nil && !errors.Is(err, syscall.EINVAL) && !errors.Is(err, syscall.ENOTSUP) {
+		return fmt.Errorf("failed to sync directory %s: %w", dir, err)
+	}
 	return nil
 }
 
@@ -138,18 +339,156 @@ func validateFileType(path string) error {
 	return nil
 }
 
-func (w *Writer) ensureDir(dir string) error {
+// chown sets the ownership of a new file or directory, -1 leaving the owner
+// or group unchanged
+func chown(path string, owner, group int) error {
+	uid, gid := owner, group
+	// New files already belong to the current user and group; skipping those
+	// lets an unprivileged process without CAP_CHOWN write them
+	if uid < 0 || uid == os.Geteuid() {
+		uid = -1
+	}
+	if gid < 0 || gid == os.Getegid() {
+		gid = -1
+	}
+	if uid < 0 && gid < 0 {
+		return nil
+	}
+	if err := os.Chown(path, uid, gid); err != nil {
+		if os.IsPermission(err) {
+			return fmt.Errorf("failed to set ownership to %d:%d (requires root or CAP_CHOWN): %w", owner, group, err)
+		}
+		return fmt.Errorf("failed to set ownership: %w", err)
+	}
+	return nil
+}
+
+// keepAttributes gives a temporary file the owner, group and SELinux context
+// of the file it replaces where config leaves them unset, so a rewrite keeps
+// what was set with chown or chcon. It is best effort: a process that may not
+// change them writes the file as its own, with the default context.
+func keepAttributes(tmpFile string, config FileConfig) {
+	info, err := os.Lstat(config.Path)
+	if err != nil || !info.Mode().IsRegular() {
+		return
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		if config.Owner < 0 && int(stat.Uid) != os.Geteuid() {
+			_ = os.Lchown(tmpFile, int(stat.Uid), -1)
+		}
+		if config.Group < 0 && int(stat.Gid) != os.Getegid() {
+			_ = os.Lchown(tmpFile, -1, int(stat.Gid))
+		}
+	}
+	if config.SELinuxContext == "" {
+		if context, err := SELinuxContext(config.Path); err == nil && context != "" {
+			if current, err := SELinuxContext(tmpFile); err == nil && current != context {
+				_ = SetSELinuxContext(tmpFile, context)
+			}
+		}
+	}
+}
+
+// ensureDir creates the missing directories of dir, top down, with the
+// directory mode and ownership of config and its SELinux context. With
+// config.Dir set, an existing dir is brought in line with it.
+func (w *Writer) ensureDir(dir string, config FileConfig) error {
 	if dir == "" || dir == "." {
 		return nil
 	}
 
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+	attrs := DirConfig{Mode: DefaultDirMode, Owner: -1, Group: -1}
+	if config.Dir != nil {
+		attrs = *config.Dir
+		if attrs.Mode == 0 {
+			attrs.Mode = DefaultDirMode
+		}
+	}
+
+	var missing []string
+	for d := dir; ; d = filepath.Dir(d) {
+		info, err := os.Stat(d)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("failed to create directory: %s is not a directory", d)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		missing = append(missing, d)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+
+	if len(missing) == 0 {
+		if config.Dir == nil {
+			return nil
+		}
+		return applyDirAttributes(dir, *config.Dir)
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		d := missing[i]
+		if err := os.Mkdir(d, attrs.Mode.Perm()); err != nil {
+			if os.IsExist(err) {
+				// Created concurrently, by another secret
+				continue
+			}
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		// Mkdir applies the umask, a configured mode is set as it is
+		if config.Dir != nil && config.Dir.Mode != 0 {
+			if err := os.Chmod(d, attrs.Mode.Perm()); err != nil {
+				return fmt.Errorf("failed to set mode of directory %s: %w", d, err)
+			}
+		}
+		if err := chown(d, attrs.Owner, attrs.Group); err != nil {
+			return fmt.Errorf("directory %s: %w", d, err)
+		}
+		if config.SELinuxContext != "" {
+			if err := SetSELinuxContext(d, config.SELinuxContext); err != nil {
+				return err
+			}
+		}
+		if !w.noSync {
+			if err := syncDir(filepath.Dir(d)); err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
 }
 
+// applyDirAttributes sets the mode and ownership attrs set on an existing
+// directory, where they differ
+func applyDirAttributes(dir string, attrs DirConfig) error {
+	mode, uid, gid, err := GetFileInfo(dir)
+	if err != nil {
+		return fmt.Errorf("failed to stat directory: %w", err)
+	}
+	if attrs.Mode != 0 && mode.Perm() != attrs.Mode.Perm() {
+		if err := os.Chmod(dir, attrs.Mode.Perm()); err != nil {
+			return fmt.Errorf("failed to set mode of directory %s: %w", dir, err)
+		}
+	}
+	owner, group := attrs.Owner, attrs.Group
+	if owner == uid {
+		owner = -1
+	}
+	if group == gid {
+		group = -1
+	}
+	if owner >= 0 || group >= 0 {
+		if err := os.Chown(dir, owner, group); err != nil {
+			return fmt.Errorf("failed to set ownership of directory %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
 // ParseMode parses a mode string (e.g., "0600") to os.FileMode
 func ParseMode(mode string) (os.FileMode, error) {
 	if mode == "" {
@@ -194,18 +533,85 @@ func validateMode(mode os.FileMode) error {
 	return nil
 }
 
-// ParseOwner parses owner/group string to int
+// ParseDirMode parses a directory mode string (e.g., "0750") to
+// os.FileMode, DefaultDirMode if empty
+func ParseDirMode(mode string) (os.FileMode, error) {
+	if mode == "" {
+		return DefaultDirMode, nil
+	}
+
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid mode: %w", err)
+	}
+	if m&^uint64(os.ModePerm) != 0 {
+		return 0, fmt.Errorf("only permission bits are allowed, got 0%o", m)
+	}
+
+	dirMode := os.FileMode(m)
+	if dirMode&0002 != 0 {
+		return 0, fmt.Errorf("world-writable permissions (0%o) are not allowed", dirMode)
+	}
+	if dirMode&0100 == 0 {
+		return 0, fmt.Errorf("permissions (0%o) do not let the owner enter the directory", dirMode)
+	}
+
+	return dirMode, nil
+}
+
+// ValidateSELinuxContext checks that context has the form
+// user:role:type[:level]
+func ValidateSELinuxContext(context string) error {
+	if strings.ContainsFunc(context, func(r rune) bool { return r <= ' ' || r == 0x7f }) {
+		return fmt.Errorf("SELinux context %q contains whitespace or control characters", context)
+	}
+	parts := strings.SplitN(context, ":", 4)
+	if len(parts) < 3 || slices.Contains(parts, "") {
+		return fmt.Errorf("SELinux context %q must have the form user:role:type[:level]", context)
+	}
+	return nil
+}
+
+// ParseOwner parses a numeric UID or a user name to a UID
 func ParseOwner(owner string) (int, error) {
 	if owner == "" {
 		return -1, nil
 	}
 
-	o, err := strconv.Atoi(owner)
+	if o, err := strconv.Atoi(owner); err == nil {
+		return o, nil
+	}
+
+	u, err := user.Lookup(owner)
 	if err != nil {
-		return -1, fmt.Errorf("invalid owner: %w", err)
+		var unknown user.UnknownUserError
+		if errors.As(err, &unknown) {
+			return -1, fmt.Errorf("user %q does not exist on this system", owner)
+		}
+		return -1, fmt.Errorf("failed to look up user %q: %w", owner, err)
 	}
+	return strconv.Atoi(u.Uid)
+}
 
-	return o, nil
+// ParseGroup parses a numeric GID or a group name to a GID
+func ParseGroup(group string) (int, error) {
+	if group == "" {
+		return -1, nil
+	}
+
+	if g, err := strconv.Atoi(group); err == nil {
+		return g, nil
+	}
+
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		var unknown user.UnknownGroupError
+		if errors.As(err, &unknown) {
+			return -1, fmt.Errorf("group %q does not exist on this system", group)
+		}
+		return -1, fmt.Errorf("failed to look up group %q: %w", group, err)
+	}
+	return strconv.Atoi(g.Gid)
 }
 
 // GetFileInfo returns file information
@@ -1,10 +1,17 @@
 package filewriter
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/user"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+
+	"github.com/ohauer/secrets-sync/internal/errkind"
 )
 
 func TestWriteFile_Success(t *testing.T) {
@@ -20,7 +27,7 @@ func TestWriteFile_Success(t *testing.T) {
 	}
 
 	content := "test content"
-	if err := writer.WriteFile(config, content); err != nil {
+	if err := writer.WriteFile(context.Background(), config, content); err != nil {
 		t.Fatalf("failed to write file: %v", err)
 	}
 
@@ -34,6 +41,102 @@ func TestWriteFile_Success(t *testing.T) {
 	}
 }
 
+func TestWriteBytes_Success(t *testing.T) {
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "test.txt")
+
+	writer := NewWriter()
+	config := FileConfig{
+		Path:  filePath,
+		Mode:  0600,
+		Owner: -1,
+		Group: -1,
+	}
+
+	content := []byte("test content")
+	if err := writer.WriteBytes(context.Background(), config, content); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	// Wiping the caller's buffer must not affect the written file
+	for i := range content {
+		content[i] = 0
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+
+	if string(data) != "test content" {
+		t.Errorf("expected 'test content', got '%s'", string(data))
+	}
+}
+
+func TestWriteBytes_ErrorKind(t *testing.T) {
+	writer := NewWriter()
+	err := writer.WriteBytes(context.Background(), FileConfig{Path: "relative/path", Mode: 0600, Owner: -1, Group: -1}, []byte("x"))
+	if !errors.Is(err, errkind.Filesystem) {
+		t.Errorf("expected filesystem error kind, got %v", err)
+	}
+}
+
+func TestWriteBytes_Cancelled(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "test.txt")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	writer := NewWriter()
+	err := writer.WriteBytes(ctx, FileConfig{Path: filePath, Mode: 0600, Owner: -1, Group: -1}, []byte("x"))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if errors.Is(err, errkind.Filesystem) {
+		t.Error("expected a cancelled write not to be classified as a filesystem error")
+	}
+	if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+		t.Errorf("expected no file after cancelled write, got %v", err)
+	}
+}
+
+func TestWriteBytesIfChanged(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "test.txt")
+	writer := NewWriter()
+	config := FileConfig{Path: filePath, Mode: 0600, Owner: -1, Group: -1}
+
+	steps := []struct {
+		name    string
+		content string
+		mode    os.FileMode
+		written bool
+	}{
+		{"missing file", "v1", 0600, true},
+		{"same content", "v1", 0600, false},
+		{"changed content", "v2", 0600, true},
+		{"changed mode", "v2", 0640, true},
+		{"same again", "v2", 0640, false},
+	}
+
+	for _, step := range steps {
+		config.Mode = step.mode
+		written, err := writer.WriteBytesIfChanged(context.Background(), config, []byte(step.content))
+		if err != nil {
+			t.Fatalf("%s: failed to write file: %v", step.name, err)
+		}
+		if written != step.written {
+			t.Errorf("%s: expected written=%v, got %v", step.name, step.written, written)
+		}
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("expected mode 0640, got %04o", info.Mode().Perm())
+	}
+}
+
 func TestWriteFile_CreatesDirectory(t *testing.T) {
 	tmpDir := t.TempDir()
 	filePath := filepath.Join(tmpDir, "subdir", "test.txt")
@@ -46,7 +149,7 @@ func TestWriteFile_CreatesDirectory(t *testing.T) {
 		Group: -1,
 	}
 
-	if err := writer.WriteFile(config, "content"); err != nil {
+	if err := writer.WriteFile(context.Background(), config, "content"); err != nil {
 		t.Fatalf("failed to write file: %v", err)
 	}
 
@@ -55,6 +158,108 @@ func TestWriteFile_CreatesDirectory(t *testing.T) {
 	}
 }
 
+func TestWriteFile_WithoutSync(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "subdir", "test.txt")
+
+	writer := NewWriter().WithSync(false)
+	config := FileConfig{Path: filePath, Mode: 0600, Owner: -1, Group: -1}
+	if err := writer.WriteFile(context.Background(), config, "content"); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if data, err := os.ReadFile(filePath); err != nil || string(data) != "content" {
+		t.Errorf("expected content written without syncing, got %q (%v)", data, err)
+	}
+}
+
+func TestSyncDir(t *testing.T) {
+	if err := syncDir(t.TempDir()); err != nil {
+		t.Errorf("failed to sync directory: %v", err)
+	}
+	if err := syncDir(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
+
+func TestWriteFile_DirAttributes(t *testing.T) {
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "app", "tls", "key.pem")
+
+	writer := NewWriter()
+	config := FileConfig{
+		Path:  filePath,
+		Mode:  0600,
+		Owner: -1,
+		Group: -1,
+		Dir:   &DirConfig{Mode: 0750, Owner: os.Geteuid(), Group: -1},
+	}
+	if err := writer.WriteFile(context.Background(), config, "content"); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	for _, dir := range []string{filepath.Join(tmpDir, "app"), filepath.Join(tmpDir, "app", "tls")} {
+		info, err := os.Stat(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0750 {
+			t.Errorf("expected %s created with mode 0750, got %04o", dir, info.Mode().Perm())
+		}
+	}
+	if info, _ := os.Stat(tmpDir); info.Mode().Perm() == 0750 {
+		t.Error("expected the existing directory above the created ones to be left alone")
+	}
+
+	// The existing parent is brought in line with a changed mode
+	config.Dir.Mode = 0700
+	if err := writer.WriteFile(context.Background(), config, "changed"); err != nil {
+		t.Fatalf("failed to rewrite file: %v", err)
+	}
+	if info, _ := os.Stat(filepath.Dir(filePath)); info.Mode().Perm() != 0700 {
+		t.Errorf("expected parent mode 0700, got %04o", info.Mode().Perm())
+	}
+
+	// Without a mode, only the ownership of an existing parent is applied
+	config.Dir.Mode = 0
+	if err := writer.WriteFile(context.Background(), config, "again"); err != nil {
+		t.Fatalf("failed to rewrite file: %v", err)
+	}
+	if info, _ := os.Stat(filepath.Dir(filePath)); info.Mode().Perm() != 0700 {
+		t.Errorf("expected parent mode to stay 0700, got %04o", info.Mode().Perm())
+	}
+}
+
+func TestWriteFile_KeepsOwnership(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing the owner of a file requires root")
+	}
+
+	filePath := filepath.Join(t.TempDir(), "test.txt")
+	writer := NewWriter()
+	config := FileConfig{Path: filePath, Mode: 0600, Owner: -1, Group: -1}
+	if err := writer.WriteFile(context.Background(), config, "content"); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.Chown(filePath, 1234, 5678); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writer.WriteFile(context.Background(), config, "changed"); err != nil {
+		t.Fatalf("failed to rewrite file: %v", err)
+	}
+	if _, uid, gid, _ := GetFileInfo(filePath); uid != 1234 || gid != 5678 {
+		t.Errorf("expected ownership 1234:5678 kept, got %d:%d", uid, gid)
+	}
+
+	// A configured owner replaces it
+	config.Owner = 0
+	if err := writer.WriteFile(context.Background(), config, "again"); err != nil {
+		t.Fatalf("failed to rewrite file: %v", err)
+	}
+	if _, uid, gid, _ := GetFileInfo(filePath); uid != 0 || gid != 5678 {
+		t.Errorf("expected ownership 0:5678, got %d:%d", uid, gid)
+	}
+}
+
 func TestWriteFile_Permissions(t *testing.T) {
 	tmpDir := t.TempDir()
 	filePath := filepath.Join(tmpDir, "test.txt")
@@ -67,7 +272,7 @@ func TestWriteFile_Permissions(t *testing.T) {
 		Group: -1,
 	}
 
-	if err := writer.WriteFile(config, "content"); err != nil {
+	if err := writer.WriteFile(context.Background(), config, "content"); err != nil {
 		t.Fatalf("failed to write file: %v", err)
 	}
 
@@ -95,7 +300,7 @@ func TestWriteFile_Atomic(t *testing.T) {
 		Group: -1,
 	}
 
-	if err := writer.WriteFile(config, "updated"); err != nil {
+	if err := writer.WriteFile(context.Background(), config, "updated"); err != nil {
 		t.Fatalf("failed to write file: %v", err)
 	}
 
@@ -137,7 +342,7 @@ func TestWriteFile_RejectsSymlink(t *testing.T) {
 		Group: -1,
 	}
 
-	err := writer.WriteFile(config, "content")
+	err := writer.WriteFile(context.Background(), config, "content")
 	if err == nil {
 		t.Fatal("expected error for symlink, got nil")
 	}
@@ -170,7 +375,7 @@ func TestWriteFile_RejectsLargeContent(t *testing.T) {
 	// Create content larger than MaxSecretSize
 	largeContent := string(make([]byte, MaxSecretSize+1))
 
-	err := writer.WriteFile(config, largeContent)
+	err := writer.WriteFile(context.Background(), config, largeContent)
 	if err == nil {
 		t.Fatal("expected error for large content, got nil")
 	}
@@ -193,7 +398,7 @@ func TestWriteFile_RandomTempNames(t *testing.T) {
 
 	// Write multiple times and verify no temp files remain
 	for i := 0; i < 5; i++ {
-		if err := writer.WriteFile(config, fmt.Sprintf("content-%d", i)); err != nil {
+		if err := writer.WriteFile(context.Background(), config, fmt.Sprintf("content-%d", i)); err != nil {
 			t.Fatalf("write %d failed: %v", i, err)
 		}
 	}
@@ -246,6 +451,34 @@ func TestParseMode_Invalid(t *testing.T) {
 	}
 }
 
+func TestParseDirMode(t *testing.T) {
+	for input, expected := range map[string]os.FileMode{"": DefaultDirMode, "0700": 0700, "0750": 0750} {
+		mode, err := ParseDirMode(input)
+		if err != nil || mode != expected {
+			t.Errorf("ParseDirMode(%q) = %o, %v, expected %o", input, mode, err, expected)
+		}
+	}
+
+	for _, input := range []string{"invalid", "0777", "0640", "2770"} {
+		if _, err := ParseDirMode(input); err == nil {
+			t.Errorf("expected error for dirMode %s", input)
+		}
+	}
+}
+
+func TestValidateSELinuxContext(t *testing.T) {
+	for _, context := range []string{"system_u:object_r:container_file_t:s0", "system_u:object_r:cert_t:s0:c1,c2", "user_u:object_r:etc_t"} {
+		if err := ValidateSELinuxContext(context); err != nil {
+			t.Errorf("expected %q to be valid, got %v", context, err)
+		}
+	}
+	for _, context := range []string{"container_file_t", "system_u::cert_t", "system_u:object_r:etc_t s0"} {
+		if err := ValidateSELinuxContext(context); err == nil {
+			t.Errorf("expected an error for %q", context)
+		}
+	}
+}
+
 func TestParseOwner_Valid(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -274,6 +507,60 @@ func TestParseOwner_Invalid(t *testing.T) {
 	}
 }
 
+func TestParseOwner_Name(t *testing.T) {
+	current, err := user.Current()
+	if err != nil {
+		t.Skipf("no current user: %v", err)
+	}
+
+	owner, err := ParseOwner(current.Username)
+	if err != nil {
+		t.Fatalf("ParseOwner(%s) failed: %v", current.Username, err)
+	}
+	if strconv.Itoa(owner) != current.Uid {
+		t.Errorf("ParseOwner(%s) = %d, expected %s", current.Username, owner, current.Uid)
+	}
+
+	_, err = ParseOwner("no-such-user-secrets-sync")
+	if err == nil || !strings.Contains(err.Error(), `user "no-such-user-secrets-sync" does not exist`) {
+		t.Errorf("expected unknown user error, got %v", err)
+	}
+}
+
+func TestParseGroup(t *testing.T) {
+	current, err := user.Current()
+	if err != nil {
+		t.Skipf("no current user: %v", err)
+	}
+	primary, err := user.LookupGroupId(current.Gid)
+	if err != nil {
+		t.Skipf("primary group not in the group database: %v", err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1000", "1000"},
+		{"", "-1"},
+		{primary.Name, primary.Gid},
+	}
+	for _, tt := range tests {
+		group, err := ParseGroup(tt.input)
+		if err != nil {
+			t.Errorf("ParseGroup(%s) failed: %v", tt.input, err)
+		}
+		if strconv.Itoa(group) != tt.expected {
+			t.Errorf("ParseGroup(%s) = %d, expected %s", tt.input, group, tt.expected)
+		}
+	}
+
+	_, err = ParseGroup("no-such-group-secrets-sync")
+	if err == nil || !strings.Contains(err.Error(), `group "no-such-group-secrets-sync" does not exist`) {
+		t.Errorf("expected unknown group error, got %v", err)
+	}
+}
+
 func TestValidatePath(t *testing.T) {
 	tests := []struct {
 		name    string
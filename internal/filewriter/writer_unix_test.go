@@ -0,0 +1,41 @@
+//go:build linux || darwin || freebsd || openbsd || netbsd || dragonfly || solaris || aix
+// +build linux darwin freebsd openbsd netbsd dragonfly solaris aix
+
+package filewriter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestWriteBytesIfChanged_StrictUmask(t *testing.T) {
+	old := syscall.Umask(077)
+	defer syscall.Umask(old)
+
+	filePath := filepath.Join(t.TempDir(), "test.txt")
+	writer := NewWriter()
+	config := FileConfig{Path: filePath, Mode: 0644, Owner: -1, Group: -1}
+
+	if _, err := writer.WriteBytesIfChanged(context.Background(), config, []byte("v1")); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("expected mode 0644 despite the umask, got %04o", info.Mode().Perm())
+	}
+
+	// An unchanged file is not rewritten on every refresh
+	written, err := writer.WriteBytesIfChanged(context.Background(), config, []byte("v1"))
+	if err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if written {
+		t.Error("expected the unchanged file not to be written again")
+	}
+}
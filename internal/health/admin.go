@@ -0,0 +1,162 @@
+package health
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrUnknownSecret is returned by a SyncFunc for a secret that is not configured
+	ErrUnknownSecret = errors.New("unknown secret")
+	// ErrSyncUnavailable is returned by a SyncFunc that cannot sync right now,
+	// e.g. on a standby replica or while Vault is sealed
+	ErrSyncUnavailable = errors.New("sync unavailable")
+	// ErrNoBackup is returned by a RollbackFunc for a secret without backups
+	ErrNoBackup = errors.New("no backup")
+)
+
+// SyncResult is the outcome of one sync triggered through the admin API
+type SyncResult struct {
+	SecretStatus
+	Kind     string  `json:"kind,omitempty"` // Cause of a failure
+	Duration float64 `json:"duration_seconds"`
+}
+
+// SyncFunc syncs the named secret, or every secret if name is empty, and
+// returns once the syncs finished
+type SyncFunc func(ctx context.Context, name string) ([]SyncResult, error)
+
+// WithSyncAPI serves POST /api/v1/sync and POST /api/v1/sync/{secret},
+// which run fn for every secret or one secret. Requests must carry token
+// as a bearer token.
+func (s *Server) WithSyncAPI(token string, fn SyncFunc) *Server {
+	s.adminToken = sha256.Sum256([]byte(token))
+	s.syncFunc = fn
+	return s
+}
+
+// RollbackFunc restores the latest backup of the files of the named secret
+// and returns their paths
+type RollbackFunc func(ctx context.Context, name string) ([]string, error)
+
+// WithRollbackAPI serves POST /api/v1/rollback/{secret}, which runs fn for
+// the secret. Requests must carry token as a bearer token.
+func (s *Server) WithRollbackAPI(token string, fn RollbackFunc) *Server {
+	s.adminToken = sha256.Sum256([]byte(token))
+	s.rollbackFunc = fn
+	return s
+}
+
+// WithLogLevel serves the log level on GET /loglevel and changes it on PUT
+// /loglevel, which must carry token as a bearer token. handler serves both,
+// e.g. a zap.AtomicLevel.
+func (s *Server) WithLogLevel(token string, handler http.Handler) *Server {
+	s.adminToken = sha256.Sum256([]byte(token))
+	s.logLevel = handler
+	return s
+}
+
+// authorized reports whether a request carries the admin API token. Digests
+// are compared, so neither content nor length leaks through timing.
+func (s *Server) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return false
+	}
+	digest := sha256.Sum256([]byte(token))
+	return subtle.ConstantTimeCompare(digest[:], s.adminToken[:]) == 1
+}
+
+// unauthorized answers a request without the admin API token
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="secrets-sync"`)
+	writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+}
+
+func (s *Server) setLogLevelHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		unauthorized(w)
+		return
+	}
+	s.logLevel.ServeHTTP(w, r)
+}
+
+func (s *Server) syncAllHandler(w http.ResponseWriter, r *http.Request) {
+	s.runSync(w, r, "")
+}
+
+func (s *Server) syncSecretHandler(w http.ResponseWriter, r *http.Request) {
+	s.runSync(w, r, r.PathValue("secret"))
+}
+
+// rollbackHandler authenticates a request, rolls the secret back and writes
+// the files restored
+func (s *Server) rollbackHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		unauthorized(w)
+		return
+	}
+
+	name := r.PathValue("secret")
+	files, err := s.rollbackFunc(r.Context(), name)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrUnknownSecret):
+			status = http.StatusNotFound
+		case errors.Is(err, ErrNoBackup):
+			status = http.StatusConflict
+		case errors.Is(err, ErrSyncUnavailable), errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, map[string]interface{}{"name": name, "files": files, "error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"name": name, "files": files})
+}
+
+// runSync authenticates a request, runs the sync and writes the results:
+// one object for a single secret, a list under "results" for all of them
+func (s *Server) runSync(w http.ResponseWriter, r *http.Request, name string) {
+	if !s.authorized(r) {
+		unauthorized(w)
+		return
+	}
+
+	results, err := s.syncFunc(r.Context(), name)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrUnknownSecret):
+			status = http.StatusNotFound
+		case errors.Is(err, ErrSyncUnavailable), errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusOK
+	for _, result := range results {
+		if result.State == "failed" {
+			status = http.StatusInternalServerError
+		}
+	}
+
+	if name != "" && len(results) == 1 {
+		writeJSON(w, status, results[0])
+		return
+	}
+	writeJSON(w, status, map[string]interface{}{"results": results})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,181 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newSyncServer(fn SyncFunc) http.Handler {
+	return NewServer(NewStatus(""), "127.0.0.1", 8080).WithSyncAPI("s3cret", fn).routes()
+}
+
+func TestSyncAPI(t *testing.T) {
+	var requested []string
+	handler := newSyncServer(func(ctx context.Context, name string) ([]SyncResult, error) {
+		requested = append(requested, name)
+		switch name {
+		case "":
+			return []SyncResult{
+				{SecretStatus: SecretStatus{Name: "app/db", State: "synced"}},
+				{SecretStatus: SecretStatus{Name: "tls", State: "failed", Error: "permission denied"}, Kind: "permission"},
+			}, nil
+		case "missing":
+			return nil, fmt.Errorf("%w: %s", ErrUnknownSecret, name)
+		case "standby":
+			return nil, ErrSyncUnavailable
+		default:
+			return []SyncResult{{SecretStatus: SecretStatus{Name: name, State: "synced"}}}, nil
+		}
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		wantStatus int
+	}{
+		{name: "one secret", method: "POST", path: "/api/v1/sync/app/db", token: "s3cret", wantStatus: http.StatusOK},
+		{name: "all secrets with a failure", method: "POST", path: "/api/v1/sync", token: "s3cret", wantStatus: http.StatusInternalServerError},
+		{name: "unknown secret", method: "POST", path: "/api/v1/sync/missing", token: "s3cret", wantStatus: http.StatusNotFound},
+		{name: "unavailable", method: "POST", path: "/api/v1/sync/standby", token: "s3cret", wantStatus: http.StatusServiceUnavailable},
+		{name: "wrong token", method: "POST", path: "/api/v1/sync", token: "wrong", wantStatus: http.StatusUnauthorized},
+		{name: "no token", method: "POST", path: "/api/v1/sync", wantStatus: http.StatusUnauthorized},
+		{name: "wrong method", method: "GET", path: "/api/v1/sync", token: "s3cret", wantStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	if len(requested) != 4 || requested[0] != "app/db" || requested[1] != "" {
+		t.Errorf("expected only authorized requests to sync, got %q", requested)
+	}
+}
+
+func TestSyncAPI_Response(t *testing.T) {
+	handler := newSyncServer(func(ctx context.Context, name string) ([]SyncResult, error) {
+		return []SyncResult{{SecretStatus: SecretStatus{Name: name, State: "synced"}, Duration: 0.25}}, nil
+	})
+
+	req := httptest.NewRequest("POST", "/api/v1/sync/db", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	var result map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if result["name"] != "db" || result["state"] != "synced" || result["duration_seconds"] != 0.25 {
+		t.Errorf("unexpected response: %v", result)
+	}
+}
+
+func TestSyncAPI_Disabled(t *testing.T) {
+	req := httptest.NewRequest("POST", "/api/v1/sync", nil)
+	w := httptest.NewRecorder()
+	NewServer(NewStatus(""), "127.0.0.1", 8080).routes().ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without the admin API, got %d", w.Code)
+	}
+}
+
+func TestRollbackAPI(t *testing.T) {
+	handler := NewServer(NewStatus(""), "127.0.0.1", 8080).WithRollbackAPI("s3cret", func(ctx context.Context, name string) ([]string, error) {
+		switch name {
+		case "missing":
+			return nil, fmt.Errorf("%w: %s", ErrUnknownSecret, name)
+		case "fresh":
+			return nil, fmt.Errorf("%w: /etc/app/key has none", ErrNoBackup)
+		case "busy":
+			return nil, ErrSyncUnavailable
+		default:
+			return []string{"/etc/app/key"}, nil
+		}
+	}).routes()
+
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		wantStatus int
+	}{
+		{name: "restored", path: "/api/v1/rollback/app/db", token: "s3cret", wantStatus: http.StatusOK},
+		{name: "unknown secret", path: "/api/v1/rollback/missing", token: "s3cret", wantStatus: http.StatusNotFound},
+		{name: "no backup", path: "/api/v1/rollback/fresh", token: "s3cret", wantStatus: http.StatusConflict},
+		{name: "unavailable", path: "/api/v1/rollback/busy", token: "s3cret", wantStatus: http.StatusServiceUnavailable},
+		{name: "wrong token", path: "/api/v1/rollback/app/db", token: "wrong", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestLogLevelAPI(t *testing.T) {
+	level := "info"
+	levelHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			level = "debug"
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"level": level})
+	})
+	handler := NewServer(NewStatus(""), "127.0.0.1", 8080).WithLogLevel("s3cret", levelHandler).routes()
+
+	tests := []struct {
+		name       string
+		method     string
+		token      string
+		wantStatus int
+		wantLevel  string
+	}{
+		{name: "get without token", method: "GET", wantStatus: http.StatusOK, wantLevel: "info"},
+		{name: "put without token", method: "PUT", wantStatus: http.StatusUnauthorized, wantLevel: "info"},
+		{name: "put with wrong token", method: "PUT", token: "wrong", wantStatus: http.StatusUnauthorized, wantLevel: "info"},
+		{name: "put", method: "PUT", token: "s3cret", wantStatus: http.StatusOK, wantLevel: "debug"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/loglevel", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if level != tt.wantLevel {
+				t.Errorf("expected level %s, got %s", tt.wantLevel, level)
+			}
+		})
+	}
+}
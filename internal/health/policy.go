@@ -0,0 +1,53 @@
+package health
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ReadyPolicy is the share of the configured secrets that must be synced
+// for the service to be ready. The zero value needs a single secret.
+type ReadyPolicy struct {
+	share float64 // 0 for any secret, 1 for all
+}
+
+// ParseReadyPolicy parses a READY_POLICY value: any, all or a percentage
+// such as 80%
+func ParseReadyPolicy(s string) (ReadyPolicy, error) {
+	switch s = strings.TrimSpace(s); s {
+	case "", "any":
+		return ReadyPolicy{}, nil
+	case "all":
+		return ReadyPolicy{share: 1}, nil
+	}
+
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if !strings.HasSuffix(s, "%") || err != nil || percent <= 0 || percent > 100 {
+		return ReadyPolicy{}, fmt.Errorf("must be any, all or a percentage between 0%% and 100%%, got: %s", s)
+	}
+	return ReadyPolicy{share: percent / 100}, nil
+}
+
+// String returns the policy as accepted by ParseReadyPolicy
+func (p ReadyPolicy) String() string {
+	switch p.share {
+	case 0:
+		return "any"
+	case 1:
+		return "all"
+	default:
+		return strconv.FormatFloat(p.share*100, 'f', -1, 64) + "%"
+	}
+}
+
+// satisfied reports whether syncedCount of secretCount secrets meet the
+// policy; no synced secret never does
+func (p ReadyPolicy) satisfied(secretCount, syncedCount int) bool {
+	if syncedCount == 0 {
+		return false
+	}
+	// Compare in whole per mille, so 3 of 4 secrets meet 75%
+	return syncedCount*1000 >= int(math.Round(p.share*1000))*secretCount
+}
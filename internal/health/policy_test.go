@@ -0,0 +1,78 @@
+package health
+
+import (
+	"testing"
+)
+
+func TestParseReadyPolicy(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", "any", false},
+		{"any", "any", false},
+		{"all", "all", false},
+		{"80%", "80%", false},
+		{"100%", "all", false},
+		{"0%", "", true},
+		{"80", "", true},
+		{"150%", "", true},
+		{"most", "", true},
+	}
+	for _, tt := range tests {
+		policy, err := ParseReadyPolicy(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseReadyPolicy(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && policy.String() != tt.want {
+			t.Errorf("ParseReadyPolicy(%q) = %s, want %s", tt.in, policy, tt.want)
+		}
+	}
+}
+
+func TestReadyPolicy_Satisfied(t *testing.T) {
+	anyOne, _ := ParseReadyPolicy("any")
+	all, _ := ParseReadyPolicy("all")
+	most, _ := ParseReadyPolicy("75%")
+
+	tests := []struct {
+		name          string
+		policy        ReadyPolicy
+		secrets, sync int
+		want          bool
+	}{
+		{"any none", anyOne, 4, 0, false},
+		{"any one", anyOne, 4, 1, true},
+		{"all missing one", all, 4, 3, false},
+		{"all", all, 4, 4, true},
+		{"75% of 4", most, 4, 3, true},
+		{"75% of 5", most, 5, 3, false},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.satisfied(tt.secrets, tt.sync); got != tt.want {
+			t.Errorf("%s: satisfied(%d, %d) = %v, want %v", tt.name, tt.secrets, tt.sync, got, tt.want)
+		}
+	}
+}
+
+func TestStatus_CriticalSecrets(t *testing.T) {
+	status := NewStatus("")
+	status.SetCritical([]string{"db"})
+
+	status.SetSecrets([]SecretStatus{{Name: "db", State: "failed"}, {Name: "tls", State: "synced"}})
+	_ = status.SetReady(2, 1)
+	if status.IsReady() {
+		t.Error("expected not ready while the critical secret failed")
+	}
+	if missing := status.MissingCritical(); len(missing) != 1 || missing[0] != "db" {
+		t.Errorf("expected db to be missing, got %v", missing)
+	}
+
+	status.SetSecrets([]SecretStatus{{Name: "db", State: "stale"}, {Name: "tls", State: "failed"}})
+	_ = status.SetReady(2, 1)
+	if !status.IsReady() {
+		t.Error("expected ready once the critical secret is stale")
+	}
+}
@@ -1,12 +1,17 @@
 package health
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"sync"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
@@ -15,31 +20,127 @@ type Status struct {
 	Ready       bool   `json:"ready"`
 	SecretCount int    `json:"secret_count"`
 	SyncedCount int    `json:"synced_count"`
+	StaleCount  int    `json:"stale_count"`
 	StatusFile  string `json:"-"`
+	secrets     []SecretStatus
+	startedAt   time.Time
+	lastSuccess map[string]time.Time // Last successful sync, by secret name; zero if none yet
+	maxAge      time.Duration        // Oldest last success /ready accepts, 0 for no limit
+	policy      ReadyPolicy
+	critical    []string // Secrets that must be synced for the service to be ready
 	mu          sync.RWMutex
 }
 
+// SecretStatus is the latest sync state of a single secret
+type SecretStatus struct {
+	Name     string    `json:"name"`
+	State    string    `json:"state"` // synced, stale or failed
+	LastSync time.Time `json:"last_sync"`
+	// LastSuccess is when the data on disk was last fetched: the last sync
+	// that succeeded, or FetchedAt for stale secrets. Set by SetSecrets.
+	LastSuccess *time.Time   `json:"last_success,omitempty"`
+	FetchedAt   *time.Time   `json:"fetched_at,omitempty"` // Age of the data, for stale secrets
+	NextSync    *time.Time   `json:"next_sync,omitempty"`
+	RetryAt     *time.Time   `json:"retry_at,omitempty"` // Retry of a failed sync before NextSync
+	Error       string       `json:"error,omitempty"`
+	Files       []FileStatus `json:"files,omitempty"`
+
+	ConsecutiveFailures int  `json:"consecutive_failures,omitempty"`
+	Alerting            bool `json:"alerting,omitempty"` // Failures reached the alert threshold
+}
+
+// FileStatus is a file written for a secret
+type FileStatus struct {
+	Path       string     `json:"path"`
+	SHA256     string     `json:"sha256,omitempty"`      // Hash of the content last rendered
+	CertExpiry *time.Time `json:"cert_expiry,omitempty"` // Expiry of the certificates in it, with certExpiry set
+}
+
 // NewStatus creates a new status tracker
 func NewStatus(statusFile string) *Status {
 	return &Status{
-		Ready:      false,
-		StatusFile: statusFile,
+		Ready:       false,
+		StatusFile:  statusFile,
+		startedAt:   time.Now(),
+		lastSuccess: make(map[string]time.Time),
 	}
 }
 
-// SetReady marks the service as ready
+// WithMaxAge makes /ready fail while a secret last synced successfully
+// longer ago than maxAge; a secret that never did counts from the start
+func (s *Status) WithMaxAge(maxAge time.Duration) *Status {
+	s.maxAge = maxAge
+	return s
+}
+
+// Outdated returns the names of the secrets not synced successfully within
+// the maximum age at now, none without a maximum age
+func (s *Status) Outdated(now time.Time) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.maxAge <= 0 {
+		return nil
+	}
+	return outdated(s.lastSuccess, s.startedAt, s.maxAge, now)
+}
+
+// WithReadyPolicy sets the share of secrets that must be synced for the
+// service to be ready
+func (s *Status) WithReadyPolicy(policy ReadyPolicy) *Status {
+	s.policy = policy
+	return s
+}
+
+// SetCritical replaces the names of the secrets that must be synced, or
+// stale, for the service to be ready, whatever the ready policy
+func (s *Status) SetCritical(names []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.critical = names
+}
+
+// MissingCritical returns the critical secrets that are neither synced nor
+// stale according to the states last set with SetSecrets
+func (s *Status) MissingCritical() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.missingCritical()
+}
+
+// missingCritical implements MissingCritical; the caller holds s.mu
+func (s *Status) missingCritical() []string {
+	if len(s.critical) == 0 {
+		return nil
+	}
+	synced := make(map[string]bool, len(s.secrets))
+	for _, secret := range s.secrets {
+		synced[secret.Name] = secret.State == "synced" || secret.State == "stale"
+	}
+	var missing []string
+	for _, name := range s.critical {
+		if !synced[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// SetReady records how many secrets are configured and synced, and marks
+// the service ready if they meet the ready policy and every critical secret
+// is synced
 func (s *Status) SetReady(secretCount, syncedCount int) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.Ready = syncedCount > 0
+	s.Ready = s.policy.satisfied(secretCount, syncedCount) && len(s.missingCritical()) == 0
 	s.SecretCount = secretCount
 	s.SyncedCount = syncedCount
 
 	if s.StatusFile != "" {
 		if s.Ready {
-			if err := os.WriteFile(s.StatusFile, []byte("ready"), 0644); err != nil {
-				return fmt.Errorf("failed to write status file: %w", err)
+			content := statusFileContent{Ready: true, StartedAt: s.startedAt, LastSuccess: s.lastSuccess}
+			if err := writeStatusFile(s.StatusFile, content); err != nil {
+				return err
 			}
 		} else {
 			_ = os.Remove(s.StatusFile)
@@ -49,6 +150,52 @@ func (s *Status) SetReady(secretCount, syncedCount int) error {
 	return nil
 }
 
+// SetStaleCount records how many of the synced secrets are served from stale data
+func (s *Status) SetStaleCount(staleCount int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.StaleCount = staleCount
+}
+
+// GetStaleCount returns how many secrets are served from stale data
+func (s *Status) GetStaleCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.StaleCount
+}
+
+// SetSecrets replaces the per-secret states reported by /status and
+// records the last success of each; secrets not listed are forgotten
+func (s *Status) SetSecrets(secrets []SecretStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lastSuccess := make(map[string]time.Time, len(secrets))
+	for i := range secrets {
+		secret := &secrets[i]
+		t := s.lastSuccess[secret.Name]
+		switch {
+		case secret.State == "synced":
+			t = secret.LastSync
+		case secret.State == "stale" && secret.FetchedAt != nil:
+			t = *secret.FetchedAt
+		}
+		lastSuccess[secret.Name] = t
+		if !t.IsZero() {
+			secret.LastSuccess = &t
+		}
+	}
+	s.lastSuccess = lastSuccess
+	s.secrets = secrets
+}
+
+// GetSecrets returns the per-secret states
+func (s *Status) GetSecrets() []SecretStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]SecretStatus(nil), s.secrets...)
+}
+
 // IsReady returns whether the service is ready
 func (s *Status) IsReady() bool {
 	s.mu.RLock()
@@ -65,10 +212,15 @@ func (s *Status) GetStatus() (bool, int, int) {
 
 // Server provides HTTP health endpoints
 type Server struct {
-	status *Status
-	addr   string
-	port   int
-	server *http.Server
+	status       *Status
+	addr         string
+	port         int
+	server       *http.Server
+	diagnostics  func(io.Writer) error
+	syncFunc     SyncFunc     // Optional admin API triggering syncs
+	logLevel     http.Handler // Optional admin API changing the log level
+	rollbackFunc RollbackFunc // Optional admin API restoring backups
+	adminToken   [sha256.Size]byte
 }
 
 // NewServer creates a new health server
@@ -80,16 +232,17 @@ func NewServer(status *Status, addr string, port int) *Server {
 	}
 }
 
+// WithDiagnostics serves diagnostics snapshots written by fn on /debug/diagnostics
+func (s *Server) WithDiagnostics(fn func(io.Writer) error) *Server {
+	s.diagnostics = fn
+	return s
+}
+
 // Start starts the health server
 func (s *Server) Start() error {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/health", s.healthHandler)
-	mux.HandleFunc("/ready", s.readyHandler)
-	mux.Handle("/metrics", promhttp.Handler())
-
 	s.server = &http.Server{
 		Addr:    fmt.Sprintf("%s:%d", s.addr, s.port),
-		Handler: mux,
+		Handler: s.routes(),
 	}
 
 	go func() {
@@ -99,6 +252,33 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// routes returns the handler serving every enabled endpoint
+func (s *Server) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", s.healthHandler)
+	mux.HandleFunc("/ready", s.readyHandler)
+	mux.HandleFunc("/status", s.statusHandler)
+	if s.diagnostics != nil {
+		mux.HandleFunc("/debug/diagnostics", s.diagnosticsHandler)
+	}
+	if s.syncFunc != nil {
+		mux.HandleFunc("POST /api/v1/sync", s.syncAllHandler)
+		mux.HandleFunc("POST /api/v1/sync/{secret...}", s.syncSecretHandler)
+	}
+	if s.rollbackFunc != nil {
+		mux.HandleFunc("POST /api/v1/rollback/{secret...}", s.rollbackHandler)
+	}
+	if s.logLevel != nil {
+		mux.Handle("GET /loglevel", s.logLevel)
+		mux.HandleFunc("PUT /loglevel", s.setLogLevelHandler)
+	}
+	// OpenMetrics, when the scraper asks for it, carries the exemplars
+	// linking histogram buckets to traces
+	mux.Handle("/metrics", promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true})))
+	return mux
+}
+
 // Stop stops the health server
 func (s *Server) Stop() error {
 	if s.server != nil {
@@ -117,6 +297,8 @@ func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) readyHandler(w http.ResponseWriter, r *http.Request) {
 	ready, secretCount, syncedCount := s.status.GetStatus()
+	outdated := s.status.Outdated(time.Now())
+	ready = ready && len(outdated) == 0
 
 	w.Header().Set("Content-Type", "application/json")
 
@@ -126,9 +308,44 @@ func (s *Server) readyHandler(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusServiceUnavailable)
 	}
 
+	response := map[string]interface{}{
+		"ready":        ready,
+		"secret_count": secretCount,
+		"synced_count": syncedCount,
+		"stale_count":  s.status.GetStaleCount(),
+	}
+	if len(outdated) > 0 {
+		response["outdated"] = outdated
+	}
+	if missing := s.status.MissingCritical(); len(missing) > 0 {
+		response["missing_critical"] = missing
+	}
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (s *Server) statusHandler(w http.ResponseWriter, r *http.Request) {
+	ready, secretCount, syncedCount := s.status.GetStatus()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
 	_ = json.NewEncoder(w).Encode(map[string]interface{}{
 		"ready":        ready,
 		"secret_count": secretCount,
 		"synced_count": syncedCount,
+		"stale_count":  s.status.GetStaleCount(),
+		"secrets":      s.status.GetSecrets(),
 	})
 }
+
+func (s *Server) diagnosticsHandler(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	if err := s.diagnostics(&buf); err != nil {
+		http.Error(w, fmt.Sprintf("failed to collect diagnostics: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
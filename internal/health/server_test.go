@@ -2,11 +2,14 @@ package health
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestStatus_SetReady(t *testing.T) {
@@ -115,6 +118,32 @@ func TestReadyHandler_Ready(t *testing.T) {
 	}
 }
 
+func TestReadyHandler_Stale(t *testing.T) {
+	status := NewStatus("")
+	_ = status.SetReady(2, 2)
+	status.SetStaleCount(1)
+
+	server := NewServer(status, "127.0.0.1", 8080)
+
+	req := httptest.NewRequest("GET", "/ready", nil)
+	w := httptest.NewRecorder()
+
+	server.readyHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected stale secrets to keep readiness, got %d", w.Code)
+	}
+
+	var response map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if response["stale_count"].(float64) != 1 {
+		t.Errorf("expected stale_count 1, got %v", response["stale_count"])
+	}
+}
+
 func TestReadyHandler_NotReady(t *testing.T) {
 	status := NewStatus("")
 	_ = status.SetReady(2, 0)
@@ -146,7 +175,7 @@ func TestCheckReadiness_Ready(t *testing.T) {
 
 	_ = os.WriteFile(statusFile, []byte("ready"), 0644)
 
-	if err := CheckReadiness(statusFile); err != nil {
+	if err := CheckReadiness(statusFile, 0); err != nil {
 		t.Errorf("expected no error, got: %v", err)
 	}
 }
@@ -155,7 +184,130 @@ func TestCheckReadiness_NotReady(t *testing.T) {
 	tmpDir := t.TempDir()
 	statusFile := filepath.Join(tmpDir, ".ready-state")
 
-	if err := CheckReadiness(statusFile); err == nil {
+	if err := CheckReadiness(statusFile, 0); err == nil {
 		t.Error("expected error for missing status file, got nil")
 	}
 }
+
+func TestCheckReadiness_MaxAge(t *testing.T) {
+	statusFile := filepath.Join(t.TempDir(), ".ready-state")
+	status := NewStatus(statusFile)
+	status.startedAt = time.Now().Add(-3 * time.Hour)
+
+	fetchedAt := time.Now().Add(-90 * time.Minute)
+	status.SetSecrets([]SecretStatus{
+		{Name: "db", State: "synced", LastSync: time.Now()},
+		{Name: "tls", State: "stale", LastSync: time.Now(), FetchedAt: &fetchedAt},
+		{Name: "api", State: "failed", LastSync: time.Now()},
+	})
+	if err := status.SetReady(3, 2); err != nil {
+		t.Fatalf("failed to set ready: %v", err)
+	}
+
+	if err := CheckReadiness(statusFile, 4*time.Hour); err != nil {
+		t.Errorf("expected all secrets within 4h, got %v", err)
+	}
+	err := CheckReadiness(statusFile, time.Hour)
+	if err == nil || !strings.Contains(err.Error(), "not synced within 1h0m0s: api, tls") {
+		t.Errorf("expected api and tls to be outdated, got %v", err)
+	}
+
+	// A failed sync keeps the last success
+	status.SetSecrets([]SecretStatus{{Name: "db", State: "failed", LastSync: time.Now()}})
+	if got := status.GetSecrets()[0].LastSuccess; got == nil || time.Since(*got) > time.Minute {
+		t.Errorf("expected the earlier success to be kept, got %v", got)
+	}
+}
+
+func TestCheckReadiness_MaxAgeLegacyFile(t *testing.T) {
+	statusFile := filepath.Join(t.TempDir(), ".ready-state")
+	_ = os.WriteFile(statusFile, []byte("ready"), 0644)
+
+	if err := CheckReadiness(statusFile, time.Hour); err == nil {
+		t.Error("expected a status file without sync times to fail with a maximum age")
+	}
+}
+
+func TestReadyHandler_Outdated(t *testing.T) {
+	status := NewStatus("").WithMaxAge(time.Hour)
+	status.startedAt = time.Now().Add(-2 * time.Hour)
+	status.SetSecrets([]SecretStatus{
+		{Name: "db", State: "synced", LastSync: time.Now()},
+		{Name: "api", State: "failed", LastSync: time.Now()},
+	})
+	_ = status.SetReady(2, 1)
+
+	w := httptest.NewRecorder()
+	NewServer(status, "127.0.0.1", 8080).readyHandler(w, httptest.NewRequest("GET", "/ready", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", w.Code)
+	}
+	var response struct {
+		Ready    bool     `json:"ready"`
+		Outdated []string `json:"outdated"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Ready || len(response.Outdated) != 1 || response.Outdated[0] != "api" {
+		t.Errorf("expected api to be outdated, got %+v", response)
+	}
+}
+
+func TestStatusHandler(t *testing.T) {
+	status := NewStatus("")
+	_ = status.SetReady(2, 1)
+	status.SetSecrets([]SecretStatus{
+		{Name: "db", State: "synced", Files: []FileStatus{{Path: "/secrets/db", SHA256: "abc"}}},
+		{Name: "tls", State: "failed", Error: "permission denied"},
+	})
+
+	server := NewServer(status, "127.0.0.1", 8080)
+
+	req := httptest.NewRequest("GET", "/status", nil)
+	w := httptest.NewRecorder()
+
+	server.statusHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+
+	var response struct {
+		Ready   bool           `json:"ready"`
+		Secrets []SecretStatus `json:"secrets"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(response.Secrets) != 2 {
+		t.Fatalf("expected 2 secrets, got %d", len(response.Secrets))
+	}
+	if files := response.Secrets[0].Files; len(files) != 1 || files[0].Path != "/secrets/db" || files[0].SHA256 != "abc" {
+		t.Errorf("unexpected files: %+v", files)
+	}
+	if response.Secrets[1].State != "failed" || response.Secrets[1].Error != "permission denied" {
+		t.Errorf("unexpected secret status: %+v", response.Secrets[1])
+	}
+}
+
+func TestDiagnosticsHandler(t *testing.T) {
+	server := NewServer(NewStatus(""), "127.0.0.1", 8080).WithDiagnostics(func(w io.Writer) error {
+		_, err := w.Write([]byte("=== diagnostics ==="))
+		return err
+	})
+
+	req := httptest.NewRequest("GET", "/debug/diagnostics", nil)
+	w := httptest.NewRecorder()
+
+	server.diagnosticsHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+	if w.Body.String() != "=== diagnostics ===" {
+		t.Errorf("unexpected body: %q", w.Body.String())
+	}
+}
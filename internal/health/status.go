@@ -1,14 +1,87 @@
 package health
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 )
 
-// CheckReadiness checks if the service is ready by reading the status file
-func CheckReadiness(statusFile string) error {
-	if _, err := os.Stat(statusFile); os.IsNotExist(err) {
+// statusFileContent is written to the status file while the service is ready
+type statusFileContent struct {
+	Ready       bool                 `json:"ready"`
+	StartedAt   time.Time            `json:"started_at"`
+	LastSuccess map[string]time.Time `json:"last_success"` // Last successful sync, by secret name
+}
+
+// CheckReadiness checks if the service is ready by reading the status file.
+// With maxAge, it also fails if a secret last synced successfully longer
+// ago than that; a secret that never did counts from the service start.
+func CheckReadiness(statusFile string, maxAge time.Duration) error {
+	data, err := os.ReadFile(statusFile)
+	if err != nil {
 		return fmt.Errorf("service not ready")
 	}
+	if maxAge <= 0 {
+		return nil
+	}
+
+	var content statusFileContent
+	if err := json.Unmarshal(data, &content); err != nil {
+		return fmt.Errorf("status file has no sync times: %w", err)
+	}
+	if names := outdated(content.LastSuccess, content.StartedAt, maxAge, time.Now()); len(names) > 0 {
+		return fmt.Errorf("not synced within %s: %s", maxAge, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// outdated returns the sorted names of the secrets whose last success, or
+// since for those without one, is older than maxAge at now
+func outdated(lastSuccess map[string]time.Time, since time.Time, maxAge time.Duration, now time.Time) []string {
+	var names []string
+	for name, t := range lastSuccess {
+		if t.IsZero() {
+			t = since
+		}
+		if now.Sub(t) > maxAge {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writeStatusFile replaces the status file, so a reader never sees it
+// partially written
+func writeStatusFile(path string, content statusFileContent) error {
+	data, err := json.Marshal(content)
+	if err != nil {
+		return fmt.Errorf("failed to encode status file: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write status file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write status file: %w", err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write status file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write status file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write status file: %w", err)
+	}
 	return nil
 }
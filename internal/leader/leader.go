@@ -0,0 +1,83 @@
+// Package leader elects a single active writer among replicas that share an
+// output volume, using an exclusive lock on a file in that volume
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrUnsupported is returned on platforms without file locking
+var ErrUnsupported = errors.New("file locking is not supported on this platform")
+
+// DefaultRetryInterval is how often a standby replica tries to take the lock
+const DefaultRetryInterval = 5 * time.Second
+
+// FileLock is an exclusive advisory lock held for the lifetime of the process
+type FileLock struct {
+	path string
+	file *os.File
+}
+
+// NewFileLock creates a lock backed by the file at path
+func NewFileLock(path string) *FileLock {
+	return &FileLock{path: path}
+}
+
+// Path returns the location of the lock file
+func (l *FileLock) Path() string {
+	return l.path
+}
+
+// Held reports whether this process holds the lock
+func (l *FileLock) Held() bool {
+	return l.file != nil
+}
+
+// Holder returns the identity recorded by the current lock holder, if any
+func (l *FileLock) Holder() string {
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// Wait tries to take the lock every interval until it succeeds or ctx is done
+func (l *FileLock) Wait(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		interval = DefaultRetryInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		acquired, err := l.TryAcquire()
+		if err != nil {
+			return err
+		}
+		if acquired {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// identity describes this process in the lock file for operators
+func identity() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s pid=%d since=%s\n", hostname, os.Getpid(), time.Now().UTC().Format(time.RFC3339))
+}
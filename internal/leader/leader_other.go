@@ -0,0 +1,14 @@
+//go:build windows
+// +build windows
+
+package leader
+
+// TryAcquire is not available on platforms without flock
+func (l *FileLock) TryAcquire() (bool, error) {
+	return false, ErrUnsupported
+}
+
+// Release is a no-op on platforms without flock
+func (l *FileLock) Release() error {
+	return nil
+}
@@ -0,0 +1,98 @@
+package leader
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileLock_Exclusive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leader.lock")
+
+	first := NewFileLock(path)
+	second := NewFileLock(path)
+
+	acquired, err := first.TryAcquire()
+	if err != nil {
+		t.Fatalf("failed to acquire lock: %v", err)
+	}
+	if !acquired || !first.Held() {
+		t.Fatal("expected first lock to be acquired")
+	}
+
+	acquired, err = second.TryAcquire()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if acquired || second.Held() {
+		t.Fatal("expected second lock to stand by")
+	}
+
+	if holder := second.Holder(); !strings.Contains(holder, "pid=") {
+		t.Errorf("expected holder identity in lock file, got %q", holder)
+	}
+
+	if err := first.Release(); err != nil {
+		t.Fatalf("failed to release lock: %v", err)
+	}
+	if first.Held() {
+		t.Error("expected lock to be released")
+	}
+
+	acquired, err = second.TryAcquire()
+	if err != nil {
+		t.Fatalf("failed to acquire lock: %v", err)
+	}
+	if !acquired {
+		t.Error("expected standby to take over after release")
+	}
+	_ = second.Release()
+}
+
+func TestFileLock_WaitTakesOver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leader.lock")
+
+	leader := NewFileLock(path)
+	if ok, err := leader.TryAcquire(); err != nil || !ok {
+		t.Fatalf("failed to acquire lock: %v", err)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = leader.Release()
+	}()
+
+	standby := NewFileLock(path)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := standby.Wait(ctx, 10*time.Millisecond); err != nil {
+		t.Fatalf("expected standby to be elected: %v", err)
+	}
+	_ = standby.Release()
+}
+
+func TestFileLock_WaitCancelled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leader.lock")
+
+	leader := NewFileLock(path)
+	if ok, err := leader.TryAcquire(); err != nil || !ok {
+		t.Fatalf("failed to acquire lock: %v", err)
+	}
+	defer func() { _ = leader.Release() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := NewFileLock(path).Wait(ctx, 10*time.Millisecond); err == nil {
+		t.Error("expected error when cancelled while standing by")
+	}
+}
+
+func TestFileLock_ReleaseWithoutAcquire(t *testing.T) {
+	if err := NewFileLock(filepath.Join(t.TempDir(), "leader.lock")).Release(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
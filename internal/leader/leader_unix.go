@@ -0,0 +1,55 @@
+//go:build !windows
+// +build !windows
+
+package leader
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// TryAcquire takes the lock without blocking, reporting whether it is now held
+func (l *FileLock) TryAcquire() (bool, error) {
+	if l.file != nil {
+		return true, nil
+	}
+
+	f, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return false, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		_ = f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to lock %s: %w", l.path, err)
+	}
+
+	// Record who holds the lock; failing to do so does not affect the lock
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt([]byte(identity()), 0)
+	}
+
+	l.file = f
+	return true, nil
+}
+
+// Release gives up the lock so a standby replica can take over
+func (l *FileLock) Release() error {
+	if l.file == nil {
+		return nil
+	}
+
+	f := l.file
+	l.file = nil
+
+	_ = f.Truncate(0)
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_UN); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to unlock %s: %w", l.path, err)
+	}
+	return f.Close()
+}
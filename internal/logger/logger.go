@@ -1,36 +1,58 @@
 package logger
 
 import (
+	"fmt"
+	"net/http"
+	"sync"
+
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
-var globalLogger *zap.Logger
+var (
+	globalLogger *zap.Logger
+
+	// level is the level of the global logger, changed at runtime by
+	// ToggleDebug and LevelHandler
+	level = zap.NewAtomicLevelAt(zap.InfoLevel)
+	// baseLevel is the level ToggleDebug returns to
+	baseLevel = zapcore.InfoLevel
+	levelMu   sync.Mutex
+)
 
-// Init initializes the global logger
-func Init(level string) error {
-	var zapLevel zap.AtomicLevel
-	switch level {
+// parseLevel returns the zap level of a LOG_LEVEL value
+func parseLevel(name string) (zapcore.Level, error) {
+	switch name {
 	case "debug":
-		zapLevel = zap.NewAtomicLevelAt(zap.DebugLevel)
+		return zap.DebugLevel, nil
 	case "info":
-		zapLevel = zap.NewAtomicLevelAt(zap.InfoLevel)
+		return zap.InfoLevel, nil
 	case "warn":
-		zapLevel = zap.NewAtomicLevelAt(zap.WarnLevel)
+		return zap.WarnLevel, nil
 	case "error":
-		zapLevel = zap.NewAtomicLevelAt(zap.ErrorLevel)
+		return zap.ErrorLevel, nil
 	default:
-		zapLevel = zap.NewAtomicLevelAt(zap.InfoLevel)
+		return zap.InfoLevel, fmt.Errorf("unknown log level %q (supported: debug, info, warn, error)", name)
 	}
+}
+
+// Init initializes the global logger; an unknown level logs at info
+func Init(name string) error {
+	zapLevel, _ := parseLevel(name)
+	levelMu.Lock()
+	level.SetLevel(zapLevel)
+	baseLevel = zapLevel
+	levelMu.Unlock()
 
 	config := zap.Config{
-		Level:            zapLevel,
+		Level:            level,
 		Encoding:         "json",
 		OutputPaths:      []string{"stdout"},
 		ErrorOutputPaths: []string{"stderr"},
 		EncoderConfig:    zap.NewProductionEncoderConfig(),
 	}
 
-	logger, err := config.Build()
+	logger, err := config.Build(zap.WrapCore(newRedactCore))
 	if err != nil {
 		return err
 	}
@@ -39,10 +61,57 @@ func Init(level string) error {
 	return nil
 }
 
+// ToggleDebug switches the global logger to debug, or back to the level it
+// had before, logs the change and returns the new level
+func ToggleDebug() zapcore.Level {
+	levelMu.Lock()
+	before := level.Level()
+	if before == zap.DebugLevel && baseLevel != zap.DebugLevel {
+		level.SetLevel(baseLevel)
+	} else {
+		level.SetLevel(zap.DebugLevel)
+	}
+	after := level.Level()
+	levelMu.Unlock()
+
+	logLevelChange(before, after)
+	return after
+}
+
+// LevelHandler serves the level of the global logger: GET returns it as
+// {"level":"info"}, PUT changes it from the same JSON body or a level form
+// value. A change is logged.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		levelMu.Lock()
+		before := level.Level()
+		level.ServeHTTP(w, r)
+		after := level.Level()
+		if after != before {
+			baseLevel = after
+		}
+		levelMu.Unlock()
+
+		logLevelChange(before, after)
+	})
+}
+
+// logLevelChange logs a change of the level at the higher of both levels,
+// so it is not filtered out
+func logLevelChange(before, after zapcore.Level) {
+	if after == before {
+		return
+	}
+	Get().Log(max(before, after), "log level changed",
+		zap.String("level", after.String()),
+		zap.String("previous", before.String()),
+	)
+}
+
 // Get returns the global logger
 func Get() *zap.Logger {
 	if globalLogger == nil {
-		globalLogger, _ = zap.NewProduction()
+		globalLogger, _ = zap.NewProduction(zap.WrapCore(newRedactCore))
 	}
 	return globalLogger
 }
@@ -3,6 +3,9 @@ package logger
 import (
 	"bytes"
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"go.uber.org/zap"
@@ -111,3 +114,74 @@ func TestLogger_DifferentLevels(t *testing.T) {
 		}
 	}
 }
+
+// useLevelLogger logs to buf at the runtime level, starting at base
+func useLevelLogger(t *testing.T, buf *bytes.Buffer, base zapcore.Level) {
+	t.Helper()
+	level.SetLevel(base)
+	baseLevel = base
+	globalLogger = zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(buf), level))
+	t.Cleanup(func() {
+		level.SetLevel(zap.InfoLevel)
+		baseLevel = zap.InfoLevel
+	})
+}
+
+func TestToggleDebug(t *testing.T) {
+	var buf bytes.Buffer
+	useLevelLogger(t, &buf, zap.WarnLevel)
+
+	if got := ToggleDebug(); got != zap.DebugLevel {
+		t.Fatalf("ToggleDebug() = %v, want debug", got)
+	}
+	Debug("debug message")
+	if !bytes.Contains(buf.Bytes(), []byte("debug message")) {
+		t.Error("debug message not logged after toggling debug on")
+	}
+
+	if got := ToggleDebug(); got != zap.WarnLevel {
+		t.Fatalf("ToggleDebug() = %v, want warn", got)
+	}
+	buf.Reset()
+	Info("info message")
+	if buf.Len() != 0 {
+		t.Errorf("info logged at warn: %s", buf.String())
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	var buf bytes.Buffer
+	useLevelLogger(t, &buf, zap.InfoLevel)
+	handler := LevelHandler()
+
+	req := httptest.NewRequest(http.MethodPut, "/loglevel", strings.NewReader(`{"level":"debug"}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d: %s", w.Code, w.Body.String())
+	}
+	if level.Level() != zap.DebugLevel {
+		t.Errorf("level = %v, want debug", level.Level())
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"msg":"log level changed"`)) {
+		t.Errorf("change not logged: %s", buf.String())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/loglevel", nil))
+	if !strings.Contains(w.Body.String(), `"level":"debug"`) {
+		t.Errorf("GET body = %s, want debug", w.Body.String())
+	}
+
+	// Toggling returns to the level set through the handler
+	ToggleDebug()
+	if level.Level() != zap.DebugLevel {
+		t.Errorf("level = %v, want debug kept as base", level.Level())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/loglevel", strings.NewReader(`{"level":"verbose"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("PUT of an unknown level status = %d, want 400", w.Code)
+	}
+}
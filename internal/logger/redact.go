@@ -0,0 +1,259 @@
+package logger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Redacted replaces secret material found in log entries
+const Redacted = "[REDACTED]"
+
+const (
+	// minSecretLength keeps short values from redacting ordinary words
+	minSecretLength = 8
+	// maxSecrets bounds the registered values; the oldest are dropped first,
+	// so a rotated token stays redacted for a while
+	maxSecrets = 256
+)
+
+var (
+	redactMu sync.RWMutex
+	// secrets are values redacted wherever they appear, oldest first
+	secrets []string
+	// contentHashes are SHA-256 hashes of rendered file content, by path;
+	// a string hashing to one of them is redacted as a whole
+	contentHashes = make(map[string]string)
+	// hashSet holds the values of contentHashes
+	hashSet = make(map[string]struct{})
+)
+
+// RegisterSecret adds a value, such as a token or secret ID, that is
+// redacted from every log entry. Values shorter than 8 bytes are ignored.
+func RegisterSecret(value string) {
+	if len(value) < minSecretLength {
+		return
+	}
+	redactMu.Lock()
+	defer redactMu.Unlock()
+	for _, s := range secrets {
+		if s == value {
+			return
+		}
+	}
+	if len(secrets) == maxSecrets {
+		secrets = secrets[1:]
+	}
+	secrets = append(secrets, value)
+}
+
+// RegisterContentHash records the SHA-256 hash of the content rendered for
+// a file, replacing the hash recorded for it before. Log values with that
+// content are redacted without the content being kept in memory.
+func RegisterContentHash(path, hash string) {
+	redactMu.Lock()
+	defer redactMu.Unlock()
+	if contentHashes[path] == hash {
+		return
+	}
+	contentHashes[path] = hash
+	hashSet = make(map[string]struct{}, len(contentHashes))
+	for _, h := range contentHashes {
+		hashSet[h] = struct{}{}
+	}
+}
+
+// resetRedaction forgets all registered secrets and hashes
+func resetRedaction() {
+	redactMu.Lock()
+	defer redactMu.Unlock()
+	secrets = nil
+	contentHashes = make(map[string]string)
+	hashSet = make(map[string]struct{})
+}
+
+// RedactedString returns s with every registered secret replaced by
+// [REDACTED], or [REDACTED] alone if s is the content of a rendered file
+func RedactedString(s string) string {
+	redacted, _ := redact(s)
+	return redacted
+}
+
+// redactionEnabled reports whether anything is registered for redaction
+func redactionEnabled() bool {
+	redactMu.RLock()
+	defer redactMu.RUnlock()
+	return len(secrets) > 0 || len(hashSet) > 0
+}
+
+// redact redacts s and reports whether anything was found
+func redact(s string) (string, bool) {
+	if s == "" {
+		return s, false
+	}
+	redactMu.RLock()
+	defer redactMu.RUnlock()
+
+	if len(hashSet) > 0 {
+		// Files often differ from the value they hold by a trailing newline
+		for _, candidate := range []string{s, strings.TrimRight(s, "\n"), s + "\n"} {
+			sum := sha256.Sum256([]byte(candidate))
+			if _, ok := hashSet[hex.EncodeToString(sum[:])]; ok {
+				return Redacted, true
+			}
+		}
+	}
+
+	found := false
+	for _, secret := range secrets {
+		if strings.Contains(s, secret) {
+			s = strings.ReplaceAll(s, secret, Redacted)
+			found = true
+		}
+	}
+	return s, found
+}
+
+// redactCore redacts registered secrets from the message and fields of
+// every entry before passing it on
+type redactCore struct {
+	zapcore.Core
+}
+
+// newRedactCore wraps core with redaction
+func newRedactCore(core zapcore.Core) zapcore.Core {
+	return &redactCore{Core: core}
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(redactFields(fields))}
+}
+
+func (c *redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !redactionEnabled() {
+		return c.Core.Write(ent, fields)
+	}
+	ent.Message = RedactedString(ent.Message)
+	return c.Core.Write(ent, redactFields(fields))
+}
+
+// redactFields returns fields with registered secrets redacted, copying the
+// slice only if a field changed
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	if !redactionEnabled() {
+		return fields
+	}
+	var out []zapcore.Field
+	for i, f := range fields {
+		redacted, changed := redactField(f)
+		if !changed {
+			continue
+		}
+		if out == nil {
+			out = append([]zapcore.Field(nil), fields...)
+		}
+		out[i] = redacted
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+// redactField redacts a single field. Strings, errors and stringers are
+// checked as text; arrays, objects and reflected values as the values they
+// encode to.
+func redactField(f zapcore.Field) (zapcore.Field, bool) {
+	switch f.Type {
+	case zapcore.StringType:
+		if s, changed := redact(f.String); changed {
+			return zap.String(f.Key, s), true
+		}
+	case zapcore.ByteStringType:
+		if s, changed := redact(string(f.Interface.([]byte))); changed {
+			return zap.String(f.Key, s), true
+		}
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok && err != nil {
+			if s, changed := redact(err.Error()); changed {
+				return zap.String(f.Key, s), true
+			}
+		}
+	case zapcore.StringerType:
+		if s, ok := stringOf(f.Interface.(fmt.Stringer)); ok {
+			if s, changed := redact(s); changed {
+				return zap.String(f.Key, s), true
+			}
+		}
+	case zapcore.ReflectType:
+		data, err := json.Marshal(f.Interface)
+		if err != nil {
+			return f, false
+		}
+		var v interface{}
+		if err := json.Unmarshal(data, &v); err != nil {
+			return f, false
+		}
+		if v, changed := redactValue(v); changed {
+			return zap.Any(f.Key, v), true
+		}
+	case zapcore.ArrayMarshalerType, zapcore.ObjectMarshalerType:
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		if v, changed := redactValue(enc.Fields[f.Key]); changed {
+			return zap.Any(f.Key, v), true
+		}
+	}
+	return f, false
+}
+
+// redactValue redacts the strings in a decoded value
+func redactValue(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case string:
+		return redact(v)
+	case map[string]interface{}:
+		changed := false
+		for key, elem := range v {
+			if redacted, ok := redactValue(elem); ok {
+				v[key] = redacted
+				changed = true
+			}
+		}
+		return v, changed
+	case []interface{}:
+		changed := false
+		for i, elem := range v {
+			if redacted, ok := redactValue(elem); ok {
+				v[i] = redacted
+				changed = true
+			}
+		}
+		return v, changed
+	default:
+		return v, false
+	}
+}
+
+// stringOf calls String, reporting false if it panics, e.g. on a nil pointer
+func stringOf(s fmt.Stringer) (str string, ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	return s.String(), true
+}
@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const testToken = "hvs.CAESIJ1testtoken"
+
+// useRedactLogger routes the global logger to buf through the redaction
+// core and registers testToken and the content "hunter2-password\n"
+func useRedactLogger(t *testing.T, buf *bytes.Buffer) {
+	t.Helper()
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(buf), zap.DebugLevel)
+	globalLogger = zap.New(newRedactCore(core))
+	RegisterSecret(testToken)
+	RegisterContentHash("/secrets/password", hashOf("hunter2-password\n"))
+	t.Cleanup(resetRedaction)
+}
+
+// hashOf returns the hex SHA-256 of s
+func hashOf(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestRedactedString(t *testing.T) {
+	var buf bytes.Buffer
+	useRedactLogger(t, &buf)
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"token " + testToken + " rejected", "token [REDACTED] rejected"},
+		{"hunter2-password", Redacted},
+		{"hunter2-password\n", Redacted},
+		{"nothing to hide", "nothing to hide"},
+	}
+	for _, tt := range tests {
+		if got := RedactedString(tt.in); got != tt.want {
+			t.Errorf("RedactedString(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRegisterSecret_IgnoresShortValues(t *testing.T) {
+	t.Cleanup(resetRedaction)
+	RegisterSecret("info")
+	if got := RedactedString("info message"); got != "info message" {
+		t.Errorf("expected short values to be ignored, got %q", got)
+	}
+}
+
+func TestRedactCore(t *testing.T) {
+	var buf bytes.Buffer
+	useRedactLogger(t, &buf)
+
+	u, _ := url.Parse("https://user:" + testToken + "@vault.example.com")
+	With(zap.String("token", testToken)).Debug("vault response for "+testToken,
+		zap.Error(errors.New("permission denied for "+testToken)),
+		zap.Any("data", map[string]interface{}{"password": "hunter2-password", "user": "app"}),
+		zap.Strings("values", []string{"app", "hunter2-password"}),
+		zap.Stringer("url", u),
+		zap.ByteString("body", []byte(`{"auth":{"client_token":"`+testToken+`"}}`)),
+	)
+
+	out := buf.String()
+	if strings.Contains(out, testToken) || strings.Contains(out, "hunter2") {
+		t.Fatalf("expected secrets to be redacted, got %s", out)
+	}
+	for _, want := range []string{`"msg":"vault response for [REDACTED]"`, `"user":"app"`, `"values":["app","[REDACTED]"]`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in %s", want, out)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+// Package memlock limits how long and where secret material lives in memory:
+// it keeps process memory out of swap and wipes buffers after use
+package memlock
+
+import (
+	"errors"
+	"runtime"
+)
+
+// ErrUnsupported is returned on platforms without mlockall support
+var ErrUnsupported = errors.New("memory locking is not supported on this platform")
+
+// Zero overwrites b with zeros so the plaintext does not linger on the heap
+func Zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+	// Keep b alive until the loop has run so the stores are not elided
+	runtime.KeepAlive(b)
+}
@@ -0,0 +1,69 @@
+//go:build linux
+// +build linux
+
+package memlock
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// capIPCLock is the capability bit allowing to lock memory beyond RLIMIT_MEMLOCK
+const capIPCLock = 14
+
+// Supported reports whether memory locking is available on this platform
+func Supported() bool {
+	return true
+}
+
+// Lock locks all current and future process memory into RAM.
+//
+// Locking future allocations with a finite RLIMIT_MEMLOCK makes the Go runtime
+// abort once the limit is reached, so Lock refuses to proceed unless the
+// process has CAP_IPC_LOCK or an unlimited RLIMIT_MEMLOCK.
+func Lock() error {
+	if !hasIPCLock() {
+		var rlim unix.Rlimit
+		if err := unix.Getrlimit(unix.RLIMIT_MEMLOCK, &rlim); err != nil {
+			return fmt.Errorf("failed to read RLIMIT_MEMLOCK: %w", err)
+		}
+		if rlim.Cur != unix.RLIM_INFINITY {
+			return fmt.Errorf("missing CAP_IPC_LOCK and RLIMIT_MEMLOCK is limited to %d bytes", rlim.Cur)
+		}
+	}
+
+	if err := unix.Mlockall(unix.MCL_CURRENT | unix.MCL_FUTURE); err != nil {
+		return fmt.Errorf("mlockall failed: %w", err)
+	}
+
+	return nil
+}
+
+// hasIPCLock reports whether CAP_IPC_LOCK is in the effective capability set
+func hasIPCLock() bool {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return false
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "CapEff:") {
+			continue
+		}
+		caps, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "CapEff:")), 16, 64)
+		if err != nil {
+			return false
+		}
+		return caps&(1<<capIPCLock) != 0
+	}
+
+	return false
+}
@@ -0,0 +1,25 @@
+//go:build linux
+// +build linux
+
+package memlock
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSupported(t *testing.T) {
+	if !Supported() {
+		t.Error("expected memory locking to be supported on linux")
+	}
+}
+
+func TestHasIPCLock_ReadsProcStatus(t *testing.T) {
+	if _, err := os.Stat("/proc/self/status"); err != nil {
+		t.Skip("/proc not available")
+	}
+
+	// Only verifies the capability lookup does not fail on a real system;
+	// the result depends on how the tests are run
+	_ = hasIPCLock()
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package memlock
+
+// Supported reports whether memory locking is available on this platform
+func Supported() bool {
+	return false
+}
+
+// Lock is a no-op on platforms without mlockall support
+func Lock() error {
+	return ErrUnsupported
+}
@@ -0,0 +1,18 @@
+package memlock
+
+import "testing"
+
+func TestZero(t *testing.T) {
+	b := []byte("s3cr3t")
+	Zero(b)
+
+	for i, c := range b {
+		if c != 0 {
+			t.Fatalf("expected byte %d to be zero, got %d", i, c)
+		}
+	}
+}
+
+func TestZero_Nil(t *testing.T) {
+	Zero(nil)
+}
@@ -1,8 +1,12 @@
 package metrics
 
 import (
+	"context"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -34,6 +38,34 @@ var (
 		[]string{"secret_name"},
 	)
 
+	// SecretSyncPhaseDuration tracks how long the phases of a sync take
+	SecretSyncPhaseDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "secret_sync_phase_duration_seconds",
+			Help:    "Duration of the fetch, render and write phase of secret syncs in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"secret_name", "phase"},
+	)
+
+	// VaultFetchDuration tracks how long single attempts to read a KV secret take
+	VaultFetchDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "vault_fetch_duration_seconds",
+			Help:    "Duration of single attempts to read a KV secret from Vault in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"status"},
+	)
+
+	// VaultFetchRetries tracks reads of KV secrets that were attempts after the first
+	VaultFetchRetries = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "vault_fetch_retries_total",
+			Help: "Total number of retried attempts to read a KV secret from Vault",
+		},
+	)
+
 	// CircuitBreakerState tracks circuit breaker state
 	CircuitBreakerState = promauto.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -51,6 +83,62 @@ var (
 		},
 	)
 
+	// SecretStale tracks secrets served from stale data while Vault is unavailable
+	SecretStale = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "secret_stale",
+			Help: "Whether a secret is served from stale data (1) or was last fetched from Vault (0)",
+		},
+		[]string{"secret_name"},
+	)
+
+	// SecretStaleAge tracks the age of the data stale secrets are served from
+	SecretStaleAge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "secret_stale_age_seconds",
+			Help: "Age of the data a stale secret is served from",
+		},
+		[]string{"secret_name"},
+	)
+
+	// SecretLastSyncTimestamp tracks when a secret was last synced from Vault
+	SecretLastSyncTimestamp = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "secret_last_sync_timestamp_seconds",
+			Help: "Unix time of the last sync that fetched the secret from Vault",
+		},
+		[]string{"secret_name"},
+	)
+
+	// SecretLastSyncSuccess tracks whether the last sync of a secret succeeded
+	SecretLastSyncSuccess = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "secret_last_sync_success",
+			Help: "Whether the last sync of a secret succeeded (1) or failed (0)",
+		},
+		[]string{"secret_name"},
+	)
+
+	// SecretSyncConsecutiveFailures tracks failed syncs of a secret since its
+	// last success. A gauge, since it drops back to 0 on success
+	SecretSyncConsecutiveFailures = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "secret_sync_consecutive_failures",
+			Help: "Number of syncs of a secret that failed in a row",
+		},
+		[]string{"secret_name"},
+	)
+
+	// SecretSyncAlerting tracks secrets whose failed syncs in a row reached
+	// their alert threshold
+	SecretSyncAlerting = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "secret_sync_alerting",
+			Help: "Whether a secret failed to sync often enough in a row to alert (1) or not (0)",
+		},
+		[]string{"secret_name"},
+	)
+
 	// SecretsSynced tracks number of successfully synced secrets
 	SecretsSynced = promauto.NewGauge(
 		prometheus.GaugeOpts{
@@ -58,6 +146,107 @@ var (
 			Help: "Number of successfully synced secrets",
 		},
 	)
+
+	// SecretDeleted tracks local files removed after their secret was deleted in Vault
+	SecretDeleted = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "secret_deleted_total",
+			Help: "Number of times the files of a secret deleted in Vault were removed or quarantined",
+		},
+		[]string{"secret_name", "action"},
+	)
+
+	// SecretFilesWritten tracks rendered files written because their content, mode or ownership changed
+	SecretFilesWritten = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "secret_files_written_total",
+			Help: "Number of rendered files written because they were missing or differed",
+		},
+		[]string{"secret_name"},
+	)
+
+	// SecretFilesUnchanged tracks rendered files left untouched because they were up to date
+	SecretFilesUnchanged = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "secret_files_unchanged_total",
+			Help: "Number of rendered files skipped because they already held the rendered content",
+		},
+		[]string{"secret_name"},
+	)
+
+	// FileDrift tracks managed files found to differ from what was written
+	FileDrift = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "file_drift_total",
+			Help: "Number of times a managed file was found missing, modified, or with wrong mode or ownership",
+		},
+		[]string{"secret_name", "kind"},
+	)
+
+	// FileRestored tracks managed files rewritten after being tampered with
+	FileRestored = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "file_restored_total",
+			Help: "Number of times a deleted, truncated or modified managed file was restored",
+		},
+		[]string{"secret_name", "status"},
+	)
+
+	// FileTampered tracks out-of-band changes to managed files caught by the
+	// file guard
+	FileTampered = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "secret_file_tampered_total",
+			Help: "Number of times a managed file was deleted, truncated or modified between syncs",
+		},
+		[]string{"secret_name", "reason"},
+	)
+
+	// Leader tracks whether this replica is the active writer
+	Leader = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "leader",
+			Help: "Whether this replica holds the leader lock and writes files (1) or stands by (0)",
+		},
+	)
+
+	// VaultSealed tracks whether syncing is paused because Vault is sealed
+	VaultSealed = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "vault_sealed",
+			Help: "Whether syncing is paused because Vault is sealed (1) or running (0)",
+		},
+	)
+
+	// VaultActiveEndpoint tracks which of the configured Vault addresses
+	// requests are sent to
+	VaultActiveEndpoint = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "vault_active_endpoint",
+			Help: "Whether requests are sent to this Vault address (1) or it is a standby endpoint (0)",
+		},
+		[]string{"address"},
+	)
+
+	// SecretCertificateExpiry tracks when the certificates in files with
+	// certExpiry set expire
+	SecretCertificateExpiry = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "secret_certificate_expiry_timestamp_seconds",
+			Help: "Unix timestamp at which the earliest-expiring certificate in a written file expires",
+		},
+		[]string{"secret_name", "file"},
+	)
+
+	// SecretLeaseExpiry tracks when the leased credentials of dynamic
+	// secrets expire
+	SecretLeaseExpiry = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "secret_lease_expiry_timestamp_seconds",
+			Help: "Unix timestamp at which the lease of the credentials written for a secret expires",
+		},
+		[]string{"secret_name"},
+	)
 )
 
 // RecordFetchSuccess records a successful secret fetch
@@ -76,6 +265,123 @@ func RecordSyncDuration(secretName string, duration float64) {
 	SecretSyncDuration.WithLabelValues(secretName).Observe(duration)
 }
 
+// RecordSyncPhase records the duration of a phase of a sync
+func RecordSyncPhase(ctx context.Context, secretName, phase string, d time.Duration) {
+	observe(ctx, SecretSyncPhaseDuration.WithLabelValues(secretName, phase), d.Seconds())
+}
+
+// RecordVaultFetch records one attempt to read a KV secret; attempt is 0
+// for the first
+func RecordVaultFetch(ctx context.Context, attempt int, d time.Duration, err error) {
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	observe(ctx, VaultFetchDuration.WithLabelValues(status), d.Seconds())
+	if attempt > 0 {
+		VaultFetchRetries.Inc()
+	}
+}
+
+// observe records a value, with the trace ID of the sampled span in ctx as
+// exemplar so a slow bucket links to the trace that landed in it
+func observe(ctx context.Context, o prometheus.Observer, v float64) {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsSampled() {
+		if eo, ok := o.(prometheus.ExemplarObserver); ok {
+			eo.ObserveWithExemplar(v, prometheus.Labels{"trace_id": sc.TraceID().String()})
+			return
+		}
+	}
+	o.Observe(v)
+}
+
+// SetSecretStale flags whether a secret is served from stale data
+func SetSecretStale(secretName string, stale bool, age float64) {
+	if stale {
+		SecretStale.WithLabelValues(secretName).Set(1)
+		SecretStaleAge.WithLabelValues(secretName).Set(age)
+		return
+	}
+	SecretStale.WithLabelValues(secretName).Set(0)
+	SecretStaleAge.WithLabelValues(secretName).Set(0)
+}
+
+// RecordSyncResult records the outcome of a sync. fresh reports whether the
+// data was fetched from Vault rather than served stale
+func RecordSyncResult(secretName string, success, fresh bool, at time.Time) {
+	if !success {
+		SecretLastSyncSuccess.WithLabelValues(secretName).Set(0)
+		SecretSyncConsecutiveFailures.WithLabelValues(secretName).Inc()
+		return
+	}
+	if fresh {
+		SecretLastSyncTimestamp.WithLabelValues(secretName).Set(float64(at.UnixNano()) / 1e9)
+	}
+	SecretLastSyncSuccess.WithLabelValues(secretName).Set(1)
+	SecretSyncConsecutiveFailures.WithLabelValues(secretName).Set(0)
+}
+
+// ForgetSecret removes the per-secret gauges of a secret that is no longer
+// configured, so it does not keep reporting its last state
+func ForgetSecret(secretName string) {
+	SecretLastSyncTimestamp.DeleteLabelValues(secretName)
+	SecretLastSyncSuccess.DeleteLabelValues(secretName)
+	SecretSyncConsecutiveFailures.DeleteLabelValues(secretName)
+	SecretSyncAlerting.DeleteLabelValues(secretName)
+	SecretStale.DeleteLabelValues(secretName)
+	SecretStaleAge.DeleteLabelValues(secretName)
+	SecretLeaseExpiry.DeleteLabelValues(secretName)
+	SecretCertificateExpiry.DeletePartialMatch(prometheus.Labels{"secret_name": secretName})
+}
+
+// SetLastSync sets when a secret was last fetched, e.g. as recorded before
+// a restart
+func SetLastSync(secretName string, at time.Time) {
+	SecretLastSyncTimestamp.WithLabelValues(secretName).Set(float64(at.UnixNano()) / 1e9)
+}
+
+// SetSecretAlerting records whether a secret is alerting
+func SetSecretAlerting(secretName string, alerting bool) {
+	if alerting {
+		SecretSyncAlerting.WithLabelValues(secretName).Set(1)
+	} else {
+		SecretSyncAlerting.WithLabelValues(secretName).Set(0)
+	}
+}
+
+// RecordSecretDeleted records that the files of a secret deleted in Vault were handled
+func RecordSecretDeleted(secretName, action string) {
+	SecretDeleted.WithLabelValues(secretName, action).Inc()
+}
+
+// RecordFileWrite records whether a rendered file was written or skipped as unchanged
+func RecordFileWrite(secretName string, written bool) {
+	if written {
+		SecretFilesWritten.WithLabelValues(secretName).Inc()
+		return
+	}
+	SecretFilesUnchanged.WithLabelValues(secretName).Inc()
+}
+
+// RecordFileDrift records that a managed file drifted from its expected state
+func RecordFileDrift(secretName, kind string) {
+	FileDrift.WithLabelValues(secretName, kind).Inc()
+}
+
+// RecordFileTampered records an out-of-band change to a managed file
+func RecordFileTampered(secretName, reason string) {
+	FileTampered.WithLabelValues(secretName, reason).Inc()
+}
+
+// RecordFileRestored records an attempt to restore a tampered file
+func RecordFileRestored(secretName string, success bool) {
+	status := "success"
+	if !success {
+		status = "error"
+	}
+	FileRestored.WithLabelValues(secretName, status).Inc()
+}
+
 // SetCircuitBreakerState sets the circuit breaker state
 func SetCircuitBreakerState(name, state string) {
 	var value float64
@@ -99,3 +405,52 @@ func SetSecretsConfigured(count int) {
 func SetSecretsSynced(count int) {
 	SecretsSynced.Set(float64(count))
 }
+
+// SetLeader records whether this replica is the active writer
+func SetLeader(leader bool) {
+	if leader {
+		Leader.Set(1)
+		return
+	}
+	Leader.Set(0)
+}
+
+// SetVaultSealed records whether syncing is paused because Vault is sealed
+func SetVaultSealed(sealed bool) {
+	if sealed {
+		VaultSealed.Set(1)
+		return
+	}
+	VaultSealed.Set(0)
+}
+
+// SetVaultActiveEndpoint records which of the Vault addresses is in use
+func SetVaultActiveEndpoint(active string, addresses []string) {
+	for _, address := range addresses {
+		if address == active {
+			VaultActiveEndpoint.WithLabelValues(address).Set(1)
+			continue
+		}
+		VaultActiveEndpoint.WithLabelValues(address).Set(0)
+	}
+}
+
+// SetCertificateExpiry records when the certificates in a file expire; the
+// zero time removes the file's series
+func SetCertificateExpiry(secretName, file string, notAfter time.Time) {
+	if notAfter.IsZero() {
+		SecretCertificateExpiry.DeleteLabelValues(secretName, file)
+		return
+	}
+	SecretCertificateExpiry.WithLabelValues(secretName, file).Set(float64(notAfter.Unix()))
+}
+
+// SetLeaseExpiry records when the lease of a secret expires; the zero time
+// removes the secret's series
+func SetLeaseExpiry(secretName string, expiresAt time.Time) {
+	if expiresAt.IsZero() {
+		SecretLeaseExpiry.DeleteLabelValues(secretName)
+		return
+	}
+	SecretLeaseExpiry.WithLabelValues(secretName).Set(float64(expiresAt.Unix()))
+}
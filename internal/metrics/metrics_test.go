@@ -1,9 +1,15 @@
 package metrics
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestRecordFetchSuccess(t *testing.T) {
@@ -32,6 +38,57 @@ func TestRecordSyncDuration(t *testing.T) {
 	t.Log("sync duration recorded successfully")
 }
 
+func TestRecordSecretDeleted(t *testing.T) {
+	RecordSecretDeleted("test-secret", "quarantine")
+
+	count := testutil.ToFloat64(SecretDeleted.WithLabelValues("test-secret", "quarantine"))
+	if count != 1 {
+		t.Errorf("expected count 1, got %f", count)
+	}
+}
+
+func TestRecordFileWrite(t *testing.T) {
+	RecordFileWrite("test-secret", true)
+	RecordFileWrite("test-secret", false)
+	RecordFileWrite("test-secret", false)
+
+	if count := testutil.ToFloat64(SecretFilesWritten.WithLabelValues("test-secret")); count != 1 {
+		t.Errorf("expected written count 1, got %f", count)
+	}
+	if count := testutil.ToFloat64(SecretFilesUnchanged.WithLabelValues("test-secret")); count != 2 {
+		t.Errorf("expected unchanged count 2, got %f", count)
+	}
+}
+
+func TestRecordFileDrift(t *testing.T) {
+	RecordFileDrift("test-secret", "mode")
+
+	count := testutil.ToFloat64(FileDrift.WithLabelValues("test-secret", "mode"))
+	if count != 1 {
+		t.Errorf("expected count 1, got %f", count)
+	}
+}
+
+func TestRecordFileRestored(t *testing.T) {
+	RecordFileRestored("test-secret", true)
+	RecordFileRestored("test-secret", false)
+
+	if count := testutil.ToFloat64(FileRestored.WithLabelValues("test-secret", "success")); count != 1 {
+		t.Errorf("expected success count 1, got %f", count)
+	}
+	if count := testutil.ToFloat64(FileRestored.WithLabelValues("test-secret", "error")); count != 1 {
+		t.Errorf("expected error count 1, got %f", count)
+	}
+}
+
+func TestRecordFileTampered(t *testing.T) {
+	RecordFileTampered("test-secret", "modified")
+
+	if count := testutil.ToFloat64(FileTampered.WithLabelValues("test-secret", "modified")); count != 1 {
+		t.Errorf("expected count 1, got %f", count)
+	}
+}
+
 func TestSetCircuitBreakerState(t *testing.T) {
 	tests := []struct {
 		state    string
@@ -69,3 +126,161 @@ func TestSetSecretsSynced(t *testing.T) {
 		t.Errorf("expected 3, got %f", value)
 	}
 }
+
+func TestSetLeader(t *testing.T) {
+	SetLeader(true)
+	if value := testutil.ToFloat64(Leader); value != 1 {
+		t.Errorf("expected 1, got %f", value)
+	}
+
+	SetLeader(false)
+	if value := testutil.ToFloat64(Leader); value != 0 {
+		t.Errorf("expected 0, got %f", value)
+	}
+}
+
+func TestSetVaultSealed(t *testing.T) {
+	SetVaultSealed(true)
+	if value := testutil.ToFloat64(VaultSealed); value != 1 {
+		t.Errorf("expected 1, got %f", value)
+	}
+
+	SetVaultSealed(false)
+	if value := testutil.ToFloat64(VaultSealed); value != 0 {
+		t.Errorf("expected 0, got %f", value)
+	}
+}
+
+func TestSetVaultActiveEndpoint(t *testing.T) {
+	addresses := []string{"https://vault-a:8200", "https://vault-b:8200"}
+	SetVaultActiveEndpoint(addresses[1], addresses)
+	if value := testutil.ToFloat64(VaultActiveEndpoint.WithLabelValues(addresses[0])); value != 0 {
+		t.Errorf("expected 0 for standby, got %f", value)
+	}
+	if value := testutil.ToFloat64(VaultActiveEndpoint.WithLabelValues(addresses[1])); value != 1 {
+		t.Errorf("expected 1 for active, got %f", value)
+	}
+}
+
+func TestRecordSyncResult(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	RecordSyncResult("sync-result", true, true, at)
+	RecordSyncResult("sync-result", false, false, at.Add(time.Minute))
+	RecordSyncResult("sync-result", false, false, at.Add(2*time.Minute))
+
+	if v := testutil.ToFloat64(SecretLastSyncTimestamp.WithLabelValues("sync-result")); v != 1700000000 {
+		t.Errorf("expected timestamp of last success, got %f", v)
+	}
+	if v := testutil.ToFloat64(SecretLastSyncSuccess.WithLabelValues("sync-result")); v != 0 {
+		t.Errorf("expected success 0, got %f", v)
+	}
+	if v := testutil.ToFloat64(SecretSyncConsecutiveFailures.WithLabelValues("sync-result")); v != 2 {
+		t.Errorf("expected 2 consecutive failures, got %f", v)
+	}
+
+	// A stale success resets failures but keeps the last fetch time
+	RecordSyncResult("sync-result", true, false, at.Add(3*time.Minute))
+	if v := testutil.ToFloat64(SecretLastSyncTimestamp.WithLabelValues("sync-result")); v != 1700000000 {
+		t.Errorf("expected stale sync to keep timestamp, got %f", v)
+	}
+	if v := testutil.ToFloat64(SecretLastSyncSuccess.WithLabelValues("sync-result")); v != 1 {
+		t.Errorf("expected success 1, got %f", v)
+	}
+	if v := testutil.ToFloat64(SecretSyncConsecutiveFailures.WithLabelValues("sync-result")); v != 0 {
+		t.Errorf("expected failures reset, got %f", v)
+	}
+}
+
+func TestSetCertificateExpiry(t *testing.T) {
+	notAfter := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	SetCertificateExpiry("tls", "/secrets/tls.crt", notAfter)
+
+	if got := testutil.ToFloat64(SecretCertificateExpiry.WithLabelValues("tls", "/secrets/tls.crt")); got != float64(notAfter.Unix()) {
+		t.Errorf("expected %d, got %f", notAfter.Unix(), got)
+	}
+
+	SetCertificateExpiry("tls", "/secrets/tls.crt", time.Time{})
+	if count := testutil.CollectAndCount(SecretCertificateExpiry); count != 0 {
+		t.Errorf("expected the series to be removed, got %d", count)
+	}
+}
+
+func TestSetLeaseExpiry(t *testing.T) {
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	SetLeaseExpiry("db", expiresAt)
+
+	if got := testutil.ToFloat64(SecretLeaseExpiry.WithLabelValues("db")); got != float64(expiresAt.Unix()) {
+		t.Errorf("expected %d, got %f", expiresAt.Unix(), got)
+	}
+
+	SetLeaseExpiry("db", time.Time{})
+	if count := testutil.CollectAndCount(SecretLeaseExpiry); count != 0 {
+		t.Errorf("expected the series to be removed, got %d", count)
+	}
+}
+
+func TestForgetSecret(t *testing.T) {
+	RecordSyncResult("removed", true, true, time.Now())
+	RecordSyncResult("removed", false, false, time.Now())
+	SetSecretStale("removed", true, 60)
+	SetCertificateExpiry("removed", "/secrets/removed.crt", time.Now())
+	RecordSyncResult("kept", true, true, time.Now())
+
+	ForgetSecret("removed")
+
+	for name, vec := range map[string]*prometheus.GaugeVec{
+		"last sync":            SecretLastSyncTimestamp,
+		"last success":         SecretLastSyncSuccess,
+		"consecutive failures": SecretSyncConsecutiveFailures,
+		"stale":                SecretStale,
+		"stale age":            SecretStaleAge,
+		"certificate expiry":   SecretCertificateExpiry,
+	} {
+		if vec.DeletePartialMatch(prometheus.Labels{"secret_name": "removed"}) != 0 {
+			t.Errorf("%s: expected the series of the removed secret to be gone", name)
+		}
+	}
+	if !SecretLastSyncSuccess.DeleteLabelValues("kept") {
+		t.Error("expected the series of other secrets to be kept")
+	}
+}
+
+func TestRecordSyncPhase_Exemplar(t *testing.T) {
+	traceID := trace.TraceID{1, 2, 3}
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     trace.SpanID{4},
+		TraceFlags: trace.FlagsSampled,
+	}))
+	RecordSyncPhase(ctx, "exemplar-secret", "fetch", 250*time.Millisecond)
+
+	var m dto.Metric
+	if err := SecretSyncPhaseDuration.WithLabelValues("exemplar-secret", "fetch").(prometheus.Metric).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	if m.GetHistogram().GetSampleCount() != 1 {
+		t.Fatalf("expected one observation, got %d", m.GetHistogram().GetSampleCount())
+	}
+	var found bool
+	for _, bucket := range m.GetHistogram().GetBucket() {
+		if e := bucket.GetExemplar(); e != nil && len(e.GetLabel()) == 1 && e.GetLabel()[0].GetValue() == traceID.String() {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected the trace ID as exemplar")
+	}
+}
+
+func TestRecordVaultFetch(t *testing.T) {
+	before := testutil.ToFloat64(VaultFetchRetries)
+	RecordVaultFetch(context.Background(), 0, time.Millisecond, errors.New("unavailable"))
+	RecordVaultFetch(context.Background(), 1, time.Millisecond, nil)
+
+	if got := testutil.ToFloat64(VaultFetchRetries) - before; got != 1 {
+		t.Errorf("expected one retry, got %f", got)
+	}
+	if count := testutil.CollectAndCount(VaultFetchDuration); count != 2 {
+		t.Errorf("expected success and error series, got %d", count)
+	}
+}
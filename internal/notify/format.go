@@ -0,0 +1,47 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+)
+
+// slackMessage is the body of a Slack incoming webhook, also understood by
+// Mattermost and Rocket.Chat
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// payload renders an event in the format of a webhook
+func payload(format string, event Event) ([]byte, error) {
+	var v any = event
+	if format == config.WebhookFormatSlack {
+		v = slackMessage{Text: slackText(event)}
+	}
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode notification: %w", err)
+	}
+	return body, nil
+}
+
+// slackText renders an event as one line of Slack markup
+func slackText(event Event) string {
+	icon := ":rotating_light:"
+	switch event.Type {
+	case config.EventRecovery:
+		icon = ":white_check_mark:"
+	case config.EventExpiry:
+		icon = ":hourglass:"
+	}
+
+	text := fmt.Sprintf("%s *%s*", icon, event.Message)
+	if event.Host != "" {
+		text += fmt.Sprintf(" on `%s`", event.Host)
+	}
+	if event.Error != "" {
+		text += fmt.Sprintf(": %s", event.Error)
+	}
+	return text
+}
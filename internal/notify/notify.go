@@ -0,0 +1,229 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/syncer"
+)
+
+// sendTimeout bounds one webhook request
+const sendTimeout = 10 * time.Second
+
+// queueSize is how many deliveries may wait for a slow webhook before new
+// ones are dropped
+const queueSize = 100
+
+// Event is a notification about one secret, POSTed as JSON to webhooks
+// with the json format
+type Event struct {
+	Type      string     `json:"event"` // failure, recovery or expiry
+	Secret    string     `json:"secret"`
+	Message   string     `json:"message"`
+	Host      string     `json:"host,omitempty"`
+	Failures  int        `json:"consecutive_failures,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Timestamp time.Time  `json:"timestamp"`
+}
+
+// Notifier turns sync results into events and delivers them to the
+// configured webhooks in the background, one at a time
+type Notifier struct {
+	client   *http.Client
+	host     string
+	onSent   func(event Event, endpoint string, err error)
+	mu       sync.Mutex
+	cfg      config.Notifications
+	alerting map[string]bool      // Secrets a failure event was sent for
+	warned   map[string]time.Time // Lease expiry an expiry event was sent for, by secret
+	queue    chan delivery
+	stopCh   chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+type delivery struct {
+	webhook config.Webhook
+	event   Event
+}
+
+// New creates a notifier; onSent is called after every delivery attempt
+// with its result and the scheme and host of the webhook, which leaves out
+// credentials carried in the URL
+func New(cfg config.Notifications, onSent func(event Event, endpoint string, err error)) *Notifier {
+	host, _ := os.Hostname()
+	return &Notifier{
+		client:   &http.Client{Timeout: sendTimeout},
+		host:     host,
+		onSent:   onSent,
+		cfg:      cfg,
+		alerting: make(map[string]bool),
+		warned:   make(map[string]time.Time),
+		queue:    make(chan delivery, queueSize),
+		stopCh:   make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Configure replaces the webhooks, e.g. after a config reload
+func (n *Notifier) Configure(cfg config.Notifications) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.cfg = cfg
+}
+
+// Start begins delivering events
+func (n *Notifier) Start() {
+	go n.run()
+}
+
+// Stop waits for the delivery in progress; queued events are dropped
+func (n *Notifier) Stop() {
+	n.stopOnce.Do(func() {
+		close(n.stopCh)
+	})
+	<-n.done
+}
+
+// Observe queues the events a sync result raises for every webhook
+// subscribed to them. It never blocks: events are dropped while the queue is
+// full.
+func (n *Notifier) Observe(result syncer.SyncResult) {
+	n.mu.Lock()
+	events := n.events(result)
+	webhooks := n.cfg.Webhooks
+	n.mu.Unlock()
+
+	for _, event := range events {
+		for _, webhook := range webhooks {
+			if !webhook.Wants(event.Type) {
+				continue
+			}
+			select {
+			case n.queue <- delivery{webhook: webhook, event: event}:
+			default:
+				n.report(event, webhook, fmt.Errorf("notification queue full"))
+			}
+		}
+	}
+}
+
+// events returns the events raised by a sync result; the caller holds n.mu
+func (n *Notifier) events(result syncer.SyncResult) []Event {
+	var events []Event
+	newEvent := func(eventType, message string) Event {
+		event := Event{
+			Type:      eventType,
+			Secret:    result.SecretName,
+			Message:   message,
+			Host:      n.host,
+			Failures:  result.Failures,
+			Timestamp: result.Timestamp,
+		}
+		if result.Error != nil {
+			event.Error = result.Error.Error()
+		}
+		return event
+	}
+
+	switch {
+	case result.Alerting && !n.alerting[result.SecretName]:
+		n.alerting[result.SecretName] = true
+		events = append(events, newEvent(config.EventFailure,
+			fmt.Sprintf("secret %s failed %d syncs in a row", result.SecretName, result.Failures)))
+	case result.Error == nil && n.alerting[result.SecretName]:
+		delete(n.alerting, result.SecretName)
+		events = append(events, newEvent(config.EventRecovery,
+			fmt.Sprintf("secret %s synced again", result.SecretName)))
+	}
+
+	if result.Error == nil || result.ExpiresAt.IsZero() {
+		return events
+	}
+	if time.Until(result.ExpiresAt) > n.cfg.ExpiryWarning || n.warned[result.SecretName].Equal(result.ExpiresAt) {
+		return events
+	}
+	n.warned[result.SecretName] = result.ExpiresAt
+	event := newEvent(config.EventExpiry, fmt.Sprintf("credentials of secret %s expire at %s and could not be replaced",
+		result.SecretName, result.ExpiresAt.UTC().Format(time.RFC3339)))
+	expiresAt := result.ExpiresAt
+	event.ExpiresAt = &expiresAt
+	return append(events, event)
+}
+
+// run delivers queued events until Stop
+func (n *Notifier) run() {
+	defer close(n.done)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-n.stopCh
+		cancel()
+	}()
+
+	for {
+		select {
+		case d := <-n.queue:
+			n.report(d.event, d.webhook, n.send(ctx, d.webhook, d.event))
+		case <-n.stopCh:
+			return
+		}
+	}
+}
+
+// send POSTs an event to a webhook
+func (n *Notifier) send(ctx context.Context, webhook config.Webhook, event Event) error {
+	body, err := payload(webhook.Format, event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range webhook.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		// The error quotes the URL, which may hold credentials
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// report passes the result of a delivery to onSent
+func (n *Notifier) report(event Event, webhook config.Webhook, err error) {
+	if n.onSent != nil {
+		n.onSent(event, endpoint(webhook.URL), err)
+	}
+}
+
+// endpoint returns the scheme and host of a webhook URL
+func endpoint(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
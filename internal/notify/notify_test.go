@@ -0,0 +1,179 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/syncer"
+)
+
+// receiver collects the bodies POSTed to it
+type receiver struct {
+	server *httptest.Server
+	bodies chan map[string]any
+	header chan http.Header
+}
+
+func newReceiver(t *testing.T) *receiver {
+	r := &receiver{bodies: make(chan map[string]any, 10), header: make(chan http.Header, 10)}
+	r.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.header <- req.Header
+		r.bodies <- body
+	}))
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+func (r *receiver) next(t *testing.T) map[string]any {
+	t.Helper()
+	select {
+	case body := <-r.bodies:
+		return body
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for notification")
+		return nil
+	}
+}
+
+func (r *receiver) none(t *testing.T) {
+	t.Helper()
+	select {
+	case body := <-r.bodies:
+		t.Errorf("expected no notification, got %v", body)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func failed(failures int, alerting bool) syncer.SyncResult {
+	return syncer.SyncResult{
+		SecretName: "db",
+		Error:      errors.New("permission denied"),
+		Timestamp:  time.Now(),
+		Failures:   failures,
+		Alerting:   alerting,
+	}
+}
+
+func TestNotifier_FailureAndRecovery(t *testing.T) {
+	r := newReceiver(t)
+	n := New(config.Notifications{Webhooks: []config.Webhook{{
+		URL:     r.server.URL,
+		Headers: map[string]string{"Authorization": "Bearer token"},
+	}}}, nil)
+	n.Start()
+	defer n.Stop()
+
+	n.Observe(failed(1, false))
+	r.none(t)
+
+	// Sent once when the secret starts alerting
+	n.Observe(failed(2, true))
+	body := r.next(t)
+	if body["event"] != config.EventFailure || body["secret"] != "db" || body["consecutive_failures"] != 2.0 {
+		t.Errorf("unexpected failure event: %v", body)
+	}
+	if body["error"] != "permission denied" {
+		t.Errorf("expected error in event, got %v", body["error"])
+	}
+	if got := (<-r.header).Get("Authorization"); got != "Bearer token" {
+		t.Errorf("expected configured header, got %q", got)
+	}
+	n.Observe(failed(3, true))
+	r.none(t)
+
+	n.Observe(syncer.SyncResult{SecretName: "db", Success: true, Timestamp: time.Now(), Recovered: true})
+	if body := r.next(t); body["event"] != config.EventRecovery {
+		t.Errorf("expected recovery event, got %v", body)
+	}
+
+	// No recovery for secrets that never alerted
+	n.Observe(syncer.SyncResult{SecretName: "other", Success: true, Timestamp: time.Now()})
+	r.none(t)
+}
+
+func TestNotifier_Expiry(t *testing.T) {
+	r := newReceiver(t)
+	n := New(config.Notifications{
+		Webhooks:      []config.Webhook{{URL: r.server.URL, Events: []string{config.EventExpiry}}},
+		ExpiryWarning: time.Hour,
+	}, nil)
+	n.Start()
+	defer n.Stop()
+
+	result := failed(1, true)
+	result.ExpiresAt = time.Now().Add(2 * time.Hour)
+	n.Observe(result)
+	r.none(t) // Not subscribed to failures, expiry still far
+
+	result.ExpiresAt = time.Now().Add(30 * time.Minute)
+	n.Observe(result)
+	body := r.next(t)
+	if body["event"] != config.EventExpiry || body["expires_at"] == nil {
+		t.Errorf("unexpected expiry event: %v", body)
+	}
+
+	// Warned once per lease
+	n.Observe(result)
+	r.none(t)
+}
+
+func TestNotifier_Slack(t *testing.T) {
+	r := newReceiver(t)
+	var sent []error
+	done := make(chan struct{}, 1)
+	n := New(config.Notifications{Webhooks: []config.Webhook{{URL: r.server.URL + "/services/T0/B0/secret", Format: config.WebhookFormatSlack}}},
+		func(event Event, endpoint string, err error) {
+			if strings.Contains(endpoint, "secret") {
+				t.Errorf("endpoint leaks the webhook path: %s", endpoint)
+			}
+			sent = append(sent, err)
+			done <- struct{}{}
+		})
+	n.Start()
+	defer n.Stop()
+
+	n.Observe(failed(3, true))
+	body := r.next(t)
+	text, _ := body["text"].(string)
+	if !strings.Contains(text, "secret db failed 3 syncs in a row") || !strings.Contains(text, "permission denied") {
+		t.Errorf("unexpected slack text: %q", text)
+	}
+	<-done
+	if len(sent) != 1 || sent[0] != nil {
+		t.Errorf("expected one successful delivery, got %v", sent)
+	}
+}
+
+func TestNotifier_WebhookError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	errs := make(chan error, 1)
+	n := New(config.Notifications{Webhooks: []config.Webhook{{URL: server.URL}}},
+		func(event Event, endpoint string, err error) { errs <- err })
+	n.Start()
+	defer n.Stop()
+
+	n.Observe(failed(3, true))
+	select {
+	case err := <-errs:
+		if err == nil || !strings.Contains(err.Error(), "500") {
+			t.Errorf("expected status error, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for delivery")
+	}
+}
@@ -0,0 +1,65 @@
+// Package privdrop lets the daemon start as root for setup and continue as
+// an unprivileged user
+package privdrop
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// ErrUnsupported is returned on platforms where privileges cannot be dropped
+var ErrUnsupported = errors.New("dropping privileges is not supported on this platform")
+
+// Config holds the identity to switch to
+type Config struct {
+	UID int
+	GID int
+	// KeepChownCaps retains CAP_CHOWN and CAP_FOWNER after the switch, so
+	// files can still be handed to their configured owner and group
+	KeepChownCaps bool
+	// KeepIPCLock retains CAP_IPC_LOCK after the switch, so memory locked
+	// with mlockall can keep growing past RLIMIT_MEMLOCK
+	KeepIPCLock bool
+}
+
+// ParseID parses a numeric user or group ID, returning -1 for empty input
+func ParseID(id string) (int, error) {
+	if id == "" {
+		return -1, nil
+	}
+
+	n, err := strconv.Atoi(id)
+	if err != nil || n < 0 {
+		return -1, fmt.Errorf("invalid id %q: must be a non-negative number", id)
+	}
+
+	return n, nil
+}
+
+// PrepareDirs creates dirs and hands them to the target user and group so
+// the unprivileged process can create and rename files in them. Sticky
+// directories like /tmp are left untouched.
+func PrepareDirs(cfg Config, dirs []string) error {
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+		info, err := os.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("failed to stat directory %s: %w", dir, err)
+		}
+		// Shared sticky directories such as /tmp are writable already
+		if info.Mode()&os.ModeSticky != 0 {
+			continue
+		}
+		if err := os.Chown(dir, cfg.UID, cfg.GID); err != nil {
+			return fmt.Errorf("failed to chown directory %s: %w", dir, err)
+		}
+	}
+	return nil
+}
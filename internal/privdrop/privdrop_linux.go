@@ -0,0 +1,93 @@
+//go:build linux
+// +build linux
+
+package privdrop
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// ErrKeepCapsUnsupported is returned when capabilities cannot be kept on
+// every thread, as in a binary built with cgo
+var ErrKeepCapsUnsupported = errors.New("keeping capabilities requires a binary built with CGO_ENABLED=0")
+
+// Drop switches every thread of the process to cfg.UID/cfg.GID, keeping
+// only the capabilities cfg asks for. It must be called as root and cannot
+// be undone.
+func Drop(cfg Config) error {
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("must run as root to drop privileges (euid %d)", os.Geteuid())
+	}
+	if cfg.UID <= 0 || cfg.GID < 0 {
+		return fmt.Errorf("a non-root uid and a gid are required")
+	}
+
+	var caps []uint
+	if cfg.KeepChownCaps {
+		caps = append(caps, unix.CAP_CHOWN, unix.CAP_FOWNER)
+	}
+	if cfg.KeepIPCLock {
+		caps = append(caps, unix.CAP_IPC_LOCK)
+	}
+	if len(caps) > 0 && !keepCapsSupported() {
+		return ErrKeepCapsUnsupported
+	}
+
+	if len(caps) > 0 {
+		// Keep permitted capabilities across the uid change
+		if _, _, errno := syscall.AllThreadsSyscall(syscall.SYS_PRCTL, unix.PR_SET_KEEPCAPS, 1, 0); errno != 0 {
+			return fmt.Errorf("failed to set keepcaps: %w", errno)
+		}
+	}
+
+	// Go applies these to all threads of the process
+	if err := syscall.Setgroups([]int{cfg.GID}); err != nil {
+		return fmt.Errorf("failed to set supplementary groups: %w", err)
+	}
+	if err := syscall.Setgid(cfg.GID); err != nil {
+		return fmt.Errorf("failed to set gid %d: %w", cfg.GID, err)
+	}
+	if err := syscall.Setuid(cfg.UID); err != nil {
+		return fmt.Errorf("failed to set uid %d: %w", cfg.UID, err)
+	}
+
+	if len(caps) > 0 {
+		if err := limitCapabilities(caps...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// limitCapabilities reduces the effective and permitted sets of all threads
+// to exactly caps
+func limitCapabilities(caps ...uint) error {
+	hdr := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	var data [2]unix.CapUserData
+	for _, c := range caps {
+		data[c/32].Effective |= 1 << (c % 32)
+		data[c/32].Permitted |= 1 << (c % 32)
+	}
+
+	if _, _, errno := syscall.AllThreadsSyscall(unix.SYS_CAPSET,
+		uintptr(unsafe.Pointer(&hdr)), uintptr(unsafe.Pointer(&data[0])), 0); errno != 0 {
+		return fmt.Errorf("failed to set capabilities: %w", errno)
+	}
+
+	return nil
+}
+
+// keepCapsSupported reports whether capabilities can be kept. They are per
+// thread, and Go only sets them on all threads without cgo, where
+// AllThreadsSyscall fails before doing anything.
+func keepCapsSupported() bool {
+	_, _, errno := syscall.AllThreadsSyscall(syscall.SYS_GETPID, 0, 0, 0)
+	return errno != syscall.ENOTSUP
+}
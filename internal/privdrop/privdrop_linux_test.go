@@ -0,0 +1,121 @@
+//go:build linux
+// +build linux
+
+package privdrop
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+const nobodyID = 65534
+
+// TestDrop runs in a child process, since the switch cannot be undone
+func TestDrop(t *testing.T) {
+	if os.Getenv("PRIVDROP_TEST_HELPER") != "" {
+		runDropHelper(os.Getenv("PRIVDROP_TEST_HELPER"))
+		return
+	}
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+
+	for _, mode := range []string{"plain", "caps", "mlock"} {
+		t.Run(mode, func(t *testing.T) {
+			if mode != "plain" && !keepCapsSupported() {
+				t.Skip("capabilities cannot be kept with cgo")
+			}
+			// t.TempDir nests below a root-only directory nobody cannot traverse
+			dir, err := os.MkdirTemp("", "privdrop")
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = os.RemoveAll(dir) })
+			if err := os.Chmod(dir, 0777); err != nil {
+				t.Fatal(err)
+			}
+
+			cmd := exec.Command(os.Args[0], "-test.run=^TestDrop$")
+			cmd.Env = append(os.Environ(), "PRIVDROP_TEST_HELPER="+mode, "PRIVDROP_TEST_DIR="+dir)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("helper failed: %v\n%s", err, out)
+			}
+		})
+	}
+}
+
+func runDropHelper(mode string) {
+	fail := func(msg string) {
+		_, _ = os.Stderr.WriteString(msg)
+		os.Exit(1)
+	}
+
+	keepCaps := mode == "caps"
+	keepIPCLock := mode == "mlock"
+	if keepIPCLock {
+		// Lock as root with a small limit, as the daemon does before the drop
+		limit := unix.Rlimit{Cur: 64 << 10, Max: 64 << 10}
+		if err := unix.Setrlimit(unix.RLIMIT_MEMLOCK, &limit); err != nil {
+			fail("setrlimit failed: " + err.Error())
+		}
+		if err := unix.Mlockall(unix.MCL_CURRENT | unix.MCL_FUTURE); err != nil {
+			fail("mlockall failed: " + err.Error())
+		}
+	}
+
+	if err := Drop(Config{UID: nobodyID, GID: nobodyID, KeepChownCaps: keepCaps, KeepIPCLock: keepIPCLock}); err != nil {
+		fail("drop failed: " + err.Error())
+	}
+	if os.Getuid() != nobodyID || os.Geteuid() != nobodyID || os.Getgid() != nobodyID {
+		fail("identity not switched: uid=" + strconv.Itoa(os.Getuid()))
+	}
+
+	if keepIPCLock {
+		// Without CAP_IPC_LOCK the runtime aborts once the heap outgrows the limit
+		buf := make([]byte, 32<<20)
+		for i := range buf {
+			buf[i] = 1
+		}
+		runtime.KeepAlive(buf)
+	}
+
+	path := filepath.Join(os.Getenv("PRIVDROP_TEST_DIR"), "file")
+	if err := os.WriteFile(path, []byte("x"), 0600); err != nil {
+		fail("write failed: " + err.Error())
+	}
+
+	err := os.Chown(path, 0, 0)
+	if keepCaps && err != nil {
+		fail("chown with CAP_CHOWN failed: " + err.Error())
+	}
+	if !keepCaps && !errors.Is(err, os.ErrPermission) {
+		fail("chown without capabilities was not denied")
+	}
+
+	os.Exit(0)
+}
+
+func TestDrop_KeepCapsUnsupported(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+	if keepCapsSupported() {
+		t.Skip("capabilities can be kept without cgo")
+	}
+
+	// Rejected before the identity changes, so this runs in-process
+	err := Drop(Config{UID: nobodyID, GID: nobodyID, KeepChownCaps: true})
+	if !errors.Is(err, ErrKeepCapsUnsupported) {
+		t.Fatalf("expected ErrKeepCapsUnsupported, got: %v", err)
+	}
+	if os.Geteuid() != 0 {
+		t.Fatal("identity changed")
+	}
+}
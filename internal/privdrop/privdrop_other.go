@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package privdrop
+
+// Drop is not available on this platform
+func Drop(cfg Config) error {
+	return ErrUnsupported
+}
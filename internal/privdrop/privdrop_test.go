@@ -0,0 +1,53 @@
+package privdrop
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseID(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{"", -1, false},
+		{"0", 0, false},
+		{"1000", 1000, false},
+		{"-5", -1, true},
+		{"nobody", -1, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseID(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseID(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ParseID(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestPrepareDirs_SkipsSticky(t *testing.T) {
+	tmpDir := t.TempDir()
+	sticky := filepath.Join(tmpDir, "shared")
+	if err := os.Mkdir(sticky, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(sticky, 0755|os.ModeSticky); err != nil {
+		t.Fatal(err)
+	}
+
+	// An unusable uid would fail the chown if the directory were not skipped
+	cfg := Config{UID: os.Getuid(), GID: os.Getgid()}
+	created := filepath.Join(tmpDir, "out", "nested")
+	if err := PrepareDirs(cfg, []string{sticky, created, ""}); err != nil {
+		t.Fatalf("PrepareDirs failed: %v", err)
+	}
+
+	if info, err := os.Stat(created); err != nil || !info.IsDir() {
+		t.Errorf("expected %s to be created", created)
+	}
+}
@@ -0,0 +1,81 @@
+package remoteconfig
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// fetchTimeout bounds a single fetch of the configuration
+const fetchTimeout = 30 * time.Second
+
+// httpFetcher fetches the configuration with an HTTP GET. A password in the
+// URL is sent as basic auth, and the ETag of the last response is sent so
+// an unchanged config is not transferred again.
+type httpFetcher struct {
+	url      string
+	user     *url.Userinfo
+	client   *http.Client
+	mu       sync.Mutex
+	etag     string
+	lastData []byte
+}
+
+func newHTTPFetcher(u *url.URL) (*httpFetcher, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid config URL: missing host")
+	}
+	stripped := *u
+	stripped.User = nil
+	return &httpFetcher{
+		url:    stripped.String(),
+		user:   u.User,
+		client: &http.Client{Timeout: fetchTimeout},
+	}, nil
+}
+
+func (f *httpFetcher) fetch(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if f.user != nil {
+		password, _ := f.user.Password()
+		req.SetBasicAuth(f.user.Username(), password)
+	}
+	return f.do(req)
+}
+
+// do sends a request for the configuration, returning the data of the last
+// response if it is unchanged
+func (f *httpFetcher) do(req *http.Request) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.etag != "" {
+		req.Header.Set("If-None-Match", f.etag)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && f.lastData != nil:
+		return f.lastData, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	data, err := readLimited(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.etag = resp.Header.Get("ETag")
+	f.lastData = data
+	return data, nil
+}
@@ -0,0 +1,116 @@
+package remoteconfig
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// serveConfig serves data with an ETag, answering a matching If-None-Match
+// with 304 Not Modified
+func serveConfig(t *testing.T, data string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(data))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPFetcher_Load(t *testing.T) {
+	server := serveConfig(t, testConfig)
+	source, err := New(server.URL + "/config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := source.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.Secrets) != 1 || cfg.Secrets[0].Name != "app" {
+		t.Errorf("Load() secrets = %+v, want app", cfg.Secrets)
+	}
+}
+
+func TestHTTPFetcher_NotModifiedReturnsLastData(t *testing.T) {
+	var requests atomic.Int32
+	server := serveConfig(t, testConfig)
+	counting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		server.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer counting.Close()
+
+	source, err := New(counting.URL + "/config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, changed, err := source.load(context.Background(), true)
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if changed || cfg != nil {
+		t.Errorf("load() changed = %v, want unchanged after 304", changed)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("requests = %d, want 2", requests.Load())
+	}
+}
+
+func TestHTTPFetcher_BasicAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "deploy" || password != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(testConfig))
+	}))
+	defer server.Close()
+
+	source, err := New(strings.Replace(server.URL, "://", "://deploy:s3cret@", 1) + "/config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+}
+
+func TestHTTPFetcher_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/large.yaml" {
+			_, _ = w.Write(make([]byte, MaxConfigSize+1))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	for path, want := range map[string]string{
+		"/missing.yaml": "404",
+		"/large.yaml":   "exceeds",
+	} {
+		source, err := New(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = source.Load(context.Background())
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Load(%s) error = %v, want %q", path, err, want)
+		}
+	}
+}
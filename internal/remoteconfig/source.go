@@ -0,0 +1,120 @@
+// Package remoteconfig loads the configuration from a remote source: an
+// HTTP(S) URL, an S3 or MinIO object, or a Vault KV secret.
+package remoteconfig
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+)
+
+// MaxConfigSize is the maximum size of a fetched configuration (10MB)
+const MaxConfigSize = 10 * 1024 * 1024
+
+// fetcher fetches the raw configuration from one kind of source
+type fetcher interface {
+	fetch(ctx context.Context) ([]byte, error)
+}
+
+// Source is a remote configuration
+type Source struct {
+	url     *url.URL
+	format  string
+	fetcher fetcher
+
+	mu     sync.Mutex
+	loaded [sha256.Size]byte // Hash of the data last loaded
+}
+
+// New returns the source of a configuration URL: http:// or https://,
+// s3://<bucket>/<key> or vault://<mount>/<path>. The format query parameter
+// sets the config format where the path has no .yaml, .json or .toml
+// extension.
+func New(rawURL string) (*Source, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config URL: %w", err)
+	}
+
+	s := &Source{url: u, format: u.Query().Get("format")}
+	switch u.Scheme {
+	case "http", "https":
+		s.fetcher, err = newHTTPFetcher(u)
+	case "s3":
+		s.fetcher, err = newS3Fetcher(u)
+	case "vault":
+		s.fetcher, err = newVaultFetcher(u)
+	default:
+		return nil, fmt.Errorf("unsupported config URL scheme %q (supported: http, https, s3, vault)", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch s.format {
+	case "", config.FormatYAML, config.FormatJSON, config.FormatTOML:
+	default:
+		return nil, fmt.Errorf("unsupported config format: %s (supported: yaml, json, toml)", s.format)
+	}
+	return s, nil
+}
+
+// String returns the URL with any password redacted, for logging
+func (s *Source) String() string {
+	return s.url.Redacted()
+}
+
+// Load fetches and parses the configuration
+func (s *Source) Load(ctx context.Context) (*config.Config, error) {
+	cfg, _, err := s.load(ctx, false)
+	return cfg, err
+}
+
+// load fetches and parses the configuration. With changedOnly, it returns
+// a nil config if the data is the same as last loaded.
+func (s *Source) load(ctx context.Context, changedOnly bool) (*config.Config, bool, error) {
+	data, err := s.fetcher.fetch(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to fetch config from %s: %w", s, err)
+	}
+
+	hash := sha256.Sum256(data)
+	s.mu.Lock()
+	unchanged := hash == s.loaded
+	// An invalid config is reported once, not on every poll
+	s.loaded = hash
+	s.mu.Unlock()
+	if changedOnly && unchanged {
+		return nil, false, nil
+	}
+
+	cfg, err := config.LoadData(ctx, s.url.Path, s.format, data)
+	if err != nil {
+		return nil, true, fmt.Errorf("%s: %w", s, err)
+	}
+	return cfg, true, nil
+}
+
+// readLimited reads a response body of at most MaxConfigSize bytes
+func readLimited(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxConfigSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	if len(data) > MaxConfigSize {
+		return nil, fmt.Errorf("config exceeds %d bytes", MaxConfigSize)
+	}
+	return data, nil
+}
+
+// splitPath returns the host of a URL and its path without the leading
+// slash, e.g. the bucket and key of an S3 URL
+func splitPath(u *url.URL) (string, string) {
+	return u.Host, strings.TrimPrefix(u.Path, "/")
+}
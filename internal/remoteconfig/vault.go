@@ -0,0 +1,129 @@
+package remoteconfig
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+
+	"github.com/ohauer/secrets-sync/internal/errkind"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+// defaultVaultField is the field of a Vault secret holding the config
+const defaultVaultField = "config"
+
+// vaultFetcher reads the configuration from a field of a Vault KV secret.
+// The client is configured from VAULT_ADDR and the other VAULT_* variables
+// the Vault API reads, and logs in with VAULT_ROLE_ID and VAULT_SECRET_ID,
+// or else VAULT_TOKEN.
+type vaultFetcher struct {
+	mount     string
+	path      string
+	kvVersion string
+	field     string
+	namespace string
+
+	mu     sync.Mutex
+	client *vault.Client // Authenticated on first use
+}
+
+func newVaultFetcher(u *url.URL) (*vaultFetcher, error) {
+	mount, path := splitPath(u)
+	if mount == "" || path == "" {
+		return nil, fmt.Errorf("invalid config URL: expected vault://<mount>/<path>")
+	}
+
+	query := u.Query()
+	f := &vaultFetcher{
+		mount:     mount,
+		path:      path,
+		kvVersion: query.Get("kvVersion"),
+		field:     query.Get("field"),
+		namespace: query.Get("namespace"),
+	}
+	if f.kvVersion == "" {
+		f.kvVersion = "v2"
+	}
+	if f.kvVersion != "v1" && f.kvVersion != "v2" {
+		return nil, fmt.Errorf("invalid config URL: kvVersion must be v1 or v2")
+	}
+	if f.field == "" {
+		f.field = defaultVaultField
+	}
+	return f, nil
+}
+
+func (f *vaultFetcher) fetch(ctx context.Context) ([]byte, error) {
+	client, err := f.vaultClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := client.FetchSecret(ctx, f.mount, f.path, f.kvVersion, f.namespace)
+	if errkind.Of(err) == errkind.Permission {
+		// The token may have expired or been revoked; log in again and ask once more
+		f.dropClient(client)
+		if client, err = f.vaultClient(ctx); err != nil {
+			return nil, err
+		}
+		data, err = client.FetchSecret(ctx, f.mount, f.path, f.kvVersion, f.namespace)
+	}
+	if err != nil {
+		return nil, err
+	}
+	value, ok := data[f.field]
+	if !ok {
+		return nil, fmt.Errorf("secret %s/%s has no field %q", f.mount, f.path, f.field)
+	}
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("field %q of secret %s/%s is not a string", f.field, f.mount, f.path)
+	}
+	if len(s) > MaxConfigSize {
+		return nil, fmt.Errorf("config exceeds %d bytes", MaxConfigSize)
+	}
+	return []byte(s), nil
+}
+
+// vaultClient returns the client, logging in on first use
+func (f *vaultFetcher) vaultClient(ctx context.Context) (*vault.Client, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.client != nil {
+		return f.client, nil
+	}
+
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return nil, fmt.Errorf("VAULT_ADDR is required for a vault:// config URL")
+	}
+	client, err := vault.NewClient(addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Authenticate(ctx, vaultAuthConfig()); err != nil {
+		return nil, err
+	}
+	f.client = client
+	return client, nil
+}
+
+// dropClient forgets client, so the next fetch logs in again
+func (f *vaultFetcher) dropClient(client *vault.Client) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.client == client {
+		f.client = nil
+	}
+}
+
+// vaultAuthConfig returns AppRole login settings if VAULT_ROLE_ID is set,
+// token login settings otherwise
+func vaultAuthConfig() vault.AuthConfig {
+	if roleID := os.Getenv("VAULT_ROLE_ID"); roleID != "" {
+		return vault.AuthConfig{Method: vault.AuthMethodAppRole, RoleID: roleID, SecretID: os.Getenv("VAULT_SECRET_ID")}
+	}
+	return vault.AuthConfig{Method: vault.AuthMethodToken, Token: os.Getenv("VAULT_TOKEN")}
+}
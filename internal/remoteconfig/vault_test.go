@@ -0,0 +1,122 @@
+package remoteconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// serveVault serves a token lookup and a KV v2 secret with the given data
+func serveVault(t *testing.T, data map[string]interface{}) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/token/lookup-self":
+			_, _ = w.Write([]byte(`{"data": {"ttl": 3600}}`))
+		case "/v1/secret/data/secrets-sync/config":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": data}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestVaultFetcher_Load(t *testing.T) {
+	server := serveVault(t, map[string]interface{}{"config": testConfig})
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "test-token")
+
+	source, err := New("vault://secret/secrets-sync/config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := source.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.Secrets) != 1 {
+		t.Errorf("Load() secrets = %d, want 1", len(cfg.Secrets))
+	}
+}
+
+func TestVaultFetcher_FieldAndFormat(t *testing.T) {
+	server := serveVault(t, map[string]interface{}{
+		"json": `{"secretStore": {"address": "https://vault.example.com", "authMethod": "token", "token": "t"},
+			"secrets": [{"name": "app", "key": "app/config", "mountPath": "secret", "kvVersion": "v2", "refreshInterval": "1h",
+				"template": {"data": {"key": "{{ .key }}"}}, "files": [{"path": "/secrets/app", "template": "key"}]}]}`,
+		"number": 1,
+	})
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "test-token")
+
+	source, err := New("vault://secret/secrets-sync/config?field=json&format=json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	for url, want := range map[string]string{
+		"vault://secret/secrets-sync/config":              `no field "config"`,
+		"vault://secret/secrets-sync/config?field=number": "not a string",
+	} {
+		source, err := New(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = source.Load(context.Background())
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Load(%s) error = %v, want %q", url, err, want)
+		}
+	}
+}
+
+func TestVaultFetcher_AppRoleLogsInAgain(t *testing.T) {
+	var logins, valid atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			n := logins.Add(1)
+			valid.Store(n)
+			_, _ = fmt.Fprintf(w, `{"auth": {"client_token": "token-%d"}}`, n)
+		case "/v1/secret/data/secrets-sync/config":
+			if r.Header.Get("X-Vault-Token") != fmt.Sprintf("token-%d", valid.Load()) {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"errors": ["permission denied"]}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": map[string]interface{}{"config": testConfig}}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_ROLE_ID", "role")
+	t.Setenv("VAULT_SECRET_ID", "secret")
+
+	source, err := New("vault://secret/secrets-sync/config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	// Revoke the token; the next fetch logs in again instead of failing
+	valid.Store(0)
+	if _, err := source.Load(context.Background()); err != nil {
+		t.Fatalf("Load() after revocation error = %v", err)
+	}
+	if got := logins.Load(); got != 2 {
+		t.Errorf("expected 2 logins, got %d", got)
+	}
+}
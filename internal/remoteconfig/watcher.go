@@ -0,0 +1,81 @@
+package remoteconfig
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+)
+
+// Watcher re-fetches a remote configuration periodically and reports it
+// when it changed, like config.Watcher does for files
+type Watcher struct {
+	source   *Source
+	interval time.Duration
+	onChange func(*config.Config) error
+	onError  func(error)
+	stopCh   chan struct{}
+	ctx      context.Context // Cancelled by Stop, aborting a fetch in progress
+	cancel   context.CancelFunc
+}
+
+// NewWatcher creates a watcher polling source every interval. Changes are
+// detected against the data the source last loaded.
+func NewWatcher(source *Source, interval time.Duration, onChange func(*config.Config) error, onError func(error)) *Watcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Watcher{
+		source:   source,
+		interval: interval,
+		onChange: onChange,
+		onError:  onError,
+		stopCh:   make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start begins polling for configuration changes
+func (w *Watcher) Start() {
+	go w.watch()
+}
+
+// Stop stops polling for configuration changes
+func (w *Watcher) Stop() {
+	w.cancel()
+	close(w.stopCh)
+}
+
+func (w *Watcher) watch() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.poll()
+		case <-w.stopCh:
+			return
+		}
+	}
+}
+
+func (w *Watcher) poll() {
+	ctx, cancel := context.WithTimeout(w.ctx, fetchTimeout)
+	defer cancel()
+
+	cfg, changed, err := w.source.load(ctx, true)
+	if err != nil {
+		if w.onError != nil && w.ctx.Err() == nil {
+			w.onError(fmt.Errorf("failed to reload config: %w", err))
+		}
+		return
+	}
+	if !changed {
+		return
+	}
+
+	if err := w.onChange(cfg); err != nil && w.onError != nil {
+		w.onError(fmt.Errorf("failed to apply config changes: %w", err))
+	}
+}
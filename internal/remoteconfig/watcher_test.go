@@ -0,0 +1,87 @@
+package remoteconfig
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+)
+
+func TestWatcher_ReportsChanges(t *testing.T) {
+	var mu sync.Mutex
+	data := testConfig
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = w.Write([]byte(data))
+	}))
+	defer server.Close()
+
+	source, err := New(server.URL + "/config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := make(chan *config.Config, 10)
+	errs := make(chan error, 10)
+	watcher := NewWatcher(source, 10*time.Millisecond,
+		func(cfg *config.Config) error {
+			changes <- cfg
+			return nil
+		},
+		func(err error) { errs <- err },
+	)
+	watcher.Start()
+	defer watcher.Stop()
+
+	// Unchanged data is not reported
+	select {
+	case <-changes:
+		t.Fatal("unchanged config reported")
+	case err := <-errs:
+		t.Fatalf("unexpected error: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	mu.Lock()
+	data = strings.Replace(testConfig, "refreshInterval: \"1h\"", "refreshInterval: \"2h\"", 1)
+	mu.Unlock()
+
+	select {
+	case cfg := <-changes:
+		if cfg.Secrets[0].RefreshInterval != 2*time.Hour {
+			t.Errorf("RefreshInterval = %v, want 2h", cfg.Secrets[0].RefreshInterval)
+		}
+	case err := <-errs:
+		t.Fatalf("unexpected error: %v", err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("change not reported")
+	}
+
+	// An invalid config is reported once
+	mu.Lock()
+	data = "secrets: ["
+	mu.Unlock()
+
+	select {
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "failed to reload config") {
+			t.Errorf("error = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("invalid config not reported")
+	}
+	select {
+	case err := <-errs:
+		t.Fatalf("invalid config reported again: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,69 @@
+// Package sandbox restricts what the daemon can do once it is initialized,
+// so a compromised process cannot write outside its output directories
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Mode selects how strictly the process is sandboxed
+type Mode string
+
+const (
+	ModeOff    Mode = "off"
+	ModeStrict Mode = "strict"
+)
+
+// ErrUnsupported is returned on platforms without landlock/seccomp support
+var ErrUnsupported = errors.New("sandboxing is not supported on this platform")
+
+// Config holds sandbox configuration
+type Config struct {
+	// WritableDirs are the only directory trees the process may modify
+	WritableDirs []string
+}
+
+// ParseMode parses a SANDBOX value, treating empty as off
+func ParseMode(mode string) (Mode, error) {
+	switch Mode(mode) {
+	case "", ModeOff:
+		return ModeOff, nil
+	case ModeStrict:
+		return ModeStrict, nil
+	default:
+		return "", fmt.Errorf("invalid sandbox mode %q (supported: strict, off)", mode)
+	}
+}
+
+// prepareDirs creates the writable directories, since landlock rules can only
+// be attached to paths that exist, and returns them cleaned and deduplicated
+func prepareDirs(dirs []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var result []string
+
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", dir, err)
+		}
+		abs = filepath.Clean(abs)
+		if seen[abs] {
+			continue
+		}
+		if err := os.MkdirAll(abs, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory %s: %w", abs, err)
+		}
+		seen[abs] = true
+		result = append(result, abs)
+	}
+
+	sort.Strings(result)
+	return result, nil
+}
@@ -0,0 +1,72 @@
+//go:build linux
+// +build linux
+
+package sandbox
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// TestApply_RestrictsWrites runs the sandbox in a child process, since it
+// cannot be removed once applied
+func TestApply_RestrictsWrites(t *testing.T) {
+	if os.Getenv("SANDBOX_TEST_HELPER") == "1" {
+		runSandboxHelper()
+		return
+	}
+
+	tmpDir := t.TempDir()
+	allowed := filepath.Join(tmpDir, "allowed")
+	denied := filepath.Join(tmpDir, "denied")
+	if err := os.MkdirAll(denied, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestApply_RestrictsWrites$")
+	cmd.Env = append(os.Environ(),
+		"SANDBOX_TEST_HELPER=1",
+		"SANDBOX_TEST_ALLOWED="+allowed,
+		"SANDBOX_TEST_DENIED="+denied,
+	)
+	out, err := cmd.CombinedOutput()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 3 {
+		t.Skipf("sandbox not available: %s", out)
+	}
+	if err != nil {
+		t.Fatalf("sandboxed helper failed: %v\n%s", err, out)
+	}
+}
+
+func runSandboxHelper() {
+	allowed := os.Getenv("SANDBOX_TEST_ALLOWED")
+	denied := os.Getenv("SANDBOX_TEST_DENIED")
+
+	if err := Apply(Config{WritableDirs: []string{allowed}}); err != nil {
+		_, _ = os.Stderr.WriteString(err.Error())
+		os.Exit(3)
+	}
+
+	if err := os.WriteFile(filepath.Join(allowed, "secret"), []byte("x"), 0600); err != nil {
+		_, _ = os.Stderr.WriteString("write to allowed dir failed: " + err.Error())
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(filepath.Join(denied, "secret"), []byte("x"), 0600); err == nil {
+		_, _ = os.Stderr.WriteString("write outside allowed dirs succeeded")
+		os.Exit(1)
+	}
+
+	if err := syscall.Exec("/bin/true", []string{"true"}, nil); err == nil || !errors.Is(err, syscall.EPERM) {
+		_, _ = os.Stderr.WriteString("execve was not denied")
+		os.Exit(1)
+	}
+
+	os.Exit(0)
+}
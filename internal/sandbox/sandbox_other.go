@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package sandbox
+
+// Apply is not available on this platform
+func Apply(cfg Config) error {
+	return ErrUnsupported
+}
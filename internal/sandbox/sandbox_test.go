@@ -0,0 +1,47 @@
+package sandbox
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Mode
+		wantErr bool
+	}{
+		{"", ModeOff, false},
+		{"off", ModeOff, false},
+		{"strict", ModeStrict, false},
+		{"permissive", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMode(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseMode(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ParseMode(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestPrepareDirs(t *testing.T) {
+	tmpDir := t.TempDir()
+	dir := filepath.Join(tmpDir, "a", "b")
+
+	dirs, err := prepareDirs([]string{dir, dir + "/", "", tmpDir})
+	if err != nil {
+		t.Fatalf("prepareDirs failed: %v", err)
+	}
+
+	if len(dirs) != 2 {
+		t.Fatalf("expected 2 deduplicated dirs, got %v", dirs)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Errorf("expected %s to be created", dir)
+	}
+}
@@ -0,0 +1,328 @@
+// Package selftest runs the sync pipeline end to end to prove that an
+// environment works before the service is enabled
+package selftest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ohauer/secrets-sync/internal/bench"
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/filewriter"
+	"github.com/ohauer/secrets-sync/internal/syncer"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+// ClientFactoryFunc builds the Vault client factory for a loaded config
+type ClientFactoryFunc func(cfg *config.Config) syncer.ClientFactory
+
+// Check is the outcome of a single self-test step
+type Check struct {
+	Name string
+	Err  error
+}
+
+// Report holds the outcome of every step that ran
+type Report struct {
+	Checks []Check
+}
+
+// Failed returns the number of failed checks
+func (r *Report) Failed() int {
+	n := 0
+	for _, c := range r.Checks {
+		if c.Err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// add records a check and reports whether it passed
+func (r *Report) add(name string, err error) bool {
+	r.Checks = append(r.Checks, Check{Name: name, Err: err})
+	return err == nil
+}
+
+// retryConfig fails fast; a self-test should report problems, not wait them out
+var retryConfig = vault.RetryConfig{MaxRetries: 0}
+
+// RunMock runs load, auth, fetch, render, write and reload against an
+// in-process mock Vault, writing the config and secret files below dir
+func RunMock(ctx context.Context, dir string, newFactory ClientFactoryFunc) *Report {
+	r := &Report{}
+
+	mock := bench.NewMockVault(0)
+	defer mock.Close()
+
+	configPath := filepath.Join(dir, "config.yaml")
+	secretsDir := filepath.Join(dir, "secrets")
+
+	if !r.add("write config", writeMockConfig(configPath, mock.URL(), secretsDir, false)) {
+		return r
+	}
+	cfg, err := config.Load(ctx, configPath)
+	if !r.add("load config", err) {
+		return r
+	}
+	if cfg.SecretStore.IsAzureKeyVault() {
+		r.add("secret store", fmt.Errorf("self-test supports Vault secret stores only"))
+		return r
+	}
+
+	factory := newFactory(cfg)
+	client, err := factory(ctx, cfg.SecretStore.GetDefaultCredentials())
+	if !r.add("authenticate", err) {
+		return r
+	}
+
+	secret := cfg.Secrets[0]
+	data, err := client.FetchSecret(ctx, secret.MountPath, secret.Key, secret.KVVersion, "")
+	if err == nil && data["username"] != secret.Key {
+		err = fmt.Errorf("expected username %q, got %v", secret.Key, data["username"])
+	}
+	if !r.add("fetch secret", err) {
+		return r
+	}
+
+	secretSyncer := syncer.NewSecretSyncer(factory, retryConfig)
+	credentialsPath := secret.Files[0].Path
+	err = secretSyncer.SyncSecret(ctx, cfg, secret)
+	if err == nil {
+		err = checkFile(credentialsPath, secret.Key+":bench-password:")
+	}
+	if !r.add("render and write", err) {
+		return r
+	}
+	before, err := os.ReadFile(credentialsPath)
+	if !r.add("read written file", err) {
+		return r
+	}
+
+	// A reload adds a file to the secret; the resync must write it and
+	// update the existing one
+	if !r.add("update config", writeMockConfig(configPath, mock.URL(), secretsDir, true)) {
+		return r
+	}
+	cfg, err = config.Load(ctx, configPath)
+	if !r.add("reload config", err) {
+		return r
+	}
+	secret = cfg.Secrets[0]
+	err = secretSyncer.SyncSecret(ctx, cfg, secret)
+	if err == nil {
+		err = checkFile(secret.Files[1].Path, secret.Key+"\n")
+	}
+	if err == nil {
+		var after []byte
+		if after, err = os.ReadFile(credentialsPath); err == nil && string(after) == string(before) {
+			err = fmt.Errorf("%s was not updated", credentialsPath)
+		}
+	}
+	r.add("sync after reload", err)
+
+	return r
+}
+
+// writeMockConfig writes a config with one secret read from the mock Vault
+func writeMockConfig(path, addr, secretsDir string, reloaded bool) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, `secretStore:
+  address: %q
+  authMethod: token
+  token: selftest
+secrets:
+  - name: selftest
+    key: selftest/app
+    mountPath: %s
+    kvVersion: v2
+    refreshInterval: 1m
+    template:
+      data:
+        credentials: "{{ .username }}:{{ .password }}:{{ .version }}\n"
+`, addr, bench.MountPath)
+	if reloaded {
+		b.WriteString(`        username: "{{ .username }}\n"
+`)
+	}
+	fmt.Fprintf(&b, `    files:
+      - path: %q
+        template: credentials
+        mode: "0600"
+`, filepath.Join(secretsDir, "credentials"))
+	if reloaded {
+		fmt.Fprintf(&b, `      - path: %q
+        template: username
+        mode: "0600"
+`, filepath.Join(secretsDir, "username"))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// checkFile verifies a written secret file starts with prefix and is only
+// readable by its owner
+func checkFile(path, prefix string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if !strings.HasPrefix(string(content), prefix) {
+		return fmt.Errorf("unexpected content in %s", path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		return fmt.Errorf("expected mode 0600 for %s, got %04o", path, perm)
+	}
+	return nil
+}
+
+// RunConfig checks a real config without touching the managed files: it
+// authenticates every credential set in use, fetches and renders every
+// secret, and proves each target directory accepts files with the
+// configured mode and ownership
+func RunConfig(ctx context.Context, path string, newFactory ClientFactoryFunc) *Report {
+	r := &Report{}
+
+	cfg, err := config.Load(ctx, path)
+	if !r.add("load config", err) {
+		return r
+	}
+	if cfg.SecretStore.IsAzureKeyVault() {
+		r.add("secret store", fmt.Errorf("self-test supports Vault secret stores only"))
+		return r
+	}
+
+	factory := newFactory(cfg)
+	authenticated := make(map[string]error)
+	for _, name := range CredentialNames(cfg) {
+		creds, ok := cfg.SecretStore.GetCredentials(name)
+		if !ok {
+			err = fmt.Errorf("credentials %q not found", name)
+		} else {
+			_, err = factory(ctx, creds)
+		}
+		authenticated[name] = err
+		r.add("authenticate "+credentialLabel(name), err)
+	}
+
+	secretSyncer := syncer.NewSecretSyncer(factory, retryConfig)
+	for _, secret := range cfg.Secrets {
+		// Failed logins were already reported
+		if authenticated[secret.ResolveCredentials()] != nil {
+			continue
+		}
+		r.add("fetch and render "+secret.Name, secretSyncer.CheckSecret(ctx, cfg, secret))
+	}
+
+	r.Checks = append(r.Checks, ProbeDirectories(ctx, cfg)...)
+	return r
+}
+
+// ProbeDirectories proves that the target directory of every file, and of
+// every wildcard secret, accepts files with the configured mode and
+// ownership, probing each combination once
+func ProbeDirectories(ctx context.Context, cfg *config.Config) []Check {
+	var checks []Check
+	probed := make(map[string]bool)
+	for _, secret := range cfg.Secrets {
+		files := secret.Files
+		if dir := secret.Directory; dir != nil {
+			// Probe the directory itself; matched secrets get subdirectories
+			files = []config.File{{Path: filepath.Join(dir.Path, "probe"), Mode: dir.Mode, Owner: dir.Owner, Group: dir.Group}}
+		}
+		for _, file := range files {
+			key := strings.Join([]string{filepath.Dir(file.Path), file.Mode, file.Owner, file.Group, strconv.FormatBool(secret.RequireTmpfs)}, "\x00")
+			if probed[key] {
+				continue
+			}
+			probed[key] = true
+			checks = append(checks, Check{Name: "write to " + filepath.Dir(file.Path), Err: probeFile(ctx, file, secret.RequireTmpfs)})
+		}
+	}
+	return checks
+}
+
+// CredentialNames returns the credential sets used by the secrets, sorted
+func CredentialNames(cfg *config.Config) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, secret := range cfg.Secrets {
+		name := secret.ResolveCredentials()
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func credentialLabel(name string) string {
+	if name == "" {
+		return "(default credentials)"
+	}
+	return fmt.Sprintf("(credentials %q)", name)
+}
+
+// probeFile writes and removes a file with the mode, ownership and SELinux
+// context of a configured file, on tmpfs if required. Missing directories are
+// not created; the nearest existing parent is probed instead, since the
+// service creates the rest.
+func probeFile(ctx context.Context, file config.File, requireTmpfs bool) error {
+	mode, err := filewriter.ParseMode(file.Mode)
+	if err != nil {
+		return fmt.Errorf("invalid mode: %w", err)
+	}
+	owner, err := filewriter.ParseOwner(file.Owner)
+	if err != nil {
+		return err
+	}
+	group, err := filewriter.ParseGroup(file.Group)
+	if err != nil {
+		return fmt.Errorf("invalid group: %w", err)
+	}
+
+	dir := filepath.Dir(file.Path)
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to stat %s: %w", dir, err)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return fmt.Errorf("no existing parent directory for %s", file.Path)
+		}
+		dir = parent
+	}
+
+	probe := filepath.Join(dir, fmt.Sprintf(".secrets-sync-selftest-%d", os.Getpid()))
+	err = filewriter.NewWriter().WriteBytes(ctx, filewriter.FileConfig{
+		Path:           probe,
+		Mode:           mode,
+		Owner:          owner,
+		Group:          group,
+		SELinuxContext: file.SELinuxContext,
+		RequireTmpfs:   requireTmpfs,
+	}, []byte("selftest\n"))
+	if removeErr := os.Remove(probe); removeErr != nil && !os.IsNotExist(removeErr) && err == nil {
+		err = fmt.Errorf("failed to remove probe file: %w", removeErr)
+	}
+	return err
+}
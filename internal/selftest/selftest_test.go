@@ -0,0 +1,150 @@
+package selftest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ohauer/secrets-sync/internal/bench"
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/syncer"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+func tokenFactory(cfg *config.Config) syncer.ClientFactory {
+	return func(ctx context.Context, creds config.CredentialSet) (*vault.Client, error) {
+		client, err := vault.NewClient(cfg.SecretStore.Address)
+		if err != nil {
+			return nil, err
+		}
+		client.GetAPIClient().SetMaxRetries(0)
+		if err := client.Authenticate(ctx, vault.AuthConfig{Method: vault.AuthMethodToken, Token: creds.Token}); err != nil {
+			return nil, err
+		}
+		return client, nil
+	}
+}
+
+func failedChecks(r *Report) []string {
+	var failed []string
+	for _, c := range r.Checks {
+		if c.Err != nil {
+			failed = append(failed, c.Name+": "+c.Err.Error())
+		}
+	}
+	return failed
+}
+
+func TestRunMock(t *testing.T) {
+	dir := t.TempDir()
+
+	report := RunMock(context.Background(), dir, tokenFactory)
+	if report.Failed() != 0 {
+		t.Fatalf("expected all checks to pass, failed: %v", failedChecks(report))
+	}
+	if last := report.Checks[len(report.Checks)-1].Name; last != "sync after reload" {
+		t.Errorf("expected pipeline to run through reload, stopped after %q", last)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "secrets", "username"))
+	if err != nil {
+		t.Fatalf("failed to read file written after reload: %v", err)
+	}
+	if string(content) != "selftest/app\n" {
+		t.Errorf("unexpected content: %q", content)
+	}
+}
+
+func TestRunMock_StopsAtFirstFailure(t *testing.T) {
+	failing := func(cfg *config.Config) syncer.ClientFactory {
+		return func(ctx context.Context, creds config.CredentialSet) (*vault.Client, error) {
+			client, err := vault.NewClient(cfg.SecretStore.Address)
+			if err != nil {
+				return nil, err
+			}
+			return client, client.Authenticate(ctx, vault.AuthConfig{Method: vault.AuthMethodAppRole})
+		}
+	}
+
+	report := RunMock(context.Background(), t.TempDir(), failing)
+	if report.Failed() != 1 {
+		t.Fatalf("expected one failed check, got %v", failedChecks(report))
+	}
+	if last := report.Checks[len(report.Checks)-1]; last.Name != "authenticate" || last.Err == nil {
+		t.Errorf("expected authenticate to fail last, got %+v", last)
+	}
+}
+
+func writeConfig(t *testing.T, addr, dir string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, "config.yaml")
+	if err := writeMockConfig(path, addr, filepath.Join(dir, "secrets", "app"), false); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunConfig(t *testing.T) {
+	mock := bench.NewMockVault(0)
+	defer mock.Close()
+
+	dir := t.TempDir()
+	report := RunConfig(context.Background(), writeConfig(t, mock.URL(), dir), tokenFactory)
+	if report.Failed() != 0 {
+		t.Fatalf("expected all checks to pass, failed: %v", failedChecks(report))
+	}
+
+	var names []string
+	for _, c := range report.Checks {
+		names = append(names, c.Name)
+	}
+	want := []string{
+		"load config",
+		"authenticate (default credentials)",
+		"fetch and render selftest",
+		"write to " + filepath.Join(dir, "secrets", "app"),
+	}
+	if strings.Join(names, "|") != strings.Join(want, "|") {
+		t.Errorf("expected checks %v, got %v", want, names)
+	}
+
+	// Neither the secret file nor its directory may be created
+	if _, err := os.Stat(filepath.Join(dir, "secrets")); !os.IsNotExist(err) {
+		t.Error("self-test must not create target directories")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected probe file to be removed, found %d entries", len(entries))
+	}
+}
+
+func TestRunConfig_UnreachableVault(t *testing.T) {
+	mock := bench.NewMockVault(0)
+	addr := mock.URL()
+	mock.Close()
+
+	report := RunConfig(context.Background(), writeConfig(t, addr, t.TempDir()), tokenFactory)
+
+	failed := failedChecks(report)
+	if len(failed) != 1 || !strings.HasPrefix(failed[0], "authenticate") {
+		t.Errorf("expected only authentication to fail, got %v", failed)
+	}
+}
+
+func TestRunConfig_InvalidConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("secrets: ["), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	report := RunConfig(context.Background(), path, tokenFactory)
+	if len(report.Checks) != 1 || report.Failed() != 1 {
+		t.Errorf("expected only the failed config load, got %+v", report.Checks)
+	}
+}
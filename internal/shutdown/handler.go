@@ -13,13 +13,27 @@ import (
 // Handler manages graceful shutdown
 type Handler struct {
 	timeout  time.Duration
-	handlers []func() error
+	handlers []registered
 	mu       sync.Mutex
 	sigCh    chan os.Signal
 	reloadCh chan os.Signal
+	diagCh   chan os.Signal
+	debugCh  chan os.Signal
+	resyncCh chan os.Signal
+
+	shutdownOnce sync.Once
+	shutdownErr  error
+	stopOnce     sync.Once
+}
+
+// registered is a shutdown handler together with its individual timeout
+type registered struct {
+	fn      func() error
+	timeout time.Duration
 }
 
-// NewHandler creates a new shutdown handler
+// NewHandler creates a new shutdown handler; timeout is the default time
+// each registered handler is given to complete
 func NewHandler(timeout time.Duration) *Handler {
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
@@ -27,19 +41,42 @@ func NewHandler(timeout time.Duration) *Handler {
 	reloadCh := make(chan os.Signal, 1)
 	signal.Notify(reloadCh, syscall.SIGHUP)
 
+	// Catching SIGQUIT replaces the runtime's dump-and-exit behaviour
+	diagCh := make(chan os.Signal, 1)
+	signal.Notify(diagCh, syscall.SIGQUIT)
+
+	debugCh := make(chan os.Signal, 1)
+	if len(debugSignals) > 0 {
+		signal.Notify(debugCh, debugSignals...)
+	}
+	resyncCh := make(chan os.Signal, 1)
+	if len(resyncSignals) > 0 {
+		signal.Notify(resyncCh, resyncSignals...)
+	}
+
 	return &Handler{
 		timeout:  timeout,
-		handlers: make([]func() error, 0),
+		handlers: make([]registered, 0),
 		sigCh:    sigCh,
 		reloadCh: reloadCh,
+		diagCh:   diagCh,
+		debugCh:  debugCh,
+		resyncCh: resyncCh,
 	}
 }
 
-// Register registers a shutdown handler
+// Register registers a shutdown handler using the default timeout.
+// Handlers run in reverse registration order, so register components in
+// the order they are started.
 func (h *Handler) Register(handler func() error) {
+	h.RegisterWithTimeout(handler, h.timeout)
+}
+
+// RegisterWithTimeout registers a shutdown handler with its own timeout
+func (h *Handler) RegisterWithTimeout(handler func() error, timeout time.Duration) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	h.handlers = append(h.handlers, handler)
+	h.handlers = append(h.handlers, registered{fn: handler, timeout: timeout})
 }
 
 // Wait waits for shutdown signal
@@ -52,43 +89,88 @@ func (h *Handler) WaitReload() <-chan os.Signal {
 	return h.reloadCh
 }
 
-// Shutdown executes all registered handlers
+// WaitDiagnostics waits for a diagnostics dump request (SIGQUIT)
+func (h *Handler) WaitDiagnostics() <-chan os.Signal {
+	return h.diagCh
+}
+
+// WaitDebugToggle waits for a request to toggle debug logging (SIGUSR1);
+// it never fires on Windows
+func (h *Handler) WaitDebugToggle() <-chan os.Signal {
+	return h.debugCh
+}
+
+// WaitResync waits for a request to sync all secrets now (SIGUSR2); it
+// never fires on Windows
+func (h *Handler) WaitResync() <-chan os.Signal {
+	return h.resyncCh
+}
+
+// Shutdown executes all registered handlers in reverse registration order.
+// Only the first call runs the handlers; later calls return the same result.
 func (h *Handler) Shutdown() error {
-	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	h.shutdownOnce.Do(func() {
+		h.shutdownErr = h.runHandlers()
+	})
+	return h.shutdownErr
+}
+
+func (h *Handler) runHandlers() error {
+	h.mu.Lock()
+	handlers := make([]registered, len(h.handlers))
+	copy(handlers, h.handlers)
+	h.mu.Unlock()
+
+	var errs []error
+	for i := len(handlers) - 1; i >= 0; i-- {
+		if err := runWithTimeout(handlers[i]); err != nil {
+			errs = append(errs, fmt.Errorf("handler %d: %w", i, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("shutdown errors: %v", errs)
+	}
+	return nil
+}
+
+// runWithTimeout runs a single handler, recovering from panics and giving up
+// once its timeout is exceeded
+func runWithTimeout(r registered) error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
 	defer cancel()
 
 	done := make(chan error, 1)
 
 	go func() {
-		h.mu.Lock()
-		defer h.mu.Unlock()
-
-		var errs []error
-		for _, handler := range h.handlers {
-			if err := handler(); err != nil {
-				errs = append(errs, err)
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("panic: %v", p)
 			}
-		}
-
-		if len(errs) > 0 {
-			done <- fmt.Errorf("shutdown errors: %v", errs)
-		} else {
-			done <- nil
-		}
+		}()
+		done <- r.fn()
 	}()
 
 	select {
 	case err := <-done:
 		return err
 	case <-ctx.Done():
-		return fmt.Errorf("shutdown timeout exceeded")
+		return fmt.Errorf("shutdown timeout of %s exceeded", r.timeout)
 	}
 }
 
-// Stop stops listening for signals
+// Stop stops listening for signals; it is safe to call more than once
 func (h *Handler) Stop() {
-	signal.Stop(h.sigCh)
-	signal.Stop(h.reloadCh)
-	close(h.sigCh)
-	close(h.reloadCh)
+	h.stopOnce.Do(func() {
+		signal.Stop(h.sigCh)
+		signal.Stop(h.reloadCh)
+		signal.Stop(h.diagCh)
+		signal.Stop(h.debugCh)
+		signal.Stop(h.resyncCh)
+		close(h.sigCh)
+		close(h.reloadCh)
+		close(h.diagCh)
+		close(h.debugCh)
+		close(h.resyncCh)
+	})
 }
@@ -111,6 +111,66 @@ func TestWait_Signal(t *testing.T) {
 	}
 }
 
+func TestWaitDiagnostics_Signal(t *testing.T) {
+	handler := NewHandler(5 * time.Second)
+	defer handler.Stop()
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		p, _ := os.FindProcess(os.Getpid())
+		_ = p.Signal(syscall.SIGQUIT)
+	}()
+
+	select {
+	case sig := <-handler.WaitDiagnostics():
+		if sig != syscall.SIGQUIT {
+			t.Errorf("expected SIGQUIT, got %v", sig)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for signal")
+	}
+}
+
+func TestWaitDebugToggle_Signal(t *testing.T) {
+	handler := NewHandler(5 * time.Second)
+	defer handler.Stop()
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		p, _ := os.FindProcess(os.Getpid())
+		_ = p.Signal(syscall.SIGUSR1)
+	}()
+
+	select {
+	case sig := <-handler.WaitDebugToggle():
+		if sig != syscall.SIGUSR1 {
+			t.Errorf("expected SIGUSR1, got %v", sig)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for signal")
+	}
+}
+
+func TestWaitResync_Signal(t *testing.T) {
+	handler := NewHandler(5 * time.Second)
+	defer handler.Stop()
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		p, _ := os.FindProcess(os.Getpid())
+		_ = p.Signal(syscall.SIGUSR2)
+	}()
+
+	select {
+	case sig := <-handler.WaitResync():
+		if sig != syscall.SIGUSR2 {
+			t.Errorf("expected SIGUSR2, got %v", sig)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for signal")
+	}
+}
+
 func TestShutdown_MultipleHandlers(t *testing.T) {
 	handler := NewHandler(5 * time.Second)
 	defer handler.Stop()
@@ -137,9 +197,80 @@ func TestShutdown_MultipleHandlers(t *testing.T) {
 		t.Errorf("expected 3 handlers, got %d", len(order))
 	}
 
+	// Handlers run in reverse registration order
 	for i, v := range order {
-		if v != i+1 {
-			t.Errorf("expected order[%d] = %d, got %d", i, i+1, v)
+		if v != 3-i {
+			t.Errorf("expected order[%d] = %d, got %d", i, 3-i, v)
 		}
 	}
 }
+
+func TestShutdown_PerHandlerTimeout(t *testing.T) {
+	handler := NewHandler(5 * time.Second)
+	defer handler.Stop()
+
+	called := false
+	handler.Register(func() error {
+		called = true
+		return nil
+	})
+	handler.RegisterWithTimeout(func() error {
+		time.Sleep(time.Second)
+		return nil
+	}, 50*time.Millisecond)
+
+	start := time.Now()
+	if err := handler.Shutdown(); err == nil {
+		t.Error("expected timeout error, got nil")
+	}
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected slow handler to be abandoned, took %v", elapsed)
+	}
+	if !called {
+		t.Error("expected remaining handlers to run after a timeout")
+	}
+}
+
+func TestShutdown_RecoversPanic(t *testing.T) {
+	handler := NewHandler(5 * time.Second)
+	defer handler.Stop()
+
+	called := false
+	handler.Register(func() error {
+		called = true
+		return nil
+	})
+	handler.Register(func() error {
+		panic("boom")
+	})
+
+	if err := handler.Shutdown(); err == nil {
+		t.Error("expected error from panicking handler")
+	}
+	if !called {
+		t.Error("expected remaining handlers to run after a panic")
+	}
+}
+
+func TestShutdown_Idempotent(t *testing.T) {
+	handler := NewHandler(5 * time.Second)
+
+	count := 0
+	handler.Register(func() error {
+		count++
+		return nil
+	})
+
+	for i := 0; i < 3; i++ {
+		if err := handler.Shutdown(); err != nil {
+			t.Fatalf("shutdown failed: %v", err)
+		}
+	}
+	if count != 1 {
+		t.Errorf("expected handler to run once, ran %d times", count)
+	}
+
+	handler.Stop()
+	handler.Stop()
+}
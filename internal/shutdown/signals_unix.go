@@ -0,0 +1,15 @@
+//go:build !windows
+// +build !windows
+
+package shutdown
+
+import (
+	"os"
+	"syscall"
+)
+
+// debugSignals toggle debug logging
+var debugSignals = []os.Signal{syscall.SIGUSR1}
+
+// resyncSignals request an immediate sync of all secrets
+var resyncSignals = []os.Signal{syscall.SIGUSR2}
@@ -0,0 +1,12 @@
+//go:build windows
+// +build windows
+
+package shutdown
+
+import "os"
+
+// debugSignals toggle debug logging; Windows has no SIGUSR1
+var debugSignals []os.Signal
+
+// resyncSignals request an immediate sync of all secrets; Windows has no SIGUSR2
+var resyncSignals []os.Signal
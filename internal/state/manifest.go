@@ -0,0 +1,174 @@
+package state
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/filewriter"
+)
+
+// ManifestVersion is the current manifest file format version
+const ManifestVersion = 1
+
+// Entry describes a single file managed by the daemon
+type Entry struct {
+	Secret    string    `json:"secret"`
+	Path      string    `json:"path"`
+	Hash      string    `json:"sha256"`
+	Mode      string    `json:"mode"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Manifest records every file written by the daemon
+type Manifest struct {
+	path    string
+	mu      sync.RWMutex
+	entries map[string]Entry
+}
+
+// manifestFile is the on-disk representation of a manifest
+type manifestFile struct {
+	Version int              `json:"version"`
+	Files   map[string]Entry `json:"files"`
+}
+
+// NewManifest creates an empty manifest persisted at path
+func NewManifest(path string) *Manifest {
+	return &Manifest{
+		path:    path,
+		entries: make(map[string]Entry),
+	}
+}
+
+// LoadManifest reads a manifest from path, returning an empty one if it does not exist
+func LoadManifest(path string) (*Manifest, error) {
+	m := NewManifest(path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var file manifestFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	if file.Version != ManifestVersion {
+		return nil, fmt.Errorf("unsupported manifest version %d (expected %d)", file.Version, ManifestVersion)
+	}
+
+	for path, entry := range file.Files {
+		m.entries[path] = entry
+	}
+
+	return m, nil
+}
+
+// Path returns the location the manifest is persisted to
+func (m *Manifest) Path() string {
+	return m.path
+}
+
+// Set records or replaces the entry for a file
+func (m *Manifest) Set(entry Entry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[entry.Path] = entry
+}
+
+// Get returns the entry for a file path
+func (m *Manifest) Get(path string) (Entry, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entry, ok := m.entries[path]
+	return entry, ok
+}
+
+// Remove deletes the entry for a file path
+func (m *Manifest) Remove(path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, path)
+}
+
+// Entries returns all entries sorted by path
+func (m *Manifest) Entries() []Entry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entries := make([]Entry, 0, len(m.entries))
+	for _, entry := range m.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	return entries
+}
+
+// Save writes the manifest to disk atomically
+func (m *Manifest) Save() error {
+	if m.path == "" {
+		return fmt.Errorf("manifest path is not set")
+	}
+
+	m.mu.RLock()
+	file := manifestFile{
+		Version: ManifestVersion,
+		Files:   make(map[string]Entry, len(m.entries)),
+	}
+	for path, entry := range m.entries {
+		file.Files[path] = entry
+	}
+	m.mu.RUnlock()
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	if err := writeFile(m.path, data); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return nil
+}
+
+// writeFile replaces a file with mode 0600 atomically. It is not
+// cancellable: the state has to record files that were already written,
+// even when the sync that wrote them is aborted.
+func writeFile(path string, data []byte) error {
+	writer := filewriter.NewWriter()
+	return writer.WriteFile(context.Background(), filewriter.FileConfig{
+		Path:  path,
+		Mode:  0600,
+		Owner: -1,
+		Group: -1,
+	}, string(data))
+}
+
+// HashContent returns the hex-encoded SHA-256 digest of content
+func HashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// HashFile returns the hex-encoded SHA-256 digest of a file on disk
+func HashFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return HashContent(data), nil
+}
@@ -0,0 +1,83 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestManifest_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+
+	m := NewManifest(path)
+	m.Set(Entry{
+		Secret:    "db",
+		Path:      "/secrets/db-password",
+		Hash:      HashContent([]byte("secret")),
+		Mode:      "0600",
+		UpdatedAt: time.Now().UTC().Truncate(time.Second),
+	})
+
+	if err := m.Save(); err != nil {
+		t.Fatalf("failed to save manifest: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("manifest not written: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %04o", info.Mode().Perm())
+	}
+
+	loaded, err := LoadManifest(path)
+	if err != nil {
+		t.Fatalf("failed to load manifest: %v", err)
+	}
+
+	entry, ok := loaded.Get("/secrets/db-password")
+	if !ok {
+		t.Fatal("expected entry to be loaded")
+	}
+	if entry.Secret != "db" || entry.Mode != "0600" || entry.Hash != HashContent([]byte("secret")) {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+}
+
+func TestLoadManifest_Missing(t *testing.T) {
+	m, err := LoadManifest(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("expected no error for missing manifest, got: %v", err)
+	}
+	if len(m.Entries()) != 0 {
+		t.Errorf("expected empty manifest, got %d entries", len(m.Entries()))
+	}
+}
+
+func TestLoadManifest_UnsupportedVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	if err := os.WriteFile(path, []byte(`{"version": 99, "files": {}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadManifest(path); err == nil {
+		t.Error("expected error for unsupported version")
+	}
+}
+
+func TestManifest_EntriesSorted(t *testing.T) {
+	m := NewManifest("")
+	m.Set(Entry{Path: "/b"})
+	m.Set(Entry{Path: "/a"})
+	m.Set(Entry{Path: "/c"})
+	m.Remove("/c")
+
+	entries := m.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Path != "/a" || entries[1].Path != "/b" {
+		t.Errorf("expected sorted entries, got %v", entries)
+	}
+}
@@ -0,0 +1,160 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// StoreVersion is the current state file format version
+const StoreVersion = 1
+
+// SecretState is what was last synced for a secret, kept across restarts
+type SecretState struct {
+	FetchedAt   time.Time         `json:"fetchedAt"`             // When the data on disk was fetched
+	Version     int               `json:"version,omitempty"`     // KV v2 version on disk, 0 if unknown
+	Fingerprint string            `json:"fingerprint,omitempty"` // Hash of the configuration the files were rendered from
+	Files       map[string]string `json:"files,omitempty"`       // SHA-256 of the content rendered, by path
+	Lease       *LeaseState       `json:"lease,omitempty"`       // Lease of the credentials of a dynamic secret
+}
+
+// LeaseState is the lease of the credentials written for a dynamic secret
+type LeaseState struct {
+	ID        string        `json:"id"`
+	Renewable bool          `json:"renewable"`
+	Term      time.Duration `json:"term"` // Duration of the lease as first issued
+	Expires   time.Time     `json:"expires"`
+}
+
+// Store records the state of every secret synced by the daemon
+type Store struct {
+	path    string
+	mu      sync.RWMutex
+	secrets map[string]SecretState
+}
+
+// storeFile is the on-disk representation of a store
+type storeFile struct {
+	Version int                    `json:"version"`
+	Secrets map[string]SecretState `json:"secrets"`
+}
+
+// NewStore creates an empty store persisted at path
+func NewStore(path string) *Store {
+	return &Store{
+		path:    path,
+		secrets: make(map[string]SecretState),
+	}
+}
+
+// LoadStore reads a store from path, returning an empty one if it does not exist
+func LoadStore(path string) (*Store, error) {
+	s := NewStore(path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var file storeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse state file: %w", err)
+	}
+
+	if file.Version != StoreVersion {
+		return nil, fmt.Errorf("unsupported state file version %d (expected %d)", file.Version, StoreVersion)
+	}
+
+	for name, secret := range file.Secrets {
+		s.secrets[name] = secret
+	}
+
+	return s, nil
+}
+
+// Set records or replaces the state of a secret
+func (s *Store) Set(name string, secret SecretState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.secrets[name] = secret
+}
+
+// Get returns the state of a secret
+func (s *Store) Get(name string) (SecretState, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	secret, ok := s.secrets[name]
+	return secret, ok
+}
+
+// Remove deletes the state of a secret
+func (s *Store) Remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.secrets, name)
+}
+
+// Retain deletes the state of every secret not in names and reports
+// whether any was deleted
+func (s *Store) Retain(names []string) bool {
+	keep := make(map[string]bool, len(names))
+	for _, name := range names {
+		keep[name] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := false
+	for name := range s.secrets {
+		if !keep[name] {
+			delete(s.secrets, name)
+			removed = true
+		}
+	}
+	return removed
+}
+
+// Names returns the names of the secrets recorded, sorted
+func (s *Store) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.secrets))
+	for name := range s.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Save writes the store to disk atomically
+func (s *Store) Save() error {
+	if s.path == "" {
+		return fmt.Errorf("state file path is not set")
+	}
+
+	s.mu.RLock()
+	file := storeFile{
+		Version: StoreVersion,
+		Secrets: make(map[string]SecretState, len(s.secrets)),
+	}
+	for name, secret := range s.secrets {
+		file.Secrets[name] = secret
+	}
+	s.mu.RUnlock()
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state file: %w", err)
+	}
+	if err := writeFile(s.path, data); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStore_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	fetchedAt := time.Now().UTC().Truncate(time.Second)
+
+	s := NewStore(path)
+	s.Set("db", SecretState{
+		FetchedAt: fetchedAt,
+		Lease:     &LeaseState{ID: "database/creds/app/1", Renewable: true, Term: time.Hour, Expires: fetchedAt.Add(time.Hour)},
+	})
+	s.Set("api", SecretState{
+		FetchedAt:   fetchedAt,
+		Version:     3,
+		Fingerprint: "abc",
+		Files:       map[string]string{"/secrets/api": HashContent([]byte("token"))},
+	})
+	if err := s.Save(); err != nil {
+		t.Fatalf("failed to save state: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("state file not written: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %04o", info.Mode().Perm())
+	}
+
+	loaded, err := LoadStore(path)
+	if err != nil {
+		t.Fatalf("failed to load state: %v", err)
+	}
+	if names := strings.Join(loaded.Names(), ","); names != "api,db" {
+		t.Errorf("expected api,db, got %s", names)
+	}
+	api, _ := loaded.Get("api")
+	if api.Version != 3 || !api.FetchedAt.Equal(fetchedAt) || api.Files["/secrets/api"] == "" {
+		t.Errorf("unexpected state %+v", api)
+	}
+	db, _ := loaded.Get("db")
+	if db.Lease == nil || db.Lease.Term != time.Hour || !db.Lease.Renewable {
+		t.Errorf("unexpected lease %+v", db.Lease)
+	}
+}
+
+func TestStore_Retain(t *testing.T) {
+	s := NewStore("")
+	s.Set("kept", SecretState{})
+	s.Set("removed", SecretState{})
+
+	if !s.Retain([]string{"kept", "unknown"}) {
+		t.Error("expected a secret to be removed")
+	}
+	if names := strings.Join(s.Names(), ","); names != "kept" {
+		t.Errorf("expected only kept, got %s", names)
+	}
+	if s.Retain([]string{"kept"}) {
+		t.Error("expected nothing left to remove")
+	}
+}
+
+func TestLoadStore(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := LoadStore(filepath.Join(dir, "missing.json"))
+	if err != nil || len(s.Names()) != 0 {
+		t.Errorf("expected an empty store for a missing file, got %v, %v", s.Names(), err)
+	}
+
+	path := filepath.Join(dir, "state.json")
+	if err := os.WriteFile(path, []byte(`{"version": 2, "secrets": {}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadStore(path); err == nil || !strings.Contains(err.Error(), "unsupported state file version 2") {
+		t.Errorf("expected version error, got %v", err)
+	}
+}
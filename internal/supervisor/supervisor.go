@@ -0,0 +1,56 @@
+// Package supervisor runs a child command with secrets in its environment
+// and restarts or signals it when they change, so secrets-sync can be the
+// entrypoint of a single-process container
+package supervisor
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrUnsupported is returned on platforms where children cannot be supervised
+var ErrUnsupported = errors.New("running a child command is not supported on this platform")
+
+// ErrNotRunning is returned when signalling while no child runs
+var ErrNotRunning = errors.New("child process is not running")
+
+// DefaultKillTimeout is how long Stop waits after SIGTERM before SIGKILL
+const DefaultKillTimeout = 10 * time.Second
+
+// Supervisor runs one instance of a command at a time
+type Supervisor struct {
+	command     []string
+	killTimeout time.Duration
+
+	mu      sync.Mutex
+	pid     int      // Zero while no child runs
+	stopped chan int // Receives the exit code of a child being stopped, nil otherwise
+	exited  chan int // Receives the exit code of a child that exited by itself
+}
+
+// New creates a supervisor for command, the program followed by its arguments
+func New(command []string, killTimeout time.Duration) *Supervisor {
+	if killTimeout <= 0 {
+		killTimeout = DefaultKillTimeout
+	}
+	return &Supervisor{
+		command:     command,
+		killTimeout: killTimeout,
+		exited:      make(chan int, 1),
+	}
+}
+
+// Exited delivers the exit code of the child when it exits without being
+// stopped; a child killed by a signal reports 128 plus the signal number
+func (s *Supervisor) Exited() <-chan int {
+	return s.exited
+}
+
+// Restart stops the child and starts it again with env
+func (s *Supervisor) Restart(env []string) error {
+	if err := s.Stop(); err != nil {
+		return err
+	}
+	return s.Start(env)
+}
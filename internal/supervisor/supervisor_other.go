@@ -0,0 +1,31 @@
+//go:build windows
+// +build windows
+
+package supervisor
+
+import (
+	"os"
+)
+
+// ForwardedSignals are passed on to the child when the supervisor receives them
+var ForwardedSignals = []os.Signal{os.Interrupt}
+
+// ParseSignal is not available on platforms without POSIX signals
+func ParseSignal(name string) (os.Signal, error) {
+	return nil, ErrUnsupported
+}
+
+// Start is not available on this platform
+func (s *Supervisor) Start(env []string) error {
+	return ErrUnsupported
+}
+
+// Signal is not available on this platform
+func (s *Supervisor) Signal(sig os.Signal) error {
+	return ErrUnsupported
+}
+
+// Stop is a no-op on this platform, as no child is ever started
+func (s *Supervisor) Stop() error {
+	return nil
+}
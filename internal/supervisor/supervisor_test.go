@@ -0,0 +1,144 @@
+//go:build !windows
+// +build !windows
+
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// waitExit waits for the child to exit by itself
+func waitExit(t *testing.T, s *Supervisor) int {
+	t.Helper()
+	select {
+	case code := <-s.Exited():
+		return code
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for child to exit")
+		return -1
+	}
+}
+
+// waitFile waits until path exists and returns its content
+func waitFile(t *testing.T, path string) string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if data, err := os.ReadFile(path); err == nil && len(data) > 0 {
+			return string(data)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timeout waiting for %s", path)
+	return ""
+}
+
+func TestSupervisor_ExitCode(t *testing.T) {
+	s := New([]string{"sh", "-c", "exit 3"}, time.Second)
+	if err := s.Start(nil); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	if code := waitExit(t, s); code != 3 {
+		t.Errorf("expected exit code 3, got %d", code)
+	}
+	if err := s.Signal(syscall.SIGHUP); err != ErrNotRunning {
+		t.Errorf("expected ErrNotRunning after exit, got %v", err)
+	}
+}
+
+func TestSupervisor_Environment(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "env")
+	s := New([]string{"sh", "-c", `printf %s "$DB_PASSWORD" > "$OUT"`}, time.Second)
+	if err := s.Start([]string{"DB_PASSWORD=s3cret", "OUT=" + out}); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	waitExit(t, s)
+
+	if got := waitFile(t, out); got != "s3cret" {
+		t.Errorf("expected s3cret, got %q", got)
+	}
+}
+
+func TestSupervisor_Signal(t *testing.T) {
+	ready := filepath.Join(t.TempDir(), "ready")
+	s := New([]string{"sh", "-c", `trap 'exit 7' USR1; echo ready > "$READY"; while :; do sleep 0.05; done`}, time.Second)
+	if err := s.Start([]string{"READY=" + ready}); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	waitFile(t, ready)
+
+	if err := s.Signal(syscall.SIGUSR1); err != nil {
+		t.Fatalf("failed to signal: %v", err)
+	}
+	if code := waitExit(t, s); code != 7 {
+		t.Errorf("expected exit code 7, got %d", code)
+	}
+}
+
+func TestSupervisor_Restart(t *testing.T) {
+	dir := t.TempDir()
+	s := New([]string{"sh", "-c", `echo "$GEN" > "$DIR/$GEN"; while :; do sleep 0.05; done`}, time.Second)
+	if err := s.Start([]string{"GEN=1", "DIR=" + dir}); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	waitFile(t, filepath.Join(dir, "1"))
+
+	if err := s.Restart([]string{"GEN=2", "DIR=" + dir}); err != nil {
+		t.Fatalf("failed to restart: %v", err)
+	}
+	if got := waitFile(t, filepath.Join(dir, "2")); strings.TrimSpace(got) != "2" {
+		t.Errorf("expected restarted child to see GEN=2, got %q", got)
+	}
+
+	// A stopped child does not count as exited
+	select {
+	case code := <-s.Exited():
+		t.Errorf("unexpected exit %d reported for the stopped child", code)
+	default:
+	}
+
+	if err := s.Stop(); err != nil {
+		t.Fatalf("failed to stop: %v", err)
+	}
+}
+
+func TestSupervisor_KillTimeout(t *testing.T) {
+	ready := filepath.Join(t.TempDir(), "ready")
+	s := New([]string{"sh", "-c", `trap '' TERM; echo ready > "$READY"; while :; do sleep 0.05; done`}, 200*time.Millisecond)
+	if err := s.Start([]string{"READY=" + ready}); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	waitFile(t, ready)
+
+	start := time.Now()
+	if err := s.Stop(); err != nil {
+		t.Fatalf("failed to stop: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("expected SIGKILL only after the kill timeout, stopped after %s", elapsed)
+	}
+}
+
+func TestSupervisor_CommandNotFound(t *testing.T) {
+	s := New([]string{"secrets-sync-no-such-command"}, time.Second)
+	if err := s.Start(nil); err == nil {
+		t.Error("expected error for unknown command")
+	}
+}
+
+func TestParseSignal(t *testing.T) {
+	for _, name := range []string{"HUP", "SIGHUP", "sighup"} {
+		sig, err := ParseSignal(name)
+		if err != nil || sig != syscall.SIGHUP {
+			t.Errorf("ParseSignal(%q) = %v, %v", name, sig, err)
+		}
+	}
+	if _, err := ParseSignal("KILL"); err == nil {
+		t.Error("expected error for KILL")
+	}
+}
@@ -0,0 +1,191 @@
+//go:build !windows
+// +build !windows
+
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ForwardedSignals are passed on to the child when the supervisor receives them
+var ForwardedSignals = []os.Signal{
+	syscall.SIGHUP, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM,
+	syscall.SIGUSR1, syscall.SIGUSR2, syscall.SIGWINCH,
+}
+
+// signals are the names accepted by ParseSignal
+var signals = map[string]syscall.Signal{
+	"HUP":   syscall.SIGHUP,
+	"INT":   syscall.SIGINT,
+	"QUIT":  syscall.SIGQUIT,
+	"TERM":  syscall.SIGTERM,
+	"USR1":  syscall.SIGUSR1,
+	"USR2":  syscall.SIGUSR2,
+	"WINCH": syscall.SIGWINCH,
+}
+
+// ParseSignal parses a signal name like SIGHUP or HUP
+func ParseSignal(name string) (os.Signal, error) {
+	sig, ok := signals[strings.TrimPrefix(strings.ToUpper(name), "SIG")]
+	if !ok {
+		return nil, fmt.Errorf("unsupported signal %q: must be HUP, INT, QUIT, TERM, USR1, USR2 or WINCH", name)
+	}
+	return sig, nil
+}
+
+// reaper collects every exited child of the process, including orphans
+// reparented to it when running as PID 1. It waits for any child, so the
+// process must not start other commands with os/exec meanwhile.
+var reaper struct {
+	once     sync.Once
+	mu       sync.Mutex
+	children map[int]*Supervisor
+}
+
+// Start starts the command with env as its environment, passing on stdin,
+// stdout and stderr
+func (s *Supervisor) Start(env []string) error {
+	path, err := exec.LookPath(s.command[0])
+	if err != nil {
+		return fmt.Errorf("failed to find %s: %w", s.command[0], err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pid != 0 {
+		return errors.New("child process is already running")
+	}
+
+	reaper.once.Do(startReaper)
+	reaper.mu.Lock()
+	defer reaper.mu.Unlock()
+
+	proc, err := os.StartProcess(path, s.command, &os.ProcAttr{
+		Env:   env,
+		Files: []*os.File{os.Stdin, os.Stdout, os.Stderr},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start %s: %w", s.command[0], err)
+	}
+	reaper.children[proc.Pid] = s
+	s.pid = proc.Pid
+	// The reaper waits for the child; the handle is not needed
+	_ = proc.Release()
+	return nil
+}
+
+// Signal sends sig to the child
+func (s *Supervisor) Signal(sig os.Signal) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pid == 0 {
+		return ErrNotRunning
+	}
+
+	if err := syscall.Kill(s.pid, sig.(syscall.Signal)); err != nil {
+		return fmt.Errorf("failed to signal child process: %w", err)
+	}
+	return nil
+}
+
+// Stop terminates the child with SIGTERM, then SIGKILL once the kill timeout
+// has passed, and waits for it to exit. Its exit is not reported on Exited.
+func (s *Supervisor) Stop() error {
+	s.mu.Lock()
+	pid := s.pid
+	if pid == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	stopped := make(chan int, 1)
+	s.stopped = stopped
+	s.mu.Unlock()
+
+	if err := syscall.Kill(pid, syscall.SIGTERM); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return fmt.Errorf("failed to stop child process: %w", err)
+	}
+
+	timer := time.NewTimer(s.killTimeout)
+	defer timer.Stop()
+	select {
+	case <-stopped:
+		return nil
+	case <-timer.C:
+	}
+
+	if err := syscall.Kill(pid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return fmt.Errorf("failed to kill child process: %w", err)
+	}
+	<-stopped
+	return nil
+}
+
+// exit records that the child with pid exited
+func (s *Supervisor) exit(pid, code int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pid != pid {
+		return
+	}
+
+	s.pid = 0
+	if s.stopped != nil {
+		s.stopped <- code
+		s.stopped = nil
+		return
+	}
+	select {
+	case s.exited <- code:
+	default:
+	}
+}
+
+// startReaper waits for exited children on every SIGCHLD
+func startReaper() {
+	reaper.children = make(map[int]*Supervisor)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGCHLD)
+	go func() {
+		for range sigs {
+			reapChildren()
+		}
+	}()
+}
+
+// reapChildren collects all exited children without blocking
+func reapChildren() {
+	for {
+		var status syscall.WaitStatus
+		pid, err := syscall.Wait4(-1, &status, syscall.WNOHANG, nil)
+		if errors.Is(err, syscall.EINTR) {
+			continue
+		}
+		if err != nil || pid <= 0 {
+			return
+		}
+
+		reaper.mu.Lock()
+		s := reaper.children[pid]
+		delete(reaper.children, pid)
+		reaper.mu.Unlock()
+		if s != nil {
+			s.exit(pid, exitCode(status))
+		}
+	}
+}
+
+// exitCode converts a wait status to a shell-style exit code
+func exitCode(status syscall.WaitStatus) int {
+	if status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return status.ExitStatus()
+}
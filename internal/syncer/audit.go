@@ -0,0 +1,96 @@
+package syncer
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/state"
+)
+
+// Reasons a sync ran, as reported to the auditor
+const (
+	ReasonStartup  = "startup"  // First sync after the daemon started
+	ReasonReload   = "reload"   // First sync after the config changed
+	ReasonSchedule = "schedule" // Refresh interval elapsed
+	ReasonManual   = "manual"   // Requested through the admin API
+	ReasonRetry    = "retry"    // Retry of a failed sync
+	ReasonLease    = "lease"    // Lease of dynamic credentials about to expire
+	ReasonUnseal   = "unseal"   // Vault was unsealed after a pause
+	ReasonRepair   = "repair"   // Drift found by verification
+	ReasonRestore  = "restore"  // Tampered file rewritten by the file guard
+	ReasonRollback = "rollback" // Latest backup written back on request
+	ReasonOneShot  = "oneshot"  // sync and apply subcommands
+)
+
+// reasonKey is the context key of the reason of a sync
+type reasonKey struct{}
+
+// WithReason returns a context that attributes the files written with it
+// to reason
+func WithReason(ctx context.Context, reason string) context.Context {
+	return context.WithValue(ctx, reasonKey{}, reason)
+}
+
+// reasonOf returns the reason stored in ctx, "sync" if there is none
+func reasonOf(ctx context.Context) string {
+	if reason, ok := ctx.Value(reasonKey{}).(string); ok {
+		return reason
+	}
+	return "sync"
+}
+
+// AuditEvent describes a file written with secret material; it carries the
+// hash and size of the content, never the content itself
+type AuditEvent struct {
+	Secret string
+	Source string // Vault path, or comma-separated paths, the content was read from
+	Path   string
+	SHA256 string
+	Size   int
+	Reason string
+}
+
+// WithAuditor registers a callback that receives every file written,
+// including files restored by the file guard
+func (s *SecretSyncer) WithAuditor(fn func(AuditEvent)) *SecretSyncer {
+	s.auditor = fn
+	if s.guard != nil {
+		s.guard.auditor = fn
+	}
+	return s
+}
+
+// audit reports a written file to the auditor, if any
+func audit(fn func(AuditEvent), secret, source, path string, content []byte, reason string) {
+	if fn == nil {
+		return
+	}
+	fn(AuditEvent{
+		Secret: secret,
+		Source: source,
+		Path:   path,
+		SHA256: state.HashContent(content),
+		Size:   len(content),
+		Reason: reason,
+	})
+}
+
+// secretSource describes where the data of a secret is read from
+func secretSource(cfg *config.Config, secret config.Secret) string {
+	if len(secret.Sources) > 0 {
+		sources := make([]string, len(secret.Sources))
+		for i, src := range secret.Sources {
+			sources[i] = path.Join(src.MountPath, src.Key)
+		}
+		return strings.Join(sources, ",")
+	}
+	if cfg.SecretStore.IsAzureKeyVault() {
+		return secret.Key
+	}
+	if secret.IsDynamic() {
+		return path.Join(secret.MountPath, "creds", secret.Key)
+	}
+	return path.Join(secret.MountPath, secret.Key)
+}
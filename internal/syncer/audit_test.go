@@ -0,0 +1,78 @@
+package syncer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/state"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+func TestSyncSecret_Auditor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": {"data": {"password": "s3cret"}}}`))
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	var events []AuditEvent
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0}).
+		WithAuditor(func(e AuditEvent) { events = append(events, e) })
+
+	path := filepath.Join(t.TempDir(), "password")
+	secret := config.Secret{
+		Name:      "app",
+		Key:       "app/db",
+		MountPath: "secret",
+		KVVersion: "v2",
+		Template:  config.Template{Data: map[string]string{"password": "{{ .password }}"}},
+		Files:     []config.File{{Path: path, Template: "password", Mode: "0600"}},
+	}
+
+	ctx := WithReason(context.Background(), ReasonManual)
+	for range 2 {
+		if err := syncer.SyncSecret(ctx, createTestConfig(), secret); err != nil {
+			t.Fatalf("failed to sync secret: %v", err)
+		}
+	}
+
+	want := AuditEvent{
+		Secret: "app",
+		Source: "secret/app/db",
+		Path:   path,
+		SHA256: state.HashContent([]byte("s3cret")),
+		Size:   len("s3cret"),
+		Reason: ReasonManual,
+	}
+	if len(events) != 1 || events[0] != want {
+		t.Errorf("expected a single event %+v for the write, got %+v", want, events)
+	}
+}
+
+func TestSecretSource(t *testing.T) {
+	cfg := createTestConfig()
+	tests := []struct {
+		name   string
+		secret config.Secret
+		want   string
+	}{
+		{"kv", config.Secret{MountPath: "secret", Key: "app/db"}, "secret/app/db"},
+		{"dynamic", config.Secret{Type: "database", MountPath: "database", Key: "readonly"}, "database/creds/readonly"},
+		{"sources", config.Secret{Sources: []config.Source{{MountPath: "secret", Key: "a"}, {MountPath: "kv", Key: "b"}}}, "secret/a,kv/b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := secretSource(cfg, tt.secret); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ohauer/secrets-sync/internal/azure"
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/errkind"
+	"github.com/ohauer/secrets-sync/internal/tracing"
+	"github.com/ohauer/secrets-sync/internal/vault"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// AzureClientFactory creates Azure Key Vault clients with specific credentials
+type AzureClientFactory func(ctx context.Context, creds config.CredentialSet) (*azure.Client, error)
+
+// WithAzure reads the secrets of configs whose secret store has type
+// azureKeyVault with clients created by factory
+func (s *SecretSyncer) WithAzure(factory AzureClientFactory) *SecretSyncer {
+	s.azureFactory = factory
+	return s
+}
+
+// azureClientFor returns a cached Azure Key Vault client for the credentials
+// of a secret, or creates a new one
+func (s *SecretSyncer) azureClientFor(ctx context.Context, cfg *config.Config, secret config.Secret) (*azure.Client, error) {
+	if s.azureFactory == nil {
+		return nil, fmt.Errorf("azure key vault is not configured")
+	}
+
+	credName := secret.ResolveCredentials()
+	creds, ok := cfg.SecretStore.GetCredentials(credName)
+	if !ok {
+		return nil, errkind.Wrap(errkind.Auth, fmt.Errorf("credentials %q not found", credName))
+	}
+
+	s.clientMu.Lock()
+	defer s.clientMu.Unlock()
+
+	if client, ok := s.azurePool[credName]; ok {
+		return client, nil
+	}
+
+	ctx, span := tracing.StartSpan(ctx, "azure.authenticate")
+	defer span.End()
+	client, err := s.azureFactory(ctx, creds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for credentials %q: %w", credName, err)
+	}
+
+	s.azurePool[credName] = client
+	return client, nil
+}
+
+// fetchAzure reads the secret, key or certificate of a secret from Azure Key Vault
+func (s *SecretSyncer) fetchAzure(ctx context.Context, cfg *config.Config, secret config.Secret) (vault.SecretData, error) {
+	client, err := s.azureClientFor(ctx, cfg, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, span := tracing.StartSpan(ctx, "azure.fetch")
+	defer span.End()
+	span.SetAttributes(attribute.String("object_type", secret.ObjectType))
+
+	var data map[string]interface{}
+	err = vault.Retry(ctx, s.retryConfigFor(cfg, secret), func() error {
+		var err error
+		data, err = client.Fetch(ctx, secret.ObjectType, secret.Key)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch secret: %w", err)
+	}
+	return vault.SecretData(data), nil
+}
@@ -0,0 +1,101 @@
+package syncer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/ohauer/secrets-sync/internal/azure"
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/errkind"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+// newAzureTestSyncer returns a syncer and config reading from a fake Azure
+// Key Vault that serves secrets by name
+func newAzureTestSyncer(t *testing.T, secrets map[string]string) (*SecretSyncer, *config.Config) {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/tenant/oauth2/v2.0/token" {
+			_, _ = w.Write([]byte(`{"access_token": "token", "expires_in": 3600}`))
+			return
+		}
+		value, ok := secrets[filepath.Base(r.URL.Path)]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": {"code": "SecretNotFound", "message": "not found"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"value": "` + value + `", "contentType": "text/plain"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{
+		SecretStore: config.SecretStore{
+			Type:         config.StoreTypeAzureKeyVault,
+			Address:      server.URL,
+			AuthMethod:   "clientSecret",
+			TenantID:     "tenant",
+			ClientID:     "app",
+			ClientSecret: "secret",
+		},
+	}
+	factory := func(ctx context.Context, creds config.CredentialSet) (*azure.Client, error) {
+		client, err := azure.NewClient(cfg.SecretStore.Address)
+		if err != nil {
+			return nil, err
+		}
+		return client, client.Authenticate(ctx, azure.AuthConfig{
+			Method:        azure.AuthMethod(creds.AuthMethod),
+			TenantID:      creds.TenantID,
+			ClientID:      creds.ClientID,
+			ClientSecret:  creds.ClientSecret,
+			AuthorityHost: server.URL,
+		})
+	}
+
+	vaultFactory := func(ctx context.Context, creds config.CredentialSet) (*vault.Client, error) {
+		t.Error("unexpected vault client")
+		return nil, nil
+	}
+	syncer := NewSecretSyncer(vaultFactory, vault.RetryConfig{MaxRetries: 0}).WithAzure(factory)
+	return syncer, cfg
+}
+
+func TestSyncSecret_AzureKeyVault(t *testing.T) {
+	syncer, cfg := newAzureTestSyncer(t, map[string]string{"db-password": "s3cret"})
+
+	path := filepath.Join(t.TempDir(), "password")
+	secret := config.Secret{
+		Name:     "db",
+		Key:      "db-password",
+		Template: config.Template{Data: map[string]string{"password": "{{ .value }}"}},
+		Files:    []config.File{{Path: path, Mode: "0600"}},
+	}
+
+	if err := syncer.SyncSecret(context.Background(), cfg, secret); err != nil {
+		t.Fatalf("SyncSecret failed: %v", err)
+	}
+	if content := readFile(t, path); content != "s3cret" {
+		t.Errorf("expected s3cret, got %q", content)
+	}
+}
+
+func TestSyncSecret_AzureKeyVaultNotFound(t *testing.T) {
+	syncer, cfg := newAzureTestSyncer(t, nil)
+
+	secret := config.Secret{
+		Name:     "db",
+		Key:      "missing",
+		Template: config.Template{Data: map[string]string{"password": "{{ .value }}"}},
+		Files:    []config.File{{Path: filepath.Join(t.TempDir(), "password"), Mode: "0600"}},
+	}
+
+	err := syncer.SyncSecret(context.Background(), cfg, secret)
+	if errkind.Of(err) != errkind.NotFound {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package syncer
+
+import (
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+)
+
+// WithFailureRetry retries a failed sync up to maxRetries times before the
+// next refresh, waiting backoff before the first retry and twice as long
+// before each further one, at most the refresh interval. A secret's own
+// onFailure.maxRetries takes precedence; 0 waits for the next refresh.
+func (s *Scheduler) WithFailureRetry(maxRetries int, backoff time.Duration) *Scheduler {
+	s.failureRetries = maxRetries
+	s.failureBackoff = backoff
+	return s
+}
+
+// WithFailureAlert flags a secret as alerting once after failed syncs in a
+// row fail, until it syncs again. A secret's own onFailure.alertAfter takes
+// precedence; 0 never alerts.
+func (s *Scheduler) WithFailureAlert(after int) *Scheduler {
+	s.alertAfter = after
+	return s
+}
+
+// maxRetries returns how often a failed sync of a secret is retried
+func (s *Scheduler) maxRetries(secret config.Secret) int {
+	if secret.OnFailure != nil && secret.OnFailure.MaxRetries != nil {
+		return *secret.OnFailure.MaxRetries
+	}
+	return s.failureRetries
+}
+
+// alertThreshold returns how many failed syncs in a row raise an alert
+func (s *Scheduler) alertThreshold(secret config.Secret) int {
+	if secret.OnFailure != nil && secret.OnFailure.AlertAfter != nil {
+		return *secret.OnFailure.AlertAfter
+	}
+	return s.alertAfter
+}
+
+// retryDelay returns the backoff before retrying a secret after failures
+// syncs failed in a row
+func (s *Scheduler) retryDelay(secret config.Secret, failures int) time.Duration {
+	delay := s.failureBackoff
+	for i := 1; i < failures && delay < secret.RefreshInterval; i++ {
+		delay *= 2
+	}
+	return min(delay, secret.RefreshInterval)
+}
+
+// recordOutcome counts the failed syncs of a job in a row, schedules its next
+// retry and fills in the failure fields of result; the caller holds s.mu
+func (s *Scheduler) recordOutcome(j *job, result *SyncResult) {
+	threshold := s.alertThreshold(j.secret)
+	alerting := threshold > 0 && j.failures >= threshold
+
+	j.retryAt = time.Time{}
+	if result.Error == nil {
+		result.Recovered = alerting
+		j.failures = 0
+		return
+	}
+
+	j.failures++
+	if j.failures <= s.maxRetries(j.secret) && s.failureBackoff > 0 {
+		j.retryAt = result.Timestamp.Add(s.retryDelay(j.secret, j.failures))
+	}
+	result.Failures = j.failures
+	result.Alerting = threshold > 0 && j.failures >= threshold
+	result.RetryAt = j.retryAt
+}
+
+// retryTimer returns a channel that fires when a failed sync of a job is
+// due for a retry, or nil if no retry is scheduled. Nothing is retried while
+// Vault is sealed; resume syncs every secret.
+func (s *Scheduler) retryTimer(j *job) <-chan time.Time {
+	s.mu.RLock()
+	at := j.retryAt
+	s.mu.RUnlock()
+	if at.IsZero() || s.sealed.Load() {
+		return nil
+	}
+	return time.After(time.Until(at))
+}
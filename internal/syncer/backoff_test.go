@@ -0,0 +1,134 @@
+package syncer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+func TestScheduler_FailureRetry(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The token is valid, access is denied by policy
+		if r.URL.Path == "/v1/auth/token/lookup-self" {
+			_, _ = w.Write([]byte(`{"data": {}}`))
+			return
+		}
+		if requests.Add(1) <= 3 {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors": ["permission denied"]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"data": {"key": "value"}}}`))
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0})
+	scheduler := NewScheduler(syncer).WithFailureRetry(5, 10*time.Millisecond).WithFailureAlert(2)
+	defer scheduler.Stop()
+
+	sub := scheduler.State().Subscribe(10)
+	defer sub.Close()
+
+	secret := deletableSecret(filepath.Join(t.TempDir(), "key"))
+	secret.RefreshInterval = time.Hour
+	scheduler.AddSecret(createTestConfig(), secret)
+
+	// Retried long before the next refresh, alerting from the second failure
+	for i := 1; i <= 4; i++ {
+		select {
+		case result := <-sub.C():
+			if i <= 3 {
+				if result.Success || result.Failures != i || result.RetryAt.IsZero() {
+					t.Fatalf("sync %d: expected failure %d with a retry, got %+v", i, i, result)
+				}
+				if result.Alerting != (i >= 2) {
+					t.Errorf("sync %d: expected alerting %v", i, i >= 2)
+				}
+				continue
+			}
+			if !result.Success || result.Failures != 0 || !result.Recovered {
+				t.Errorf("expected recovery, got %+v", result)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for sync %d", i)
+		}
+	}
+
+	if jobs := scheduler.Jobs(); jobs[0].Failures != 0 || !jobs[0].RetryAt.IsZero() {
+		t.Errorf("expected no retry after success, got %+v", jobs[0])
+	}
+}
+
+func TestScheduler_FailureRetryDisabled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0})
+	scheduler := NewScheduler(syncer).WithFailureRetry(5, 10*time.Millisecond).WithFailureAlert(2)
+	defer scheduler.Stop()
+
+	sub := scheduler.State().Subscribe(10)
+	defer sub.Close()
+
+	none := 0
+	secret := deletableSecret(filepath.Join(t.TempDir(), "key"))
+	secret.RefreshInterval = time.Hour
+	secret.OnFailure = &config.OnFailure{MaxRetries: &none, AlertAfter: &none}
+	scheduler.AddSecret(createTestConfig(), secret)
+
+	select {
+	case result := <-sub.C():
+		if result.Success || !result.RetryAt.IsZero() || result.Alerting {
+			t.Errorf("expected failure without retry or alert, got %+v", result)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for sync result")
+	}
+
+	select {
+	case result := <-sub.C():
+		t.Errorf("expected no retry, got %+v", result)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestScheduler_RetryDelay(t *testing.T) {
+	scheduler := NewScheduler(nil).WithFailureRetry(10, 30*time.Second)
+	secret := config.Secret{RefreshInterval: 5 * time.Minute}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{4, 4 * time.Minute},
+		{5, 5 * time.Minute}, // Capped at the refresh interval
+		{60, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := scheduler.retryDelay(secret, tt.failures); got != tt.want {
+			t.Errorf("retryDelay(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package syncer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/state"
+)
+
+// appendChecksums adds the checksum files of a secret to its rendered files,
+// in sha256sum format so they can be checked with sha256sum -c: a
+// <file>.sha256 companion naming each file with checksum set by its base
+// name, and checksumFile naming every file of the secret by its full path.
+// They come last, so they are written once the files they describe are.
+// Files of the secret not among those given are hashed on disk.
+func appendChecksums(secret config.Secret, files []renderedFile) ([]renderedFile, error) {
+	if len(files) == 0 {
+		return files, nil
+	}
+
+	rendered := make(map[string]renderedFile, len(files))
+	for _, f := range files {
+		rendered[f.config.Path] = f
+		if f.checksum {
+			line := fmt.Sprintf("%s  %s\n", state.HashContent(f.content), filepath.Base(f.config.Path))
+			files = append(files, checksumFile(f, f.config.Path+config.ChecksumSuffix, line))
+		}
+	}
+
+	if secret.ChecksumFile == "" {
+		return files, nil
+	}
+	var b strings.Builder
+	for _, file := range secret.Files {
+		var hash string
+		if f, ok := rendered[file.Path]; ok {
+			hash = state.HashContent(f.content)
+		} else {
+			var err error
+			if hash, err = state.HashFile(file.Path); err != nil {
+				return files, fmt.Errorf("failed to hash %s for checksumFile: %w", file.Path, err)
+			}
+		}
+		fmt.Fprintf(&b, "%s  %s\n", hash, file.Path)
+	}
+
+	// Written like the first file of the secret, whichever files were given
+	base := files[0]
+	if f, ok := rendered[secret.Files[0].Path]; ok {
+		base = f
+	}
+	return append(files, checksumFile(base, secret.ChecksumFile, b.String())), nil
+}
+
+// checksumFile returns a checksum file written with the mode, ownership and
+// directory settings of the file it is based on, without backups
+func checksumFile(base renderedFile, path, content string) renderedFile {
+	fileConfig := base.config
+	fileConfig.Path = path
+	fileConfig.KeepBackups = 0
+	return renderedFile{
+		secret:  base.secret,
+		source:  base.source,
+		config:  fileConfig,
+		mode:    base.mode,
+		content: []byte(content),
+	}
+}
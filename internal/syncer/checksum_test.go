@@ -0,0 +1,98 @@
+package syncer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+)
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestSyncSecret_Checksums(t *testing.T) {
+	var deleted atomic.Bool
+	syncer := newTestSyncer(t, deletableVault(&deleted))
+	dir := t.TempDir()
+	secret := deletableSecret(filepath.Join(dir, "key"))
+	secret.Files[0].Checksum = true
+	secret.ChecksumFile = filepath.Join(dir, "SHA256SUMS")
+
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+
+	want := map[string]string{
+		filepath.Join(dir, "key.sha256"): sha256Hex("value") + "  key\n",
+		filepath.Join(dir, "SHA256SUMS"): sha256Hex("value") + "  " + filepath.Join(dir, "key") + "\n",
+	}
+	for path, content := range want {
+		data, err := os.ReadFile(path)
+		if err != nil || string(data) != content {
+			t.Errorf("expected %s to hold %q, got %q (%v)", path, content, data, err)
+		}
+		if info, err := os.Stat(path); err == nil && info.Mode().Perm() != 0600 {
+			t.Errorf("expected %s to have the mode of the file, got %o", path, info.Mode().Perm())
+		}
+	}
+
+	files := syncer.Files(secret)
+	if len(files) != 3 {
+		t.Errorf("expected the file and both checksum files listed, got %+v", files)
+	}
+
+	// Deleting the secret removes the checksum files with the file
+	syncer.WithDeletionPolicy(DeletionRemove, "")
+	deleted.Store(true)
+	err := syncer.SyncSecret(context.Background(), createTestConfig(), secret)
+	var deletedErr *DeletedError
+	if !errors.As(err, &deletedErr) || len(deletedErr.Files) != 3 {
+		t.Fatalf("expected DeletedError with 3 files, got %v", err)
+	}
+	for path := range want {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed, got %v", path, err)
+		}
+	}
+}
+
+func TestRollback_Checksums(t *testing.T) {
+	var deleted atomic.Bool
+	syncer := newTestSyncer(t, deletableVault(&deleted))
+	dir := t.TempDir()
+	secret := deletableSecret(filepath.Join(dir, "key"))
+	secret.Files[0].Checksum = true
+	secret.Files[0].KeepBackups = 1
+	secret.Files = append(secret.Files, config.File{Path: filepath.Join(dir, "other"), Mode: "0600"})
+	secret.Template.Data["other"] = "{{ .key }}"
+	secret.Files[0].Template = "key"
+	secret.Files[1].Template = "other"
+	secret.ChecksumFile = filepath.Join(dir, "SHA256SUMS")
+
+	if err := os.WriteFile(secret.Files[0].Path, []byte("previous"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+
+	files, err := syncer.Rollback(context.Background(), secret)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected one file rolled back, got %v (%v)", files, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "key.sha256")); string(data) != sha256Hex("previous")+"  key\n" {
+		t.Errorf("expected the companion to describe the restored content, got %q", data)
+	}
+	wantSums := sha256Hex("previous") + "  " + secret.Files[0].Path + "\n" + sha256Hex("value") + "  " + secret.Files[1].Path + "\n"
+	if data, _ := os.ReadFile(secret.ChecksumFile); string(data) != wantSums {
+		t.Errorf("expected checksumFile %q, got %q", wantSums, data)
+	}
+}
@@ -0,0 +1,100 @@
+package syncer
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+)
+
+// CleanupResult lists the files removed by Cleanup. Unshredded lists those
+// that were to be shredded but were removed without being overwritten,
+// because they could not be opened for writing.
+type CleanupResult struct {
+	Removed    []string
+	Unshredded []string
+}
+
+// Cleanup removes or shreds the files of the secrets with cleanupOnShutdown
+// set, so no plaintext is left behind once the service stops. It must run
+// after the scheduler stopped, or a sync could write the files again. Files
+// changed by something other than this daemon are left alone.
+func (s *SecretSyncer) Cleanup(secrets []config.Secret) (CleanupResult, error) {
+	var result CleanupResult
+	var errs []error
+	for _, secret := range secrets {
+		mode := secret.CleanupOnShutdown
+		if mode == "" || mode == config.CleanupKeep {
+			continue
+		}
+
+		for _, file := range s.Files(secret) {
+			info, err := os.Lstat(file.Path)
+			if err != nil {
+				if !os.IsNotExist(err) {
+					errs = append(errs, err)
+				}
+				continue
+			}
+			if err := s.checkUnmodified(file.Path); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if s.guard != nil {
+				s.guard.Forget(file.Path)
+			}
+
+			// Symbolic links are removed, never followed
+			if mode == config.CleanupShred && info.Mode().IsRegular() {
+				if err := shredFile(file.Path, info.Size()); err != nil {
+					result.Unshredded = append(result.Unshredded, file.Path)
+				}
+			}
+			if err := os.Remove(file.Path); err != nil {
+				errs = append(errs, fmt.Errorf("failed to delete %s: %w", file.Path, err))
+				continue
+			}
+
+			if s.manifest != nil {
+				s.manifest.Remove(file.Path)
+			}
+			s.setFileHash(file.Path, "")
+			result.Removed = append(result.Removed, file.Path)
+
+			backups, err := removeBackups(file.Path, mode == config.CleanupShred)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			result.Removed = append(result.Removed, backups...)
+		}
+	}
+
+	if s.manifest != nil && len(result.Removed) > 0 {
+		if err := s.manifest.Save(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to save manifest: %w", err))
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// shredFile overwrites the first size bytes of a file with random data and
+// flushes them to disk. On copy-on-write and journaling filesystems the old
+// blocks may survive; overwriting is best effort.
+func shredFile(path string, size int64) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	if _, err := io.CopyN(f, rand.Reader, size); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to overwrite %s: %w", path, err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to sync %s: %w", path, err)
+	}
+	return f.Close()
+}
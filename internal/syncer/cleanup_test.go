@@ -0,0 +1,95 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+)
+
+func TestCleanup(t *testing.T) {
+	var deleted atomic.Bool
+	syncer := newTestSyncer(t, deletableVault(&deleted))
+	dir := t.TempDir()
+
+	kept := deletableSecret(filepath.Join(dir, "kept"))
+	kept.Name = "kept"
+	removed := deletableSecret(filepath.Join(dir, "removed"))
+	removed.Name = "removed"
+	removed.CleanupOnShutdown = config.CleanupRemove
+	shredded := deletableSecret(filepath.Join(dir, "shredded"))
+	shredded.Name = "shredded"
+	shredded.CleanupOnShutdown = config.CleanupShred
+
+	secrets := []config.Secret{kept, removed, shredded}
+	for _, secret := range secrets {
+		if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+			t.Fatalf("failed to sync %s: %v", secret.Name, err)
+		}
+	}
+
+	result, err := syncer.Cleanup(secrets)
+	if err != nil {
+		t.Fatalf("cleanup failed: %v", err)
+	}
+	if len(result.Removed) != 2 || len(result.Unshredded) != 0 {
+		t.Errorf("expected 2 files removed and all shredded, got %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "kept")); err != nil {
+		t.Errorf("expected the kept file to remain: %v", err)
+	}
+	for _, name := range []string{"removed", "shredded"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be deleted, got %v", name, err)
+		}
+	}
+}
+
+func TestShredFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret")
+	content := []byte("super-secret-value")
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := shredFile(path, int64(len(content))); err != nil {
+		t.Fatalf("failed to shred: %v", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(content) || string(got) == string(content) {
+		t.Errorf("expected the content to be overwritten in place, got %q", got)
+	}
+}
+
+func TestCleanup_RemovesBackups(t *testing.T) {
+	var deleted atomic.Bool
+	syncer := newTestSyncer(t, deletableVault(&deleted))
+	path := filepath.Join(t.TempDir(), "key")
+	secret := deletableSecret(path)
+	secret.CleanupOnShutdown = config.CleanupShred
+	secret.Files[0].KeepBackups = 1
+
+	if err := os.WriteFile(path, []byte("previous"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+
+	result, err := syncer.Cleanup([]config.Secret{secret})
+	if err != nil {
+		t.Fatalf("cleanup failed: %v", err)
+	}
+	if len(result.Removed) != 2 {
+		t.Errorf("expected the file and its backup removed, got %+v", result)
+	}
+	if _, err := os.Stat(path + ".bak.1"); !os.IsNotExist(err) {
+		t.Errorf("expected the backup to be deleted, got %v", err)
+	}
+}
@@ -0,0 +1,221 @@
+package syncer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/filewriter"
+	"github.com/ohauer/secrets-sync/internal/memlock"
+	"github.com/ohauer/secrets-sync/internal/state"
+)
+
+// DeletionPolicy selects what happens to the local files of a secret that
+// was deleted in Vault
+type DeletionPolicy string
+
+const (
+	DeletionKeep       DeletionPolicy = "keep"
+	DeletionRemove     DeletionPolicy = "delete"
+	DeletionQuarantine DeletionPolicy = "quarantine"
+)
+
+// ParseDeletionPolicy parses a DELETED_SECRET_ACTION value, treating empty as keep
+func ParseDeletionPolicy(policy string) (DeletionPolicy, error) {
+	switch DeletionPolicy(policy) {
+	case "", DeletionKeep:
+		return DeletionKeep, nil
+	case DeletionRemove:
+		return DeletionRemove, nil
+	case DeletionQuarantine:
+		return DeletionQuarantine, nil
+	default:
+		return "", fmt.Errorf("invalid deleted secret action %q (supported: keep, delete, quarantine)", policy)
+	}
+}
+
+// WithDeletionPolicy sets what happens to the files of a secret deleted in
+// Vault. Quarantined files are moved beneath quarantineDir.
+func (s *SecretSyncer) WithDeletionPolicy(policy DeletionPolicy, quarantineDir string) *SecretSyncer {
+	s.deletionPolicy = policy
+	s.quarantineDir = quarantineDir
+	return s
+}
+
+// DeletedError reports that a secret no longer exists in Vault. Files lists
+// the local files removed or quarantined by this sync, so it is empty once
+// they are gone.
+type DeletedError struct {
+	Err    error
+	Action DeletionPolicy
+	Files  []string
+}
+
+func (e *DeletedError) Error() string {
+	if len(e.Files) == 0 {
+		return fmt.Sprintf("no local files left: %v", e.Err)
+	}
+	return fmt.Sprintf("%s local files %s: %v", actionVerb(e.Action), strings.Join(e.Files, ", "), e.Err)
+}
+
+func (e *DeletedError) Unwrap() error {
+	return e.Err
+}
+
+func actionVerb(action DeletionPolicy) string {
+	if action == DeletionQuarantine {
+		return "quarantined"
+	}
+	return "removed"
+}
+
+// handleDeleted removes or quarantines the files of a secret deleted in
+// Vault, and forgets its cached data. Files changed by something other than
+// this daemon are left alone.
+func (s *SecretSyncer) handleDeleted(secret config.Secret, fetchErr error) error {
+	deleted := &DeletedError{Err: fetchErr, Action: s.deletionPolicy}
+	quarantine := filepath.Join(s.quarantineDir,
+		strings.ReplaceAll(secret.Name, string(filepath.Separator), "_"),
+		time.Now().UTC().Format("20060102T150405Z"))
+
+	// Files that must stay in memory are not moved to a disk
+	var quarantineErr error
+	if s.deletionPolicy == DeletionQuarantine && secret.RequireTmpfs {
+		if err := filewriter.CheckMemoryBacked(s.quarantineDir); err != nil {
+			quarantineErr = fmt.Errorf("requireTmpfs: %w", err)
+		}
+	}
+
+	var errs []error
+	// Checksum files go with the files they describe
+	paths := make([]string, 0, len(secret.Files))
+	for _, file := range secret.Files {
+		paths = append(paths, file.Path)
+	}
+	for _, path := range append(paths, secret.ChecksumPaths()...) {
+		if _, err := os.Lstat(path); err != nil {
+			if !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		if err := s.checkUnmodified(path); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if quarantineErr != nil {
+			errs = append(errs, fmt.Errorf("refusing to quarantine %s: %w", path, quarantineErr))
+			continue
+		}
+		if s.guard != nil {
+			s.guard.Forget(path)
+		}
+
+		var err error
+		if s.deletionPolicy == DeletionQuarantine {
+			err = quarantineFile(quarantine, path)
+		} else if err = os.Remove(path); err != nil {
+			err = fmt.Errorf("failed to delete %s: %w", path, err)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		// Backups go where the file went
+		if s.deletionPolicy == DeletionQuarantine {
+			backups, err := filewriter.Backups(path)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			for _, backup := range backups {
+				if err := quarantineFile(quarantine, backup); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		} else if _, err := removeBackups(path, false); err != nil {
+			errs = append(errs, err)
+		}
+
+		if s.manifest != nil {
+			s.manifest.Remove(path)
+		}
+		s.setFileHash(path, "")
+		deleted.Files = append(deleted.Files, path)
+	}
+
+	if s.manifest != nil && len(deleted.Files) > 0 {
+		if err := s.manifest.Save(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to save manifest: %w", err))
+		}
+	}
+	if s.cache != nil {
+		if err := s.cache.Delete(secret.Name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.forgetMemory(secret.Name)
+	s.clearFetchedAt(secret.Name)
+	s.clearSyncedVersion(secret.Name)
+	s.clearLease(secret.Name)
+	if err := s.forgetState(secret.Name); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w (cleanup errors: %v)", deleted, errs)
+	}
+	return deleted
+}
+
+// checkUnmodified refuses files whose content differs from what the
+// manifest recorded for them
+func (s *SecretSyncer) checkUnmodified(path string) error {
+	if s.manifest == nil {
+		return nil
+	}
+	entry, ok := s.manifest.Get(path)
+	if !ok {
+		return nil
+	}
+
+	hash, err := state.HashFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if hash != entry.Hash {
+		return fmt.Errorf("refusing to remove %s: content was modified outside secrets-sync", path)
+	}
+	return nil
+}
+
+// quarantineFile moves a file beneath dir, keeping its full path so files of
+// the same name cannot collide. Across filesystems it is copied and removed.
+func quarantineFile(dir, path string) error {
+	target := filepath.Join(dir, path)
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+
+	if err := os.Rename(path, target); err == nil {
+		return nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer memlock.Zero(content)
+
+	if err := os.WriteFile(target, content, 0600); err != nil {
+		return fmt.Errorf("failed to quarantine %s: %w", path, err)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to delete %s after quarantining it: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/state"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+// deletableVault serves a KV v2 secret that reads as deleted once deleted is set
+func deletableVault(deleted *atomic.Bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if deleted.Load() {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"data": {"data": null, "metadata": {"deletion_time": "2024-01-01T00:00:00Z", "destroyed": false}}}`))
+			return
+		}
+		keyValueVault(w, r)
+	}
+}
+
+func deletableSecret(path string) config.Secret {
+	return config.Secret{
+		Name:      "test-secret",
+		Key:       "test/path",
+		MountPath: "secret",
+		KVVersion: "v2",
+		Template:  config.Template{Data: map[string]string{"key": "{{ .key }}"}},
+		Files:     []config.File{{Path: path, Mode: "0600"}},
+	}
+}
+
+func TestParseDeletionPolicy(t *testing.T) {
+	tests := map[string]DeletionPolicy{
+		"":           DeletionKeep,
+		"keep":       DeletionKeep,
+		"delete":     DeletionRemove,
+		"quarantine": DeletionQuarantine,
+	}
+	for input, want := range tests {
+		got, err := ParseDeletionPolicy(input)
+		if err != nil || got != want {
+			t.Errorf("ParseDeletionPolicy(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+
+	if _, err := ParseDeletionPolicy("shred"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
+
+func TestSyncSecret_DeletedKeepsFilesByDefault(t *testing.T) {
+	var deleted atomic.Bool
+	syncer := newTestSyncer(t, deletableVault(&deleted))
+	path := filepath.Join(t.TempDir(), "key")
+	secret := deletableSecret(path)
+
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("failed to sync secret: %v", err)
+	}
+
+	deleted.Store(true)
+	err := syncer.SyncSecret(context.Background(), createTestConfig(), secret)
+	var stale *StaleError
+	if !errors.As(err, &stale) {
+		t.Fatalf("expected StaleError, got %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected file to be kept: %v", err)
+	}
+}
+
+func TestSyncSecret_DeletedRemovesFiles(t *testing.T) {
+	var deleted atomic.Bool
+	tmpDir := t.TempDir()
+	manifest := state.NewManifest(filepath.Join(tmpDir, "manifest.json"))
+	syncer := newTestSyncer(t, deletableVault(&deleted)).
+		WithManifest(manifest).
+		WithDeletionPolicy(DeletionRemove, "")
+	path := filepath.Join(tmpDir, "key")
+	secret := deletableSecret(path)
+
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("failed to sync secret: %v", err)
+	}
+
+	deleted.Store(true)
+	err := syncer.SyncSecret(context.Background(), createTestConfig(), secret)
+	var deletedErr *DeletedError
+	if !errors.As(err, &deletedErr) {
+		t.Fatalf("expected DeletedError, got %v", err)
+	}
+	if !errors.Is(err, vault.ErrSecretDeleted) {
+		t.Errorf("expected error to wrap ErrSecretDeleted, got %v", err)
+	}
+	if len(deletedErr.Files) != 1 || deletedErr.Files[0] != path {
+		t.Errorf("expected %s to be reported, got %v", path, deletedErr.Files)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected file to be removed, got %v", err)
+	}
+	if _, ok := manifest.Get(path); ok {
+		t.Error("expected manifest entry to be removed")
+	}
+
+	// Later syncs keep reporting the deletion without files
+	err = syncer.SyncSecret(context.Background(), createTestConfig(), secret)
+	if !errors.As(err, &deletedErr) || len(deletedErr.Files) != 0 {
+		t.Errorf("expected DeletedError without files, got %v", err)
+	}
+}
+
+func TestSyncSecret_DeletedQuarantinesFiles(t *testing.T) {
+	var deleted atomic.Bool
+	quarantineDir := t.TempDir()
+	syncer := newTestSyncer(t, deletableVault(&deleted)).WithDeletionPolicy(DeletionQuarantine, quarantineDir)
+	path := filepath.Join(t.TempDir(), "key")
+	secret := deletableSecret(path)
+
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("failed to sync secret: %v", err)
+	}
+
+	deleted.Store(true)
+	err := syncer.SyncSecret(context.Background(), createTestConfig(), secret)
+	var deletedErr *DeletedError
+	if !errors.As(err, &deletedErr) || deletedErr.Action != DeletionQuarantine {
+		t.Fatalf("expected quarantine DeletedError, got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected file to be moved away, got %v", err)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(quarantineDir, "test-secret", "*", path))
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("expected one quarantined file, got %v (%v)", matches, err)
+	}
+	content, err := os.ReadFile(matches[0])
+	if err != nil || string(content) != "value" {
+		t.Errorf("expected quarantined content 'value', got %q (%v)", content, err)
+	}
+}
+
+func TestSyncSecret_DeletedKeepsModifiedFiles(t *testing.T) {
+	var deleted atomic.Bool
+	tmpDir := t.TempDir()
+	manifest := state.NewManifest(filepath.Join(tmpDir, "manifest.json"))
+	syncer := newTestSyncer(t, deletableVault(&deleted)).
+		WithManifest(manifest).
+		WithDeletionPolicy(DeletionRemove, "")
+	path := filepath.Join(tmpDir, "key")
+	secret := deletableSecret(path)
+
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("failed to sync secret: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("edited"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted.Store(true)
+	err := syncer.SyncSecret(context.Background(), createTestConfig(), secret)
+	var deletedErr *DeletedError
+	if !errors.As(err, &deletedErr) {
+		t.Fatalf("expected DeletedError, got %v", err)
+	}
+	if len(deletedErr.Files) != 0 {
+		t.Errorf("expected no files to be removed, got %v", deletedErr.Files)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected modified file to be kept: %v", err)
+	}
+}
@@ -0,0 +1,272 @@
+package syncer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/filewriter"
+	"github.com/ohauer/secrets-sync/internal/memlock"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// maxDiffCells bounds the work of diffing two files line by line; larger
+// files are shown as replaced entirely
+const maxDiffCells = 4 << 20
+
+// maskedValue replaces values in diffs
+const maskedValue = "***"
+
+// FileDiff is how a file on disk differs from what a sync would write
+type FileDiff struct {
+	Secret     string
+	Path       string
+	Missing    bool     // The file does not exist
+	Content    string   // Unified diff of the content, empty if it is equal
+	Binary     bool     // The content differs but is not text, so Content is empty
+	Mismatches []string // Differences of mode and ownership
+}
+
+// Drifted reports whether the file differs from what a sync would write
+func (d FileDiff) Drifted() bool {
+	return d.Missing || d.Content != "" || d.Binary || len(d.Mismatches) > 0
+}
+
+// Diff renders the files of a secret without writing them and compares each
+// with the file on disk. Values in the content diff are masked, keeping only
+// what precedes the first = or : of a line, unless showValues is set.
+func (s *SecretSyncer) Diff(ctx context.Context, cfg *config.Config, secret config.Secret, showValues bool) ([]FileDiff, error) {
+	files, err := s.renderSecret(ctx, cfg, secret)
+	if err != nil {
+		return nil, err
+	}
+	defer wipeFiles(files)
+
+	diffs := make([]FileDiff, 0, len(files))
+	for _, f := range files {
+		d, err := diffRendered(f, showValues)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs, nil
+}
+
+// diffRendered compares a rendered file with the file on disk
+func diffRendered(f renderedFile, showValues bool) (FileDiff, error) {
+	d := FileDiff{Secret: f.secret, Path: f.config.Path}
+
+	mode, uid, gid, err := filewriter.GetFileInfo(f.config.Path)
+	if os.IsNotExist(err) {
+		d.Missing = true
+		return d, nil
+	}
+	if err != nil {
+		return d, fmt.Errorf("failed to stat %s: %w", f.config.Path, err)
+	}
+
+	if mode.Perm() != f.config.Mode.Perm() {
+		d.Mismatches = append(d.Mismatches, fmt.Sprintf("mode %04o, want %04o", mode.Perm(), f.config.Mode.Perm()))
+	}
+	if uid >= 0 && f.config.Owner >= 0 && uid != f.config.Owner {
+		d.Mismatches = append(d.Mismatches, fmt.Sprintf("owner %d, want %d", uid, f.config.Owner))
+	}
+	if gid >= 0 && f.config.Group >= 0 && gid != f.config.Group {
+		d.Mismatches = append(d.Mismatches, fmt.Sprintf("group %d, want %d", gid, f.config.Group))
+	}
+
+	current, err := os.ReadFile(f.config.Path)
+	if err != nil {
+		return d, fmt.Errorf("failed to read %s: %w", f.config.Path, err)
+	}
+	defer memlock.Zero(current)
+
+	if bytes.Equal(current, f.content) {
+		return d, nil
+	}
+	if !utf8.Valid(current) || !utf8.Valid(f.content) {
+		d.Binary = true
+		return d, nil
+	}
+
+	mask := maskLine
+	if showValues {
+		mask = func(line string) string { return line }
+	}
+	d.Content = unifiedDiff(splitLines(string(current)), splitLines(string(f.content)), mask)
+	return d, nil
+}
+
+// lineKey is the key of a line, plain or double-quoted
+const lineKey = `(?:"[A-Za-z_][A-Za-z0-9_.-]*"|[A-Za-z_][A-Za-z0-9_.-]*)`
+
+// keyedLine matches an env line (KEY=value) or a YAML or JSON line
+// (key: value). A value starting with = is base64 padding, and a colon
+// without a space is part of a raw value such as user:password.
+var keyedLine = regexp.MustCompile(`^(?:((?:export\s+)?` + lineKey + `\s*=)[^=]|(` + lineKey + `\s*:)\s+\S)`)
+
+// maskLine hides the value of a line, keeping its indentation and the key
+// of an env, YAML or JSON line. Any other line is masked as a whole.
+func maskLine(line string) string {
+	trimmed := strings.TrimLeft(line, " \t")
+	if trimmed == "" {
+		return line
+	}
+	indent := line[:len(line)-len(trimmed)]
+	if m := keyedLine.FindStringSubmatch(trimmed); m != nil {
+		return indent + m[1] + m[2] + " " + maskedValue
+	}
+	return indent + maskedValue
+}
+
+// splitLines splits text into lines without their line breaks
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffOp is one line of an edit script: ' ' kept, '-' removed or '+' added
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// unifiedDiff returns the hunks of a unified diff turning a into b, with
+// every line passed through mask
+func unifiedDiff(a, b []string, mask func(string) string) string {
+	ops := editScript(a, b)
+
+	var out strings.Builder
+	for start := 0; start < len(ops); {
+		// Find the next change and the extent of its hunk
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		lo := max(first-diffContext, start)
+		hi := first
+		for unchanged := 0; hi < len(ops) && unchanged <= 2*diffContext; hi++ {
+			if ops[hi].kind == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		// Trim trailing context beyond diffContext
+		for hi > lo && ops[hi-1].kind == ' ' && trailingContext(ops[lo:hi]) > diffContext {
+			hi--
+		}
+
+		oldStart, newStart := position(ops[:lo])
+		oldLen, newLen := count(ops[lo:hi])
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(oldStart, oldLen), hunkRange(newStart, newLen))
+		for _, op := range ops[lo:hi] {
+			out.WriteByte(op.kind)
+			out.WriteString(mask(op.line))
+			out.WriteByte('\n')
+		}
+		start = hi
+	}
+	return out.String()
+}
+
+// editScript returns the edits turning a into b, based on their longest
+// common subsequence of lines
+func editScript(a, b []string) []diffOp {
+	var ops []diffOp
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the length of the common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	return ops
+}
+
+// trailingContext returns the number of unchanged lines ending ops
+func trailingContext(ops []diffOp) int {
+	n := 0
+	for i := len(ops) - 1; i >= 0 && ops[i].kind == ' '; i-- {
+		n++
+	}
+	return n
+}
+
+// position returns the 1-based line numbers in a and b following ops
+func position(ops []diffOp) (int, int) {
+	oldLines, newLines := count(ops)
+	return oldLines + 1, newLines + 1
+}
+
+// count returns the number of lines of a and b covered by ops
+func count(ops []diffOp) (int, int) {
+	oldLines, newLines := 0, 0
+	for _, op := range ops {
+		if op.kind != '+' {
+			oldLines++
+		}
+		if op.kind != '-' {
+			newLines++
+		}
+	}
+	return oldLines, newLines
+}
+
+// hunkRange formats the start and length of a hunk; an empty range starts
+// at the line before it
+func hunkRange(start, length int) string {
+	if length == 0 {
+		start--
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, length)
+}
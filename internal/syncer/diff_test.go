@@ -0,0 +1,124 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+)
+
+func TestDiff_MissingChangedAndEqual(t *testing.T) {
+	syncer := newTestSyncer(t, keyValueVault)
+	path := filepath.Join(t.TempDir(), "key")
+	cfg := createTestConfig()
+	secret := planTestSecret(path)
+
+	diffs, err := syncer.Diff(context.Background(), cfg, secret, false)
+	if err != nil {
+		t.Fatalf("diff failed: %v", err)
+	}
+	if len(diffs) != 1 || !diffs[0].Missing || !diffs[0].Drifted() {
+		t.Fatalf("expected a missing file, got %+v", diffs)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("diff must not write files")
+	}
+
+	if err := os.WriteFile(path, []byte("stale"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	diffs, err = syncer.Diff(context.Background(), cfg, secret, true)
+	if err != nil {
+		t.Fatalf("diff failed: %v", err)
+	}
+	d := diffs[0]
+	if d.Content != "@@ -1 +1 @@\n-stale\n+value\n" {
+		t.Errorf("unexpected content diff:\n%s", d.Content)
+	}
+	if len(d.Mismatches) != 1 || d.Mismatches[0] != "mode 0644, want 0600" {
+		t.Errorf("expected a mode mismatch, got %v", d.Mismatches)
+	}
+
+	if err := os.WriteFile(path, []byte("value"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+	diffs, err = syncer.Diff(context.Background(), cfg, secret, false)
+	if err != nil {
+		t.Fatalf("diff failed: %v", err)
+	}
+	if diffs[0].Drifted() {
+		t.Errorf("expected no drift, got %+v", diffs[0])
+	}
+}
+
+func TestDiff_MasksValues(t *testing.T) {
+	syncer := newTestSyncer(t, keyValueVault)
+	path := filepath.Join(t.TempDir(), "app.env")
+	cfg := createTestConfig()
+	secret := planTestSecret(path)
+	secret.Template = config.Template{Data: map[string]string{"key": "KEY={{ .key }}\nMODE=prod\n"}}
+
+	if err := os.WriteFile(path, []byte("KEY=old-secret\nMODE=prod\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	diffs, err := syncer.Diff(context.Background(), cfg, secret, false)
+	if err != nil {
+		t.Fatalf("diff failed: %v", err)
+	}
+	content := diffs[0].Content
+	if strings.Contains(content, "old-secret") || strings.Contains(content, "value") {
+		t.Errorf("expected values to be masked:\n%s", content)
+	}
+	if !strings.Contains(content, "-KEY= ***\n+KEY= ***\n") {
+		t.Errorf("expected masked key lines:\n%s", content)
+	}
+}
+
+func TestMaskLine(t *testing.T) {
+	tests := map[string]string{
+		"":                        "",
+		"password=secret":         "password= ***",
+		`  "password": "secret",`: `  "password": ***`,
+		"MIIBIjANBgkqhkiG9w0B":    "***",
+		"=leading":                "***",
+		"export TOKEN=abc":        "export TOKEN= ***",
+		"KEY=":                    "***",
+		"key: value":              "key: ***",
+		"key:":                    "***",
+		// Raw values that merely contain = or :
+		"dGhpcyBpcyBhIHNlY3JldA==": "***",
+		"c2VjcmV0=":                "***",
+		"hunter2:x":                "***",
+		"admin:hunter2":            "***",
+	}
+	for line, want := range tests {
+		if got := maskLine(line); got != want {
+			t.Errorf("maskLine(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	identity := func(line string) string { return line }
+
+	a := []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12"}
+	b := []string{"1", "2", "3", "4", "x", "6", "7", "8", "9", "10", "11", "12", "13"}
+	want := "@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+x\n 6\n 7\n 8\n" +
+		"@@ -10,3 +10,4 @@\n 10\n 11\n 12\n+13\n"
+	if got := unifiedDiff(a, b, identity); got != want {
+		t.Errorf("unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+
+	if got := unifiedDiff(nil, []string{"new"}, identity); got != "@@ -0,0 +1 @@\n+new\n" {
+		t.Errorf("unexpected diff of empty file:\n%s", got)
+	}
+	if got := unifiedDiff(a, a, identity); got != "" {
+		t.Errorf("expected no diff for equal input, got:\n%s", got)
+	}
+}
@@ -0,0 +1,63 @@
+package syncer
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/memlock"
+	"github.com/ohauer/secrets-sync/internal/template"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+// certificateExpiry returns the earliest expiry of the PEM certificates in
+// data, or the zero time if it holds none that parse
+func certificateExpiry(data []byte) time.Time {
+	var expiry time.Time
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return expiry
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+		if expiry.IsZero() || cert.NotAfter.Before(expiry) {
+			expiry = cert.NotAfter
+		}
+	}
+}
+
+// fileCertExpiry returns the certificate expiry of a rendered file with
+// certExpiry set. Keystores are binary, so the certificate and chain
+// templates they are assembled from are rendered again and parsed instead.
+func fileCertExpiry(engine *template.Engine, file config.File, data vault.SecretData, content []byte) time.Time {
+	if !file.CertExpiry {
+		return time.Time{}
+	}
+	if file.Keystore == nil {
+		return certificateExpiry(content)
+	}
+
+	var expiry time.Time
+	for _, name := range []string{file.Keystore.Certificate, file.Keystore.Chain} {
+		if name == "" {
+			continue
+		}
+		pemData, err := engine.RenderBytes(name, map[string]interface{}(data))
+		if err != nil {
+			continue
+		}
+		if e := certificateExpiry(pemData); !e.IsZero() && (expiry.IsZero() || e.Before(expiry)) {
+			expiry = e
+		}
+		memlock.Zero(pemData)
+	}
+	return expiry
+}
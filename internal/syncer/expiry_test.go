@@ -0,0 +1,125 @@
+package syncer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+// newCertificatePEM returns a self-signed PEM certificate expiring at
+// notAfter and its PEM private key
+func newCertificatePEM(t *testing.T, notAfter time.Time) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "app.example.com"},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+}
+
+func TestCertificateExpiry(t *testing.T) {
+	leafExpiry := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	caExpiry := time.Date(2029, 6, 1, 0, 0, 0, 0, time.UTC)
+	leaf, key := newCertificatePEM(t, leafExpiry)
+	ca, _ := newCertificatePEM(t, caExpiry)
+
+	tests := []struct {
+		name string
+		data string
+		want time.Time
+	}{
+		{"certificate", leaf, leafExpiry},
+		{"earliest of a chain", leaf + ca, caExpiry},
+		{"key is skipped", key + leaf, leafExpiry},
+		{"no certificate", key, time.Time{}},
+		{"not PEM", "password", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := certificateExpiry([]byte(tt.data)); !got.Equal(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSyncSecret_CertExpiry(t *testing.T) {
+	notAfter := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	cert, key := newCertificatePEM(t, notAfter)
+	body, err := json.Marshal(map[string]interface{}{"data": map[string]interface{}{"data": map[string]string{
+		"cert": cert, "key": key, "password": "changeit",
+	}}})
+	if err != nil {
+		t.Fatalf("failed to encode response: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0})
+
+	tmpDir := t.TempDir()
+	secret := config.Secret{
+		Name:      "tls",
+		Key:       "test/path",
+		MountPath: "secret",
+		KVVersion: "v2",
+		Template: config.Template{Data: map[string]string{
+			"crt":  "{{ .cert }}",
+			"key":  "{{ .key }}",
+			"pass": "{{ .password }}",
+		}},
+		Files: []config.File{
+			{Path: filepath.Join(tmpDir, "tls.crt"), Template: "crt", CertExpiry: true, Mode: "0600"},
+			{Path: filepath.Join(tmpDir, "tls.key"), Template: "key", CertExpiry: true, Mode: "0600"},
+			{Path: filepath.Join(tmpDir, "app.p12"), Format: "pkcs12", CertExpiry: true, Mode: "0600",
+				Keystore: &config.Keystore{Certificate: "crt", PrivateKey: "key", Password: "pass"}},
+			{Path: filepath.Join(tmpDir, "other.crt"), Template: "crt", Mode: "0600"},
+		},
+	}
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("failed to sync secret: %v", err)
+	}
+
+	want := []time.Time{notAfter, {}, notAfter, {}}
+	files := syncer.Files(secret)
+	for i, file := range files {
+		if !file.CertExpiry.Equal(want[i]) {
+			t.Errorf("%s: expected expiry %v, got %v", file.Path, want[i], file.CertExpiry)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package syncer
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+)
+
+// FileStatus describes a file managed for a secret
+type FileStatus struct {
+	Path       string
+	Hash       string    // SHA-256 of the content last rendered, empty if unknown
+	CertExpiry time.Time // Earliest expiry of the certificates in it, with certExpiry set
+}
+
+// Files returns the files of a secret and the hash and certificate expiry
+// of the content last rendered to each. Wildcard secrets report the files of every matched key.
+func (s *SecretSyncer) Files(secret config.Secret) []FileStatus {
+	var paths []string
+	if secret.IsWildcard() {
+		for _, known := range s.wildcardKnown(secret.Name) {
+			paths = append(paths, known...)
+		}
+		sort.Strings(paths)
+	} else {
+		for _, file := range secret.Files {
+			paths = append(paths, file.Path)
+		}
+		paths = append(paths, secret.ChecksumPaths()...)
+	}
+
+	s.hashMu.Lock()
+	defer s.hashMu.Unlock()
+	files := make([]FileStatus, 0, len(paths))
+	for _, path := range paths {
+		files = append(files, FileStatus{Path: path, Hash: s.hashes[path], CertExpiry: s.certExpiries[path]})
+	}
+	return files
+}
+
+// setFileHash records the hash of the content rendered to path; an empty
+// hash forgets the file
+func (s *SecretSyncer) setFileHash(path, hash string) {
+	s.hashMu.Lock()
+	defer s.hashMu.Unlock()
+	if hash == "" {
+		delete(s.hashes, path)
+		delete(s.certExpiries, path)
+		return
+	}
+	s.hashes[path] = hash
+}
+
+// setCertExpiry records the certificate expiry of the content rendered to
+// path; the zero time forgets it
+func (s *SecretSyncer) setCertExpiry(path string, expiry time.Time) {
+	s.hashMu.Lock()
+	defer s.hashMu.Unlock()
+	if expiry.IsZero() {
+		delete(s.certExpiries, path)
+		return
+	}
+	s.certExpiries[path] = expiry
+}
@@ -0,0 +1,79 @@
+package syncer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/state"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+func TestScheduler_ResultFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": {"data": {"key": "v1"}}}`))
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0})
+	scheduler := NewScheduler(syncer)
+	defer scheduler.Stop()
+
+	sub := scheduler.State().Subscribe(10)
+	defer sub.Close()
+
+	path := filepath.Join(t.TempDir(), "secret")
+	secret := deletableSecret(path)
+	secret.RefreshInterval = time.Hour
+	start := time.Now()
+	scheduler.AddSecret(createTestConfig(), secret)
+
+	var result SyncResult
+	select {
+	case result = <-sub.C():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the sync")
+	}
+
+	if len(result.Files) != 1 || result.Files[0].Path != path {
+		t.Fatalf("expected the written file, got %+v", result.Files)
+	}
+	if result.Files[0].Hash != state.HashContent([]byte("v1")) {
+		t.Errorf("unexpected hash %q", result.Files[0].Hash)
+	}
+	if next := result.NextSync.Sub(start); next < time.Hour || next > time.Hour+time.Minute {
+		t.Errorf("expected the next sync in an hour, got %s", next)
+	}
+
+	if jobs := scheduler.Jobs(); len(jobs) != 1 || !jobs[0].NextSync.Equal(result.NextSync) {
+		t.Errorf("expected job to report the next sync, got %+v", jobs)
+	}
+}
+
+func TestSyncSecret_ForgetsHashOfDeletedFiles(t *testing.T) {
+	var deleted atomic.Bool
+	syncer := newTestSyncer(t, deletableVault(&deleted)).WithDeletionPolicy(DeletionRemove, "")
+	secret := deletableSecret(filepath.Join(t.TempDir(), "key"))
+
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("failed to sync secret: %v", err)
+	}
+	if files := syncer.Files(secret); len(files) != 1 || files[0].Hash != state.HashContent([]byte("value")) {
+		t.Fatalf("expected the hash of the written file, got %+v", files)
+	}
+
+	deleted.Store(true)
+	_ = syncer.SyncSecret(context.Background(), createTestConfig(), secret)
+	if files := syncer.Files(secret); len(files) != 1 || files[0].Hash != "" {
+		t.Errorf("expected the hash of the deleted file to be forgotten, got %+v", files)
+	}
+}
@@ -0,0 +1,302 @@
+package syncer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/filewriter"
+	"github.com/ohauer/secrets-sync/internal/memlock"
+)
+
+// Tamper is how a guarded file was changed out-of-band
+type Tamper string
+
+// Changes the guard restores a file after
+const (
+	TamperDeleted   Tamper = "deleted"
+	TamperTruncated Tamper = "truncated"
+	TamperModified  Tamper = "modified" // Only with WithContentCheck
+)
+
+// FileGuard watches managed files and rewrites them from the last written
+// content as soon as they are deleted or truncated, or with WithContentCheck
+// modified. It keeps a copy of that content in memory for as long as a file
+// is guarded.
+type FileGuard struct {
+	watcher      *fsnotify.Watcher
+	writer       *filewriter.Writer
+	onRestore    func(secret, path string, tamper Tamper, err error)
+	auditor      func(AuditEvent) // Set by the syncer's WithAuditor
+	checkContent bool
+	mu           sync.Mutex // Held while writing, so events never see a partial write
+	files        map[string]*guardedFile
+	dirs         map[string]int // Watched directories and how many guarded files they hold
+	stopCh       chan struct{}
+	done         chan struct{}
+	stopOnce     sync.Once
+}
+
+type guardedFile struct {
+	secret  string
+	source  string
+	config  filewriter.FileConfig
+	content []byte
+}
+
+// NewFileGuard creates a file guard; onRestore is called after every restore
+// attempt with the change found and the result
+func NewFileGuard(onRestore func(secret, path string, tamper Tamper, err error)) (*FileGuard, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+
+	return &FileGuard{
+		watcher:   w,
+		writer:    filewriter.NewWriter(),
+		onRestore: onRestore,
+		files:     make(map[string]*guardedFile),
+		dirs:      make(map[string]int),
+		stopCh:    make(chan struct{}),
+		done:      make(chan struct{}),
+	}, nil
+}
+
+// WithContentCheck also restores files whose content was modified, reading
+// a guarded file back whenever it changes
+func (g *FileGuard) WithContentCheck() *FileGuard {
+	g.checkContent = true
+	return g
+}
+
+// WithFileGuard writes files through the guard, so they are restored when
+// deleted or truncated between syncs
+func (s *SecretSyncer) WithFileGuard(g *FileGuard) *SecretSyncer {
+	s.guard = g
+	// Restores are written like syncs
+	g.writer = s.writer
+	if s.auditor != nil {
+		g.auditor = s.auditor
+	}
+	return s
+}
+
+// Start begins watching guarded files
+func (g *FileGuard) Start() {
+	go g.watch()
+}
+
+// Stop stops watching and wipes the retained content. Safe to call more than once.
+func (g *FileGuard) Stop() {
+	g.stopOnce.Do(func() {
+		close(g.stopCh)
+		_ = g.watcher.Close()
+		<-g.done
+
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		for path, f := range g.files {
+			memlock.Zero(f.content)
+			delete(g.files, path)
+		}
+	})
+}
+
+// write writes a rendered file unless it is unchanged, and guards it with
+// its content. It reports whether the file was written.
+func (g *FileGuard) write(ctx context.Context, f renderedFile) (bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	written, err := g.writer.WriteBytesIfChanged(ctx, f.config, f.content)
+	if err != nil {
+		return false, err
+	}
+
+	content := make([]byte, len(f.content))
+	copy(content, f.content)
+
+	path := f.config.Path
+	if old, ok := g.files[path]; ok {
+		memlock.Zero(old.content)
+	} else if err := g.watchDir(filepath.Dir(path)); err != nil {
+		return written, err
+	}
+	g.files[path] = &guardedFile{secret: f.secret, source: f.source, config: f.config, content: content}
+	return written, nil
+}
+
+// Forget stops guarding a file, e.g. before it is removed on purpose
+func (g *FileGuard) Forget(path string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.forget(path)
+}
+
+// Retain stops guarding files that are not part of cfg
+func (g *FileGuard) Retain(cfg *config.Config) {
+	configured := make(map[string]bool)
+	var dirs []string
+	for _, secret := range cfg.Secrets {
+		for _, file := range secret.Files {
+			configured[file.Path] = true
+		}
+		// Files of a wildcard key are only known once written
+		if secret.Directory != nil {
+			dirs = append(dirs, secret.Directory.Path+string(filepath.Separator))
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for path := range g.files {
+		if !configured[path] && !underAny(path, dirs) {
+			g.forget(path)
+		}
+	}
+}
+
+// underAny reports whether path lies below one of dirs, each ending in a separator
+func underAny(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if strings.HasPrefix(path, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// Guarded returns the number of guarded files
+func (g *FileGuard) Guarded() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.files)
+}
+
+func (g *FileGuard) forget(path string) {
+	f, ok := g.files[path]
+	if !ok {
+		return
+	}
+	memlock.Zero(f.content)
+	delete(g.files, path)
+
+	dir := filepath.Dir(path)
+	if g.dirs[dir]--; g.dirs[dir] <= 0 {
+		delete(g.dirs, dir)
+		_ = g.watcher.Remove(dir)
+	}
+}
+
+// watchDir watches the directory of a guarded file. Files are replaced by
+// rename on every write, so watching the files themselves would lose the watch.
+func (g *FileGuard) watchDir(dir string) error {
+	if g.dirs[dir] == 0 {
+		if err := g.watcher.Add(dir); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+	g.dirs[dir]++
+	return nil
+}
+
+func (g *FileGuard) watch() {
+	defer close(g.done)
+
+	for {
+		select {
+		case event, ok := <-g.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Remove|fsnotify.Rename|fsnotify.Write|fsnotify.Create) != 0 {
+				g.check(event.Name)
+			}
+		case _, ok := <-g.watcher.Errors:
+			if !ok {
+				return
+			}
+		case <-g.stopCh:
+			return
+		}
+	}
+}
+
+// check restores a guarded file that was tampered with, or all files of a
+// watched directory that was removed
+func (g *FileGuard) check(name string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if f, ok := g.files[name]; ok {
+		if tamper, ok := g.tampered(f); ok {
+			g.restore(f, tamper)
+		}
+		return
+	}
+
+	if _, ok := g.dirs[name]; !ok {
+		return
+	}
+	if _, err := os.Stat(name); !errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	// The whole directory is gone; recreate it and watch it again
+	for path, f := range g.files {
+		if filepath.Dir(path) == name {
+			g.restore(f, TamperDeleted)
+		}
+	}
+	_ = g.watcher.Add(name)
+}
+
+// tampered reports how a guarded file was changed, if it was deleted,
+// truncated, or with the content check modified. Other changes, such as its
+// mode, are left to the next sync or the verifier.
+func (g *FileGuard) tampered(f *guardedFile) (Tamper, bool) {
+	info, err := os.Stat(f.config.Path)
+	if err != nil {
+		return TamperDeleted, errors.Is(err, os.ErrNotExist)
+	}
+	if info.Size() < int64(len(f.content)) {
+		return TamperTruncated, true
+	}
+	if !g.checkContent {
+		return "", false
+	}
+	if info.Size() != int64(len(f.content)) {
+		return TamperModified, true
+	}
+
+	content, err := os.ReadFile(f.config.Path)
+	if err != nil {
+		return "", false
+	}
+	defer memlock.Zero(content)
+	return TamperModified, !bytes.Equal(content, f.content)
+}
+
+// restore rewrites a tampered file. Restores are not part of a sync and
+// cannot be cancelled. The tampered content is not worth a backup.
+func (g *FileGuard) restore(f *guardedFile, tamper Tamper) {
+	config := f.config
+	config.KeepBackups = 0
+	err := g.writer.WriteBytes(context.Background(), config, f.content)
+	if err != nil {
+		err = fmt.Errorf("failed to restore %s: %w", f.config.Path, err)
+	} else {
+		audit(g.auditor, f.secret, f.source, f.config.Path, f.content, ReasonRestore)
+	}
+	if g.onRestore != nil {
+		g.onRestore(f.secret, f.config.Path, tamper, err)
+	}
+}
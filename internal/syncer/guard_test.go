@@ -0,0 +1,193 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+)
+
+type restoreEvent struct {
+	secret string
+	path   string
+	tamper Tamper
+	err    error
+}
+
+func newGuardedSyncer(t *testing.T, checkContent bool) (*SecretSyncer, *FileGuard, chan restoreEvent) {
+	t.Helper()
+
+	restored := make(chan restoreEvent, 10)
+	guard, err := NewFileGuard(func(secret, path string, tamper Tamper, err error) {
+		restored <- restoreEvent{secret: secret, path: path, tamper: tamper, err: err}
+	})
+	if err != nil {
+		t.Fatalf("failed to create guard: %v", err)
+	}
+	if checkContent {
+		guard.WithContentCheck()
+	}
+	guard.Start()
+	t.Cleanup(guard.Stop)
+
+	var deleted atomic.Bool
+	return newTestSyncer(t, deletableVault(&deleted)).WithFileGuard(guard), guard, restored
+}
+
+func waitForRestore(t *testing.T, restored chan restoreEvent, path string, tamper Tamper) {
+	t.Helper()
+
+	select {
+	case event := <-restored:
+		if event.err != nil || event.path != path || event.secret != "test-secret" || event.tamper != tamper {
+			t.Fatalf("unexpected restore event %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for restore")
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil || string(content) != "value" {
+		t.Errorf("expected restored content 'value', got %q (%v)", content, err)
+	}
+}
+
+func TestFileGuard_RestoresDeletedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	syncer, _, restored := newGuardedSyncer(t, false)
+
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), deletableSecret(path)); err != nil {
+		t.Fatalf("failed to sync secret: %v", err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForRestore(t, restored, path, TamperDeleted)
+}
+
+func TestFileGuard_RestoresTruncatedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	syncer, _, restored := newGuardedSyncer(t, false)
+
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), deletableSecret(path)); err != nil {
+		t.Fatalf("failed to sync secret: %v", err)
+	}
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForRestore(t, restored, path, TamperTruncated)
+}
+
+func TestFileGuard_RestoresRemovedDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	path := filepath.Join(dir, "key")
+	syncer, _, restored := newGuardedSyncer(t, false)
+
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), deletableSecret(path)); err != nil {
+		t.Fatalf("failed to sync secret: %v", err)
+	}
+	// Moved away at once; removing it file by file races the restore of
+	// the file, which makes rmdir fail on the directory refilled meanwhile
+	if err := os.Rename(dir, dir+".removed"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(dir + ".removed"); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForRestore(t, restored, path, TamperDeleted)
+}
+
+// replaceFile replaces a file the way config-management tools do, by
+// renaming a new file over it
+func replaceFile(t *testing.T, path, content string) {
+	t.Helper()
+	tmp := path + ".new"
+	if err := os.WriteFile(tmp, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileGuard_RestoresModifiedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	syncer, _, restored := newGuardedSyncer(t, true)
+
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), deletableSecret(path)); err != nil {
+		t.Fatalf("failed to sync secret: %v", err)
+	}
+
+	replaceFile(t, path, "value, appended")
+	waitForRestore(t, restored, path, TamperModified)
+
+	replaceFile(t, path, "VALUE")
+	waitForRestore(t, restored, path, TamperModified)
+}
+
+func TestFileGuard_IgnoresModifiedFileWithoutContentCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	syncer, _, restored := newGuardedSyncer(t, false)
+
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), deletableSecret(path)); err != nil {
+		t.Fatalf("failed to sync secret: %v", err)
+	}
+	replaceFile(t, path, "VALUE")
+
+	select {
+	case event := <-restored:
+		t.Errorf("unexpected restore %+v", event)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestFileGuard_OwnWritesAreNotRestored(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	syncer, guard, restored := newGuardedSyncer(t, false)
+	secret := deletableSecret(path)
+
+	for i := 0; i < 3; i++ {
+		if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+			t.Fatalf("failed to sync secret: %v", err)
+		}
+	}
+	if guard.Guarded() != 1 {
+		t.Errorf("expected 1 guarded file, got %d", guard.Guarded())
+	}
+
+	select {
+	case event := <-restored:
+		t.Errorf("unexpected restore %+v", event)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestFileGuard_RetainForgetsUnconfiguredFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	syncer, guard, restored := newGuardedSyncer(t, false)
+
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), deletableSecret(path)); err != nil {
+		t.Fatalf("failed to sync secret: %v", err)
+	}
+
+	guard.Retain(&config.Config{})
+	if guard.Guarded() != 0 {
+		t.Fatalf("expected no guarded files, got %d", guard.Guarded())
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case event := <-restored:
+		t.Errorf("unexpected restore %+v", event)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -0,0 +1,104 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/tracing"
+	"github.com/ohauer/secrets-sync/internal/vault"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// keepLease reports whether the credentials written for a dynamic secret
+// remain in use, renewing their lease when due. New credentials are needed
+// when the configuration or the files changed, or the lease manager finds
+// the lease cannot be extended far enough.
+func (s *SecretSyncer) keepLease(ctx context.Context, cfg *config.Config, secret config.Secret) bool {
+	s.leaseMu.Lock()
+	fp, ok := s.leased[secret.Name]
+	resumable, resume := s.resumable[secret.Name]
+	delete(s.resumable, secret.Name)
+	s.leaseMu.Unlock()
+	if !ok && resume && s.resumeLease(ctx, cfg, secret, resumable) {
+		fp, ok = resumable.fingerprint, true
+	}
+	if !ok || fp != fingerprint(cfg, secret) || !s.filesIntact(secret) {
+		return false
+	}
+
+	ctx, span := tracing.StartSpan(ctx, "vault.renew_lease")
+	defer span.End()
+	kept := s.leases.Renew(ctx, secret.Name)
+	span.SetAttributes(attribute.Bool("kept", kept))
+	return kept
+}
+
+// fetchDynamic generates new credentials for a dynamic secret and returns
+// them with their lease
+func (s *SecretSyncer) fetchDynamic(ctx context.Context, cfg *config.Config, secret config.Secret) (vault.SecretData, vault.Lease, error) {
+	client, err := s.clientFor(ctx, cfg, secret)
+	if err != nil {
+		return nil, vault.Lease{}, err
+	}
+
+	namespace := secret.ResolveNamespace(cfg.SecretStore.Namespace)
+	ctx, span := tracing.StartSpan(ctx, "vault.fetch")
+	defer span.End()
+	span.SetAttributes(vaultAttributes(secret, namespace)...)
+
+	var data vault.SecretData
+	var lease vault.Lease
+	err = client.Retry(ctx, s.retryConfigFor(cfg, secret), func() error {
+		var err error
+		data, lease, err = client.FetchDatabaseCredentials(ctx, secret.MountPath, secret.Key, namespace)
+		return err
+	})
+	if err != nil {
+		return nil, vault.Lease{}, fmt.Errorf("failed to fetch credentials: %w", err)
+	}
+	span.SetAttributes(attribute.Int64("lease_seconds", int64(lease.Duration.Seconds())))
+	return data, lease, nil
+}
+
+// setLease hands the lease of the credentials written for a dynamic secret
+// to the lease manager; credentials without a lease are fetched again on
+// every sync
+func (s *SecretSyncer) setLease(ctx context.Context, cfg *config.Config, secret config.Secret, lease vault.Lease) {
+	client, err := s.clientFor(ctx, cfg, secret)
+	if err != nil {
+		return
+	}
+	s.leases.Track(secret.Name, client, secret.ResolveNamespace(cfg.SecretStore.Namespace), lease)
+
+	s.leaseMu.Lock()
+	defer s.leaseMu.Unlock()
+	s.leased[secret.Name] = fingerprint(cfg, secret)
+}
+
+// clearLease stops renewing the lease of a secret
+func (s *SecretSyncer) clearLease(name string) {
+	s.leases.Release(name)
+
+	s.leaseMu.Lock()
+	defer s.leaseMu.Unlock()
+	delete(s.leased, name)
+}
+
+// NextLeaseRenewal returns when the lease of the credentials written for a
+// dynamic secret is due for renewal
+func (s *SecretSyncer) NextLeaseRenewal(name string) (time.Time, bool) {
+	return s.leases.NextRenewal(name)
+}
+
+// LeaseExpiry returns when the credentials written for a dynamic secret
+// expire unless their lease is renewed
+func (s *SecretSyncer) LeaseExpiry(name string) (time.Time, bool) {
+	return s.leases.Expiry(name)
+}
+
+// Leases returns the manager of the leases of dynamic secrets
+func (s *SecretSyncer) Leases() *vault.LeaseManager {
+	return s.leases
+}
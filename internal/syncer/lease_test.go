@@ -0,0 +1,143 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+// databaseServer issues numbered credentials for a database role with a
+// one second lease and renews it for renewSeconds, counting both
+type databaseServer struct {
+	issued       atomic.Int32
+	renewals     atomic.Int32
+	renewSeconds atomic.Int32
+	revoked      atomic.Value
+}
+
+func (d *databaseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/v1/database/creds/app":
+		n := d.issued.Add(1)
+		_, _ = fmt.Fprintf(w, `{"lease_id": "database/creds/app/%d", "lease_duration": 1, "renewable": true,
+			"data": {"username": "v-app-%d", "password": "pw"}}`, n, n)
+	case "/v1/sys/leases/renew":
+		d.renewals.Add(1)
+		_, _ = fmt.Fprintf(w, `{"lease_id": "database/creds/app/%d", "lease_duration": %d, "renewable": true}`,
+			d.issued.Load(), d.renewSeconds.Load())
+	case "/v1/sys/leases/revoke":
+		var body struct {
+			LeaseID string `json:"lease_id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		d.revoked.Store(body.LeaseID)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func databaseSecret(path string) config.Secret {
+	return config.Secret{
+		Name:      "db",
+		Key:       "app",
+		Type:      config.SecretTypeDatabase,
+		MountPath: "database",
+		Template:  config.Template{Data: map[string]string{"user": "{{ .username }}"}},
+		Files:     []config.File{{Path: path, Template: "user", Mode: "0600"}},
+	}
+}
+
+// waitRenewal sleeps until the lease of a secret is due for renewal
+func waitRenewal(t *testing.T, s *SecretSyncer, name string) {
+	t.Helper()
+	renewAt, ok := s.NextLeaseRenewal(name)
+	if !ok {
+		t.Fatal("expected a lease to renew")
+	}
+	time.Sleep(time.Until(renewAt))
+}
+
+func TestSyncSecret_DatabaseLease(t *testing.T) {
+	handler := &databaseServer{}
+	handler.renewSeconds.Store(1)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0})
+	cfg := createTestConfig()
+	path := filepath.Join(t.TempDir(), "user")
+	secret := databaseSecret(path)
+	ctx := context.Background()
+
+	if err := syncer.SyncSecret(ctx, cfg, secret); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if got := readFile(t, path); got != "v-app-1" {
+		t.Fatalf("expected v-app-1, got %q", got)
+	}
+
+	// The lease is valid: the credentials are kept without asking Vault
+	if err := syncer.SyncSecret(ctx, cfg, secret); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if handler.issued.Load() != 1 || handler.renewals.Load() != 0 {
+		t.Errorf("expected no request, got %d issued and %d renewals", handler.issued.Load(), handler.renewals.Load())
+	}
+
+	// Due for renewal: the lease is extended
+	waitRenewal(t, syncer, secret.Name)
+	if err := syncer.SyncSecret(ctx, cfg, secret); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if handler.issued.Load() != 1 || handler.renewals.Load() != 1 {
+		t.Errorf("expected one renewal, got %d issued and %d renewals", handler.issued.Load(), handler.renewals.Load())
+	}
+	if got := readFile(t, path); got != "v-app-1" {
+		t.Errorf("expected renewed credentials to stay, got %q", got)
+	}
+
+	// Capped by the maximum TTL: new credentials replace the old ones
+	handler.renewSeconds.Store(0)
+	waitRenewal(t, syncer, secret.Name)
+	if err := syncer.SyncSecret(ctx, cfg, secret); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if got := readFile(t, path); got != "v-app-2" {
+		t.Errorf("expected new credentials v-app-2, got %q", got)
+	}
+
+	// A changed configuration issues new credentials
+	secret.Template.Data["user"] = "user={{ .username }}"
+	if err := syncer.SyncSecret(ctx, cfg, secret); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if got := readFile(t, path); got != "user=v-app-3" {
+		t.Errorf("expected new credentials v-app-3, got %q", got)
+	}
+
+	if _, err := syncer.Environment(ctx, cfg, secret); err == nil {
+		t.Error("expected error for dynamic secret as environment")
+	}
+
+	// Nothing renews the credentials after shutdown
+	if err := syncer.Leases().RevokeAll(ctx); err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+	if revoked, _ := handler.revoked.Load().(string); revoked != "database/creds/app/3" {
+		t.Errorf("expected current lease revoked, last revoked %q", revoked)
+	}
+}
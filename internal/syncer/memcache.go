@@ -0,0 +1,91 @@
+package syncer
+
+import (
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+// memoryCache keeps the last successfully fetched data of each secret in
+// memory for a limited time, so its files can be rendered again while Vault
+// is unreachable. Unlike the encrypted cache it does not survive a restart.
+type memoryCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	data      vault.SecretData
+	fetchedAt time.Time
+}
+
+func newMemoryCache(ttl time.Duration) *memoryCache {
+	return &memoryCache{ttl: ttl, entries: make(map[string]memoryEntry)}
+}
+
+// put stores a copy of the data of a secret and drops expired entries.
+// Copies are kept and handed out, as rendering clears the data it is given.
+func (c *memoryCache) put(name string, data vault.SecretData, fetchedAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expire(time.Now())
+	c.entries[name] = memoryEntry{data: maps.Clone(data), fetchedAt: fetchedAt}
+}
+
+// get returns the data of a secret unless it is missing or older than the TTL
+func (c *memoryCache) get(name string) (vault.SecretData, time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expire(time.Now())
+	entry, ok := c.entries[name]
+	return maps.Clone(entry.data), entry.fetchedAt, ok
+}
+
+// touch records that the data held for a secret was confirmed current in
+// Vault at fetchedAt, without reading it again
+func (c *memoryCache) touch(name string, fetchedAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[name]; ok {
+		entry.fetchedAt = fetchedAt
+		c.entries[name] = entry
+	}
+}
+
+// remove forgets the data of a secret
+func (c *memoryCache) remove(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, name)
+}
+
+// expire drops the entries older than the TTL; the caller holds mu
+func (c *memoryCache) expire(now time.Time) {
+	for name, entry := range c.entries {
+		if now.Sub(entry.fetchedAt) > c.ttl {
+			delete(c.entries, name)
+		}
+	}
+}
+
+// WithMemoryCache keeps the data of every secret fetched in memory for ttl,
+// and renders files from it when Vault is unreachable, before trying the
+// encrypted cache or keeping the files on disk. Dynamic secrets are not
+// cached. 0 disables it.
+func (s *SecretSyncer) WithMemoryCache(ttl time.Duration) *SecretSyncer {
+	s.memCache = nil
+	if ttl > 0 {
+		s.memCache = newMemoryCache(ttl)
+	}
+	return s
+}
+
+// forgetMemory drops the data of a secret from the memory cache
+func (s *SecretSyncer) forgetMemory(name string) {
+	if s.memCache != nil {
+		s.memCache.remove(name)
+	}
+}
@@ -0,0 +1,154 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+func TestMemoryCache_Expiry(t *testing.T) {
+	c := newMemoryCache(time.Minute)
+	c.put("fresh", vault.SecretData{"key": "value"}, time.Now())
+	c.put("old", vault.SecretData{"key": "value"}, time.Now().Add(-2*time.Minute))
+
+	if _, _, ok := c.get("fresh"); !ok {
+		t.Error("expected the fresh entry")
+	}
+	if _, _, ok := c.get("old"); ok {
+		t.Error("expected the entry older than the TTL to expire")
+	}
+
+	c.put("touched", vault.SecretData{"key": "value"}, time.Now().Add(-2*time.Minute))
+	c.touch("touched", time.Now())
+	if _, _, ok := c.get("touched"); !ok {
+		t.Error("expected a touched entry to be kept")
+	}
+
+	c.remove("fresh")
+	if _, _, ok := c.get("fresh"); ok {
+		t.Error("expected the removed entry to be gone")
+	}
+}
+
+func TestSyncSecret_MemoryCacheFallback(t *testing.T) {
+	var available atomic.Bool
+	available.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"data": {"data": {"key": "value"}}}`))
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	client.GetAPIClient().SetMaxRetries(0)
+
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0}).WithMemoryCache(time.Hour)
+	path := filepath.Join(t.TempDir(), "key")
+	secret := config.Secret{
+		Name:      "test-secret",
+		Key:       "test/path",
+		MountPath: "secret",
+		KVVersion: "v2",
+		Template:  config.Template{Data: map[string]string{"key": "{{ .key }}"}},
+		Files:     []config.File{{Path: path, Mode: "0600"}},
+	}
+
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("failed to sync secret: %v", err)
+	}
+
+	// The file is lost while Vault is down
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	available.Store(false)
+
+	err = syncer.SyncSecret(context.Background(), createTestConfig(), secret)
+	var stale *StaleError
+	if !errors.As(err, &stale) || !stale.FromCache {
+		t.Fatalf("expected StaleError from the cache, got %v", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected file to be restored from memory: %v", err)
+	}
+	if string(content) != "value" {
+		t.Errorf("expected 'value', got '%s'", string(content))
+	}
+
+	// Once forgotten, the fetch error is reported
+	syncer.forgetMemory(secret.Name)
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	err = syncer.SyncSecret(context.Background(), createTestConfig(), secret)
+	if err == nil || errors.As(err, &stale) {
+		t.Errorf("expected a hard failure without cached data, got %v", err)
+	}
+}
+
+// waitForSync waits until the job of a secret synced once
+func waitForSync(t *testing.T, scheduler *Scheduler, name string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if last, _ := scheduler.GetLastSyncTime(name); !last.IsZero() {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s to sync", name)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestScheduler_ReconcileForgetsMemory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": {"data": {"key": "value"}}}`))
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0}).WithMemoryCache(time.Hour)
+	scheduler := NewScheduler(syncer)
+	defer scheduler.Stop()
+
+	secret := deletableSecret(filepath.Join(t.TempDir(), "key"))
+	secret.RefreshInterval = time.Hour
+	cfg := createTestConfig()
+	cfg.Secrets = []config.Secret{secret}
+	scheduler.Reconcile(cfg)
+	waitForSync(t, scheduler, secret.Name)
+	if _, _, ok := syncer.memCache.get(secret.Name); !ok {
+		t.Fatal("expected the secret to be cached in memory")
+	}
+
+	// Dropping the secret from the config drops its plaintext
+	result := scheduler.Reconcile(createTestConfig())
+	if len(result.Removed) != 1 {
+		t.Fatalf("expected the secret to be removed, got %+v", result)
+	}
+	if _, _, ok := syncer.memCache.get(secret.Name); ok {
+		t.Error("expected the removed secret to be forgotten")
+	}
+}
@@ -0,0 +1,136 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/state"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+// resumableLease is a lease recorded before a restart, resumed by the first
+// sync of its secret
+type resumableLease struct {
+	lease       vault.LeaseState
+	fingerprint string
+}
+
+// WithStateFile records what was synced for every secret in st and restores
+// what it recorded before a restart: when the data on disk was fetched, so
+// staleness is reported from then; the KV v2 version, so unchanged secrets
+// are not read again; the content hashes; and the leases of dynamic secrets,
+// which are renewed at once to confirm they are still valid. Secrets with a
+// wildcard key are not recorded. Register a hash observer first, so it is
+// told the restored hashes.
+func (s *SecretSyncer) WithStateFile(st *state.Store) *SecretSyncer {
+	s.stateFile = st
+
+	s.leaseMu.Lock()
+	defer s.leaseMu.Unlock()
+	s.resumable = make(map[string]resumableLease)
+	for _, name := range st.Names() {
+		entry, _ := st.Get(name)
+		if !entry.FetchedAt.IsZero() {
+			s.setFetchedAt(name, entry.FetchedAt)
+		}
+		if entry.Version > 0 {
+			s.putSyncedVersion(name, syncedVersion{version: entry.Version, fingerprint: entry.Fingerprint})
+		}
+		for path, hash := range entry.Files {
+			s.setFileHash(path, hash)
+			if s.hashObserver != nil {
+				s.hashObserver(path, hash)
+			}
+		}
+		if l := entry.Lease; l != nil {
+			s.resumable[name] = resumableLease{
+				lease: vault.LeaseState{
+					Lease:   vault.Lease{ID: l.ID, Renewable: l.Renewable},
+					Term:    l.Term,
+					Expires: l.Expires,
+				},
+				fingerprint: entry.Fingerprint,
+			}
+		}
+	}
+	return s
+}
+
+// saveState records the state of a secret after a sync
+func (s *SecretSyncer) saveState(cfg *config.Config, secret config.Secret) error {
+	if s.stateFile == nil || secret.Directory != nil {
+		return nil
+	}
+	fetchedAt, ok := s.getFetchedAt(secret.Name)
+	if !ok {
+		return nil
+	}
+
+	entry := state.SecretState{FetchedAt: fetchedAt, Fingerprint: fingerprint(cfg, secret)}
+	if synced, ok := s.getSyncedVersion(secret.Name); ok {
+		entry.Version = synced.version
+	}
+	s.hashMu.Lock()
+	for _, file := range secret.Files {
+		if hash, ok := s.hashes[file.Path]; ok {
+			if entry.Files == nil {
+				entry.Files = make(map[string]string, len(secret.Files))
+			}
+			entry.Files[file.Path] = hash
+		}
+	}
+	s.hashMu.Unlock()
+	if lease, ok := s.leases.State(secret.Name); ok {
+		entry.Lease = &state.LeaseState{
+			ID:        lease.ID,
+			Renewable: lease.Renewable,
+			Term:      lease.Term,
+			Expires:   lease.Expires,
+		}
+	}
+
+	s.stateFile.Set(secret.Name, entry)
+	if err := s.stateFile.Save(); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	return nil
+}
+
+// forgetState drops the recorded state of a secret
+func (s *SecretSyncer) forgetState(name string) error {
+	s.leaseMu.Lock()
+	delete(s.resumable, name)
+	s.leaseMu.Unlock()
+
+	if s.stateFile == nil {
+		return nil
+	}
+	if _, ok := s.stateFile.Get(name); !ok {
+		return nil
+	}
+	s.stateFile.Remove(name)
+	if err := s.stateFile.Save(); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	return nil
+}
+
+// resumeLease hands a lease recorded before a restart to the lease manager.
+// It is tracked even for a changed configuration, so it is revoked once
+// replaced.
+func (s *SecretSyncer) resumeLease(ctx context.Context, cfg *config.Config, secret config.Secret, resumable resumableLease) bool {
+	client, err := s.clientFor(ctx, cfg, secret)
+	if err != nil {
+		return false
+	}
+	s.leases.Resume(secret.Name, client, secret.ResolveNamespace(cfg.SecretStore.Namespace), resumable.lease)
+	if _, ok := s.leases.State(secret.Name); !ok {
+		return false
+	}
+
+	s.leaseMu.Lock()
+	defer s.leaseMu.Unlock()
+	s.leased[secret.Name] = resumable.fingerprint
+	return true
+}
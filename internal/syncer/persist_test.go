@@ -0,0 +1,95 @@
+package syncer
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/state"
+)
+
+func TestWithStateFile_Restart(t *testing.T) {
+	handler := &versionedServer{}
+	handler.version.Store(1)
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	path := filepath.Join(t.TempDir(), "key")
+	secret := deletableSecret(path)
+	cfg := createTestConfig()
+
+	first := newTestSyncer(t, handler).WithStateFile(state.NewStore(statePath))
+	if err := first.SyncSecret(context.Background(), cfg, secret); err != nil {
+		t.Fatalf("failed to sync secret: %v", err)
+	}
+	fetchedAt, _ := first.getFetchedAt(secret.Name)
+
+	// A restarted daemon knows when the data was fetched and does not read
+	// an unchanged version again
+	store, err := state.LoadStore(statePath)
+	if err != nil {
+		t.Fatalf("failed to load state: %v", err)
+	}
+	second := newTestSyncer(t, handler).WithStateFile(store)
+	if got, ok := second.getFetchedAt(secret.Name); !ok || !got.Equal(fetchedAt) {
+		t.Errorf("expected fetch time %v restored, got %v", fetchedAt, got)
+	}
+	if files := second.Files(secret); files[0].Hash == "" {
+		t.Error("expected the content hash restored")
+	}
+	if err := second.SyncSecret(context.Background(), cfg, secret); err != nil {
+		t.Fatalf("failed to sync secret after restart: %v", err)
+	}
+	if reads := handler.dataReads.Load(); reads != 1 {
+		t.Errorf("expected 1 data read across the restart, got %d", reads)
+	}
+	if got, _ := second.getFetchedAt(secret.Name); !got.After(fetchedAt.Add(-time.Second)) {
+		t.Errorf("expected the fetch time to advance, got %v", got)
+	}
+
+	// Removing the secret forgets its state
+	if err := second.forgetState(secret.Name); err != nil {
+		t.Fatalf("failed to forget state: %v", err)
+	}
+	reloaded, err := state.LoadStore(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reloaded.Get(secret.Name); ok {
+		t.Error("expected the state of the secret to be removed")
+	}
+}
+
+func TestScheduler_ReconcileForgetsState(t *testing.T) {
+	handler := &versionedServer{}
+	handler.version.Store(1)
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	syncer := newTestSyncer(t, handler).WithStateFile(state.NewStore(statePath))
+	scheduler := NewScheduler(syncer)
+	defer scheduler.Stop()
+
+	secret := deletableSecret(filepath.Join(t.TempDir(), "key"))
+	secret.RefreshInterval = time.Hour
+	cfg := createTestConfig()
+	cfg.Secrets = []config.Secret{secret}
+	scheduler.Reconcile(cfg)
+	waitForSync(t, scheduler, secret.Name)
+
+	stored, err := state.LoadStore(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := stored.Get(secret.Name); !ok {
+		t.Fatal("expected the state of the secret to be saved")
+	}
+
+	// Dropping the secret from the config drops its entry
+	scheduler.Reconcile(createTestConfig())
+	reloaded, err := state.LoadStore(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reloaded.Get(secret.Name); ok {
+		t.Error("expected the state of the removed secret to be removed")
+	}
+}
@@ -0,0 +1,222 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/state"
+)
+
+// Action describes what applying a plan would do to a file
+type Action string
+
+const (
+	ActionCreate    Action = "create"
+	ActionUpdate    Action = "update"
+	ActionDelete    Action = "delete"
+	ActionUnchanged Action = "unchanged"
+)
+
+// Change is a single planned change to a managed file
+type Change struct {
+	Action Action
+	Secret string
+	Path   string
+	Reason string
+}
+
+// Plan holds the changes required to bring disk in line with config and Vault
+type Plan struct {
+	Changes []Change
+	files   map[string][]renderedFile // Rendered files by secret name
+}
+
+// HasChanges reports whether the plan contains any create, update or delete
+func (p *Plan) HasChanges() bool {
+	for _, c := range p.Changes {
+		if c.Action != ActionUnchanged {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of changes with the given action
+func (p *Plan) Count(action Action) int {
+	n := 0
+	for _, c := range p.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// Plan compares config and Vault against the manifest and disk without writing anything
+func (s *SecretSyncer) Plan(ctx context.Context, cfg *config.Config) (*Plan, error) {
+	plan := &Plan{files: make(map[string][]renderedFile)}
+	desired := make(map[string]bool)
+
+	for _, secret := range cfg.Secrets {
+		files, err := s.renderSecret(ctx, cfg, secret)
+		if err != nil {
+			return nil, fmt.Errorf("secret %q: %w", secret.Name, err)
+		}
+		// Files of a wildcard key belong to the secret each was matched for
+		for _, f := range files {
+			plan.files[f.secret] = append(plan.files[f.secret], f)
+			desired[f.config.Path] = true
+			plan.Changes = append(plan.Changes, diffFile(f))
+		}
+	}
+
+	// Files recorded in the manifest but no longer configured are orphans
+	if s.manifest != nil {
+		for _, entry := range s.manifest.Entries() {
+			if desired[entry.Path] {
+				continue
+			}
+			plan.Changes = append(plan.Changes, Change{
+				Action: ActionDelete,
+				Secret: entry.Secret,
+				Path:   entry.Path,
+				Reason: "no longer configured",
+			})
+		}
+	}
+
+	sort.SliceStable(plan.Changes, func(i, j int) bool {
+		return plan.Changes[i].Path < plan.Changes[j].Path
+	})
+
+	return plan, nil
+}
+
+// diffFile compares a rendered file against what is currently on disk
+func diffFile(f renderedFile) Change {
+	change := Change{Secret: f.secret, Path: f.config.Path}
+
+	info, err := os.Stat(f.config.Path)
+	if err != nil {
+		change.Action = ActionCreate
+		change.Reason = "file does not exist"
+		return change
+	}
+
+	hash, err := state.HashFile(f.config.Path)
+	if err != nil {
+		change.Action = ActionUpdate
+		change.Reason = fmt.Sprintf("cannot read current file: %v", err)
+		return change
+	}
+
+	if hash != state.HashContent(f.content) {
+		change.Action = ActionUpdate
+		change.Reason = "content differs"
+		return change
+	}
+
+	if info.Mode().Perm() != f.config.Mode.Perm() {
+		change.Action = ActionUpdate
+		change.Reason = fmt.Sprintf("mode %04o differs from %04o", info.Mode().Perm(), f.config.Mode.Perm())
+		return change
+	}
+
+	change.Action = ActionUnchanged
+	return change
+}
+
+// Wipe zeroes all rendered content held by the plan
+func (p *Plan) Wipe() {
+	for _, files := range p.files {
+		wipeFiles(files)
+	}
+}
+
+// Apply writes the files and removes the orphans described by a plan.
+// The rendered content is wiped afterwards, so a plan can only be applied once.
+func (s *SecretSyncer) Apply(ctx context.Context, plan *Plan) error {
+	defer plan.Wipe()
+
+	var errs []error
+
+	for _, c := range plan.Changes {
+		switch c.Action {
+		case ActionCreate, ActionUpdate:
+			f, ok := plan.renderedFile(c.Secret, c.Path)
+			if !ok {
+				errs = append(errs, fmt.Errorf("no rendered content for %s", c.Path))
+				continue
+			}
+			if err := s.writeFile(ctx, f); err != nil {
+				errs = append(errs, err)
+			}
+		case ActionDelete:
+			if err := s.removeOrphan(c.Path); err != nil {
+				errs = append(errs, err)
+			}
+		case ActionUnchanged:
+			// Adopt files that already match so later orphan cleanup knows about them
+			if f, ok := plan.renderedFile(c.Secret, c.Path); ok {
+				s.recordFile(f)
+			}
+		}
+	}
+
+	if s.manifest != nil {
+		if err := s.manifest.Save(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to save manifest: %w", err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("apply errors: %v", errs)
+	}
+	return nil
+}
+
+// removeOrphan deletes a file that is no longer configured, refusing to touch
+// files whose content was changed by something other than this daemon
+func (s *SecretSyncer) removeOrphan(path string) error {
+	if s.manifest == nil {
+		return nil
+	}
+
+	entry, ok := s.manifest.Get(path)
+	if !ok {
+		return nil
+	}
+
+	hash, err := state.HashFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.manifest.Remove(path)
+			return nil
+		}
+		return fmt.Errorf("failed to read orphan %s: %w", path, err)
+	}
+
+	if hash != entry.Hash {
+		return fmt.Errorf("refusing to delete %s: content was modified outside secrets-sync", path)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to delete orphan %s: %w", path, err)
+	}
+
+	s.manifest.Remove(path)
+	return nil
+}
+
+// renderedFile looks up the rendered content for a path of a secret
+func (p *Plan) renderedFile(secret, path string) (renderedFile, bool) {
+	for _, f := range p.files[secret] {
+		if f.config.Path == path {
+			return f, true
+		}
+	}
+	return renderedFile{}, false
+}
@@ -0,0 +1,150 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/state"
+)
+
+func planTestSecret(path string) config.Secret {
+	return config.Secret{
+		Name:      "test-secret",
+		Key:       "test/path",
+		MountPath: "secret",
+		KVVersion: "v2",
+		Template: config.Template{
+			Data: map[string]string{"key": "{{ .key }}"},
+		},
+		Files: []config.File{{Path: path, Mode: "0600"}},
+	}
+}
+
+func TestPlan_CreateUpdateUnchanged(t *testing.T) {
+	syncer := newTestSyncer(t, keyValueVault)
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "key")
+
+	cfg := createTestConfig()
+	cfg.Secrets = []config.Secret{planTestSecret(path)}
+
+	plan, err := syncer.Plan(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	if plan.Count(ActionCreate) != 1 {
+		t.Errorf("expected 1 create, got %+v", plan.Changes)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("plan must not write files")
+	}
+
+	if err := os.WriteFile(path, []byte("stale"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	plan, err = syncer.Plan(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	if plan.Count(ActionUpdate) != 1 {
+		t.Errorf("expected 1 update, got %+v", plan.Changes)
+	}
+
+	if err := syncer.Apply(context.Background(), plan); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	plan, err = syncer.Plan(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	if plan.HasChanges() {
+		t.Errorf("expected no changes after apply, got %+v", plan.Changes)
+	}
+}
+
+func TestPlan_DeletesOrphans(t *testing.T) {
+	syncer := newTestSyncer(t, keyValueVault)
+	tmpDir := t.TempDir()
+	manifest := state.NewManifest(filepath.Join(tmpDir, "manifest.json"))
+	syncer.WithManifest(manifest)
+
+	oldPath := filepath.Join(tmpDir, "old")
+	newPath := filepath.Join(tmpDir, "new")
+
+	cfg := createTestConfig()
+	cfg.Secrets = []config.Secret{planTestSecret(oldPath)}
+	if err := syncer.SyncSecret(context.Background(), cfg, cfg.Secrets[0]); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if _, ok := manifest.Get(oldPath); !ok {
+		t.Fatal("expected written file to be recorded in manifest")
+	}
+
+	cfg.Secrets = []config.Secret{planTestSecret(newPath)}
+	plan, err := syncer.Plan(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	if plan.Count(ActionDelete) != 1 || plan.Count(ActionCreate) != 1 {
+		t.Fatalf("expected 1 create and 1 delete, got %+v", plan.Changes)
+	}
+
+	if err := syncer.Apply(context.Background(), plan); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	if _, err := os.Stat(oldPath); !os.IsNotExist(err) {
+		t.Error("expected orphaned file to be deleted")
+	}
+	if _, ok := manifest.Get(oldPath); ok {
+		t.Error("expected orphan to be removed from manifest")
+	}
+}
+
+func TestApply_KeepsModifiedOrphans(t *testing.T) {
+	syncer := newTestSyncer(t, keyValueVault)
+	tmpDir := t.TempDir()
+	manifest := state.NewManifest(filepath.Join(tmpDir, "manifest.json"))
+	syncer.WithManifest(manifest)
+
+	oldPath := filepath.Join(tmpDir, "old")
+	cfg := createTestConfig()
+	cfg.Secrets = []config.Secret{planTestSecret(oldPath)}
+	if err := syncer.SyncSecret(context.Background(), cfg, cfg.Secrets[0]); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	if err := os.WriteFile(oldPath, []byte("edited by hand"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Secrets = []config.Secret{planTestSecret(filepath.Join(tmpDir, "new"))}
+	plan, err := syncer.Plan(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+
+	if err := syncer.Apply(context.Background(), plan); err == nil {
+		t.Error("expected apply to refuse deleting a modified orphan")
+	}
+	if _, err := os.Stat(oldPath); err != nil {
+		t.Error("expected modified orphan to be kept")
+	}
+}
+
+func TestCheckSecret_DoesNotWrite(t *testing.T) {
+	syncer := newTestSyncer(t, keyValueVault)
+	path := filepath.Join(t.TempDir(), "key")
+
+	cfg := createTestConfig()
+	secret := planTestSecret(path)
+
+	if err := syncer.CheckSecret(context.Background(), cfg, secret); err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("check must not write files")
+	}
+}
@@ -0,0 +1,124 @@
+package syncer
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+)
+
+// WithMaxConcurrentSyncs limits how many secrets are synced at the same time;
+// further syncs wait for a free slot. 0 means no limit.
+func (s *Scheduler) WithMaxConcurrentSyncs(n int) *Scheduler {
+	if n > 0 {
+		s.slots = make(chan struct{}, n)
+	} else {
+		s.slots = nil
+	}
+	return s
+}
+
+// WithJitter starts each secret's refresh ticker a random offset up to d
+// after its first sync, so secrets added together do not refresh in lockstep.
+// The offset never exceeds the refresh interval; 0 disables jitter.
+func (s *Scheduler) WithJitter(d time.Duration) *Scheduler {
+	s.jitter = d
+	return s
+}
+
+// acquire waits for a sync slot. It reports false if the job or the
+// scheduler was stopped while waiting.
+func (s *Scheduler) acquire(j *job) bool {
+	if s.slots == nil {
+		return true
+	}
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	case <-j.stopCh:
+		return false
+	case <-s.stopCh:
+		return false
+	}
+}
+
+// release frees the slot taken by acquire
+func (s *Scheduler) release() {
+	if s.slots != nil {
+		<-s.slots
+	}
+}
+
+// WithRefreshJitter moves every refresh by a random share of the refresh
+// interval, up to fraction either way, so secrets with the same interval
+// drift apart instead of ticking together. A secret's own refreshJitter
+// takes precedence; 0 disables it.
+func (s *Scheduler) WithRefreshJitter(fraction float64) *Scheduler {
+	s.refreshJitter = fraction
+	return s
+}
+
+// WithStartupSplay delays the first sync of each secret added with
+// AddSecret by a random time up to d, spreading the burst of initial reads.
+// Requested syncs still run at once; 0 syncs every secret immediately.
+func (s *Scheduler) WithStartupSplay(d time.Duration) *Scheduler {
+	s.startupSplay = d
+	return s
+}
+
+// refreshInterval returns the time until the next refresh of a secret,
+// randomized by its refresh jitter
+func (s *Scheduler) refreshInterval(secret config.Secret) time.Duration {
+	fraction := s.refreshJitter
+	if secret.RefreshJitter != "" {
+		fraction, _ = config.ParseJitter(secret.RefreshJitter)
+	}
+	window := time.Duration(float64(secret.RefreshInterval) * fraction)
+	if window <= 0 {
+		return secret.RefreshInterval
+	}
+	return secret.RefreshInterval - window + rand.N(2*window+1)
+}
+
+// jitterDelay picks the random offset of a job's refresh ticker
+func (s *Scheduler) jitterDelay(j *job) time.Duration {
+	jitter := min(s.jitter, j.secret.RefreshInterval)
+	if jitter <= 0 {
+		return 0
+	}
+	return rand.N(jitter)
+}
+
+// waitJitter delays a job's refresh ticker by delay; requested syncs still
+// run meanwhile. It reports false once the job is stopped.
+func (s *Scheduler) waitJitter(j *job, delay time.Duration) bool {
+	if delay <= 0 {
+		return true
+	}
+	if !s.wait(j, delay) {
+		return false
+	}
+	j.ticker.Reset(s.refreshInterval(j.secret))
+	return true
+}
+
+// wait sleeps for delay while running requested syncs and retries of
+// failed ones. It reports false once the job is stopped.
+func (s *Scheduler) wait(j *job, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			return true
+		case reason := <-j.syncNow:
+			s.syncAndReport(s.ctx, j, reason)
+		case <-s.retryTimer(j):
+			s.syncAndReport(s.ctx, j, ReasonRetry)
+		case <-j.stopCh:
+			return false
+		case <-s.stopCh:
+			return false
+		}
+	}
+}
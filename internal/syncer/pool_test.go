@@ -0,0 +1,161 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+func TestScheduler_MaxConcurrentSyncs(t *testing.T) {
+	var active, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"data": {"data": {"key": "value"}}}`))
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0})
+	scheduler := NewScheduler(syncer).WithMaxConcurrentSyncs(2)
+	defer scheduler.Stop()
+
+	sub := scheduler.State().Subscribe(10)
+	defer sub.Close()
+
+	dir := t.TempDir()
+	const secrets = 6
+	for i := 0; i < secrets; i++ {
+		secret := deletableSecret(filepath.Join(dir, fmt.Sprintf("key-%d", i)))
+		secret.Name = fmt.Sprintf("secret-%d", i)
+		secret.RefreshInterval = time.Hour
+		scheduler.AddSecret(createTestConfig(), secret)
+	}
+
+	for i := 0; i < secrets; i++ {
+		select {
+		case result := <-sub.C():
+			if !result.Success {
+				t.Errorf("sync of %s failed: %v", result.SecretName, result.Error)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out after %d of %d syncs", i, secrets)
+		}
+	}
+
+	if p := peak.Load(); p > 2 {
+		t.Errorf("expected at most 2 concurrent syncs, got %d", p)
+	}
+}
+
+func TestScheduler_Jitter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": {"data": {"key": "value"}}}`))
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0})
+	scheduler := NewScheduler(syncer).WithJitter(time.Hour)
+	defer scheduler.Stop()
+
+	sub := scheduler.State().Subscribe(10)
+	defer sub.Close()
+
+	secret := deletableSecret(filepath.Join(t.TempDir(), "key"))
+	secret.RefreshInterval = 50 * time.Millisecond
+	scheduler.AddSecret(createTestConfig(), secret)
+
+	// The offset is capped at the refresh interval, so refreshes follow quickly
+	for i := 0; i < 3; i++ {
+		select {
+		case <-sub.C():
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for sync %d", i+1)
+		}
+	}
+}
+
+func TestScheduler_RefreshJitter(t *testing.T) {
+	scheduler := NewScheduler(nil).WithRefreshJitter(0.1)
+	defer scheduler.Stop()
+
+	secret := deletableSecret("/unused")
+	secret.RefreshInterval = time.Hour
+	varied := false
+	for i := 0; i < 100; i++ {
+		interval := scheduler.refreshInterval(secret)
+		if interval < 54*time.Minute || interval > 66*time.Minute {
+			t.Fatalf("interval %s outside of 10%% window", interval)
+		}
+		varied = varied || interval != time.Hour
+	}
+	if !varied {
+		t.Error("expected intervals to vary")
+	}
+
+	// A secret's own jitter takes precedence
+	secret.RefreshJitter = "0%"
+	if interval := scheduler.refreshInterval(secret); interval != time.Hour {
+		t.Errorf("expected jitter disabled for the secret, got %s", interval)
+	}
+}
+
+func TestScheduler_StartupSplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": {"data": {"key": "value"}}}`))
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0})
+	scheduler := NewScheduler(syncer).WithStartupSplay(time.Hour)
+	defer scheduler.Stop()
+
+	sub := scheduler.State().Subscribe(10)
+	defer sub.Close()
+
+	secret := deletableSecret(filepath.Join(t.TempDir(), "key"))
+	secret.RefreshInterval = time.Hour
+	scheduler.AddSecret(createTestConfig(), secret)
+
+	select {
+	case <-sub.C():
+		// Rarely, up to an hour of splay comes out close to zero
+		t.Skip("splay shorter than 100ms")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Requested syncs are not delayed
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if result, err := scheduler.SyncNow(ctx, secret.Name); err != nil || !result.Success {
+		t.Fatalf("expected requested sync during splay, got %+v, %v", result, err)
+	}
+}
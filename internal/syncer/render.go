@@ -0,0 +1,75 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/filewriter"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+// RenderedFile holds the content a file would be written with
+type RenderedFile struct {
+	Secret  string
+	Path    string
+	Mode    string
+	Content []byte
+}
+
+// Render renders the files of a secret without writing them. The data is
+// fetched from Vault unless fixture is set, which then stands in for the
+// fields of the secret; a wildcard key needs Vault to list its matches.
+func (s *SecretSyncer) Render(ctx context.Context, cfg *config.Config, secret config.Secret, fixture map[string]interface{}) ([]RenderedFile, error) {
+	var files []renderedFile
+	var err error
+	switch {
+	case fixture == nil:
+		files, err = s.renderSecret(ctx, cfg, secret)
+	case secret.IsWildcard():
+		return nil, fmt.Errorf("wildcard key %q cannot be rendered from fixture data", secret.Key)
+	default:
+		// renderData clears the data it was given
+		files, err = s.renderData(ctx, cfg, secret, vault.SecretData(maps.Clone(fixture)))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := make([]RenderedFile, 0, len(files))
+	for _, f := range files {
+		rendered = append(rendered, RenderedFile{Secret: f.secret, Path: f.config.Path, Mode: f.mode, Content: f.content})
+	}
+	return rendered, nil
+}
+
+// Environment fetches a secret and returns its fields as NAME=value pairs
+// for a process environment, sorted by name. The fetch is bounded by the
+// sync timeout of the secret.
+func (s *SecretSyncer) Environment(ctx context.Context, cfg *config.Config, secret config.Secret) ([]string, error) {
+	if secret.IsWildcard() {
+		return nil, fmt.Errorf("wildcard key %q cannot be used as environment", secret.Key)
+	}
+	// Every fetch would issue new credentials and look like a change
+	if secret.IsDynamic() {
+		return nil, fmt.Errorf("dynamic secret %q cannot be used as environment", secret.Name)
+	}
+
+	timeout := s.syncTimeout
+	if secret.SyncTimeout > 0 {
+		timeout = secret.SyncTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	data, _, err := s.fetchData(ctx, cfg, secret)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(data)
+	return filewriter.EnvVars(data)
+}
@@ -0,0 +1,102 @@
+package syncer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+func TestRender(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": {"data": {"key": "live"}}}`))
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0})
+
+	path := filepath.Join(t.TempDir(), "key")
+	secret := deletableSecret(path)
+
+	tests := []struct {
+		name    string
+		fixture map[string]interface{}
+		want    string
+	}{
+		{name: "live", want: "live"},
+		{name: "fixture", fixture: map[string]interface{}{"key": "fixture"}, want: "fixture"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := syncer.Render(context.Background(), createTestConfig(), secret, tt.fixture)
+			if err != nil {
+				t.Fatalf("failed to render: %v", err)
+			}
+			if len(files) != 1 || files[0].Path != path || files[0].Mode != "0600" || string(files[0].Content) != tt.want {
+				t.Errorf("unexpected files: %+v", files)
+			}
+		})
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected no file to be written, got: %v", err)
+	}
+}
+
+func TestRender_FixtureKept(t *testing.T) {
+	syncer := NewSecretSyncer(nil, vault.RetryConfig{})
+	fixture := map[string]interface{}{"key": "value"}
+	secret := deletableSecret(filepath.Join(t.TempDir(), "key"))
+
+	for i := 0; i < 2; i++ {
+		files, err := syncer.Render(context.Background(), createTestConfig(), secret, fixture)
+		if err != nil {
+			t.Fatalf("render %d failed: %v", i+1, err)
+		}
+		if string(files[0].Content) != "value" {
+			t.Errorf("render %d: expected fixture to be reused, got %q", i+1, files[0].Content)
+		}
+	}
+
+	wildcard := config.Secret{Name: "all", Key: "apps/*", Directory: &config.Directory{Path: t.TempDir()}}
+	if _, err := syncer.Render(context.Background(), createTestConfig(), wildcard, fixture); err == nil {
+		t.Error("expected wildcard key to need live data")
+	}
+}
+
+func TestEnvironment(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": {"data": {"DB_USER": "admin", "DB_PORT": 5432}}}`))
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0})
+
+	env, err := syncer.Environment(context.Background(), createTestConfig(), deletableSecret(filepath.Join(t.TempDir(), "key")))
+	if err != nil {
+		t.Fatalf("failed to read environment: %v", err)
+	}
+	if len(env) != 2 || env[0] != "DB_PORT=5432" || env[1] != "DB_USER=admin" {
+		t.Errorf("unexpected environment: %q", env)
+	}
+
+	wildcard := deletableSecret(filepath.Join(t.TempDir(), "key"))
+	wildcard.Key = "app/*"
+	if _, err := syncer.Environment(context.Background(), createTestConfig(), wildcard); err == nil {
+		t.Error("expected error for wildcard key")
+	}
+}
@@ -0,0 +1,31 @@
+package syncer
+
+import (
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+// retryConfigFor returns the retry configuration for the Vault reads of a
+// secret: the syncer's defaults, overridden by secretStore.retry, then by the
+// secret's own retry block
+func (s *SecretSyncer) retryConfigFor(cfg *config.Config, secret config.Secret) vault.RetryConfig {
+	rc := s.retryConfig
+	for _, r := range []*config.Retry{cfg.SecretStore.Retry, secret.Retry} {
+		if r == nil {
+			continue
+		}
+		if r.InitialBackoff > 0 {
+			rc.InitialBackoff = r.InitialBackoff
+		}
+		if r.MaxBackoff > 0 {
+			rc.MaxBackoff = r.MaxBackoff
+		}
+		if r.Multiplier > 0 {
+			rc.Multiplier = r.Multiplier
+		}
+		if r.MaxRetries != nil {
+			rc.MaxRetries = *r.MaxRetries
+		}
+	}
+	return rc
+}
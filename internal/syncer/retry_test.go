@@ -0,0 +1,73 @@
+package syncer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+func TestRetryConfigFor(t *testing.T) {
+	zero, five := 0, 5
+	defaults := vault.RetryConfig{InitialBackoff: time.Second, MaxBackoff: time.Minute, Multiplier: 2, MaxRetries: 3}
+	syncer := NewSecretSyncer(nil, defaults)
+
+	cfg := createTestConfig()
+	secret := config.Secret{Name: "app"}
+	if got := syncer.retryConfigFor(cfg, secret); got != defaults {
+		t.Errorf("expected defaults, got %+v", got)
+	}
+
+	cfg.SecretStore.Retry = &config.Retry{MaxBackoff: 10 * time.Second, MaxRetries: &zero}
+	want := vault.RetryConfig{InitialBackoff: time.Second, MaxBackoff: 10 * time.Second, Multiplier: 2, MaxRetries: 0}
+	if got := syncer.retryConfigFor(cfg, secret); got != want {
+		t.Errorf("expected store override %+v, got %+v", want, got)
+	}
+
+	secret.Retry = &config.Retry{InitialBackoff: 100 * time.Millisecond, Multiplier: 1.5, MaxRetries: &five}
+	want = vault.RetryConfig{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 10 * time.Second, Multiplier: 1.5, MaxRetries: 5}
+	if got := syncer.retryConfigFor(cfg, secret); got != want {
+		t.Errorf("expected secret override %+v, got %+v", want, got)
+	}
+}
+
+func TestSyncSecret_RetryOverride(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"data": {"key": "value"}}}`))
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	client.GetAPIClient().SetMaxRetries(0)
+
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0})
+	cfg := createTestConfig()
+	secret := deletableSecret(filepath.Join(t.TempDir(), "key"))
+
+	if err := syncer.SyncSecret(context.Background(), cfg, secret); err == nil {
+		t.Fatal("expected the first attempt to fail without retries")
+	}
+
+	retries := 2
+	secret.Retry = &config.Retry{InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Multiplier: 1, MaxRetries: &retries}
+	if err := syncer.SyncSecret(context.Background(), cfg, secret); err != nil {
+		t.Fatalf("expected the secret's retries to succeed, got: %v", err)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+}
@@ -0,0 +1,102 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/filewriter"
+)
+
+// ErrNoBackup is returned by Rollback for a secret without backups to
+// restore
+var ErrNoBackup = errors.New("no backup to roll back to")
+
+// Rollback writes the latest backup of every file of a secret that sets
+// keepBackups back in place. The files are written like a sync writes them,
+// so the content replaced becomes the latest backup and a second rollback
+// undoes the first. Either every such file has a backup and all are
+// restored, or nothing is written. It returns the paths restored.
+func (s *SecretSyncer) Rollback(ctx context.Context, secret config.Secret) ([]string, error) {
+	ctx = WithReason(ctx, ReasonRollback)
+
+	var files []renderedFile
+	defer func() { wipeFiles(files) }()
+	for _, file := range secret.Files {
+		if file.KeepBackups == 0 {
+			continue
+		}
+		fileConfig, err := newFileConfig(file)
+		if err != nil {
+			return nil, err
+		}
+		fileConfig.RequireTmpfs = secret.RequireTmpfs
+		content, err := filewriter.ReadBackup(file.Path)
+		if errors.Is(err, filewriter.ErrNoBackup) {
+			return nil, fmt.Errorf("%w: %s has none", ErrNoBackup, file.Path)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		f := renderedFile{
+			secret:   secret.Name,
+			source:   filewriter.BackupPath(file.Path, 1),
+			config:   fileConfig,
+			mode:     fmt.Sprintf("%04o", fileConfig.Mode.Perm()),
+			content:  content,
+			checksum: file.Checksum,
+		}
+		if file.CertExpiry && file.Keystore == nil {
+			f.certExpiry = certificateExpiry(content)
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w: no file of secret %q sets keepBackups", ErrNoBackup, secret.Name)
+	}
+	restored := len(files)
+
+	// The checksums describe the restored content
+	files, err := appendChecksums(secret, files)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, restored)
+	for i, f := range files {
+		if err := s.writeFile(ctx, f); err != nil {
+			return paths, err
+		}
+		if i < restored {
+			paths = append(paths, f.config.Path)
+		}
+	}
+
+	if s.manifest != nil {
+		if err := s.manifest.Save(); err != nil {
+			return paths, fmt.Errorf("failed to save manifest: %w", err)
+		}
+	}
+	return paths, nil
+}
+
+// removeBackups removes the backups of a file with the file itself, so no
+// older plaintext is left behind; shred overwrites them first
+func removeBackups(path string, shred bool) ([]string, error) {
+	backups, err := filewriter.Backups(path)
+	if err != nil || len(backups) == 0 {
+		return nil, err
+	}
+	if shred {
+		for _, backup := range backups {
+			// Best effort like the file itself, see shredFile
+			if info, err := os.Lstat(backup); err == nil && info.Mode().IsRegular() {
+				_ = shredFile(backup, info.Size())
+			}
+		}
+	}
+	return filewriter.RemoveBackups(path)
+}
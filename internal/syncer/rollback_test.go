@@ -0,0 +1,129 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/filewriter"
+)
+
+func TestRollback(t *testing.T) {
+	var deleted atomic.Bool
+	syncer := newTestSyncer(t, deletableVault(&deleted))
+	path := filepath.Join(t.TempDir(), "key")
+	secret := deletableSecret(path)
+	secret.Files[0].KeepBackups = 2
+
+	if err := os.WriteFile(path, []byte("previous"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("failed to sync: %v", err)
+	}
+
+	files, err := syncer.Rollback(context.Background(), secret)
+	if err != nil || len(files) != 1 || files[0] != path {
+		t.Fatalf("expected %s rolled back, got %v (%v)", path, files, err)
+	}
+	if content, _ := os.ReadFile(path); string(content) != "previous" {
+		t.Errorf("expected the backup restored, got %q", content)
+	}
+	if content, _ := filewriter.ReadBackup(path); string(content) != "value" {
+		t.Errorf("expected the replaced content as latest backup, got %q", content)
+	}
+
+	// Rolling back again undoes the rollback
+	if _, err := syncer.Rollback(context.Background(), secret); err != nil {
+		t.Fatalf("second rollback failed: %v", err)
+	}
+	if content, _ := os.ReadFile(path); string(content) != "value" {
+		t.Errorf("expected the second rollback to undo the first, got %q", content)
+	}
+}
+
+func TestRollback_NoBackup(t *testing.T) {
+	var deleted atomic.Bool
+	syncer := newTestSyncer(t, deletableVault(&deleted))
+	dir := t.TempDir()
+	secret := deletableSecret(filepath.Join(dir, "with-backup"))
+	secret.Files[0].KeepBackups = 1
+	secret.Files = append(secret.Files, config.File{Path: filepath.Join(dir, "without-backup"), Mode: "0600", KeepBackups: 1})
+
+	if err := os.WriteFile(secret.Files[0].Path, []byte("current"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filewriter.BackupPath(secret.Files[0].Path, 1), []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := syncer.Rollback(context.Background(), secret); !errors.Is(err, ErrNoBackup) {
+		t.Fatalf("expected ErrNoBackup, got %v", err)
+	}
+	if content, _ := os.ReadFile(secret.Files[0].Path); string(content) != "current" {
+		t.Errorf("expected nothing written when a file has no backup, got %q", content)
+	}
+
+	if _, err := syncer.Rollback(context.Background(), deletableSecret(filepath.Join(dir, "plain"))); !errors.Is(err, ErrNoBackup) {
+		t.Errorf("expected ErrNoBackup without keepBackups, got %v", err)
+	}
+}
+
+func TestScheduler_Rollback(t *testing.T) {
+	var deleted atomic.Bool
+	syncer := newTestSyncer(t, deletableVault(&deleted))
+	scheduler := NewScheduler(syncer)
+	defer scheduler.Stop()
+
+	path := filepath.Join(t.TempDir(), "key")
+	secret := deletableSecret(path)
+	secret.Files[0].KeepBackups = 2
+	secret.RefreshInterval = time.Hour
+	if err := os.WriteFile(path, []byte("previous"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	scheduler.AddSecret(createTestConfig(), secret)
+	waitForSync(t, scheduler, secret.Name)
+
+	scheduler.mu.RLock()
+	j := scheduler.jobs[secret.Name]
+	scheduler.mu.RUnlock()
+
+	// Refused while a sync writes the files
+	j.writing.Lock()
+	if _, err := scheduler.Rollback(context.Background(), secret.Name); !errors.Is(err, ErrSyncRunning) {
+		t.Errorf("expected ErrSyncRunning, got: %v", err)
+	}
+
+	// A sync due while the files are written, e.g. by a rollback, waits
+	synced := make(chan error, 1)
+	go func() {
+		_, err := scheduler.SyncNow(context.Background(), secret.Name)
+		synced <- err
+	}()
+	select {
+	case err := <-synced:
+		t.Fatalf("expected the sync to wait, got: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	j.writing.Unlock()
+	if err := <-synced; err != nil {
+		t.Fatalf("SyncNow failed: %v", err)
+	}
+
+	files, err := scheduler.Rollback(context.Background(), secret.Name)
+	if err != nil || len(files) != 1 || files[0] != path {
+		t.Fatalf("expected %s rolled back, got %v (%v)", path, files, err)
+	}
+	if content, _ := os.ReadFile(path); string(content) != "previous" {
+		t.Errorf("expected the backup restored, got %q", content)
+	}
+	if _, err := scheduler.Rollback(context.Background(), "missing"); !errors.Is(err, ErrUnknownSecret) {
+		t.Errorf("expected ErrUnknownSecret, got: %v", err)
+	}
+}
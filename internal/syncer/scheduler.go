@@ -2,57 +2,211 @@ package syncer
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/errkind"
+	"github.com/ohauer/secrets-sync/internal/tracing"
+	"github.com/ohauer/secrets-sync/internal/vault"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// DefaultDrainTimeout is how long Stop waits for in-flight syncs to finish
+const DefaultDrainTimeout = 25 * time.Second
+
 // Scheduler manages periodic secret synchronization
 type Scheduler struct {
-	syncer  *SecretSyncer
-	jobs    map[string]*job
-	mu      sync.RWMutex
-	stopCh  chan struct{}
-	results chan SyncResult
+	syncer         *SecretSyncer
+	jobs           map[string]*job
+	mu             sync.RWMutex
+	stopCh         chan struct{}
+	stopOnce       sync.Once
+	stopped        bool
+	state          *StateStore
+	ctx            context.Context
+	cancel         context.CancelFunc
+	wg             sync.WaitGroup // Tracks running job goroutines
+	inFlight       atomic.Int32   // Number of SyncSecret calls currently executing
+	drainTimeout   time.Duration
+	verify         verifyConfig
+	verifyOnce     sync.Once
+	seal           sealConfig
+	sealed         atomic.Bool   // Syncing is paused until Vault is unsealed
+	slots          chan struct{} // Limits concurrent syncs, nil for no limit
+	jitter         time.Duration // Maximum random offset of each refresh ticker
+	refreshJitter  float64       // Share of the refresh interval each refresh is moved by at most
+	startupSplay   time.Duration // Maximum random delay of the first sync of added secrets
+	failureRetries int           // Retries of a failed sync before the next refresh
+	failureBackoff time.Duration // Delay before the first retry, doubled for each further one
+	alertAfter     int           // Failed syncs in a row that raise an alert, 0 for never
+	removeObserver func(string)  // Optional callback told the name of every removed secret
 }
 
 type job struct {
-	secret   config.Secret
-	ticker   *time.Ticker
-	stopCh   chan struct{}
-	lastSync time.Time
+	cfg          *config.Config // Replaced by Reconcile, guarded by Scheduler.mu
+	secret       config.Secret
+	ticker       *time.Ticker
+	syncNow      chan string   // Requests a sync before the next tick, for the reason sent
+	splay        time.Duration // Delay of the first sync
+	reason       string        // Why the first sync runs: ReasonStartup or ReasonReload
+	stopCh       chan struct{}
+	lastSync     time.Time         // Guarded by Scheduler.mu
+	runningSince time.Time         // Zero while idle, guarded by Scheduler.mu
+	nextSync     time.Time         // Next tick of the refresh ticker, guarded by Scheduler.mu
+	failures     int               // Failed syncs in a row, guarded by Scheduler.mu
+	retryAt      time.Time         // When a failed sync is retried, zero if not, guarded by Scheduler.mu
+	waiters      []chan SyncResult // Served by the next sync to start, guarded by Scheduler.mu
+	writing      sync.Mutex        // Held while a sync or a rollback writes the files
+}
+
+// JobInfo describes a scheduled secret for diagnostics
+type JobInfo struct {
+	Name            string
+	RefreshInterval time.Duration
+	LastSync        time.Time
+	NextSync        time.Time
+	RunningSince    time.Time // Zero while idle
+	Failures        int       // Failed syncs in a row
+	RetryAt         time.Time // When a failed sync is retried, zero if not
 }
 
 // NewScheduler creates a new scheduler
 func NewScheduler(syncer *SecretSyncer) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Scheduler{
-		syncer:  syncer,
-		jobs:    make(map[string]*job),
-		stopCh:  make(chan struct{}),
-		results: make(chan SyncResult, 100),
+		syncer:       syncer,
+		jobs:         make(map[string]*job),
+		stopCh:       make(chan struct{}),
+		state:        NewStateStore(),
+		ctx:          ctx,
+		cancel:       cancel,
+		drainTimeout: DefaultDrainTimeout,
 	}
 }
 
-// AddSecret adds a secret to the scheduler
+// WithDrainTimeout sets how long Stop waits for in-flight syncs to finish
+func (s *Scheduler) WithDrainTimeout(timeout time.Duration) *Scheduler {
+	s.drainTimeout = timeout
+	return s
+}
+
+// WithRemoveObserver registers a callback that receives the name of every
+// secret removed by Reconcile or RemoveSecret, e.g. to drop its metrics. It
+// is called with the scheduler locked and must not call back into it.
+func (s *Scheduler) WithRemoveObserver(fn func(secret string)) *Scheduler {
+	s.removeObserver = fn
+	return s
+}
+
+// WithStateStore shares a state store between schedulers, so subscribers
+// keep receiving results when the scheduler is replaced on reload
+func (s *Scheduler) WithStateStore(store *StateStore) *Scheduler {
+	s.state = store
+	return s
+}
+
+// ReconcileResult lists the secrets whose jobs Reconcile changed
+type ReconcileResult struct {
+	Added     []string
+	Updated   []string // Restarted because their definition changed
+	Removed   []string
+	Unchanged []string
+}
+
+// AddSecret adds a secret to the scheduler, restarting its job if it is
+// already scheduled
 func (s *Scheduler) AddSecret(cfg *config.Config, secret config.Secret) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.stopped {
+		return
+	}
+	var splay time.Duration
+	if s.startupSplay > 0 {
+		splay = rand.N(s.startupSplay)
+	}
+	s.startJob(cfg, secret, splay, ReasonStartup)
+}
+
+// Reconcile brings the scheduled jobs in line with cfg: new secrets are
+// added, deleted ones removed and changed ones restarted with an immediate
+// sync. Jobs of unchanged secrets keep their ticker and last sync time and
+// use cfg from their next sync on.
+func (s *Scheduler) Reconcile(cfg *config.Config) ReconcileResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result ReconcileResult
+	if s.stopped {
+		return result
+	}
+
+	configured := make(map[string]bool, len(cfg.Secrets))
+	for _, secret := range cfg.Secrets {
+		configured[secret.Name] = true
+		existing, ok := s.jobs[secret.Name]
+		switch {
+		case !ok:
+			result.Added = append(result.Added, secret.Name)
+		case reflect.DeepEqual(existing.secret, secret):
+			existing.cfg = cfg
+			result.Unchanged = append(result.Unchanged, secret.Name)
+			continue
+		default:
+			result.Updated = append(result.Updated, secret.Name)
+		}
+		s.startJob(cfg, secret, 0, ReasonReload)
+	}
+
+	for name := range s.jobs {
+		if configured[name] {
+			continue
+		}
+		s.removeJob(name)
+		result.Removed = append(result.Removed, name)
+	}
+	sort.Strings(result.Removed)
+
+	return result
+}
+
+// startJob starts the job of a secret, replacing a running one, with its
+// first sync delayed by splay; the caller holds s.mu
+func (s *Scheduler) startJob(cfg *config.Config, secret config.Secret, splay time.Duration, reason string) {
 	if existing, ok := s.jobs[secret.Name]; ok {
 		existing.ticker.Stop()
 		close(existing.stopCh)
 	}
 
 	j := &job{
-		secret: secret,
-		ticker: time.NewTicker(secret.RefreshInterval),
-		stopCh: make(chan struct{}),
+		cfg:     cfg,
+		secret:  secret,
+		ticker:  time.NewTicker(s.refreshInterval(secret)),
+		syncNow: make(chan string, 1),
+		splay:   splay,
+		reason:  reason,
+		stopCh:  make(chan struct{}),
 	}
 
 	s.jobs[secret.Name] = j
 
-	go s.runJob(cfg, j)
+	s.wg.Add(1)
+	go s.runJob(j)
+
+	if s.verify.interval > 0 {
+		s.verifyOnce.Do(func() {
+			s.wg.Add(1)
+			go s.runVerifier()
+		})
+	}
 }
 
 // RemoveSecret removes a secret from the scheduler
@@ -60,67 +214,211 @@ func (s *Scheduler) RemoveSecret(name string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.removeJob(name)
+}
+
+// removeJob stops the job of a secret and forgets what is kept of it, in
+// memory and in STATE_FILE; the caller holds s.mu
+func (s *Scheduler) removeJob(name string) {
 	if j, ok := s.jobs[name]; ok {
 		j.ticker.Stop()
 		close(j.stopCh)
 		delete(s.jobs, name)
 	}
+	s.state.Remove(name)
+	s.syncer.clearLease(name)
+	s.syncer.forgetMemory(name)
+	// A failed save leaves the entry to be pruned on the next start
+	_ = s.syncer.forgetState(name)
+	if s.removeObserver != nil {
+		s.removeObserver(name)
+	}
 }
 
-// Stop stops all scheduled jobs
+// Stop stops all scheduled jobs and waits for in-flight syncs to drain
 func (s *Scheduler) Stop() {
-	close(s.stopCh)
+	_ = s.Shutdown(s.drainTimeout)
+}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// Shutdown stops all scheduled jobs and waits up to timeout for in-flight
+// syncs to finish. Syncs still running after the timeout have their context
+// cancelled; an error is returned in that case. Safe to call more than once.
+func (s *Scheduler) Shutdown(timeout time.Duration) error {
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
 
-	for _, j := range s.jobs {
-		j.ticker.Stop()
-		close(j.stopCh)
+		s.mu.Lock()
+		s.stopped = true
+		for _, j := range s.jobs {
+			j.ticker.Stop()
+			close(j.stopCh)
+		}
+		s.jobs = make(map[string]*job)
+		s.mu.Unlock()
+	})
+
+	drained := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		s.cancel()
+		return nil
+	case <-time.After(timeout):
 	}
-	s.jobs = make(map[string]*job)
+
+	// Abort remaining syncs; they stop at their next Vault request or file write
+	s.cancel()
+	<-drained
+	return fmt.Errorf("cancelled in-flight syncs after drain timeout of %s", timeout)
+}
+
+// InFlight returns the number of syncs currently executing
+func (s *Scheduler) InFlight() int {
+	return int(s.inFlight.Load())
 }
 
-// Results returns the results channel
-func (s *Scheduler) Results() <-chan SyncResult {
-	return s.results
+// State returns the store holding the latest result of every secret
+func (s *Scheduler) State() *StateStore {
+	return s.state
 }
 
-func (s *Scheduler) runJob(cfg *config.Config, j *job) {
-	ctx := context.Background()
+func (s *Scheduler) runJob(j *job) {
+	defer s.wg.Done()
+	ctx := s.ctx
 
-	s.syncAndReport(ctx, cfg, j)
+	if j.splay > 0 {
+		s.setNextSync(j, time.Now().Add(j.splay))
+		if !s.wait(j, j.splay) {
+			return
+		}
+	}
 
+	delay := s.jitterDelay(j)
+	s.setNextSync(j, time.Now().Add(delay+j.secret.RefreshInterval))
+	s.syncAndReport(ctx, j, j.reason)
+	if !s.waitJitter(j, delay) {
+		return
+	}
+
+	// Leases of dynamic secrets are renewed on time however long the
+	// refresh interval is
+	renew := s.leaseRenewal(j)
+	retry := s.retryTimer(j)
 	for {
 		select {
-		case <-j.ticker.C:
-			s.syncAndReport(ctx, cfg, j)
+		case tick := <-j.ticker.C:
+			interval := s.refreshInterval(j.secret)
+			j.ticker.Reset(interval)
+			s.setNextSync(j, tick.Add(interval))
+			s.syncAndReport(ctx, j, ReasonSchedule)
+		case reason := <-j.syncNow:
+			s.syncAndReport(ctx, j, reason)
+		case <-renew:
+			s.syncAndReport(ctx, j, ReasonLease)
+		case <-retry:
+			s.syncAndReport(ctx, j, ReasonRetry)
 		case <-j.stopCh:
 			return
 		case <-s.stopCh:
 			return
 		}
+		renew = s.leaseRenewal(j)
+		retry = s.retryTimer(j)
+	}
+}
+
+// leaseRenewal returns a channel that fires when the lease of a dynamic
+// secret is due for renewal, or nil if no renewal is ahead. A lease overdue
+// after a failed sync is retried on the next tick.
+func (s *Scheduler) leaseRenewal(j *job) <-chan time.Time {
+	at, ok := s.syncer.NextLeaseRenewal(j.secret.Name)
+	if !ok || !at.After(time.Now()) {
+		return nil
 	}
+	return time.After(time.Until(at))
 }
 
-func (s *Scheduler) syncAndReport(ctx context.Context, cfg *config.Config, j *job) {
+func (s *Scheduler) syncAndReport(ctx context.Context, j *job, reason string) {
+	// Files are left as they are while paused; resume syncs every secret.
+	// Callers waiting for the sync learn that it was skipped.
+	if s.sealed.Load() {
+		for _, w := range s.takeWaiters(j) {
+			w <- SyncResult{SecretName: j.secret.Name, Error: ErrPaused}
+		}
+		return
+	}
+	cfg := s.jobConfig(j)
+	ctx = WithReason(ctx, reason)
+
+	// The span includes the wait for a free sync slot
+	ctx, span := tracing.StartSpan(ctx, "scheduler.sync")
+	defer span.End()
+	span.SetAttributes(attribute.String("secret", j.secret.Name))
+
+	if !s.acquire(j) {
+		return
+	}
+	waiters := s.takeWaiters(j)
+	j.writing.Lock()
+	start := time.Now()
+	s.setRunning(j, start)
+	s.inFlight.Add(1)
 	err := s.syncer.SyncSecret(ctx, cfg, j.secret)
+	s.inFlight.Add(-1)
+	s.setRunning(j, time.Time{})
+	j.writing.Unlock()
+	s.release()
 
 	result := SyncResult{
 		SecretName: j.secret.Name,
 		Success:    err == nil,
 		Error:      err,
 		Timestamp:  time.Now(),
+		Duration:   time.Since(start),
+	}
+	if err != nil {
+		result.Kind = errkind.Of(err)
+	}
+	result.Files = s.syncer.Files(j.secret)
+	if expires, ok := s.syncer.LeaseExpiry(j.secret.Name); ok {
+		result.ExpiresAt = expires
+	}
+
+	// Stale files count as synced so readiness does not flap while Vault is
+	// unavailable, but are flagged as stale
+	var stale *StaleError
+	if errors.As(err, &stale) {
+		result.Success = true
+		result.Stale = true
+		result.FetchedAt = stale.FetchedAt
+	}
+	var deleted *DeletedError
+	if errors.As(err, &deleted) {
+		result.Deleted = true
+	}
+
+	if errors.Is(err, vault.ErrSealed) {
+		s.pause()
 	}
 
+	s.mu.Lock()
 	if err == nil {
 		j.lastSync = result.Timestamp
 	}
+	result.NextSync = j.nextSync
+	s.recordOutcome(j, &result)
+	s.mu.Unlock()
 
-	select {
-	case s.results <- result:
-	default:
+	for _, w := range waiters {
+		w <- result
 	}
+
+	// Blocks while subscribers are busy; only gives up once syncs are aborted
+	_ = s.state.Publish(ctx, result)
 }
 
 // GetLastSyncTime returns the last successful sync time for a secret
@@ -133,3 +431,47 @@ func (s *Scheduler) GetLastSyncTime(name string) (time.Time, bool) {
 	}
 	return time.Time{}, false
 }
+
+// jobConfig returns the configuration a job syncs with
+func (s *Scheduler) jobConfig(j *job) *config.Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return j.cfg
+}
+
+// setNextSync records when the refresh ticker of a job fires next
+func (s *Scheduler) setNextSync(j *job, next time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j.nextSync = next
+}
+
+// setRunning records when the current sync of a job started
+func (s *Scheduler) setRunning(j *job, since time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j.runningSince = since
+}
+
+// Jobs returns the scheduled secrets, sorted by name
+func (s *Scheduler) Jobs() []JobInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jobs := make([]JobInfo, 0, len(s.jobs))
+	for name, j := range s.jobs {
+		jobs = append(jobs, JobInfo{
+			Name:            name,
+			RefreshInterval: j.secret.RefreshInterval,
+			LastSync:        j.lastSync,
+			NextSync:        j.nextSync,
+			RunningSince:    j.runningSince,
+			Failures:        j.failures,
+			RetryAt:         j.retryAt,
+		})
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].Name < jobs[j].Name
+	})
+	return jobs
+}
@@ -0,0 +1,113 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+// DefaultSealPollInterval is how often seal status is checked while paused
+const DefaultSealPollInterval = 10 * time.Second
+
+// SealStatus reports whether Vault is sealed, asking any pooled client. All
+// credential sets talk to the same Vault, so one answer is enough.
+func (s *SecretSyncer) SealStatus(ctx context.Context) (bool, error) {
+	s.clientMu.Lock()
+	names := make([]string, 0, len(s.clientPool))
+	for name := range s.clientPool {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var client *vault.Client
+	if len(names) > 0 {
+		client = s.clientPool[names[0]]
+	}
+	s.clientMu.Unlock()
+
+	if client == nil {
+		return false, errors.New("no vault client available")
+	}
+	return client.SealStatus(ctx)
+}
+
+type sealConfig struct {
+	interval time.Duration
+	onChange func(sealed bool)
+}
+
+// WithSealPolling sets how often seal status is polled while syncing is
+// paused for a sealed Vault, and a callback for pause and resume
+func (s *Scheduler) WithSealPolling(interval time.Duration, onChange func(sealed bool)) *Scheduler {
+	s.seal = sealConfig{interval: interval, onChange: onChange}
+	return s
+}
+
+// Paused reports whether syncing is paused because Vault is sealed
+func (s *Scheduler) Paused() bool {
+	return s.sealed.Load()
+}
+
+// pause stops syncing until Vault is unsealed. Only the first caller starts
+// polling; syncs still in flight may report sealed as well.
+func (s *Scheduler) pause() {
+	if !s.sealed.CompareAndSwap(false, true) {
+		return
+	}
+	if s.seal.onChange != nil {
+		s.seal.onChange(true)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.stopped {
+		return
+	}
+	s.wg.Add(1)
+	go s.pollSeal()
+}
+
+// pollSeal checks seal status until Vault is unsealed, then resumes all jobs
+func (s *Scheduler) pollSeal() {
+	defer s.wg.Done()
+
+	interval := s.seal.interval
+	if interval <= 0 {
+		interval = DefaultSealPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// Errors mean Vault is unreachable; keep paused until it answers
+			if sealed, err := s.syncer.SealStatus(s.ctx); err != nil || sealed {
+				continue
+			}
+			s.resume()
+			return
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// resume lifts the pause and syncs every secret right away
+func (s *Scheduler) resume() {
+	s.sealed.Store(false)
+	if s.seal.onChange != nil {
+		s.seal.onChange(false)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, j := range s.jobs {
+		select {
+		case j.syncNow <- ReasonUnseal:
+		default:
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package syncer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+func TestScheduler_PausesWhileSealed(t *testing.T) {
+	var sealed atomic.Bool
+	var reads atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/sys/seal-status" {
+			w.WriteHeader(http.StatusOK)
+			if sealed.Load() {
+				_, _ = w.Write([]byte(`{"sealed": true}`))
+			} else {
+				_, _ = w.Write([]byte(`{"sealed": false}`))
+			}
+			return
+		}
+
+		reads.Add(1)
+		if sealed.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"errors": ["Vault is sealed"]}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"data": {"data": {"key": "value"}}}`))
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	client.GetAPIClient().SetMaxRetries(0)
+
+	changes := make(chan bool, 2)
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0})
+	scheduler := NewScheduler(syncer).WithSealPolling(20*time.Millisecond, func(sealed bool) {
+		changes <- sealed
+	})
+	defer scheduler.Stop()
+
+	sub := scheduler.State().Subscribe(10)
+	defer sub.Close()
+
+	secret := deletableSecret(filepath.Join(t.TempDir(), "key"))
+	secret.RefreshInterval = 10 * time.Millisecond
+	sealed.Store(true)
+	scheduler.AddSecret(createTestConfig(), secret)
+
+	select {
+	case paused := <-changes:
+		if !paused || !scheduler.Paused() {
+			t.Fatal("expected scheduler to pause")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for pause")
+	}
+
+	// No reads while paused, despite the short refresh interval
+	time.Sleep(100 * time.Millisecond)
+	if n := reads.Load(); n != 1 {
+		t.Errorf("expected 1 read before pausing, got %d", n)
+	}
+
+	sealed.Store(false)
+	select {
+	case paused := <-changes:
+		if paused || scheduler.Paused() {
+			t.Fatal("expected scheduler to resume")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for resume")
+	}
+
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case result := <-sub.C():
+			if result.Success && result.Error == nil {
+				return
+			}
+		case <-deadline:
+			t.Fatal("timed out waiting for a successful sync after resume")
+		}
+	}
+}
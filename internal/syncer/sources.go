@@ -0,0 +1,24 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+// fetchSources reads every source of a secret and returns the data of each
+// below its name, e.g. {{ .db.password }}. One source failing fails the
+// whole read, so files never combine old and new data of different sources.
+func (s *SecretSyncer) fetchSources(ctx context.Context, cfg *config.Config, secret config.Secret) (vault.SecretData, error) {
+	data := make(vault.SecretData, len(secret.Sources))
+	for _, source := range secret.Sources {
+		sourceData, _, err := s.fetchData(ctx, cfg, secret.ForSource(source))
+		if err != nil {
+			return nil, fmt.Errorf("source %s: %w", source.Name, err)
+		}
+		data[source.Name] = map[string]interface{}(sourceData)
+	}
+	return data, nil
+}
@@ -0,0 +1,70 @@
+package syncer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+func TestSyncSecret_Sources(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/secret/data/app/db":
+			_, _ = w.Write([]byte(`{"data": {"data": {"user": "app", "password": "s3cret"}}}`))
+		case "/v1/kv/app/pgbouncer":
+			_, _ = w.Write([]byte(`{"data": {"port": "6432"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors": []}`))
+		}
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	client.GetAPIClient().SetMaxRetries(0)
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0})
+
+	path := filepath.Join(t.TempDir(), "pgbouncer.ini")
+	secret := config.Secret{
+		Name: "pgbouncer",
+		Sources: []config.Source{
+			{Name: "db", Key: "app/db", MountPath: "secret", KVVersion: "v2"},
+			{Name: "pool", Key: "app/pgbouncer", MountPath: "kv", KVVersion: "v1"},
+		},
+		Template: config.Template{Data: map[string]string{
+			"ini": "listen_port = {{ .pool.port }}\nauth_user = {{ .db.user }}:{{ .db.password }}\n",
+		}},
+		Files: []config.File{{Path: path, Template: "ini", Mode: "0600"}},
+	}
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("failed to sync secret: %v", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if want := "listen_port = 6432\nauth_user = app:s3cret\n"; string(got) != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	// A failing source fails the sync and keeps the file
+	secret.Sources = append(secret.Sources, config.Source{Name: "missing", Key: "app/missing", MountPath: "secret", KVVersion: "v2"})
+	err = syncer.SyncSecret(context.Background(), createTestConfig(), secret)
+	if err == nil || !strings.Contains(err.Error(), "source missing") {
+		t.Fatalf("expected the failing source in the error, got %v", err)
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(got) {
+		t.Errorf("expected the file to be kept, got %q", after)
+	}
+}
@@ -0,0 +1,146 @@
+package syncer
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// historySize is the number of recent results kept for diagnostics
+const historySize = 100
+
+// StateStore keeps the latest sync result of every secret and delivers each
+// result to subscribers. Delivery applies backpressure instead of dropping:
+// a sync does not finish until every subscriber has received its result.
+type StateStore struct {
+	mu          sync.RWMutex
+	latest      map[string]SyncResult
+	history     []SyncResult // Ring buffer of the most recent results
+	next        int          // Position in history the next result is written to
+	subscribers map[*Subscription]struct{}
+}
+
+// Subscription receives every sync result published after it was created
+type Subscription struct {
+	ch    chan SyncResult
+	done  chan struct{}
+	once  sync.Once
+	store *StateStore
+}
+
+// NewStateStore creates an empty state store
+func NewStateStore() *StateStore {
+	return &StateStore{
+		latest:      make(map[string]SyncResult),
+		history:     make([]SyncResult, 0, historySize),
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Get returns the latest result of a secret
+func (s *StateStore) Get(name string) (SyncResult, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result, ok := s.latest[name]
+	return result, ok
+}
+
+// All returns the latest result of every secret, sorted by name
+func (s *StateStore) All() []SyncResult {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := make([]SyncResult, 0, len(s.latest))
+	for _, result := range s.latest {
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].SecretName < results[j].SecretName
+	})
+	return results
+}
+
+// Recent returns the most recent results of all secrets, oldest first
+func (s *StateStore) Recent() []SyncResult {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.history) < historySize {
+		return append([]SyncResult(nil), s.history...)
+	}
+	recent := make([]SyncResult, 0, historySize)
+	recent = append(recent, s.history[s.next:]...)
+	return append(recent, s.history[:s.next]...)
+}
+
+// Remove forgets the state of a secret that is no longer configured
+func (s *StateStore) Remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.latest, name)
+}
+
+// Subscribe registers a subscriber whose channel holds up to buffer results
+// before publishing blocks
+func (s *StateStore) Subscribe(buffer int) *Subscription {
+	sub := &Subscription{
+		ch:    make(chan SyncResult, buffer),
+		done:  make(chan struct{}),
+		store: s,
+	}
+
+	s.mu.Lock()
+	s.subscribers[sub] = struct{}{}
+	s.mu.Unlock()
+
+	return sub
+}
+
+// Publish records a result and delivers it to all subscribers. It blocks
+// while a subscriber's buffer is full and returns ctx.Err() if ctx is done
+// before every subscriber received the result.
+func (s *StateStore) Publish(ctx context.Context, result SyncResult) error {
+	s.mu.Lock()
+	s.latest[result.SecretName] = result
+	if len(s.history) < historySize {
+		s.history = append(s.history, result)
+	} else {
+		s.history[s.next] = result
+	}
+	s.next = (s.next + 1) % historySize
+	subscribers := make([]*Subscription, 0, len(s.subscribers))
+	for sub := range s.subscribers {
+		subscribers = append(subscribers, sub)
+	}
+	s.mu.Unlock()
+
+	for _, sub := range subscribers {
+		select {
+		case sub.ch <- result:
+		case <-sub.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// C returns the channel results are delivered on; it is never closed
+func (sub *Subscription) C() <-chan SyncResult {
+	return sub.ch
+}
+
+// Done is closed when the subscription is closed
+func (sub *Subscription) Done() <-chan struct{} {
+	return sub.done
+}
+
+// Close unsubscribes, releasing any publisher blocked on this subscriber
+func (sub *Subscription) Close() {
+	sub.once.Do(func() {
+		sub.store.mu.Lock()
+		delete(sub.store.subscribers, sub)
+		sub.store.mu.Unlock()
+		close(sub.done)
+	})
+}
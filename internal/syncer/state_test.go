@@ -0,0 +1,120 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestStateStore_Latest(t *testing.T) {
+	store := NewStateStore()
+	ctx := context.Background()
+
+	_ = store.Publish(ctx, SyncResult{SecretName: "b", Success: false})
+	_ = store.Publish(ctx, SyncResult{SecretName: "a", Success: true})
+	_ = store.Publish(ctx, SyncResult{SecretName: "b", Success: true})
+
+	result, ok := store.Get("b")
+	if !ok || !result.Success {
+		t.Errorf("expected latest result of b to be successful, got %+v", result)
+	}
+
+	all := store.All()
+	if len(all) != 2 || all[0].SecretName != "a" || all[1].SecretName != "b" {
+		t.Errorf("expected results for a and b sorted by name, got %+v", all)
+	}
+
+	store.Remove("a")
+	if _, ok := store.Get("a"); ok {
+		t.Error("expected a to be removed")
+	}
+}
+
+func TestStateStore_DeliversEveryResult(t *testing.T) {
+	store := NewStateStore()
+	sub := store.Subscribe(0)
+	defer sub.Close()
+
+	const count = 250 // More than the old 100-slot channel held
+	go func() {
+		for i := 0; i < count; i++ {
+			_ = store.Publish(context.Background(), SyncResult{SecretName: fmt.Sprintf("s%d", i)})
+		}
+	}()
+
+	for i := 0; i < count; i++ {
+		select {
+		case result := <-sub.C():
+			if want := fmt.Sprintf("s%d", i); result.SecretName != want {
+				t.Fatalf("expected %s, got %s", want, result.SecretName)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout after %d results", i)
+		}
+	}
+}
+
+func TestStateStore_CloseReleasesPublisher(t *testing.T) {
+	store := NewStateStore()
+	sub := store.Subscribe(0)
+
+	published := make(chan error, 1)
+	go func() {
+		published <- store.Publish(context.Background(), SyncResult{SecretName: "a"})
+	}()
+
+	select {
+	case <-published:
+		t.Fatal("expected publish to block on an unread subscriber")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	sub.Close()
+	sub.Close()
+
+	select {
+	case err := <-published:
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected close to release the publisher")
+	}
+
+	if _, ok := store.Get("a"); !ok {
+		t.Error("expected result to be stored regardless of delivery")
+	}
+}
+
+func TestStateStore_PublishCancelled(t *testing.T) {
+	store := NewStateStore()
+	sub := store.Subscribe(0)
+	defer sub.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := store.Publish(ctx, SyncResult{SecretName: "a"}); err == nil {
+		t.Error("expected error when cancelled while blocked")
+	}
+}
+
+func TestStateStore_Recent(t *testing.T) {
+	store := NewStateStore()
+
+	for i := 0; i < historySize+5; i++ {
+		_ = store.Publish(context.Background(), SyncResult{SecretName: fmt.Sprintf("s%d", i)})
+	}
+
+	recent := store.Recent()
+	if len(recent) != historySize {
+		t.Fatalf("expected %d results, got %d", historySize, len(recent))
+	}
+	if recent[0].SecretName != "s5" {
+		t.Errorf("expected oldest kept result s5, got %s", recent[0].SecretName)
+	}
+	if last := recent[len(recent)-1].SecretName; last != fmt.Sprintf("s%d", historySize+4) {
+		t.Errorf("expected newest result last, got %s", last)
+	}
+}
@@ -1,47 +1,208 @@
 package syncer
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"os"
 	"sort"
+	"sync"
 	"time"
 
+	"github.com/ohauer/secrets-sync/internal/azure"
+	"github.com/ohauer/secrets-sync/internal/cache"
 	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/errkind"
 	"github.com/ohauer/secrets-sync/internal/filewriter"
+	"github.com/ohauer/secrets-sync/internal/memlock"
+	"github.com/ohauer/secrets-sync/internal/state"
 	"github.com/ohauer/secrets-sync/internal/template"
+	"github.com/ohauer/secrets-sync/internal/tracing"
 	"github.com/ohauer/secrets-sync/internal/vault"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
 // ClientFactory creates Vault clients with specific credentials
-type ClientFactory func(creds config.CredentialSet) (*vault.Client, error)
+type ClientFactory func(ctx context.Context, creds config.CredentialSet) (*vault.Client, error)
 
 // SecretSyncer handles secret synchronization
 type SecretSyncer struct {
-	clientFactory ClientFactory
-	clientPool    map[string]*vault.Client // Cache clients by credential set name
-	writer        *filewriter.Writer
-	retryConfig   vault.RetryConfig
+	clientFactory  ClientFactory
+	clientPool     map[string]*vault.Client // Cache clients by credential set name
+	azureFactory   AzureClientFactory
+	azurePool      map[string]*azure.Client // Cache Azure Key Vault clients by credential set name
+	clientMu       sync.Mutex
+	writer         *filewriter.Writer
+	retryConfig    vault.RetryConfig
+	manifest       *state.Manifest // Optional record of managed files
+	stateFile      *state.Store    // Optional record of what was synced, kept across restarts
+	cache          *cache.Cache    // Optional encrypted copy of fetched data
+	memCache       *memoryCache    // Optional in-memory copy of fetched data
+	maxStaleness   time.Duration   // How long stale data is tolerated, 0 for no limit
+	syncTimeout    time.Duration   // Deadline for syncing a secret without its own, 0 for none
+	fetchedMu      sync.Mutex
+	fetchedAt      map[string]time.Time // When the data on disk was fetched, by secret name
+	versionMu      sync.Mutex
+	versions       map[string]syncedVersion // KV v2 version on disk, by secret name
+	leaseMu        sync.Mutex
+	leases         *vault.LeaseManager       // Leases of the credentials on disk, by dynamic secret name
+	leased         map[string]string         // Fingerprint of the configuration leased credentials were written for
+	resumable      map[string]resumableLease // Leases recorded before a restart, by dynamic secret name
+	writeObserver  func(time.Duration)       // Optional callback timing every file write
+	fileObserver   func(string, bool)        // Optional callback told whether each rendered file was written
+	dataObserver   DataObserver              // Optional callback given the data of every secret written
+	phaseObserver  PhaseObserver             // Optional callback timing the phases of every sync
+	auditor        func(AuditEvent)          // Optional callback told about every file written
+	hashObserver   func(string, string)      // Optional callback told the content hash of every rendered file
+	deletionPolicy DeletionPolicy            // What happens to files of secrets deleted in Vault
+	quarantineDir  string                    // Where quarantined files are moved
+	guard          *FileGuard                // Optional watcher restoring deleted files
+	wildcardMu     sync.Mutex
+	wildcardFiles  map[string]map[string][]string // Files written per matched key, by wildcard secret name
+	hashMu         sync.Mutex
+	hashes         map[string]string    // Hash of the content last rendered, by path
+	certExpiries   map[string]time.Time // Certificate expiry of the content last rendered, by path
 }
 
 // NewSecretSyncer creates a new secret syncer with a client factory
 func NewSecretSyncer(factory ClientFactory, retryConfig vault.RetryConfig) *SecretSyncer {
 	return &SecretSyncer{
-		clientFactory: factory,
-		clientPool:    make(map[string]*vault.Client),
-		writer:        filewriter.NewWriter(),
-		retryConfig:   retryConfig,
+		clientFactory:  factory,
+		clientPool:     make(map[string]*vault.Client),
+		azurePool:      make(map[string]*azure.Client),
+		writer:         filewriter.NewWriter(),
+		retryConfig:    retryConfig,
+		fetchedAt:      make(map[string]time.Time),
+		versions:       make(map[string]syncedVersion),
+		leases:         vault.NewLeaseManager(),
+		leased:         make(map[string]string),
+		deletionPolicy: DeletionKeep,
+		wildcardFiles:  make(map[string]map[string][]string),
+		hashes:         make(map[string]string),
+		certExpiries:   make(map[string]time.Time),
 	}
 }
 
+// WithManifest enables recording of every written file in the given manifest
+func (s *SecretSyncer) WithManifest(m *state.Manifest) *SecretSyncer {
+	s.manifest = m
+	return s
+}
+
+// WithCache enables persisting fetched data and falling back to it when
+// Vault is unavailable
+func (s *SecretSyncer) WithCache(c *cache.Cache) *SecretSyncer {
+	s.cache = c
+	return s
+}
+
+// WithMaxStaleness limits how long a secret may be served from stale data
+// before a failed fetch is reported as a hard failure; 0 means no limit
+func (s *SecretSyncer) WithMaxStaleness(d time.Duration) *SecretSyncer {
+	s.maxStaleness = d
+	return s
+}
+
+// WithSyncTimeout bounds how long syncing a secret may take, including
+// retries, for secrets without their own syncTimeout; 0 means no limit
+func (s *SecretSyncer) WithSyncTimeout(d time.Duration) *SecretSyncer {
+	s.syncTimeout = d
+	return s
+}
+
+// WithFsync sets whether files are flushed to disk when written (default:
+// true); see filewriter.Writer.WithSync
+func (s *SecretSyncer) WithFsync(enabled bool) *SecretSyncer {
+	s.writer.WithSync(enabled)
+	return s
+}
+
+// WithAllowedPaths restricts writes to files below one of dirs; none allows
+// every path. It may be called again on reload, see
+// filewriter.Writer.WithAllowedPaths.
+func (s *SecretSyncer) WithAllowedPaths(dirs []string) *SecretSyncer {
+	s.writer.WithAllowedPaths(dirs)
+	return s
+}
+
+// WithWriteObserver registers a callback that receives the duration of every file write
+func (s *SecretSyncer) WithWriteObserver(fn func(time.Duration)) *SecretSyncer {
+	s.writeObserver = fn
+	return s
+}
+
+// WithFileObserver registers a callback that receives the secret name of
+// every rendered file and whether it was written, or skipped as unchanged
+func (s *SecretSyncer) WithFileObserver(fn func(secret string, written bool)) *SecretSyncer {
+	s.fileObserver = fn
+	return s
+}
+
+// DataObserver receives the data of a secret whose files were written. The
+// data is cleared once it returns and must not be kept.
+type DataObserver func(secret string, data vault.SecretData)
+
+// WithDataObserver registers a callback that receives the data of every
+// secret whose files were written, e.g. to pass it on without fetching again
+func (s *SecretSyncer) WithDataObserver(fn DataObserver) *SecretSyncer {
+	s.dataObserver = fn
+	return s
+}
+
+// WithHashObserver registers a callback that receives the path and SHA-256
+// hash of every rendered file, e.g. to redact its content from logs
+func (s *SecretSyncer) WithHashObserver(fn func(path, hash string)) *SecretSyncer {
+	s.hashObserver = fn
+	return s
+}
+
+// Phases of a sync timed by a PhaseObserver
+const (
+	PhaseFetch  = "fetch"  // Reading the secret from Vault or Azure Key Vault
+	PhaseRender = "render" // Rendering the templates of its files
+	PhaseWrite  = "write"  // Writing its files
+)
+
+// PhaseObserver receives how long a phase of syncing a secret took; ctx
+// carries the span of the sync, if tracing is enabled
+type PhaseObserver func(ctx context.Context, secret, phase string, d time.Duration)
+
+// WithPhaseObserver registers a callback that receives the duration of the
+// fetch, render and write phase of every sync, failed ones included
+func (s *SecretSyncer) WithPhaseObserver(fn PhaseObserver) *SecretSyncer {
+	s.phaseObserver = fn
+	return s
+}
+
+// observePhase passes the time since start to the phase observer, if any
+func (s *SecretSyncer) observePhase(ctx context.Context, secret, phase string, start time.Time) {
+	if s.phaseObserver != nil {
+		s.phaseObserver(ctx, secret, phase, time.Since(start))
+	}
+}
+
+// Manifest returns the manifest used to record written files, if any
+func (s *SecretSyncer) Manifest() *state.Manifest {
+	return s.manifest
+}
+
 // getOrCreateClient returns a cached client or creates a new one
-func (s *SecretSyncer) getOrCreateClient(credName string, creds config.CredentialSet) (*vault.Client, error) {
+func (s *SecretSyncer) getOrCreateClient(ctx context.Context, credName string, creds config.CredentialSet) (*vault.Client, error) {
+	s.clientMu.Lock()
+	defer s.clientMu.Unlock()
+
 	// Check cache
 	if client, ok := s.clientPool[credName]; ok {
 		return client, nil
 	}
 
 	// Create new client
-	client, err := s.clientFactory(creds)
+	ctx, span := tracing.StartSpan(ctx, "vault.authenticate")
+	defer span.End()
+	client, err := s.clientFactory(ctx, creds)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create client for credentials %q: %w", credName, err)
 	}
@@ -51,93 +212,684 @@ func (s *SecretSyncer) getOrCreateClient(credName string, creds config.Credentia
 	return client, nil
 }
 
-// SyncSecret synchronizes a single secret
+// dropExpiredClient removes a pooled client from the pool if Vault no longer
+// accepts its token, so the next request logs in again, and reports whether
+// it did. A token that is still valid was denied by policy, and logging in
+// again would not help.
+func (s *SecretSyncer) dropExpiredClient(ctx context.Context, credName string, client *vault.Client) bool {
+	if valid, err := client.TokenValid(ctx); err != nil || valid {
+		return false
+	}
+
+	s.clientMu.Lock()
+	defer s.clientMu.Unlock()
+	if s.clientPool[credName] == client {
+		delete(s.clientPool, credName)
+	}
+	return true
+}
+
+// ClientInfo describes a pooled Vault client
+type ClientInfo struct {
+	Credentials  string
+	BreakerState string
+}
+
+// Clients returns the pooled Vault clients, sorted by credential set name
+func (s *SecretSyncer) Clients() []ClientInfo {
+	s.clientMu.Lock()
+	defer s.clientMu.Unlock()
+
+	clients := make([]ClientInfo, 0, len(s.clientPool))
+	for name, client := range s.clientPool {
+		clients = append(clients, ClientInfo{Credentials: name, BreakerState: client.BreakerState()})
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].Credentials < clients[j].Credentials
+	})
+	return clients
+}
+
+// renderedFile pairs an output file with its rendered content
+type renderedFile struct {
+	secret     string
+	source     string // Where the content was read from, for the audit log
+	config     filewriter.FileConfig
+	mode       string
+	content    []byte
+	certExpiry time.Time // Earliest expiry of the certificates in the file, with certExpiry set
+	checksum   bool      // A <path>.sha256 companion is written, see appendChecksums
+}
+
+// wipeFiles zeroes the rendered content of all files
+func wipeFiles(files []renderedFile) {
+	for _, f := range files {
+		memlock.Zero(f.content)
+	}
+}
+
+// SyncSecret synchronizes a single secret. When Vault cannot be read, files
+// are restored from the cache or the last written files are kept in place,
+// and a *StaleError is returned. When the secret was deleted in Vault and a
+// deletion policy other than keep is set, its files are removed or
+// quarantined and a *DeletedError is returned. A wildcard key syncs every
+// secret it matches.
 func (s *SecretSyncer) SyncSecret(ctx context.Context, cfg *config.Config, secret config.Secret) error {
+	ctx, span := tracing.StartSpan(ctx, "sync_secret")
+	defer span.End()
+	span.SetAttributes(attribute.String("secret", secret.Name))
+
+	timeout := s.syncTimeout
+	if secret.SyncTimeout > 0 {
+		timeout = secret.SyncTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var err error
+	if secret.IsWildcard() {
+		err = s.syncWildcard(ctx, cfg, secret)
+	} else {
+		_, err = s.syncSecret(ctx, cfg, secret)
+	}
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("sync timed out after %s: %w", timeout, err)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// syncSecret synchronizes a secret with a single key and returns the paths
+// of the files it wrote
+func (s *SecretSyncer) syncSecret(ctx context.Context, cfg *config.Config, secret config.Secret) ([]string, error) {
+	if s.upToDate(ctx, cfg, secret) {
+		return s.skipUnchanged(secret), s.saveState(cfg, secret)
+	}
+	if secret.IsDynamic() && s.keepLease(ctx, cfg, secret) {
+		return s.skipUnchanged(secret), s.saveState(cfg, secret)
+	}
+
+	var stale *StaleError
+	var cacheErr error
+
+	fetchedAt := time.Now()
+	var data vault.SecretData
+	var version int
+	var lease vault.Lease
+	var err error
+	if secret.IsDynamic() {
+		data, lease, err = s.fetchDynamic(ctx, cfg, secret)
+	} else {
+		data, version, err = s.fetchData(ctx, cfg, secret)
+	}
+	s.observePhase(ctx, secret.Name, PhaseFetch, fetchedAt)
+	if err != nil {
+		if errors.Is(err, vault.ErrSecretDeleted) && s.deletionPolicy != DeletionKeep {
+			return nil, s.handleDeleted(secret, err)
+		}
+		// Cached credentials of a dynamic secret have most likely expired
+		if s.memCache != nil && !secret.IsDynamic() {
+			if cached, cachedAt, ok := s.memCache.get(secret.Name); ok {
+				data = cached
+				fetchedAt = cachedAt
+				stale = &StaleError{Err: err, FetchedAt: cachedAt, FromCache: true}
+			}
+		}
+		if stale == nil && s.cache != nil && !secret.IsDynamic() {
+			cached, cachedAt, getErr := s.cache.Get(secret.Name)
+			switch {
+			case getErr == nil:
+				data = vault.SecretData(cached)
+				fetchedAt = cachedAt
+				stale = &StaleError{Err: err, FetchedAt: cachedAt, FromCache: true}
+			case !errors.Is(getErr, cache.ErrNotFound):
+				err = fmt.Errorf("%w (cache fallback: %v)", err, getErr)
+			}
+		}
+		if stale == nil {
+			return nil, s.retainFiles(secret, err)
+		}
+		if err := s.checkStaleness(stale); err != nil {
+			return nil, err
+		}
+	} else if !secret.IsDynamic() {
+		if s.memCache != nil {
+			s.memCache.put(secret.Name, data, fetchedAt)
+		}
+		// A cache failure must not keep fresh data from being written
+		if s.cache != nil {
+			if err := s.cache.Put(ctx, secret.Name, data, fetchedAt); err != nil {
+				cacheErr = fmt.Errorf("failed to update cache: %w", err)
+			}
+		}
+	}
+
+	// renderData clears the data it was given
+	var observed vault.SecretData
+	if s.dataObserver != nil {
+		observed = maps.Clone(data)
+		defer clear(observed)
+	}
+
+	renderStart := time.Now()
+	files, err := s.renderData(ctx, cfg, secret, data)
+	s.observePhase(ctx, secret.Name, PhaseRender, renderStart)
+	if err != nil {
+		return nil, err
+	}
+	defer wipeFiles(files)
+
+	// Bail out before touching disk if cancelled. Syncs are only cancelled
+	// once a shutdown's drain timeout expires, e.g. on a hung NFS mount; a
+	// multi-file secret may then be left partly updated, but every file is
+	// still replaced atomically.
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("sync cancelled: %w", err)
+	}
+
+	writeStart := time.Now()
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		if err := s.writeFile(ctx, f); err != nil {
+			s.observePhase(ctx, secret.Name, PhaseWrite, writeStart)
+			return paths, err
+		}
+		paths = append(paths, f.config.Path)
+	}
+	s.observePhase(ctx, secret.Name, PhaseWrite, writeStart)
+	s.setFetchedAt(secret.Name, fetchedAt)
+	if stale == nil {
+		s.setSyncedVersion(cfg, secret, version)
+	} else {
+		s.clearSyncedVersion(secret.Name)
+	}
+	if secret.IsDynamic() {
+		s.setLease(ctx, cfg, secret, lease)
+	}
+	if s.dataObserver != nil {
+		s.dataObserver(secret.Name, observed)
+	}
+
+	if s.manifest != nil {
+		if err := s.manifest.Save(); err != nil {
+			return paths, fmt.Errorf("failed to save manifest: %w", err)
+		}
+	}
+	if err := s.saveState(cfg, secret); err != nil {
+		return paths, err
+	}
+
+	if stale != nil {
+		return paths, stale
+	}
+	return paths, cacheErr
+}
+
+// retainFiles keeps the last known good files of a secret when its fetch
+// failed. The fetch error is returned unchanged if any file is missing.
+func (s *SecretSyncer) retainFiles(secret config.Secret, fetchErr error) error {
+	if len(secret.Files) == 0 {
+		return fetchErr
+	}
+
+	// Without an in-memory record (e.g. after a restart) the oldest file
+	// modification time is the best estimate of when the data was fetched
+	fetchedAt, known := s.getFetchedAt(secret.Name)
+	for _, file := range secret.Files {
+		info, err := os.Stat(file.Path)
+		if err != nil {
+			return fetchErr
+		}
+		if !known && (fetchedAt.IsZero() || info.ModTime().Before(fetchedAt)) {
+			fetchedAt = info.ModTime()
+		}
+	}
+
+	stale := &StaleError{Err: fetchErr, FetchedAt: fetchedAt}
+	if err := s.checkStaleness(stale); err != nil {
+		return err
+	}
+	return stale
+}
+
+// checkStaleness turns stale data older than the configured maximum into a hard failure
+func (s *SecretSyncer) checkStaleness(stale *StaleError) error {
+	if s.maxStaleness <= 0 {
+		return nil
+	}
+	if age := time.Since(stale.FetchedAt); age > s.maxStaleness {
+		return fmt.Errorf("data is %s old, exceeding maximum staleness of %s: %w",
+			age.Truncate(time.Second), s.maxStaleness, stale.Err)
+	}
+	return nil
+}
+
+// getFetchedAt returns when the data currently on disk for a secret was fetched
+func (s *SecretSyncer) getFetchedAt(name string) (time.Time, bool) {
+	s.fetchedMu.Lock()
+	defer s.fetchedMu.Unlock()
+	t, ok := s.fetchedAt[name]
+	return t, ok
+}
+
+// setFetchedAt records when the data written for a secret was fetched
+func (s *SecretSyncer) setFetchedAt(name string, t time.Time) {
+	s.fetchedMu.Lock()
+	defer s.fetchedMu.Unlock()
+	s.fetchedAt[name] = t
+}
+
+// clearFetchedAt forgets when the data of a secret was fetched
+func (s *SecretSyncer) clearFetchedAt(name string) {
+	s.fetchedMu.Lock()
+	defer s.fetchedMu.Unlock()
+	delete(s.fetchedAt, name)
+}
+
+// StaleError reports that Vault could not be read and the secret's files hold
+// older data, either restored from the cache or kept from an earlier sync
+type StaleError struct {
+	Err       error
+	FetchedAt time.Time
+	FromCache bool
+}
+
+func (e *StaleError) Error() string {
+	if e.FromCache {
+		return fmt.Sprintf("served from cache fetched at %s: %v", e.FetchedAt.Format(time.RFC3339), e.Err)
+	}
+	return fmt.Sprintf("kept files last fetched at %s: %v", e.FetchedAt.Format(time.RFC3339), e.Err)
+}
+
+func (e *StaleError) Unwrap() error {
+	return e.Err
+}
+
+// writeFile writes a rendered file and records it in the manifest. Files
+// that already hold the rendered content are left untouched.
+func (s *SecretSyncer) writeFile(ctx context.Context, f renderedFile) error {
+	ctx, span := tracing.StartSpan(ctx, "file.write")
+	defer span.End()
+	span.SetAttributes(attribute.String("path", f.config.Path))
+
+	start := time.Now()
+	var written bool
+	var err error
+	if s.guard != nil {
+		written, err = s.guard.write(ctx, f)
+	} else {
+		written, err = s.writer.WriteBytesIfChanged(ctx, f.config, f.content)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write file %s: %w", f.config.Path, err)
+	}
+	span.SetAttributes(attribute.Bool("written", written))
+	if written && s.writeObserver != nil {
+		s.writeObserver(time.Since(start))
+	}
+	if s.fileObserver != nil {
+		s.fileObserver(f.secret, written)
+	}
+	if written {
+		audit(s.auditor, f.secret, f.source, f.config.Path, f.content, reasonOf(ctx))
+	}
+
+	s.recordFile(f)
+	return nil
+}
+
+// recordFile remembers the hash and certificate expiry of a rendered file
+// and stores it in the manifest, if one is configured
+func (s *SecretSyncer) recordFile(f renderedFile) {
+	hash := state.HashContent(f.content)
+	s.setFileHash(f.config.Path, hash)
+	if s.hashObserver != nil {
+		s.hashObserver(f.config.Path, hash)
+	}
+	s.setCertExpiry(f.config.Path, f.certExpiry)
+	if s.manifest == nil {
+		return
+	}
+
+	s.manifest.Set(state.Entry{
+		Secret:    f.secret,
+		Path:      f.config.Path,
+		Hash:      hash,
+		Mode:      f.mode,
+		UpdatedAt: time.Now(),
+	})
+}
+
+// renderSecret fetches a secret and renders the content of each of its
+// files, or of the files of every secret matched by a wildcard key
+func (s *SecretSyncer) renderSecret(ctx context.Context, cfg *config.Config, secret config.Secret) ([]renderedFile, error) {
+	if secret.IsWildcard() {
+		return s.renderWildcard(ctx, cfg, secret)
+	}
+
+	data, _, err := s.fetchData(ctx, cfg, secret)
+	if err != nil {
+		return nil, err
+	}
+	return s.renderData(ctx, cfg, secret, data)
+}
+
+// CheckSecret fetches and renders a secret without writing any file
+func (s *SecretSyncer) CheckSecret(ctx context.Context, cfg *config.Config, secret config.Secret) error {
+	files, err := s.renderSecret(ctx, cfg, secret)
+	if err != nil {
+		return err
+	}
+	wipeFiles(files)
+	return nil
+}
+
+// clientFor returns the client for the credentials of a secret
+func (s *SecretSyncer) clientFor(ctx context.Context, cfg *config.Config, secret config.Secret) (*vault.Client, error) {
 	// Resolve credentials (per-secret overrides default)
 	credName := secret.ResolveCredentials()
 	creds, ok := cfg.SecretStore.GetCredentials(credName)
 	if !ok {
-		return fmt.Errorf("credentials %q not found", credName)
+		return nil, errkind.Wrap(errkind.Auth, fmt.Errorf("credentials %q not found", credName))
 	}
 
 	// Get or create client for these credentials
-	client, err := s.getOrCreateClient(credName, creds)
+	return s.getOrCreateClient(ctx, credName, creds)
+}
+
+// fetchData reads the secret data from Vault, pinned to the secret's version
+// if set, and returns the KV v2 version read. Objects in Azure Key Vault
+// and secrets with sources have no version. Dynamic secrets get new
+// credentials whose lease is not tracked.
+func (s *SecretSyncer) fetchData(ctx context.Context, cfg *config.Config, secret config.Secret) (vault.SecretData, int, error) {
+	if len(secret.Sources) > 0 {
+		data, err := s.fetchSources(ctx, cfg, secret)
+		return data, 0, err
+	}
+	if cfg.SecretStore.IsAzureKeyVault() {
+		data, err := s.fetchAzure(ctx, cfg, secret)
+		return data, 0, err
+	}
+	if secret.IsDynamic() {
+		data, _, err := s.fetchDynamic(ctx, cfg, secret)
+		return data, 0, err
+	}
+
+	client, err := s.clientFor(ctx, cfg, secret)
 	if err != nil {
-		return err
+		return nil, 0, err
+	}
+
+	data, version, err := s.fetchKV(ctx, cfg, secret, client)
+	if errkind.Of(err) == errkind.Permission && s.dropExpiredClient(ctx, secret.ResolveCredentials(), client) {
+		// The token expired or was revoked; log in again and ask once more
+		if client, err = s.clientFor(ctx, cfg, secret); err != nil {
+			return nil, 0, err
+		}
+		data, version, err = s.fetchKV(ctx, cfg, secret, client)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch secret: %w", err)
 	}
 
+	return data, version, nil
+}
+
+// fetchKV reads a KV secret with the given client
+func (s *SecretSyncer) fetchKV(ctx context.Context, cfg *config.Config, secret config.Secret, client *vault.Client) (vault.SecretData, int, error) {
 	// Resolve namespace (per-secret overrides global)
 	namespace := secret.ResolveNamespace(cfg.SecretStore.Namespace)
 
+	ctx, span := tracing.StartSpan(ctx, "vault.fetch")
+	defer span.End()
+	span.SetAttributes(vaultAttributes(secret, namespace)...)
+
+	if secret.KVVersion == "v2" {
+		data, version, err := client.FetchSecretVersionWithRetry(
+			ctx,
+			secret.MountPath,
+			secret.Key,
+			namespace,
+			secret.Version,
+			s.retryConfigFor(cfg, secret),
+		)
+		span.SetAttributes(attribute.Int("version", version))
+		return data, version, err
+	}
+
 	data, err := client.FetchSecretWithRetry(
 		ctx,
 		secret.MountPath,
 		secret.Key,
 		secret.KVVersion,
 		namespace,
-		s.retryConfig,
+		s.retryConfigFor(cfg, secret),
 	)
-	if err != nil {
-		return fmt.Errorf("failed to fetch secret: %w", err)
+	return data, 0, err
+}
+
+// vaultAttributes describes the Vault location of a secret on a span
+func vaultAttributes(secret config.Secret, namespace string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("mount", secret.MountPath),
+		attribute.String("key", secret.Key),
+	}
+	if namespace != "" {
+		attrs = append(attrs, attribute.String("namespace", namespace))
+	}
+	return attrs
+}
+
+// renderData renders the files of a secret from already fetched data
+func (s *SecretSyncer) renderData(ctx context.Context, cfg *config.Config, secret config.Secret, data vault.SecretData) ([]renderedFile, error) {
+	ctx, span := tracing.StartSpan(ctx, "template.render")
+	defer span.End()
+	span.SetAttributes(attribute.Int("files", len(secret.Files)))
+
+	// Vault response values are immutable strings and cannot be wiped; drop
+	// the references as soon as rendering is done so they can be collected
+	defer clear(data)
+
+	if secret.Directory != nil {
+		var err error
+		if secret, err = bindDirectory(secret, data); err != nil {
+			return nil, err
+		}
 	}
 
-	engine := template.NewEngine()
+	engine := template.NewEngine().WithDecrypter(s.transitDecrypter(ctx, cfg, secret))
 	for name, tmpl := range secret.Template.Data {
 		if err := engine.AddTemplate(name, tmpl); err != nil {
-			return fmt.Errorf("failed to add template %s: %w", name, err)
+			return nil, fmt.Errorf("failed to add template %s: %w", name, err)
 		}
 	}
 
-	rendered, err := engine.RenderAll(map[string]interface{}(data))
-	if err != nil {
-		return fmt.Errorf("failed to render templates: %w", err)
+	templated := 0
+	for _, file := range secret.Files {
+		if file.Format == "" {
+			templated++
+		}
 	}
-
-	if len(rendered) != len(secret.Files) {
-		return fmt.Errorf("template count (%d) does not match file count (%d)", len(rendered), len(secret.Files))
+	if secret.UsesImplicitTemplates() && len(secret.Template.Data) != templated {
+		return nil, errkind.Wrap(errkind.Template, fmt.Errorf("template count (%d) does not match file count (%d)", len(secret.Template.Data), templated))
 	}
 
-	// Sort template names for deterministic file mapping
-	templateNames := make([]string, 0, len(secret.Template.Data))
-	for name := range secret.Template.Data {
-		templateNames = append(templateNames, name)
+	templateNames := secret.FileTemplates()
+	for i, name := range templateNames {
+		if _, ok := secret.Template.Data[name]; !ok && secret.Files[i].Format == "" {
+			return nil, errkind.Wrap(errkind.Template, fmt.Errorf("no template for file %s", secret.Files[i].Path))
+		}
 	}
-	sort.Strings(templateNames)
 
-	for i, file := range secret.Files {
-		mode, err := filewriter.ParseMode(file.Mode)
+	fileConfigs := make([]filewriter.FileConfig, 0, len(secret.Files))
+	for _, file := range secret.Files {
+		fileConfig, err := newFileConfig(file)
 		if err != nil {
-			return fmt.Errorf("invalid mode for file %s: %w", file.Path, err)
+			return nil, err
 		}
+		fileConfig.RequireTmpfs = secret.RequireTmpfs
+		fileConfigs = append(fileConfigs, fileConfig)
+	}
 
-		owner, err := filewriter.ParseOwner(file.Owner)
+	files := make([]renderedFile, 0, len(secret.Files))
+	for i, name := range templateNames {
+		var content []byte
+		var err error
+		if format := secret.Files[i].Format; filewriter.IsKeystoreFormat(format) {
+			content, err = renderKeystore(engine, secret.Name, secret.Files[i], data)
+		} else if format == filewriter.FormatConcat {
+			content, err = renderConcat(engine, secret.Files[i], data)
+		} else if format != "" {
+			content, err = filewriter.RenderFormat(format, data, secret.Files[i].Keys)
+		} else {
+			content, err = engine.RenderBytes(name, map[string]interface{}(data))
+		}
 		if err != nil {
-			return fmt.Errorf("invalid owner for file %s: %w", file.Path, err)
+			wipeFiles(files)
+			return nil, fmt.Errorf("failed to render templates: %w", err)
 		}
 
-		group, err := filewriter.ParseOwner(file.Group)
-		if err != nil {
-			return fmt.Errorf("invalid group for file %s: %w", file.Path, err)
+		files = append(files, renderedFile{
+			secret:     secret.Name,
+			source:     secretSource(cfg, secret),
+			config:     fileConfigs[i],
+			mode:       fmt.Sprintf("%04o", fileConfigs[i].Mode.Perm()),
+			content:    content,
+			certExpiry: fileCertExpiry(engine, secret.Files[i], data, content),
+			checksum:   secret.Files[i].Checksum,
+		})
+	}
+
+	return appendChecksums(secret, files)
+}
+
+// renderKeystore renders the templates a keystore file is assembled from and
+// encodes them. The alias defaults to the secret name, and trailing newlines
+// of a block scalar are dropped from the password.
+func renderKeystore(engine *template.Engine, secretName string, file config.File, data vault.SecretData) ([]byte, error) {
+	if file.Keystore == nil {
+		return nil, errkind.Wrap(errkind.Template, fmt.Errorf("no keystore for file %s", file.Path))
+	}
+
+	ks := filewriter.Keystore{Alias: file.Keystore.Alias}
+	if ks.Alias == "" {
+		ks.Alias = secretName
+	}
+	defer func() {
+		for _, b := range [][]byte{ks.Certificate, ks.PrivateKey, ks.Chain, ks.Password} {
+			memlock.Zero(b)
 		}
+	}()
 
-		var content string
-		if i < len(templateNames) {
-			content = rendered[templateNames[i]]
+	parts := []struct {
+		name string
+		dst  *[]byte
+	}{
+		{file.Keystore.Certificate, &ks.Certificate},
+		{file.Keystore.PrivateKey, &ks.PrivateKey},
+		{file.Keystore.Chain, &ks.Chain},
+		{file.Keystore.Password, &ks.Password},
+	}
+	for _, part := range parts {
+		if part.name == "" {
+			continue
 		}
+		rendered, err := engine.RenderBytes(part.name, map[string]interface{}(data))
+		if err != nil {
+			return nil, err
+		}
+		*part.dst = rendered
+	}
+	ks.Password = bytes.TrimRight(ks.Password, "\r\n")
+
+	return filewriter.RenderKeystore(file.Format, ks)
+}
+
+// renderConcat renders the templates a concat file is joined from, in order
+func renderConcat(engine *template.Engine, file config.File, data vault.SecretData) ([]byte, error) {
+	if file.Concat == nil {
+		return nil, errkind.Wrap(errkind.Template, fmt.Errorf("no concat for file %s", file.Path))
+	}
 
-		fileConfig := filewriter.FileConfig{
-			Path:  file.Path,
-			Mode:  mode,
-			Owner: owner,
-			Group: group,
+	parts := make([][]byte, 0, len(file.Concat.Templates))
+	defer func() {
+		for _, part := range parts {
+			memlock.Zero(part)
 		}
+	}()
+	for _, name := range file.Concat.Templates {
+		rendered, err := engine.RenderBytes(name, map[string]interface{}(data))
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, rendered)
+	}
+
+	return filewriter.Concat(parts, file.Concat.Separator), nil
+}
+
+// newFileConfig converts a configured output file into writer settings
+func newFileConfig(file config.File) (filewriter.FileConfig, error) {
+	mode, err := filewriter.ParseMode(file.Mode)
+	if err != nil {
+		return filewriter.FileConfig{}, fmt.Errorf("invalid mode for file %s: %w", file.Path, err)
+	}
+
+	owner, err := filewriter.ParseOwner(file.Owner)
+	if err != nil {
+		return filewriter.FileConfig{}, fmt.Errorf("invalid owner for file %s: %w", file.Path, err)
+	}
 
-		if err := s.writer.WriteFile(fileConfig, content); err != nil {
-			return fmt.Errorf("failed to write file %s: %w", file.Path, err)
+	group, err := filewriter.ParseGroup(file.Group)
+	if err != nil {
+		return filewriter.FileConfig{}, fmt.Errorf("invalid group for file %s: %w", file.Path, err)
+	}
+
+	dir, err := newDirConfig(file)
+	if err != nil {
+		return filewriter.FileConfig{}, err
+	}
+
+	return filewriter.FileConfig{
+		Path:           file.Path,
+		Mode:           mode,
+		Owner:          owner,
+		Group:          group,
+		Dir:            dir,
+		SELinuxContext: file.SELinuxContext,
+		KeepBackups:    file.KeepBackups,
+	}, nil
+}
+
+// newDirConfig converts the directory settings of a file, nil if it has none
+func newDirConfig(file config.File) (*filewriter.DirConfig, error) {
+	if file.DirMode == "" && file.DirOwner == "" && file.DirGroup == "" {
+		return nil, nil
+	}
+
+	dir := &filewriter.DirConfig{}
+	if file.DirMode != "" {
+		mode, err := filewriter.ParseDirMode(file.DirMode)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dirMode for file %s: %w", file.Path, err)
 		}
+		dir.Mode = mode
 	}
 
-	return nil
+	var err error
+	if dir.Owner, err = filewriter.ParseOwner(file.DirOwner); err != nil {
+		return nil, fmt.Errorf("invalid dirOwner for file %s: %w", file.Path, err)
+	}
+	if dir.Group, err = filewriter.ParseGroup(file.DirGroup); err != nil {
+		return nil, fmt.Errorf("invalid dirGroup for file %s: %w", file.Path, err)
+	}
+	return dir, nil
 }
 
 // SyncResult holds the result of a sync operation
@@ -146,4 +898,16 @@ type SyncResult struct {
 	Success    bool
 	Error      error
 	Timestamp  time.Time
+	Duration   time.Duration // Time spent in SyncSecret
+	Kind       errkind.Kind  // Cause of Error, errkind.Unknown if unclassified
+	Deleted    bool          // The secret no longer exists in Vault
+	Stale      bool          // Vault was unavailable and the files hold older data
+	FetchedAt  time.Time     // When the stale data was fetched, set if Stale
+	NextSync   time.Time     // When the refresh interval triggers the next sync
+	Files      []FileStatus  // Files of the secret after the sync
+	Failures   int           // Failed syncs in a row, including this one
+	RetryAt    time.Time     // When this failed sync is retried, zero if not
+	Alerting   bool          // Failures reached the alert threshold of the secret
+	Recovered  bool          // This sync succeeded after the secret was alerting
+	ExpiresAt  time.Time     // When the leased credentials in the files expire, for dynamic secrets
 }
@@ -1,26 +1,67 @@
 package syncer
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/ohauer/secrets-sync/internal/cache"
 	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/errkind"
+	"github.com/ohauer/secrets-sync/internal/filewriter"
+	"github.com/ohauer/secrets-sync/internal/state"
 	"github.com/ohauer/secrets-sync/internal/vault"
+	"software.sslmate.com/src/go-pkcs12"
 )
 
 // createTestFactory creates a client factory for testing
 func createTestFactory(client *vault.Client) ClientFactory {
-	return func(creds config.CredentialSet) (*vault.Client, error) {
+	return func(ctx context.Context, creds config.CredentialSet) (*vault.Client, error) {
 		return client, nil
 	}
 }
 
+// newTestSyncer returns a syncer reading from a Vault served by handler,
+// which fails at once instead of retrying
+func newTestSyncer(t *testing.T, handler http.Handler) *SecretSyncer {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	client.GetAPIClient().SetMaxRetries(0)
+
+	return NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0})
+}
+
+// keyValueVault serves a KV v2 secret with a single field "key"
+var keyValueVault http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(`{"data": {"data": {"key": "value"}}}`))
+}
+
 // createTestConfig creates a test config with default credentials
 func createTestConfig() *config.Config {
 	return &config.Config{
@@ -57,7 +98,13 @@ func TestSyncSecret_Success(t *testing.T) {
 		MaxRetries:     3,
 	}
 
-	syncer := NewSecretSyncer(createTestFactory(client), retryConfig)
+	writes := 0
+	var phases []string
+	syncer := NewSecretSyncer(createTestFactory(client), retryConfig).
+		WithWriteObserver(func(time.Duration) { writes++ }).
+		WithPhaseObserver(func(ctx context.Context, secret, phase string, d time.Duration) {
+			phases = append(phases, secret+":"+phase)
+		})
 
 	tmpDir := t.TempDir()
 	cfg := createTestConfig()
@@ -84,6 +131,13 @@ func TestSyncSecret_Success(t *testing.T) {
 		t.Fatalf("failed to sync secret: %v", err)
 	}
 
+	if writes != 2 {
+		t.Errorf("expected 2 observed writes, got %d", writes)
+	}
+	if got := strings.Join(phases, ","); got != "test-secret:fetch,test-secret:render,test-secret:write" {
+		t.Errorf("expected the fetch, render and write phase, got %s", got)
+	}
+
 	username, err := os.ReadFile(filepath.Join(tmpDir, "username"))
 	if err != nil {
 		t.Fatalf("failed to read username file: %v", err)
@@ -101,6 +155,251 @@ func TestSyncSecret_Success(t *testing.T) {
 	}
 }
 
+func TestSyncSecret_SkipsUnchanged(t *testing.T) {
+	var password atomic.Value
+	password.Store("v1")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": {"data": {"username": "testuser", "password": "` + password.Load().(string) + `"}}}`))
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	written, unchanged := 0, 0
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0}).
+		WithFileObserver(func(secret string, w bool) {
+			if secret != "test-secret" {
+				t.Errorf("unexpected secret name %q", secret)
+			}
+			if w {
+				written++
+			} else {
+				unchanged++
+			}
+		})
+
+	tmpDir := t.TempDir()
+	secret := config.Secret{
+		Name:      "test-secret",
+		Key:       "test/path",
+		MountPath: "secret",
+		KVVersion: "v2",
+		Template: config.Template{
+			Data: map[string]string{
+				"username": "{{ .username }}",
+				"password": "{{ .password }}",
+			},
+		},
+		Files: []config.File{
+			{Path: filepath.Join(tmpDir, "password"), Template: "password", Mode: "0600"},
+			{Path: filepath.Join(tmpDir, "username"), Template: "username", Mode: "0600"},
+		},
+	}
+
+	ctx := context.Background()
+	for _, next := range []string{"v1", "v2"} {
+		if err := syncer.SyncSecret(ctx, createTestConfig(), secret); err != nil {
+			t.Fatalf("failed to sync secret: %v", err)
+		}
+		password.Store(next)
+	}
+	if written != 2 || unchanged != 2 {
+		t.Fatalf("expected 2 written and 2 unchanged after a repeated sync, got %d and %d", written, unchanged)
+	}
+
+	// Only the changed file is rewritten
+	written, unchanged = 0, 0
+	if err := syncer.SyncSecret(ctx, createTestConfig(), secret); err != nil {
+		t.Fatalf("failed to sync secret: %v", err)
+	}
+	if written != 1 || unchanged != 1 {
+		t.Errorf("expected 1 written and 1 unchanged, got %d and %d", written, unchanged)
+	}
+}
+
+func TestSyncSecret_FormatFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": {"data": {"DB_USER": "admin", "DB_PASSWORD": "p@ss word", "DB_PORT": 5432}}}`))
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0})
+
+	tmpDir := t.TempDir()
+	secret := config.Secret{
+		Name:      "test-secret",
+		Key:       "test/path",
+		MountPath: "secret",
+		KVVersion: "v2",
+		Template:  config.Template{Data: map[string]string{"user": "{{ .DB_USER }}"}},
+		Files: []config.File{
+			{Path: filepath.Join(tmpDir, "app.env"), Format: "env", Mode: "0600"},
+			{Path: filepath.Join(tmpDir, "db.json"), Format: "json", Keys: []string{"DB_USER", "DB_PORT"}, Mode: "0600"},
+			{Path: filepath.Join(tmpDir, "user"), Template: "user", Mode: "0600"},
+		},
+	}
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("failed to sync secret: %v", err)
+	}
+
+	want := map[string]string{
+		"app.env": "DB_PASSWORD=\"p@ss word\"\nDB_PORT=5432\nDB_USER=admin\n",
+		"db.json": "{\n  \"DB_PORT\": 5432,\n  \"DB_USER\": \"admin\"\n}\n",
+		"user":    "admin",
+	}
+	for name, content := range want {
+		got, err := os.ReadFile(filepath.Join(tmpDir, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if string(got) != content {
+			t.Errorf("%s: expected %q, got %q", name, content, got)
+		}
+	}
+}
+
+func TestSyncSecret_ConcatFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": {"data": {"cert": "CERT", "intermediate": "INT\n", "ca": "CA"}}}`))
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0})
+
+	tmpDir := t.TempDir()
+	separator := "|"
+	secret := config.Secret{
+		Name:      "test-secret",
+		Key:       "test/path",
+		MountPath: "secret",
+		KVVersion: "v2",
+		Template: config.Template{Data: map[string]string{
+			"crt": "{{ .cert }}",
+			"int": "{{ .intermediate }}",
+			"ca":  "{{ .ca }}",
+		}},
+		Files: []config.File{
+			{Path: filepath.Join(tmpDir, "fullchain.pem"), Format: "concat", Mode: "0600",
+				Concat: &config.Concat{Templates: []string{"crt", "int", "ca"}}},
+			{Path: filepath.Join(tmpDir, "chain.pem"), Format: "concat", Mode: "0600",
+				Concat: &config.Concat{Templates: []string{"ca", "crt"}, Separator: &separator}},
+		},
+	}
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("failed to sync secret: %v", err)
+	}
+
+	want := map[string]string{
+		"fullchain.pem": "CERT\nINT\nCA\n",
+		"chain.pem":     "CA|CERT",
+	}
+	for name, content := range want {
+		got, err := os.ReadFile(filepath.Join(tmpDir, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if string(got) != content {
+			t.Errorf("%s: expected %q, got %q", name, content, got)
+		}
+	}
+}
+
+func TestSyncSecret_KeystoreFiles(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "app.example.com"},
+		NotBefore:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2034, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	body, err := json.Marshal(map[string]interface{}{"data": map[string]interface{}{"data": map[string]string{
+		"cert":     string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		"key":      string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})),
+		"password": "changeit",
+	}}})
+	if err != nil {
+		t.Fatalf("failed to encode response: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0})
+
+	tmpDir := t.TempDir()
+	keystore := &config.Keystore{Certificate: "crt", PrivateKey: "key", Password: "pass"}
+	secret := config.Secret{
+		Name:      "test-secret",
+		Key:       "test/path",
+		MountPath: "secret",
+		KVVersion: "v2",
+		Template: config.Template{Data: map[string]string{
+			"crt":  "{{ .cert }}",
+			"key":  "{{ .key }}",
+			"pass": "{{ .password }}\n",
+		}},
+		Files: []config.File{
+			{Path: filepath.Join(tmpDir, "app.p12"), Format: "pkcs12", Keystore: keystore, Mode: "0600"},
+			{Path: filepath.Join(tmpDir, "app.jks"), Format: "jks", Keystore: keystore, Mode: "0600"},
+		},
+	}
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("failed to sync secret: %v", err)
+	}
+
+	p12, err := os.ReadFile(filepath.Join(tmpDir, "app.p12"))
+	if err != nil {
+		t.Fatalf("failed to read keystore: %v", err)
+	}
+	_, cert, err := pkcs12.Decode(p12, "changeit")
+	if err != nil {
+		t.Fatalf("failed to decode PKCS#12: %v", err)
+	}
+	if cert.Subject.CommonName != "app.example.com" {
+		t.Errorf("unexpected certificate %q", cert.Subject.CommonName)
+	}
+
+	jks, err := os.ReadFile(filepath.Join(tmpDir, "app.jks"))
+	if err != nil {
+		t.Fatalf("failed to read keystore: %v", err)
+	}
+	if !bytes.HasPrefix(jks, []byte{0xfe, 0xed, 0xfe, 0xed}) {
+		t.Error("expected JKS magic")
+	}
+	// The alias defaults to the secret name
+	if !bytes.Contains(jks, []byte("test-secret")) {
+		t.Error("expected the secret name as alias")
+	}
+}
+
 func TestScheduler_AddSecret(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -130,6 +429,9 @@ func TestScheduler_AddSecret(t *testing.T) {
 	scheduler := NewScheduler(syncer)
 	defer scheduler.Stop()
 
+	sub := scheduler.State().Subscribe(1)
+	defer sub.Close()
+
 	tmpDir := t.TempDir()
 	cfg := &config.Config{
 		SecretStore: config.SecretStore{AuthMethod: "token", Token: "test-token"},
@@ -154,7 +456,7 @@ func TestScheduler_AddSecret(t *testing.T) {
 	scheduler.AddSecret(cfg, secret)
 
 	select {
-	case result := <-scheduler.Results():
+	case result := <-sub.C():
 		if !result.Success {
 			t.Errorf("expected success, got error: %v", result.Error)
 		}
@@ -166,6 +468,48 @@ func TestScheduler_AddSecret(t *testing.T) {
 	}
 }
 
+func TestScheduler_ResultKind(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errors": ["permission denied"]}`))
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 3})
+	scheduler := NewScheduler(syncer)
+	defer scheduler.Stop()
+
+	sub := scheduler.State().Subscribe(1)
+	defer sub.Close()
+
+	scheduler.AddSecret(createTestConfig(), config.Secret{
+		Name:            "denied",
+		Key:             "test/path",
+		MountPath:       "secret",
+		KVVersion:       "v2",
+		RefreshInterval: time.Hour,
+		Template:        config.Template{Data: map[string]string{"key": "{{ .key }}"}},
+		Files:           []config.File{{Path: filepath.Join(t.TempDir(), "key"), Mode: "0600"}},
+	})
+
+	select {
+	case result := <-sub.C():
+		if result.Success {
+			t.Fatal("expected failure")
+		}
+		if result.Kind != errkind.Permission {
+			t.Errorf("expected kind permission, got %s (%v)", result.Kind, result.Error)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for sync result")
+	}
+}
+
 func TestScheduler_PeriodicSync(t *testing.T) {
 	var syncCount int32
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -281,6 +625,11 @@ func TestScheduler_RemoveSecret(t *testing.T) {
 	scheduler.AddSecret(cfg, secret)
 	time.Sleep(100 * time.Millisecond)
 
+	jobs := scheduler.Jobs()
+	if len(jobs) != 1 || jobs[0].Name != "test-secret" || jobs[0].RefreshInterval != 50*time.Millisecond {
+		t.Errorf("expected job table with test-secret, got %+v", jobs)
+	}
+
 	scheduler.RemoveSecret("test-secret")
 
 	_, ok := scheduler.GetLastSyncTime("test-secret")
@@ -288,3 +637,644 @@ func TestScheduler_RemoveSecret(t *testing.T) {
 		t.Error("expected secret to be removed")
 	}
 }
+
+func TestScheduler_Reconcile(t *testing.T) {
+	var mu sync.Mutex
+	fetches := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fetches[r.URL.Path]++
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"data": {"data": {"key": "value"}}}`))
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	var removed []string
+	scheduler := NewScheduler(NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0})).
+		WithRemoveObserver(func(name string) { removed = append(removed, name) })
+	defer scheduler.Stop()
+
+	sub := scheduler.State().Subscribe(4)
+	defer sub.Close()
+
+	tmpDir := t.TempDir()
+	newSecret := func(name string) config.Secret {
+		return config.Secret{
+			Name:            name,
+			Key:             "test/" + name,
+			MountPath:       "secret",
+			KVVersion:       "v2",
+			RefreshInterval: time.Hour,
+			Template:        config.Template{Data: map[string]string{"key": "{{ .key }}"}},
+			Files:           []config.File{{Path: filepath.Join(tmpDir, name), Mode: "0600"}},
+		}
+	}
+	waitSyncs := func(n int) {
+		t.Helper()
+		for range n {
+			select {
+			case <-sub.C():
+			case <-time.After(2 * time.Second):
+				t.Fatal("timeout waiting for sync result")
+			}
+		}
+	}
+
+	cfg := createTestConfig()
+	cfg.Secrets = []config.Secret{newSecret("kept"), newSecret("changed"), newSecret("deleted")}
+	result := scheduler.Reconcile(cfg)
+	if len(result.Added) != 3 {
+		t.Fatalf("expected 3 added jobs, got %+v", result)
+	}
+	waitSyncs(3)
+	keptSync, _ := scheduler.GetLastSyncTime("kept")
+
+	changed := newSecret("changed")
+	changed.RefreshInterval = 2 * time.Hour
+	newCfg := createTestConfig()
+	newCfg.Secrets = []config.Secret{newSecret("kept"), changed, newSecret("added")}
+	result = scheduler.Reconcile(newCfg)
+	waitSyncs(2)
+
+	want := ReconcileResult{
+		Added:     []string{"added"},
+		Updated:   []string{"changed"},
+		Removed:   []string{"deleted"},
+		Unchanged: []string{"kept"},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("expected %+v, got %+v", want, result)
+	}
+	if !reflect.DeepEqual(removed, want.Removed) {
+		t.Errorf("expected the observer to be told about %v, got %v", want.Removed, removed)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if n := fetches["/v1/secret/data/test/kept"]; n != 1 {
+		t.Errorf("expected the unchanged secret to be fetched once, got %d", n)
+	}
+	if n := fetches["/v1/secret/data/test/changed"]; n != 2 {
+		t.Errorf("expected the changed secret to be fetched again, got %d", n)
+	}
+	if last, ok := scheduler.GetLastSyncTime("kept"); !ok || !last.Equal(keptSync) {
+		t.Errorf("expected the unchanged job to keep its last sync time %v, got %v", keptSync, last)
+	}
+	if _, ok := scheduler.GetLastSyncTime("deleted"); ok {
+		t.Error("expected the deleted secret's job to be removed")
+	}
+	if _, ok := scheduler.State().Get("deleted"); ok {
+		t.Error("expected the deleted secret's result to be removed")
+	}
+}
+
+func TestScheduler_StopDrainsInFlightSync(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"data": {"data": {"key": "value"}}}`))
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0})
+	scheduler := NewScheduler(syncer)
+
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "key")
+	secret := config.Secret{
+		Name:            "test-secret",
+		Key:             "test/path",
+		MountPath:       "secret",
+		KVVersion:       "v2",
+		RefreshInterval: time.Hour,
+		Template:        config.Template{Data: map[string]string{"key": "{{ .key }}"}},
+		Files:           []config.File{{Path: path, Mode: "0600"}},
+	}
+	scheduler.AddSecret(createTestConfig(), secret)
+
+	// Wait until the sync is blocked on Vault
+	deadline := time.Now().Add(2 * time.Second)
+	for scheduler.InFlight() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+	}()
+
+	if err := scheduler.Shutdown(5 * time.Second); err != nil {
+		t.Fatalf("expected clean drain, got: %v", err)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected in-flight sync to complete before shutdown returned: %v", err)
+	}
+	if scheduler.InFlight() != 0 {
+		t.Errorf("expected no in-flight syncs, got %d", scheduler.InFlight())
+	}
+}
+
+func TestScheduler_ShutdownCancelsAfterTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"data": {"data": {"key": "value"}}}`))
+	}))
+	defer server.Close()
+	var releaseOnce sync.Once
+	releaseServer := func() { releaseOnce.Do(func() { close(release) }) }
+	defer releaseServer()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0})
+	scheduler := NewScheduler(syncer)
+
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "key")
+	secret := config.Secret{
+		Name:            "test-secret",
+		Key:             "test/path",
+		MountPath:       "secret",
+		KVVersion:       "v2",
+		RefreshInterval: time.Hour,
+		Template:        config.Template{Data: map[string]string{"key": "{{ .key }}"}},
+		Files:           []config.File{{Path: path, Mode: "0600"}},
+	}
+	scheduler.AddSecret(createTestConfig(), secret)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for scheduler.InFlight() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		releaseServer()
+	}()
+
+	if err := scheduler.Shutdown(50 * time.Millisecond); err == nil {
+		t.Error("expected drain timeout error")
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("expected cancelled sync not to write any file")
+	}
+
+	// Second call is a no-op
+	_ = scheduler.Shutdown(time.Millisecond)
+}
+
+func TestSyncSecret_CacheFallback(t *testing.T) {
+	var available atomic.Bool
+	available.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"data": {"data": {"key": "value"}}}`))
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	client.GetAPIClient().SetMaxRetries(0)
+
+	secretCache, err := cache.New(t.TempDir(), bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0}).WithCache(secretCache)
+
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "key")
+	secret := config.Secret{
+		Name:      "test-secret",
+		Key:       "test/path",
+		MountPath: "secret",
+		KVVersion: "v2",
+		Template:  config.Template{Data: map[string]string{"key": "{{ .key }}"}},
+		Files:     []config.File{{Path: path, Mode: "0600"}},
+	}
+
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("failed to sync secret: %v", err)
+	}
+
+	// Simulate a restart while Vault is down
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+	available.Store(false)
+
+	err = syncer.SyncSecret(context.Background(), createTestConfig(), secret)
+	var stale *StaleError
+	if !errors.As(err, &stale) {
+		t.Fatalf("expected StaleError, got %v", err)
+	}
+	if stale.FetchedAt.IsZero() {
+		t.Error("expected cache fetch time to be set")
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected file to be restored from cache: %v", err)
+	}
+	if string(content) != "value" {
+		t.Errorf("expected 'value', got '%s'", string(content))
+	}
+}
+
+func TestSyncSecret_CacheMiss(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	client.GetAPIClient().SetMaxRetries(0)
+
+	secretCache, err := cache.New(t.TempDir(), bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0}).WithCache(secretCache)
+
+	secret := config.Secret{
+		Name:      "test-secret",
+		Key:       "test/path",
+		MountPath: "secret",
+		KVVersion: "v2",
+		Template:  config.Template{Data: map[string]string{"key": "{{ .key }}"}},
+		Files:     []config.File{{Path: filepath.Join(t.TempDir(), "key"), Mode: "0600"}},
+	}
+
+	err = syncer.SyncSecret(context.Background(), createTestConfig(), secret)
+	if err == nil {
+		t.Fatal("expected error without cached data")
+	}
+	var stale *StaleError
+	if errors.As(err, &stale) {
+		t.Error("expected a plain error, not StaleError")
+	}
+}
+
+// unavailableVault serves a KV v2 secret while available is set and fails
+// with 503 otherwise
+func unavailableVault(available *atomic.Bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !available.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		keyValueVault(w, r)
+	}
+}
+
+func TestSyncSecret_RetainsFilesWhenUnavailable(t *testing.T) {
+	var available atomic.Bool
+	available.Store(true)
+	syncer := newTestSyncer(t, unavailableVault(&available))
+
+	path := filepath.Join(t.TempDir(), "key")
+	secret := config.Secret{
+		Name:      "test-secret",
+		Key:       "test/path",
+		MountPath: "secret",
+		KVVersion: "v2",
+		Template:  config.Template{Data: map[string]string{"key": "{{ .key }}"}},
+		Files:     []config.File{{Path: path, Mode: "0600"}},
+	}
+
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("failed to sync secret: %v", err)
+	}
+	synced := time.Now()
+
+	available.Store(false)
+
+	err := syncer.SyncSecret(context.Background(), createTestConfig(), secret)
+	var stale *StaleError
+	if !errors.As(err, &stale) {
+		t.Fatalf("expected StaleError, got %v", err)
+	}
+	if stale.FromCache {
+		t.Error("expected retained files, not cache")
+	}
+	if stale.FetchedAt.After(synced) {
+		t.Errorf("expected fetch time of last good sync, got %v", stale.FetchedAt)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected file to be kept: %v", err)
+	}
+	if string(content) != "value" {
+		t.Errorf("expected 'value', got '%s'", string(content))
+	}
+}
+
+func TestSyncSecret_RetainedFilesFromDisk(t *testing.T) {
+	var available atomic.Bool
+	syncer := newTestSyncer(t, unavailableVault(&available))
+
+	// Files left by an earlier run, with no in-memory record of the sync
+	path := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(path, []byte("old"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	modTime := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("failed to set mtime: %v", err)
+	}
+
+	secret := config.Secret{
+		Name:      "test-secret",
+		Key:       "test/path",
+		MountPath: "secret",
+		KVVersion: "v2",
+		Template:  config.Template{Data: map[string]string{"key": "{{ .key }}"}},
+		Files:     []config.File{{Path: path, Mode: "0600"}},
+	}
+
+	err := syncer.SyncSecret(context.Background(), createTestConfig(), secret)
+	var stale *StaleError
+	if !errors.As(err, &stale) {
+		t.Fatalf("expected StaleError, got %v", err)
+	}
+	if !stale.FetchedAt.Equal(modTime) {
+		t.Errorf("expected fetch time %v from file mtime, got %v", modTime, stale.FetchedAt)
+	}
+
+	// Beyond the maximum staleness the failure is hard
+	syncer.WithMaxStaleness(30 * time.Minute)
+	err = syncer.SyncSecret(context.Background(), createTestConfig(), secret)
+	if err == nil {
+		t.Fatal("expected error after maximum staleness")
+	}
+	if errors.As(err, &stale) {
+		t.Errorf("expected hard failure, got StaleError: %v", err)
+	}
+
+	content, _ := os.ReadFile(path)
+	if string(content) != "old" {
+		t.Errorf("expected file to be left untouched, got '%s'", string(content))
+	}
+}
+
+func TestSyncSecret_MissingFilesFailWhenUnavailable(t *testing.T) {
+	var available atomic.Bool
+	syncer := newTestSyncer(t, unavailableVault(&available))
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a"), []byte("old"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	secret := config.Secret{
+		Name:      "test-secret",
+		Key:       "test/path",
+		MountPath: "secret",
+		KVVersion: "v2",
+		Template:  config.Template{Data: map[string]string{"a": "{{ .key }}", "b": "{{ .key }}"}},
+		Files: []config.File{
+			{Path: filepath.Join(dir, "a"), Mode: "0600"},
+			{Path: filepath.Join(dir, "b"), Mode: "0600"},
+		},
+	}
+
+	err := syncer.SyncSecret(context.Background(), createTestConfig(), secret)
+	if err == nil {
+		t.Fatal("expected error when files are missing")
+	}
+	var stale *StaleError
+	if errors.As(err, &stale) {
+		t.Errorf("expected hard failure, got StaleError: %v", err)
+	}
+}
+
+func TestSyncSecret_ExplicitTemplateBinding(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"data": {"data": {"username": "testuser", "password": "testpass"}}}`))
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0})
+
+	// Files are listed in the opposite of the sorted template order
+	tmpDir := t.TempDir()
+	secret := config.Secret{
+		Name:      "test-secret",
+		Key:       "test/path",
+		MountPath: "secret",
+		KVVersion: "v2",
+		Template: config.Template{
+			Data: map[string]string{
+				"password": "{{ .password }}",
+				"username": "{{ .username }}",
+			},
+		},
+		Files: []config.File{
+			{Path: filepath.Join(tmpDir, "username"), Template: "username", Mode: "0600"},
+			{Path: filepath.Join(tmpDir, "password"), Template: "password", Mode: "0600"},
+		},
+	}
+
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("failed to sync secret: %v", err)
+	}
+
+	for name, want := range map[string]string{"username": "testuser", "password": "testpass"} {
+		content, err := os.ReadFile(filepath.Join(tmpDir, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if string(content) != want {
+			t.Errorf("%s: expected '%s', got '%s'", name, want, string(content))
+		}
+	}
+}
+
+func TestSyncSecret_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	client.GetAPIClient().SetMaxRetries(0)
+
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0}).
+		WithSyncTimeout(time.Minute)
+
+	secret := config.Secret{
+		Name:        "test-secret",
+		Key:         "test/path",
+		MountPath:   "secret",
+		KVVersion:   "v2",
+		SyncTimeout: 50 * time.Millisecond, // Overrides the syncer's minute
+		Template:    config.Template{Data: map[string]string{"value": "{{ .value }}"}},
+		Files:       []config.File{{Path: filepath.Join(t.TempDir(), "value"), Template: "value", Mode: "0600"}},
+	}
+
+	start := time.Now()
+	err = syncer.SyncSecret(context.Background(), createTestConfig(), secret)
+	if err == nil || !strings.Contains(err.Error(), "sync timed out after 50ms") {
+		t.Fatalf("expected timeout error, got: %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("sync took %s despite the timeout", elapsed)
+	}
+}
+
+func TestSyncSecret_LogsInAgainWhenTokenExpired(t *testing.T) {
+	var mu sync.Mutex
+	var logins int
+	valid := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/v1/auth/approle/login" {
+			logins++
+			valid = fmt.Sprintf("token-%d", logins)
+			_, _ = fmt.Fprintf(w, `{"auth": {"client_token": %q}}`, valid)
+			return
+		}
+		if r.Header.Get("X-Vault-Token") != valid {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors": ["permission denied"]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"data": {"key": "value"}}}`))
+	}))
+	defer server.Close()
+
+	factory := func(ctx context.Context, creds config.CredentialSet) (*vault.Client, error) {
+		client, err := vault.NewClient(server.URL)
+		if err != nil {
+			return nil, err
+		}
+		return client, client.Authenticate(ctx, vault.AuthConfig{Method: vault.AuthMethodAppRole, RoleID: "role", SecretID: "secret"})
+	}
+
+	syncer := NewSecretSyncer(factory, vault.RetryConfig{MaxRetries: 0})
+	cfg := createTestConfig()
+	secret := deletableSecret(filepath.Join(t.TempDir(), "key"))
+	if err := syncer.SyncSecret(context.Background(), cfg, secret); err != nil {
+		t.Fatalf("first sync failed: %v", err)
+	}
+
+	// The token expires
+	mu.Lock()
+	valid = "expired"
+	mu.Unlock()
+
+	if err := syncer.SyncSecret(context.Background(), cfg, secret); err != nil {
+		t.Fatalf("expected sync to log in again, got: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if logins != 2 {
+		t.Errorf("expected 2 logins, got %d", logins)
+	}
+}
+
+func TestSyncSecret_HashObserver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": {"data": {"password": "s3cret"}}}`))
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	hashes := make(map[string]string)
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0}).
+		WithHashObserver(func(path, hash string) { hashes[path] = hash })
+
+	path := filepath.Join(t.TempDir(), "password")
+	secret := config.Secret{
+		Name:      "app",
+		Key:       "app/db",
+		MountPath: "secret",
+		KVVersion: "v2",
+		Template:  config.Template{Data: map[string]string{"password": "{{ .password }}"}},
+		Files:     []config.File{{Path: path, Template: "password", Mode: "0600"}},
+	}
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("failed to sync secret: %v", err)
+	}
+	if want := state.HashContent([]byte("s3cret")); hashes[path] != want {
+		t.Errorf("expected hash %s for %s, got %v", want, path, hashes)
+	}
+}
+
+func TestNewFileConfig_Dir(t *testing.T) {
+	fileConfig, err := newFileConfig(config.File{Path: "/secrets/app", Mode: "0600"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileConfig.Dir != nil {
+		t.Errorf("expected no directory settings, got %+v", fileConfig.Dir)
+	}
+
+	fileConfig, err = newFileConfig(config.File{Path: "/secrets/app", Mode: "0600", DirOwner: "1000", SELinuxContext: "system_u:object_r:container_file_t:s0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (filewriter.DirConfig{Mode: 0, Owner: 1000, Group: -1}); fileConfig.Dir == nil || *fileConfig.Dir != want {
+		t.Errorf("expected %+v, got %+v", want, fileConfig.Dir)
+	}
+	if fileConfig.SELinuxContext != "system_u:object_r:container_file_t:s0" {
+		t.Errorf("expected the SELinux context, got %q", fileConfig.SELinuxContext)
+	}
+
+	if _, err := newFileConfig(config.File{Path: "/secrets/app", Mode: "0600", DirMode: "0777"}); err == nil {
+		t.Error("expected an error for a world-writable dirMode")
+	}
+}
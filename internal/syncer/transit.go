@@ -0,0 +1,46 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/template"
+	"github.com/ohauer/secrets-sync/internal/tracing"
+)
+
+// defaultTransitMount is the mount path of transitDecrypt keys given
+// without one
+const defaultTransitMount = "transit"
+
+// transitDecrypter returns the function behind transitDecrypt for a
+// secret's templates. It decrypts with the client and namespace of the
+// secret; a key given as <mount>/<key> selects another transit mount.
+func (s *SecretSyncer) transitDecrypter(ctx context.Context, cfg *config.Config, secret config.Secret) template.Decrypter {
+	return func(key, ciphertext string) (string, error) {
+		if cfg.SecretStore.IsAzureKeyVault() {
+			return "", fmt.Errorf("transitDecrypt requires a Vault secret store")
+		}
+		mount, name := path.Split(key)
+		mount = path.Clean(mount)
+		if mount == "." {
+			mount = defaultTransitMount
+		}
+		if name == "" {
+			return "", fmt.Errorf("transitDecrypt: invalid key %q", key)
+		}
+
+		client, err := s.clientFor(ctx, cfg, secret)
+		if err != nil {
+			return "", err
+		}
+		ctx, span := tracing.StartSpan(ctx, "vault.transit_decrypt")
+		defer span.End()
+		plaintext, err := client.TransitDecrypt(ctx, mount, name, ciphertext, secret.ResolveNamespace(cfg.SecretStore.Namespace))
+		if err != nil {
+			return "", err
+		}
+		return string(plaintext), nil
+	}
+}
@@ -0,0 +1,71 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+func TestSyncSecret_TransitDecrypt(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/secret/data/test/path":
+			_, _ = w.Write([]byte(`{"data": {"data": {"blob": "vault:v1:abc"}, "metadata": {"version": 1}}}`))
+		case "/v1/transit/decrypt/app", "/v1/kms/decrypt/app":
+			var body struct {
+				Ciphertext string `json:"ciphertext"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Ciphertext != "vault:v1:abc" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			// "hunter2"
+			_, _ = w.Write([]byte(`{"data": {"plaintext": "aHVudGVyMg=="}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0})
+
+	dir := t.TempDir()
+	secret := config.Secret{
+		Name:      "test-secret",
+		Key:       "test/path",
+		MountPath: "secret",
+		KVVersion: "v2",
+		Template: config.Template{Data: map[string]string{
+			"default": `{{ .blob | transitDecrypt "app" }}`,
+			"mount":   `{{ transitDecrypt "kms/app" .blob }}`,
+		}},
+		Files: []config.File{
+			{Path: filepath.Join(dir, "default"), Template: "default"},
+			{Path: filepath.Join(dir, "mount"), Template: "mount"},
+		},
+	}
+
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	for _, file := range secret.Files {
+		if got := readFile(t, file.Path); got != "hunter2" {
+			t.Errorf("%s: expected hunter2, got %q", file.Path, got)
+		}
+	}
+
+	secret.Template.Data["default"] = `{{ .blob | transitDecrypt "missing" }}`
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err == nil {
+		t.Error("expected error for unknown transit key")
+	}
+}
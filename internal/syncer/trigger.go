@@ -0,0 +1,138 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUnknownSecret is returned when syncing a secret that is not scheduled
+	ErrUnknownSecret = errors.New("secret is not scheduled")
+	// ErrPaused is returned when syncing while paused for a sealed Vault
+	ErrPaused = errors.New("syncing is paused while Vault is sealed")
+	// ErrStopped is returned when the scheduler or the secret's job stops
+	// before the requested sync ran
+	ErrStopped = errors.New("scheduler stopped")
+	// ErrSyncRunning is returned by Rollback while the secret is being synced
+	ErrSyncRunning = errors.New("a sync of the secret is running")
+)
+
+// SyncNow syncs a scheduled secret immediately instead of waiting for its
+// next refresh and returns the result. A sync already running is not taken
+// as the result, as it may have read Vault before the request. Cancelling
+// ctx stops waiting, not the sync.
+func (s *Scheduler) SyncNow(ctx context.Context, name string) (SyncResult, error) {
+	s.mu.Lock()
+	if s.sealed.Load() {
+		s.mu.Unlock()
+		return SyncResult{}, ErrPaused
+	}
+	j, ok := s.jobs[name]
+	if !ok {
+		s.mu.Unlock()
+		return SyncResult{}, ErrUnknownSecret
+	}
+	done := make(chan SyncResult, 1)
+	j.waiters = append(j.waiters, done)
+	s.mu.Unlock()
+
+	select {
+	case j.syncNow <- ReasonManual:
+	default: // A sync is already requested
+	}
+
+	select {
+	case result := <-done:
+		// Vault was sealed before the sync started
+		if errors.Is(result.Error, ErrPaused) {
+			return SyncResult{}, ErrPaused
+		}
+		return result, nil
+	case <-ctx.Done():
+		return SyncResult{}, ctx.Err()
+	case <-j.stopCh:
+		return SyncResult{}, ErrStopped
+	case <-s.stopCh:
+		return SyncResult{}, ErrStopped
+	}
+}
+
+// SyncAllNow syncs every scheduled secret immediately, see SyncNow, and
+// returns the results sorted by secret name. Secrets removed meanwhile are
+// left out.
+func (s *Scheduler) SyncAllNow(ctx context.Context) ([]SyncResult, error) {
+	if s.sealed.Load() {
+		return nil, ErrPaused
+	}
+
+	s.mu.RLock()
+	names := make([]string, 0, len(s.jobs))
+	for name := range s.jobs {
+		names = append(names, name)
+	}
+	s.mu.RUnlock()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var firstErr error
+	results := make([]SyncResult, 0, len(names))
+	for _, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := s.SyncNow(ctx, name)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				results = append(results, result)
+			case errors.Is(err, ErrUnknownSecret):
+			case firstErr == nil:
+				firstErr = err
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].SecretName < results[j].SecretName
+	})
+	return results, nil
+}
+
+// Rollback restores the latest backup of the files of a scheduled secret,
+// see SecretSyncer.Rollback. It is refused while the secret is being synced,
+// as the sync could overwrite the restored files, and a sync due meanwhile
+// waits for the restore.
+func (s *Scheduler) Rollback(ctx context.Context, name string) ([]string, error) {
+	s.mu.RLock()
+	j, ok := s.jobs[name]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownSecret
+	}
+	if !j.writing.TryLock() {
+		return nil, ErrSyncRunning
+	}
+	defer j.writing.Unlock()
+
+	// Shown as running, and skipped by the verifier, until restored
+	s.setRunning(j, time.Now())
+	defer s.setRunning(j, time.Time{})
+	return s.syncer.Rollback(ctx, j.secret)
+}
+
+// takeWaiters returns the callers waiting for the next sync of a job to start
+func (s *Scheduler) takeWaiters(j *job) []chan SyncResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	waiters := j.waiters
+	j.waiters = nil
+	return waiters
+}
@@ -0,0 +1,202 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+func TestScheduler_SyncNow(t *testing.T) {
+	var value atomic.Value
+	value.Store("v1")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": {"data": {"key": "` + value.Load().(string) + `"}}}`))
+	}))
+	defer server.Close()
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0})
+	scheduler := NewScheduler(syncer)
+	defer scheduler.Stop()
+
+	sub := scheduler.State().Subscribe(10)
+	defer sub.Close()
+
+	dir := t.TempDir()
+	for _, name := range []string{"b", "a"} {
+		secret := deletableSecret(filepath.Join(dir, name))
+		secret.Name = name
+		secret.RefreshInterval = time.Hour
+		scheduler.AddSecret(createTestConfig(), secret)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-sub.C():
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the first syncs")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	value.Store("v2")
+	result, err := scheduler.SyncNow(ctx, "a")
+	if err != nil {
+		t.Fatalf("SyncNow failed: %v", err)
+	}
+	if !result.Success || result.SecretName != "a" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "a")); string(content) != "v2" {
+		t.Errorf("expected the rotated value to be written, got %q", content)
+	}
+
+	if _, err := scheduler.SyncNow(ctx, "missing"); !errors.Is(err, ErrUnknownSecret) {
+		t.Errorf("expected ErrUnknownSecret, got: %v", err)
+	}
+
+	value.Store("v3")
+	results, err := scheduler.SyncAllNow(ctx)
+	if err != nil {
+		t.Fatalf("SyncAllNow failed: %v", err)
+	}
+	if len(results) != 2 || results[0].SecretName != "a" || results[1].SecretName != "b" {
+		t.Fatalf("expected results for a and b, got %+v", results)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "b")); string(content) != "v3" {
+		t.Errorf("expected every secret to be synced, got %q", content)
+	}
+}
+
+func TestScheduler_SyncNowStopped(t *testing.T) {
+	block := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+		_, _ = w.Write([]byte(`{"data": {"data": {"key": "value"}}}`))
+	}))
+	defer server.Close()
+	defer close(block)
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0})
+	scheduler := NewScheduler(syncer).WithDrainTimeout(10 * time.Millisecond)
+
+	secret := deletableSecret(filepath.Join(t.TempDir(), "key"))
+	secret.RefreshInterval = time.Hour
+	scheduler.AddSecret(createTestConfig(), secret)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := scheduler.SyncNow(context.Background(), secret.Name)
+		done <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	scheduler.RemoveSecret(secret.Name)
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrStopped) {
+			t.Errorf("expected ErrStopped, got: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SyncNow did not return after the secret was removed")
+	}
+	scheduler.Stop()
+}
+
+func TestScheduler_SyncNowSealedMeanwhile(t *testing.T) {
+	block := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+		_, _ = w.Write([]byte(`{"data": {"data": {"key": "value"}}}`))
+	}))
+	defer server.Close()
+	var release sync.Once
+	defer release.Do(func() { close(block) })
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	syncer := NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0})
+	scheduler := NewScheduler(syncer)
+	defer scheduler.Stop()
+
+	secret := deletableSecret(filepath.Join(t.TempDir(), "key"))
+	secret.RefreshInterval = time.Hour
+	scheduler.AddSecret(createTestConfig(), secret)
+	waitForRunning(t, scheduler, secret.Name)
+
+	// Request a sync while the first one runs, so it waits for the next
+	done := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err := scheduler.SyncNow(ctx, secret.Name)
+		done <- err
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		scheduler.mu.RLock()
+		waiting := len(scheduler.jobs[secret.Name].waiters)
+		scheduler.mu.RUnlock()
+		if waiting > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for SyncNow to register")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Vault is sealed before the requested sync starts
+	scheduler.sealed.Store(true)
+	release.Do(func() { close(block) })
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrPaused) {
+			t.Errorf("expected ErrPaused, got: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("SyncNow did not return after syncing was paused")
+	}
+}
+
+// waitForRunning waits until a sync of a secret is running
+func waitForRunning(t *testing.T, scheduler *Scheduler, name string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		for _, j := range scheduler.Jobs() {
+			if j.Name == name && !j.RunningSince.IsZero() {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s to sync", name)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,212 @@
+package syncer
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/filewriter"
+	"github.com/ohauer/secrets-sync/internal/state"
+)
+
+// DriftKind classifies how a managed file differs from what was written
+type DriftKind string
+
+const (
+	DriftMissing DriftKind = "missing"
+	DriftContent DriftKind = "content"
+	DriftMode    DriftKind = "mode"
+	DriftOwner   DriftKind = "owner"
+	DriftContext DriftKind = "context" // SELinux context
+)
+
+// Drift describes a managed file that no longer matches what was written
+type Drift struct {
+	Secret    string
+	Path      string
+	Kind      DriftKind
+	Detail    string
+	Repaired  bool  // Fixed in place, or a resync was queued for content drift
+	RepairErr error // Set if repair was attempted and failed
+}
+
+type verifyConfig struct {
+	interval time.Duration
+	repair   bool
+	report   func(Drift)
+}
+
+// WithVerification re-checks the files of every successfully synced secret
+// each interval and passes drift to report. With repair, mode and ownership
+// are restored in place and missing or modified files trigger a resync.
+// Disabled if interval is zero.
+func (s *Scheduler) WithVerification(interval time.Duration, repair bool, report func(Drift)) *Scheduler {
+	s.verify = verifyConfig{interval: interval, repair: repair, report: report}
+	return s
+}
+
+func (s *Scheduler) runVerifier() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.verify.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.verifyAll()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// verifyAll checks the files of all idle jobs whose last sync succeeded
+func (s *Scheduler) verifyAll() {
+	s.mu.RLock()
+	jobs := make([]*job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		if j.runningSince.IsZero() {
+			jobs = append(jobs, j)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, j := range jobs {
+		if result, ok := s.state.Get(j.secret.Name); !ok || !result.Success {
+			continue
+		}
+
+		resync := false
+		for _, drift := range s.syncer.VerifySecret(j.secret) {
+			if s.verify.repair {
+				switch drift.Kind {
+				case DriftMode, DriftOwner, DriftContext:
+					drift.RepairErr = s.syncer.RepairMetadata(j.secret, drift)
+					drift.Repaired = drift.RepairErr == nil
+				default:
+					resync = true
+					drift.Repaired = true
+				}
+			}
+			if s.verify.report != nil {
+				s.verify.report(drift)
+			}
+		}
+
+		if resync {
+			select {
+			case j.syncNow <- ReasonRepair:
+			default: // A resync is already queued
+			}
+		}
+	}
+}
+
+// VerifySecret compares the files of a secret against their configured mode
+// and ownership and, with a manifest, the content hash recorded at write time
+func (s *SecretSyncer) VerifySecret(secret config.Secret) []Drift {
+	var drifts []Drift
+
+	for _, file := range secret.Files {
+		drift := func(kind DriftKind, format string, args ...interface{}) {
+			drifts = append(drifts, Drift{
+				Secret: secret.Name,
+				Path:   file.Path,
+				Kind:   kind,
+				Detail: fmt.Sprintf(format, args...),
+			})
+		}
+
+		expected, err := newFileConfig(file)
+		if err != nil {
+			continue
+		}
+
+		mode, uid, gid, err := filewriter.GetFileInfo(file.Path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				drift(DriftMissing, "file does not exist")
+			} else {
+				drift(DriftMissing, "cannot stat file: %v", err)
+			}
+			continue
+		}
+
+		if s.manifest != nil {
+			if entry, ok := s.manifest.Get(file.Path); ok {
+				hash, err := state.HashFile(file.Path)
+				switch {
+				case err != nil:
+					drift(DriftContent, "cannot read file: %v", err)
+				case hash != entry.Hash:
+					drift(DriftContent, "content hash differs from the one written")
+				}
+			}
+		}
+
+		if mode.Perm() != expected.Mode.Perm() {
+			drift(DriftMode, "mode %04o, expected %04o", mode.Perm(), expected.Mode.Perm())
+		}
+
+		if (expected.Owner >= 0 && uid >= 0 && uid != expected.Owner) ||
+			(expected.Group >= 0 && gid >= 0 && gid != expected.Group) {
+			drift(DriftOwner, "owner %d:%d, expected %s", uid, gid, ownerString(expected))
+		}
+
+		if expected.SELinuxContext != "" {
+			if context, err := filewriter.SELinuxContext(file.Path); err == nil && context != expected.SELinuxContext {
+				drift(DriftContext, "SELinux context %q, expected %q", context, expected.SELinuxContext)
+			}
+		}
+	}
+
+	return drifts
+}
+
+// RepairMetadata restores the configured mode, ownership or SELinux context
+// of a drifted file
+func (s *SecretSyncer) RepairMetadata(secret config.Secret, drift Drift) error {
+	for _, file := range secret.Files {
+		if file.Path != drift.Path {
+			continue
+		}
+
+		expected, err := newFileConfig(file)
+		if err != nil {
+			return err
+		}
+
+		switch drift.Kind {
+		case DriftMode:
+			if err := os.Chmod(file.Path, expected.Mode.Perm()); err != nil {
+				return fmt.Errorf("failed to restore mode of %s: %w", file.Path, err)
+			}
+		case DriftOwner:
+			if err := os.Lchown(file.Path, expected.Owner, expected.Group); err != nil {
+				return fmt.Errorf("failed to restore ownership of %s: %w", file.Path, err)
+			}
+		case DriftContext:
+			if err := filewriter.SetSELinuxContext(file.Path, expected.SELinuxContext); err != nil {
+				return fmt.Errorf("failed to restore SELinux context: %w", err)
+			}
+		default:
+			return fmt.Errorf("%s drift of %s cannot be repaired in place", drift.Kind, file.Path)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("file %s is not part of secret %q", drift.Path, secret.Name)
+}
+
+// ownerString formats the expected owner and group, "*" meaning any
+func ownerString(cfg filewriter.FileConfig) string {
+	id := func(n int) string {
+		if n < 0 {
+			return "*"
+		}
+		return fmt.Sprintf("%d", n)
+	}
+	return id(cfg.Owner) + ":" + id(cfg.Group)
+}
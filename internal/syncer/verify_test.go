@@ -0,0 +1,119 @@
+package syncer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/state"
+)
+
+func TestVerifySecret(t *testing.T) {
+	var deleted atomic.Bool
+	tmpDir := t.TempDir()
+	syncer := newTestSyncer(t, deletableVault(&deleted)).
+		WithManifest(state.NewManifest(filepath.Join(tmpDir, "manifest.json")))
+	path := filepath.Join(tmpDir, "key")
+	secret := deletableSecret(path)
+
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("failed to sync secret: %v", err)
+	}
+	if drifts := syncer.VerifySecret(secret); len(drifts) != 0 {
+		t.Fatalf("expected no drift after sync, got %+v", drifts)
+	}
+
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	kinds := map[DriftKind]Drift{}
+	for _, drift := range syncer.VerifySecret(secret) {
+		kinds[drift.Kind] = drift
+	}
+	if len(kinds) != 2 {
+		t.Fatalf("expected content and mode drift, got %+v", kinds)
+	}
+	if _, ok := kinds[DriftContent]; !ok {
+		t.Error("expected content drift")
+	}
+
+	if err := syncer.RepairMetadata(secret, kinds[DriftMode]); err != nil {
+		t.Fatalf("failed to repair mode: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600 after repair, got %04o", info.Mode().Perm())
+	}
+	if err := syncer.RepairMetadata(secret, kinds[DriftContent]); err == nil {
+		t.Error("expected content drift not to be repairable in place")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	drifts := syncer.VerifySecret(secret)
+	if len(drifts) != 1 || drifts[0].Kind != DriftMissing {
+		t.Errorf("expected missing drift, got %+v", drifts)
+	}
+}
+
+func TestScheduler_VerificationResyncsMissingFile(t *testing.T) {
+	var deleted atomic.Bool
+	syncer := newTestSyncer(t, deletableVault(&deleted))
+	path := filepath.Join(t.TempDir(), "key")
+	secret := deletableSecret(path)
+	secret.RefreshInterval = time.Hour
+
+	var mu sync.Mutex
+	var reported []Drift
+	scheduler := NewScheduler(syncer).WithVerification(20*time.Millisecond, true, func(drift Drift) {
+		mu.Lock()
+		defer mu.Unlock()
+		reported = append(reported, drift)
+	})
+	defer scheduler.Stop()
+
+	sub := scheduler.State().Subscribe(10)
+	defer sub.Close()
+
+	scheduler.AddSecret(createTestConfig(), secret)
+	select {
+	case <-sub.C():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for initial sync")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case result := <-sub.C():
+		if !result.Success {
+			t.Fatalf("expected resync to succeed, got %v", result.Error)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for resync")
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected file to be restored: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reported) == 0 || reported[0].Kind != DriftMissing || !reported[0].Repaired {
+		t.Errorf("expected repaired missing drift to be reported, got %+v", reported)
+	}
+}
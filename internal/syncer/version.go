@@ -0,0 +1,148 @@
+package syncer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/errkind"
+	"github.com/ohauer/secrets-sync/internal/state"
+	"github.com/ohauer/secrets-sync/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// syncedVersion records which KV v2 version was last written for a secret
+type syncedVersion struct {
+	version     int
+	fingerprint string // Hash of the secret's configuration the files were rendered from
+	noMetadata  bool   // The metadata endpoint is not readable, always read the data
+}
+
+// upToDate reports whether the files of an unpinned KV v2 secret already
+// hold its current version, so the data read can be skipped. It asks the
+// metadata endpoint only if the configuration is unchanged since that sync
+// and every file is still in place. Any doubt, including a failed metadata
+// read, falls back to a full read.
+func (s *SecretSyncer) upToDate(ctx context.Context, cfg *config.Config, secret config.Secret) bool {
+	if secret.KVVersion != "v2" || secret.Version > 0 || len(secret.Files) == 0 {
+		return false
+	}
+	synced, ok := s.getSyncedVersion(secret.Name)
+	if !ok || synced.noMetadata || synced.version == 0 || synced.fingerprint != fingerprint(cfg, secret) {
+		return false
+	}
+	if !s.filesIntact(secret) {
+		return false
+	}
+
+	client, err := s.clientFor(ctx, cfg, secret)
+	if err != nil {
+		return false
+	}
+
+	namespace := secret.ResolveNamespace(cfg.SecretStore.Namespace)
+	ctx, span := tracing.StartSpan(ctx, "vault.metadata")
+	defer span.End()
+	span.SetAttributes(vaultAttributes(secret, namespace)...)
+	current, err := client.CurrentVersion(ctx, secret.MountPath, secret.Key, namespace)
+	if err != nil {
+		// Policies often grant read on data/ only; stop asking
+		if errkind.Of(err) == errkind.Permission {
+			synced.noMetadata = true
+			s.putSyncedVersion(secret.Name, synced)
+		}
+		return false
+	}
+	span.SetAttributes(attribute.Int("version", current))
+	return current == synced.version
+}
+
+// skipUnchanged records a sync that found the files up to date and returns
+// their paths
+func (s *SecretSyncer) skipUnchanged(secret config.Secret) []string {
+	now := time.Now()
+	s.setFetchedAt(secret.Name, now)
+	if s.memCache != nil {
+		s.memCache.touch(secret.Name, now)
+	}
+	paths := make([]string, 0, len(secret.Files))
+	for _, file := range secret.Files {
+		if s.fileObserver != nil {
+			s.fileObserver(secret.Name, false)
+		}
+		paths = append(paths, file.Path)
+	}
+	return paths
+}
+
+// filesIntact reports whether every file of a secret exists and, if a
+// manifest is kept, still holds the content written
+func (s *SecretSyncer) filesIntact(secret config.Secret) bool {
+	for _, file := range secret.Files {
+		info, err := os.Lstat(file.Path)
+		if err != nil || !info.Mode().IsRegular() {
+			return false
+		}
+		if s.manifest == nil {
+			continue
+		}
+		entry, ok := s.manifest.Get(file.Path)
+		if !ok {
+			return false
+		}
+		if hash, err := state.HashFile(file.Path); err != nil || hash != entry.Hash {
+			return false
+		}
+	}
+	return true
+}
+
+// fingerprint hashes the configuration a secret's files are rendered from
+func fingerprint(cfg *config.Config, secret config.Secret) string {
+	data, err := json.Marshal(struct {
+		Namespace string
+		Secret    config.Secret
+	}{cfg.SecretStore.Namespace, secret})
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// setSyncedVersion records the KV v2 version written for an unpinned
+// secret; anything else clears the record
+func (s *SecretSyncer) setSyncedVersion(cfg *config.Config, secret config.Secret, version int) {
+	if secret.KVVersion != "v2" || secret.Version > 0 || version == 0 {
+		s.clearSyncedVersion(secret.Name)
+		return
+	}
+	synced, _ := s.getSyncedVersion(secret.Name)
+	synced.version = version
+	synced.fingerprint = fingerprint(cfg, secret)
+	s.putSyncedVersion(secret.Name, synced)
+}
+
+func (s *SecretSyncer) getSyncedVersion(name string) (syncedVersion, bool) {
+	s.versionMu.Lock()
+	defer s.versionMu.Unlock()
+	v, ok := s.versions[name]
+	return v, ok
+}
+
+func (s *SecretSyncer) putSyncedVersion(name string, v syncedVersion) {
+	s.versionMu.Lock()
+	defer s.versionMu.Unlock()
+	s.versions[name] = v
+}
+
+// clearSyncedVersion forgets the version written for a secret
+func (s *SecretSyncer) clearSyncedVersion(name string) {
+	s.versionMu.Lock()
+	defer s.versionMu.Unlock()
+	delete(s.versions, name)
+}
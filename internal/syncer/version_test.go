@@ -0,0 +1,175 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+// versionedServer serves a KV v2 secret whose value and version are set by
+// the test, counting data and metadata reads
+type versionedServer struct {
+	version       atomic.Int32
+	dataReads     atomic.Int32
+	metadataReads atomic.Int32
+	metadataCode  int
+}
+
+func (v *versionedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	current := v.version.Load()
+	switch r.URL.Path {
+	case "/v1/secret/data/test/path":
+		v.dataReads.Add(1)
+		version := current
+		if q := r.URL.Query().Get("version"); q != "" {
+			_, _ = fmt.Sscanf(q, "%d", &version)
+		}
+		_, _ = fmt.Fprintf(w, `{"data": {"data": {"key": "value-%d"}, "metadata": {"version": %d}}}`, version, version)
+	case "/v1/secret/metadata/test/path":
+		v.metadataReads.Add(1)
+		if v.metadataCode != 0 {
+			w.WriteHeader(v.metadataCode)
+			_, _ = w.Write([]byte(`{"errors": ["permission denied"]}`))
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"data": {"current_version": %d, "versions": {"%d": {"deletion_time": "", "destroyed": false}}}}`, current, current)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	return string(content)
+}
+
+func TestSyncSecret_SkipsUnchangedVersion(t *testing.T) {
+	handler := &versionedServer{}
+	handler.version.Store(1)
+	syncer := newTestSyncer(t, handler)
+
+	path := filepath.Join(t.TempDir(), "key")
+	secret := deletableSecret(path)
+	cfg := createTestConfig()
+
+	for i := 0; i < 3; i++ {
+		if err := syncer.SyncSecret(context.Background(), cfg, secret); err != nil {
+			t.Fatalf("sync %d failed: %v", i+1, err)
+		}
+	}
+	if got := handler.dataReads.Load(); got != 1 {
+		t.Errorf("expected 1 data read while the version is unchanged, got %d", got)
+	}
+	if got := handler.metadataReads.Load(); got != 2 {
+		t.Errorf("expected 2 metadata reads, got %d", got)
+	}
+
+	handler.version.Store(2)
+	if err := syncer.SyncSecret(context.Background(), cfg, secret); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if got := readFile(t, path); got != "value-2" {
+		t.Errorf("expected new version to be written, got %q", got)
+	}
+
+	// A removed file is restored even though the version is unchanged
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := syncer.SyncSecret(context.Background(), cfg, secret); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if got := readFile(t, path); got != "value-2" {
+		t.Errorf("expected removed file to be restored, got %q", got)
+	}
+
+	// A changed configuration is rendered again
+	secret.Template.Data["key"] = "changed-{{ .key }}"
+	if err := syncer.SyncSecret(context.Background(), cfg, secret); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if got := readFile(t, path); got != "changed-value-2" {
+		t.Errorf("expected changed template to be rendered, got %q", got)
+	}
+}
+
+func TestSyncSecret_DataObserver(t *testing.T) {
+	handler := &versionedServer{}
+	handler.version.Store(1)
+	syncer := newTestSyncer(t, handler)
+
+	var observed []string
+	syncer.WithDataObserver(func(secret string, data vault.SecretData) {
+		observed = append(observed, fmt.Sprintf("%s=%v", secret, data["key"]))
+	})
+
+	secret := deletableSecret(filepath.Join(t.TempDir(), "key"))
+	cfg := createTestConfig()
+	for _, version := range []int32{1, 1, 2} {
+		handler.version.Store(version)
+		if err := syncer.SyncSecret(context.Background(), cfg, secret); err != nil {
+			t.Fatalf("sync failed: %v", err)
+		}
+	}
+
+	// The unchanged version is neither read nor observed
+	want := []string{"test-secret=value-1", "test-secret=value-2"}
+	if !slices.Equal(observed, want) {
+		t.Errorf("expected %q to be observed, got %q", want, observed)
+	}
+	if got := handler.dataReads.Load(); got != 2 {
+		t.Errorf("expected 2 data reads, got %d", got)
+	}
+}
+
+func TestSyncSecret_PinnedVersion(t *testing.T) {
+	handler := &versionedServer{}
+	handler.version.Store(5)
+	syncer := newTestSyncer(t, handler)
+
+	path := filepath.Join(t.TempDir(), "key")
+	secret := deletableSecret(path)
+	secret.Version = 3
+
+	for i := 0; i < 2; i++ {
+		if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+			t.Fatalf("sync failed: %v", err)
+		}
+	}
+	if got := readFile(t, path); got != "value-3" {
+		t.Errorf("expected pinned version, got %q", got)
+	}
+	if got := handler.metadataReads.Load(); got != 0 {
+		t.Errorf("expected no metadata reads for a pinned version, got %d", got)
+	}
+}
+
+func TestSyncSecret_MetadataDenied(t *testing.T) {
+	handler := &versionedServer{metadataCode: http.StatusForbidden}
+	handler.version.Store(1)
+	syncer := newTestSyncer(t, handler)
+
+	secret := deletableSecret(filepath.Join(t.TempDir(), "key"))
+	for i := 0; i < 3; i++ {
+		if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+			t.Fatalf("sync %d failed: %v", i+1, err)
+		}
+	}
+	if got := handler.dataReads.Load(); got != 3 {
+		t.Errorf("expected a full read on every sync, got %d", got)
+	}
+	if got := handler.metadataReads.Load(); got != 1 {
+		t.Errorf("expected metadata to be asked once, got %d", got)
+	}
+}
@@ -0,0 +1,266 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/errkind"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+// syncWildcard lists the secrets matched by a wildcard key and syncs each of
+// them into its own subdirectory. Secrets no longer listed are handled like
+// secrets deleted in Vault. If listing fails, the secrets matched last time
+// are synced, so their files are kept or served from the cache as usual.
+func (s *SecretSyncer) syncWildcard(ctx context.Context, cfg *config.Config, secret config.Secret) error {
+	known := s.wildcardKnown(secret.Name)
+
+	keys, listErr := s.listWildcard(ctx, cfg, secret)
+	if listErr != nil {
+		if len(known) == 0 {
+			return listErr
+		}
+		keys = make([]string, 0, len(known))
+		for key := range known {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+	}
+
+	var failed []error
+	var stale *StaleError
+	for _, key := range keys {
+		child, err := wildcardChild(secret, key, known[key])
+		if err != nil {
+			failed = append(failed, err)
+			continue
+		}
+
+		paths, err := s.syncSecret(ctx, cfg, child)
+		if len(paths) > 0 {
+			s.setWildcardFiles(secret.Name, key, paths)
+		}
+
+		var childStale *StaleError
+		switch {
+		case err == nil:
+		case isDeletion(err):
+			s.setWildcardFiles(secret.Name, key, nil)
+		case errors.As(err, &childStale):
+			if stale == nil {
+				stale = childStale
+			}
+		default:
+			failed = append(failed, fmt.Errorf("%s: %w", child.Key, err))
+		}
+	}
+
+	if listErr == nil {
+		failed = append(failed, s.removeUnlisted(secret, keys, known)...)
+	}
+
+	if len(failed) > 0 {
+		// Only hard failures are wrapped, so the result is not mistaken for stale
+		return fmt.Errorf("%d of %d secrets matched by %s failed: %w", len(failed), len(keys), secret.Key, errors.Join(failed...))
+	}
+	if stale != nil {
+		return stale
+	}
+	return listErr
+}
+
+// removeUnlisted forgets the secrets matched last time but no longer listed,
+// and applies the deletion policy to their files
+func (s *SecretSyncer) removeUnlisted(secret config.Secret, keys []string, known map[string][]string) []error {
+	listed := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		listed[key] = true
+	}
+
+	var failed []error
+	for key, paths := range known {
+		if listed[key] {
+			continue
+		}
+		s.setWildcardFiles(secret.Name, key, nil)
+		if s.deletionPolicy == DeletionKeep {
+			continue
+		}
+
+		child, err := wildcardChild(secret, key, paths)
+		if err != nil {
+			failed = append(failed, err)
+			continue
+		}
+		err = s.handleDeleted(child, fmt.Errorf("%w: %s is no longer listed", vault.ErrSecretDeleted, child.Key))
+		if !isDeletion(err) {
+			failed = append(failed, err)
+		}
+	}
+	return failed
+}
+
+// isDeletion reports whether err only records the files of a deleted secret
+// being removed, rather than a failure to remove them
+func isDeletion(err error) bool {
+	_, ok := err.(*DeletedError)
+	return ok
+}
+
+// renderWildcard renders the files of every secret matched by a wildcard key
+func (s *SecretSyncer) renderWildcard(ctx context.Context, cfg *config.Config, secret config.Secret) ([]renderedFile, error) {
+	keys, err := s.listWildcard(ctx, cfg, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, _, _ := secret.WildcardPrefix()
+	var files []renderedFile
+	for _, key := range keys {
+		child, err := wildcardChild(secret, key, nil)
+		if err == nil {
+			var rendered []renderedFile
+			rendered, err = s.renderSecret(ctx, cfg, child)
+			files = append(files, rendered...)
+		}
+		if err != nil {
+			wipeFiles(files)
+			return nil, fmt.Errorf("%s%s: %w", prefix, key, err)
+		}
+	}
+	return files, nil
+}
+
+// listWildcard returns the keys matched by a wildcard key, relative to its
+// prefix and sorted. Recursive wildcards descend into every folder.
+func (s *SecretSyncer) listWildcard(ctx context.Context, cfg *config.Config, secret config.Secret) ([]string, error) {
+	client, err := s.clientFor(ctx, cfg, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, recursive, _ := secret.WildcardPrefix()
+	namespace := secret.ResolveNamespace(cfg.SecretStore.Namespace)
+
+	var keys []string
+	pending := []string{""}
+	for len(pending) > 0 {
+		folder := pending[0]
+		pending = pending[1:]
+
+		listed, err := client.ListSecrets(ctx, secret.MountPath, prefix+folder, secret.KVVersion, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range listed {
+			if strings.HasSuffix(key, "/") {
+				if recursive {
+					pending = append(pending, folder+key)
+				}
+				continue
+			}
+			keys = append(keys, folder+key)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// wildcardChild returns the secret for one key matched by a wildcard key.
+// Its files are bound once its data is fetched, see bindDirectory; paths
+// holds the files written for it before, so they can be kept or removed.
+func wildcardChild(secret config.Secret, key string, paths []string) (config.Secret, error) {
+	if !filepath.IsLocal(filepath.FromSlash(key)) {
+		return config.Secret{}, fmt.Errorf("key %q cannot be used as a directory name", key)
+	}
+
+	prefix, _, _ := secret.WildcardPrefix()
+	dir := *secret.Directory
+	dir.Path = filepath.Join(secret.Directory.Path, filepath.FromSlash(key))
+
+	child := secret
+	child.Name = secret.Name + "/" + key
+	child.Key = prefix + key
+	child.Directory = &dir
+	child.Files = make([]config.File, 0, len(paths))
+	for _, path := range paths {
+		child.Files = append(child.Files, config.File{Path: path, Mode: dir.Mode, Owner: dir.Owner, Group: dir.Group})
+	}
+	return child, nil
+}
+
+// bindDirectory derives the files of a secret matched by a wildcard key
+// from its data: one file per template, or one file per field holding its
+// raw value if no templates are configured
+func bindDirectory(secret config.Secret, data vault.SecretData) (config.Secret, error) {
+	templates := secret.Template.Data
+	if len(templates) == 0 {
+		templates = make(map[string]string, len(data))
+		for field := range data {
+			templates[field] = fmt.Sprintf("{{ index . %q }}", field)
+		}
+	}
+
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		if !filepath.IsLocal(name) || strings.ContainsRune(name, filepath.Separator) {
+			return secret, errkind.Wrap(errkind.Template, fmt.Errorf("field %q cannot be used as a file name", name))
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	dir := secret.Directory
+	secret.Template = config.Template{Data: templates}
+	secret.Files = make([]config.File, 0, len(names))
+	for _, name := range names {
+		secret.Files = append(secret.Files, config.File{
+			Path:           filepath.Join(dir.Path, name),
+			Template:       name,
+			Mode:           dir.Mode,
+			Owner:          dir.Owner,
+			Group:          dir.Group,
+			DirMode:        dir.DirMode,
+			DirOwner:       dir.DirOwner,
+			DirGroup:       dir.DirGroup,
+			SELinuxContext: dir.SELinuxContext,
+			Checksum:       dir.Checksum,
+		})
+	}
+	return secret, nil
+}
+
+// wildcardKnown returns a copy of the files written per key matched by a
+// wildcard secret
+func (s *SecretSyncer) wildcardKnown(name string) map[string][]string {
+	s.wildcardMu.Lock()
+	defer s.wildcardMu.Unlock()
+
+	known := make(map[string][]string, len(s.wildcardFiles[name]))
+	for key, paths := range s.wildcardFiles[name] {
+		known[key] = append([]string(nil), paths...)
+	}
+	return known
+}
+
+// setWildcardFiles records the files written for a key matched by a
+// wildcard secret; nil forgets the key
+func (s *SecretSyncer) setWildcardFiles(name, key string, paths []string) {
+	s.wildcardMu.Lock()
+	defer s.wildcardMu.Unlock()
+
+	if paths == nil {
+		delete(s.wildcardFiles[name], key)
+		return
+	}
+	if s.wildcardFiles[name] == nil {
+		s.wildcardFiles[name] = make(map[string][]string)
+	}
+	s.wildcardFiles[name][key] = paths
+}
@@ -0,0 +1,273 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ohauer/secrets-sync/internal/config"
+	"github.com/ohauer/secrets-sync/internal/vault"
+)
+
+// wildcardVault is a KV v2 mount "secret" serving lists and reads from a
+// mutable set of secrets
+type wildcardVault struct {
+	mu      sync.Mutex
+	secrets map[string]map[string]string
+	down    atomic.Bool
+}
+
+func (v *wildcardVault) set(key string, data map[string]string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if data == nil {
+		delete(v.secrets, key)
+		return
+	}
+	v.secrets[key] = data
+}
+
+func (v *wildcardVault) handle(w http.ResponseWriter, r *http.Request) {
+	if v.down.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if folder, ok := strings.CutPrefix(r.URL.Path, "/v1/secret/metadata/"); ok || r.URL.Path == "/v1/secret/metadata" {
+		if folder != "" {
+			folder = strings.TrimSuffix(folder, "/") + "/"
+		}
+		seen := make(map[string]bool)
+		var keys []string
+		for key := range v.secrets {
+			rest, ok := strings.CutPrefix(key, folder)
+			if !ok {
+				continue
+			}
+			if i := strings.Index(rest, "/"); i >= 0 {
+				rest = rest[:i+1]
+			}
+			if !seen[rest] {
+				seen[rest] = true
+				keys = append(keys, rest)
+			}
+		}
+		if len(keys) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		sort.Strings(keys)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"keys": keys}})
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/v1/secret/data/")
+	data, ok := v.secrets[key]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": data}})
+}
+
+func newWildcardSyncer(t *testing.T) (*SecretSyncer, *wildcardVault) {
+	t.Helper()
+
+	v := &wildcardVault{secrets: map[string]map[string]string{
+		"apps/db":       {"username": "db-user", "password": "db-pass"},
+		"apps/web":      {"username": "web-user", "password": "web-pass"},
+		"apps/team/api": {"username": "api-user", "password": "api-pass"},
+		"other/secret":  {"username": "other"},
+	}}
+	server := httptest.NewServer(http.HandlerFunc(v.handle))
+	t.Cleanup(server.Close)
+
+	client, err := vault.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	client.GetAPIClient().SetMaxRetries(0)
+
+	return NewSecretSyncer(createTestFactory(client), vault.RetryConfig{MaxRetries: 0}), v
+}
+
+func wildcardSecret(key, dir string) config.Secret {
+	return config.Secret{
+		Name:      "apps",
+		Key:       key,
+		MountPath: "secret",
+		KVVersion: "v2",
+		Directory: &config.Directory{Path: dir, Mode: "0600"},
+	}
+}
+
+func assertFileContent(t *testing.T, path, want string) {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	if string(content) != want {
+		t.Errorf("expected %s to contain %q, got %q", path, want, content)
+	}
+}
+
+func TestSyncWildcard_FilePerField(t *testing.T) {
+	syncer, _ := newWildcardSyncer(t)
+	dir := t.TempDir()
+
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), wildcardSecret("apps/*", dir)); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	assertFileContent(t, filepath.Join(dir, "db", "username"), "db-user")
+	assertFileContent(t, filepath.Join(dir, "db", "password"), "db-pass")
+	assertFileContent(t, filepath.Join(dir, "web", "username"), "web-user")
+	if _, err := os.Stat(filepath.Join(dir, "team")); !os.IsNotExist(err) {
+		t.Error("single-level wildcard must not descend into folders")
+	}
+}
+
+func TestSyncWildcard_RecursiveWithTemplates(t *testing.T) {
+	syncer, _ := newWildcardSyncer(t)
+	dir := t.TempDir()
+
+	secret := wildcardSecret("apps/**", dir)
+	secret.Template = config.Template{Data: map[string]string{"credentials": "{{ .username }}:{{ .password }}"}}
+
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	assertFileContent(t, filepath.Join(dir, "db", "credentials"), "db-user:db-pass")
+	assertFileContent(t, filepath.Join(dir, "team", "api", "credentials"), "api-user:api-pass")
+	if _, err := os.Stat(filepath.Join(dir, "db", "username")); !os.IsNotExist(err) {
+		t.Error("fields must not be written when templates are set")
+	}
+}
+
+func TestSyncWildcard_RemovesUnlisted(t *testing.T) {
+	syncer, v := newWildcardSyncer(t)
+	syncer.WithDeletionPolicy(DeletionRemove, "")
+	dir := t.TempDir()
+	secret := wildcardSecret("apps/*", dir)
+
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	v.set("apps/web", nil)
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "web", "username")); !os.IsNotExist(err) {
+		t.Error("expected files of the unlisted secret to be removed")
+	}
+	assertFileContent(t, filepath.Join(dir, "db", "username"), "db-user")
+}
+
+func TestSyncWildcard_KeepsUnlistedByDefault(t *testing.T) {
+	syncer, v := newWildcardSyncer(t)
+	dir := t.TempDir()
+	secret := wildcardSecret("apps/*", dir)
+
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	v.set("apps/web", nil)
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	assertFileContent(t, filepath.Join(dir, "web", "username"), "web-user")
+}
+
+func TestSyncWildcard_StaleWhenVaultDown(t *testing.T) {
+	syncer, v := newWildcardSyncer(t)
+	dir := t.TempDir()
+	secret := wildcardSecret("apps/*", dir)
+
+	// Nothing was matched yet, so there is nothing to keep
+	v.down.Store(true)
+	err := syncer.SyncSecret(context.Background(), createTestConfig(), secret)
+	var stale *StaleError
+	if err == nil || errors.As(err, &stale) {
+		t.Fatalf("expected listing error, got: %v", err)
+	}
+
+	v.down.Store(false)
+	if err := syncer.SyncSecret(context.Background(), createTestConfig(), secret); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	v.down.Store(true)
+	err = syncer.SyncSecret(context.Background(), createTestConfig(), secret)
+	if !errors.As(err, &stale) {
+		t.Fatalf("expected stale error, got: %v", err)
+	}
+	assertFileContent(t, filepath.Join(dir, "db", "username"), "db-user")
+}
+
+func TestSyncWildcard_PartialFailureIsNotStale(t *testing.T) {
+	syncer, v := newWildcardSyncer(t)
+	dir := t.TempDir()
+	secret := wildcardSecret("apps/*", dir)
+
+	// A field name escaping the directory fails only that secret
+	v.set("apps/web", map[string]string{"../escape": "value"})
+
+	err := syncer.SyncSecret(context.Background(), createTestConfig(), secret)
+	var stale *StaleError
+	if err == nil || errors.As(err, &stale) {
+		t.Fatalf("expected hard failure, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "1 of 2 secrets") {
+		t.Errorf("expected failure count in error, got: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape")); !os.IsNotExist(err) {
+		t.Error("field name must not escape the secret's directory")
+	}
+	assertFileContent(t, filepath.Join(dir, "db", "username"), "db-user")
+}
+
+func TestPlan_Wildcard(t *testing.T) {
+	syncer, _ := newWildcardSyncer(t)
+	dir := t.TempDir()
+
+	cfg := createTestConfig()
+	cfg.Secrets = []config.Secret{wildcardSecret("apps/*", dir)}
+
+	plan, err := syncer.Plan(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	defer plan.Wipe()
+
+	if got := plan.Count(ActionCreate); got != 4 {
+		t.Errorf("expected 4 creates, got %+v", plan.Changes)
+	}
+	for _, c := range plan.Changes {
+		if !strings.HasPrefix(c.Secret, "apps/") {
+			t.Errorf("expected change to name the matched secret, got %q", c.Secret)
+		}
+	}
+
+	if err := syncer.Apply(context.Background(), plan); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	assertFileContent(t, filepath.Join(dir, "web", "password"), "web-pass")
+}
@@ -5,13 +5,20 @@ import (
 	"fmt"
 	"strings"
 	"text/template"
+
+	"github.com/ohauer/secrets-sync/internal/errkind"
 )
 
 // Engine handles template rendering
 type Engine struct {
 	templates map[string]*template.Template
+	decrypt   Decrypter // Backs transitDecrypt, nil if unavailable
 }
 
+// Decrypter decrypts a Vault transit ciphertext with the named key, given
+// as <key> or <mount>/<key>
+type Decrypter func(key, ciphertext string) (string, error)
+
 // NewEngine creates a new template engine
 func NewEngine() *Engine {
 	return &Engine{
@@ -19,34 +26,70 @@ func NewEngine() *Engine {
 	}
 }
 
+// WithDecrypter makes transitDecrypt decrypt with fn in templates added
+// afterwards
+func (e *Engine) WithDecrypter(fn Decrypter) *Engine {
+	e.decrypt = fn
+	return e
+}
+
 // AddTemplate adds a template with the given name
 func (e *Engine) AddTemplate(name, tmpl string) error {
+	funcs := funcMap()
+	if e.decrypt != nil {
+		funcs["transitDecrypt"] = e.decrypt
+	}
+
 	// Sanitize template name - Go templates don't allow hyphens in names
 	// Use the name as-is for lookup, but sanitize for template.New()
 	safeName := strings.ReplaceAll(name, "-", "_")
-	t, err := template.New(safeName).Parse(tmpl)
+	t, err := template.New(safeName).Funcs(funcs).Parse(tmpl)
 	if err != nil {
-		return fmt.Errorf("failed to parse template %s: %w", name, err)
+		return errkind.Wrap(errkind.Template, fmt.Errorf("failed to parse template %s: %w", name, err))
 	}
 	e.templates[name] = t
 	return nil
 }
 
+// Check parses a template with the available functions without adding it
+func Check(tmpl string) error {
+	if _, err := template.New("check").Funcs(funcMap()).Parse(tmpl); err != nil {
+		return errkind.Wrap(errkind.Template, err)
+	}
+	return nil
+}
+
 // Render renders a template with the given data
 func (e *Engine) Render(name string, data map[string]interface{}) (string, error) {
 	t, ok := e.templates[name]
 	if !ok {
-		return "", fmt.Errorf("template not found: %s", name)
+		return "", errkind.Wrap(errkind.Template, fmt.Errorf("template not found: %s", name))
 	}
 
 	var buf bytes.Buffer
 	if err := t.Execute(&buf, data); err != nil {
-		return "", fmt.Errorf("failed to render template %s: %w", name, err)
+		return "", errkind.Wrap(errkind.Template, fmt.Errorf("failed to render template %s: %w", name, err))
 	}
 
 	return buf.String(), nil
 }
 
+// RenderBytes renders a template into a byte slice owned by the caller, who
+// can wipe it once the content is no longer needed
+func (e *Engine) RenderBytes(name string, data map[string]interface{}) ([]byte, error) {
+	t, ok := e.templates[name]
+	if !ok {
+		return nil, errkind.Wrap(errkind.Template, fmt.Errorf("template not found: %s", name))
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, errkind.Wrap(errkind.Template, fmt.Errorf("failed to render template %s: %w", name, err))
+	}
+
+	return buf.Bytes(), nil
+}
+
 // RenderAll renders all templates with the given data
 func (e *Engine) RenderAll(data map[string]interface{}) (map[string]string, error) {
 	results := make(map[string]string)
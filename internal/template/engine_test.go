@@ -1,7 +1,10 @@
 package template
 
 import (
+	"errors"
 	"testing"
+
+	"github.com/ohauer/secrets-sync/internal/errkind"
 )
 
 func TestNewEngine(t *testing.T) {
@@ -28,6 +31,9 @@ func TestAddTemplate_Invalid(t *testing.T) {
 	if err == nil {
 		t.Error("expected error for invalid template, got nil")
 	}
+	if !errors.Is(err, errkind.Template) {
+		t.Errorf("expected template error kind, got %s", errkind.Of(err))
+	}
 }
 
 func TestRender_Success(t *testing.T) {
@@ -153,3 +159,64 @@ MIICljCCAX4CCQCKz8Zr8vJKZDANBg
 		t.Errorf("expected:\n%s\ngot:\n%s", expected, result)
 	}
 }
+
+func TestRenderBytes_Success(t *testing.T) {
+	engine := NewEngine()
+	_ = engine.AddTemplate("greeting", "Hello {{ .name }}!")
+
+	result, err := engine.RenderBytes("greeting", map[string]interface{}{"name": "World"})
+	if err != nil {
+		t.Fatalf("failed to render: %v", err)
+	}
+
+	if string(result) != "Hello World!" {
+		t.Errorf("expected 'Hello World!', got '%s'", string(result))
+	}
+}
+
+func TestRenderBytes_NotFound(t *testing.T) {
+	engine := NewEngine()
+	if _, err := engine.RenderBytes("missing", nil); err == nil {
+		t.Error("expected error for missing template, got nil")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if err := Check("{{ .value | b64dec | trim }}"); err != nil {
+		t.Errorf("expected supported functions to parse, got: %v", err)
+	}
+
+	err := Check("{{ .value | pkcs12key }}")
+	if err == nil {
+		t.Fatal("expected error for unknown function, got nil")
+	}
+	if !errors.Is(err, errkind.Template) {
+		t.Errorf("expected template error kind, got %s", errkind.Of(err))
+	}
+}
+
+func TestRender_TransitDecrypt(t *testing.T) {
+	data := map[string]interface{}{"blob": "vault:v1:abc"}
+
+	engine := NewEngine()
+	if err := engine.AddTemplate("test", `{{ .blob | transitDecrypt "app" }}`); err != nil {
+		t.Fatalf("failed to add template: %v", err)
+	}
+	if _, err := engine.Render("test", data); err == nil {
+		t.Error("expected error without a decrypter")
+	}
+
+	engine = NewEngine().WithDecrypter(func(key, ciphertext string) (string, error) {
+		return key + ":" + ciphertext, nil
+	})
+	if err := engine.AddTemplate("test", `{{ .blob | transitDecrypt "app" }}`); err != nil {
+		t.Fatalf("failed to add template: %v", err)
+	}
+	result, err := engine.Render("test", data)
+	if err != nil {
+		t.Fatalf("failed to render: %v", err)
+	}
+	if result != "app:vault:v1:abc" {
+		t.Errorf("unexpected result %q", result)
+	}
+}
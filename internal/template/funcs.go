@@ -0,0 +1,297 @@
+package template
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// funcMap returns the functions available to templates: a subset of the sprig
+// library that ExternalSecret templates commonly use, with the same names and
+// argument order. Functions reading the environment, the clock or random
+// sources are left out, so rendering stays deterministic and cannot leak
+// anything but the secret's own data. Unlike sprig, decoding functions fail
+// the render on invalid input instead of writing the error into the file.
+// transitDecrypt is the one function calling out, to Vault; it fails unless
+// the engine was given a Decrypter.
+func funcMap() template.FuncMap {
+	return template.FuncMap{
+		// Strings
+		"trim":       strings.TrimSpace,
+		"trimSpace":  strings.TrimSpace,
+		"trimAll":    func(cutset, s string) string { return strings.Trim(s, cutset) },
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"repeat":     func(count int, s string) string { return strings.Repeat(s, count) },
+		"nospace":    func(s string) string { return strings.Join(strings.Fields(s), "") },
+		"indent":     indent,
+		"nindent":    func(spaces int, s string) string { return "\n" + indent(spaces, s) },
+		"quote":      quote,
+		"squote":     squote,
+		"splitList":  func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":       join,
+		"toString":   toString,
+
+		// Encoding
+		"b64enc":       func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+		"b64dec":       b64dec,
+		"b32enc":       func(s string) string { return base32.StdEncoding.EncodeToString([]byte(s)) },
+		"b32dec":       b32dec,
+		"toJson":       toJSON,
+		"toPrettyJson": toPrettyJSON,
+		"fromJson":     fromJSON,
+		"toYaml":       toYAML,
+		"fromYaml":     fromYAML,
+		"sha256sum":    sha256sum,
+
+		// Defaults and flow
+		"default":  defaultValue,
+		"empty":    empty,
+		"coalesce": coalesce,
+		"ternary":  ternary,
+
+		// Lists and dictionaries
+		"list":   func(v ...interface{}) []interface{} { return v },
+		"dict":   dict,
+		"get":    func(d map[string]interface{}, key string) interface{} { return d[key] },
+		"hasKey": func(d map[string]interface{}, key string) bool { _, ok := d[key]; return ok },
+
+		// Vault
+		"transitDecrypt": func(key, ciphertext string) (string, error) {
+			return "", fmt.Errorf("transitDecrypt is not available without Vault")
+		},
+	}
+}
+
+// indent prefixes every line of s with the given number of spaces
+func indent(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+// quote returns each argument double-quoted, separated by spaces; nil
+// arguments are skipped
+func quote(v ...interface{}) string {
+	out := make([]string, 0, len(v))
+	for _, s := range v {
+		if s != nil {
+			out = append(out, fmt.Sprintf("%q", toString(s)))
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// squote returns each argument single-quoted, separated by spaces; nil
+// arguments are skipped
+func squote(v ...interface{}) string {
+	out := make([]string, 0, len(v))
+	for _, s := range v {
+		if s != nil {
+			out = append(out, "'"+toString(s)+"'")
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// join concatenates the elements of a list with sep
+func join(sep string, v interface{}) string {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+		return toString(v)
+	}
+	out := make([]string, 0, val.Len())
+	for i := 0; i < val.Len(); i++ {
+		if item := val.Index(i).Interface(); item != nil {
+			out = append(out, toString(item))
+		}
+	}
+	return strings.Join(out, sep)
+}
+
+// toString formats v as a string; byte slices are taken as text
+func toString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case []byte:
+		return string(s)
+	case error:
+		return s.Error()
+	case fmt.Stringer:
+		return s.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func b64dec(s string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", fmt.Errorf("b64dec: %w", err)
+	}
+	return string(decoded), nil
+}
+
+func b32dec(s string) (string, error) {
+	decoded, err := base32.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", fmt.Errorf("b32dec: %w", err)
+	}
+	return string(decoded), nil
+}
+
+func sha256sum(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func toJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("toJson: %w", err)
+	}
+	return string(data), nil
+}
+
+func toPrettyJSON(v interface{}) (string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("toPrettyJson: %w", err)
+	}
+	return string(data), nil
+}
+
+func fromJSON(s string) (interface{}, error) {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader([]byte(s)))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("fromJson: %w", err)
+	}
+	return v, nil
+}
+
+// toYAML encodes v as YAML with two-space indentation and without the
+// trailing newline, so it can be piped into indent or nindent
+func toYAML(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(yamlNumbers(v)); err != nil {
+		return "", fmt.Errorf("toYaml: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return "", fmt.Errorf("toYaml: %w", err)
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// yamlNumbers replaces the json.Number values fromJson returns with numbers,
+// which YAML would otherwise quote as strings
+func yamlNumbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		if f, err := val.Float64(); err == nil {
+			return f
+		}
+		return val.String()
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = yamlNumbers(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = yamlNumbers(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func fromYAML(s string) (interface{}, error) {
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(s), &v); err != nil {
+		return nil, fmt.Errorf("fromYaml: %w", err)
+	}
+	return v, nil
+}
+
+// defaultValue returns given if it is set and not empty, d otherwise. It is
+// meant to be piped into: {{ .port | default "5432" }}
+func defaultValue(d interface{}, given ...interface{}) interface{} {
+	if len(given) == 0 || empty(given[0]) {
+		return d
+	}
+	return given[0]
+}
+
+// empty reports whether v is nil or the zero value of its type; strings,
+// slices and maps are empty without elements
+func empty(v interface{}) bool {
+	val := reflect.ValueOf(v)
+	if !val.IsValid() {
+		return true
+	}
+	switch val.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return val.Len() == 0
+	case reflect.Bool:
+		return !val.Bool()
+	case reflect.Interface, reflect.Pointer:
+		return val.IsNil()
+	default:
+		return val.IsZero()
+	}
+}
+
+// coalesce returns the first argument that is not empty
+func coalesce(v ...interface{}) interface{} {
+	for _, item := range v {
+		if !empty(item) {
+			return item
+		}
+	}
+	return nil
+}
+
+// ternary returns yes if cond is true and no otherwise
+func ternary(yes, no interface{}, cond bool) interface{} {
+	if cond {
+		return yes
+	}
+	return no
+}
+
+// dict builds a map from alternating keys and values
+func dict(v ...interface{}) (map[string]interface{}, error) {
+	if len(v)%2 != 0 {
+		return nil, fmt.Errorf("dict: odd number of arguments")
+	}
+	d := make(map[string]interface{}, len(v)/2)
+	for i := 0; i < len(v); i += 2 {
+		d[toString(v[i])] = v[i+1]
+	}
+	return d, nil
+}
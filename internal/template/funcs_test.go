@@ -0,0 +1,109 @@
+package template
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ohauer/secrets-sync/internal/errkind"
+)
+
+func TestFuncs(t *testing.T) {
+	data := map[string]interface{}{
+		"user":    "admin",
+		"encoded": "c2VjcmV0",
+		"cert":    "line1\nline2",
+		"padded":  "  value \n",
+		"empty":   "",
+		"port":    5432,
+		"json":    `{"host": "db", "port": 5432}`,
+	}
+
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{`{{ .encoded | b64dec }}`, "secret"},
+		{`{{ .user | b64enc }}`, "YWRtaW4="},
+		{`{{ .user | b32enc | b32dec }}`, "admin"},
+		{`{{ .padded | trim }}`, "value"},
+		{`{{ .padded | trimSpace }}`, "value"},
+		{`{{ .user | upper }}`, "ADMIN"},
+		{`{{ .user | trimPrefix "ad" }}`, "min"},
+		{`{{ .user | replace "admin" "root" }}`, "root"},
+		{`{{ .user | quote }}`, `"admin"`},
+		{`{{ .user | squote }}`, `'admin'`},
+		{`{{ .cert | indent 2 }}`, "  line1\n  line2"},
+		{`key:{{ .cert | nindent 2 }}`, "key:\n  line1\n  line2"},
+		{`{{ .missing | default "fallback" }}`, "fallback"},
+		{`{{ .empty | default "fallback" }}`, "fallback"},
+		{`{{ .user | default "fallback" }}`, "admin"},
+		{`{{ coalesce .missing .empty .user }}`, "admin"},
+		{`{{ ternary "yes" "no" (empty .empty) }}`, "yes"},
+		{`{{ dict "user" .user "port" .port | toJson }}`, `{"port":5432,"user":"admin"}`},
+		{`{{ (.json | fromJson).host }}`, "db"},
+		{`{{ dict "db" (dict "user" .user "port" .port) | toYaml }}`, "db:\n  port: 5432\n  user: admin"},
+		{`config:{{ .json | fromJson | toYaml | nindent 2 }}`, "config:\n  host: db\n  port: 5432"},
+		{`{{ ("user: admin\nport: 5432" | fromYaml).user }}`, "admin"},
+		{`{{ printf "{\"auths\":{\"r.io\":{\"auth\":%s}}}" (printf "%s:%s" .user .user | b64enc | quote) }}`, `{"auths":{"r.io":{"auth":"YWRtaW46YWRtaW4="}}}`},
+		{`{{ get (.json | fromJson) "port" }}`, "5432"},
+		{`{{ hasKey . "user" }}`, "true"},
+		{`{{ list "a" "b" .user | join "," }}`, "a,b,admin"},
+		{`{{ splitList "\n" .cert | join "," }}`, "line1,line2"},
+		{`{{ .user | sha256sum }}`, "8c6976e5b5410415bde908bd4dee15dfb167a9c873fc4bb8a81f6f2ab448a918"},
+		{`{{ .port | toString | quote }}`, `"5432"`},
+		{`{{ if contains "dm" .user }}match{{ end }}`, "match"},
+	}
+
+	for _, tt := range tests {
+		engine := NewEngine()
+		if err := engine.AddTemplate("test", tt.tmpl); err != nil {
+			t.Errorf("%s: failed to parse: %v", tt.tmpl, err)
+			continue
+		}
+		got, err := engine.Render("test", data)
+		if err != nil {
+			t.Errorf("%s: failed to render: %v", tt.tmpl, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.tmpl, tt.want, got)
+		}
+	}
+}
+
+func TestFuncs_DecodeError(t *testing.T) {
+	engine := NewEngine()
+	if err := engine.AddTemplate("test", "{{ .value | b64dec }}"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := engine.Render("test", map[string]interface{}{"value": "not base64!"})
+	if err == nil || !strings.Contains(err.Error(), "b64dec") {
+		t.Fatalf("expected b64dec error, got: %v", err)
+	}
+	if !errors.Is(err, errkind.Template) {
+		t.Errorf("expected template error kind, got %s", errkind.Of(err))
+	}
+}
+
+func TestFuncs_NoEnvironmentAccess(t *testing.T) {
+	for _, name := range []string{"env", "expandenv", "now", "randAlphaNum", "getHostByName"} {
+		engine := NewEngine()
+		if err := engine.AddTemplate("test", "{{ "+name+" }}"); err == nil {
+			t.Errorf("expected %s to be undefined", name)
+		}
+	}
+}
+
+func TestFuncs_FromYAMLError(t *testing.T) {
+	engine := NewEngine()
+	if err := engine.AddTemplate("test", "{{ .value | fromYaml }}"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := engine.Render("test", map[string]interface{}{"value": "key: [unterminated"})
+	if err == nil || !strings.Contains(err.Error(), "fromYaml") {
+		t.Fatalf("expected fromYaml error, got: %v", err)
+	}
+}
@@ -1,81 +1,280 @@
 package vault
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/vault/api"
+
+	"github.com/ohauer/secrets-sync/internal/errkind"
 )
 
 // AuthMethod represents the authentication method
 type AuthMethod string
 
 const (
-	AuthMethodToken   AuthMethod = "token"
-	AuthMethodAppRole AuthMethod = "approle"
+	AuthMethodToken      AuthMethod = "token"
+	AuthMethodTokenFile  AuthMethod = "tokenFile"
+	AuthMethodAppRole    AuthMethod = "approle"
+	AuthMethodKubernetes AuthMethod = "kubernetes"
+	AuthMethodCert       AuthMethod = "cert"
+)
+
+const (
+	// DefaultKubernetesTokenPath is where Kubernetes mounts the service account token
+	DefaultKubernetesTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	// DefaultKubernetesMountPath is the default mount of the kubernetes auth method
+	DefaultKubernetesMountPath = "kubernetes"
+	// DefaultCertMountPath is the default mount of the cert auth method
+	DefaultCertMountPath = "cert"
 )
 
 // AuthConfig holds authentication configuration
 type AuthConfig struct {
-	Method   AuthMethod
-	Token    string
-	RoleID   string
-	SecretID string
+	Method          AuthMethod
+	Token           string
+	TokenFile       string // Token file for AuthMethodTokenFile, e.g. a Vault Agent sink
+	RoleID          string
+	SecretID        string
+	SecretIDFile    string // Read on every login instead of SecretID (optional)
+	SecretIDWrapped bool   // SecretID is a response-wrapping token to unwrap
+
+	KubernetesRole      string
+	KubernetesTokenPath string // Defaults to DefaultKubernetesTokenPath
+	KubernetesMountPath string // Defaults to DefaultKubernetesMountPath
+
+	CertRole      string // Certificate role to log in with; any matching role if empty
+	CertMountPath string // Defaults to DefaultCertMountPath
+
+	Namespace string // Namespace to log in and look up the token in, root if empty
 }
 
 // Authenticate authenticates the client with Vault
-func (c *Client) Authenticate(config AuthConfig) error {
+func (c *Client) Authenticate(ctx context.Context, config AuthConfig) error {
+	c.authNamespace = config.Namespace
 	switch config.Method {
 	case AuthMethodToken:
-		return c.authenticateToken(config.Token)
+		return c.authenticateToken(ctx, config.Token)
+	case AuthMethodTokenFile:
+		return c.authenticateTokenFile(ctx, config.TokenFile)
 	case AuthMethodAppRole:
-		return c.authenticateAppRole(config.RoleID, config.SecretID)
+		return c.authenticateAppRole(ctx, config)
+	case AuthMethodKubernetes:
+		return c.authenticateKubernetes(ctx, config.KubernetesRole, config.KubernetesTokenPath, config.KubernetesMountPath)
+	case AuthMethodCert:
+		return c.authenticateCert(ctx, config.CertRole, config.CertMountPath)
 	default:
-		return fmt.Errorf("unsupported auth method: %s", config.Method)
+		return errkind.Wrap(errkind.Auth, fmt.Errorf("unsupported auth method: %s", config.Method))
 	}
 }
 
-func (c *Client) authenticateToken(token string) error {
+func (c *Client) authenticateToken(ctx context.Context, token string) error {
 	if token == "" {
-		return fmt.Errorf("token is required")
+		return errkind.Wrap(errkind.Auth, fmt.Errorf("token is required"))
 	}
 
-	c.client.SetToken(token)
+	c.setToken(token)
 
 	_, err := c.executeWithBreaker(func() (interface{}, error) {
-		return c.client.Auth().Token().LookupSelf()
+		return c.traced(ctx, c.authNamespace).Auth().Token().LookupSelfWithContext(ctx)
 	})
 	if err != nil {
-		return fmt.Errorf("token authentication failed: %w", err)
+		return authError("token authentication failed", err)
 	}
 
 	return nil
 }
 
-func (c *Client) authenticateAppRole(roleID, secretID string) error {
-	if roleID == "" {
-		return fmt.Errorf("roleId is required")
+func (c *Client) authenticateAppRole(ctx context.Context, config AuthConfig) error {
+	if config.RoleID == "" {
+		return errkind.Wrap(errkind.Auth, fmt.Errorf("roleId is required"))
+	}
+
+	secretID := config.SecretID
+	if config.SecretIDFile != "" {
+		content, err := os.ReadFile(config.SecretIDFile)
+		if err != nil {
+			return errkind.Wrap(errkind.Auth, fmt.Errorf("failed to read secret ID: %w", err))
+		}
+		secretID = strings.TrimSpace(string(content))
 	}
 	if secretID == "" {
-		return fmt.Errorf("secretId is required")
+		return errkind.Wrap(errkind.Auth, fmt.Errorf("secretId is required"))
 	}
 
+	if config.SecretIDWrapped {
+		unwrapped, err := c.unwrapSecretID(ctx, secretID)
+		if err != nil {
+			return err
+		}
+		secretID = unwrapped
+	}
+	c.observeCredential(secretID)
+
 	data := map[string]interface{}{
-		"role_id":   roleID,
+		"role_id":   config.RoleID,
 		"secret_id": secretID,
 	}
 
 	result, err := c.executeWithBreaker(func() (interface{}, error) {
-		return c.client.Logical().Write("auth/approle/login", data)
+		return c.traced(ctx, c.authNamespace).Logical().WriteWithContext(ctx, "auth/approle/login", data)
+	})
+	if err != nil {
+		return authError("approle authentication failed", err)
+	}
+
+	resp, ok := result.(*api.Secret)
+	if !ok || resp == nil || resp.Auth == nil {
+		return errkind.Wrap(errkind.Auth, fmt.Errorf("approle authentication returned no token"))
+	}
+
+	c.setToken(resp.Auth.ClientToken)
+	return nil
+}
+
+// authenticateKubernetes logs in with the pod's service account token. The
+// token is read on every login, as projected tokens are rotated by the kubelet.
+func (c *Client) authenticateKubernetes(ctx context.Context, role, tokenPath, mountPath string) error {
+	if role == "" {
+		return errkind.Wrap(errkind.Auth, fmt.Errorf("kubernetesRole is required"))
+	}
+	if tokenPath == "" {
+		tokenPath = DefaultKubernetesTokenPath
+	}
+	if mountPath == "" {
+		mountPath = DefaultKubernetesMountPath
+	}
+
+	jwt, err := os.ReadFile(tokenPath)
+	if err != nil {
+		return errkind.Wrap(errkind.Auth, fmt.Errorf("failed to read service account token: %w", err))
+	}
+	token := strings.TrimSpace(string(jwt))
+	if token == "" {
+		return errkind.Wrap(errkind.Auth, fmt.Errorf("service account token %s is empty", tokenPath))
+	}
+	c.observeCredential(token)
+
+	data := map[string]interface{}{
+		"role": role,
+		"jwt":  token,
+	}
+
+	result, err := c.executeWithBreaker(func() (interface{}, error) {
+		return c.traced(ctx, c.authNamespace).Logical().WriteWithContext(ctx, "auth/"+mountPath+"/login", data)
+	})
+	if err != nil {
+		return authError("kubernetes authentication failed", err)
+	}
+
+	resp, ok := result.(*api.Secret)
+	if !ok || resp == nil || resp.Auth == nil {
+		return errkind.Wrap(errkind.Auth, fmt.Errorf("kubernetes authentication returned no token"))
+	}
+
+	c.setToken(resp.Auth.ClientToken)
+	return nil
+}
+
+// authenticateCert logs in with the TLS client certificate the client was
+// created with; Vault identifies the caller by the certificate presented
+// during the TLS handshake.
+func (c *Client) authenticateCert(ctx context.Context, role, mountPath string) error {
+	if !c.hasClientCert {
+		return errkind.Wrap(errkind.Auth, fmt.Errorf("cert auth requires a client certificate (tlsClientCert or VAULT_CLIENT_CERT)"))
+	}
+	if mountPath == "" {
+		mountPath = DefaultCertMountPath
+	}
+
+	data := map[string]interface{}{}
+	if role != "" {
+		data["name"] = role
+	}
+
+	result, err := c.executeWithBreaker(func() (interface{}, error) {
+		return c.traced(ctx, c.authNamespace).Logical().WriteWithContext(ctx, "auth/"+mountPath+"/login", data)
 	})
 	if err != nil {
-		return fmt.Errorf("approle authentication failed: %w", err)
+		return authError("cert authentication failed", err)
 	}
 
 	resp, ok := result.(*api.Secret)
 	if !ok || resp == nil || resp.Auth == nil {
-		return fmt.Errorf("approle authentication returned no token")
+		return errkind.Wrap(errkind.Auth, fmt.Errorf("cert authentication returned no token"))
 	}
 
-	c.client.SetToken(resp.Auth.ClientToken)
+	c.setToken(resp.Auth.ClientToken)
 	return nil
 }
+
+// WithCredentialObserver registers a callback told every token and secret ID
+// the client logs in or authenticates with, e.g. to redact them from logs
+func (c *Client) WithCredentialObserver(fn func(value string)) {
+	c.onCredential = fn
+}
+
+// setToken sets the token of the client and passes it to the observer
+func (c *Client) setToken(token string) {
+	c.client.SetToken(token)
+	c.observeCredential(token)
+}
+
+// observeCredential passes a credential to the observer, if any
+func (c *Client) observeCredential(value string) {
+	if c.onCredential != nil && value != "" {
+		c.onCredential(value)
+	}
+}
+
+// TokenValid reports whether Vault still accepts the client's token, e.g.
+// to tell an expired token from a policy denying access
+func (c *Client) TokenValid(ctx context.Context) (bool, error) {
+	_, err := c.executeWithBreaker(func() (interface{}, error) {
+		return c.traced(ctx, c.authNamespace).Auth().Token().LookupSelfWithContext(ctx)
+	})
+	if err == nil {
+		return true, nil
+	}
+	if rejected(err) {
+		return false, nil
+	}
+	return false, classify(fmt.Errorf("token lookup failed: %w", err))
+}
+
+// TokenInfo describes the client's token
+type TokenInfo struct {
+	TTL       time.Duration // Zero if the token does not expire
+	Renewable bool
+	Policies  []string
+}
+
+// LookupToken returns the remaining TTL, renewability and policies of the
+// client's token
+func (c *Client) LookupToken(ctx context.Context) (TokenInfo, error) {
+	result, err := c.executeWithBreaker(func() (interface{}, error) {
+		return c.traced(ctx, c.authNamespace).Auth().Token().LookupSelfWithContext(ctx)
+	})
+	if err != nil {
+		return TokenInfo{}, classify(fmt.Errorf("token lookup failed: %w", err))
+	}
+	secret, ok := result.(*api.Secret)
+	if !ok || secret == nil {
+		return TokenInfo{}, fmt.Errorf("invalid token lookup response")
+	}
+
+	var info TokenInfo
+	if info.TTL, err = secret.TokenTTL(); err != nil {
+		return TokenInfo{}, fmt.Errorf("invalid token TTL: %w", err)
+	}
+	if info.Renewable, err = secret.TokenIsRenewable(); err != nil {
+		return TokenInfo{}, fmt.Errorf("invalid token renewable flag: %w", err)
+	}
+	if info.Policies, err = secret.TokenPolicies(); err != nil {
+		return TokenInfo{}, fmt.Errorf("invalid token policies: %w", err)
+	}
+	return info, nil
+}
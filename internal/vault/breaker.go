@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -25,6 +26,10 @@ func (c *Client) WithCircuitBreaker(config BreakerConfig, onStateChange func(str
 			failureRatio := float64(counts.TotalFailures) / float64(counts.Requests)
 			return counts.Requests >= 3 && failureRatio >= 0.6
 		},
+		// A sealed Vault pauses syncing instead of tripping the breaker
+		IsSuccessful: func(err error) bool {
+			return err == nil || errors.Is(err, ErrSealed)
+		},
 		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
 			if onStateChange != nil {
 				onStateChange(from.String(), to.String())
@@ -35,8 +40,11 @@ func (c *Client) WithCircuitBreaker(config BreakerConfig, onStateChange func(str
 	c.breaker = gobreaker.NewCircuitBreaker(settings)
 }
 
-// executeWithBreaker executes a function with circuit breaker protection
+// executeWithBreaker executes a function with circuit breaker protection,
+// failing over to another endpoint when the active one is unreachable and
+// picking up a changed token file
 func (c *Client) executeWithBreaker(fn func() (interface{}, error)) (interface{}, error) {
+	fn = c.withTokenFile(c.failover(fn))
 	if c.breaker == nil {
 		return fn()
 	}
@@ -47,3 +55,12 @@ func (c *Client) executeWithBreaker(fn func() (interface{}, error)) (interface{}
 	}
 	return result, nil
 }
+
+// BreakerState returns the circuit breaker state: closed, half-open, open,
+// or disabled when no breaker is configured
+func (c *Client) BreakerState() string {
+	if c.breaker == nil {
+		return "disabled"
+	}
+	return c.breaker.State().String()
+}
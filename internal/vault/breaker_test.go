@@ -175,3 +175,28 @@ func TestCircuitBreaker_StateTransitions(t *testing.T) {
 		t.Error("expected state changes, got none")
 	}
 }
+
+func TestBreakerState(t *testing.T) {
+	client, err := NewClient("http://localhost:8200")
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	if state := client.BreakerState(); state != "disabled" {
+		t.Errorf("expected disabled without breaker, got %s", state)
+	}
+
+	client.WithCircuitBreaker(BreakerConfig{MaxRequests: 1, Interval: time.Second, Timeout: time.Minute}, nil)
+	if state := client.BreakerState(); state != "closed" {
+		t.Errorf("expected closed, got %s", state)
+	}
+
+	for i := 0; i < 5; i++ {
+		_, _ = client.executeWithBreaker(func() (interface{}, error) {
+			return nil, errors.New("test error")
+		})
+	}
+	if state := client.BreakerState(); state != "open" {
+		t.Errorf("expected open after failures, got %s", state)
+	}
+}
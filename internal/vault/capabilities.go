@@ -0,0 +1,23 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+)
+
+// Capabilities returns the capabilities the client's token has on a path in
+// namespace, as reported by sys/capabilities-self, e.g. read and list, or
+// deny if it has none
+func (c *Client) Capabilities(ctx context.Context, fullPath, namespace string) ([]string, error) {
+	result, err := c.executeWithBreaker(func() (interface{}, error) {
+		return c.traced(ctx, namespace).Sys().CapabilitiesSelfWithContext(ctx, fullPath)
+	})
+	if err != nil {
+		return nil, classify(fmt.Errorf("capabilities lookup failed for %s: %w", fullPath, err))
+	}
+	capabilities, ok := result.([]string)
+	if !ok {
+		return nil, fmt.Errorf("invalid capabilities response")
+	}
+	return capabilities, nil
+}
@@ -0,0 +1,78 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/sys/capabilities-self" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if ns := r.Header.Get("X-Vault-Namespace"); ns != "team-a" {
+			t.Errorf("expected namespace team-a, got %q", ns)
+		}
+		var body struct {
+			Path string `json:"path"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusOK)
+		if body.Path == "secret/data/app" {
+			_, _ = w.Write([]byte(`{"data": {"secret/data/app": ["read", "list"]}}`))
+		} else {
+			_, _ = w.Write([]byte(`{"data": {"capabilities": ["deny"]}}`))
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	client.GetAPIClient().SetToken("test-token")
+
+	capabilities, err := client.Capabilities(context.Background(), "secret/data/app", "team-a")
+	if err != nil {
+		t.Fatalf("capabilities failed: %v", err)
+	}
+	if len(capabilities) != 2 || capabilities[0] != "read" || capabilities[1] != "list" {
+		t.Errorf("unexpected capabilities: %v", capabilities)
+	}
+
+	capabilities, err = client.Capabilities(context.Background(), "secret/data/other", "team-a")
+	if err != nil {
+		t.Fatalf("capabilities failed: %v", err)
+	}
+	if len(capabilities) != 1 || capabilities[0] != "deny" {
+		t.Errorf("expected deny, got %v", capabilities)
+	}
+}
+
+func TestLookupToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/token/lookup-self" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"data": {"ttl": 3600, "renewable": true, "policies": ["default", "app"]}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	client.GetAPIClient().SetToken("test-token")
+
+	info, err := client.LookupToken(context.Background())
+	if err != nil {
+		t.Fatalf("lookup failed: %v", err)
+	}
+	if info.TTL.Seconds() != 3600 || !info.Renewable || len(info.Policies) != 2 {
+		t.Errorf("unexpected token info: %+v", info)
+	}
+}
@@ -1,9 +1,11 @@
 package vault
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 
 	"github.com/hashicorp/vault/api"
 	"github.com/sony/gobreaker"
@@ -25,8 +27,21 @@ type TLSConfig struct {
 
 // Client wraps the Vault API client
 type Client struct {
-	client  *api.Client
-	breaker *gobreaker.CircuitBreaker
+	client        *api.Client
+	breaker       *gobreaker.CircuitBreaker
+	hasClientCert bool // Whether a TLS client certificate is presented, for cert auth
+
+	// Failover
+	endpoints  []string // Addresses tried in order when the active one is unreachable
+	onFailover func(from, to string)
+	failoverMu sync.Mutex
+
+	tokenFile     *tokenFile // Set with tokenFile auth
+	authNamespace string     // Namespace to log in and look up the token in, root if empty
+	throttle      *throttle  // Retry-After of the last rate-limited response
+
+	onFetch      FetchObserver // Times every attempt to read a KV secret
+	onCredential func(string)  // Told every token and secret ID the client uses
 }
 
 // NewClient creates a new Vault client
@@ -36,6 +51,12 @@ func NewClient(address string) (*Client, error) {
 
 // NewClientWithTLS creates a new Vault client with TLS configuration
 func NewClientWithTLS(address string, tlsConfig *TLSConfig) (*Client, error) {
+	return NewClientWithConnection(address, tlsConfig, nil)
+}
+
+// NewClientWithConnection creates a new Vault client with TLS and connection
+// configuration, either of which may be nil
+func NewClientWithConnection(address string, tlsConfig *TLSConfig, conn *ConnectionConfig) (*Client, error) {
 	config := api.DefaultConfig()
 	config.Address = address
 
@@ -44,6 +65,20 @@ func NewClientWithTLS(address string, tlsConfig *TLSConfig) (*Client, error) {
 			return nil, fmt.Errorf("failed to configure TLS: %w", err)
 		}
 	}
+	if conn != nil {
+		if err := configureConnection(config, conn); err != nil {
+			return nil, fmt.Errorf("failed to configure connection: %w", err)
+		}
+	}
+
+	// Every response passes the retry check, which notes Retry-After
+	throttle := &throttle{}
+	config.CheckRetry = func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		if resp != nil {
+			throttle.observe(resp)
+		}
+		return api.DefaultRetryPolicy(ctx, resp, err)
+	}
 
 	client, err := api.NewClient(config)
 	if err != nil {
@@ -57,7 +92,11 @@ func NewClientWithTLS(address string, tlsConfig *TLSConfig) (*Client, error) {
 		maxBytes: MaxResponseSize,
 	}
 
-	return &Client{client: client}, nil
+	return &Client{
+		client:        client,
+		hasClientCert: tlsConfig != nil && tlsConfig.ClientCert != "",
+		throttle:      throttle,
+	}, nil
 }
 
 func configureTLS(config *api.Config, tlsConfig *TLSConfig) error {
@@ -90,12 +129,12 @@ func (c *Client) GetAPIClient() *api.Client {
 }
 
 // Ping checks if the Vault server is reachable
-func (c *Client) Ping() error {
+func (c *Client) Ping(ctx context.Context) error {
 	_, err := c.executeWithBreaker(func() (interface{}, error) {
-		return c.client.Sys().Health()
+		return c.client.Sys().HealthWithContext(ctx)
 	})
 	if err != nil {
-		return fmt.Errorf("vault health check failed: %w", err)
+		return classify(fmt.Errorf("vault health check failed: %w", err))
 	}
 	return nil
 }
@@ -1,9 +1,26 @@
 package vault
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/errkind"
 )
 
 func TestNewClient(t *testing.T) {
@@ -37,7 +54,7 @@ func TestClient_Ping(t *testing.T) {
 		t.Fatalf("failed to create client: %v", err)
 	}
 
-	if err := client.Ping(); err != nil {
+	if err := client.Ping(context.Background()); err != nil {
 		t.Errorf("ping failed: %v", err)
 	}
 }
@@ -63,7 +80,7 @@ func TestClient_AuthenticateToken_Success(t *testing.T) {
 		Token:  "test-token",
 	}
 
-	if err := client.Authenticate(config); err != nil {
+	if err := client.Authenticate(context.Background(), config); err != nil {
 		t.Errorf("token authentication failed: %v", err)
 	}
 }
@@ -79,7 +96,7 @@ func TestClient_AuthenticateToken_Empty(t *testing.T) {
 		Token:  "",
 	}
 
-	if err := client.Authenticate(config); err == nil {
+	if err := client.Authenticate(context.Background(), config); err == nil {
 		t.Error("expected error for empty token, got nil")
 	}
 }
@@ -100,19 +117,25 @@ func TestClient_AuthenticateAppRole_Success(t *testing.T) {
 		t.Fatalf("failed to create client: %v", err)
 	}
 
+	var credentials []string
+	client.WithCredentialObserver(func(value string) { credentials = append(credentials, value) })
+
 	config := AuthConfig{
 		Method:   AuthMethodAppRole,
 		RoleID:   "test-role-id",
 		SecretID: "test-secret-id",
 	}
 
-	if err := client.Authenticate(config); err != nil {
+	if err := client.Authenticate(context.Background(), config); err != nil {
 		t.Errorf("approle authentication failed: %v", err)
 	}
 
 	if client.GetAPIClient().Token() != "test-token" {
 		t.Errorf("expected token 'test-token', got: %s", client.GetAPIClient().Token())
 	}
+	if strings.Join(credentials, ",") != "test-secret-id,test-token" {
+		t.Errorf("expected the secret ID and token to be observed, got %v", credentials)
+	}
 }
 
 func TestClient_AuthenticateAppRole_MissingCredentials(t *testing.T) {
@@ -139,13 +162,179 @@ func TestClient_AuthenticateAppRole_MissingCredentials(t *testing.T) {
 				SecretID: tt.secretID,
 			}
 
-			if err := client.Authenticate(config); err == nil {
+			if err := client.Authenticate(context.Background(), config); err == nil {
 				t.Error("expected error for missing credentials, got nil")
 			}
 		})
 	}
 }
 
+func TestClient_AuthenticateKubernetes_Success(t *testing.T) {
+	var login map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/auth/k8s-prod/login" {
+			_ = json.NewDecoder(r.Body).Decode(&login)
+			_, _ = w.Write([]byte(`{"auth":{"client_token":"k8s-token"}}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenPath, []byte("service-account-jwt\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	config := AuthConfig{
+		Method:              AuthMethodKubernetes,
+		KubernetesRole:      "secrets-sync",
+		KubernetesTokenPath: tokenPath,
+		KubernetesMountPath: "k8s-prod",
+	}
+
+	if err := client.Authenticate(context.Background(), config); err != nil {
+		t.Fatalf("kubernetes authentication failed: %v", err)
+	}
+
+	if login["role"] != "secrets-sync" || login["jwt"] != "service-account-jwt" {
+		t.Errorf("unexpected login request: %v", login)
+	}
+	if client.GetAPIClient().Token() != "k8s-token" {
+		t.Errorf("expected token 'k8s-token', got: %s", client.GetAPIClient().Token())
+	}
+}
+
+func TestClient_AuthenticateKubernetes_MissingToken(t *testing.T) {
+	client, err := NewClient("http://localhost:8200")
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		config AuthConfig
+	}{
+		{"missing role", AuthConfig{Method: AuthMethodKubernetes}},
+		{"missing token file", AuthConfig{
+			Method:              AuthMethodKubernetes,
+			KubernetesRole:      "secrets-sync",
+			KubernetesTokenPath: filepath.Join(t.TempDir(), "missing"),
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := client.Authenticate(context.Background(), tt.config)
+			if !errors.Is(err, errkind.Auth) {
+				t.Errorf("expected auth error, got: %v", err)
+			}
+		})
+	}
+}
+
+// writeClientCert writes a self-signed client certificate and its key to dir
+func writeClientCert(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "secrets-sync"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile = filepath.Join(dir, "client.pem")
+	keyFile = filepath.Join(dir, "client-key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestClient_AuthenticateCert_Success(t *testing.T) {
+	var login map[string]string
+	var clientCN string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/mtls/login" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if len(r.TLS.PeerCertificates) > 0 {
+			clientCN = r.TLS.PeerCertificates[0].Subject.CommonName
+		}
+		_ = json.NewDecoder(r.Body).Decode(&login)
+		_, _ = w.Write([]byte(`{"auth":{"client_token":"cert-token"}}`))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := writeClientCert(t, dir)
+
+	client, err := NewClientWithTLS(server.URL, &TLSConfig{CACert: caFile, ClientCert: certFile, ClientKey: keyFile})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	config := AuthConfig{
+		Method:        AuthMethodCert,
+		CertRole:      "web",
+		CertMountPath: "mtls",
+	}
+	if err := client.Authenticate(context.Background(), config); err != nil {
+		t.Fatalf("cert authentication failed: %v", err)
+	}
+
+	if clientCN != "secrets-sync" {
+		t.Errorf("expected the client certificate to be presented, got CN %q", clientCN)
+	}
+	if login["name"] != "web" {
+		t.Errorf("unexpected login request: %v", login)
+	}
+	if client.GetAPIClient().Token() != "cert-token" {
+		t.Errorf("expected token 'cert-token', got: %s", client.GetAPIClient().Token())
+	}
+}
+
+func TestClient_AuthenticateCert_NoClientCert(t *testing.T) {
+	client, err := NewClient("http://localhost:8200")
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	err = client.Authenticate(context.Background(), AuthConfig{Method: AuthMethodCert})
+	if !errors.Is(err, errkind.Auth) {
+		t.Errorf("expected auth error, got: %v", err)
+	}
+}
+
 func TestClient_AuthenticateUnsupportedMethod(t *testing.T) {
 	client, err := NewClient("http://localhost:8200")
 	if err != nil {
@@ -156,7 +345,55 @@ func TestClient_AuthenticateUnsupportedMethod(t *testing.T) {
 		Method: "unsupported",
 	}
 
-	if err := client.Authenticate(config); err == nil {
+	if err := client.Authenticate(context.Background(), config); err == nil {
 		t.Error("expected error for unsupported auth method, got nil")
 	}
 }
+
+func TestAuthenticate_ContextCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = client.Authenticate(ctx, AuthConfig{Method: AuthMethodToken, Token: "test"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected authentication to be interrupted, took %s", elapsed)
+	}
+}
+
+func TestFetchSecret_ContextCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = client.FetchSecret(ctx, "secret", "test", "v2", "")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected fetch to be interrupted, took %s", elapsed)
+	}
+}
@@ -0,0 +1,74 @@
+package vault
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"golang.org/x/time/rate"
+)
+
+// ConnectionConfig tunes the HTTP connections of a client; zero fields keep
+// the defaults of the Vault API client
+type ConnectionConfig struct {
+	Timeout      time.Duration // Deadline of one request, 60s by default
+	DialTimeout  time.Duration // Deadline of establishing a TCP connection, 30s by default
+	KeepAlive    time.Duration // Interval of TCP keepalive probes, 30s by default
+	MaxIdleConns int           // Idle connections kept open for reuse
+	Proxy        string        // URL of the proxy all requests go through, by default taken from HTTPS_PROXY
+	Limiter      *rate.Limiter // Bounds the rate of requests; share one to bound several clients together
+}
+
+// NewRateLimiter returns a limiter allowing perSecond requests in bursts of
+// up to burst, nil if perSecond is 0. A burst of 0 allows perSecond rounded up.
+func NewRateLimiter(perSecond float64, burst int) *rate.Limiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = int(math.Ceil(perSecond))
+	}
+	return rate.NewLimiter(rate.Limit(perSecond), burst)
+}
+
+// configureConnection applies a connection configuration to an API client
+// configuration before the client is created
+func configureConnection(config *api.Config, conn *ConnectionConfig) error {
+	transport, ok := config.HttpClient.Transport.(*http.Transport)
+	if !ok {
+		return fmt.Errorf("unexpected transport %T", config.HttpClient.Transport)
+	}
+
+	if conn.Timeout > 0 {
+		config.Timeout = conn.Timeout
+	}
+	if conn.DialTimeout > 0 || conn.KeepAlive > 0 {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		if conn.DialTimeout > 0 {
+			dialer.Timeout = conn.DialTimeout
+		}
+		if conn.KeepAlive > 0 {
+			dialer.KeepAlive = conn.KeepAlive
+		}
+		transport.DialContext = dialer.DialContext
+	}
+	if conn.MaxIdleConns > 0 {
+		transport.MaxIdleConns = conn.MaxIdleConns
+		transport.MaxIdleConnsPerHost = conn.MaxIdleConns
+	}
+	if conn.Proxy != "" {
+		proxy, err := url.Parse(conn.Proxy)
+		if err != nil {
+			return fmt.Errorf("invalid proxy: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	// Replaces a limiter of VAULT_RATE_LIMIT, which would not be shared
+	config.Limiter = conn.Limiter
+	return nil
+}
@@ -0,0 +1,71 @@
+package vault
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewClientWithConnection_Proxy(t *testing.T) {
+	var proxied atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A proxy receives the absolute URL of the target
+		if r.URL.Host == "vault.invalid:8200" {
+			proxied.Add(1)
+		}
+		_, _ = w.Write([]byte(`{"data": {"data": {"username": "testuser"}}}`))
+	}))
+	defer proxy.Close()
+
+	client, err := NewClientWithConnection("http://vault.invalid:8200", nil, &ConnectionConfig{Proxy: proxy.URL})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	if _, err := client.FetchSecret(context.Background(), "secret", "test", "v2", ""); err != nil {
+		t.Fatalf("failed to fetch secret: %v", err)
+	}
+	if proxied.Load() != 1 {
+		t.Errorf("expected the request to go through the proxy, got %d", proxied.Load())
+	}
+}
+
+func TestNewClientWithConnection_SharedLimiter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": {"data": {"username": "testuser"}}}`))
+	}))
+	defer server.Close()
+
+	conn := &ConnectionConfig{Limiter: NewRateLimiter(10, 1)}
+	var clients []*Client
+	for range 2 {
+		client, err := NewClientWithConnection(server.URL, nil, conn)
+		if err != nil {
+			t.Fatalf("failed to create client: %v", err)
+		}
+		clients = append(clients, client)
+	}
+
+	// Four requests at 10/s with a burst of 1 take at least 300ms, however
+	// they are spread across the clients
+	start := time.Now()
+	for i := range 4 {
+		if _, err := clients[i%2].FetchSecret(context.Background(), "secret", "test", "v2", ""); err != nil {
+			t.Fatalf("failed to fetch secret: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 250*time.Millisecond {
+		t.Errorf("expected the shared limiter to delay requests, took %s", elapsed)
+	}
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	if NewRateLimiter(0, 5) != nil {
+		t.Error("expected no limiter for a rate of 0")
+	}
+	if burst := NewRateLimiter(2.5, 0).Burst(); burst != 3 {
+		t.Errorf("expected the burst to default to the rate rounded up, got %d", burst)
+	}
+}
@@ -0,0 +1,51 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/sony/gobreaker"
+
+	"github.com/ohauer/secrets-sync/internal/errkind"
+)
+
+// kindOf classifies an error returned by the Vault API or the circuit breaker
+func kindOf(err error) errkind.Kind {
+	var respErr *api.ResponseError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrSealed),
+		errors.Is(err, gobreaker.ErrOpenState),
+		errors.Is(err, gobreaker.ErrTooManyRequests):
+		return errkind.Network
+	case errors.As(err, &respErr):
+		switch {
+		case respErr.StatusCode == http.StatusUnauthorized, respErr.StatusCode == http.StatusForbidden:
+			return errkind.Permission
+		case respErr.StatusCode == http.StatusNotFound:
+			return errkind.NotFound
+		case respErr.StatusCode == http.StatusTooManyRequests, respErr.StatusCode >= 500:
+			return errkind.Network
+		}
+	case errors.As(err, &netErr):
+		return errkind.Network
+	}
+	return errkind.Unknown
+}
+
+// classify attaches the kind of a Vault API error to it
+func classify(err error) error {
+	return errkind.Wrap(kindOf(err), err)
+}
+
+// authError classifies a failed login as Auth, unless Vault was unreachable
+func authError(msg string, err error) error {
+	kind := kindOf(err)
+	if kind != errkind.Network {
+		kind = errkind.Auth
+	}
+	return errkind.Wrap(kind, fmt.Errorf("%s: %w", msg, err))
+}
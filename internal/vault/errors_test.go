@@ -0,0 +1,110 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/errkind"
+)
+
+func TestFetchSecret_ErrorKinds(t *testing.T) {
+	tests := []struct {
+		status int
+		want   errkind.Kind
+	}{
+		{http.StatusForbidden, errkind.Permission},
+		{http.StatusNotFound, errkind.NotFound},
+		{http.StatusInternalServerError, errkind.Network},
+		{http.StatusTooManyRequests, errkind.Network},
+		{http.StatusBadRequest, errkind.Unknown},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			_, _ = w.Write([]byte(`{"errors": ["some error"]}`))
+		}))
+
+		client, err := NewClient(server.URL)
+		if err != nil {
+			t.Fatalf("failed to create client: %v", err)
+		}
+		// Skip the API client's own retries of 5xx and 429 responses
+		client.GetAPIClient().SetMaxRetries(0)
+		_, err = client.FetchSecret(context.Background(), "secret", "test", "v2", "")
+		if got := errkind.Of(err); got != tt.want {
+			t.Errorf("status %d: expected kind %s, got %s (%v)", tt.status, tt.want, got, err)
+		}
+		server.Close()
+	}
+}
+
+func TestFetchSecret_UnreachableIsNetwork(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	addr := server.URL
+	server.Close()
+
+	client, err := NewClient(addr)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	client.GetAPIClient().SetMaxRetries(0)
+	_, err = client.FetchSecret(context.Background(), "secret", "test", "v2", "")
+	if !errors.Is(err, errkind.Network) {
+		t.Errorf("expected network error, got %v", err)
+	}
+}
+
+func TestAuthenticate_ErrorKinds(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errors": ["permission denied"]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	err = client.Authenticate(context.Background(), AuthConfig{Method: AuthMethodToken, Token: "bad"})
+	if !errors.Is(err, errkind.Auth) {
+		t.Errorf("expected auth error for rejected token, got %v", err)
+	}
+	err = client.Authenticate(context.Background(), AuthConfig{Method: AuthMethodAppRole, RoleID: "role"})
+	if !errors.Is(err, errkind.Auth) {
+		t.Errorf("expected auth error for missing secret id, got %v", err)
+	}
+}
+
+func TestFetchSecretWithRetry_NoRetryOnPermission(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errors": ["permission denied"]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	_, err = client.FetchSecretWithRetry(context.Background(), "secret", "test", "v2", "", RetryConfig{
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+		Multiplier:     2,
+		MaxRetries:     3,
+	})
+	if !errors.Is(err, errkind.Permission) {
+		t.Errorf("expected permission error, got %v", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expected 1 request without retries, got %d", n)
+	}
+}
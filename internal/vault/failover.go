@@ -0,0 +1,82 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// WithFailover sets the addresses of the Vault endpoints, the client's
+// address first. When the active endpoint is unreachable, a request is
+// retried on the others in order, and the first to answer becomes active.
+// onFailover is called with the old and new address on every switch.
+func (c *Client) WithFailover(addresses []string, onFailover func(from, to string)) {
+	c.endpoints = addresses
+	c.onFailover = onFailover
+}
+
+// Address returns the address of the active Vault endpoint
+func (c *Client) Address() string {
+	return c.client.Address()
+}
+
+// failover wraps fn to retry it on the other endpoints when the active one
+// is unreachable
+func (c *Client) failover(fn func() (interface{}, error)) func() (interface{}, error) {
+	if len(c.endpoints) < 2 {
+		return fn
+	}
+
+	return func() (interface{}, error) {
+		failed := c.client.Address()
+		result, err := fn()
+		if err == nil || !unreachable(err) {
+			return result, err
+		}
+
+		c.failoverMu.Lock()
+		defer c.failoverMu.Unlock()
+
+		// Another request may have switched endpoints in the meantime
+		if active := c.client.Address(); active != failed {
+			if result, retryErr := fn(); retryErr == nil || !unreachable(retryErr) {
+				return result, retryErr
+			}
+			failed = active
+		}
+
+		for _, address := range c.endpoints {
+			if address == failed {
+				continue
+			}
+			if setErr := c.client.SetAddress(address); setErr != nil {
+				continue
+			}
+			result, retryErr := fn()
+			if retryErr != nil && unreachable(retryErr) {
+				continue
+			}
+			if c.onFailover != nil {
+				c.onFailover(failed, address)
+			}
+			return result, retryErr
+		}
+
+		// No endpoint answered, stay on the one that failed
+		if setErr := c.client.SetAddress(failed); setErr != nil {
+			return nil, fmt.Errorf("failed to restore vault address: %w", setErr)
+		}
+		return nil, err
+	}
+}
+
+// unreachable reports whether a request failed because the endpoint could
+// not be reached, rather than with a response or a cancelled context
+func unreachable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
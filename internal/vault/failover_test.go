@@ -0,0 +1,101 @@
+package vault
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newHealthServer returns a Vault stub answering health checks
+func newHealthServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"initialized":true,"sealed":false}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// closedAddress returns the address of a server that is no longer listening
+func closedAddress() string {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	return server.URL
+}
+
+func TestClient_Failover(t *testing.T) {
+	primary := closedAddress()
+	secondary := newHealthServer(t)
+
+	client, err := NewClient(primary)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	client.GetAPIClient().SetMaxRetries(0)
+
+	var switches []string
+	client.WithFailover([]string{primary, secondary.URL}, func(from, to string) {
+		switches = append(switches, from+"->"+to)
+	})
+
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("expected failover to the secondary, got: %v", err)
+	}
+	if client.Address() != secondary.URL {
+		t.Errorf("expected active address %s, got %s", secondary.URL, client.Address())
+	}
+	if len(switches) != 1 || switches[0] != primary+"->"+secondary.URL {
+		t.Errorf("unexpected switches: %v", switches)
+	}
+
+	// Stays on the secondary while it answers
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("ping failed: %v", err)
+	}
+	if len(switches) != 1 {
+		t.Errorf("expected no further switch, got %v", switches)
+	}
+}
+
+func TestClient_FailoverAllUnreachable(t *testing.T) {
+	primary, secondary := closedAddress(), closedAddress()
+
+	client, err := NewClient(primary)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	client.GetAPIClient().SetMaxRetries(0)
+	client.WithFailover([]string{primary, secondary}, func(from, to string) {
+		t.Errorf("unexpected switch %s -> %s", from, to)
+	})
+
+	if err := client.Ping(context.Background()); err == nil {
+		t.Fatal("expected an error with no endpoint reachable")
+	}
+	if client.Address() != primary {
+		t.Errorf("expected to stay on %s, got %s", primary, client.Address())
+	}
+}
+
+func TestClient_NoFailoverOnResponse(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+	}))
+	defer primary.Close()
+	secondary := newHealthServer(t)
+
+	client, err := NewClient(primary.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	client.WithFailover([]string{primary.URL, secondary.URL}, nil)
+
+	if _, err := client.FetchSecret(context.Background(), "secret", "app", "v2", ""); err == nil {
+		t.Fatal("expected the primary's error")
+	}
+	if client.Address() != primary.URL {
+		t.Errorf("expected no failover on an error response, got %s", client.Address())
+	}
+}
@@ -1,55 +1,118 @@
 package vault
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"path"
+	"strconv"
 
 	"github.com/hashicorp/vault/api"
+
+	"github.com/ohauer/secrets-sync/internal/errkind"
 )
 
 // SecretData represents the data retrieved from Vault
 type SecretData map[string]interface{}
 
-// FetchSecret fetches a secret from Vault KV v1 or v2
-func (c *Client) FetchSecret(mountPath, secretPath, kvVersion, namespace string) (SecretData, error) {
-	var fullPath string
+// ErrSecretDeleted is returned when a secret does not exist in Vault, or its
+// latest KV v2 version has been deleted or destroyed
+var ErrSecretDeleted = errors.New("secret deleted")
+
+// FetchSecret fetches the latest version of a secret from Vault KV v1 or v2
+func (c *Client) FetchSecret(ctx context.Context, mountPath, secretPath, kvVersion, namespace string) (SecretData, error) {
 	if kvVersion == "v2" {
-		fullPath = path.Join(mountPath, "data", secretPath)
-	} else {
-		fullPath = path.Join(mountPath, secretPath)
+		data, _, err := c.FetchSecretVersion(ctx, mountPath, secretPath, namespace, 0)
+		return data, err
+	}
+
+	secret, err := c.read(ctx, path.Join(mountPath, secretPath), namespace, nil)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, errkind.Wrap(errkind.NotFound, fmt.Errorf("%w: not found at path %s", ErrSecretDeleted, secretPath))
+	}
+	return SecretData(secret.Data), nil
+}
+
+// FetchSecretVersion fetches a KV v2 secret and returns the version read.
+// A version of 0 reads the latest version.
+func (c *Client) FetchSecretVersion(ctx context.Context, mountPath, secretPath, namespace string, version int) (SecretData, int, error) {
+	var query map[string][]string
+	label := "latest version"
+	if version > 0 {
+		query = map[string][]string{"version": {strconv.Itoa(version)}}
+		label = fmt.Sprintf("version %d", version)
+	}
+
+	secret, err := c.read(ctx, path.Join(mountPath, "data", secretPath), namespace, query)
+	if err != nil {
+		return nil, 0, err
+	}
+	if secret == nil {
+		return nil, 0, errkind.Wrap(errkind.NotFound, fmt.Errorf("%w: not found at path %s", ErrSecretDeleted, secretPath))
 	}
 
+	metadata, _ := secret.Data["metadata"].(map[string]interface{})
+
+	// Deleted and destroyed versions are returned with metadata but no data
+	if secret.Data["data"] == nil && metadata != nil {
+		if destroyed, _ := metadata["destroyed"].(bool); destroyed {
+			return nil, 0, errkind.Wrap(errkind.NotFound, fmt.Errorf("%w: %s at path %s was destroyed", ErrSecretDeleted, label, secretPath))
+		}
+		if deletedAt, _ := metadata["deletion_time"].(string); deletedAt != "" {
+			return nil, 0, errkind.Wrap(errkind.NotFound, fmt.Errorf("%w: %s at path %s was deleted at %s", ErrSecretDeleted, label, secretPath, deletedAt))
+		}
+	}
+
+	data, ok := secret.Data["data"].(map[string]interface{})
+	if !ok {
+		return nil, 0, fmt.Errorf("invalid secret data format for KV v2")
+	}
+	return SecretData(data), intValue(metadata["version"]), nil
+}
+
+// read reads a path through the circuit breaker. A missing secret returns
+// a nil secret without error.
+func (c *Client) read(ctx context.Context, fullPath, namespace string, query map[string][]string) (*api.Secret, error) {
 	result, err := c.executeWithBreaker(func() (interface{}, error) {
-		// Set namespace if provided
-		if namespace != "" {
-			c.client.SetNamespace(namespace)
+		secret, err := c.traced(ctx, namespace).Logical().ReadWithDataWithContext(ctx, fullPath, query)
+		if isSealed(err) {
+			return nil, fmt.Errorf("%w: %v", ErrSealed, err)
 		}
-		return c.client.Logical().Read(fullPath)
+		return secret, err
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to read secret: %w", err)
-	}
-
-	if result == nil {
-		return nil, fmt.Errorf("secret not found at path: %s", secretPath)
+		return nil, classify(fmt.Errorf("failed to read secret: %w", err))
 	}
 
+	// A 404 without a body comes back as a nil *api.Secret
 	secret, ok := result.(*api.Secret)
-	if !ok || secret == nil {
+	if result == nil || (ok && secret == nil) {
+		return nil, nil
+	}
+	if !ok {
 		return nil, fmt.Errorf("invalid secret response")
 	}
-
 	if secret.Data == nil {
 		return nil, fmt.Errorf("secret has no data")
 	}
+	return secret, nil
+}
 
-	if kvVersion == "v2" {
-		data, ok := secret.Data["data"].(map[string]interface{})
-		if !ok {
-			return nil, fmt.Errorf("invalid secret data format for KV v2")
-		}
-		return SecretData(data), nil
+// intValue converts a number decoded from a Vault response to an int
+func intValue(v interface{}) int {
+	switch n := v.(type) {
+	case json.Number:
+		i, _ := n.Int64()
+		return int(i)
+	case float64:
+		return int(n)
+	case int:
+		return n
+	default:
+		return 0
 	}
-
-	return SecretData(secret.Data), nil
 }
@@ -2,6 +2,7 @@ package vault
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -31,7 +32,7 @@ func TestFetchSecret_Success(t *testing.T) {
 		t.Fatalf("failed to create client: %v", err)
 	}
 
-	data, err := client.FetchSecret("secret", "test/path", "v2", "")
+	data, err := client.FetchSecret(context.Background(), "secret", "test/path", "v2", "")
 	if err != nil {
 		t.Fatalf("failed to fetch secret: %v", err)
 	}
@@ -56,10 +57,69 @@ func TestFetchSecret_NotFound(t *testing.T) {
 		t.Fatalf("failed to create client: %v", err)
 	}
 
-	_, err = client.FetchSecret("secret", "nonexistent", "v2", "")
+	_, err = client.FetchSecret(context.Background(), "secret", "nonexistent", "v2", "")
 	if err == nil {
 		t.Error("expected error for nonexistent secret, got nil")
 	}
+	if !errors.Is(err, ErrSecretDeleted) {
+		t.Errorf("expected ErrSecretDeleted, got: %v", err)
+	}
+}
+
+func TestFetchSecret_DeletedVersion(t *testing.T) {
+	tests := map[string]string{
+		"deleted":   `{"data": {"data": null, "metadata": {"deletion_time": "2024-01-01T00:00:00Z", "destroyed": false, "version": 3}}}`,
+		"destroyed": `{"data": {"data": null, "metadata": {"deletion_time": "", "destroyed": true, "version": 3}}}`,
+	}
+
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(body))
+			}))
+			defer server.Close()
+
+			client, err := NewClient(server.URL)
+			if err != nil {
+				t.Fatalf("failed to create client: %v", err)
+			}
+
+			_, err = client.FetchSecret(context.Background(), "secret", "test/path", "v2", "")
+			if !errors.Is(err, ErrSecretDeleted) {
+				t.Errorf("expected ErrSecretDeleted, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestFetchSecretWithRetry_DeletedNotRetried(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	config := RetryConfig{
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     10 * time.Millisecond,
+		Multiplier:     2.0,
+		MaxRetries:     3,
+	}
+
+	_, err = client.FetchSecretWithRetry(context.Background(), "secret", "test/path", "v2", "", config)
+	if !errors.Is(err, ErrSecretDeleted) {
+		t.Errorf("expected ErrSecretDeleted, got: %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("expected 1 attempt, got: %d", attempts)
+	}
 }
 
 func TestFetchSecretWithRetry_Success(t *testing.T) {
@@ -215,7 +275,7 @@ func TestFetchSecret_V1_Success(t *testing.T) {
 		t.Fatalf("failed to create client: %v", err)
 	}
 
-	data, err := client.FetchSecret("secret", "test/path", "v1", "")
+	data, err := client.FetchSecret(context.Background(), "secret", "test/path", "v1", "")
 	if err != nil {
 		t.Fatalf("failed to fetch secret: %v", err)
 	}
@@ -243,7 +303,7 @@ func TestFetchSecret_V1_PathConstruction(t *testing.T) {
 		t.Fatalf("failed to create client: %v", err)
 	}
 
-	_, _ = client.FetchSecret("secret", "test/path", "v1", "")
+	_, _ = client.FetchSecret(context.Background(), "secret", "test/path", "v1", "")
 
 	expectedPath := "/v1/secret/test/path"
 	if requestedPath != expectedPath {
@@ -265,7 +325,7 @@ func TestFetchSecret_V2_PathConstruction(t *testing.T) {
 		t.Fatalf("failed to create client: %v", err)
 	}
 
-	_, _ = client.FetchSecret("secret", "test/path", "v2", "")
+	_, _ = client.FetchSecret(context.Background(), "secret", "test/path", "v2", "")
 
 	expectedPath := "/v1/secret/data/test/path"
 	if requestedPath != expectedPath {
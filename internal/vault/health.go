@@ -0,0 +1,61 @@
+package vault
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// healthParams make sys/health answer 2xx in every server state, so the
+// state can be read from the body
+var healthParams = map[string][]string{
+	"uninitcode":             {"299"},
+	"sealedcode":             {"299"},
+	"standbycode":            {"299"},
+	"performancestandbycode": {"299"},
+	"drsecondarycode":        {"299"},
+}
+
+// ServerInfo is what a health check tells about a server and the connection
+// to it
+type ServerInfo struct {
+	Version     string
+	Initialized bool
+	Sealed      bool
+	Standby     bool
+	Date        time.Time            // Server clock from the Date header, zero if missing
+	RoundTrip   time.Duration        // Time the health check took
+	TLS         *tls.ConnectionState // nil over plain HTTP
+}
+
+// Probe sends a health check and returns the server state along with the
+// TLS connection it was sent over. Like SealStatus it bypasses the circuit
+// breaker and failover, so it reports on the client's current address.
+func (c *Client) Probe(ctx context.Context) (*ServerInfo, error) {
+	start := time.Now()
+	resp, err := c.client.Logical().ReadRawWithDataWithContext(ctx, "sys/health", healthParams)
+	if resp != nil {
+		defer func() { _ = resp.Body.Close() }()
+	}
+	if err != nil {
+		return nil, classify(fmt.Errorf("vault health check failed: %w", err))
+	}
+
+	info := &ServerInfo{RoundTrip: time.Since(start), TLS: resp.TLS}
+	var health api.HealthResponse
+	if err := resp.DecodeJSON(&health); err != nil {
+		return nil, fmt.Errorf("invalid health check response: %w", err)
+	}
+	info.Version = health.Version
+	info.Initialized = health.Initialized
+	info.Sealed = health.Sealed
+	info.Standby = health.Standby
+	if date, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+		info.Date = date
+	}
+	return info, nil
+}
@@ -0,0 +1,82 @@
+package vault
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newHealthHandler(t *testing.T) http.HandlerFunc {
+	t.Helper()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/sys/health" || r.URL.Query().Get("standbycode") != "299" {
+			t.Errorf("unexpected request: %s", r.URL)
+		}
+		w.Header().Set("Date", time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC).Format(http.TimeFormat))
+		w.WriteHeader(299)
+		_, _ = w.Write([]byte(`{"initialized": true, "sealed": false, "standby": true, "version": "1.15.0"}`))
+	}
+}
+
+func TestProbe(t *testing.T) {
+	server := httptest.NewServer(newHealthHandler(t))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	info, err := client.Probe(context.Background())
+	if err != nil {
+		t.Fatalf("probe failed: %v", err)
+	}
+	if info.Version != "1.15.0" || !info.Initialized || info.Sealed || !info.Standby {
+		t.Errorf("unexpected server state: %+v", info)
+	}
+	if !info.Date.Equal(time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("unexpected server date: %v", info.Date)
+	}
+	if info.TLS != nil {
+		t.Error("expected no TLS state over plain HTTP")
+	}
+}
+
+func TestProbe_TLS(t *testing.T) {
+	server := httptest.NewTLSServer(newHealthHandler(t))
+	defer server.Close()
+
+	caCert := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caCert, certPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewClientWithTLS(server.URL, &TLSConfig{CACert: caCert})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	info, err := client.Probe(context.Background())
+	if err != nil {
+		t.Fatalf("probe failed: %v", err)
+	}
+	if info.TLS == nil || len(info.TLS.VerifiedChains) == 0 {
+		t.Fatalf("expected a verified TLS chain, got %+v", info.TLS)
+	}
+
+	untrusted, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	untrusted.GetAPIClient().SetMaxRetries(0)
+	if _, err := untrusted.Probe(context.Background()); err == nil {
+		t.Error("expected probe of an untrusted certificate to fail")
+	}
+}
@@ -0,0 +1,276 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+
+	"github.com/ohauer/secrets-sync/internal/errkind"
+)
+
+// Lease is the lease of a dynamic secret
+type Lease struct {
+	ID        string
+	Duration  time.Duration
+	Renewable bool
+}
+
+// FetchDatabaseCredentials generates credentials for a database secrets
+// engine role and returns them with their lease. Every call creates a new
+// database user.
+func (c *Client) FetchDatabaseCredentials(ctx context.Context, mountPath, role, namespace string) (SecretData, Lease, error) {
+	secret, err := c.read(ctx, path.Join(mountPath, "creds", role), namespace, nil)
+	if err != nil {
+		return nil, Lease{}, err
+	}
+	if secret == nil {
+		return nil, Lease{}, errkind.Wrap(errkind.NotFound, fmt.Errorf("%w: role %s not found at %s", ErrSecretDeleted, role, mountPath))
+	}
+	return SecretData(secret.Data), leaseOf(secret), nil
+}
+
+// RenewLease asks to extend a lease by increment and returns the lease as
+// granted, which may be shorter once its maximum TTL is reached
+func (c *Client) RenewLease(ctx context.Context, leaseID string, increment time.Duration, namespace string) (Lease, error) {
+	result, err := c.executeWithBreaker(func() (interface{}, error) {
+		secret, err := c.traced(ctx, namespace).Sys().RenewWithContext(ctx, leaseID, int(increment.Seconds()))
+		if isSealed(err) {
+			return nil, fmt.Errorf("%w: %v", ErrSealed, err)
+		}
+		return secret, err
+	})
+	if err != nil {
+		return Lease{}, classify(fmt.Errorf("failed to renew lease: %w", err))
+	}
+
+	secret, ok := result.(*api.Secret)
+	if !ok || secret == nil {
+		return Lease{}, fmt.Errorf("invalid lease renewal response")
+	}
+	lease := leaseOf(secret)
+	if lease.ID == "" {
+		lease.ID = leaseID
+	}
+	return lease, nil
+}
+
+// RevokeLease revokes a lease, invalidating its credentials at once
+func (c *Client) RevokeLease(ctx context.Context, leaseID, namespace string) error {
+	_, err := c.executeWithBreaker(func() (interface{}, error) {
+		err := c.traced(ctx, namespace).Sys().RevokeWithContext(ctx, leaseID)
+		if isSealed(err) {
+			return nil, fmt.Errorf("%w: %v", ErrSealed, err)
+		}
+		return nil, err
+	})
+	if err != nil {
+		return classify(fmt.Errorf("failed to revoke lease: %w", err))
+	}
+	return nil
+}
+
+// LeaseManager tracks the leases of the credentials read for each owner,
+// such as a secret, renews them before they expire and revokes them on
+// shutdown. Leases replaced by new credentials are kept until they expire,
+// so they are revoked as well.
+type LeaseManager struct {
+	mu      sync.Mutex
+	leases  map[string]managedLease // Current lease, by owner
+	retired []managedLease          // Replaced leases not yet expired
+}
+
+// managedLease is a tracked lease with the client it was issued through
+type managedLease struct {
+	Lease
+	client    *Client
+	namespace string
+	term      time.Duration // Duration of the lease as first issued
+	renewAt   time.Time     // Two thirds into the lease
+	expires   time.Time
+}
+
+// NewLeaseManager creates an empty lease manager
+func NewLeaseManager() *LeaseManager {
+	return &LeaseManager{leases: make(map[string]managedLease)}
+}
+
+// Track records the lease of credentials just read for owner, replacing its
+// previous lease. A response without a lease stops the tracking.
+func (m *LeaseManager) Track(owner string, client *Client, namespace string, lease Lease) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.retire(owner)
+	if lease.ID == "" || lease.Duration <= 0 {
+		return
+	}
+	m.leases[owner] = newManagedLease(lease, client, namespace, lease.Duration, time.Now())
+}
+
+// Release stops tracking the lease of owner; it is still revoked on
+// shutdown while it has not expired
+func (m *LeaseManager) Release(owner string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retire(owner)
+}
+
+// Renew reports whether the credentials of owner remain usable. Once two
+// thirds of the lease have passed it is renewed for its original duration.
+// False means new credentials are needed: nothing is tracked, the lease is
+// not renewable or has expired, the renewal failed, or the maximum TTL caps
+// it to less than a third of the original duration, leaving enough time to
+// replace the credentials before the old ones expire.
+func (m *LeaseManager) Renew(ctx context.Context, owner string) bool {
+	m.mu.Lock()
+	lease, ok := m.leases[owner]
+	m.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	now := time.Now()
+	if now.Before(lease.renewAt) {
+		return true
+	}
+	if !lease.Renewable || !now.Before(lease.expires) {
+		return false
+	}
+	renewed, err := lease.client.RenewLease(ctx, lease.ID, lease.term, lease.namespace)
+	if err != nil || renewed.Duration < lease.term/3 {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// Replaced while renewing
+	if current, ok := m.leases[owner]; !ok || current.ID != lease.ID {
+		return false
+	}
+	m.leases[owner] = newManagedLease(renewed, lease.client, lease.namespace, lease.term, now)
+	return true
+}
+
+// NextRenewal returns when the lease of owner is due for renewal
+func (m *LeaseManager) NextRenewal(owner string) (time.Time, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lease, ok := m.leases[owner]
+	return lease.renewAt, ok
+}
+
+// Expiry returns when the lease of owner expires unless renewed
+func (m *LeaseManager) Expiry(owner string) (time.Time, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lease, ok := m.leases[owner]
+	return lease.expires, ok
+}
+
+// LeaseState is the schedule of a tracked lease, to resume it after a restart
+type LeaseState struct {
+	Lease
+	Term    time.Duration // Duration of the lease as first issued
+	Expires time.Time
+}
+
+// State returns the lease of owner and when it expires
+func (m *LeaseManager) State(owner string) (LeaseState, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lease, ok := m.leases[owner]
+	return LeaseState{Lease: lease.Lease, Term: lease.term, Expires: lease.expires}, ok
+}
+
+// Resume tracks a lease issued before a restart. It is due for renewal at
+// once, so the next Renew confirms it is still valid. An expired lease is
+// not tracked.
+func (m *LeaseManager) Resume(owner string, client *Client, namespace string, state LeaseState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.retire(owner)
+	now := time.Now()
+	if state.ID == "" || !now.Before(state.Expires) {
+		return
+	}
+	lease := state.Lease
+	lease.Duration = state.Expires.Sub(now)
+	m.leases[owner] = managedLease{
+		Lease:     lease,
+		client:    client,
+		namespace: namespace,
+		term:      state.Term,
+		renewAt:   now,
+		expires:   state.Expires,
+	}
+}
+
+// RevokeAll revokes every lease that has not expired, current and replaced,
+// and stops tracking them. Errors are joined; leases failing to revoke
+// expire on their own.
+func (m *LeaseManager) RevokeAll(ctx context.Context) error {
+	m.mu.Lock()
+	leases := m.retired
+	for _, lease := range m.leases {
+		leases = append(leases, lease)
+	}
+	m.leases = make(map[string]managedLease)
+	m.retired = nil
+	m.mu.Unlock()
+
+	var errs []error
+	now := time.Now()
+	for _, lease := range leases {
+		if !now.Before(lease.expires) {
+			continue
+		}
+		if err := lease.client.RevokeLease(ctx, lease.ID, lease.namespace); err != nil {
+			errs = append(errs, fmt.Errorf("lease %s: %w", lease.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// retire moves the lease of owner to the replaced leases and drops those
+// expired; the caller holds m.mu
+func (m *LeaseManager) retire(owner string) {
+	now := time.Now()
+	retired := m.retired[:0]
+	for _, lease := range m.retired {
+		if now.Before(lease.expires) {
+			retired = append(retired, lease)
+		}
+	}
+	if lease, ok := m.leases[owner]; ok {
+		retired = append(retired, lease)
+		delete(m.leases, owner)
+	}
+	m.retired = retired
+}
+
+// newManagedLease starts the schedule of a lease granted at now
+func newManagedLease(lease Lease, client *Client, namespace string, term time.Duration, now time.Time) managedLease {
+	return managedLease{
+		Lease:     lease,
+		client:    client,
+		namespace: namespace,
+		term:      term,
+		renewAt:   now.Add(lease.Duration * 2 / 3),
+		expires:   now.Add(lease.Duration),
+	}
+}
+
+// leaseOf returns the lease of a response
+func leaseOf(secret *api.Secret) Lease {
+	return Lease{
+		ID:        secret.LeaseID,
+		Duration:  time.Duration(secret.LeaseDuration) * time.Second,
+		Renewable: secret.Renewable,
+	}
+}
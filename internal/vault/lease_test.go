@@ -0,0 +1,234 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFetchDatabaseCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/database/creds/readonly" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{
+			"lease_id": "database/creds/readonly/abc",
+			"lease_duration": 3600,
+			"renewable": true,
+			"data": {"username": "v-app-1", "password": "pw"}
+		}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	data, lease, err := client.FetchDatabaseCredentials(context.Background(), "database", "readonly", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data["username"] != "v-app-1" || data["password"] != "pw" {
+		t.Errorf("unexpected data: %v", data)
+	}
+	want := Lease{ID: "database/creds/readonly/abc", Duration: time.Hour, Renewable: true}
+	if lease != want {
+		t.Errorf("expected lease %+v, got %+v", want, lease)
+	}
+
+	if _, _, err := client.FetchDatabaseCredentials(context.Background(), "database", "missing", ""); !errors.Is(err, ErrSecretDeleted) {
+		t.Errorf("expected ErrSecretDeleted for unknown role, got: %v", err)
+	}
+}
+
+func TestRenewLease(t *testing.T) {
+	var body struct {
+		LeaseID   string `json:"lease_id"`
+		Increment int    `json:"increment"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/sys/leases/renew" || r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// Capped by the role's max TTL
+		_, _ = w.Write([]byte(`{"lease_id": "database/creds/readonly/abc", "lease_duration": 600, "renewable": true}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	lease, err := client.RenewLease(context.Background(), "database/creds/readonly/abc", time.Hour, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body.LeaseID != "database/creds/readonly/abc" || body.Increment != 3600 {
+		t.Errorf("unexpected renewal request %+v", body)
+	}
+	if lease.Duration != 10*time.Minute || !lease.Renewable {
+		t.Errorf("unexpected lease %+v", lease)
+	}
+}
+
+// leaseServer renews leases for renewSeconds and records revoked lease IDs
+type leaseServer struct {
+	mu           sync.Mutex
+	renewSeconds int
+	renewals     int
+	revoked      []string
+}
+
+func (l *leaseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		LeaseID string `json:"lease_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	switch r.URL.Path {
+	case "/v1/sys/leases/renew":
+		l.renewals++
+		_, _ = w.Write([]byte(`{"lease_id": "` + body.LeaseID + `", "lease_duration": ` + strconv.Itoa(l.renewSeconds) + `, "renewable": true}`))
+	case "/v1/sys/leases/revoke":
+		l.revoked = append(l.revoked, body.LeaseID)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// dueForRenewal moves the renewal of a tracked lease into the past
+func dueForRenewal(m *LeaseManager, owner string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lease := m.leases[owner]
+	lease.renewAt = time.Now().Add(-time.Second)
+	m.leases[owner] = lease
+}
+
+func TestLeaseManager(t *testing.T) {
+	handler := &leaseServer{renewSeconds: 3600}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	ctx := context.Background()
+	m := NewLeaseManager()
+
+	if m.Renew(ctx, "db") {
+		t.Error("expected no lease to keep before tracking")
+	}
+
+	m.Track("db", client, "", Lease{ID: "creds/1", Duration: time.Hour, Renewable: true})
+	renewAt, ok := m.NextRenewal("db")
+	if !ok || time.Until(renewAt) < 39*time.Minute || time.Until(renewAt) > 40*time.Minute {
+		t.Errorf("expected renewal two thirds into the lease, got %v, %v", renewAt, ok)
+	}
+
+	// Not due yet: kept without asking Vault
+	if !m.Renew(ctx, "db") || handler.renewals != 0 {
+		t.Errorf("expected lease kept without renewal, got %d renewals", handler.renewals)
+	}
+
+	dueForRenewal(m, "db")
+	if !m.Renew(ctx, "db") || handler.renewals != 1 {
+		t.Errorf("expected lease renewed once, got %d renewals", handler.renewals)
+	}
+	if renewAt, _ := m.NextRenewal("db"); !renewAt.After(time.Now()) {
+		t.Errorf("expected next renewal in the future, got %v", renewAt)
+	}
+
+	// Capped by the maximum TTL to less than a third: replace the credentials
+	handler.renewSeconds = 600
+	dueForRenewal(m, "db")
+	if m.Renew(ctx, "db") {
+		t.Error("expected capped lease not to be kept")
+	}
+
+	// Not renewable: replace the credentials
+	m.Track("db", client, "", Lease{ID: "creds/2", Duration: time.Hour})
+	dueForRenewal(m, "db")
+	if m.Renew(ctx, "db") {
+		t.Error("expected non-renewable lease not to be kept")
+	}
+
+	// Without a lease nothing is tracked
+	m.Track("static", client, "", Lease{})
+	if _, ok := m.NextRenewal("static"); ok {
+		t.Error("expected response without lease to be ignored")
+	}
+
+	m.Track("other", client, "", Lease{ID: "creds/3", Duration: time.Hour})
+	m.Release("other")
+	if _, ok := m.NextRenewal("other"); ok {
+		t.Error("expected released lease not to be renewed")
+	}
+
+	// Replaced and released leases are revoked too
+	if err := m.RevokeAll(ctx); err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+	sort.Strings(handler.revoked)
+	if want := []string{"creds/1", "creds/2", "creds/3"}; !slices.Equal(handler.revoked, want) {
+		t.Errorf("expected revoked %v, got %v", want, handler.revoked)
+	}
+	if _, ok := m.NextRenewal("db"); ok {
+		t.Error("expected no lease tracked after revoking")
+	}
+}
+
+func TestLeaseManager_Resume(t *testing.T) {
+	handler := &leaseServer{renewSeconds: 3600}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	ctx := context.Background()
+	m := NewLeaseManager()
+
+	m.Track("db", client, "", Lease{ID: "creds/1", Duration: time.Hour, Renewable: true})
+	state, ok := m.State("db")
+	if !ok || state.ID != "creds/1" || state.Term != time.Hour || time.Until(state.Expires) < 59*time.Minute {
+		t.Fatalf("unexpected lease state %+v, %v", state, ok)
+	}
+
+	// A restarted daemon renews a resumed lease at once
+	resumed := NewLeaseManager()
+	resumed.Resume("db", client, "", state)
+	if !resumed.Renew(ctx, "db") || handler.renewals != 1 {
+		t.Errorf("expected resumed lease renewed once, got %d renewals", handler.renewals)
+	}
+
+	state.Expires = time.Now().Add(-time.Minute)
+	resumed.Resume("expired", client, "", state)
+	if _, ok := resumed.NextRenewal("expired"); ok {
+		t.Error("expected an expired lease not to be resumed")
+	}
+}
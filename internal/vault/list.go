@@ -0,0 +1,48 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// ListSecrets lists the keys directly below a path of a KV v1 or v2 mount.
+// Keys ending in "/" are folders. A path without keys returns an empty list.
+func (c *Client) ListSecrets(ctx context.Context, mountPath, secretPath, kvVersion, namespace string) ([]string, error) {
+	var fullPath string
+	if kvVersion == "v2" {
+		fullPath = path.Join(mountPath, "metadata", secretPath)
+	} else {
+		fullPath = path.Join(mountPath, secretPath)
+	}
+
+	result, err := c.executeWithBreaker(func() (interface{}, error) {
+		secret, err := c.traced(ctx, namespace).Logical().ListWithContext(ctx, fullPath)
+		if isSealed(err) {
+			return nil, fmt.Errorf("%w: %v", ErrSealed, err)
+		}
+		return secret, err
+	})
+	if err != nil {
+		return nil, classify(fmt.Errorf("failed to list secrets: %w", err))
+	}
+
+	secret, _ := result.(*api.Secret)
+	if secret == nil || secret.Data == nil {
+		return nil, nil
+	}
+
+	raw, ok := secret.Data["keys"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid list response for path %s", secretPath)
+	}
+	keys := make([]string, 0, len(raw))
+	for _, k := range raw {
+		if key, ok := k.(string); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
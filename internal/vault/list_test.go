@@ -0,0 +1,77 @@
+package vault
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestListSecrets_KVv2(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/secret/metadata/apps" && r.URL.Query().Get("list") == "true" {
+			_, _ = w.Write([]byte(`{"data": {"keys": ["db", "web/"]}}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	keys, err := client.ListSecrets(context.Background(), "secret", "apps", "v2", "")
+	if err != nil {
+		t.Fatalf("failed to list secrets: %v", err)
+	}
+	if want := []string{"db", "web/"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("expected %v, got %v", want, keys)
+	}
+}
+
+func TestListSecrets_KVv1(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/kv/apps" && r.URL.Query().Get("list") == "true" {
+			_, _ = w.Write([]byte(`{"data": {"keys": ["db"]}}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	keys, err := client.ListSecrets(context.Background(), "kv", "apps", "v1", "")
+	if err != nil {
+		t.Fatalf("failed to list secrets: %v", err)
+	}
+	if want := []string{"db"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("expected %v, got %v", want, keys)
+	}
+}
+
+func TestListSecrets_Empty(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	keys, err := client.ListSecrets(context.Background(), "secret", "missing", "v2", "")
+	if err != nil {
+		t.Fatalf("expected no error for missing path, got: %v", err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("expected no keys, got %v", keys)
+	}
+}
@@ -2,8 +2,16 @@ package vault
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
 	"time"
+
+	"github.com/hashicorp/vault/api"
+
+	"github.com/ohauer/secrets-sync/internal/errkind"
 )
 
 // RetryConfig holds retry configuration
@@ -14,17 +22,83 @@ type RetryConfig struct {
 	MaxRetries     int
 }
 
+// FetchObserver receives how long an attempt to read a KV secret took, its
+// number (0 for the first, 1 for the first retry, ...) and its error; ctx
+// carries the span of the read, if tracing is enabled
+type FetchObserver func(ctx context.Context, attempt int, d time.Duration, err error)
+
+// WithFetchObserver registers a callback timing every attempt of
+// FetchSecretWithRetry and FetchSecretVersionWithRetry
+func (c *Client) WithFetchObserver(fn FetchObserver) {
+	c.onFetch = fn
+}
+
 // FetchSecretWithRetry fetches a secret with exponential backoff retry
 func (c *Client) FetchSecretWithRetry(ctx context.Context, mountPath, secretPath, kvVersion, namespace string, config RetryConfig) (SecretData, error) {
+	var data SecretData
+	attempt := 0
+	err := c.Retry(ctx, config, func() error {
+		start := time.Now()
+		var err error
+		data, err = c.FetchSecret(ctx, mountPath, secretPath, kvVersion, namespace)
+		c.observeFetch(ctx, &attempt, start, err)
+		return err
+	})
+	return data, err
+}
+
+// FetchSecretVersionWithRetry fetches a KV v2 secret version with
+// exponential backoff retry
+func (c *Client) FetchSecretVersionWithRetry(ctx context.Context, mountPath, secretPath, namespace string, version int, config RetryConfig) (SecretData, int, error) {
+	var data SecretData
+	var read int
+	attempt := 0
+	err := c.Retry(ctx, config, func() error {
+		start := time.Now()
+		var err error
+		data, read, err = c.FetchSecretVersion(ctx, mountPath, secretPath, namespace, version)
+		c.observeFetch(ctx, &attempt, start, err)
+		return err
+	})
+	return data, read, err
+}
+
+// observeFetch passes an attempt to the fetch observer, if any, and counts it
+func (c *Client) observeFetch(ctx context.Context, attempt *int, start time.Time, err error) {
+	if c.onFetch != nil {
+		c.onFetch(ctx, *attempt, time.Since(start), err)
+	}
+	*attempt++
+}
+
+// Retry calls fn with exponential backoff until it succeeds, fails with an
+// error not worth retrying, or the retries are used up
+func Retry(ctx context.Context, config RetryConfig, fn func() error) error {
+	return retry(ctx, config, fn, nil)
+}
+
+// Retry is Retry that also waits as long as Vault asked for with a
+// Retry-After header before asking again
+func (c *Client) Retry(ctx context.Context, config RetryConfig, fn func() error) error {
+	return retry(ctx, config, fn, c.throttle.wait)
+}
+
+// retry implements Retry; wait, if set, returns how long the server asked
+// to wait, and delays a retry beyond its backoff
+func retry(ctx context.Context, config RetryConfig, fn func() error, wait func() time.Duration) error {
 	var lastErr error
 	backoff := config.InitialBackoff
 
 	for attempt := 0; attempt <= config.MaxRetries; attempt++ {
 		if attempt > 0 {
+			delay := backoff
+			if wait != nil {
+				delay = max(delay, wait())
+			}
 			select {
 			case <-ctx.Done():
-				return nil, fmt.Errorf("context cancelled: %w", ctx.Err())
-			case <-time.After(backoff):
+				return fmt.Errorf("context cancelled: %w", ctx.Err())
+			case <-time.After(delay):
 			}
 
 			backoff = time.Duration(float64(backoff) * config.Multiplier)
@@ -33,13 +107,82 @@ func (c *Client) FetchSecretWithRetry(ctx context.Context, mountPath, secretPath
 			}
 		}
 
-		data, err := c.FetchSecret(mountPath, secretPath, kvVersion, namespace)
+		err := fn()
 		if err == nil {
-			return data, nil
+			return nil
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("context cancelled: %w", err)
+		}
+		if !retryable(err) {
+			return err
 		}
 
 		lastErr = err
 	}
 
-	return nil, fmt.Errorf("failed after %d retries: %w", config.MaxRetries, lastErr)
+	return fmt.Errorf("failed after %d retries: %w", config.MaxRetries, lastErr)
+}
+
+// retryable reports whether asking again may succeed. Only network trouble,
+// rate limiting and server errors pass by asking again: a sealed Vault stays
+// sealed until an operator unseals it, and a request Vault rejected with
+// another 4xx, such as 400, 403 or 404, is rejected again.
+func retryable(err error) bool {
+	if !errkind.Retryable(err) || errors.Is(err, ErrSealed) {
+		return false
+	}
+	var respErr *api.ResponseError
+	if errors.As(err, &respErr) && respErr.StatusCode >= 400 && respErr.StatusCode < 500 {
+		return respErr.StatusCode == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// throttle remembers until when Vault asked not to be asked again, with a
+// Retry-After header on a 429 or 503 response
+type throttle struct {
+	until atomic.Int64 // Unix nanoseconds
+}
+
+// observe records the Retry-After header of a response
+func (t *throttle) observe(resp *http.Response) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return
+	}
+	delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	if !ok {
+		return
+	}
+	until := time.Now().Add(delay).UnixNano()
+	for {
+		current := t.until.Load()
+		if current >= until || t.until.CompareAndSwap(current, until) {
+			return
+		}
+	}
+}
+
+// wait returns how much longer Vault asked not to be asked again
+func (t *throttle) wait() time.Duration {
+	return max(time.Until(time.Unix(0, t.until.Load())), 0)
+}
+
+// parseRetryAfter parses a Retry-After header, given in seconds or as an
+// HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return max(at.Sub(now), 0), true
 }
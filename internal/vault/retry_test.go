@@ -0,0 +1,127 @@
+package vault
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetry_FailFast(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound} {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"errors": ["some error"]}`))
+		}))
+
+		client, err := NewClient(server.URL)
+		if err != nil {
+			t.Fatalf("failed to create client: %v", err)
+		}
+		client.GetAPIClient().SetMaxRetries(0)
+
+		config := RetryConfig{InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Multiplier: 1, MaxRetries: 3}
+		if _, err := client.FetchSecretWithRetry(context.Background(), "secret", "test", "v2", "", config); err == nil {
+			t.Errorf("status %d: expected an error", status)
+		}
+		if n := requests.Load(); n != 1 {
+			t.Errorf("status %d: expected no retries, got %d requests", status, n)
+		}
+		server.Close()
+	}
+}
+
+func TestRetry_RetryAfter(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"errors": ["rate limit quota exceeded"]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"data": {"key": "value"}}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	client.GetAPIClient().SetMaxRetries(0)
+
+	start := time.Now()
+	config := RetryConfig{InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Multiplier: 1, MaxRetries: 3}
+	if _, err := client.FetchSecretWithRetry(context.Background(), "secret", "test", "v2", "", config); err != nil {
+		t.Fatalf("expected the retry to succeed, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Errorf("expected the retry to wait for Retry-After, took %s", elapsed)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"-1", 0, false},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second, true},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %s, %v; expected %s, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFetchSecretWithRetry_Observer(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"errors": ["unavailable"]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"data": {"key": "value"}, "metadata": {"version": 1}}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	client.GetAPIClient().SetMaxRetries(0)
+
+	type attempt struct {
+		n      int
+		failed bool
+	}
+	var attempts []attempt
+	client.WithFetchObserver(func(ctx context.Context, n int, d time.Duration, err error) {
+		attempts = append(attempts, attempt{n, err != nil})
+	})
+
+	config := RetryConfig{InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Multiplier: 1, MaxRetries: 3}
+	if _, _, err := client.FetchSecretVersionWithRetry(context.Background(), "secret", "test", "", 0, config); err != nil {
+		t.Fatalf("expected the retry to succeed, got: %v", err)
+	}
+	want := []attempt{{0, true}, {1, false}}
+	if len(attempts) != len(want) || attempts[0] != want[0] || attempts[1] != want[1] {
+		t.Errorf("expected attempts %v, got %v", want, attempts)
+	}
+}
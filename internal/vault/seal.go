@@ -0,0 +1,38 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// ErrSealed is returned when Vault refuses a request because it is sealed
+var ErrSealed = errors.New("vault is sealed")
+
+// isSealed reports whether err is the 503 response of a sealed Vault
+func isSealed(err error) bool {
+	var respErr *api.ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusServiceUnavailable {
+		return false
+	}
+	for _, msg := range respErr.Errors {
+		if strings.Contains(msg, "Vault is sealed") {
+			return true
+		}
+	}
+	return false
+}
+
+// SealStatus reports whether Vault is sealed. It bypasses the circuit
+// breaker, so it can be polled cheaply while secrets cannot be read.
+func (c *Client) SealStatus(ctx context.Context) (bool, error) {
+	status, err := c.client.Sys().SealStatusWithContext(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to read seal status: %w", err)
+	}
+	return status.Sealed, nil
+}
@@ -0,0 +1,112 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newSealedServer(t *testing.T, sealed *atomic.Bool, requests *atomic.Int32) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/sys/seal-status" {
+			w.WriteHeader(http.StatusOK)
+			if sealed.Load() {
+				_, _ = w.Write([]byte(`{"sealed": true}`))
+			} else {
+				_, _ = w.Write([]byte(`{"sealed": false}`))
+			}
+			return
+		}
+
+		requests.Add(1)
+		if sealed.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"errors": ["Vault is sealed"]}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"data": {"data": {"key": "value"}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	client.GetAPIClient().SetMaxRetries(0)
+	return client
+}
+
+func TestFetchSecret_Sealed(t *testing.T) {
+	var sealed atomic.Bool
+	var requests atomic.Int32
+	sealed.Store(true)
+	client := newSealedServer(t, &sealed, &requests)
+
+	config := RetryConfig{InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Multiplier: 2, MaxRetries: 3}
+	_, err := client.FetchSecretWithRetry(context.Background(), "secret", "test/path", "v2", "", config)
+	if !errors.Is(err, ErrSealed) {
+		t.Fatalf("expected ErrSealed, got %v", err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("expected sealed read not to be retried, got %d requests", requests.Load())
+	}
+}
+
+func TestFetchSecret_SealedDoesNotOpenBreaker(t *testing.T) {
+	var sealed atomic.Bool
+	var requests atomic.Int32
+	sealed.Store(true)
+	client := newSealedServer(t, &sealed, &requests)
+	client.WithCircuitBreaker(BreakerConfig{MaxRequests: 1, Interval: time.Minute, Timeout: time.Minute}, nil)
+
+	for i := 0; i < 5; i++ {
+		if _, err := client.FetchSecret(context.Background(), "secret", "test/path", "v2", ""); !errors.Is(err, ErrSealed) {
+			t.Fatalf("expected ErrSealed, got %v", err)
+		}
+	}
+	if state := client.BreakerState(); state != "closed" {
+		t.Errorf("expected breaker to stay closed, got %s", state)
+	}
+}
+
+func TestFetchSecret_ServiceUnavailableIsNotSealed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"errors": ["standby node"]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	client.GetAPIClient().SetMaxRetries(0)
+
+	if _, err := client.FetchSecret(context.Background(), "secret", "test/path", "v2", ""); err == nil || errors.Is(err, ErrSealed) {
+		t.Errorf("expected a non-sealed error, got %v", err)
+	}
+}
+
+func TestSealStatus(t *testing.T) {
+	var sealed atomic.Bool
+	var requests atomic.Int32
+	client := newSealedServer(t, &sealed, &requests)
+
+	for _, want := range []bool{false, true} {
+		sealed.Store(want)
+		got, err := client.SealStatus(context.Background())
+		if err != nil {
+			t.Fatalf("failed to read seal status: %v", err)
+		}
+		if got != want {
+			t.Errorf("expected sealed=%t, got %t", want, got)
+		}
+	}
+}
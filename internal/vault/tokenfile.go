@@ -0,0 +1,137 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+
+	"github.com/ohauer/secrets-sync/internal/errkind"
+)
+
+// tokenFile tracks the file a token is read from, such as a Vault Agent
+// sink, so a token rotated by the agent is picked up
+type tokenFile struct {
+	path    string
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	token   string
+}
+
+// authenticateTokenFile logs in with the token held by a file. The file is
+// read again before a request when it changed, and when Vault rejects the
+// token.
+func (c *Client) authenticateTokenFile(ctx context.Context, path string) error {
+	if path == "" {
+		return errkind.Wrap(errkind.Auth, fmt.Errorf("tokenFile is required"))
+	}
+
+	tf := &tokenFile{path: path}
+	if _, err := tf.read(); err != nil {
+		return errkind.Wrap(errkind.Auth, err)
+	}
+	c.setToken(tf.token)
+	c.tokenFile = tf
+
+	_, err := c.executeWithBreaker(func() (interface{}, error) {
+		return c.traced(ctx, c.authNamespace).Auth().Token().LookupSelfWithContext(ctx)
+	})
+	if err != nil {
+		return authError("token file authentication failed", err)
+	}
+	return nil
+}
+
+// withTokenFile wraps fn to use the token in the token file if it changed,
+// and to retry fn once with a re-read token if Vault rejects the current one
+func (c *Client) withTokenFile(fn func() (interface{}, error)) func() (interface{}, error) {
+	tf := c.tokenFile
+	if tf == nil {
+		return fn
+	}
+
+	return func() (interface{}, error) {
+		// Keep the current token while the file cannot be read, e.g. while
+		// the agent replaces it
+		if changed, err := tf.refresh(false); err == nil && changed {
+			c.setToken(tf.current())
+		}
+
+		result, err := fn()
+		if err == nil || !rejected(err) {
+			return result, err
+		}
+		if changed, readErr := tf.refresh(true); readErr != nil || !changed {
+			return result, err
+		}
+		c.setToken(tf.current())
+		return fn()
+	}
+}
+
+// refresh reads the token file if it changed since it was last read, or
+// always if force is set, and reports whether the token changed
+func (tf *tokenFile) refresh(force bool) (bool, error) {
+	tf.mu.Lock()
+	defer tf.mu.Unlock()
+
+	if !force {
+		info, err := os.Stat(tf.path)
+		if err != nil {
+			return false, fmt.Errorf("failed to stat token file: %w", err)
+		}
+		if info.ModTime().Equal(tf.modTime) && info.Size() == tf.size {
+			return false, nil
+		}
+	}
+	return tf.readLocked()
+}
+
+// read reads the token file and reports whether the token changed
+func (tf *tokenFile) read() (bool, error) {
+	tf.mu.Lock()
+	defer tf.mu.Unlock()
+	return tf.readLocked()
+}
+
+func (tf *tokenFile) readLocked() (bool, error) {
+	info, err := os.Stat(tf.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat token file: %w", err)
+	}
+	content, err := os.ReadFile(tf.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read token file: %w", err)
+	}
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		return false, fmt.Errorf("token file %s is empty", tf.path)
+	}
+
+	changed := token != tf.token
+	tf.token = token
+	tf.modTime = info.ModTime()
+	tf.size = info.Size()
+	return changed, nil
+}
+
+// current returns the token last read from the file
+func (tf *tokenFile) current() string {
+	tf.mu.Lock()
+	defer tf.mu.Unlock()
+	return tf.token
+}
+
+// rejected reports whether Vault refused a request for its token
+func rejected(err error) bool {
+	var respErr *api.ResponseError
+	return errors.As(err, &respErr) &&
+		(respErr.StatusCode == http.StatusForbidden || respErr.StatusCode == http.StatusUnauthorized)
+}
@@ -0,0 +1,154 @@
+package vault
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ohauer/secrets-sync/internal/errkind"
+)
+
+// tokenServer is a Vault stub accepting a single token, which tests rotate
+type tokenServer struct {
+	*httptest.Server
+	mu    sync.Mutex
+	valid string
+	seen  []string
+}
+
+func newTokenServer(t *testing.T, valid string) *tokenServer {
+	s := &tokenServer{valid: valid}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-Vault-Token")
+		s.mu.Lock()
+		s.seen = append(s.seen, token)
+		ok := token == s.valid
+		s.mu.Unlock()
+		if !ok {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/auth/token/lookup-self":
+			_, _ = w.Write([]byte(`{"data":{"id":"` + token + `"}}`))
+		case "/v1/secret/data/app":
+			_, _ = w.Write([]byte(`{"data":{"data":{"key":"value"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *tokenServer) rotate(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.valid = token
+}
+
+func writeToken(t *testing.T, path, token string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTokenFileClient(t *testing.T, server *tokenServer, path string) *Client {
+	t.Helper()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	if err := client.Authenticate(context.Background(), AuthConfig{Method: AuthMethodTokenFile, TokenFile: path}); err != nil {
+		t.Fatalf("token file authentication failed: %v", err)
+	}
+	return client
+}
+
+func TestClient_AuthenticateTokenFile_FileChanged(t *testing.T) {
+	server := newTokenServer(t, "token-1")
+	path := filepath.Join(t.TempDir(), "token")
+	modTime := time.Now().Add(-time.Hour)
+	writeToken(t, path, "token-1", modTime)
+
+	client := newTokenFileClient(t, server, path)
+	if client.GetAPIClient().Token() != "token-1" {
+		t.Fatalf("expected token from file, got %q", client.GetAPIClient().Token())
+	}
+
+	// The agent writes a new token before the old one is revoked
+	writeToken(t, path, "token-2", modTime.Add(time.Minute))
+	server.rotate("token-2")
+
+	if _, err := client.FetchSecret(context.Background(), "secret", "app", "v2", ""); err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	if client.GetAPIClient().Token() != "token-2" {
+		t.Errorf("expected changed token to be used, got %q", client.GetAPIClient().Token())
+	}
+	if len(server.seen) != 2 || server.seen[1] != "token-2" {
+		t.Errorf("expected the fetch to use the new token up front, got %v", server.seen)
+	}
+}
+
+func TestClient_AuthenticateTokenFile_RereadOnRejection(t *testing.T) {
+	server := newTokenServer(t, "token-1")
+	path := filepath.Join(t.TempDir(), "token")
+	modTime := time.Now().Add(-time.Hour)
+	writeToken(t, path, "token-1", modTime)
+
+	client := newTokenFileClient(t, server, path)
+
+	// Same size and modification time, so only a rejection reveals the change
+	writeToken(t, path, "token-2", modTime)
+	server.rotate("token-2")
+
+	if _, err := client.FetchSecret(context.Background(), "secret", "app", "v2", ""); err != nil {
+		t.Fatalf("expected fetch to succeed after re-reading the token, got: %v", err)
+	}
+	if client.GetAPIClient().Token() != "token-2" {
+		t.Errorf("expected re-read token, got %q", client.GetAPIClient().Token())
+	}
+
+	// A rejected token that did not change in the file is not retried
+	server.rotate("token-3")
+	_, err := client.FetchSecret(context.Background(), "secret", "app", "v2", "")
+	if errkind.Of(err) != errkind.Permission {
+		t.Errorf("expected permission error, got: %v", err)
+	}
+}
+
+func TestClient_AuthenticateTokenFile_Errors(t *testing.T) {
+	client, err := NewClient("http://localhost:8200")
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty")
+	if err := os.WriteFile(empty, []byte("\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, path := range map[string]string{
+		"missing path": "",
+		"missing file": filepath.Join(t.TempDir(), "absent"),
+		"empty file":   empty,
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := client.Authenticate(context.Background(), AuthConfig{Method: AuthMethodTokenFile, TokenFile: path})
+			if errkind.Of(err) != errkind.Auth {
+				t.Errorf("expected auth error, got: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package vault
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/hashicorp/vault/api"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traced returns the Vault client to send a request for ctx in namespace
+// with. The namespace is set on a copy, so it is sent with this request only
+// and an empty namespace reads the root namespace. When ctx carries a span,
+// requests carry its trace context (traceparent) so Vault's own traces and
+// audit log can be joined with ours.
+func (c *Client) traced(ctx context.Context, namespace string) *api.Client {
+	client := c.client.WithNamespace(namespace)
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return client
+	}
+	return client.WithRequestCallbacks(func(r *api.Request) {
+		if r.Headers == nil {
+			r.Headers = make(http.Header)
+		}
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.Headers))
+	})
+}
@@ -0,0 +1,119 @@
+package vault
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestFetchSecret_PropagatesTraceContext(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	var traceparents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		_, _ = w.Write([]byte(`{"data": {"data": {"username": "testuser"}}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	if _, err := client.FetchSecret(ctx, "secret", "test", "v2", ""); err != nil {
+		t.Fatalf("failed to fetch secret: %v", err)
+	}
+	if _, err := client.FetchSecret(context.Background(), "secret", "test", "v2", ""); err != nil {
+		t.Fatalf("failed to fetch secret: %v", err)
+	}
+
+	if len(traceparents) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(traceparents))
+	}
+	if !strings.Contains(traceparents[0], "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7") {
+		t.Errorf("expected traceparent of the span, got %q", traceparents[0])
+	}
+	if traceparents[1] != "" {
+		t.Errorf("expected no traceparent without a span, got %q", traceparents[1])
+	}
+}
+
+func TestFetchSecret_NamespacePerRequest(t *testing.T) {
+	var namespaces []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		namespaces = append(namespaces, r.Header.Get("X-Vault-Namespace"))
+		_, _ = w.Write([]byte(`{"data": {"data": {"username": "testuser"}}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	for _, ns := range []string{"team-a", "team-b/", ""} {
+		if _, err := client.FetchSecret(context.Background(), "secret", "test", "v2", ns); err != nil {
+			t.Fatalf("failed to fetch secret: %v", err)
+		}
+	}
+
+	want := []string{"team-a", "team-b/", ""}
+	if strings.Join(namespaces, ",") != strings.Join(want, ",") {
+		t.Errorf("expected namespaces %q, got %q", want, namespaces)
+	}
+}
+
+func TestAuthenticate_AuthNamespace(t *testing.T) {
+	var login, read string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/auth/approle/login"):
+			login = r.Header.Get("X-Vault-Namespace")
+			_, _ = w.Write([]byte(`{"auth": {"client_token": "s.token"}}`))
+		default:
+			read = r.Header.Get("X-Vault-Namespace")
+			_, _ = w.Write([]byte(`{"data": {"data": {"username": "testuser"}}}`))
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	err = client.Authenticate(context.Background(), AuthConfig{
+		Method:    AuthMethodAppRole,
+		RoleID:    "role",
+		SecretID:  "secret",
+		Namespace: "tenant",
+	})
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+	if _, err := client.FetchSecret(context.Background(), "secret", "test", "v2", "tenant/team-a"); err != nil {
+		t.Fatalf("failed to fetch secret: %v", err)
+	}
+
+	if login != "tenant" {
+		t.Errorf("expected login in namespace tenant, got %q", login)
+	}
+	if read != "tenant/team-a" {
+		t.Errorf("expected read in namespace tenant/team-a, got %q", read)
+	}
+}
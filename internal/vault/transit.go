@@ -0,0 +1,41 @@
+package vault
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"path"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// TransitDecrypt decrypts a transit ciphertext (vault:v1:...) with the
+// named key of the transit engine mounted at mountPath
+func (c *Client) TransitDecrypt(ctx context.Context, mountPath, key, ciphertext, namespace string) ([]byte, error) {
+	result, err := c.executeWithBreaker(func() (interface{}, error) {
+		secret, err := c.traced(ctx, namespace).Logical().WriteWithContext(ctx, path.Join(mountPath, "decrypt", key), map[string]interface{}{
+			"ciphertext": ciphertext,
+		})
+		if isSealed(err) {
+			return nil, fmt.Errorf("%w: %v", ErrSealed, err)
+		}
+		return secret, err
+	})
+	if err != nil {
+		return nil, classify(fmt.Errorf("failed to decrypt with transit key %s: %w", key, err))
+	}
+
+	secret, ok := result.(*api.Secret)
+	if !ok || secret == nil || secret.Data == nil {
+		return nil, fmt.Errorf("invalid transit decrypt response")
+	}
+	encoded, ok := secret.Data["plaintext"].(string)
+	if !ok {
+		return nil, fmt.Errorf("transit decrypt response has no plaintext")
+	}
+	plaintext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transit plaintext: %w", err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,49 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTransitDecrypt(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Ciphertext string `json:"ciphertext"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch {
+		case r.URL.Path != "/v1/transit/decrypt/app" || r.Method != http.MethodPut:
+			w.WriteHeader(http.StatusNotFound)
+		case body.Ciphertext != "vault:v1:abc":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errors": ["invalid ciphertext"]}`))
+		default:
+			// "hunter2"
+			_, _ = w.Write([]byte(`{"data": {"plaintext": "aHVudGVyMg=="}}`))
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	plaintext, err := client.TransitDecrypt(context.Background(), "transit", "app", "vault:v1:abc", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(plaintext) != "hunter2" {
+		t.Errorf("expected hunter2, got %q", plaintext)
+	}
+
+	if _, err := client.TransitDecrypt(context.Background(), "transit", "app", "vault:v1:bad", ""); err == nil {
+		t.Error("expected error for invalid ciphertext")
+	}
+}
@@ -0,0 +1,39 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+
+	"github.com/ohauer/secrets-sync/internal/errkind"
+)
+
+// CurrentVersion returns the current version of a KV v2 secret from its
+// metadata, without reading the secret data. A current version that was
+// deleted or destroyed returns ErrSecretDeleted.
+func (c *Client) CurrentVersion(ctx context.Context, mountPath, secretPath, namespace string) (int, error) {
+	secret, err := c.read(ctx, path.Join(mountPath, "metadata", secretPath), namespace, nil)
+	if err != nil {
+		return 0, err
+	}
+	if secret == nil {
+		return 0, errkind.Wrap(errkind.NotFound, fmt.Errorf("%w: not found at path %s", ErrSecretDeleted, secretPath))
+	}
+
+	current := intValue(secret.Data["current_version"])
+	if current == 0 {
+		return 0, fmt.Errorf("invalid metadata for path %s", secretPath)
+	}
+
+	versions, _ := secret.Data["versions"].(map[string]interface{})
+	if info, ok := versions[strconv.Itoa(current)].(map[string]interface{}); ok {
+		if destroyed, _ := info["destroyed"].(bool); destroyed {
+			return 0, errkind.Wrap(errkind.NotFound, fmt.Errorf("%w: latest version at path %s was destroyed", ErrSecretDeleted, secretPath))
+		}
+		if deletedAt, _ := info["deletion_time"].(string); deletedAt != "" {
+			return 0, errkind.Wrap(errkind.NotFound, fmt.Errorf("%w: latest version at path %s was deleted at %s", ErrSecretDeleted, secretPath, deletedAt))
+		}
+	}
+	return current, nil
+}
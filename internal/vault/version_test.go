@@ -0,0 +1,124 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchSecretVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/secret/data/test/path" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.URL.Query().Get("version") {
+		case "":
+			_, _ = w.Write([]byte(`{"data": {"data": {"key": "latest"}, "metadata": {"version": 3}}}`))
+		case "2":
+			_, _ = w.Write([]byte(`{"data": {"data": {"key": "pinned"}, "metadata": {"version": 2}}}`))
+		case "1":
+			_, _ = w.Write([]byte(`{"data": {"data": null, "metadata": {"version": 1, "destroyed": true}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	tests := []struct {
+		version     int
+		wantKey     string
+		wantVersion int
+		wantDeleted bool
+	}{
+		{version: 0, wantKey: "latest", wantVersion: 3},
+		{version: 2, wantKey: "pinned", wantVersion: 2},
+		{version: 1, wantDeleted: true},
+		{version: 9, wantDeleted: true},
+	}
+
+	for _, tt := range tests {
+		data, version, err := client.FetchSecretVersion(context.Background(), "secret", "test/path", "", tt.version)
+		if tt.wantDeleted {
+			if !errors.Is(err, ErrSecretDeleted) {
+				t.Errorf("version %d: expected ErrSecretDeleted, got: %v", tt.version, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("version %d: unexpected error: %v", tt.version, err)
+			continue
+		}
+		if data["key"] != tt.wantKey || version != tt.wantVersion {
+			t.Errorf("version %d: expected %s (v%d), got %v (v%d)", tt.version, tt.wantKey, tt.wantVersion, data["key"], version)
+		}
+	}
+}
+
+func TestCurrentVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		want        int
+		wantDeleted bool
+	}{
+		{
+			name: "current",
+			body: `{"data": {"current_version": 2, "versions": {"1": {"deletion_time": "", "destroyed": false}, "2": {"deletion_time": "", "destroyed": false}}}}`,
+			want: 2,
+		},
+		{
+			name:        "deleted",
+			body:        `{"data": {"current_version": 2, "versions": {"2": {"deletion_time": "2024-01-01T00:00:00Z", "destroyed": false}}}}`,
+			wantDeleted: true,
+		},
+		{
+			name:        "destroyed",
+			body:        `{"data": {"current_version": 2, "versions": {"2": {"deletion_time": "", "destroyed": true}}}}`,
+			wantDeleted: true,
+		},
+		{
+			name:        "missing",
+			wantDeleted: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.body == "" || r.URL.Path != "/v1/secret/metadata/test/path" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client, err := NewClient(server.URL)
+			if err != nil {
+				t.Fatalf("failed to create client: %v", err)
+			}
+
+			got, err := client.CurrentVersion(context.Background(), "secret", "test/path", "")
+			if tt.wantDeleted {
+				if !errors.Is(err, ErrSecretDeleted) {
+					t.Errorf("expected ErrSecretDeleted, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected version %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/vault/api"
+
+	"github.com/ohauer/secrets-sync/internal/errkind"
+)
+
+// unwrappedSecretIDs maps wrapping tokens to the secret IDs they wrapped.
+// A wrapping token can be unwrapped only once, but a credential set logs in
+// again whenever a client is created, e.g. after a config reload.
+var unwrappedSecretIDs = struct {
+	sync.Mutex
+	ids map[string]string
+}{ids: make(map[string]string)}
+
+// unwrapSecretID returns the AppRole secret ID wrapped by wrappingToken. The
+// token is looked up first, so a token that was not created for a secret ID,
+// or was already unwrapped by someone else, is rejected.
+func (c *Client) unwrapSecretID(ctx context.Context, wrappingToken string) (string, error) {
+	unwrappedSecretIDs.Lock()
+	defer unwrappedSecretIDs.Unlock()
+
+	if secretID, ok := unwrappedSecretIDs.ids[wrappingToken]; ok {
+		return secretID, nil
+	}
+
+	result, err := c.executeWithBreaker(func() (interface{}, error) {
+		return c.traced(ctx, c.authNamespace).Logical().WriteWithContext(ctx, "sys/wrapping/lookup", map[string]interface{}{"token": wrappingToken})
+	})
+	if err != nil {
+		return "", authError("secret ID wrapping token lookup failed", err)
+	}
+	lookup, ok := result.(*api.Secret)
+	if !ok || lookup == nil {
+		return "", errkind.Wrap(errkind.Auth, fmt.Errorf("secret ID wrapping token lookup returned no data"))
+	}
+	creationPath, _ := lookup.Data["creation_path"].(string)
+	if !strings.HasPrefix(creationPath, "auth/") || !strings.HasSuffix(creationPath, "/secret-id") {
+		return "", errkind.Wrap(errkind.Auth, fmt.Errorf("wrapping token was not created for a secret ID (creation path %q)", creationPath))
+	}
+
+	result, err = c.executeWithBreaker(func() (interface{}, error) {
+		return c.traced(ctx, c.authNamespace).Logical().UnwrapWithContext(ctx, wrappingToken)
+	})
+	if err != nil {
+		return "", authError("secret ID unwrap failed", err)
+	}
+	wrapped, ok := result.(*api.Secret)
+	if !ok || wrapped == nil {
+		return "", errkind.Wrap(errkind.Auth, fmt.Errorf("secret ID unwrap returned no data"))
+	}
+	secretID, _ := wrapped.Data["secret_id"].(string)
+	if secretID == "" {
+		return "", errkind.Wrap(errkind.Auth, fmt.Errorf("wrapped response contains no secret_id"))
+	}
+
+	unwrappedSecretIDs.ids[wrappingToken] = secretID
+	return secretID, nil
+}
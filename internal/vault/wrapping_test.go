@@ -0,0 +1,121 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ohauer/secrets-sync/internal/errkind"
+)
+
+// newWrappingServer serves a wrapping token wrapping "unwrapped-secret-id",
+// created at creationPath, that can be unwrapped once
+func newWrappingServer(t *testing.T, wrappingToken, creationPath string, unwraps *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		switch r.URL.Path {
+		case "/v1/sys/wrapping/lookup":
+			if body["token"] != wrappingToken {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"errors":["wrapping token is not valid or does not exist"]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":{"creation_path":"` + creationPath + `","creation_ttl":300}}`))
+		case "/v1/sys/wrapping/unwrap":
+			if r.Header.Get("X-Vault-Token") != wrappingToken || unwraps.Add(1) > 1 {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"errors":["wrapping token is not valid or does not exist"]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":{"secret_id":"unwrapped-secret-id","secret_id_accessor":"accessor"}}`))
+		case "/v1/auth/approle/login":
+			if body["secret_id"] != "unwrapped-secret-id" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"errors":["invalid secret id"]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"auth":{"client_token":"approle-token"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_AuthenticateAppRole_WrappedSecretID(t *testing.T) {
+	var unwraps atomic.Int32
+	server := newWrappingServer(t, "hvs.wrapping-1", "auth/approle/role/web/secret-id", &unwraps)
+
+	tokenFile := filepath.Join(t.TempDir(), "secret-id")
+	if err := os.WriteFile(tokenFile, []byte("hvs.wrapping-1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config := AuthConfig{
+		Method:          AuthMethodAppRole,
+		RoleID:          "role-id",
+		SecretIDFile:    tokenFile,
+		SecretIDWrapped: true,
+	}
+
+	// Clients created later for the same credentials must not unwrap again
+	for i := 0; i < 2; i++ {
+		client, err := NewClient(server.URL)
+		if err != nil {
+			t.Fatalf("failed to create client: %v", err)
+		}
+		if err := client.Authenticate(context.Background(), config); err != nil {
+			t.Fatalf("login %d failed: %v", i+1, err)
+		}
+		if client.GetAPIClient().Token() != "approle-token" {
+			t.Errorf("expected token 'approle-token', got: %s", client.GetAPIClient().Token())
+		}
+	}
+	if n := unwraps.Load(); n != 1 {
+		t.Errorf("expected the token to be unwrapped once, got %d", n)
+	}
+}
+
+func TestClient_AuthenticateAppRole_WrappedSecretIDRejected(t *testing.T) {
+	var unwraps atomic.Int32
+	server := newWrappingServer(t, "hvs.wrapping-2", "sys/wrapping/wrap", &unwraps)
+
+	tests := []struct {
+		name     string
+		secretID string
+	}{
+		{"not created for a secret ID", "hvs.wrapping-2"},
+		{"unknown token", "hvs.unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(server.URL)
+			if err != nil {
+				t.Fatalf("failed to create client: %v", err)
+			}
+			err = client.Authenticate(context.Background(), AuthConfig{
+				Method:          AuthMethodAppRole,
+				RoleID:          "role-id",
+				SecretID:        tt.secretID,
+				SecretIDWrapped: true,
+			})
+			if !errors.Is(err, errkind.Auth) {
+				t.Errorf("expected auth error, got: %v", err)
+			}
+		})
+	}
+	if n := unwraps.Load(); n != 0 {
+		t.Errorf("expected no unwrap, got %d", n)
+	}
+}
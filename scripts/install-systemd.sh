@@ -14,6 +14,9 @@ CONFIG_DIR="/etc/secrets-sync"
 MAN_PAGE_SRC="docs/secrets-sync.1"
 MAN_PAGE_DEST="/usr/share/man/man1/secrets-sync.1"
 DOC_DIR="/usr/share/doc/secrets-sync"
+BASH_COMPLETION_DEST="/usr/share/bash-completion/completions/secrets-sync"
+ZSH_COMPLETION_DEST="/usr/share/zsh/site-functions/_secrets-sync"
+FISH_COMPLETION_DEST="/usr/share/fish/vendor_completions.d/secrets-sync.fish"
 
 log_message() {
     echo "$(date '+%Y-%m-%d %H:%M:%S') ${SCRIPT_NAME} - $1"
@@ -112,6 +115,18 @@ install_man_page() {
     fi
 }
 
+install_completions() {
+    log_message "Installing shell completions"
+    for shell_dest in "bash:${BASH_COMPLETION_DEST}" "zsh:${ZSH_COMPLETION_DEST}" "fish:${FISH_COMPLETION_DEST}"; do
+        shell="${shell_dest%%:*}"
+        dest="${shell_dest#*:}"
+        mkdir -p "$(dirname "${dest}")"
+        "${BINARY_DEST}" completion "${shell}" > "${dest}"
+        chmod 644 "${dest}"
+    done
+    log_message "Shell completions installed for bash, zsh and fish"
+}
+
 install_documentation() {
     log_message "Installing documentation to ${DOC_DIR}"
     mkdir -p "${DOC_DIR}"
@@ -155,6 +170,7 @@ main() {
     install_env_file
     generate_config
     install_man_page
+    install_completions
     install_documentation
     reload_systemd
     enable_service
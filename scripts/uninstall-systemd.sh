@@ -10,6 +10,7 @@ ENV_FILE_DEST="/etc/default/secrets-sync"
 CONFIG_DIR="/etc/secrets-sync"
 MAN_PAGE_DEST="/usr/share/man/man1/secrets-sync.1.gz"
 DOC_DIR="/usr/share/doc/secrets-sync"
+COMPLETION_FILES="/usr/share/bash-completion/completions/secrets-sync /usr/share/zsh/site-functions/_secrets-sync /usr/share/fish/vendor_completions.d/secrets-sync.fish"
 
 log_message() {
     echo "$(date '+%Y-%m-%d %H:%M:%S') ${SCRIPT_NAME} - $1"
@@ -85,6 +86,15 @@ remove_man_page() {
     fi
 }
 
+remove_completions() {
+    for file in ${COMPLETION_FILES}; do
+        if [ -f "${file}" ]; then
+            log_message "Removing shell completion ${file}"
+            rm -f "${file}"
+        fi
+    done
+}
+
 remove_documentation() {
     if [ -d "${DOC_DIR}" ]; then
         log_message "Removing documentation ${DOC_DIR}"
@@ -120,6 +130,7 @@ main() {
     remove_env_file
     remove_config
     remove_man_page
+    remove_completions
     remove_documentation
     remove_user
     reload_systemd